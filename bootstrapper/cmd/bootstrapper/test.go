@@ -9,6 +9,8 @@ package main
 import (
 	"context"
 
+	"github.com/edgelesssys/constellation/v2/bootstrapper/initproto"
+	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/registrymirror"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
 	"github.com/edgelesssys/constellation/v2/internal/role"
 	"github.com/edgelesssys/constellation/v2/internal/versions/components"
@@ -21,7 +23,8 @@ type clusterFake struct{}
 // InitCluster fakes bootstrapping a new cluster with the current node being the master, returning the arguments required to join the cluster.
 func (c *clusterFake) InitCluster(
 	context.Context, string, string,
-	bool, components.Components, []string, string,
+	bool, components.Components, []string, string, *initproto.OIDCConfig, *initproto.DebugAccess,
+	[]registrymirror.Mirror,
 ) ([]byte, error) {
 	return []byte{}, nil
 }
@@ -0,0 +1,45 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+/*
+Package debugaccess sets up the SSH access to a node for debugging.
+
+The authorized keys are written to the state disk. The image starts its SSH daemon
+as soon as the file exists, so nodes of a cluster without debug access don't run one.
+*/
+package debugaccess
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"golang.org/x/crypto/ssh"
+)
+
+// AuthorizedKeysPath is the file on the state disk the SSH daemon of the image reads the authorized keys from.
+const AuthorizedKeysPath = "/run/state/ssh/authorized_keys"
+
+// Setup writes the authorized keys to the state disk. If no keys are given, debug access stays disabled.
+func Setup(fileHandler file.Handler, authorizedKeys []string) error {
+	if len(authorizedKeys) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for i, key := range authorizedKeys {
+		// Reject anything but a single public key per entry, so no additional lines or options are written to the file.
+		if strings.ContainsAny(key, "\r\n") {
+			return fmt.Errorf("authorized key %d must be a single line", i)
+		}
+		if _, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key)); err != nil {
+			return fmt.Errorf("parsing authorized key %d: %w", i, err)
+		}
+		buf.WriteString(key + "\n")
+	}
+	return fileHandler.Write(AuthorizedKeysPath, buf.Bytes(), file.OptMkdirAll, file.OptOverwrite)
+}
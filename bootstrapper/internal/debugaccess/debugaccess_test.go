@@ -0,0 +1,64 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package debugaccess
+
+import (
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetup(t *testing.T) {
+	const key = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIHO6fX0WwmfN84U0OmpPNix0WMZySwwHKx5vcU21QqMj admin@example.com"
+
+	testCases := map[string]struct {
+		authorizedKeys     []string
+		wantAuthorizedKeys string
+		wantErr            bool
+	}{
+		"debug access disabled": {},
+		"authorized keys are written": {
+			authorizedKeys:     []string{key, key},
+			wantAuthorizedKeys: key + "\n" + key + "\n",
+		},
+		"invalid key": {
+			authorizedKeys: []string{"ssh-ed25519 invalid"},
+			wantErr:        true,
+		},
+		"additional line": {
+			authorizedKeys: []string{key + "\ncommand=\"sh\" " + key},
+			wantErr:        true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+
+			err := Setup(fileHandler, tc.authorizedKeys)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			authorizedKeys, err := fileHandler.Read(AuthorizedKeysPath)
+			if tc.wantAuthorizedKeys == "" {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tc.wantAuthorizedKeys, string(authorizedKeys))
+		})
+	}
+}
@@ -30,7 +30,9 @@ import (
 	"time"
 
 	"github.com/edgelesssys/constellation/v2/bootstrapper/initproto"
+	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/debugaccess"
 	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/journald"
+	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/registrymirror"
 	"github.com/edgelesssys/constellation/v2/internal/atls"
 	"github.com/edgelesssys/constellation/v2/internal/attestation"
 	"github.com/edgelesssys/constellation/v2/internal/crypto"
@@ -222,6 +224,26 @@ func (s *Server) Init(req *initproto.InitRequest, stream initproto.API_InitServe
 		return err
 	}
 
+	if authorizedKeys := req.DebugAccess.GetAuthorizedKeys(); len(authorizedKeys) > 0 {
+		log.Warn("Debug access is enabled. SSH access to the node breaks the confidentiality of the cluster", "authorizedKeys", len(authorizedKeys))
+	}
+	if err := debugaccess.Setup(s.fileHandler, req.DebugAccess.GetAuthorizedKeys()); err != nil {
+		if e := s.sendLogsWithMessage(stream, status.Errorf(codes.Internal, "setting up debug access: %s", err)); e != nil {
+			err = errors.Join(err, e)
+		}
+		return err
+	}
+
+	for _, mirror := range req.RegistryMirrors {
+		log.Warn("Images of the registry are pulled from mirrors, which aren't covered by attestation", "registry", mirror.Registry, "endpoints", mirror.Endpoints)
+	}
+	if err := registrymirror.Setup(s.fileHandler, registryMirrors(req.RegistryMirrors)); err != nil {
+		if e := s.sendLogsWithMessage(stream, status.Errorf(codes.Internal, "setting up registry mirrors: %s", err)); e != nil {
+			err = errors.Join(err, e)
+		}
+		return err
+	}
+
 	clusterName := req.ClusterName
 	if clusterName == "" {
 		clusterName = "constellation"
@@ -234,6 +256,9 @@ func (s *Server) Init(req *initproto.InitRequest, stream initproto.API_InitServe
 		req.KubernetesComponents,
 		req.ApiserverCertSans,
 		req.ServiceCidr,
+		req.OidcConfig,
+		req.DebugAccess,
+		registryMirrors(req.RegistryMirrors),
 	)
 	if err != nil {
 		if e := s.sendLogsWithMessage(stream, status.Errorf(codes.Internal, "initializing cluster: %s", err)); e != nil {
@@ -311,6 +336,15 @@ func (s *Server) Stop() {
 	s.log.Info("Stopped")
 }
 
+// registryMirrors converts the registry mirrors of the init request.
+func registryMirrors(mirrors []*initproto.RegistryMirror) []registrymirror.Mirror {
+	var res []registrymirror.Mirror
+	for _, mirror := range mirrors {
+		res = append(res, registrymirror.Mirror{Registry: mirror.Registry, Endpoints: mirror.Endpoints})
+	}
+	return res
+}
+
 func (s *Server) setupDisk(ctx context.Context, cloudKms kms.CloudKMS) error {
 	free, err := s.disk.Open()
 	if err != nil {
@@ -356,6 +390,9 @@ type ClusterInitializer interface {
 		kubernetesComponents components.Components,
 		apiServerCertSANs []string,
 		serviceCIDR string,
+		oidcConfig *initproto.OIDCConfig,
+		debugAccess *initproto.DebugAccess,
+		registryMirrors []registrymirror.Mirror,
 	) ([]byte, error)
 }
 
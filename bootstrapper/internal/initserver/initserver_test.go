@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"github.com/edgelesssys/constellation/v2/bootstrapper/initproto"
+	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/registrymirror"
 	"github.com/edgelesssys/constellation/v2/internal/atls"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/variant"
 	"github.com/edgelesssys/constellation/v2/internal/crypto/testvector"
@@ -419,7 +420,8 @@ type stubClusterInitializer struct {
 
 func (i *stubClusterInitializer) InitCluster(
 	context.Context, string, string,
-	bool, components.Components, []string, string,
+	bool, components.Components, []string, string, *initproto.OIDCConfig, *initproto.DebugAccess,
+	[]registrymirror.Mirror,
 ) ([]byte, error) {
 	return i.initClusterKubeconfig, i.initClusterErr
 }
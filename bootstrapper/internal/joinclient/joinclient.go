@@ -28,6 +28,8 @@ import (
 	"time"
 
 	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/certificate"
+	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/debugaccess"
+	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/registrymirror"
 	"github.com/edgelesssys/constellation/v2/internal/attestation"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
@@ -271,6 +273,21 @@ func (c *JoinClient) startNodeAndJoin(ticket *joinproto.IssueJoinTicketResponse,
 		return fmt.Errorf("writing kubelet key: %w", err)
 	}
 
+	if len(ticket.DebugAccessAuthorizedKeys) > 0 {
+		c.log.Warn("Debug access is enabled. SSH access to the node breaks the confidentiality of the cluster", "authorizedKeys", len(ticket.DebugAccessAuthorizedKeys))
+	}
+	if err := debugaccess.Setup(c.fileHandler, ticket.DebugAccessAuthorizedKeys); err != nil {
+		return fmt.Errorf("setting up debug access: %w", err)
+	}
+	mirrors := make([]registrymirror.Mirror, 0, len(ticket.RegistryMirrors))
+	for _, mirror := range ticket.RegistryMirrors {
+		c.log.Warn("Images of the registry are pulled from mirrors, which aren't covered by attestation", "registry", mirror.Registry, "endpoints", mirror.Endpoints)
+		mirrors = append(mirrors, registrymirror.Mirror{Registry: mirror.Registry, Endpoints: mirror.Endpoints})
+	}
+	if err := registrymirror.Setup(c.fileHandler, mirrors); err != nil {
+		return fmt.Errorf("setting up registry mirrors: %w", err)
+	}
+
 	state := nodestate.NodeState{
 		Role:            c.role,
 		MeasurementSalt: ticket.MeasurementSalt,
@@ -9,11 +9,14 @@ package joinclient
 import (
 	"context"
 	"net"
+	"path/filepath"
 	"strconv"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/debugaccess"
+	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/registrymirror"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/file"
@@ -51,6 +54,8 @@ func TestClient(t *testing.T) {
 		{Role: role.ControlPlane, Name: "node-5", VPCIP: "192.0.2.3"},
 	}
 
+	const authorizedKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIHO6fX0WwmfN84U0OmpPNix0WMZySwwHKx5vcU21QqMj admin@example.com"
+
 	testCases := map[string]struct {
 		role          role.Role
 		clusterJoiner *stubClusterJoiner
@@ -60,6 +65,8 @@ func TestClient(t *testing.T) {
 		wantLock      bool
 		wantJoin      bool
 		wantNumJoins  int
+		wantAuthKeys  string
+		wantHosts     bool
 	}{
 		"on worker: metadata self: errors occur": {
 			role: role.Worker,
@@ -186,6 +193,38 @@ func TestClient(t *testing.T) {
 			wantLock:      true,
 			wantNumJoins:  2,
 		},
+		"on worker: debug access is set up": {
+			role: role.Worker,
+			apiAnswers: []any{
+				selfAnswer{instance: workerSelf},
+				listAnswer{instances: peers},
+				issueJoinTicketAnswer{resp: &joinproto.IssueJoinTicketResponse{
+					DebugAccessAuthorizedKeys: []string{authorizedKey},
+				}},
+			},
+			clusterJoiner: &stubClusterJoiner{},
+			nodeLock:      newFakeLock(),
+			disk:          &stubDisk{},
+			wantJoin:      true,
+			wantLock:      true,
+			wantAuthKeys:  authorizedKey + "\n",
+		},
+		"on worker: registry mirrors are set up": {
+			role: role.Worker,
+			apiAnswers: []any{
+				selfAnswer{instance: workerSelf},
+				listAnswer{instances: peers},
+				issueJoinTicketAnswer{resp: &joinproto.IssueJoinTicketResponse{
+					RegistryMirrors: []*joinproto.RegistryMirror{{Registry: "docker.io", Endpoints: []string{"https://mirror.example.com"}}},
+				}},
+			},
+			clusterJoiner: &stubClusterJoiner{},
+			nodeLock:      newFakeLock(),
+			disk:          &stubDisk{},
+			wantJoin:      true,
+			wantLock:      true,
+			wantHosts:     true,
+		},
 		"on control plane: node already locked": {
 			role: role.ControlPlane,
 			apiAnswers: []any{
@@ -277,6 +316,15 @@ func TestClient(t *testing.T) {
 			} else {
 				assert.True(client.nodeLock.TryLockOnce(nil))
 			}
+			authorizedKeys, err := fileHandler.Read(debugaccess.AuthorizedKeysPath)
+			if tc.wantAuthKeys != "" {
+				assert.NoError(err)
+				assert.Equal(tc.wantAuthKeys, string(authorizedKeys))
+			} else {
+				assert.Error(err)
+			}
+			_, err = fileHandler.Read(filepath.Join(registrymirror.HostsDir, "docker.io", "hosts.toml"))
+			assert.Equal(tc.wantHosts, err == nil)
 		})
 	}
 }
@@ -9,6 +9,7 @@ package k8sapi
 import (
 	"path/filepath"
 
+	"github.com/edgelesssys/constellation/v2/bootstrapper/initproto"
 	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/certificate"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/kubernetes"
@@ -242,6 +243,27 @@ func (k *KubeadmJoinYAML) SetControlPlane(advertiseAddress string) {
 	k.JoinConfiguration.SkipPhases = []string{"control-plane-prepare/download-certs"}
 }
 
+// SetOIDC configures the API server to authenticate users with ID tokens of the given OIDC issuer.
+// Empty claim mappings keep the defaults of the API server.
+func (k *KubeadmInitYAML) SetOIDC(oidc *initproto.OIDCConfig) {
+	if oidc.GetIssuerUrl() == "" {
+		return
+	}
+	args := map[string]string{
+		"oidc-issuer-url":      oidc.GetIssuerUrl(),
+		"oidc-client-id":       oidc.GetClientId(),
+		"oidc-username-claim":  oidc.GetUsernameClaim(),
+		"oidc-username-prefix": oidc.GetUsernamePrefix(),
+		"oidc-groups-claim":    oidc.GetGroupsClaim(),
+		"oidc-groups-prefix":   oidc.GetGroupsPrefix(),
+	}
+	for name, value := range args {
+		if value != "" {
+			k.ClusterConfiguration.APIServer.ExtraArgs[name] = value
+		}
+	}
+}
+
 // Marshal into a k8s resource YAML.
 func (k *KubeadmJoinYAML) Marshal() ([]byte, error) {
 	return kubernetes.MarshalK8SResources(k)
@@ -7,8 +7,10 @@ SPDX-License-Identifier: AGPL-3.0-only
 package k8sapi
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/edgelesssys/constellation/v2/bootstrapper/initproto"
 	"github.com/edgelesssys/constellation/v2/internal/kubernetes"
 	"github.com/edgelesssys/constellation/v2/internal/versions"
 	"github.com/stretchr/testify/assert"
@@ -36,6 +38,7 @@ func TestInitConfiguration(t *testing.T) {
 				c.SetNodeIP("192.0.2.0")
 				c.SetNodeName("node")
 				c.SetProviderID("somecloudprovider://instance-id")
+				c.SetOIDC(&initproto.OIDCConfig{IssuerUrl: "https://issuer.example.com", ClientId: "constellation"})
 				return c
 			}(),
 		},
@@ -56,6 +59,62 @@ func TestInitConfiguration(t *testing.T) {
 	}
 }
 
+func TestSetOIDC(t *testing.T) {
+	testCases := map[string]struct {
+		oidc          *initproto.OIDCConfig
+		wantExtraArgs map[string]string
+	}{
+		"no OIDC config": {},
+		"empty issuer": {
+			oidc: &initproto.OIDCConfig{ClientId: "constellation"},
+		},
+		"issuer and client ID": {
+			oidc: &initproto.OIDCConfig{IssuerUrl: "https://issuer.example.com", ClientId: "constellation"},
+			wantExtraArgs: map[string]string{
+				"oidc-issuer-url": "https://issuer.example.com",
+				"oidc-client-id":  "constellation",
+			},
+		},
+		"all fields": {
+			oidc: &initproto.OIDCConfig{
+				IssuerUrl:      "https://issuer.example.com",
+				ClientId:       "constellation",
+				UsernameClaim:  "email",
+				UsernamePrefix: "oidc:",
+				GroupsClaim:    "groups",
+				GroupsPrefix:   "oidc:",
+			},
+			wantExtraArgs: map[string]string{
+				"oidc-issuer-url":      "https://issuer.example.com",
+				"oidc-client-id":       "constellation",
+				"oidc-username-claim":  "email",
+				"oidc-username-prefix": "oidc:",
+				"oidc-groups-claim":    "groups",
+				"oidc-groups-prefix":   "oidc:",
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			kubeadmConfig := KubdeadmConfiguration{}
+			config := kubeadmConfig.InitConfiguration(true, versions.VersionConfigs[versions.Default].ClusterVersion)
+			config.SetOIDC(tc.oidc)
+
+			for arg, value := range config.ClusterConfiguration.APIServer.ExtraArgs {
+				if strings.HasPrefix(arg, "oidc-") {
+					assert.Equal(tc.wantExtraArgs[arg], value, arg)
+				}
+			}
+			for arg, value := range tc.wantExtraArgs {
+				assert.Equal(value, config.ClusterConfiguration.APIServer.ExtraArgs[arg], arg)
+			}
+		})
+	}
+}
+
 func TestInitConfigurationKubeadmCompatibility(t *testing.T) {
 	kubeadmConfig := KubdeadmConfiguration{}
 
@@ -9,6 +9,7 @@ package kubernetes
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net"
@@ -16,9 +17,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/edgelesssys/constellation/v2/bootstrapper/initproto"
 	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/etcdio"
 	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/kubernetes/k8sapi"
 	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/kubernetes/kubewaiter"
+	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/registrymirror"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/kubernetes"
@@ -81,6 +84,7 @@ func New(cloudProvider string, clusterUtil clusterUtil, configProvider configura
 // InitCluster initializes a new Kubernetes cluster and applies pod network provider.
 func (k *KubeWrapper) InitCluster(
 	ctx context.Context, versionString, clusterName string, conformanceMode bool, kubernetesComponents components.Components, apiServerCertSANs []string, serviceCIDR string,
+	oidcConfig *initproto.OIDCConfig, debugAccess *initproto.DebugAccess, registryMirrors []registrymirror.Mirror,
 ) ([]byte, error) {
 	k.log.With(slog.String("version", versionString)).Info("Installing Kubernetes components")
 	if err := k.clusterUtil.InstallComponents(ctx, kubernetesComponents); err != nil {
@@ -140,6 +144,7 @@ func (k *KubeWrapper) InitCluster(
 	initConfig.SetProviderID(instance.ProviderID)
 	initConfig.SetControlPlaneEndpoint(controlPlaneHost)
 	initConfig.SetServiceSubnet(serviceCIDR)
+	initConfig.SetOIDC(oidcConfig)
 	initConfigYAML, err := initConfig.Marshal()
 	if err != nil {
 		return nil, fmt.Errorf("encoding kubeadm init configuration as YAML: %w", err)
@@ -190,7 +195,7 @@ func (k *KubeWrapper) InitCluster(
 	}
 
 	k.log.Info("Setting up internal-config ConfigMap")
-	if err := k.setupInternalConfigMap(ctx); err != nil {
+	if err := k.setupInternalConfigMap(ctx, debugAccess, registryMirrors); err != nil {
 		return nil, fmt.Errorf("failed to setup internal ConfigMap: %w", err)
 	}
 
@@ -281,7 +286,8 @@ func (k *KubeWrapper) setupK8sComponentsConfigMap(ctx context.Context, component
 }
 
 // setupInternalConfigMap applies a ConfigMap (cf. server-side apply) to store information that is not supposed to be user-editable.
-func (k *KubeWrapper) setupInternalConfigMap(ctx context.Context) error {
+// The join service sends the node settings stored in it to joining nodes.
+func (k *KubeWrapper) setupInternalConfigMap(ctx context.Context, debugAccess *initproto.DebugAccess, registryMirrors []registrymirror.Mirror) error {
 	config := corev1.ConfigMap{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: "v1",
@@ -293,6 +299,16 @@ func (k *KubeWrapper) setupInternalConfigMap(ctx context.Context) error {
 		},
 		Data: map[string]string{},
 	}
+	if authorizedKeys := debugAccess.GetAuthorizedKeys(); len(authorizedKeys) > 0 {
+		config.Data[constants.DebugAccessAuthorizedKeysKey] = strings.Join(authorizedKeys, "\n")
+	}
+	if len(registryMirrors) > 0 {
+		registryMirrorsJSON, err := json.Marshal(registryMirrors)
+		if err != nil {
+			return fmt.Errorf("marshaling registry mirrors: %w", err)
+		}
+		config.Data[constants.RegistryMirrorsKey] = string(registryMirrorsJSON)
+	}
 
 	// We do not use the client's Apply method here since we are handling a kubernetes-native type.
 	// These types don't implement our custom Marshaler interface.
@@ -14,8 +14,10 @@ import (
 	"strconv"
 	"testing"
 
+	"github.com/edgelesssys/constellation/v2/bootstrapper/initproto"
 	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/kubernetes/k8sapi"
 	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/kubernetes/kubewaiter"
+	"github.com/edgelesssys/constellation/v2/bootstrapper/internal/registrymirror"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/kubernetes"
@@ -202,7 +204,7 @@ func TestInitCluster(t *testing.T) {
 
 			_, err := kube.InitCluster(
 				context.Background(), string(tc.k8sVersion), "kubernetes",
-				false, nil, nil, "",
+				false, nil, nil, "", nil, nil, nil,
 			)
 
 			if tc.wantErr {
@@ -219,6 +221,45 @@ func TestInitCluster(t *testing.T) {
 	}
 }
 
+func TestSetupInternalConfigMap(t *testing.T) {
+	const key = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIHO6fX0WwmfN84U0OmpPNix0WMZySwwHKx5vcU21QqMj admin@example.com"
+
+	testCases := map[string]struct {
+		debugAccess     *initproto.DebugAccess
+		registryMirrors []registrymirror.Mirror
+		wantData        map[string]string
+	}{
+		"debug access disabled": {
+			wantData: map[string]string{},
+		},
+		"debug access enabled": {
+			debugAccess: &initproto.DebugAccess{AuthorizedKeys: []string{key, key}},
+			wantData:    map[string]string{constants.DebugAccessAuthorizedKeysKey: key + "\n" + key},
+		},
+		"registry mirrors": {
+			registryMirrors: []registrymirror.Mirror{{Registry: "docker.io", Endpoints: []string{"https://mirror.example.com"}}},
+			wantData: map[string]string{
+				constants.RegistryMirrorsKey: `[{"registry":"docker.io","endpoints":["https://mirror.example.com"]}]`,
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			kubectl := &stubKubectl{}
+			kube := KubeWrapper{client: kubectl}
+
+			require.NoError(kube.setupInternalConfigMap(context.Background(), tc.debugAccess, tc.registryMirrors))
+			require.Len(kubectl.createdConfigMaps, 1)
+			assert.Equal(constants.InternalConfigMap, kubectl.createdConfigMaps[0].Name)
+			assert.Equal(tc.wantData, kubectl.createdConfigMaps[0].Data)
+		})
+	}
+}
+
 func TestJoinCluster(t *testing.T) {
 	joinCommand := &kubeadm.BootstrapTokenDiscovery{
 		APIServerEndpoint: "192.0.2.0:" + strconv.Itoa(constants.KubernetesPort),
@@ -528,13 +569,15 @@ type stubKubectl struct {
 	enforceCoreDNSSpreadErr          error
 
 	listAllNamespacesResp *corev1.NamespaceList
+	createdConfigMaps     []*corev1.ConfigMap
 }
 
 func (s *stubKubectl) Initialize(_ []byte) error {
 	return nil
 }
 
-func (s *stubKubectl) CreateConfigMap(_ context.Context, _ *corev1.ConfigMap) error {
+func (s *stubKubectl) CreateConfigMap(_ context.Context, configMap *corev1.ConfigMap) error {
+	s.createdConfigMaps = append(s.createdConfigMaps, configMap)
 	return s.createConfigMapErr
 }
 
@@ -0,0 +1,81 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+/*
+Package registrymirror configures containerd to pull images of a registry from its mirrors.
+
+A hosts.toml is written for each mirrored registry to the state disk, before the kubelet is started.
+containerd reads the files on every image pull, so the mirrors are used without restarting it.
+*/
+package registrymirror
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/edgelesssys/constellation/v2/internal/file"
+)
+
+// HostsDir is the directory on the state disk containerd reads the host configuration of the registries from.
+// It is set as config_path of the CRI registry configuration in the containerd config of the image.
+const HostsDir = "/run/state/containerd/certs.d"
+
+// Mirror holds the endpoints a registry is mirrored to.
+type Mirror struct {
+	// Registry is the host of the mirrored registry.
+	Registry string `json:"registry"`
+	// Endpoints are the URLs of the mirrors, in the order they are tried.
+	Endpoints []string `json:"endpoints"`
+}
+
+// Setup writes a containerd hosts.toml for each mirrored registry to the state disk.
+func Setup(fileHandler file.Handler, mirrors []Mirror) error {
+	for _, mirror := range mirrors {
+		hostsFile, err := renderHosts(mirror)
+		if err != nil {
+			return fmt.Errorf("registry %q: %w", mirror.Registry, err)
+		}
+		if err := fileHandler.Write(filepath.Join(HostsDir, mirror.Registry, "hosts.toml"), hostsFile, file.OptMkdirAll, file.OptOverwrite); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderHosts renders the containerd hosts.toml of a registry, which lists the mirrors in the order they are tried.
+// The server isn't set, so containerd falls back to the registry itself if no mirror serves an image.
+func renderHosts(mirror Mirror) ([]byte, error) {
+	// The registry is used as directory name, so it must not escape the hosts directory.
+	if mirror.Registry == "" || mirror.Registry == "." || mirror.Registry == ".." || strings.ContainsAny(mirror.Registry, "/\\") {
+		return nil, errors.New("invalid registry host")
+	}
+	if len(mirror.Endpoints) == 0 {
+		return nil, errors.New("no endpoints")
+	}
+
+	var hostsFile bytes.Buffer
+	for i, endpoint := range mirror.Endpoints {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("parsing endpoint %d: %w", i, err)
+		}
+		if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, fmt.Errorf("endpoint %d must be an absolute http or https URL", i)
+		}
+		// The parsed URL escapes quotes and backslashes in its path, but not in its host.
+		// Reject the URL if they remain, so it can't end the TOML string.
+		hostURL := u.String()
+		if strings.ContainsAny(hostURL, "\"\\") {
+			return nil, fmt.Errorf("endpoint %d contains invalid characters", i)
+		}
+		fmt.Fprintf(&hostsFile, "[host.\"%s\"]\n  capabilities = [\"pull\", \"resolve\"]\n\n", hostURL)
+	}
+	return bytes.TrimSuffix(hostsFile.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package registrymirror
+
+import (
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetup(t *testing.T) {
+	testCases := map[string]struct {
+		mirrors        []Mirror
+		wantHostsFiles map[string]string
+		wantErr        bool
+	}{
+		"no mirrors": {},
+		"mirrors are rendered": {
+			mirrors: []Mirror{
+				{Registry: "docker.io", Endpoints: []string{"https://mirror.example.com", "http://10.0.0.1:5000/v2"}},
+				{Registry: "registry.example.com:5000", Endpoints: []string{"https://mirror.example.com"}},
+			},
+			wantHostsFiles: map[string]string{
+				"/run/state/containerd/certs.d/docker.io/hosts.toml": `[host."https://mirror.example.com"]
+  capabilities = ["pull", "resolve"]
+
+[host."http://10.0.0.1:5000/v2"]
+  capabilities = ["pull", "resolve"]
+`,
+				"/run/state/containerd/certs.d/registry.example.com:5000/hosts.toml": `[host."https://mirror.example.com"]
+  capabilities = ["pull", "resolve"]
+`,
+			},
+		},
+		"quotes in path are escaped": {
+			mirrors: []Mirror{{Registry: "docker.io", Endpoints: []string{`https://mirror.example.com/"]`}}},
+			wantHostsFiles: map[string]string{
+				"/run/state/containerd/certs.d/docker.io/hosts.toml": `[host."https://mirror.example.com/%22%5D"]
+  capabilities = ["pull", "resolve"]
+`,
+			},
+		},
+		"quote in host": {
+			mirrors: []Mirror{{Registry: "docker.io", Endpoints: []string{`https://mirror".example.com`}}},
+			wantErr: true,
+		},
+		"registry escapes hosts directory": {
+			mirrors: []Mirror{{Registry: "../../etc", Endpoints: []string{"https://mirror.example.com"}}},
+			wantErr: true,
+		},
+		"endpoint without scheme": {
+			mirrors: []Mirror{{Registry: "docker.io", Endpoints: []string{"mirror.example.com"}}},
+			wantErr: true,
+		},
+		"no endpoints": {
+			mirrors: []Mirror{{Registry: "docker.io"}},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+
+			err := Setup(fileHandler, tc.mirrors)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			for path, want := range tc.wantHostsFiles {
+				hostsFile, err := fileHandler.Read(path)
+				require.NoError(err)
+				assert.Equal(want, string(hostsFile))
+			}
+			if len(tc.wantHostsFiles) == 0 {
+				_, err := fileHandler.Stat(HostsDir)
+				assert.Error(err)
+			}
+		})
+	}
+}
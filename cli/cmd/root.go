@@ -18,6 +18,7 @@ import (
 	"os/signal"
 
 	"github.com/edgelesssys/constellation/v2/cli/internal/cmd"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
 	"github.com/spf13/cobra"
 )
 
@@ -44,10 +45,16 @@ func NewRootCmd() *cobra.Command {
 
 	rootCmd.PersistentFlags().StringP("workspace", "C", "", "path to the Constellation workspace")
 	rootCmd.PersistentFlags().Bool("debug", false, "enable debug logging")
+	rootCmd.PersistentFlags().String("log-format", string(logger.FormatText), "format of the operational logs written to stderr, one of: text, json")
 	rootCmd.PersistentFlags().Bool("force", false, "disable version compatibility checks - might result in corrupted clusters")
 	rootCmd.PersistentFlags().String("tf-log", "NONE", "Terraform log level")
+	rootCmd.PersistentFlags().String("profile", "", "name of the config profile whose overlay file is merged into the config file, e.g. 'prod' for 'constellation-conf.prod.yaml'")
+	rootCmd.PersistentFlags().String("config", "", "load the config from a ConfigMap or Secret of the cluster instead of the config file, passed as configmap://NAMESPACE/NAME/KEY or secret://NAMESPACE/NAME/KEY\n"+
+		"The cluster is accessed with the kubeconfig of the workspace, or the one set with --kubeconfig")
+	rootCmd.PersistentFlags().String("kubeconfig", "", "path to the kubeconfig used to connect to the cluster, instead of the admin kubeconfig of the workspace")
 
 	must(rootCmd.MarkPersistentFlagDirname("workspace"))
+	must(rootCmd.MarkPersistentFlagFilename("kubeconfig"))
 
 	rootCmd.AddCommand(cmd.NewConfigCmd())
 	rootCmd.AddCommand(cmd.NewCreateCmd())
@@ -57,6 +64,8 @@ func NewRootCmd() *cobra.Command {
 	rootCmd.AddCommand(cmd.NewVerifyCmd())
 	rootCmd.AddCommand(cmd.NewUpgradeCmd())
 	rootCmd.AddCommand(cmd.NewRecoverCmd())
+	rootCmd.AddCommand(cmd.NewRotateCmd())
+	rootCmd.AddCommand(cmd.NewStateCmd())
 	rootCmd.AddCommand(cmd.NewTerminateCmd())
 	rootCmd.AddCommand(cmd.NewIAMCmd())
 	rootCmd.AddCommand(cmd.NewVersionCmd())
@@ -101,6 +110,14 @@ func preRunRoot(cmd *cobra.Command, _ []string) error {
 		return fmt.Errorf("getting workspace flag: %w", err)
 	}
 
+	logFormat, err := cmd.Flags().GetString("log-format")
+	if err != nil {
+		return fmt.Errorf("getting log-format flag: %w", err)
+	}
+	if _, err := logger.ParseFormat(logFormat); err != nil {
+		return err
+	}
+
 	// Change to workspace directory if set.
 	if workspace != "" {
 		if err := os.Chdir(workspace); err != nil {
@@ -0,0 +1,425 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cloudcmd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/edgelesssys/constellation/v2/cli/internal/terraform"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+)
+
+// Kinds of cloud resources that are adopted, but never collected as orphans.
+const (
+	// ResourceKindClusterUID is the kind of the random UID Terraform generates for a cluster.
+	// It's part of the names of all cloud resources of the cluster.
+	ResourceKindClusterUID = "cluster UID"
+	// ResourceKindNodeGroupUID is the kind of the random UID Terraform generates for a node group.
+	// It's part of the names of the scale set or instance group of the node group.
+	ResourceKindNodeGroupUID = "node group UID"
+
+	ResourceKindPublicIP                      = "public IP"
+	ResourceKindNATGateway                    = "NAT gateway"
+	ResourceKindNATGatewaySubnetAssociation   = "NAT gateway subnet association"
+	ResourceKindNATGatewayPublicIPAssociation = "NAT gateway public IP association"
+	ResourceKindLoadBalancerBackendPool       = "load balancer backend pool"
+	ResourceKindLoadBalancerProbe             = "load balancer probe"
+	ResourceKindLoadBalancerRule              = "load balancer rule"
+	ResourceKindSecurityRule                  = "network security rule"
+	ResourceKindScaleSet                      = "scale set"
+
+	ResourceKindRouter                 = "router"
+	ResourceKindRouterNAT              = "router NAT"
+	ResourceKindFirewall               = "firewall"
+	ResourceKindAddress                = "address"
+	ResourceKindGlobalAddress          = "global address"
+	ResourceKindHealthCheck            = "health check"
+	ResourceKindRegionalHealthCheck    = "regional health check"
+	ResourceKindBackendService         = "backend service"
+	ResourceKindRegionalBackendService = "regional backend service"
+	ResourceKindTargetTCPProxy         = "target TCP proxy"
+	ResourceKindRegionalTargetTCPProxy = "regional target TCP proxy"
+	ResourceKindForwardingRule         = "forwarding rule"
+	ResourceKindGlobalForwardingRule   = "global forwarding rule"
+	ResourceKindInstanceTemplate       = "instance template"
+	ResourceKindInstanceGroupManager   = "instance group manager"
+)
+
+// uidPattern matches the UID Terraform generates for a cluster or node group (4 random bytes, hex encoded).
+const uidPattern = "[0-9a-f]{8}"
+
+// nameTemplateToken replaces the UID when expanding the name template to a regular expression.
+const nameTemplateToken = "\x00"
+
+// nodeGroupTag is the tag, or label, naming the node group of a scale set or instance template.
+const nodeGroupTag = "constellation-node-group"
+
+// AdoptableResource is a cloud resource of a cluster that exists at the cloud provider,
+// but isn't managed by Terraform, e.g., because a previous apply failed before the Terraform state was written.
+type AdoptableResource struct {
+	Resource
+	// Address is the address of the resource in the Terraform configuration of the cluster.
+	Address string
+}
+
+// adoptableResource describes a resource of the Terraform configuration of a cluster that can be adopted.
+type adoptableResource struct {
+	kind string
+	// name is a regular expression matching the whole name of the resource.
+	// The placeholders {base}, {name} and {uid} stand for the resource name, the name and the UID of the cluster.
+	// Sub-resources are listed as "<parent name>/<name>".
+	// The submatch "key" is the for_each key of the resource, and the submatch "group" the UID of its node group.
+	name string
+	// address is the address of the resource in the Terraform configuration.
+	// It contains a %q verb for the for_each key, if the resource has keys.
+	address string
+	// keys returns the for_each keys the Terraform configuration creates the resource with.
+	keys func(conf *config.Config) []string
+	// keyTag is the tag the for_each key is read from, if it isn't part of the name.
+	keyTag string
+	// groupUIDAddress is the address of the random UID of the node group, with a %q verb for the for_each key.
+	groupUIDAddress string
+	// created reports whether the Terraform configuration creates the resource for the config.
+	// The resource is always created if it's nil.
+	created func(conf *config.Config) bool
+}
+
+// adoptableResources lists the resources that can be adopted for each provider.
+// Resources that can't be adopted are listed in TestAdoptableResourcesCoverModules.
+var adoptableResources = map[cloudprovider.Provider][]adoptableResource{
+	cloudprovider.Azure: {
+		{kind: ResourceKindClusterUID, address: "random_id.uid"},
+		{kind: ResourceKindPublicIP, name: "{base}-lb", address: "azurerm_public_ip.loadbalancer_ip[0]", created: externalLoadBalancer},
+		{kind: ResourceKindPublicIP, name: "{base}-nat", address: "azurerm_public_ip.nat_gateway_ip"},
+		{kind: ResourceKindNATGateway, name: "{base}", address: "azurerm_nat_gateway.gateway"},
+		{kind: ResourceKindNATGatewaySubnetAssociation, name: "{base}-node/{base}", address: "azurerm_subnet_nat_gateway_association.example[0]", created: createsNetwork},
+		{kind: ResourceKindNATGatewayPublicIPAssociation, name: "{base}/{base}-nat", address: "azurerm_nat_gateway_public_ip_association.example"},
+		{kind: ResourceKindLoadBalancer, name: "{base}", address: "azurerm_lb.loadbalancer"},
+		{
+			kind: ResourceKindLoadBalancerBackendPool, name: "{base}/{base}-control-plane",
+			address: "module.loadbalancer_backend_control_plane.azurerm_lb_backend_address_pool.backend_pool",
+		},
+		{
+			kind: ResourceKindLoadBalancerProbe, name: "{base}/(?P<key>.+)", keys: azurePorts,
+			address: "module.loadbalancer_backend_control_plane.azurerm_lb_probe.health_probes[%q]",
+		},
+		{
+			kind: ResourceKindLoadBalancerRule, name: "{base}/(?P<key>.+)", keys: azurePorts,
+			address: "module.loadbalancer_backend_control_plane.azurerm_lb_rule.rules[%q]",
+		},
+		{
+			kind: ResourceKindLoadBalancerBackendPool, name: "{base}/{base}-worker",
+			address: "module.loadbalancer_backend_worker.azurerm_lb_backend_address_pool.backend_pool",
+		},
+		{kind: ResourceKindLoadBalancerBackendPool, name: "{base}/{name}-all", address: "azurerm_lb_backend_address_pool.all"},
+		{kind: ResourceKindVirtualNetwork, name: "{base}", address: "azurerm_virtual_network.network[0]", created: createsNetwork},
+		{kind: ResourceKindSubnet, name: "{base}-lb", address: "azurerm_subnet.loadbalancer_subnet[0]", created: internalLoadBalancer},
+		{kind: ResourceKindSubnet, name: "{base}-node", address: "azurerm_subnet.node_subnet[0]", created: createsNetwork},
+		{kind: ResourceKindNetworkSecurityGroup, name: "{base}", address: "azurerm_network_security_group.security_group"},
+		{kind: ResourceKindSecurityRule, name: "{base}/(?P<key>.+)", keys: azurePorts, address: "azurerm_network_security_rule.nsg_rule[%q]"},
+		{
+			kind: ResourceKindScaleSet, name: "{base}-(control-plane|worker)-(?P<group>" + uidPattern + ")", keys: nodeGroups, keyTag: nodeGroupTag,
+			address:         "module.scale_set_group[%q].azurerm_linux_virtual_machine_scale_set.scale_set",
+			groupUIDAddress: "module.scale_set_group[%q].random_id.uid",
+		},
+	},
+	cloudprovider.GCP: {
+		{kind: ResourceKindClusterUID, address: "random_id.uid"},
+		{kind: ResourceKindVirtualNetwork, name: "{base}", address: "google_compute_network.vpc_network[0]", created: createsNetwork},
+		{kind: ResourceKindSubnet, name: "{base}", address: "google_compute_subnetwork.vpc_subnetwork[0]", created: createsNetwork},
+		{kind: ResourceKindSubnet, name: "{base}-proxy", address: "google_compute_subnetwork.proxy_subnet[0]", created: internalLoadBalancer},
+		{kind: ResourceKindSubnet, name: "{base}-ilb", address: "google_compute_subnetwork.ilb_subnet[0]", created: internalLoadBalancer},
+		{kind: ResourceKindRouter, name: "{base}", address: "google_compute_router.vpc_router[0]", created: createsNetwork},
+		{kind: ResourceKindRouterNAT, name: "{base}/{base}", address: "google_compute_router_nat.vpc_router_nat[0]", created: createsNetwork},
+		{kind: ResourceKindFirewall, name: "{base}", address: "google_compute_firewall.firewall_external"},
+		{kind: ResourceKindFirewall, name: "{base}-nodes", address: "google_compute_firewall.firewall_internal_nodes"},
+		{kind: ResourceKindFirewall, name: "{base}-pods", address: "google_compute_firewall.firewall_internal_pods"},
+		{
+			kind: ResourceKindInstanceTemplate, name: "{base}-(control-plane|worker)-(?P<group>" + uidPattern + ")", keys: nodeGroups, keyTag: nodeGroupTag,
+			address:         "module.instance_group[%q].google_compute_instance_template.template",
+			groupUIDAddress: "module.instance_group[%q].random_id.uid",
+		},
+		{
+			kind: ResourceKindInstanceGroupManager, name: "{base}-(control-plane|worker)-(?P<group>" + uidPattern + ")", keys: nodeGroups, keyTag: nodeGroupTag,
+			address:         "module.instance_group[%q].google_compute_instance_group_manager.instance_group_manager",
+			groupUIDAddress: "module.instance_group[%q].random_id.uid",
+		},
+		{kind: ResourceKindAddress, name: "{base}", address: "google_compute_address.loadbalancer_ip_internal[0]", created: internalLoadBalancer},
+		{kind: ResourceKindGlobalAddress, name: "{base}", address: "google_compute_global_address.loadbalancer_ip[0]", created: externalLoadBalancer},
+		{
+			kind: ResourceKindHealthCheck, name: "{base}-(?P<key>.+)", keys: gcpPorts, created: externalLoadBalancer,
+			address: "module.loadbalancer_public[%q].google_compute_health_check.health",
+		},
+		{
+			kind: ResourceKindBackendService, name: "{base}-(?P<key>.+)", keys: gcpPorts, created: externalLoadBalancer,
+			address: "module.loadbalancer_public[%q].google_compute_backend_service.backend",
+		},
+		{
+			kind: ResourceKindTargetTCPProxy, name: "{base}-(?P<key>.+)", keys: gcpPorts, created: externalLoadBalancer,
+			address: "module.loadbalancer_public[%q].google_compute_target_tcp_proxy.proxy",
+		},
+		{
+			kind: ResourceKindGlobalForwardingRule, name: "{base}-(?P<key>.+)", keys: gcpPorts, created: externalLoadBalancer,
+			address: "module.loadbalancer_public[%q].google_compute_global_forwarding_rule.forwarding",
+		},
+		{
+			kind: ResourceKindRegionalHealthCheck, name: "{base}-(?P<key>.+)", keys: gcpPorts, created: internalLoadBalancer,
+			address: "module.loadbalancer_internal[%q].google_compute_region_health_check.health",
+		},
+		{
+			kind: ResourceKindRegionalBackendService, name: "{base}-(?P<key>.+)", keys: gcpPorts, created: internalLoadBalancer,
+			address: "module.loadbalancer_internal[%q].google_compute_region_backend_service.backend",
+		},
+		{
+			kind: ResourceKindRegionalTargetTCPProxy, name: "{base}-(?P<key>.+)", keys: gcpPorts, created: internalLoadBalancer,
+			address: "module.loadbalancer_internal[%q].google_compute_region_target_tcp_proxy.proxy",
+		},
+		{
+			kind: ResourceKindForwardingRule, name: "{base}-(?P<key>.+)", keys: gcpPorts, created: internalLoadBalancer,
+			address: "module.loadbalancer_internal[%q].google_compute_forwarding_rule.forwarding",
+		},
+	},
+}
+
+func internalLoadBalancer(conf *config.Config) bool { return conf.InternalLoadBalancer }
+
+func externalLoadBalancer(conf *config.Config) bool { return !conf.InternalLoadBalancer }
+
+// createsNetwork reports whether Terraform creates the network of the cluster, instead of attaching it to an existing one.
+func createsNetwork(conf *config.Config) bool {
+	switch {
+	case conf.Provider.Azure != nil:
+		return conf.Provider.Azure.SubnetID == ""
+	case conf.Provider.GCP != nil:
+		return conf.Provider.GCP.SubnetworkID == ""
+	default:
+		return true
+	}
+}
+
+// azurePorts returns the names of the ports the load balancer of an Azure cluster forwards.
+// They must match local.ports of the Terraform configuration.
+func azurePorts(conf *config.Config) []string {
+	ports := []string{"kubernetes", "bootstrapper", "verify", "recovery", "join"}
+	if conf.IsDebugCluster() {
+		ports = append(ports, "debugd")
+	}
+	return ports
+}
+
+// gcpPorts returns the names of the ports the load balancer of a GCP cluster forwards.
+// They must match local.control_plane_named_ports of the Terraform configuration.
+func gcpPorts(conf *config.Config) []string {
+	ports := []string{"kubernetes", "bootstrapper", "verify", "konnectivity", "recovery", "join"}
+	if conf.IsDebugCluster() {
+		ports = append(ports, "debugd")
+	}
+	return ports
+}
+
+func nodeGroups(conf *config.Config) []string {
+	names := make([]string, 0, len(conf.NodeGroups))
+	for name := range conf.NodeGroups {
+		names = append(names, name)
+	}
+	return names
+}
+
+// pattern returns a regular expression matching the name of the resource.
+// If uid is empty, the expression matches the name of the resource of any cluster,
+// and its first submatch "uid" is the UID of the cluster.
+func (r adoptableResource) pattern(conf *config.Config, uid string) (*regexp.Regexp, error) {
+	baseName, err := conf.ResourceName(nameTemplateToken)
+	if err != nil {
+		return nil, fmt.Errorf("expanding name template: %w", err)
+	}
+	expanded := strings.NewReplacer(
+		"{base}", regexp.QuoteMeta(baseName),
+		"{name}", regexp.QuoteMeta(conf.Name),
+		"{uid}", nameTemplateToken,
+	).Replace(r.name)
+	parts := strings.Split(expanded, nameTemplateToken)
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("name %q of %s doesn't contain the cluster UID", r.name, r.address)
+	}
+	uidExpr := uidPattern
+	if uid != "" {
+		uidExpr = regexp.QuoteMeta(uid)
+	}
+	return regexp.Compile("^" + parts[0] + "(?P<uid>" + uidExpr + ")" + strings.Join(parts[1:], uidExpr) + "$")
+}
+
+// match checks if the listed resource is the resource of the configured cluster.
+// It returns the UID of the cluster, and the adoptable resources.
+func (r adoptableResource) match(conf *config.Config, resource listedResource) (string, []AdoptableResource, error) {
+	if r.kind != resource.Kind || r.name == "" || (r.created != nil && !r.created(conf)) {
+		return "", nil, nil
+	}
+	anyCluster, err := r.pattern(conf, "")
+	if err != nil {
+		return "", nil, err
+	}
+	match := anyCluster.FindStringSubmatch(resource.name)
+	if match == nil {
+		return "", nil, nil
+	}
+	// The name may contain the UID more than once, so the name is matched again with the UID found.
+	uid := match[anyCluster.SubexpIndex("uid")]
+	cluster, err := r.pattern(conf, uid)
+	if err != nil {
+		return "", nil, err
+	}
+	match = cluster.FindStringSubmatch(resource.name)
+	if match == nil {
+		return "", nil, nil
+	}
+
+	if r.keys == nil {
+		return uid, []AdoptableResource{{Resource: resource.Resource, Address: r.address}}, nil
+	}
+	var key string
+	if r.keyTag != "" {
+		key = resource.tags[r.keyTag]
+	} else {
+		key = match[cluster.SubexpIndex("key")]
+	}
+	if !slices.Contains(r.keys(conf), key) {
+		return "", nil, nil
+	}
+	adoptable := []AdoptableResource{{Resource: resource.Resource, Address: fmt.Sprintf(r.address, key)}}
+	if r.groupUIDAddress != "" {
+		adoptable = append(adoptable, AdoptableResource{
+			Resource: Resource{Kind: ResourceKindNodeGroupUID, ID: match[cluster.SubexpIndex("group")]},
+			Address:  fmt.Sprintf(r.groupUIDAddress, key),
+		})
+	}
+	return uid, adoptable, nil
+}
+
+// ResourceAdopter finds the cloud resources a previous apply created for a cluster without recording them in a Terraform state,
+// so they can be imported into the Terraform state instead of being created again.
+type ResourceAdopter struct {
+	newResourceLister func(ctx context.Context, conf *config.Config) (cloudResourceLister, func(), error)
+}
+
+// NewResourceAdopter creates a new ResourceAdopter.
+func NewResourceAdopter() *ResourceAdopter {
+	return &ResourceAdopter{
+		newResourceLister: newCloudResourceLister,
+	}
+}
+
+// Detect returns the resources at the cloud provider whose names match the resource names of the configured cluster.
+// The UID of the cluster is derived from the names and adopted as well, so Terraform names the remaining resources like the adopted ones.
+// An error is returned if the names match the resources of more than one cluster.
+func (a *ResourceAdopter) Detect(ctx context.Context, conf *config.Config) ([]AdoptableResource, error) {
+	provider := conf.GetProvider()
+	resources, ok := adoptableResources[provider]
+	if !ok {
+		return nil, fmt.Errorf("adopting resources isn't supported for %s", provider)
+	}
+
+	lister, closeLister, err := a.newResourceLister(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+	defer closeLister()
+	listed, err := lister.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing cloud resources: %w", err)
+	}
+
+	configured := configuredResources(conf)
+	uids := make(map[string]struct{})
+	var adoptable []AdoptableResource
+	for _, resource := range listed {
+		if slices.Contains(configured, resource.Resource) {
+			continue
+		}
+		for _, candidate := range resources {
+			uid, matched, err := candidate.match(conf, resource)
+			if err != nil {
+				return nil, err
+			}
+			if len(matched) == 0 {
+				continue
+			}
+			uids[uid] = struct{}{}
+			for _, m := range matched {
+				// Node groups have a scale set, or an instance template and group, sharing the UID of the node group.
+				if !slices.Contains(adoptable, m) {
+					adoptable = append(adoptable, m)
+				}
+			}
+			break
+		}
+	}
+
+	if len(uids) == 0 {
+		return nil, nil
+	}
+	if len(uids) > 1 {
+		found := make([]string, 0, len(uids))
+		for uid := range uids {
+			found = append(found, uid)
+		}
+		sort.Strings(found)
+		return nil, fmt.Errorf("found resources of more than one cluster named %q, with the UIDs %s: delete the resources of all but one of them",
+			conf.Name, strings.Join(found, ", "))
+	}
+
+	for uid := range uids {
+		adoptable = append(adoptable, AdoptableResource{
+			Resource: Resource{Kind: ResourceKindClusterUID, ID: uid},
+			Address:  "random_id.uid",
+		})
+	}
+	sort.Slice(adoptable, func(i, j int) bool { return adoptable[i].Address < adoptable[j].Address })
+	return adoptable, nil
+}
+
+// adoptionMigration returns the manual state migration importing the resource into the Terraform state.
+func adoptionMigration(resource AdoptableResource) terraform.StateMigration {
+	return terraform.StateMigration{
+		DisplayName: fmt.Sprintf("adopt %s", resource),
+		Hook: func(ctx context.Context, tfClient terraform.TFMigrator) error {
+			id := resource.ID
+			if resource.Kind == ResourceKindClusterUID || resource.Kind == ResourceKindNodeGroupUID {
+				// Random IDs are imported by their base64url encoded bytes.
+				uid, err := hex.DecodeString(resource.ID)
+				if err != nil {
+					return fmt.Errorf("decoding cluster UID: %w", err)
+				}
+				id = base64.RawURLEncoding.EncodeToString(uid)
+			}
+			return tfClient.Import(ctx, resource.Address, id)
+		},
+	}
+}
+
+// listedResource is a cloud resource listed at the cloud provider.
+type listedResource struct {
+	Resource
+	// name is the name of the resource at the cloud provider.
+	// Sub-resources, like the rules of a load balancer, are named "<parent name>/<name>".
+	name string
+	// tags are the tags, or labels, of the resource.
+	tags map[string]string
+}
+
+// cloudResourceLister lists the cloud resources a cluster may have been created with.
+type cloudResourceLister interface {
+	List(ctx context.Context) ([]listedResource, error)
+}
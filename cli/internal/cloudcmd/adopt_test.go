@@ -0,0 +1,340 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cloudcmd
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/terraform"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/terraform-exec/tfexec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceAdopterDetect(t *testing.T) {
+	const (
+		rg     = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network"
+		vnet   = rg + "/virtualNetworks/constell-0a1b2c3d"
+		subnet = vnet + "/subnets/constell-0a1b2c3d-node"
+	)
+	azureConfig := func() *config.Config {
+		conf := config.Default()
+		conf.RemoveProviderExcept(cloudprovider.Azure)
+		conf.Name = "constell"
+		return conf
+	}
+	gcpConfig := func() *config.Config {
+		conf := config.Default()
+		conf.RemoveProviderExcept(cloudprovider.GCP)
+		conf.Name = "constell"
+		return conf
+	}
+
+	testCases := map[string]struct {
+		conf          *config.Config
+		listed        []listedResource
+		listErr       error
+		wantAdoptable []AdoptableResource
+		wantErr       bool
+	}{
+		"resources of the cluster are adoptable": {
+			conf: azureConfig(),
+			listed: []listedResource{
+				{Resource: Resource{Kind: ResourceKindNetworkSecurityGroup, ID: rg + "/networkSecurityGroups/constell-0a1b2c3d"}, name: "constell-0a1b2c3d"},
+				{Resource: Resource{Kind: ResourceKindVirtualNetwork, ID: vnet}, name: "constell-0a1b2c3d"},
+				{Resource: Resource{Kind: ResourceKindSubnet, ID: subnet}, name: "constell-0a1b2c3d-node"},
+				// Resources of other clusters, and resources the CLI doesn't create, are ignored.
+				{Resource: Resource{Kind: ResourceKindLoadBalancer, ID: rg + "/loadBalancers/other-0a1b2c3d"}, name: "other-0a1b2c3d"},
+				{Resource: Resource{Kind: ResourceKindSubnet, ID: vnet + "/subnets/constell-0a1b2c3d-custom"}, name: "constell-0a1b2c3d-custom"},
+				{Resource: Resource{Kind: ResourceKindLoadBalancer, ID: rg + "/loadBalancers/constell-lb"}, name: "constell-lb"},
+			},
+			wantAdoptable: []AdoptableResource{
+				{Resource: Resource{Kind: ResourceKindNetworkSecurityGroup, ID: rg + "/networkSecurityGroups/constell-0a1b2c3d"}, Address: "azurerm_network_security_group.security_group"},
+				{Resource: Resource{Kind: ResourceKindSubnet, ID: subnet}, Address: "azurerm_subnet.node_subnet[0]"},
+				{Resource: Resource{Kind: ResourceKindVirtualNetwork, ID: vnet}, Address: "azurerm_virtual_network.network[0]"},
+				{Resource: Resource{Kind: ResourceKindClusterUID, ID: "0a1b2c3d"}, Address: "random_id.uid"},
+			},
+		},
+		"name template": {
+			conf: func() *config.Config {
+				conf := gcpConfig()
+				conf.NameTemplate = "prod-{uid}-{name}"
+				conf.InternalLoadBalancer = true
+				return conf
+			}(),
+			listed: []listedResource{
+				{Resource: Resource{Kind: ResourceKindVirtualNetwork, ID: "projects/p/global/networks/prod-0a1b2c3d-constell"}, name: "prod-0a1b2c3d-constell"},
+				{Resource: Resource{Kind: ResourceKindSubnet, ID: "projects/p/regions/r/subnetworks/prod-0a1b2c3d-constell-ilb"}, name: "prod-0a1b2c3d-constell-ilb"},
+				{Resource: Resource{Kind: ResourceKindVirtualNetwork, ID: "projects/p/global/networks/constell-0a1b2c3d"}, name: "constell-0a1b2c3d"},
+			},
+			wantAdoptable: []AdoptableResource{
+				{Resource: Resource{Kind: ResourceKindVirtualNetwork, ID: "projects/p/global/networks/prod-0a1b2c3d-constell"}, Address: "google_compute_network.vpc_network[0]"},
+				{Resource: Resource{Kind: ResourceKindSubnet, ID: "projects/p/regions/r/subnetworks/prod-0a1b2c3d-constell-ilb"}, Address: "google_compute_subnetwork.ilb_subnet[0]"},
+				{Resource: Resource{Kind: ResourceKindClusterUID, ID: "0a1b2c3d"}, Address: "random_id.uid"},
+			},
+		},
+		"sub-resources and node groups": {
+			conf: azureConfig(),
+			listed: []listedResource{
+				{Resource: Resource{Kind: ResourceKindLoadBalancer, ID: rg + "/loadBalancers/constell-0a1b2c3d"}, name: "constell-0a1b2c3d"},
+				{Resource: Resource{Kind: ResourceKindLoadBalancerProbe, ID: rg + "/loadBalancers/constell-0a1b2c3d/probes/kubernetes"}, name: "constell-0a1b2c3d/kubernetes"},
+				{
+					Resource: Resource{Kind: ResourceKindScaleSet, ID: rg + "/virtualMachineScaleSets/constell-0a1b2c3d-worker-ffffffff"},
+					name:     "constell-0a1b2c3d-worker-ffffffff",
+					tags:     map[string]string{nodeGroupTag: constants.DefaultWorkerGroupName},
+				},
+				// Probes of ports the load balancer doesn't forward, and scale sets of node groups that aren't configured, are ignored.
+				{Resource: Resource{Kind: ResourceKindLoadBalancerProbe, ID: rg + "/loadBalancers/constell-0a1b2c3d/probes/konnectivity"}, name: "constell-0a1b2c3d/konnectivity"},
+				{
+					Resource: Resource{Kind: ResourceKindScaleSet, ID: rg + "/virtualMachineScaleSets/constell-0a1b2c3d-worker-eeeeeeee"},
+					name:     "constell-0a1b2c3d-worker-eeeeeeee",
+					tags:     map[string]string{nodeGroupTag: "removed"},
+				},
+			},
+			wantAdoptable: []AdoptableResource{
+				{Resource: Resource{Kind: ResourceKindLoadBalancer, ID: rg + "/loadBalancers/constell-0a1b2c3d"}, Address: "azurerm_lb.loadbalancer"},
+				{
+					Resource: Resource{Kind: ResourceKindLoadBalancerProbe, ID: rg + "/loadBalancers/constell-0a1b2c3d/probes/kubernetes"},
+					Address:  `module.loadbalancer_backend_control_plane.azurerm_lb_probe.health_probes["kubernetes"]`,
+				},
+				{
+					Resource: Resource{Kind: ResourceKindScaleSet, ID: rg + "/virtualMachineScaleSets/constell-0a1b2c3d-worker-ffffffff"},
+					Address:  `module.scale_set_group["worker_default"].azurerm_linux_virtual_machine_scale_set.scale_set`,
+				},
+				{Resource: Resource{Kind: ResourceKindNodeGroupUID, ID: "ffffffff"}, Address: `module.scale_set_group["worker_default"].random_id.uid`},
+				{Resource: Resource{Kind: ResourceKindClusterUID, ID: "0a1b2c3d"}, Address: "random_id.uid"},
+			},
+		},
+		"load balancer resources depend on the load balancer type": {
+			conf: gcpConfig(),
+			listed: []listedResource{
+				{Resource: Resource{Kind: ResourceKindGlobalAddress, ID: "projects/p/global/addresses/constell-0a1b2c3d"}, name: "constell-0a1b2c3d"},
+				{Resource: Resource{Kind: ResourceKindHealthCheck, ID: "projects/p/global/healthChecks/constell-0a1b2c3d-join"}, name: "constell-0a1b2c3d-join"},
+				// Only created with an internal load balancer.
+				{Resource: Resource{Kind: ResourceKindSubnet, ID: "projects/p/regions/r/subnetworks/constell-0a1b2c3d-proxy"}, name: "constell-0a1b2c3d-proxy"},
+				{Resource: Resource{Kind: ResourceKindRegionalHealthCheck, ID: "projects/p/regions/r/healthChecks/constell-0a1b2c3d-join"}, name: "constell-0a1b2c3d-join"},
+			},
+			wantAdoptable: []AdoptableResource{
+				{Resource: Resource{Kind: ResourceKindGlobalAddress, ID: "projects/p/global/addresses/constell-0a1b2c3d"}, Address: "google_compute_global_address.loadbalancer_ip[0]"},
+				{Resource: Resource{Kind: ResourceKindHealthCheck, ID: "projects/p/global/healthChecks/constell-0a1b2c3d-join"}, Address: `module.loadbalancer_public["join"].google_compute_health_check.health`},
+				{Resource: Resource{Kind: ResourceKindClusterUID, ID: "0a1b2c3d"}, Address: "random_id.uid"},
+			},
+		},
+		"configured network isn't adopted": {
+			conf: func() *config.Config {
+				conf := azureConfig()
+				conf.Provider.Azure.VirtualNetworkID = vnet
+				conf.Provider.Azure.SubnetID = subnet
+				return conf
+			}(),
+			listed: []listedResource{
+				{Resource: Resource{Kind: ResourceKindVirtualNetwork, ID: vnet}, name: "constell-0a1b2c3d"},
+				{Resource: Resource{Kind: ResourceKindSubnet, ID: subnet}, name: "constell-0a1b2c3d-node"},
+			},
+		},
+		"no resources of the cluster": {
+			conf: azureConfig(),
+			listed: []listedResource{
+				{Resource: Resource{Kind: ResourceKindLoadBalancer, ID: rg + "/loadBalancers/other-0a1b2c3d"}, name: "other-0a1b2c3d"},
+			},
+		},
+		"resources of several clusters": {
+			conf: azureConfig(),
+			listed: []listedResource{
+				{Resource: Resource{Kind: ResourceKindLoadBalancer, ID: rg + "/loadBalancers/constell-0a1b2c3d"}, name: "constell-0a1b2c3d"},
+				{Resource: Resource{Kind: ResourceKindLoadBalancer, ID: rg + "/loadBalancers/constell-ffffffff"}, name: "constell-ffffffff"},
+			},
+			wantErr: true,
+		},
+		"listing fails": {
+			conf:    azureConfig(),
+			listErr: errors.New("failed"),
+			wantErr: true,
+		},
+		"unsupported provider": {
+			conf: func() *config.Config {
+				conf := config.Default()
+				conf.RemoveProviderExcept(cloudprovider.AWS)
+				return conf
+			}(),
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			adopter := &ResourceAdopter{
+				newResourceLister: func(context.Context, *config.Config) (cloudResourceLister, func(), error) {
+					return &stubResourceLister{resources: tc.listed, err: tc.listErr}, func() {}, nil
+				},
+			}
+
+			adoptable, err := adopter.Detect(context.Background(), tc.conf)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantAdoptable, adoptable)
+		})
+	}
+}
+
+func TestApplierAdopt(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	conf := config.Default()
+	conf.RemoveProviderExcept(cloudprovider.GCP)
+	conf.Name = "constell"
+	adopter := &ResourceAdopter{
+		newResourceLister: func(context.Context, *config.Config) (cloudResourceLister, func(), error) {
+			return &stubResourceLister{resources: []listedResource{
+				{Resource: Resource{Kind: ResourceKindVirtualNetwork, ID: "projects/p/global/networks/constell-0a1b2c3d"}, name: "constell-0a1b2c3d"},
+				{Resource: Resource{Kind: ResourceKindSubnet, ID: "projects/p/regions/r/subnetworks/constell-0a1b2c3d"}, name: "constell-0a1b2c3d"},
+			}}, func() {}, nil
+		},
+	}
+	adoptable, err := adopter.Detect(context.Background(), conf)
+	require.NoError(err)
+
+	tfClient := &stubTerraformClient{}
+	applier := &Applier{terraformClient: tfClient}
+	applier.Adopt(adoptable)
+
+	// The resources are imported when the migrations run on planning, instead of being created by Terraform.
+	migrator := &stubTFMigrator{}
+	for _, migration := range tfClient.stateMigrations {
+		require.NoError(migration.Hook(context.Background(), migrator))
+	}
+	assert.Equal(map[string]string{
+		"google_compute_network.vpc_network[0]":       "projects/p/global/networks/constell-0a1b2c3d",
+		"google_compute_subnetwork.vpc_subnetwork[0]": "projects/p/regions/r/subnetworks/constell-0a1b2c3d",
+		"random_id.uid": "ChssPQ",
+	}, migrator.imported)
+}
+
+// TestAdoptableResourcesCoverModules checks that every resource of the Terraform configuration of a cluster can be adopted,
+// so a failed apply never leaves resources behind that a retry tries to create again.
+func TestAdoptableResourcesCoverModules(t *testing.T) {
+	// notAdoptable are the resources that can't be adopted, and why.
+	notAdoptable := map[cloudprovider.Provider]map[string]string{
+		cloudprovider.Azure: {
+			"terraform_data.replacement":                                        "not a cloud resource",
+			"random_password.init_secret":                                       "the secret can't be read back, a new one is generated",
+			"module.scale_set_group.random_password.password":                   "the password can't be read back, a new one is generated",
+			"module.loadbalancer_backend_worker.azurerm_lb_probe.health_probes": "the worker backend forwards no ports",
+			"module.loadbalancer_backend_worker.azurerm_lb_rule.rules":          "the worker backend forwards no ports",
+			"azurerm_attestation_provider.attestation_provider":                 "the CLI has no client listing attestation providers",
+			"module.jump_host.azurerm_linux_virtual_machine.jump_host":          "jump hosts of debug clusters aren't adopted",
+			"module.jump_host.azurerm_network_interface.jump_host":              "jump hosts of debug clusters aren't adopted",
+			"module.jump_host.azurerm_public_ip.jump_host":                      "jump hosts of debug clusters aren't adopted",
+			"module.jump_host.tls_private_key.ssh_key":                          "jump hosts of debug clusters aren't adopted",
+		},
+		cloudprovider.GCP: {
+			"terraform_data.replacement":                           "not a cloud resource",
+			"random_password.init_secret":                          "the secret can't be read back, a new one is generated",
+			"module.jump_host.google_compute_instance.vm_instance": "jump hosts of debug clusters aren't adopted",
+		},
+	}
+	// keys matches the for_each keys and count indexes of an address.
+	keys := regexp.MustCompile(`\[[^]]*\]`)
+
+	for provider, resources := range adoptableResources {
+		t.Run(provider.String(), func(t *testing.T) {
+			assert := assert.New(t)
+
+			moduleResources := terraformResources(t, path.Join("infrastructure", strings.ToLower(provider.String())), "")
+			adoptable := make(map[string]bool)
+			for _, resource := range resources {
+				adoptable[keys.ReplaceAllString(resource.address, "")] = true
+				if resource.groupUIDAddress != "" {
+					adoptable[keys.ReplaceAllString(resource.groupUIDAddress, "")] = true
+				}
+			}
+
+			for _, address := range moduleResources {
+				_, excluded := notAdoptable[provider][address]
+				assert.True(adoptable[address] != excluded, "resource %s must either be adoptable or listed as not adoptable", address)
+			}
+			for address := range adoptable {
+				assert.Contains(moduleResources, address, "adoptable resource isn't part of the Terraform configuration")
+			}
+			for address := range notAdoptable[provider] {
+				assert.Contains(moduleResources, address, "resource listed as not adoptable isn't part of the Terraform configuration")
+			}
+		})
+	}
+}
+
+// terraformResources returns the addresses of the resources in the embedded Terraform module, without keys or indexes.
+func terraformResources(t *testing.T, dir, prefix string) []string {
+	t.Helper()
+	require := require.New(t)
+
+	files, err := fs.Glob(terraform.Assets, path.Join(dir, "*.tf"))
+	require.NoError(err)
+	require.NotEmpty(files)
+
+	var addresses []string
+	for _, file := range files {
+		content, err := fs.ReadFile(terraform.Assets, file)
+		require.NoError(err)
+		parsed, diags := hclsyntax.ParseConfig(content, file, hcl.Pos{Line: 1, Column: 1})
+		require.False(diags.HasErrors(), diags.Error())
+
+		for _, block := range parsed.Body.(*hclsyntax.Body).Blocks {
+			switch block.Type {
+			case "resource":
+				addresses = append(addresses, prefix+block.Labels[0]+"."+block.Labels[1])
+			case "module":
+				source, diags := block.Body.Attributes["source"].Expr.Value(nil)
+				require.False(diags.HasErrors(), diags.Error())
+				addresses = append(addresses, terraformResources(t, path.Join(dir, source.AsString()), prefix+"module."+block.Labels[0]+".")...)
+			}
+		}
+	}
+	return addresses
+}
+
+type stubResourceLister struct {
+	resources []listedResource
+	err       error
+}
+
+func (l *stubResourceLister) List(context.Context) ([]listedResource, error) {
+	return l.resources, l.err
+}
+
+type stubTFMigrator struct {
+	imported map[string]string
+}
+
+func (m *stubTFMigrator) StateMv(context.Context, string, string, ...tfexec.StateMvCmdOption) error {
+	return errors.New("unexpected state move")
+}
+
+func (m *stubTFMigrator) Import(_ context.Context, address, id string, _ ...tfexec.ImportOption) error {
+	if m.imported == nil {
+		m.imported = make(map[string]string)
+	}
+	m.imported[address] = id
+	return nil
+}
@@ -0,0 +1,475 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cloudcmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	compute "cloud.google.com/go/compute/apiv1"
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v6"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+func newCloudResourceLister(ctx context.Context, conf *config.Config) (cloudResourceLister, func(), error) {
+	switch {
+	case conf.Provider.Azure != nil:
+		lister, err := newAzureResourceLister(conf.Provider.Azure.SubscriptionID, conf.Provider.Azure.ResourceGroup)
+		if err != nil {
+			return nil, nil, err
+		}
+		return lister, func() {}, nil
+	case conf.Provider.GCP != nil:
+		zones := make(map[string]struct{})
+		for _, nodeGroup := range conf.NodeGroups {
+			zones[nodeGroup.Zone] = struct{}{}
+		}
+		return newGCPResourceLister(ctx, conf.Provider.GCP.Project, conf.Provider.GCP.Region, zones)
+	default:
+		return nil, nil, fmt.Errorf("listing cloud resources isn't supported for %s", conf.GetProvider())
+	}
+}
+
+// azureResourceLister lists the resources the Terraform configuration of a cluster creates on Azure.
+type azureResourceLister struct {
+	resourceGroup   string
+	securityGroups  *armnetwork.SecurityGroupsClient
+	loadBalancers   *armnetwork.LoadBalancersClient
+	virtualNetworks *armnetwork.VirtualNetworksClient
+	subnets         *armnetwork.SubnetsClient
+	publicIPs       *armnetwork.PublicIPAddressesClient
+	natGateways     *armnetwork.NatGatewaysClient
+	scaleSets       *armcompute.VirtualMachineScaleSetsClient
+}
+
+func newAzureResourceLister(subscriptionID, resourceGroup string) (*azureResourceLister, error) {
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("loading Azure credentials: %w", err)
+	}
+	networkFactory, err := armnetwork.NewClientFactory(subscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating Azure network client: %w", err)
+	}
+	scaleSets, err := armcompute.NewVirtualMachineScaleSetsClient(subscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating Azure scale set client: %w", err)
+	}
+	return &azureResourceLister{
+		resourceGroup:   resourceGroup,
+		securityGroups:  networkFactory.NewSecurityGroupsClient(),
+		loadBalancers:   networkFactory.NewLoadBalancersClient(),
+		virtualNetworks: networkFactory.NewVirtualNetworksClient(),
+		subnets:         networkFactory.NewSubnetsClient(),
+		publicIPs:       networkFactory.NewPublicIPAddressesClient(),
+		natGateways:     networkFactory.NewNatGatewaysClient(),
+		scaleSets:       scaleSets,
+	}, nil
+}
+
+// List lists the resources in the resource group, and their sub-resources.
+func (l *azureResourceLister) List(ctx context.Context) ([]listedResource, error) {
+	var resources []listedResource
+
+	securityGroups, err := collectPages(ctx, l.securityGroups.NewListPager(l.resourceGroup, nil),
+		func(page armnetwork.SecurityGroupsClientListResponse) []*armnetwork.SecurityGroup { return page.Value })
+	if err != nil {
+		return nil, fmt.Errorf("listing network security groups: %w", err)
+	}
+	for _, securityGroup := range securityGroups {
+		group := newAzureListedResource(ResourceKindNetworkSecurityGroup, securityGroup.ID, securityGroup.Name, securityGroup.Tags)
+		resources = append(resources, group)
+		if securityGroup.Properties == nil {
+			continue
+		}
+		for _, rule := range securityGroup.Properties.SecurityRules {
+			resources = append(resources, group.child(ResourceKindSecurityRule, rule.ID, rule.Name))
+		}
+	}
+
+	loadBalancers, err := collectPages(ctx, l.loadBalancers.NewListPager(l.resourceGroup, nil),
+		func(page armnetwork.LoadBalancersClientListResponse) []*armnetwork.LoadBalancer { return page.Value })
+	if err != nil {
+		return nil, fmt.Errorf("listing load balancers: %w", err)
+	}
+	for _, loadBalancer := range loadBalancers {
+		lb := newAzureListedResource(ResourceKindLoadBalancer, loadBalancer.ID, loadBalancer.Name, loadBalancer.Tags)
+		resources = append(resources, lb)
+		if loadBalancer.Properties == nil {
+			continue
+		}
+		for _, pool := range loadBalancer.Properties.BackendAddressPools {
+			resources = append(resources, lb.child(ResourceKindLoadBalancerBackendPool, pool.ID, pool.Name))
+		}
+		for _, probe := range loadBalancer.Properties.Probes {
+			resources = append(resources, lb.child(ResourceKindLoadBalancerProbe, probe.ID, probe.Name))
+		}
+		for _, rule := range loadBalancer.Properties.LoadBalancingRules {
+			resources = append(resources, lb.child(ResourceKindLoadBalancerRule, rule.ID, rule.Name))
+		}
+	}
+
+	virtualNetworks, err := collectPages(ctx, l.virtualNetworks.NewListPager(l.resourceGroup, nil),
+		func(page armnetwork.VirtualNetworksClientListResponse) []*armnetwork.VirtualNetwork {
+			return page.Value
+		})
+	if err != nil {
+		return nil, fmt.Errorf("listing virtual networks: %w", err)
+	}
+	for _, virtualNetwork := range virtualNetworks {
+		resources = append(resources, newAzureListedResource(ResourceKindVirtualNetwork, virtualNetwork.ID, virtualNetwork.Name, virtualNetwork.Tags))
+		if virtualNetwork.Name == nil {
+			continue
+		}
+		subnets, err := collectPages(ctx, l.subnets.NewListPager(l.resourceGroup, *virtualNetwork.Name, nil),
+			func(page armnetwork.SubnetsClientListResponse) []*armnetwork.Subnet { return page.Value })
+		if err != nil {
+			return nil, fmt.Errorf("listing subnets of virtual network %s: %w", *virtualNetwork.Name, err)
+		}
+		for _, subnet := range subnets {
+			listed := newAzureListedResource(ResourceKindSubnet, subnet.ID, subnet.Name, nil)
+			resources = append(resources, listed)
+			if subnet.Properties == nil || subnet.Properties.NatGateway == nil {
+				continue
+			}
+			// The association is imported by the ID of the subnet.
+			resources = append(resources, listed.child(ResourceKindNATGatewaySubnetAssociation, subnet.ID, azureResourceName(subnet.Properties.NatGateway.ID)))
+		}
+	}
+
+	publicIPs, err := collectPages(ctx, l.publicIPs.NewListPager(l.resourceGroup, nil),
+		func(page armnetwork.PublicIPAddressesClientListResponse) []*armnetwork.PublicIPAddress {
+			return page.Value
+		})
+	if err != nil {
+		return nil, fmt.Errorf("listing public IPs: %w", err)
+	}
+	for _, publicIP := range publicIPs {
+		resources = append(resources, newAzureListedResource(ResourceKindPublicIP, publicIP.ID, publicIP.Name, publicIP.Tags))
+	}
+
+	natGateways, err := collectPages(ctx, l.natGateways.NewListPager(l.resourceGroup, nil),
+		func(page armnetwork.NatGatewaysClientListResponse) []*armnetwork.NatGateway { return page.Value })
+	if err != nil {
+		return nil, fmt.Errorf("listing NAT gateways: %w", err)
+	}
+	for _, natGateway := range natGateways {
+		gateway := newAzureListedResource(ResourceKindNATGateway, natGateway.ID, natGateway.Name, natGateway.Tags)
+		resources = append(resources, gateway)
+		if natGateway.Properties == nil {
+			continue
+		}
+		for _, publicIP := range natGateway.Properties.PublicIPAddresses {
+			if publicIP.ID == nil {
+				continue
+			}
+			// The association is imported by the IDs of the NAT gateway and the public IP, separated by "|".
+			id := gateway.ID + "|" + *publicIP.ID
+			resources = append(resources, gateway.child(ResourceKindNATGatewayPublicIPAssociation, &id, azureResourceName(publicIP.ID)))
+		}
+	}
+
+	scaleSets, err := collectPages(ctx, l.scaleSets.NewListPager(l.resourceGroup, nil),
+		func(page armcompute.VirtualMachineScaleSetsClientListResponse) []*armcompute.VirtualMachineScaleSet {
+			return page.Value
+		})
+	if err != nil {
+		return nil, fmt.Errorf("listing scale sets: %w", err)
+	}
+	for _, scaleSet := range scaleSets {
+		resources = append(resources, newAzureListedResource(ResourceKindScaleSet, scaleSet.ID, scaleSet.Name, scaleSet.Tags))
+	}
+
+	return resources, nil
+}
+
+// collectPages returns the values of all pages of the pager.
+func collectPages[P, V any](ctx context.Context, pager *runtime.Pager[P], values func(P) []V) ([]V, error) {
+	var all []V
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, values(page)...)
+	}
+	return all, nil
+}
+
+func newAzureListedResource(kind string, id, name *string, tags map[string]*string) listedResource {
+	resource := listedResource{Resource: Resource{Kind: kind}}
+	if id != nil {
+		resource.ID = *id
+	}
+	if name != nil {
+		resource.name = *name
+	}
+	for key, value := range tags {
+		if value == nil {
+			continue
+		}
+		if resource.tags == nil {
+			resource.tags = make(map[string]string)
+		}
+		resource.tags[key] = *value
+	}
+	return resource
+}
+
+// child returns the sub-resource of the resource.
+func (r listedResource) child(kind string, id, name *string) listedResource {
+	child := newAzureListedResource(kind, id, name, nil)
+	child.name = r.name + "/" + child.name
+	return child
+}
+
+// azureResourceName returns the name of the resource with the given ID.
+func azureResourceName(id *string) *string {
+	if id == nil {
+		return nil
+	}
+	parsed, err := arm.ParseResourceID(*id)
+	if err != nil {
+		return nil
+	}
+	return &parsed.Name
+}
+
+// gcpResourceLister lists the resources the Terraform configuration of a cluster creates on GCP.
+type gcpResourceLister struct {
+	project string
+	region  string
+	// zones are the zones of the node groups.
+	zones map[string]struct{}
+
+	networks               *compute.NetworksClient
+	subnetworks            *compute.SubnetworksClient
+	routers                *compute.RoutersClient
+	firewalls              *compute.FirewallsClient
+	addresses              *compute.AddressesClient
+	globalAddresses        *compute.GlobalAddressesClient
+	healthChecks           *compute.HealthChecksClient
+	regionHealthChecks     *compute.RegionHealthChecksClient
+	backendServices        *compute.BackendServicesClient
+	regionBackendServices  *compute.RegionBackendServicesClient
+	targetTCPProxies       *compute.TargetTcpProxiesClient
+	regionTargetTCPProxies *compute.RegionTargetTcpProxiesClient
+	forwardingRules        *compute.ForwardingRulesClient
+	globalForwardingRules  *compute.GlobalForwardingRulesClient
+	instanceTemplates      *compute.InstanceTemplatesClient
+	instanceGroupManagers  *compute.InstanceGroupManagersClient
+}
+
+func newGCPResourceLister(ctx context.Context, project, region string, zones map[string]struct{}) (*gcpResourceLister, func(), error) {
+	clients := &gcpClients{ctx: ctx}
+	lister := &gcpResourceLister{
+		project:                project,
+		region:                 region,
+		zones:                  zones,
+		networks:               newGCPClient(clients, compute.NewNetworksRESTClient),
+		subnetworks:            newGCPClient(clients, compute.NewSubnetworksRESTClient),
+		routers:                newGCPClient(clients, compute.NewRoutersRESTClient),
+		firewalls:              newGCPClient(clients, compute.NewFirewallsRESTClient),
+		addresses:              newGCPClient(clients, compute.NewAddressesRESTClient),
+		globalAddresses:        newGCPClient(clients, compute.NewGlobalAddressesRESTClient),
+		healthChecks:           newGCPClient(clients, compute.NewHealthChecksRESTClient),
+		regionHealthChecks:     newGCPClient(clients, compute.NewRegionHealthChecksRESTClient),
+		backendServices:        newGCPClient(clients, compute.NewBackendServicesRESTClient),
+		regionBackendServices:  newGCPClient(clients, compute.NewRegionBackendServicesRESTClient),
+		targetTCPProxies:       newGCPClient(clients, compute.NewTargetTcpProxiesRESTClient),
+		regionTargetTCPProxies: newGCPClient(clients, compute.NewRegionTargetTcpProxiesRESTClient),
+		forwardingRules:        newGCPClient(clients, compute.NewForwardingRulesRESTClient),
+		globalForwardingRules:  newGCPClient(clients, compute.NewGlobalForwardingRulesRESTClient),
+		instanceTemplates:      newGCPClient(clients, compute.NewInstanceTemplatesRESTClient),
+		instanceGroupManagers:  newGCPClient(clients, compute.NewInstanceGroupManagersRESTClient),
+	}
+	if clients.err != nil {
+		clients.close()
+		return nil, nil, fmt.Errorf("creating GCP compute client: %w", clients.err)
+	}
+	return lister, clients.close, nil
+}
+
+// gcpClients creates GCP clients. Once creating a client fails, no further clients are created.
+type gcpClients struct {
+	ctx     context.Context
+	closers []func() error
+	err     error
+}
+
+func newGCPClient[T interface{ Close() error }](clients *gcpClients, newClient func(context.Context, ...option.ClientOption) (T, error)) T {
+	var client T
+	if clients.err != nil {
+		return client
+	}
+	client, clients.err = newClient(clients.ctx)
+	if clients.err == nil {
+		clients.closers = append(clients.closers, client.Close)
+	}
+	return client
+}
+
+func (c *gcpClients) close() {
+	for _, closeClient := range c.closers {
+		_ = closeClient()
+	}
+}
+
+// List lists the global resources of the project, the resources in the region, and the instance groups in the zones of the node groups.
+func (l *gcpResourceLister) List(ctx context.Context) ([]listedResource, error) {
+	var resources []listedResource
+	lists := []func() error{
+		func() error {
+			return appendGCPResources(&resources, ResourceKindVirtualNetwork, l.networks.List(ctx, &computepb.ListNetworksRequest{Project: l.project}))
+		},
+		func() error {
+			return appendGCPResources(&resources, ResourceKindSubnet, l.subnetworks.List(ctx, &computepb.ListSubnetworksRequest{Project: l.project, Region: l.region}))
+		},
+		func() error {
+			return appendGCPResources(&resources, ResourceKindFirewall, l.firewalls.List(ctx, &computepb.ListFirewallsRequest{Project: l.project}))
+		},
+		func() error {
+			return appendGCPResources(&resources, ResourceKindAddress, l.addresses.List(ctx, &computepb.ListAddressesRequest{Project: l.project, Region: l.region}))
+		},
+		func() error {
+			return appendGCPResources(&resources, ResourceKindGlobalAddress, l.globalAddresses.List(ctx, &computepb.ListGlobalAddressesRequest{Project: l.project}))
+		},
+		func() error {
+			return appendGCPResources(&resources, ResourceKindHealthCheck, l.healthChecks.List(ctx, &computepb.ListHealthChecksRequest{Project: l.project}))
+		},
+		func() error {
+			return appendGCPResources(&resources, ResourceKindRegionalHealthCheck, l.regionHealthChecks.List(ctx, &computepb.ListRegionHealthChecksRequest{Project: l.project, Region: l.region}))
+		},
+		func() error {
+			return appendGCPResources(&resources, ResourceKindBackendService, l.backendServices.List(ctx, &computepb.ListBackendServicesRequest{Project: l.project}))
+		},
+		func() error {
+			return appendGCPResources(&resources, ResourceKindRegionalBackendService, l.regionBackendServices.List(ctx, &computepb.ListRegionBackendServicesRequest{Project: l.project, Region: l.region}))
+		},
+		func() error {
+			return appendGCPResources(&resources, ResourceKindTargetTCPProxy, l.targetTCPProxies.List(ctx, &computepb.ListTargetTcpProxiesRequest{Project: l.project}))
+		},
+		func() error {
+			return appendGCPResources(&resources, ResourceKindRegionalTargetTCPProxy, l.regionTargetTCPProxies.List(ctx, &computepb.ListRegionTargetTcpProxiesRequest{Project: l.project, Region: l.region}))
+		},
+		func() error {
+			return appendGCPResources(&resources, ResourceKindForwardingRule, l.forwardingRules.List(ctx, &computepb.ListForwardingRulesRequest{Project: l.project, Region: l.region}))
+		},
+		func() error {
+			return appendGCPResources(&resources, ResourceKindGlobalForwardingRule, l.globalForwardingRules.List(ctx, &computepb.ListGlobalForwardingRulesRequest{Project: l.project}))
+		},
+	}
+	for _, list := range lists {
+		if err := list(); err != nil {
+			return nil, err
+		}
+	}
+
+	routers := l.routers.List(ctx, &computepb.ListRoutersRequest{Project: l.project, Region: l.region})
+	for {
+		router, err := routers.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("listing routers: %w", err)
+		}
+		listed := newGCPListedResource(ResourceKindRouter, router, nil)
+		resources = append(resources, listed)
+		for _, nat := range router.GetNats() {
+			// NATs are imported by the ID of the router, followed by the name of the NAT.
+			resources = append(resources, listedResource{
+				Resource: Resource{Kind: ResourceKindRouterNAT, ID: listed.ID + "/" + nat.GetName()},
+				name:     listed.name + "/" + nat.GetName(),
+			})
+		}
+	}
+
+	// Instance group managers have no labels, so they're labeled like the instance template they're using.
+	templateLabels := make(map[string]map[string]string)
+	templates := l.instanceTemplates.List(ctx, &computepb.ListInstanceTemplatesRequest{Project: l.project})
+	for {
+		template, err := templates.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("listing instance templates: %w", err)
+		}
+		labels := template.GetProperties().GetLabels()
+		templateLabels[template.GetName()] = labels
+		resources = append(resources, newGCPListedResource(ResourceKindInstanceTemplate, template, labels))
+	}
+	for zone := range l.zones {
+		managers := l.instanceGroupManagers.List(ctx, &computepb.ListInstanceGroupManagersRequest{Project: l.project, Zone: zone})
+		for {
+			manager, err := managers.Next()
+			if errors.Is(err, iterator.Done) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("listing instance group managers in zone %s: %w", zone, err)
+			}
+			var labels map[string]string
+			for _, version := range manager.GetVersions() {
+				if templateLabels, ok := templateLabels[path.Base(version.GetInstanceTemplate())]; ok {
+					labels = templateLabels
+				}
+			}
+			resources = append(resources, newGCPListedResource(ResourceKindInstanceGroupManager, manager, labels))
+		}
+	}
+
+	return resources, nil
+}
+
+// gcpResource is a resource returned by the GCP compute API.
+type gcpResource interface {
+	GetName() string
+	GetSelfLink() string
+}
+
+// appendGCPResources appends the resources of the iterator, which is one of the typed iterators of the GCP compute API.
+func appendGCPResources[T gcpResource](resources *[]listedResource, kind string, it interface{ Next() (T, error) }) error {
+	for {
+		resource, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("listing %s resources: %w", kind, err)
+		}
+		*resources = append(*resources, newGCPListedResource(kind, resource, nil))
+	}
+}
+
+func newGCPListedResource(kind string, resource gcpResource, labels map[string]string) listedResource {
+	return listedResource{
+		Resource: Resource{Kind: kind, ID: gcpResourceIDFromSelfLink(resource.GetSelfLink())},
+		name:     resource.GetName(),
+		tags:     labels,
+	}
+}
+
+// gcpResourceIDFromSelfLink returns the ID Terraform imports the resource by,
+// e.g., "projects/PROJECT/global/networks/NAME" for "https://www.googleapis.com/compute/v1/projects/PROJECT/global/networks/NAME".
+func gcpResourceIDFromSelfLink(selfLink string) string {
+	if _, id, ok := strings.Cut(selfLink, "/projects/"); ok {
+		return "projects/" + id
+	}
+	return selfLink
+}
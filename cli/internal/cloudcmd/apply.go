@@ -45,6 +45,8 @@ type Applier struct {
 	terraformClient tfResourceClient
 	logLevel        terraform.LogLevel
 
+	credentialsValidator credentialsValidator
+
 	workingDir string
 	backupDir  string
 	out        io.Writer
@@ -71,6 +73,8 @@ func NewApplier(
 		workingDir:      workingDir,
 		backupDir:       backupDir,
 		out:             out,
+
+		credentialsValidator: newCredentialsValidator(),
 	}, tfClient.RemoveInstaller, nil
 }
 
@@ -80,6 +84,7 @@ func (a *Applier) Plan(ctx context.Context, conf *config.Config) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("creating terraform variables: %w", err)
 	}
+	a.terraformClient.WithBackend(terraformBackend(conf))
 
 	return plan(
 		ctx, a.terraformClient, a.fileHandler, a.out, a.logLevel, vars,
@@ -117,6 +122,26 @@ func (a *Applier) Apply(
 	return infraState, nil
 }
 
+// Adopt imports the given existing resources into the Terraform state of the workspace when planning,
+// so Terraform doesn't create them again.
+func (a *Applier) Adopt(resources []AdoptableResource) {
+	for _, resource := range resources {
+		a.terraformClient.WithManualStateMigration(adoptionMigration(resource))
+	}
+}
+
+// terraformBackend returns the configured remote backend for the Terraform state,
+// or nil if the state is stored in the workspace.
+func terraformBackend(conf *config.Config) *terraform.Backend {
+	if conf.TerraformBackend == nil {
+		return nil
+	}
+	return &terraform.Backend{
+		Type:   conf.TerraformBackend.Type,
+		Config: conf.TerraformBackend.Config,
+	}
+}
+
 // RestoreWorkspace rolls back the existing workspace to the backup directory created when planning an action,
 // and the user decides to not apply it.
 // Note that this will not apply the restored state from the backup.
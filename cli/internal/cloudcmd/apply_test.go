@@ -225,17 +225,32 @@ func TestPlan(t *testing.T) {
 	}
 
 	testCases := map[string]struct {
-		upgradeID string
-		tf        *stubTerraformClient
-		fs        file.Handler
-		want      bool
-		wantErr   bool
+		upgradeID   string
+		tf          *stubTerraformClient
+		fs          file.Handler
+		backend     *config.TerraformBackendConfig
+		want        bool
+		wantBackend *terraform.Backend
+		wantErr     bool
 	}{
 		"success no diff": {
 			upgradeID: "1234",
 			tf:        &stubTerraformClient{},
 			fs:        setUpFilesystem([]string{}),
 		},
+		"remote backend": {
+			upgradeID: "1234",
+			tf:        &stubTerraformClient{},
+			fs:        setUpFilesystem([]string{}),
+			backend: &config.TerraformBackendConfig{
+				Type:   "azurerm",
+				Config: map[string]string{"storage_account_name": "account", "container_name": "tfstate", "key": "constellation.tfstate"},
+			},
+			wantBackend: &terraform.Backend{
+				Type:   "azurerm",
+				Config: map[string]string{"storage_account_name": "account", "container_name": "tfstate", "key": "constellation.tfstate"},
+			},
+		},
 		"success diff": {
 			upgradeID: "1234",
 			tf: &stubTerraformClient{
@@ -302,6 +317,7 @@ func TestPlan(t *testing.T) {
 
 			cfg := config.Default()
 			cfg.RemoveProviderAndAttestationExcept(cloudprovider.Azure)
+			cfg.TerraformBackend = tc.backend
 
 			diff, err := u.Plan(context.Background(), cfg)
 			if tc.wantErr {
@@ -309,6 +325,7 @@ func TestPlan(t *testing.T) {
 			} else {
 				require.NoError(err)
 				require.Equal(tc.want, diff)
+				require.Equal(tc.wantBackend, tc.tf.backend)
 			}
 		})
 	}
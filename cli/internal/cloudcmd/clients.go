@@ -40,6 +40,8 @@ type tfResourceClient interface {
 	tfDestroyer
 	tfPlanner
 	ApplyCluster(ctx context.Context, provider cloudprovider.Provider, logLevel terraform.LogLevel) (state.Infrastructure, error)
+	WithManualStateMigration(migration terraform.StateMigration) *terraform.Client
+	WithBackend(backend *terraform.Backend) *terraform.Client
 }
 
 type tfIAMClient interface {
@@ -48,6 +48,18 @@ type stubTerraformClient struct {
 	planDiff               bool
 	planErr                error
 	showPlanErr            error
+	stateMigrations        []terraform.StateMigration
+	backend                *terraform.Backend
+}
+
+func (c *stubTerraformClient) WithManualStateMigration(migration terraform.StateMigration) *terraform.Client {
+	c.stateMigrations = append(c.stateMigrations, migration)
+	return nil
+}
+
+func (c *stubTerraformClient) WithBackend(backend *terraform.Backend) *terraform.Client {
+	c.backend = backend
+	return nil
 }
 
 func (c *stubTerraformClient) ApplyCluster(_ context.Context, _ cloudprovider.Provider, _ terraform.LogLevel) (state.Infrastructure, error) {
@@ -0,0 +1,175 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cloudcmd
+
+import (
+	"context"
+	"fmt"
+
+	compute "cloud.google.com/go/compute/apiv1"
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v6"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/googleapis/gax-go/v2"
+)
+
+// CredentialsError is returned if the credentials for a cloud provider are missing or invalid.
+type CredentialsError struct {
+	Provider cloudprovider.Provider
+	hint     string
+	err      error
+}
+
+// Error returns the error message, including a provider-specific hint on how to fix the credentials.
+func (e *CredentialsError) Error() string {
+	return fmt.Sprintf("validating %s credentials: %s\n%s", e.Provider, e.err, e.hint)
+}
+
+// Unwrap returns the underlying error.
+func (e *CredentialsError) Unwrap() error {
+	return e.err
+}
+
+// ValidateCredentials checks that valid credentials for the configured cloud provider are available
+// by performing a cheap, read-only API call.
+// This allows failing before any cloud resources are modified.
+// Providers without a credentials check are skipped.
+func (a *Applier) ValidateCredentials(ctx context.Context, conf *config.Config) error {
+	return a.credentialsValidator.validate(ctx, conf)
+}
+
+// credentialsValidator creates cloud provider clients to validate credentials.
+type credentialsValidator struct {
+	newAWSClient   func(ctx context.Context, region string) (awsCredentialsClient, error)
+	newAzureClient func(subscriptionID string) (azureCredentialsClient, error)
+	newGCPClient   func(ctx context.Context) (gcpCredentialsClient, func(), error)
+}
+
+func newCredentialsValidator() credentialsValidator {
+	return credentialsValidator{
+		newAWSClient:   newAWSCredentialsClient,
+		newAzureClient: newAzureCredentialsClient,
+		newGCPClient:   newGCPCredentialsClient,
+	}
+}
+
+func (v credentialsValidator) validate(ctx context.Context, conf *config.Config) error {
+	switch conf.GetProvider() {
+	case cloudprovider.AWS:
+		return v.validateAWS(ctx, conf.Provider.AWS.Region)
+	case cloudprovider.Azure:
+		return v.validateAzure(ctx, conf.Provider.Azure.SubscriptionID, conf.Provider.Azure.ResourceGroup)
+	case cloudprovider.GCP:
+		return v.validateGCP(ctx, conf.Provider.GCP.Project)
+	default:
+		return nil
+	}
+}
+
+func (v credentialsValidator) validateAWS(ctx context.Context, region string) error {
+	credentialsErr := func(err error) error {
+		return &CredentialsError{
+			Provider: cloudprovider.AWS,
+			hint:     "Make sure valid AWS credentials are configured, e.g., by running \"aws configure\" or setting AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.",
+			err:      err,
+		}
+	}
+
+	client, err := v.newAWSClient(ctx, region)
+	if err != nil {
+		return credentialsErr(err)
+	}
+	if _, err := client.DescribeAvailabilityZones(ctx, &ec2.DescribeAvailabilityZonesInput{}); err != nil {
+		return credentialsErr(fmt.Errorf("listing availability zones in region %q: %w", region, err))
+	}
+	return nil
+}
+
+func (v credentialsValidator) validateAzure(ctx context.Context, subscriptionID, resourceGroup string) error {
+	credentialsErr := func(err error) error {
+		return &CredentialsError{
+			Provider: cloudprovider.Azure,
+			hint:     "Make sure you are logged in to the Azure subscription, e.g., by running \"az login\", and can access the resource group.",
+			err:      err,
+		}
+	}
+
+	client, err := v.newAzureClient(subscriptionID)
+	if err != nil {
+		return credentialsErr(err)
+	}
+	// Fetching the first page is enough to verify that the credentials are accepted.
+	if _, err := client.NewListPager(resourceGroup, nil).NextPage(ctx); err != nil {
+		return credentialsErr(fmt.Errorf("listing scale sets in resource group %q: %w", resourceGroup, err))
+	}
+	return nil
+}
+
+func (v credentialsValidator) validateGCP(ctx context.Context, project string) error {
+	credentialsErr := func(err error) error {
+		return &CredentialsError{
+			Provider: cloudprovider.GCP,
+			hint:     "Make sure Application Default Credentials are configured, e.g., by running \"gcloud auth application-default login\".",
+			err:      err,
+		}
+	}
+
+	client, closeClient, err := v.newGCPClient(ctx)
+	if err != nil {
+		return credentialsErr(err)
+	}
+	defer closeClient()
+	if _, err := client.Get(ctx, &computepb.GetProjectRequest{Project: project}); err != nil {
+		return credentialsErr(fmt.Errorf("getting project %q: %w", project, err))
+	}
+	return nil
+}
+
+type awsCredentialsClient interface {
+	DescribeAvailabilityZones(ctx context.Context, params *ec2.DescribeAvailabilityZonesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeAvailabilityZonesOutput, error)
+}
+
+type azureCredentialsClient interface {
+	NewListPager(resourceGroupName string, options *armcompute.VirtualMachineScaleSetsClientListOptions) *runtime.Pager[armcompute.VirtualMachineScaleSetsClientListResponse]
+}
+
+type gcpCredentialsClient interface {
+	Get(ctx context.Context, req *computepb.GetProjectRequest, opts ...gax.CallOption) (*computepb.Project, error)
+}
+
+func newAWSCredentialsClient(ctx context.Context, region string) (awsCredentialsClient, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS config: %w", err)
+	}
+	return ec2.NewFromConfig(cfg), nil
+}
+
+func newAzureCredentialsClient(subscriptionID string) (azureCredentialsClient, error) {
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("loading Azure credentials: %w", err)
+	}
+	client, err := armcompute.NewVirtualMachineScaleSetsClient(subscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating Azure scale set client: %w", err)
+	}
+	return client, nil
+}
+
+func newGCPCredentialsClient(ctx context.Context) (gcpCredentialsClient, func(), error) {
+	client, err := compute.NewProjectsRESTClient(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating GCP projects client: %w", err)
+	}
+	return client, func() { _ = client.Close() }, nil
+}
@@ -0,0 +1,196 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cloudcmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v6"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/googleapis/gax-go/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCredentials(t *testing.T) {
+	errAuth := errors.New("authentication failed")
+	awsConf := func() *config.Config {
+		conf := config.Default()
+		conf.RemoveProviderAndAttestationExcept(cloudprovider.AWS)
+		conf.Provider.AWS.Region = "eu-central-1"
+		return conf
+	}
+	azureConf := func() *config.Config {
+		conf := config.Default()
+		conf.RemoveProviderAndAttestationExcept(cloudprovider.Azure)
+		conf.Provider.Azure.SubscriptionID = "subscription"
+		conf.Provider.Azure.ResourceGroup = "resource-group"
+		return conf
+	}
+	gcpConf := func() *config.Config {
+		conf := config.Default()
+		conf.RemoveProviderAndAttestationExcept(cloudprovider.GCP)
+		conf.Provider.GCP.Project = "project"
+		return conf
+	}
+	qemuConf := func() *config.Config {
+		conf := config.Default()
+		conf.RemoveProviderAndAttestationExcept(cloudprovider.QEMU)
+		return conf
+	}
+
+	testCases := map[string]struct {
+		conf         *config.Config
+		aws          *stubAWSCredentialsClient
+		newAWSErr    error
+		azure        *stubAzureCredentialsClient
+		newAzureErr  error
+		gcp          *stubGCPCredentialsClient
+		newGCPErr    error
+		wantErr      bool
+		wantProvider cloudprovider.Provider
+		wantHint     string
+	}{
+		"aws valid credentials": {
+			conf: awsConf(),
+			aws:  &stubAWSCredentialsClient{},
+		},
+		"aws invalid credentials": {
+			conf:         awsConf(),
+			aws:          &stubAWSCredentialsClient{err: errAuth},
+			wantErr:      true,
+			wantProvider: cloudprovider.AWS,
+			wantHint:     "aws configure",
+		},
+		"aws config cannot be loaded": {
+			conf:         awsConf(),
+			newAWSErr:    errAuth,
+			wantErr:      true,
+			wantProvider: cloudprovider.AWS,
+			wantHint:     "aws configure",
+		},
+		"azure valid credentials": {
+			conf:  azureConf(),
+			azure: &stubAzureCredentialsClient{},
+		},
+		"azure invalid credentials": {
+			conf:         azureConf(),
+			azure:        &stubAzureCredentialsClient{err: errAuth},
+			wantErr:      true,
+			wantProvider: cloudprovider.Azure,
+			wantHint:     "az login",
+		},
+		"azure credentials missing": {
+			conf:         azureConf(),
+			newAzureErr:  errAuth,
+			wantErr:      true,
+			wantProvider: cloudprovider.Azure,
+			wantHint:     "az login",
+		},
+		"gcp valid credentials": {
+			conf: gcpConf(),
+			gcp:  &stubGCPCredentialsClient{},
+		},
+		"gcp invalid credentials": {
+			conf:         gcpConf(),
+			gcp:          &stubGCPCredentialsClient{err: errAuth},
+			wantErr:      true,
+			wantProvider: cloudprovider.GCP,
+			wantHint:     "gcloud auth application-default login",
+		},
+		"gcp credentials missing": {
+			conf:         gcpConf(),
+			newGCPErr:    errAuth,
+			wantErr:      true,
+			wantProvider: cloudprovider.GCP,
+			wantHint:     "gcloud auth application-default login",
+		},
+		"qemu is skipped": {
+			conf: qemuConf(),
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			a := &Applier{
+				credentialsValidator: credentialsValidator{
+					newAWSClient: func(_ context.Context, region string) (awsCredentialsClient, error) {
+						assert.Equal("eu-central-1", region)
+						return tc.aws, tc.newAWSErr
+					},
+					newAzureClient: func(subscriptionID string) (azureCredentialsClient, error) {
+						assert.Equal("subscription", subscriptionID)
+						return tc.azure, tc.newAzureErr
+					},
+					newGCPClient: func(_ context.Context) (gcpCredentialsClient, func(), error) {
+						return tc.gcp, func() {}, tc.newGCPErr
+					},
+				},
+			}
+
+			err := a.ValidateCredentials(context.Background(), tc.conf)
+			if tc.wantErr {
+				var credErr *CredentialsError
+				assert.True(errors.As(err, &credErr))
+				assert.Equal(tc.wantProvider, credErr.Provider)
+				assert.ErrorIs(err, errAuth)
+				assert.ErrorContains(err, tc.wantHint)
+				return
+			}
+			assert.NoError(err)
+			if tc.azure != nil {
+				assert.Equal("resource-group", tc.azure.resourceGroup)
+			}
+			if tc.gcp != nil {
+				assert.Equal("project", tc.gcp.project)
+			}
+		})
+	}
+}
+
+type stubAWSCredentialsClient struct {
+	err error
+}
+
+func (c *stubAWSCredentialsClient) DescribeAvailabilityZones(_ context.Context, _ *ec2.DescribeAvailabilityZonesInput, _ ...func(*ec2.Options)) (*ec2.DescribeAvailabilityZonesOutput, error) {
+	return &ec2.DescribeAvailabilityZonesOutput{}, c.err
+}
+
+type stubAzureCredentialsClient struct {
+	resourceGroup string
+	err           error
+}
+
+func (c *stubAzureCredentialsClient) NewListPager(resourceGroupName string, _ *armcompute.VirtualMachineScaleSetsClientListOptions,
+) *runtime.Pager[armcompute.VirtualMachineScaleSetsClientListResponse] {
+	c.resourceGroup = resourceGroupName
+	return runtime.NewPager(runtime.PagingHandler[armcompute.VirtualMachineScaleSetsClientListResponse]{
+		More: func(_ armcompute.VirtualMachineScaleSetsClientListResponse) bool {
+			return false
+		},
+		Fetcher: func(_ context.Context, _ *armcompute.VirtualMachineScaleSetsClientListResponse) (armcompute.VirtualMachineScaleSetsClientListResponse, error) {
+			return armcompute.VirtualMachineScaleSetsClientListResponse{}, c.err
+		},
+	})
+}
+
+type stubGCPCredentialsClient struct {
+	project string
+	err     error
+}
+
+func (c *stubGCPCredentialsClient) Get(_ context.Context, req *computepb.GetProjectRequest, _ ...gax.CallOption) (*computepb.Project, error) {
+	c.project = req.GetProject()
+	return &computepb.Project{}, c.err
+}
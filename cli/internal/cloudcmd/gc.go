@@ -0,0 +1,216 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cloudcmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/edgelesssys/constellation/v2/cli/internal/terraform"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+)
+
+// Kinds of cloud resources recorded in the state file.
+const (
+	ResourceKindNetworkSecurityGroup = "network security group"
+	ResourceKindLoadBalancer         = "load balancer"
+	ResourceKindVirtualNetwork       = "virtual network"
+	ResourceKindSubnet               = "subnet"
+)
+
+// Resource is a cloud resource recorded in the state file.
+type Resource struct {
+	// Kind is the type of the resource.
+	Kind string
+	// ID is the name or ID identifying the resource at the cloud provider.
+	ID string
+}
+
+// String returns the kind and ID of the resource.
+func (r Resource) String() string {
+	return fmt.Sprintf("%s %s", r.Kind, r.ID)
+}
+
+// ResourceCollector finds and deletes orphaned cloud resources.
+// A resource is orphaned if it's recorded in the state file and still exists,
+// but is no longer managed by Terraform, e.g., after a failed apply replaced it.
+type ResourceCollector struct {
+	newTerraformClient func(ctx context.Context, tfWorkspace string) (tfInfrastructureClient, error)
+	newResourceClient  func(ctx context.Context, provider cloudprovider.Provider, infra state.Infrastructure) (cloudResourceClient, func(), error)
+}
+
+// NewResourceCollector creates a new ResourceCollector.
+func NewResourceCollector() *ResourceCollector {
+	return &ResourceCollector{
+		newTerraformClient: func(ctx context.Context, tfWorkspace string) (tfInfrastructureClient, error) {
+			return terraform.New(ctx, tfWorkspace)
+		},
+		newResourceClient: newCloudResourceClient,
+	}
+}
+
+// Plan returns the orphaned resources of the given recorded infrastructure.
+// Existing resources configured in the config are never considered orphaned, since they aren't owned by the cluster.
+// Resources that aren't tagged with the UID of the cluster are skipped as well, e.g., if the state file was edited or copied from another cluster.
+func (c *ResourceCollector) Plan(ctx context.Context, conf *config.Config, tfWorkspace string, recorded state.Infrastructure) ([]Resource, error) {
+	provider := conf.GetProvider()
+	candidates := recordedResources(provider, recorded)
+	for _, resource := range configuredResources(conf) {
+		delete(candidates, resource)
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	tfClient, err := c.newTerraformClient(ctx, tfWorkspace)
+	if err != nil {
+		return nil, fmt.Errorf("creating Terraform client: %w", err)
+	}
+	defer tfClient.RemoveInstaller()
+	managed, err := tfClient.ShowInfrastructure(ctx, provider)
+	if err != nil {
+		return nil, fmt.Errorf("getting infrastructure managed by Terraform: %w", err)
+	}
+	for resource := range recordedResources(provider, managed) {
+		delete(candidates, resource)
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	client, closeClient, err := c.newResourceClient(ctx, provider, recorded)
+	if err != nil {
+		return nil, err
+	}
+	defer closeClient()
+
+	var orphans []Resource
+	for _, resource := range orderedResources(candidates) {
+		exists, err := client.Exists(ctx, resource)
+		if err != nil {
+			return nil, fmt.Errorf("checking if %s exists: %w", resource, err)
+		}
+		if !exists {
+			continue
+		}
+		owned, err := client.IsOwnedBy(ctx, resource, recorded.UID)
+		if err != nil {
+			return nil, fmt.Errorf("checking owner of %s: %w", resource, err)
+		}
+		if owned {
+			orphans = append(orphans, resource)
+		}
+	}
+	return orphans, nil
+}
+
+// Delete deletes the given resources of the given recorded infrastructure.
+// All resources are tried, even if deleting one of them fails.
+// Resources that aren't tagged with the UID of the cluster are skipped and reported as an error.
+func (c *ResourceCollector) Delete(ctx context.Context, conf *config.Config, recorded state.Infrastructure, resources []Resource) error {
+	if len(resources) == 0 {
+		return nil
+	}
+	client, closeClient, err := c.newResourceClient(ctx, conf.GetProvider(), recorded)
+	if err != nil {
+		return err
+	}
+	defer closeClient()
+
+	var errs []error
+	for _, resource := range resources {
+		owned, err := client.IsOwnedBy(ctx, resource, recorded.UID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("checking owner of %s: %w", resource, err))
+			continue
+		}
+		if !owned {
+			errs = append(errs, fmt.Errorf("skipping %s: not tagged with the UID %q of the cluster", resource, recorded.UID))
+			continue
+		}
+		if err := client.Delete(ctx, resource); err != nil {
+			errs = append(errs, fmt.Errorf("deleting %s: %w", resource, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// recordedResources returns the cloud resources of the given infrastructure.
+func recordedResources(provider cloudprovider.Provider, infra state.Infrastructure) map[Resource]struct{} {
+	var resources []Resource
+	switch {
+	case provider == cloudprovider.Azure && infra.Azure != nil:
+		resources = []Resource{
+			{Kind: ResourceKindLoadBalancer, ID: infra.Azure.LoadBalancerName},
+			{Kind: ResourceKindSubnet, ID: infra.Azure.SubnetID},
+			{Kind: ResourceKindVirtualNetwork, ID: infra.Azure.VirtualNetworkID},
+			{Kind: ResourceKindNetworkSecurityGroup, ID: infra.Azure.NetworkSecurityGroupName},
+		}
+	case provider == cloudprovider.GCP && infra.GCP != nil:
+		resources = []Resource{
+			{Kind: ResourceKindSubnet, ID: infra.GCP.SubnetworkID},
+			{Kind: ResourceKindVirtualNetwork, ID: infra.GCP.NetworkID},
+		}
+	}
+
+	recorded := make(map[Resource]struct{}, len(resources))
+	for _, resource := range resources {
+		if resource.ID != "" {
+			recorded[resource] = struct{}{}
+		}
+	}
+	return recorded
+}
+
+// configuredResources returns the existing cloud resources the config attaches the cluster to.
+func configuredResources(conf *config.Config) []Resource {
+	switch {
+	case conf.Provider.Azure != nil:
+		return []Resource{
+			{Kind: ResourceKindVirtualNetwork, ID: conf.Provider.Azure.VirtualNetworkID},
+			{Kind: ResourceKindSubnet, ID: conf.Provider.Azure.SubnetID},
+		}
+	case conf.Provider.GCP != nil:
+		return []Resource{
+			{Kind: ResourceKindVirtualNetwork, ID: conf.Provider.GCP.NetworkID},
+			{Kind: ResourceKindSubnet, ID: conf.Provider.GCP.SubnetworkID},
+		}
+	default:
+		return nil
+	}
+}
+
+// orderedResources returns the resources in the order they can be deleted in.
+// Resources are deleted before the resources they depend on.
+func orderedResources(resources map[Resource]struct{}) []Resource {
+	kinds := []string{ResourceKindLoadBalancer, ResourceKindSubnet, ResourceKindVirtualNetwork, ResourceKindNetworkSecurityGroup}
+	var ordered []Resource
+	for _, kind := range kinds {
+		for resource := range resources {
+			if resource.Kind == kind {
+				ordered = append(ordered, resource)
+			}
+		}
+	}
+	return ordered
+}
+
+type tfInfrastructureClient interface {
+	ShowInfrastructure(ctx context.Context, provider cloudprovider.Provider) (state.Infrastructure, error)
+	RemoveInstaller()
+}
+
+// cloudResourceClient checks for and deletes cloud resources.
+type cloudResourceClient interface {
+	Exists(ctx context.Context, resource Resource) (bool, error)
+	// IsOwnedBy checks if the resource belongs to the cluster with the given UID.
+	IsOwnedBy(ctx context.Context, resource Resource, uid string) (bool, error)
+	Delete(ctx context.Context, resource Resource) error
+}
@@ -0,0 +1,386 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cloudcmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceCollectorPlan(t *testing.T) {
+	const (
+		oldVNet   = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/old"
+		oldSubnet = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/old/subnets/nodes"
+		newVNet   = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/new"
+		newSubnet = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/new/subnets/nodes"
+	)
+	azureRecorded := state.Infrastructure{
+		UID: "uid",
+		Azure: &state.Azure{
+			ResourceGroup:            "rg",
+			LoadBalancerName:         "old-lb",
+			VirtualNetworkID:         oldVNet,
+			SubnetID:                 oldSubnet,
+			NetworkSecurityGroupName: "nsg",
+		},
+	}
+	azureManaged := state.Infrastructure{
+		Azure: &state.Azure{
+			ResourceGroup:            "rg",
+			LoadBalancerName:         "new-lb",
+			VirtualNetworkID:         newVNet,
+			SubnetID:                 newSubnet,
+			NetworkSecurityGroupName: "nsg",
+		},
+	}
+	azureConfig := func() *config.Config {
+		conf := config.Default()
+		conf.RemoveProviderExcept(cloudprovider.Azure)
+		return conf
+	}
+	someErr := errors.New("failed")
+
+	testCases := map[string]struct {
+		conf           *config.Config
+		recorded       state.Infrastructure
+		tfClient       *stubTerraformClient
+		newTFClientErr error
+		resourceClient *fakeResourceClient
+		wantOrphans    []Resource
+		wantErr        bool
+	}{
+		"only existing resources not managed by Terraform are orphaned": {
+			conf:     azureConfig(),
+			recorded: azureRecorded,
+			tfClient: &stubTerraformClient{infraState: azureManaged},
+			resourceClient: newFakeResourceClient(
+				Resource{Kind: ResourceKindLoadBalancer, ID: "new-lb"},
+				Resource{Kind: ResourceKindVirtualNetwork, ID: newVNet},
+				Resource{Kind: ResourceKindSubnet, ID: newSubnet},
+				Resource{Kind: ResourceKindNetworkSecurityGroup, ID: "nsg"},
+				// The load balancer of the state file was already deleted.
+				Resource{Kind: ResourceKindVirtualNetwork, ID: oldVNet},
+				Resource{Kind: ResourceKindSubnet, ID: oldSubnet},
+			),
+			wantOrphans: []Resource{
+				{Kind: ResourceKindSubnet, ID: oldSubnet},
+				{Kind: ResourceKindVirtualNetwork, ID: oldVNet},
+			},
+		},
+		"state matches Terraform": {
+			conf:           azureConfig(),
+			recorded:       azureManaged,
+			tfClient:       &stubTerraformClient{infraState: azureManaged},
+			resourceClient: newFakeResourceClient(),
+		},
+		"recorded resources no longer exist": {
+			conf:           azureConfig(),
+			recorded:       azureRecorded,
+			tfClient:       &stubTerraformClient{infraState: azureManaged},
+			resourceClient: newFakeResourceClient(),
+		},
+		"configured network is never orphaned": {
+			conf: func() *config.Config {
+				conf := azureConfig()
+				conf.Provider.Azure.VirtualNetworkID = oldVNet
+				conf.Provider.Azure.SubnetID = oldSubnet
+				return conf
+			}(),
+			recorded: azureRecorded,
+			tfClient: &stubTerraformClient{infraState: azureManaged},
+			resourceClient: newFakeResourceClient(
+				Resource{Kind: ResourceKindLoadBalancer, ID: "old-lb"},
+				Resource{Kind: ResourceKindVirtualNetwork, ID: oldVNet},
+				Resource{Kind: ResourceKindSubnet, ID: oldSubnet},
+			),
+			wantOrphans: []Resource{
+				{Kind: ResourceKindLoadBalancer, ID: "old-lb"},
+			},
+		},
+		"gcp": {
+			conf: func() *config.Config {
+				conf := config.Default()
+				conf.RemoveProviderExcept(cloudprovider.GCP)
+				return conf
+			}(),
+			recorded: state.Infrastructure{
+				UID: "uid",
+				GCP: &state.GCP{
+					NetworkID:    "projects/p/global/networks/old",
+					SubnetworkID: "projects/p/regions/r/subnetworks/old",
+				},
+			},
+			tfClient: &stubTerraformClient{infraState: state.Infrastructure{
+				GCP: &state.GCP{
+					NetworkID:    "projects/p/global/networks/new",
+					SubnetworkID: "projects/p/regions/r/subnetworks/new",
+				},
+			}},
+			resourceClient: newFakeResourceClient(
+				Resource{Kind: ResourceKindVirtualNetwork, ID: "projects/p/global/networks/old"},
+			),
+			wantOrphans: []Resource{
+				{Kind: ResourceKindVirtualNetwork, ID: "projects/p/global/networks/old"},
+			},
+		},
+		"resources of another cluster aren't orphaned": {
+			conf:     azureConfig(),
+			recorded: azureRecorded,
+			tfClient: &stubTerraformClient{infraState: azureManaged},
+			resourceClient: func() *fakeResourceClient {
+				client := newFakeResourceClient(
+					Resource{Kind: ResourceKindVirtualNetwork, ID: oldVNet},
+					Resource{Kind: ResourceKindSubnet, ID: oldSubnet},
+				)
+				client.owners = map[Resource]string{{Kind: ResourceKindVirtualNetwork, ID: oldVNet}: "other-uid"}
+				return client
+			}(),
+			wantOrphans: []Resource{
+				{Kind: ResourceKindSubnet, ID: oldSubnet},
+			},
+		},
+		"checking owner fails": {
+			conf:     azureConfig(),
+			recorded: azureRecorded,
+			tfClient: &stubTerraformClient{infraState: azureManaged},
+			resourceClient: func() *fakeResourceClient {
+				client := newFakeResourceClient(Resource{Kind: ResourceKindVirtualNetwork, ID: oldVNet})
+				client.isOwnedByErr = someErr
+				return client
+			}(),
+			wantErr: true,
+		},
+		"creating Terraform client fails": {
+			conf:           azureConfig(),
+			recorded:       azureRecorded,
+			newTFClientErr: someErr,
+			resourceClient: newFakeResourceClient(),
+			wantErr:        true,
+		},
+		"showing infrastructure fails": {
+			conf:           azureConfig(),
+			recorded:       azureRecorded,
+			tfClient:       &stubTerraformClient{showInfrastructureErr: someErr},
+			resourceClient: newFakeResourceClient(),
+			wantErr:        true,
+		},
+		"checking existence fails": {
+			conf:     azureConfig(),
+			recorded: azureRecorded,
+			tfClient: &stubTerraformClient{infraState: azureManaged},
+			resourceClient: func() *fakeResourceClient {
+				client := newFakeResourceClient()
+				client.existsErr = someErr
+				return client
+			}(),
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			collector := &ResourceCollector{
+				newTerraformClient: func(_ context.Context, _ string) (tfInfrastructureClient, error) {
+					return tc.tfClient, tc.newTFClientErr
+				},
+				newResourceClient: func(_ context.Context, _ cloudprovider.Provider, _ state.Infrastructure) (cloudResourceClient, func(), error) {
+					return tc.resourceClient, func() {}, nil
+				},
+			}
+
+			orphans, err := collector.Plan(context.Background(), tc.conf, "test", tc.recorded)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tc.wantOrphans, orphans)
+			assert.True(tc.tfClient.removeInstallerCalled)
+			assert.Empty(tc.resourceClient.deleted)
+		})
+	}
+}
+
+func TestResourceCollectorDelete(t *testing.T) {
+	lb := Resource{Kind: ResourceKindLoadBalancer, ID: "lb"}
+	nsg := Resource{Kind: ResourceKindNetworkSecurityGroup, ID: "nsg"}
+	conf := config.Default()
+	conf.RemoveProviderExcept(cloudprovider.Azure)
+
+	testCases := map[string]struct {
+		resourceClient *fakeResourceClient
+		wantDeleted    []Resource
+		wantErr        bool
+	}{
+		"success": {
+			resourceClient: newFakeResourceClient(lb, nsg),
+			wantDeleted:    []Resource{lb, nsg},
+		},
+		"deletion of one resource fails": {
+			resourceClient: func() *fakeResourceClient {
+				client := newFakeResourceClient(lb, nsg)
+				client.deleteErrs = map[Resource]error{lb: errors.New("failed")}
+				return client
+			}(),
+			wantDeleted: []Resource{nsg},
+			wantErr:     true,
+		},
+		"resource of another cluster isn't deleted": {
+			resourceClient: func() *fakeResourceClient {
+				client := newFakeResourceClient(lb, nsg)
+				client.owners = map[Resource]string{lb: "other-uid"}
+				return client
+			}(),
+			wantDeleted: []Resource{nsg},
+			wantErr:     true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			collector := &ResourceCollector{
+				newResourceClient: func(_ context.Context, _ cloudprovider.Provider, _ state.Infrastructure) (cloudResourceClient, func(), error) {
+					return tc.resourceClient, func() {}, nil
+				},
+			}
+
+			err := collector.Delete(context.Background(), conf, state.Infrastructure{UID: "uid", Azure: &state.Azure{}}, []Resource{lb, nsg})
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			assert.Equal(tc.wantDeleted, tc.resourceClient.deleted)
+		})
+	}
+}
+
+func TestParseGCPResourceID(t *testing.T) {
+	testCases := map[string]struct {
+		resource Resource
+		wantID   gcpResourceID
+		wantErr  bool
+	}{
+		"network": {
+			resource: Resource{Kind: ResourceKindVirtualNetwork, ID: "projects/p/global/networks/n"},
+			wantID:   gcpResourceID{project: "p", name: "n"},
+		},
+		"subnetwork": {
+			resource: Resource{Kind: ResourceKindSubnet, ID: "projects/p/regions/r/subnetworks/s"},
+			wantID:   gcpResourceID{project: "p", region: "r", name: "s"},
+		},
+		"subnetwork ID of network": {
+			resource: Resource{Kind: ResourceKindVirtualNetwork, ID: "projects/p/regions/r/subnetworks/s"},
+			wantErr:  true,
+		},
+		"unsupported kind": {
+			resource: Resource{Kind: ResourceKindLoadBalancer, ID: "projects/p/global/networks/n"},
+			wantErr:  true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			id, err := parseGCPResourceID(tc.resource)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantID, id)
+		})
+	}
+}
+
+func TestGCPDescriptionLabel(t *testing.T) {
+	testCases := map[string]struct {
+		description string
+		wantValue   string
+		wantOK      bool
+	}{
+		"label": {
+			description: "Constellation VPC network constellation-uid=uid",
+			wantValue:   "uid",
+			wantOK:      true,
+		},
+		"no label": {
+			description: "Constellation VPC network",
+		},
+		"empty description": {},
+		"label with empty value": {
+			description: "Constellation VPC network constellation-uid=",
+			wantOK:      true,
+		},
+		"other label": {
+			description: "Constellation VPC network other-constellation-uid=uid",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			value, ok := gcpDescriptionLabel(tc.description, constellationUIDTag)
+			assert.Equal(tc.wantOK, ok)
+			assert.Equal(tc.wantValue, value)
+		})
+	}
+}
+
+// fakeResourceClient is a cloud provider holding the given existing resources.
+// Resources belong to the cluster with UID "uid", unless another owner is set.
+type fakeResourceClient struct {
+	existing     map[Resource]bool
+	owners       map[Resource]string
+	deleted      []Resource
+	existsErr    error
+	isOwnedByErr error
+	deleteErrs   map[Resource]error
+}
+
+func newFakeResourceClient(existing ...Resource) *fakeResourceClient {
+	client := &fakeResourceClient{existing: map[Resource]bool{}}
+	for _, resource := range existing {
+		client.existing[resource] = true
+	}
+	return client
+}
+
+func (c *fakeResourceClient) Exists(_ context.Context, resource Resource) (bool, error) {
+	return c.existing[resource], c.existsErr
+}
+
+func (c *fakeResourceClient) IsOwnedBy(_ context.Context, resource Resource, uid string) (bool, error) {
+	owner, ok := c.owners[resource]
+	if !ok {
+		owner = "uid"
+	}
+	return owner == uid, c.isOwnedByErr
+}
+
+func (c *fakeResourceClient) Delete(_ context.Context, resource Resource) error {
+	if err := c.deleteErrs[resource]; err != nil {
+		return err
+	}
+	delete(c.existing, resource)
+	c.deleted = append(c.deleted, resource)
+	return nil
+}
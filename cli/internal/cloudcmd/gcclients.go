@@ -0,0 +1,311 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cloudcmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	compute "cloud.google.com/go/compute/apiv1"
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+	"google.golang.org/api/googleapi"
+)
+
+// constellationUIDTag is the tag the cloud resources of a cluster are tagged with. Its value is the UID of the cluster.
+const constellationUIDTag = "constellation-uid"
+
+func newCloudResourceClient(ctx context.Context, provider cloudprovider.Provider, infra state.Infrastructure) (cloudResourceClient, func(), error) {
+	switch {
+	case provider == cloudprovider.Azure && infra.Azure != nil:
+		client, err := newAzureResourceClient(infra.Azure.SubscriptionID, infra.Azure.ResourceGroup)
+		if err != nil {
+			return nil, nil, err
+		}
+		return client, func() {}, nil
+	case provider == cloudprovider.GCP && infra.GCP != nil:
+		return newGCPResourceClient(ctx)
+	default:
+		return nil, nil, fmt.Errorf("collecting orphaned resources isn't supported for %s", provider)
+	}
+}
+
+// azureResourceClient checks for and deletes the network resources of a cluster on Azure.
+type azureResourceClient struct {
+	resourceGroup   string
+	securityGroups  *armnetwork.SecurityGroupsClient
+	loadBalancers   *armnetwork.LoadBalancersClient
+	virtualNetworks *armnetwork.VirtualNetworksClient
+	subnets         *armnetwork.SubnetsClient
+}
+
+func newAzureResourceClient(subscriptionID, resourceGroup string) (*azureResourceClient, error) {
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("loading Azure credentials: %w", err)
+	}
+	factory, err := armnetwork.NewClientFactory(subscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating Azure network client: %w", err)
+	}
+	return &azureResourceClient{
+		resourceGroup:   resourceGroup,
+		securityGroups:  factory.NewSecurityGroupsClient(),
+		loadBalancers:   factory.NewLoadBalancersClient(),
+		virtualNetworks: factory.NewVirtualNetworksClient(),
+		subnets:         factory.NewSubnetsClient(),
+	}, nil
+}
+
+// Exists checks if the resource exists.
+func (c *azureResourceClient) Exists(ctx context.Context, resource Resource) (bool, error) {
+	var err error
+	switch resource.Kind {
+	case ResourceKindNetworkSecurityGroup:
+		_, err = c.securityGroups.Get(ctx, c.resourceGroup, resource.ID, nil)
+	case ResourceKindLoadBalancer:
+		_, err = c.loadBalancers.Get(ctx, c.resourceGroup, resource.ID, nil)
+	case ResourceKindVirtualNetwork:
+		id, parseErr := arm.ParseResourceID(resource.ID)
+		if parseErr != nil {
+			return false, fmt.Errorf("parsing resource ID: %w", parseErr)
+		}
+		_, err = c.virtualNetworks.Get(ctx, id.ResourceGroupName, id.Name, nil)
+	case ResourceKindSubnet:
+		id, parseErr := arm.ParseResourceID(resource.ID)
+		if parseErr != nil {
+			return false, fmt.Errorf("parsing resource ID: %w", parseErr)
+		}
+		_, err = c.subnets.Get(ctx, id.ResourceGroupName, id.Parent.Name, id.Name, nil)
+	default:
+		return false, fmt.Errorf("unsupported resource kind %q", resource.Kind)
+	}
+
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// IsOwnedBy checks if the resource is tagged with the given cluster UID.
+// Subnets can't be tagged, so the tag of their virtual network is checked.
+func (c *azureResourceClient) IsOwnedBy(ctx context.Context, resource Resource, uid string) (bool, error) {
+	if uid == "" {
+		return false, nil
+	}
+	var tags map[string]*string
+	switch resource.Kind {
+	case ResourceKindNetworkSecurityGroup:
+		resp, err := c.securityGroups.Get(ctx, c.resourceGroup, resource.ID, nil)
+		if err != nil {
+			return false, err
+		}
+		tags = resp.Tags
+	case ResourceKindLoadBalancer:
+		resp, err := c.loadBalancers.Get(ctx, c.resourceGroup, resource.ID, nil)
+		if err != nil {
+			return false, err
+		}
+		tags = resp.Tags
+	case ResourceKindVirtualNetwork, ResourceKindSubnet:
+		id, err := arm.ParseResourceID(resource.ID)
+		if err != nil {
+			return false, fmt.Errorf("parsing resource ID: %w", err)
+		}
+		networkName := id.Name
+		if resource.Kind == ResourceKindSubnet {
+			networkName = id.Parent.Name
+		}
+		resp, err := c.virtualNetworks.Get(ctx, id.ResourceGroupName, networkName, nil)
+		if err != nil {
+			return false, err
+		}
+		tags = resp.Tags
+	default:
+		return false, fmt.Errorf("unsupported resource kind %q", resource.Kind)
+	}
+	owner, ok := tags[constellationUIDTag]
+	return ok && owner != nil && *owner == uid, nil
+}
+
+// Delete deletes the resource and waits for the deletion to finish.
+func (c *azureResourceClient) Delete(ctx context.Context, resource Resource) error {
+	switch resource.Kind {
+	case ResourceKindNetworkSecurityGroup:
+		poller, err := c.securityGroups.BeginDelete(ctx, c.resourceGroup, resource.ID, nil)
+		if err != nil {
+			return err
+		}
+		_, err = poller.PollUntilDone(ctx, nil)
+		return err
+	case ResourceKindLoadBalancer:
+		poller, err := c.loadBalancers.BeginDelete(ctx, c.resourceGroup, resource.ID, nil)
+		if err != nil {
+			return err
+		}
+		_, err = poller.PollUntilDone(ctx, nil)
+		return err
+	case ResourceKindVirtualNetwork:
+		id, err := arm.ParseResourceID(resource.ID)
+		if err != nil {
+			return fmt.Errorf("parsing resource ID: %w", err)
+		}
+		poller, err := c.virtualNetworks.BeginDelete(ctx, id.ResourceGroupName, id.Name, nil)
+		if err != nil {
+			return err
+		}
+		_, err = poller.PollUntilDone(ctx, nil)
+		return err
+	case ResourceKindSubnet:
+		id, err := arm.ParseResourceID(resource.ID)
+		if err != nil {
+			return fmt.Errorf("parsing resource ID: %w", err)
+		}
+		poller, err := c.subnets.BeginDelete(ctx, id.ResourceGroupName, id.Parent.Name, id.Name, nil)
+		if err != nil {
+			return err
+		}
+		_, err = poller.PollUntilDone(ctx, nil)
+		return err
+	default:
+		return fmt.Errorf("unsupported resource kind %q", resource.Kind)
+	}
+}
+
+// gcpResourceClient checks for and deletes the network resources of a cluster on GCP.
+type gcpResourceClient struct {
+	networks    *compute.NetworksClient
+	subnetworks *compute.SubnetworksClient
+}
+
+func newGCPResourceClient(ctx context.Context) (*gcpResourceClient, func(), error) {
+	networks, err := compute.NewNetworksRESTClient(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating GCP networks client: %w", err)
+	}
+	subnetworks, err := compute.NewSubnetworksRESTClient(ctx)
+	if err != nil {
+		_ = networks.Close()
+		return nil, nil, fmt.Errorf("creating GCP subnetworks client: %w", err)
+	}
+	closeClients := func() {
+		_ = networks.Close()
+		_ = subnetworks.Close()
+	}
+	return &gcpResourceClient{networks: networks, subnetworks: subnetworks}, closeClients, nil
+}
+
+// Exists checks if the resource exists.
+func (c *gcpResourceClient) Exists(ctx context.Context, resource Resource) (bool, error) {
+	id, err := parseGCPResourceID(resource)
+	if err != nil {
+		return false, err
+	}
+	switch resource.Kind {
+	case ResourceKindVirtualNetwork:
+		_, err = c.networks.Get(ctx, &computepb.GetNetworkRequest{Project: id.project, Network: id.name})
+	case ResourceKindSubnet:
+		_, err = c.subnetworks.Get(ctx, &computepb.GetSubnetworkRequest{Project: id.project, Region: id.region, Subnetwork: id.name})
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// IsOwnedBy checks if the resource is labeled with the given cluster UID.
+// Networks and subnetworks can't be labeled, so the label is part of their description, e.g., "Constellation VPC network constellation-uid=UID".
+// Resources without the label, e.g., of clusters created before the label was added, aren't owned.
+func (c *gcpResourceClient) IsOwnedBy(ctx context.Context, resource Resource, uid string) (bool, error) {
+	if uid == "" {
+		return false, nil
+	}
+	id, err := parseGCPResourceID(resource)
+	if err != nil {
+		return false, err
+	}
+	var description string
+	switch resource.Kind {
+	case ResourceKindVirtualNetwork:
+		network, err := c.networks.Get(ctx, &computepb.GetNetworkRequest{Project: id.project, Network: id.name})
+		if err != nil {
+			return false, err
+		}
+		description = network.GetDescription()
+	case ResourceKindSubnet:
+		subnetwork, err := c.subnetworks.Get(ctx, &computepb.GetSubnetworkRequest{Project: id.project, Region: id.region, Subnetwork: id.name})
+		if err != nil {
+			return false, err
+		}
+		description = subnetwork.GetDescription()
+	}
+	owner, ok := gcpDescriptionLabel(description, constellationUIDTag)
+	return ok && owner == uid, nil
+}
+
+// Delete deletes the resource and waits for the deletion to finish.
+func (c *gcpResourceClient) Delete(ctx context.Context, resource Resource) error {
+	id, err := parseGCPResourceID(resource)
+	if err != nil {
+		return err
+	}
+	var op *compute.Operation
+	switch resource.Kind {
+	case ResourceKindVirtualNetwork:
+		op, err = c.networks.Delete(ctx, &computepb.DeleteNetworkRequest{Project: id.project, Network: id.name})
+	case ResourceKindSubnet:
+		op, err = c.subnetworks.Delete(ctx, &computepb.DeleteSubnetworkRequest{Project: id.project, Region: id.region, Subnetwork: id.name})
+	}
+	if err != nil {
+		return err
+	}
+	return op.Wait(ctx)
+}
+
+// gcpDescriptionLabel returns the value of the label with the given key from a description
+// containing space separated "KEY=VALUE" labels.
+func gcpDescriptionLabel(description, key string) (string, bool) {
+	for _, field := range strings.Fields(description) {
+		if k, v, ok := strings.Cut(field, "="); ok && k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+type gcpResourceID struct {
+	project string
+	region  string
+	name    string
+}
+
+// parseGCPResourceID parses the ID of a network ("projects/PROJECT/global/networks/NAME")
+// or a subnetwork ("projects/PROJECT/regions/REGION/subnetworks/NAME").
+func parseGCPResourceID(resource Resource) (gcpResourceID, error) {
+	parts := strings.Split(resource.ID, "/")
+	switch {
+	case resource.Kind == ResourceKindVirtualNetwork &&
+		len(parts) == 5 && parts[0] == "projects" && parts[2] == "global" && parts[3] == "networks":
+		return gcpResourceID{project: parts[1], name: parts[4]}, nil
+	case resource.Kind == ResourceKindSubnet &&
+		len(parts) == 6 && parts[0] == "projects" && parts[2] == "regions" && parts[4] == "subnetworks":
+		return gcpResourceID{project: parts[1], region: parts[3], name: parts[5]}, nil
+	default:
+		return gcpResourceID{}, fmt.Errorf("invalid ID %q of %s", resource.ID, resource.Kind)
+	}
+}
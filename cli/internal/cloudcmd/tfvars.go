@@ -94,6 +94,7 @@ func awsTerraformVars(conf *config.Config, imageRef string) *terraform.AWSCluste
 
 	return &terraform.AWSClusterVariables{
 		Name:                   conf.Name,
+		NameTemplate:           conf.NameTemplate,
 		NodeGroups:             nodeGroups,
 		Region:                 conf.Provider.AWS.Region,
 		Zone:                   conf.Provider.AWS.Zone,
@@ -105,6 +106,7 @@ func awsTerraformVars(conf *config.Config, imageRef string) *terraform.AWSCluste
 		CustomEndpoint:         conf.CustomEndpoint,
 		InternalLoadBalancer:   conf.InternalLoadBalancer,
 		AdditionalTags:         conf.Tags,
+		UserData:               conf.UserData,
 	}
 }
 
@@ -149,6 +151,7 @@ func azureTerraformVars(conf *config.Config, imageRef string) (*terraform.AzureC
 	vars := &terraform.AzureClusterVariables{
 		SubscriptionID:       conf.Provider.Azure.SubscriptionID,
 		Name:                 conf.Name,
+		NameTemplate:         conf.NameTemplate,
 		NodeGroups:           nodeGroups,
 		Location:             conf.Provider.Azure.Location,
 		CreateMAA:            toPtr(conf.GetAttestationConfig().GetVariant().Equal(variant.AzureSEVSNP{})),
@@ -161,6 +164,10 @@ func azureTerraformVars(conf *config.Config, imageRef string) (*terraform.AzureC
 		InternalLoadBalancer: conf.InternalLoadBalancer,
 		MarketplaceImage:     nil,
 		AdditionalTags:       conf.Tags,
+		UserData:             conf.UserData,
+		VirtualNetworkID:     conf.Provider.Azure.VirtualNetworkID,
+		SubnetID:             conf.Provider.Azure.SubnetID,
+		SubnetCIDR:           conf.Provider.Azure.SubnetCIDR,
 	}
 
 	if conf.UseMarketplaceImage() {
@@ -221,6 +228,7 @@ func gcpTerraformVars(conf *config.Config, imageRef string) *terraform.GCPCluste
 
 	return &terraform.GCPClusterVariables{
 		Name:                 conf.Name,
+		NameTemplate:         conf.NameTemplate,
 		NodeGroups:           nodeGroups,
 		Project:              conf.Provider.GCP.Project,
 		Region:               conf.Provider.GCP.Region,
@@ -231,6 +239,11 @@ func gcpTerraformVars(conf *config.Config, imageRef string) *terraform.GCPCluste
 		InternalLoadBalancer: conf.InternalLoadBalancer,
 		CCTechnology:         ccTech,
 		AdditionalLabels:     conf.Tags,
+		UserData:             conf.UserData,
+		NetworkID:            conf.Provider.GCP.NetworkID,
+		SubnetworkID:         conf.Provider.GCP.SubnetworkID,
+		SubnetworkCIDR:       conf.Provider.GCP.SubnetworkCIDR,
+		DiskEncryptionKey:    conf.Provider.GCP.DiskEncryptionKey,
 	}
 }
 
@@ -276,6 +289,7 @@ func openStackTerraformVars(conf *config.Config, imageRef string) (*terraform.Op
 
 	return &terraform.OpenStackClusterVariables{
 		Name:                    conf.Name,
+		NameTemplate:            conf.NameTemplate,
 		Cloud:                   toPtr(conf.Provider.OpenStack.Cloud),
 		OpenStackCloudsYAMLPath: conf.Provider.OpenStack.CloudsYAMLPath,
 		FloatingIPPoolID:        conf.Provider.OpenStack.FloatingIPPoolID,
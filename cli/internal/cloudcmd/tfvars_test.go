@@ -7,10 +7,14 @@ SPDX-License-Identifier: AGPL-3.0-only
 package cloudcmd
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/edgelesssys/constellation/v2/cli/internal/terraform"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNormalizeAzureURIs(t *testing.T) {
@@ -66,3 +70,142 @@ func TestNormalizeAzureURIs(t *testing.T) {
 		})
 	}
 }
+
+func TestTerraformVarsUserData(t *testing.T) {
+	const userData = "#!/bin/sh\nsystemctl start monitoring-agent\n"
+
+	testCases := map[string]struct {
+		provider cloudprovider.Provider
+		getVars  func(conf *config.Config) (terraform.Variables, string, error)
+	}{
+		"aws": {
+			provider: cloudprovider.AWS,
+			getVars: func(conf *config.Config) (terraform.Variables, string, error) {
+				vars := awsTerraformVars(conf, "ami-123")
+				return vars, vars.UserData, nil
+			},
+		},
+		"azure": {
+			provider: cloudprovider.Azure,
+			getVars: func(conf *config.Config) (terraform.Variables, string, error) {
+				vars, err := azureTerraformVars(conf, "/communityGalleries/foo/images/constellation/versions/2.1.0")
+				if err != nil {
+					return nil, "", err
+				}
+				return vars, vars.UserData, nil
+			},
+		},
+		"gcp": {
+			provider: cloudprovider.GCP,
+			getVars: func(conf *config.Config) (terraform.Variables, string, error) {
+				vars := gcpTerraformVars(conf, "projects/constellation-images/global/images/test")
+				return vars, vars.UserData, nil
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			conf := config.Default()
+			conf.RemoveProviderAndAttestationExcept(tc.provider)
+			conf.UserData = userData
+
+			vars, got, err := tc.getVars(conf)
+			require.NoError(err)
+			assert.Equal(userData, got)
+			assert.Contains(vars.String(), `"#!/bin/sh\nsystemctl start monitoring-agent\n"`)
+		})
+	}
+}
+
+func TestTerraformVarsExistingNetwork(t *testing.T) {
+	testCases := map[string]struct {
+		provider  cloudprovider.Provider
+		configure func(conf *config.Config)
+		wantVars  []string
+	}{
+		"azure": {
+			provider: cloudprovider.Azure,
+			configure: func(conf *config.Config) {
+				conf.Provider.Azure.VirtualNetworkID = "/subscriptions/0123/resourceGroups/network/providers/Microsoft.Network/virtualNetworks/enterprise"
+				conf.Provider.Azure.SubnetID = "/subscriptions/0123/resourceGroups/network/providers/Microsoft.Network/virtualNetworks/enterprise/subnets/nodes"
+				conf.Provider.Azure.SubnetCIDR = "10.9.0.0/16"
+			},
+			wantVars: []string{
+				`virtual_network_id = "/subscriptions/0123/resourceGroups/network/providers/Microsoft.Network/virtualNetworks/enterprise"`,
+				`subnet_id = "/subscriptions/0123/resourceGroups/network/providers/Microsoft.Network/virtualNetworks/enterprise/subnets/nodes"`,
+				`subnet_cidr = "10.9.0.0/16"`,
+			},
+		},
+		"gcp": {
+			provider: cloudprovider.GCP,
+			configure: func(conf *config.Config) {
+				conf.Provider.GCP.NetworkID = "projects/host-project/global/networks/enterprise"
+				conf.Provider.GCP.SubnetworkID = "projects/host-project/regions/europe-west3/subnetworks/nodes"
+				conf.Provider.GCP.SubnetworkCIDR = "192.168.178.0/24"
+			},
+			wantVars: []string{
+				`network_id = "projects/host-project/global/networks/enterprise"`,
+				`subnetwork_id = "projects/host-project/regions/europe-west3/subnetworks/nodes"`,
+				`subnetwork_cidr = "192.168.178.0/24"`,
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			conf := config.Default()
+			conf.RemoveProviderAndAttestationExcept(tc.provider)
+			tc.configure(conf)
+
+			var vars terraform.Variables
+			switch tc.provider {
+			case cloudprovider.Azure:
+				azureVars, err := azureTerraformVars(conf, "/communityGalleries/foo/images/constellation/versions/2.1.0")
+				require.NoError(err)
+				vars = azureVars
+			case cloudprovider.GCP:
+				vars = gcpTerraformVars(conf, "projects/constellation-images/global/images/test")
+			}
+
+			// alignment depends on the other variables, so ignore whitespace differences
+			got := strings.Join(strings.Fields(vars.String()), " ")
+			for _, want := range tc.wantVars {
+				assert.Contains(got, want)
+			}
+		})
+	}
+}
+
+func TestTerraformVarsGCPDiskEncryptionKey(t *testing.T) {
+	testCases := map[string]struct {
+		key     string
+		wantVar string
+	}{
+		"customer-managed key": {
+			key:     "projects/my-project/locations/europe-west3/keyRings/constellation/cryptoKeys/disks",
+			wantVar: `disk_encryption_key = "projects/my-project/locations/europe-west3/keyRings/constellation/cryptoKeys/disks"`,
+		},
+		"google-managed key": {
+			wantVar: `disk_encryption_key = ""`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			conf := config.Default()
+			conf.RemoveProviderAndAttestationExcept(cloudprovider.GCP)
+			conf.Provider.GCP.DiskEncryptionKey = tc.key
+
+			vars := gcpTerraformVars(conf, "projects/constellation-images/global/images/test")
+			// alignment depends on the other variables, so ignore whitespace differences
+			assert.Contains(t, strings.Join(strings.Fields(vars.String()), " "), tc.wantVar)
+		})
+	}
+}
@@ -15,8 +15,11 @@ import (
 	"io/fs"
 	"log/slog"
 	"net"
+	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
 	"slices"
 	"strings"
 	"time"
@@ -25,12 +28,14 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/api/attestationconfigapi"
 	"github.com/edgelesssys/constellation/v2/internal/api/versionsapi"
 	"github.com/edgelesssys/constellation/v2/internal/atls"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/measurements"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/variant"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
 	"github.com/edgelesssys/constellation/v2/internal/compatibility"
 	"github.com/edgelesssys/constellation/v2/internal/config"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/constellation"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/featureset"
 	"github.com/edgelesssys/constellation/v2/internal/constellation/helm"
 	"github.com/edgelesssys/constellation/v2/internal/constellation/kubecmd"
 	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
@@ -39,18 +44,21 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/imagefetcher"
 	"github.com/edgelesssys/constellation/v2/internal/kms/uri"
 	"github.com/edgelesssys/constellation/v2/internal/semver"
+	"github.com/edgelesssys/constellation/v2/internal/sigstore"
 	"github.com/edgelesssys/constellation/v2/internal/versions"
 	slogmulti "github.com/samber/slog-multi"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	xsemver "golang.org/x/mod/semver"
+	networkingv1 "k8s.io/api/networking/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
 )
 
 // phases that can be skipped during apply.
-// New phases should also be added to [allPhases].
+// New phases should also be registered in [newApplyPhaseRegistry].
 const (
 	// skipInfrastructurePhase skips the Terraform apply of the apply process.
 	skipInfrastructurePhase skipPhase = "infrastructure"
@@ -70,23 +78,8 @@ const (
 
 // allPhases returns a list of all phases that can be skipped as strings.
 func allPhases(except ...skipPhase) []string {
-	phases := []string{
-		string(skipInfrastructurePhase),
-		string(skipInitPhase),
-		string(skipAttestationConfigPhase),
-		string(skipCertSANsPhase),
-		string(skipHelmPhase),
-		string(skipImagePhase),
-		string(skipK8sPhase),
-	}
-
-	var returnedPhases []string
-	for idx, phase := range phases {
-		if !slices.Contains(except, skipPhase(phase)) {
-			returnedPhases = append(returnedPhases, phases[idx])
-		}
-	}
-	return returnedPhases
+	// the registry is only used for its phase names, so the phases don't need an applyCmd
+	return newApplyPhaseRegistry(nil).names(except...)
 }
 
 // formatSkipPhases returns a formatted string of all phases that can be skipped.
@@ -129,7 +122,13 @@ func NewApplyCmd() *cobra.Command {
 		Args:  cobra.NoArgs,
 		RunE:  runApply,
 	}
+	registerApplyFlags(cmd)
+	return cmd
+}
 
+// registerApplyFlags registers the flags read by runApply on cmd.
+// It is shared by the apply command and its deprecated "upgrade apply" alias, so both accept the same flags.
+func registerApplyFlags(cmd *cobra.Command) {
 	cmd.Flags().Bool("conformance", false, "enable conformance mode")
 	cmd.Flags().Bool("skip-helm-wait", false, "install helm charts without waiting for deployments to be ready")
 	cmd.Flags().Bool("merge-kubeconfig", false, "merge Constellation kubeconfig file with default kubeconfig file in $HOME/.kube/config")
@@ -137,24 +136,163 @@ func NewApplyCmd() *cobra.Command {
 		"WARNING: the command might delete or update existing resources without additional checks. Please read the docs.\n")
 	cmd.Flags().Duration("helm-timeout", 10*time.Minute, "change helm install/upgrade timeout\n"+
 		"Might be useful for slow connections or big clusters.")
+	cmd.Flags().Duration("helm-atomic-timeout", 0, "change timeout for atomic helm installs/upgrades, including the rollback on failure\n"+
+		"Defaults to the value of --helm-timeout.")
+	cmd.Flags().Int("helm-history-max", 10, "maximum number of revisions kept per Helm release on upgrades\n"+
+		"Older revisions are pruned. Use 0 for no limit.")
+	cmd.Flags().String("helm-namespace", "", "namespace the Helm charts of the system components are installed to\n"+
+		"The namespace is recorded in the state file when the cluster is initialized and can't be changed afterwards. Defaults to "+constants.HelmNamespace+".")
 	cmd.Flags().StringSlice("skip-phases", nil, "comma-separated list of upgrade phases to skip\n"+
 		fmt.Sprintf("one or multiple of %s", formatSkipPhases()))
-
+	cmd.Flags().String("post-hook", "", "command to run after a successful apply\n"+
+		"The cluster endpoint, UID, and kubeconfig path are passed as environment variables "+
+		envVarHookClusterEndpoint+", "+envVarHookClusterUID+", and "+envVarHookKubeconfig+".")
+	cmd.Flags().Bool("post-hook-always", false, "run the post-hook even if apply failed")
+	cmd.Flags().Duration("init-timeout", 0, "maximum duration of the init phase, including the retries while the first node is still booting\n"+
+		"If not set, the init phase is only bounded by the overall timeout of apply.")
+	cmd.Flags().Duration("init-retry-interval", constellation.DefaultInitRetry.Interval, "time to wait before retrying to connect to the first node while it is still booting\n"+
+		"The wait time doubles with every retry, up to "+constellation.DefaultInitRetry.MaxInterval.String()+".")
+	cmd.Flags().Bool("watch-events", false, "stream Kubernetes events, like pod scheduling and image pulls, while the cluster is initialized")
+	cmd.Flags().String("master-secret-in", "", "initialize the cluster with the master secret from the given file instead of generating a new one\n"+
+		"WARNING: anyone with access to the master secret can decrypt the cluster's state. Only import secrets that were generated securely and aren't used by another cluster.")
+	cmd.Flags().Bool("no-backup", false, "skip the backup of Helm charts, CRDs, and CRs before upgrading\n"+
+		"WARNING: rolling back a failed upgrade won't be possible. Only use this for throwaway clusters.")
+	cmd.Flags().Bool("no-upgrade-image", false, "fail instead of changing the node image, which replaces all nodes of the cluster\n"+
+		"Unlike skipping the image phase, apply fails if the configured image differs from the image of the cluster.")
+	cmd.Flags().StringSlice("target-groups", nil, "comma-separated list of node groups to upgrade the node image of\n"+
+		"The other node groups keep their image. The Kubernetes phase is skipped, since Kubernetes can only be upgraded for the whole cluster.")
+	cmd.Flags().Duration("drain-grace-period", 0, "time pods get to terminate when their node is drained to be replaced by an image or Kubernetes upgrade\n"+
+		"Evictions blocked by a PodDisruptionBudget are retried until the drain timeout. If not set, pods get their own termination grace period.")
+	cmd.Flags().Duration("drain-timeout", 0, "time a node gets to drain before pods blocked by a PodDisruptionBudget are deleted and the node is replaced anyway\n"+
+		"If not set, draining doesn't time out and waits for PodDisruptionBudgets.")
+	cmd.Flags().Bool("print-resource-ids", false, "print the identifiers of the cluster's cloud resources as JSON after the infrastructure phase\n"+
+		"The identifiers are taken from the state file, e.g., the resource group, load balancer, and network security group on Azure.")
+	cmd.Flags().Bool("detect-and-adopt", false, "adopt the cloud resources a previous, failed apply created for the cluster instead of creating them again\n"+
+		"Only used if no Terraform state exists. Resources are found by the naming scheme of the cluster's resources.")
+	cmd.Flags().Bool("pre-pull-images", false, "pull the container images of the Helm charts on all nodes before installing or upgrading the charts\n"+
+		"Shortens the time Kubernetes components are unavailable on clusters with slow registry access.")
+
+	cmd.Flags().Duration("lock-timeout", 0, "time to wait for the state lock held by another apply to be released")
+	cmd.Flags().Bool("force-unlock", false, "remove a stale state lock left behind by a crashed apply\n"+
+		"Locks of applies that are still running are never removed.")
+	cmd.Flags().String("dump-state-on-error", "", "write the in-memory state to the given file if apply fails\n"+
+		"Secrets are redacted unless --dump-state-full is set. Defaults to "+constants.StateDumpFilename+" if no file is given.\n"+
+		"A file must be given as --dump-state-on-error=<file>, since the value is optional.")
+	cmd.Flags().Lookup("dump-state-on-error").NoOptDefVal = constants.StateDumpFilename
+	cmd.Flags().Bool("dump-state-full", false, "include secrets in the state written by --dump-state-on-error")
+	cmd.Flags().Bool("reconcile", false, "only run the phases whose inputs changed since their last successful apply\n"+
+		"Unchanged phases are skipped, in addition to the phases set by --skip-phases.")
+	cmd.Flags().String("since-state", "", "only run the phases whose inputs changed since the apply that wrote the given state file\n"+
+		"Like --reconcile, but compares with the phase fingerprints of the given baseline instead of the current state file.")
+	cmd.Flags().StringP("output", "o", "", "stream progress events in the output format {ndjson}\n"+
+		"Events are written to stdout, all other output is written to stderr.")
+	cmd.Flags().Bool("compare-measurements-source", false, "compare the measurements in the config with the signed measurements published for the configured image before using them\n"+
+		"Fails if they differ, unless --force is set.")
+	cmd.Flags().Bool("quiet", false, "only print warnings, errors, and the result of the run\n"+
+		"Without --yes, confirmation prompts are still shown together with the output leading up to them. Can't be used together with --debug.")
+	cmd.Flags().Int("max-retries-per-phase", 0, "retry failed phases up to the given number of times before giving up\n"+
+		"The init phase is never retried.")
+	cmd.Flags().StringToInt("phase-retries", nil, "override --max-retries-per-phase for single phases, passed as PHASE=RETRIES, e.g. helm=3")
+	cmd.Flags().Bool("show-plan-graph", false, "print the phases that would run, the skipped phases, and their dependencies, then exit without applying")
+	cmd.Flags().String("graph-format", planGraphFormatText, "format of the graph printed by --show-plan-graph {text|dot}")
+	cmd.Flags().Bool("fail-fast", true, "stop at the first failed phase\n"+
+		"If set to false, phases that don't depend on a failed phase still run, and all failures are reported at the end.")
+	cmd.Flags().Bool("pause-before-cluster-delete", true, "ask for confirmation before applying config changes that delete and recreate the cluster, like changing the attestation variant\n"+
+		"Without --force, such changes are rejected if confirmations are skipped with --yes.")
+	cmd.Flags().String("clusters", "", "apply the clusters in all subdirectories of the given directory that contain a config and a state file\n"+
+		"Each cluster is applied in a process of its own with the other flags of this command. Requires --yes.")
+	cmd.Flags().Int("max-parallel-clusters", defaultMaxParallelClusters, "maximum number of clusters applied at the same time with --clusters")
+	must(cmd.MarkFlagDirname("clusters"))
 	must(cmd.Flags().MarkHidden("helm-timeout"))
+	must(cmd.Flags().MarkHidden("helm-atomic-timeout"))
 
 	must(cmd.RegisterFlagCompletionFunc("skip-phases", skipPhasesCompletion))
-	return cmd
 }
 
 // applyFlags defines the flags for the apply command.
 type applyFlags struct {
 	rootFlags
-	yes          bool
-	conformance  bool
-	mergeConfigs bool
-	helmTimeout  time.Duration
-	helmWaitMode helm.WaitMode
-	skipPhases   skipPhases
+	yes               bool
+	conformance       bool
+	mergeConfigs      bool
+	helmTimeout       time.Duration
+	helmAtomicTimeout time.Duration
+	helmHistoryMax    int
+	helmNamespace     string
+	helmWaitMode      helm.WaitMode
+	skipPhases        skipPhases
+	postHook          string
+	postHookAlways    bool
+	watchEvents       bool
+	masterSecretIn    string
+	initRetry         constellation.InitRetry
+	noBackup          bool
+	noUpgradeImage    bool
+	targetGroups      []string
+	drainGracePeriod  time.Duration
+	drainTimeout      time.Duration
+	prePullImages     bool
+	printResourceIDs  bool
+	detectAndAdopt    bool
+	lockTimeout       time.Duration
+	forceUnlock       bool
+	dumpStatePath     string
+	dumpStateFull     bool
+	output            string
+	reconcile         bool
+	// sinceState is the path of the baseline state file the changed phases are determined against.
+	sinceState string
+	verbosity  applyVerbosity
+	// compareMeasurements compares the measurements of the config with the signed upstream measurements.
+	compareMeasurements bool
+	retries             phaseRetries
+	showPlanGraph       bool
+	graphFormat         string
+	// continueOnError runs the phases that don't depend on a failed phase, instead of stopping at the first failure.
+	continueOnError bool
+	// allowClusterRecreation applies changes that recreate the cluster without pausing for confirmation.
+	allowClusterRecreation bool
+}
+
+// phaseFlags are the flags that only affect the given phases.
+// Setting them while all of their phases are skipped is rejected, since they would have no effect.
+var phaseFlags = []struct {
+	flag   string
+	phases []skipPhase
+}{
+	{flag: "skip-helm-wait", phases: []skipPhase{skipHelmPhase}},
+	{flag: "helm-timeout", phases: []skipPhase{skipHelmPhase}},
+	{flag: "helm-atomic-timeout", phases: []skipPhase{skipHelmPhase}},
+	{flag: "helm-history-max", phases: []skipPhase{skipHelmPhase}},
+	{flag: "helm-namespace", phases: []skipPhase{skipInitPhase, skipHelmPhase}},
+	{flag: "no-backup", phases: []skipPhase{skipHelmPhase}},
+	{flag: "pre-pull-images", phases: []skipPhase{skipHelmPhase}},
+	{flag: "no-upgrade-image", phases: []skipPhase{skipImagePhase}},
+	{flag: "target-groups", phases: []skipPhase{skipImagePhase}},
+	{flag: "drain-grace-period", phases: []skipPhase{skipImagePhase, skipK8sPhase}},
+	{flag: "drain-timeout", phases: []skipPhase{skipImagePhase, skipK8sPhase}},
+	{flag: "print-resource-ids", phases: []skipPhase{skipInfrastructurePhase}},
+	{flag: "detect-and-adopt", phases: []skipPhase{skipInfrastructurePhase}},
+	{flag: "conformance", phases: []skipPhase{skipInitPhase, skipHelmPhase}},
+	{flag: "merge-kubeconfig", phases: []skipPhase{skipInitPhase}},
+	{flag: "watch-events", phases: []skipPhase{skipInitPhase}},
+	{flag: "master-secret-in", phases: []skipPhase{skipInitPhase}},
+	{flag: "init-timeout", phases: []skipPhase{skipInitPhase}},
+	{flag: "init-retry-interval", phases: []skipPhase{skipInitPhase}},
+}
+
+// validatePhaseFlags checks that no flag is set whose phases are all skipped.
+func validatePhaseFlags(flags *pflag.FlagSet, skipPhases skipPhases) error {
+	for _, phaseFlag := range phaseFlags {
+		if flags.Changed(phaseFlag.flag) && skipPhases.contains(phaseFlag.phases...) {
+			phases := make([]string, 0, len(phaseFlag.phases))
+			for _, phase := range phaseFlag.phases {
+				phases = append(phases, string(phase))
+			}
+			return fmt.Errorf("--%s has no effect if the %s phase is skipped", phaseFlag.flag, strings.Join(phases, " and "))
+		}
+	}
+	return nil
 }
 
 // parse the apply command flags.
@@ -177,6 +315,9 @@ func (f *applyFlags) parse(flags *pflag.FlagSet) error {
 		}
 	}
 	f.skipPhases = skipPhases
+	if err := validatePhaseFlags(flags, skipPhases); err != nil {
+		return err
+	}
 
 	f.yes, err = flags.GetBool("yes")
 	if err != nil {
@@ -188,6 +329,32 @@ func (f *applyFlags) parse(flags *pflag.FlagSet) error {
 		return fmt.Errorf("getting 'helm-timeout' flag: %w", err)
 	}
 
+	f.helmAtomicTimeout, err = flags.GetDuration("helm-atomic-timeout")
+	if err != nil {
+		return fmt.Errorf("getting 'helm-atomic-timeout' flag: %w", err)
+	}
+	if f.helmAtomicTimeout == 0 {
+		f.helmAtomicTimeout = f.helmTimeout
+	}
+
+	f.helmHistoryMax, err = flags.GetInt("helm-history-max")
+	if err != nil {
+		return fmt.Errorf("getting 'helm-history-max' flag: %w", err)
+	}
+	if f.helmHistoryMax < 0 {
+		return fmt.Errorf("invalid value %d for 'helm-history-max': must not be negative", f.helmHistoryMax)
+	}
+
+	f.helmNamespace, err = flags.GetString("helm-namespace")
+	if err != nil {
+		return fmt.Errorf("getting 'helm-namespace' flag: %w", err)
+	}
+	if f.helmNamespace != "" {
+		if errs := k8svalidation.IsDNS1123Label(f.helmNamespace); len(errs) > 0 {
+			return fmt.Errorf("invalid value %q for 'helm-namespace': %s", f.helmNamespace, strings.Join(errs, "; "))
+		}
+	}
+
 	f.conformance, err = flags.GetBool("conformance")
 	if err != nil {
 		return fmt.Errorf("getting 'conformance' flag: %w", err)
@@ -206,6 +373,196 @@ func (f *applyFlags) parse(flags *pflag.FlagSet) error {
 	if err != nil {
 		return fmt.Errorf("getting 'merge-kubeconfig' flag: %w", err)
 	}
+
+	f.postHook, err = flags.GetString("post-hook")
+	if err != nil {
+		return fmt.Errorf("getting 'post-hook' flag: %w", err)
+	}
+
+	f.postHookAlways, err = flags.GetBool("post-hook-always")
+	if err != nil {
+		return fmt.Errorf("getting 'post-hook-always' flag: %w", err)
+	}
+
+	f.watchEvents, err = flags.GetBool("watch-events")
+	if err != nil {
+		return fmt.Errorf("getting 'watch-events' flag: %w", err)
+	}
+
+	f.masterSecretIn, err = flags.GetString("master-secret-in")
+	if err != nil {
+		return fmt.Errorf("getting 'master-secret-in' flag: %w", err)
+	}
+
+	f.initRetry.Timeout, err = flags.GetDuration("init-timeout")
+	if err != nil {
+		return fmt.Errorf("getting 'init-timeout' flag: %w", err)
+	}
+	f.initRetry.Interval, err = flags.GetDuration("init-retry-interval")
+	if err != nil {
+		return fmt.Errorf("getting 'init-retry-interval' flag: %w", err)
+	}
+	if f.initRetry.Timeout < 0 || f.initRetry.Interval <= 0 {
+		return errors.New("flags 'init-timeout' and 'init-retry-interval' must be positive")
+	}
+	f.initRetry.MaxInterval = max(f.initRetry.Interval, constellation.DefaultInitRetry.MaxInterval)
+
+	f.noBackup, err = flags.GetBool("no-backup")
+	if err != nil {
+		return fmt.Errorf("getting 'no-backup' flag: %w", err)
+	}
+
+	f.noUpgradeImage, err = flags.GetBool("no-upgrade-image")
+	if err != nil {
+		return fmt.Errorf("getting 'no-upgrade-image' flag: %w", err)
+	}
+
+	targetGroups, err := flags.GetStringSlice("target-groups")
+	if err != nil {
+		return fmt.Errorf("getting 'target-groups' flag: %w", err)
+	}
+	if len(targetGroups) > 0 {
+		if f.noUpgradeImage {
+			return errors.New("--target-groups and --no-upgrade-image are mutually exclusive")
+		}
+		f.targetGroups = targetGroups
+	}
+
+	f.drainGracePeriod, err = flags.GetDuration("drain-grace-period")
+	if err != nil {
+		return fmt.Errorf("getting 'drain-grace-period' flag: %w", err)
+	}
+	if f.drainGracePeriod < 0 || f.drainGracePeriod%time.Second != 0 {
+		return fmt.Errorf("invalid value %s for 'drain-grace-period': must be a non-negative number of whole seconds", f.drainGracePeriod)
+	}
+
+	f.drainTimeout, err = flags.GetDuration("drain-timeout")
+	if err != nil {
+		return fmt.Errorf("getting 'drain-timeout' flag: %w", err)
+	}
+	if f.drainTimeout < 0 || f.drainTimeout%time.Second != 0 {
+		return fmt.Errorf("invalid value %s for 'drain-timeout': must be a non-negative number of whole seconds", f.drainTimeout)
+	}
+
+	f.prePullImages, err = flags.GetBool("pre-pull-images")
+	if err != nil {
+		return fmt.Errorf("getting 'pre-pull-images' flag: %w", err)
+	}
+
+	f.printResourceIDs, err = flags.GetBool("print-resource-ids")
+	if err != nil {
+		return fmt.Errorf("getting 'print-resource-ids' flag: %w", err)
+	}
+
+	f.detectAndAdopt, err = flags.GetBool("detect-and-adopt")
+	if err != nil {
+		return fmt.Errorf("getting 'detect-and-adopt' flag: %w", err)
+	}
+
+	f.lockTimeout, err = flags.GetDuration("lock-timeout")
+	if err != nil {
+		return fmt.Errorf("getting 'lock-timeout' flag: %w", err)
+	}
+
+	f.forceUnlock, err = flags.GetBool("force-unlock")
+	if err != nil {
+		return fmt.Errorf("getting 'force-unlock' flag: %w", err)
+	}
+
+	f.dumpStatePath, err = flags.GetString("dump-state-on-error")
+	if err != nil {
+		return fmt.Errorf("getting 'dump-state-on-error' flag: %w", err)
+	}
+
+	f.dumpStateFull, err = flags.GetBool("dump-state-full")
+	if err != nil {
+		return fmt.Errorf("getting 'dump-state-full' flag: %w", err)
+	}
+	if f.dumpStatePath != "" && filepath.Clean(f.dumpStatePath) == constants.StateFilename && !f.dumpStateFull {
+		return fmt.Errorf("refusing to overwrite %s with a redacted state: set --dump-state-full to dump the full state to it",
+			constants.StateFilename)
+	}
+
+	f.output, err = flags.GetString("output")
+	if err != nil {
+		return fmt.Errorf("getting 'output' flag: %w", err)
+	}
+	if f.output != "" && f.output != applyOutputNDJSON {
+		return fmt.Errorf("invalid output format %q, must be one of {%s}", f.output, applyOutputNDJSON)
+	}
+
+	f.reconcile, err = flags.GetBool("reconcile")
+	if err != nil {
+		return fmt.Errorf("getting 'reconcile' flag: %w", err)
+	}
+	f.sinceState, err = flags.GetString("since-state")
+	if err != nil {
+		return fmt.Errorf("getting 'since-state' flag: %w", err)
+	}
+	if f.reconcile && f.sinceState != "" {
+		return errors.New("flags 'reconcile' and 'since-state' can't be combined")
+	}
+
+	f.compareMeasurements, err = flags.GetBool("compare-measurements-source")
+	if err != nil {
+		return fmt.Errorf("getting 'compare-measurements-source' flag: %w", err)
+	}
+
+	maxRetries, err := flags.GetInt("max-retries-per-phase")
+	if err != nil {
+		return fmt.Errorf("getting 'max-retries-per-phase' flag: %w", err)
+	}
+	retryOverrides, err := flags.GetStringToInt("phase-retries")
+	if err != nil {
+		return fmt.Errorf("getting 'phase-retries' flag: %w", err)
+	}
+	f.retries, err = parsePhaseRetries(maxRetries, retryOverrides, skipPhases)
+	if err != nil {
+		return err
+	}
+
+	f.showPlanGraph, err = flags.GetBool("show-plan-graph")
+	if err != nil {
+		return fmt.Errorf("getting 'show-plan-graph' flag: %w", err)
+	}
+	if f.showPlanGraph {
+		f.graphFormat, err = flags.GetString("graph-format")
+		if err != nil {
+			return fmt.Errorf("getting 'graph-format' flag: %w", err)
+		}
+		if f.graphFormat != planGraphFormatText && f.graphFormat != planGraphFormatDOT {
+			return fmt.Errorf("invalid graph format %q, must be one of {%s, %s}", f.graphFormat, planGraphFormatText, planGraphFormatDOT)
+		}
+	} else if flags.Changed("graph-format") {
+		return errors.New("--graph-format has no effect without --show-plan-graph")
+	}
+
+	failFast, err := flags.GetBool("fail-fast")
+	if err != nil {
+		return fmt.Errorf("getting 'fail-fast' flag: %w", err)
+	}
+	f.continueOnError = !failFast
+
+	pauseBeforeClusterDelete, err := flags.GetBool("pause-before-cluster-delete")
+	if err != nil {
+		return fmt.Errorf("getting 'pause-before-cluster-delete' flag: %w", err)
+	}
+	f.allowClusterRecreation = !pauseBeforeClusterDelete
+
+	quiet, err := flags.GetBool("quiet")
+	if err != nil {
+		return fmt.Errorf("getting 'quiet' flag: %w", err)
+	}
+	switch {
+	case quiet && f.debug:
+		return errors.New("--quiet and --debug are mutually exclusive")
+	case quiet && f.printResourceIDs:
+		return errors.New("--quiet and --print-resource-ids are mutually exclusive")
+	case quiet:
+		f.verbosity = applyVerbosityQuiet
+	case f.debug:
+		f.verbosity = applyVerbosityDebug
+	}
 	return nil
 }
 
@@ -222,6 +579,29 @@ func runApply(cmd *cobra.Command, _ []string) error {
 		return err
 	}
 
+	if cmd.Flags().Changed("clusters") {
+		spinner.Stop()
+		log, err := newCLILogger(cmd)
+		if err != nil {
+			return fmt.Errorf("creating logger: %w", err)
+		}
+		return runMultiClusterApply(cmd, flags, log)
+	}
+
+	if flags.verbosity == applyVerbosityQuiet {
+		// Spinners and the output written through them, like Terraform logs, are progress.
+		spinner = &nopSpinner{io.Discard}
+	}
+
+	var progress progressReporter = nopProgressReporter{}
+	if flags.output == applyOutputNDJSON {
+		// Events are the only output on stdout, so every line of the stream can be parsed.
+		progress = newNDJSONProgressReporter(cmd.OutOrStdout())
+		cmd.SetOut(cmd.ErrOrStderr())
+		spinner = progressSpinner{spinnerInterf: spinner, progress: progress}
+	}
+	resultOut := setOutputVerbosity(cmd, flags.verbosity, flags.yes)
+
 	fileHandler := file.NewHandler(afero.NewOsFs())
 	debugLogger, err := newDebugFileLogger(cmd, fileHandler)
 	if err != nil {
@@ -247,6 +627,16 @@ func runApply(cmd *cobra.Command, _ []string) error {
 	}
 
 	applier := constellation.NewApplier(debugLogger, spinner, constellation.ApplyContextCLI, newDialer)
+	applier.SetInitRetry(flags.initRetry)
+
+	var measurementsFetcher verifyFetcher
+	if flags.compareMeasurements {
+		rekor, err := sigstore.NewRekor()
+		if err != nil {
+			return fmt.Errorf("constructing Rekor client: %w", err)
+		}
+		measurementsFetcher = measurements.NewVerifyFetcher(sigstore.NewCosignVerifier, rekor, http.DefaultClient)
+	}
 
 	apply := &applyCmd{
 		fileHandler:     fileHandler,
@@ -254,33 +644,84 @@ func runApply(cmd *cobra.Command, _ []string) error {
 		log:             debugLogger,
 		wLog:            &warnLogger{cmd: cmd, log: debugLogger},
 		spinner:         spinner,
+		progress:        progress,
 		merger:          &kubeconfigMerger{log: debugLogger},
 		newInfraApplier: newInfraApplier,
+		adopter:         cloudcmd.NewResourceAdopter(),
 		imageFetcher:    imagefetcher.New(),
+		channelFetcher:  versionsapi.NewFetcher(),
 		applier:         applier,
+		hookRunner:      shellHookRunner{},
+		newEventWatcher: newKubeEventWatcher,
+		kubeClientRetry: defaultKubeClientRetry,
+
+		phaseRetryInterval: defaultPhaseRetryInterval,
+		eventRetryInterval: defaultEventRetryInterval,
+
+		newMasterKeyBackend: newManagedHSMBackend,
+
+		canFetchMeasurements: featureset.CanFetchMeasurements,
+		verifyFetcher:        measurementsFetcher,
 	}
 
 	ctx, cancel := context.WithTimeout(cmd.Context(), time.Hour)
 	defer cancel()
 	cmd.SetContext(ctx)
 
-	return apply.apply(cmd, attestationconfigapi.NewFetcher(), upgradeDir)
+	applyErr := apply.applyWithStateLock(cmd, attestationconfigapi.NewFetcher(), upgradeDir)
+	err = apply.runPostHook(cmd, applyErr)
+	progress.applyFinished(err)
+	printApplyResult(resultOut, flags.verbosity, err)
+	return err
 }
 
 type applyCmd struct {
 	fileHandler file.Handler
 	flags       applyFlags
 
-	log     debugLog
-	wLog    warnLog
-	spinner spinnerInterf
+	log      debugLog
+	wLog     warnLog
+	spinner  spinnerInterf
+	progress progressReporter
 
 	merger configMerger
 
-	imageFetcher imageFetcher
-	applier      applier
+	imageFetcher    imageFetcher
+	channelFetcher  channelFetcher
+	applier         applier
+	hookRunner      hookRunner
+	kubeClientRetry kubeClientRetry
+	// phaseRetryInterval is the time waited before a failed phase is retried.
+	phaseRetryInterval time.Duration
+	// eventRetryInterval is the time waited before watching Kubernetes events is retried.
+	eventRetryInterval time.Duration
 
 	newInfraApplier func(context.Context) (cloudApplier, func(), error)
+	adopter         resourceAdopter
+	newEventWatcher func(kubeConfig []byte, clusterEndpoint string) (eventWatcher, error)
+
+	newMasterKeyBackend newMasterKeyBackendFunc
+
+	canFetchMeasurements bool
+	verifyFetcher        verifyFetcher
+}
+
+// applyWithStateLock runs apply while holding the state lock,
+// so concurrent applies in the same workspace can't modify the state file at the same time.
+func (a *applyCmd) applyWithStateLock(
+	cmd *cobra.Command, configFetcher attestationconfigapi.Fetcher, upgradeDir string,
+) error {
+	locker := state.NewLocker(a.fileHandler, constants.StateLockFilename, stateLockHolder())
+	a.log.Debug("Acquiring state lock", "timeout", a.flags.lockTimeout, "forceUnlock", a.flags.forceUnlock)
+	if err := locker.Acquire(cmd.Context(), a.flags.lockTimeout, a.flags.forceUnlock); err != nil {
+		return err
+	}
+
+	applyErr := a.apply(cmd, configFetcher, upgradeDir)
+	if err := locker.Release(); err != nil {
+		return errors.Join(applyErr, err)
+	}
+	return applyErr
 }
 
 /*
@@ -360,91 +801,60 @@ func (a *applyCmd) apply(
 		return err
 	}
 
-	// Check license
-	a.checkLicenseFile(cmd, conf.GetProvider(), conf.UseMarketplaceImage())
-
-	// Now start actually running the apply command
+	if err := validatePhaseHooks(conf.PhaseHooks, a.flags.configRef, exec.LookPath); err != nil {
+		return err
+	}
 
-	// Check current Terraform state, if it exists and infrastructure upgrades are not skipped,
-	// and apply migrations if necessary.
-	if !a.flags.skipPhases.contains(skipInfrastructurePhase) {
-		if err := a.runTerraformApply(cmd, conf, stateFile, upgradeDir); err != nil {
-			return fmt.Errorf("applying Terraform configuration: %w", err)
-		}
+	// Print the phases that would run instead of applying
+	if a.flags.showPlanGraph {
+		return a.showPlanGraph(cmd, conf, stateFile)
 	}
 
-	bufferedOutput := &bytes.Buffer{}
-	// Run init RPC if required
-	if !a.flags.skipPhases.contains(skipInitPhase) {
-		bufferedOutput, err = a.runInit(cmd, conf, stateFile)
-		if err != nil {
+	// Compare the measurements with the signed upstream measurements before they are used
+	if a.flags.compareMeasurements {
+		if err := a.compareMeasurementsWithUpstream(cmd, conf); err != nil {
 			return err
 		}
 	}
 
-	if a.flags.skipPhases.contains(skipAttestationConfigPhase, skipCertSANsPhase, skipHelmPhase, skipK8sPhase, skipImagePhase) {
-		cmd.Print(bufferedOutput.String())
-		return nil
-	}
-
-	// From now on we can assume a valid Kubernetes admin config file exists
-	kubeConfig, err := a.fileHandler.Read(constants.AdminConfFilename)
-	if err != nil {
-		return fmt.Errorf("reading kubeconfig: %w", err)
-	}
-	if err := a.applier.SetKubeConfig(kubeConfig); err != nil {
+	// Pause before changes that would delete and recreate the cluster
+	if err := a.confirmRecreatingChanges(cmd, conf, stateFile); err != nil {
 		return err
 	}
 
-	// Apply Attestation Config
-	if !a.flags.skipPhases.contains(skipAttestationConfigPhase) {
-		a.log.Debug("Applying new attestation config to cluster")
-		if err := a.applyJoinConfig(cmd, conf.GetAttestationConfig(), stateFile.ClusterValues.MeasurementSalt); err != nil {
-			return fmt.Errorf("applying attestation config: %w", err)
-		}
-	}
-
-	// Extend API Server Cert SANs
-	if !a.flags.skipPhases.contains(skipCertSANsPhase) {
-		if err := a.applier.ExtendClusterConfigCertSANs(
-			cmd.Context(),
-			stateFile.Infrastructure.ClusterEndpoint,
-			conf.CustomEndpoint,
-			stateFile.Infrastructure.APIServerCertSANs,
-		); err != nil {
-			return fmt.Errorf("extending cert SANs: %w", err)
-		}
-	}
+	// Check license
+	a.checkLicenseFile(cmd, conf.GetProvider(), conf.UseMarketplaceImage())
 
-	// Apply Helm Charts
-	if !a.flags.skipPhases.contains(skipHelmPhase) {
-		if err := a.applier.AnnotateCoreDNSResources(cmd.Context()); err != nil {
-			return fmt.Errorf("annotating CoreDNS: %w", err)
-		}
-		if err := a.runHelmApply(cmd, conf, stateFile, upgradeDir); err != nil {
-			return err
-		}
-		if err := a.applier.CleanupCoreDNSResources(cmd.Context()); err != nil {
-			return fmt.Errorf("cleaning up CoreDNS: %w", err)
-		}
+	// Check access to the KMS protecting the master secret
+	if err := a.validateMasterKeyHSM(cmd.Context(), conf, stateFile); err != nil {
+		return err
 	}
 
-	// Upgrade node image
-	if !a.flags.skipPhases.contains(skipImagePhase) {
-		if err := a.runNodeImageUpgrade(cmd, conf); err != nil {
-			return err
-		}
+	// Now start actually running the apply command
+	applyState := &applyState{
+		cmd:        cmd,
+		conf:       conf,
+		stateFile:  stateFile,
+		upgradeDir: upgradeDir,
+		initOutput: &bytes.Buffer{},
+		progress:   a.progress,
+	}
+	registry := newApplyPhaseRegistry(a)
+	if err := a.reconcilePhases(cmd.OutOrStderr(), registry, conf, stateFile); err != nil {
+		return err
 	}
-
-	// Upgrade Kubernetes version
-	if !a.flags.skipPhases.contains(skipK8sPhase) {
-		if err := a.runK8sVersionUpgrade(cmd, conf); err != nil {
-			return err
-		}
+	err = registry.withRetries(a.flags.retries, a.phaseRetryInterval, a.wLog).
+		withHooks(conf.PhaseHooks, a.runPhaseHook).
+		withFingerprints(a.recordPhaseFingerprints).
+		run(cmd.Context(), applyState, a.flags.skipPhases, !a.flags.continueOnError)
+	applyState.stopWatchingEvents()
+	if err != nil {
+		a.dumpStateOnError(cmd, applyState.stateFile)
+		return err
 	}
 
 	// Write success output
-	cmd.Print(bufferedOutput.String())
+	cmd.Print(applyState.initOutput.String())
 
 	return nil
 }
@@ -452,7 +862,7 @@ func (a *applyCmd) apply(
 func (a *applyCmd) validateInputs(cmd *cobra.Command, configFetcher attestationconfigapi.Fetcher) (*config.Config, *state.State, error) {
 	// Read user's config and state file
 	a.log.Debug(fmt.Sprintf("Reading config from %q", a.flags.pathPrefixer.PrefixPrintablePath(constants.ConfigFilename)))
-	conf, err := config.New(a.fileHandler, constants.ConfigFilename, configFetcher, a.flags.force)
+	conf, err := a.flags.loadConfig(cmd.Context(), a.fileHandler, configFetcher)
 	var configValidationErr *config.ValidationError
 	if errors.As(err, &configValidationErr) {
 		cmd.PrintErrln(configValidationErr.LongMessage())
@@ -507,6 +917,17 @@ func (a *applyCmd) validateInputs(cmd *cobra.Command, configFetcher attestationc
 		if err := a.checkInitFilesClean(); err != nil {
 			return nil, nil, err
 		}
+		if a.flags.masterSecretIn != "" {
+			if err := a.confirmMasterSecretImport(cmd); err != nil {
+				return nil, nil, err
+			}
+		}
+		if len(a.flags.targetGroups) > 0 {
+			return nil, nil, errors.New("node groups can only be targeted once the cluster is initialized: remove --target-groups")
+		}
+		if a.flags.kubeConfig != "" {
+			return nil, nil, errors.New("the kubeconfig of a new cluster is written to the workspace: remove --kubeconfig")
+		}
 
 		// Skip image and k8s phase, since they are covered by the init RPC
 		a.flags.skipPhases.add(skipImagePhase, skipK8sPhase)
@@ -519,6 +940,25 @@ func (a *applyCmd) validateInputs(cmd *cobra.Command, configFetcher attestationc
 		if err := a.checkPostInitFilesExist(); err != nil {
 			return nil, nil, err
 		}
+		if a.flags.masterSecretIn != "" {
+			return nil, nil, errors.New("the master secret can only be imported when the cluster is initialized: the cluster is already initialized")
+		}
+		if !reflect.DeepEqual(conf.OIDC, stateFile.ClusterValues.OIDC) {
+			return nil, nil, errors.New("OIDC config doesn't match the config the cluster was initialized with: the OIDC issuer can't be changed after initialization")
+		}
+		if recorded := helmNamespace(stateFile, ""); a.flags.helmNamespace != "" && a.flags.helmNamespace != recorded {
+			return nil, nil, fmt.Errorf("the cluster was initialized with Helm namespace %q: the Helm namespace can't be changed after initialization", recorded)
+		}
+		if len(a.flags.targetGroups) > 0 {
+			if err := validateTargetGroups(a.flags.targetGroups, conf.NodeGroups); err != nil {
+				return nil, nil, err
+			}
+			if !a.flags.skipPhases.contains(skipK8sPhase) {
+				cmd.PrintErrln("Kubernetes can only be upgraded for the whole cluster, not for single node groups")
+				cmd.PrintErrln("Kubernetes phase will be skipped")
+				a.flags.skipPhases.add(skipK8sPhase)
+			}
+		}
 
 		// Skip init phase, since the init RPC has already been run
 		a.flags.skipPhases.add(skipInitPhase)
@@ -643,7 +1083,20 @@ func (a *applyCmd) applyJoinConfig(cmd *cobra.Command, newConfig config.Attestat
 	return nil
 }
 
-func (a *applyCmd) runNodeImageUpgrade(cmd *cobra.Command, conf *config.Config) error {
+// recordAttestationConfig stores the attestation config applied to the cluster in the state file,
+// so the cluster can be verified with only the state file.
+func recordAttestationConfig(stateFile *state.State, attestationCfg config.AttestationCfg) error {
+	attestation, err := config.NewAttestationConfig(attestationCfg)
+	if err != nil {
+		return fmt.Errorf("recording attestation config in state file: %w", err)
+	}
+	stateFile.Attestation = &attestation
+	return nil
+}
+
+// If node groups are targeted, only their image is upgraded.
+// The image versions of the node groups running an image other than the cluster's are recorded in the state file.
+func (a *applyCmd) runNodeImageUpgrade(cmd *cobra.Command, conf *config.Config, stateFile *state.State) error {
 	provider := conf.GetProvider()
 	attestationVariant := conf.GetAttestationConfig().GetVariant()
 	region := conf.GetRegion()
@@ -661,6 +1114,16 @@ func (a *applyCmd) runNodeImageUpgrade(cmd *cobra.Command, conf *config.Config)
 		return fmt.Errorf("parsing image version: %w", err)
 	}
 
+	if a.flags.noUpgradeImage {
+		if err := a.checkNoImageChange(cmd.Context(), imageVersion, imageReference); err != nil {
+			return err
+		}
+	}
+
+	if len(a.flags.targetGroups) > 0 {
+		return a.runNodeGroupImageUpgrade(cmd, imageVersion, imageReference, stateFile)
+	}
+
 	err = a.applier.UpgradeNodeImage(cmd.Context(), imageVersion, imageReference, a.flags.force)
 	var upgradeErr *compatibility.InvalidUpgradeError
 	switch {
@@ -670,11 +1133,68 @@ func (a *applyCmd) runNodeImageUpgrade(cmd *cobra.Command, conf *config.Config)
 		cmd.PrintErrln(err)
 	case err != nil:
 		return fmt.Errorf("upgrading NodeVersion: %w", err)
+	default:
+		// Upgrading the whole cluster moves all node groups back to the cluster's image.
+		stateFile.NodeGroupVersions = nil
 	}
 
 	return nil
 }
 
+// runNodeGroupImageUpgrade upgrades the image of the node groups set by --target-groups.
+// The image of the other node groups is left unchanged.
+func (a *applyCmd) runNodeGroupImageUpgrade(cmd *cobra.Command, imageVersion semver.Semver, imageReference string, stateFile *state.State) error {
+	for _, nodeGroup := range a.flags.targetGroups {
+		err := a.applier.UpgradeNodeGroupImage(cmd.Context(), nodeGroup, imageVersion, imageReference, a.flags.force)
+		var upgradeErr *compatibility.InvalidUpgradeError
+		switch {
+		case errors.Is(err, kubecmd.ErrInProgress):
+			cmd.PrintErrf("Skipping image upgrade of node group %s: Another upgrade is already in progress.\n", nodeGroup)
+		case errors.As(err, &upgradeErr):
+			cmd.PrintErrln(err)
+		case err != nil:
+			return fmt.Errorf("upgrading NodeVersion of node group %s: %w", nodeGroup, err)
+		default:
+			if stateFile.NodeGroupVersions == nil {
+				stateFile.NodeGroupVersions = map[string]string{}
+			}
+			stateFile.NodeGroupVersions[nodeGroup] = imageVersion.String()
+		}
+	}
+	return nil
+}
+
+// validateTargetGroups checks that all node groups targeted by --target-groups exist in the config.
+func validateTargetGroups(targetGroups []string, nodeGroups map[string]config.NodeGroup) error {
+	for _, nodeGroup := range targetGroups {
+		if _, ok := nodeGroups[nodeGroup]; !ok {
+			return fmt.Errorf("node group %q targeted by --target-groups doesn't exist in the config", nodeGroup)
+		}
+	}
+	return nil
+}
+
+// checkNoImageChange fails if the cluster doesn't run the given image yet.
+// Changing the image replaces all nodes, which --no-upgrade-image forbids.
+func (a *applyCmd) checkNoImageChange(ctx context.Context, imageVersion semver.Semver, imageReference string) error {
+	current, err := a.applier.GetConstellationVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("getting image of the cluster: %w", err)
+	}
+	if current.ImageVersion() != imageVersion.String() {
+		return fmt.Errorf("--no-upgrade-image is set, but applying the config would change the node image from %s to %s and replace all nodes: "+
+			"set image to %s in your config, or skip the image phase with --skip-phases %s",
+			current.ImageVersion(), imageVersion, current.ImageVersion(), skipImagePhase)
+	}
+	if current.ImageReference() != imageReference {
+		return fmt.Errorf("--no-upgrade-image is set, but applying the config would change the node image reference from %s to %s and replace all nodes: "+
+			"skip the image phase with --skip-phases %s",
+			current.ImageReference(), imageReference, skipImagePhase)
+	}
+	a.log.Debug("Node image is unchanged", "imageVersion", current.ImageVersion())
+	return nil
+}
+
 func (a *applyCmd) runK8sVersionUpgrade(cmd *cobra.Command, conf *config.Config) error {
 	err := a.applier.UpgradeKubernetesVersion(cmd.Context(), conf.KubernetesVersion, a.flags.force)
 	var upgradeErr *compatibility.InvalidUpgradeError
@@ -731,9 +1251,10 @@ func (a *applyCmd) checkInitFilesClean() error {
 }
 
 // checkPostInitFilesExist ensures that the workspace contains the files from a previous init RPC.
+// If a kubeconfig is set with --kubeconfig, it replaces the admin kubeconfig of the workspace.
 func (a *applyCmd) checkPostInitFilesExist() error {
-	if _, err := a.fileHandler.Stat(constants.AdminConfFilename); err != nil {
-		return fmt.Errorf("checking for %q: %w", a.flags.pathPrefixer.PrefixPrintablePath(constants.AdminConfFilename), err)
+	if _, err := a.fileHandler.Stat(a.flags.kubeConfigPath()); err != nil {
+		return fmt.Errorf("checking for %q: %w", a.flags.pathPrefixer.PrefixPrintablePath(a.flags.kubeConfigPath()), err)
 	}
 	if _, err := a.fileHandler.Stat(constants.MasterSecretFilename); err != nil {
 		return fmt.Errorf("checking for %q: %w", a.flags.pathPrefixer.PrefixPrintablePath(constants.MasterSecretFilename), err)
@@ -764,6 +1285,11 @@ func printCreateWarnings(out io.Writer, conf *config.Config) {
 		printedAWarning = true
 	}
 
+	if conf.UserData != "" {
+		fmt.Fprintln(out, "WARNING: userData is set. User data runs on the nodes outside of Constellation's attestation guarantees.")
+		printedAWarning = true
+	}
+
 	// Print an extra new line later to separate warnings from the prompt message of the create command
 	if printedAWarning {
 		fmt.Fprintln(out, "")
@@ -833,6 +1359,7 @@ type warnLog interface {
 // applier is used to run the different phases of the apply command.
 type applier interface {
 	SetKubeConfig(kubeConfig []byte) error
+	CheckAPIServerReachable() error
 	CheckLicense(ctx context.Context, csp cloudprovider.Provider, initRequest bool, licenseID string) (int, error)
 
 	// methods required by "init"
@@ -857,12 +1384,26 @@ type applier interface {
 	ExtendClusterConfigCertSANs(ctx context.Context, clusterEndpoint, customEndpoint string, additionalAPIServerCertSANs []string) error
 	GetClusterAttestationConfig(ctx context.Context, variant variant.Variant) (config.AttestationCfg, error)
 	ApplyJoinConfig(ctx context.Context, newAttestConfig config.AttestationCfg, measurementSalt []byte) error
+	ApplyNetworkPolicies(ctx context.Context, policies []networkingv1.NetworkPolicy) error
+	PrePullImages(ctx context.Context, images []string) error
 	UpgradeNodeImage(ctx context.Context, imageVersion semver.Semver, imageReference string, force bool) error
+	UpgradeNodeGroupImage(ctx context.Context, nodeGroup string, imageVersion semver.Semver, imageReference string, force bool) error
 	UpgradeKubernetesVersion(ctx context.Context, kubernetesVersion versions.ValidK8sVersion, force bool) error
+	SetDrainSettings(ctx context.Context, gracePeriod, timeout time.Duration) error
+	GetConstellationVersion(ctx context.Context) (kubecmd.NodeVersion, error)
 	BackupCRDs(ctx context.Context, fileHandler file.Handler, upgradeDir string) ([]apiextensionsv1.CustomResourceDefinition, error)
 	BackupCRs(ctx context.Context, fileHandler file.Handler, crds []apiextensionsv1.CustomResourceDefinition, upgradeDir string) error
 }
 
+// stateLockHolder identifies this process in the state lock file.
+func stateLockHolder() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", hostname, os.Getpid())
+}
+
 // imageFetcher gets an image reference from the versionsapi.
 type imageFetcher interface {
 	FetchReference(ctx context.Context,
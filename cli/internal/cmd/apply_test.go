@@ -12,7 +12,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -20,28 +22,33 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/atls"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/gcpshared"
+	"github.com/edgelesssys/constellation/v2/internal/compatibility"
 	"github.com/edgelesssys/constellation/v2/internal/config"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/constellation"
 	"github.com/edgelesssys/constellation/v2/internal/constellation/helm"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/kubecmd"
 	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
 	"github.com/edgelesssys/constellation/v2/internal/file"
 	"github.com/edgelesssys/constellation/v2/internal/kms/uri"
 	"github.com/edgelesssys/constellation/v2/internal/logger"
 	"github.com/edgelesssys/constellation/v2/internal/versions"
+	updatev1alpha1 "github.com/edgelesssys/constellation/v2/operators/constellation-node-operator/api/v1alpha1"
 	"github.com/spf13/afero"
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // defaultStateFile returns a valid default state for testing.
 func defaultStateFile(csp cloudprovider.Provider) *state.State {
 	stateFile := &state.State{
-		Version: "v1",
+		Version: "v2",
 		Infrastructure: state.Infrastructure{
 			UID:               "123",
-			Name:              "test-cluster",
+			Name:              "kubernetes-123",
 			ClusterEndpoint:   "192.0.2.1",
 			InClusterEndpoint: "192.0.2.1",
 			InitSecret:        []byte{0x41},
@@ -51,7 +58,7 @@ func defaultStateFile(csp cloudprovider.Provider) *state.State {
 			},
 			IPCidrNode: "0.0.0.0/24",
 			Azure: &state.Azure{
-				ResourceGroup:            "test-rg",
+				ResourceGroup:            "test-resource-group",
 				SubscriptionID:           "test-sub",
 				NetworkSecurityGroupName: "test-nsg",
 				LoadBalancerName:         "test-lb",
@@ -66,7 +73,7 @@ func defaultStateFile(csp cloudprovider.Provider) *state.State {
 		ClusterValues: state.ClusterValues{
 			ClusterID:       "deadbeef",
 			OwnerID:         "deadbeef",
-			MeasurementSalt: []byte{0x41},
+			MeasurementSalt: bytes.Repeat([]byte{0x41}, 32),
 		},
 	}
 	switch csp {
@@ -88,6 +95,9 @@ func TestParseApplyFlags(t *testing.T) {
 		// Register persistent flags
 		flags.String("workspace", "", "")
 		flags.String("tf-log", "NONE", "")
+		flags.String("profile", "", "")
+		flags.String("config", "", "")
+		flags.String("kubeconfig", "", "")
 		flags.Bool("force", false, "")
 		flags.Bool("debug", false, "")
 		return flags
@@ -101,8 +111,26 @@ func TestParseApplyFlags(t *testing.T) {
 		"default flags": {
 			flags: defaultFlags(),
 			wantFlags: applyFlags{
-				helmWaitMode: helm.WaitModeAtomic,
-				helmTimeout:  10 * time.Minute,
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+			},
+		},
+		"fail fast disabled": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("fail-fast", "false"))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+				continueOnError:   true,
 			},
 		},
 		"skip phases": {
@@ -112,40 +140,1339 @@ func TestParseApplyFlags(t *testing.T) {
 				return flags
 			}(),
 			wantFlags: applyFlags{
-				skipPhases:   newPhases(skipHelmPhase, skipK8sPhase),
-				helmWaitMode: helm.WaitModeAtomic,
-				helmTimeout:  10 * time.Minute,
+				skipPhases:        newPhases(skipHelmPhase, skipK8sPhase),
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+			},
+		},
+		"config from cluster": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("config", "secret://gitops/constellation/config.yaml"))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				rootFlags: rootFlags{
+					configRef: &clusterConfigRef{kind: clusterConfigKindSecret, namespace: "gitops", name: "constellation", key: "config.yaml"},
+				},
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+			},
+		},
+		"invalid config reference": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("config", "constellation-conf.yaml"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"config from cluster with profile": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("config", "secret://gitops/constellation/config.yaml"))
+				require.NoError(flags.Set("profile", "prod"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"skip helm wait": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("skip-helm-wait", "true"))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				helmWaitMode:      helm.WaitModeNone,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+			},
+		},
+		"skip helm wait while skipping helm phase": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("skip-phases", string(skipHelmPhase)))
+				require.NoError(flags.Set("skip-helm-wait", "true"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"skip helm phase with default wait mode": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("skip-phases", string(skipHelmPhase)))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				skipPhases:        newPhases(skipHelmPhase),
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+			},
+		},
+		"no backup while skipping helm phase": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("skip-phases", string(skipHelmPhase)))
+				require.NoError(flags.Set("no-backup", "true"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"watch events while skipping init phase": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("skip-phases", string(skipInitPhase)))
+				require.NoError(flags.Set("watch-events", "true"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"conformance while only skipping init phase": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("skip-phases", string(skipInitPhase)))
+				require.NoError(flags.Set("conformance", "true"))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				skipPhases:        newPhases(skipInitPhase),
+				conformance:       true,
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+			},
+		},
+		"conformance while skipping init and helm phase": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("skip-phases", fmt.Sprintf("%s,%s", skipInitPhase, skipHelmPhase)))
+				require.NoError(flags.Set("conformance", "true"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"helm history max": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("helm-history-max", "3"))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    3,
+				initRetry:         constellation.DefaultInitRetry,
+			},
+		},
+		"helm namespace": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("helm-namespace", "constellation-system"))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				helmNamespace:     "constellation-system",
+				initRetry:         constellation.DefaultInitRetry,
+			},
+		},
+		"invalid helm namespace": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("helm-namespace", "Constellation_System"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"negative helm history max": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("helm-history-max", "-1"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"helm atomic timeout": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("helm-atomic-timeout", "30m"))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 30 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+			},
+		},
+		"helm atomic timeout defaults to helm timeout": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("helm-timeout", "5m"))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       5 * time.Minute,
+				helmAtomicTimeout: 5 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+			},
+		},
+		"watch events": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("watch-events", "true"))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+				watchEvents:       true,
+			},
+		},
+		"no backup": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("no-backup", "true"))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+				noBackup:          true,
+			},
+		},
+		"no upgrade image": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("no-upgrade-image", "true"))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+				noUpgradeImage:    true,
+			},
+		},
+		"no upgrade image while skipping image phase": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("skip-phases", string(skipImagePhase)))
+				require.NoError(flags.Set("no-upgrade-image", "true"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"target groups": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("target-groups", "worker-canary,worker-eu"))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+				targetGroups:      []string{"worker-canary", "worker-eu"},
+			},
+		},
+		"target groups while skipping image phase": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("skip-phases", string(skipImagePhase)))
+				require.NoError(flags.Set("target-groups", "worker-canary"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"target groups with no upgrade image": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("target-groups", "worker-canary"))
+				require.NoError(flags.Set("no-upgrade-image", "true"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"drain grace period": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("drain-grace-period", "5m"))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+				drainGracePeriod:  5 * time.Minute,
+			},
+		},
+		"negative drain grace period": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("drain-grace-period", "-1m"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"drain grace period in fractions of a second": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("drain-grace-period", "1500ms"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"drain grace period while skipping image and k8s phases": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("skip-phases", strings.Join([]string{string(skipImagePhase), string(skipK8sPhase)}, ",")))
+				require.NoError(flags.Set("drain-grace-period", "5m"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"drain timeout": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("drain-timeout", "1h"))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+				drainTimeout:      time.Hour,
+			},
+		},
+		"negative drain timeout": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("drain-timeout", "-1m"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"print resource IDs": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("print-resource-ids", "true"))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+				printResourceIDs:  true,
+			},
+		},
+		"print resource IDs while skipping infrastructure phase": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("skip-phases", string(skipInfrastructurePhase)))
+				require.NoError(flags.Set("print-resource-ids", "true"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"print resource IDs and quiet": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("print-resource-ids", "true"))
+				require.NoError(flags.Set("quiet", "true"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"pre-pull images": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("pre-pull-images", "true"))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+				prePullImages:     true,
+			},
+		},
+		"pre-pull images while skipping helm phase": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("skip-phases", string(skipHelmPhase)))
+				require.NoError(flags.Set("pre-pull-images", "true"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"init retry": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("init-timeout", "20m"))
+				require.NoError(flags.Set("init-retry-interval", "10s"))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry: constellation.InitRetry{
+					Timeout:     20 * time.Minute,
+					Interval:    10 * time.Second,
+					MaxInterval: 30 * time.Second,
+				},
+			},
+		},
+		"init retry interval above max interval": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("init-retry-interval", "1m"))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.InitRetry{Interval: time.Minute, MaxInterval: time.Minute},
+			},
+		},
+		"zero init retry interval": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("init-retry-interval", "0s"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"init timeout while skipping init phase": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("skip-phases", string(skipInitPhase)))
+				require.NoError(flags.Set("init-timeout", "20m"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"lock timeout and force unlock": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("lock-timeout", "2m"))
+				require.NoError(flags.Set("force-unlock", "true"))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+				lockTimeout:       2 * time.Minute,
+				forceUnlock:       true,
+			},
+		},
+		"dump state on error": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Parse([]string{"--dump-state-on-error", "--dump-state-full"}))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+				dumpStatePath:     constants.StateDumpFilename,
+				dumpStateFull:     true,
+			},
+		},
+		"dump state on error to file": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Parse([]string{"--dump-state-on-error=failed-apply.yaml"}))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+				dumpStatePath:     "failed-apply.yaml",
+			},
+		},
+		"ndjson output": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("output", "ndjson"))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+				output:            applyOutputNDJSON,
+			},
+		},
+		"reconcile": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("reconcile", "true"))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+				reconcile:         true,
+			},
+		},
+		"since state": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("since-state", "baseline-state.yaml"))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+				sinceState:        "baseline-state.yaml",
+			},
+		},
+		"reconcile and since state": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("reconcile", "true"))
+				require.NoError(flags.Set("since-state", "baseline-state.yaml"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"quiet": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("quiet", "true"))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+				verbosity:         applyVerbosityQuiet,
+			},
+		},
+		"quiet and debug": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("quiet", "true"))
+				require.NoError(flags.Set("debug", "true"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"invalid output": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("output", "yaml"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"max retries per phase": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("max-retries-per-phase", "2"))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+				retries:           phaseRetries{maxRetries: 2},
+			},
+		},
+		"phase retries override": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("max-retries-per-phase", "1"))
+				require.NoError(flags.Set("phase-retries", "Helm=3,image=0"))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+				retries: phaseRetries{
+					maxRetries: 1,
+					perPhase:   map[skipPhase]int{skipHelmPhase: 3, skipImagePhase: 0},
+				},
+			},
+		},
+		"negative max retries per phase": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("max-retries-per-phase", "-1"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"negative phase retries": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("phase-retries", "helm=-1"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"phase retries for unknown phase": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("phase-retries", "terraform=3"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"phase retries for init phase": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("phase-retries", "init=3"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"phase retries for skipped phase": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("skip-phases", string(skipHelmPhase)))
+				require.NoError(flags.Set("phase-retries", "helm=3"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"redacted state dump to state file": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("dump-state-on-error", "./"+constants.StateFilename))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"show plan graph": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("show-plan-graph", "true"))
+				require.NoError(flags.Set("graph-format", planGraphFormatDOT))
+				return flags
+			}(),
+			wantFlags: applyFlags{
+				helmWaitMode:      helm.WaitModeAtomic,
+				helmTimeout:       10 * time.Minute,
+				helmAtomicTimeout: 10 * time.Minute,
+				helmHistoryMax:    10,
+				initRetry:         constellation.DefaultInitRetry,
+				showPlanGraph:     true,
+				graphFormat:       planGraphFormatDOT,
 			},
 		},
-		"skip helm wait": {
-			flags: func() *pflag.FlagSet {
-				flags := defaultFlags()
-				require.NoError(flags.Set("skip-helm-wait", "true"))
-				return flags
-			}(),
-			wantFlags: applyFlags{
-				helmWaitMode: helm.WaitModeNone,
-				helmTimeout:  10 * time.Minute,
-			},
+		"invalid graph format": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("show-plan-graph", "true"))
+				require.NoError(flags.Set("graph-format", "svg"))
+				return flags
+			}(),
+			wantErr: true,
+		},
+		"graph format without plan graph": {
+			flags: func() *pflag.FlagSet {
+				flags := defaultFlags()
+				require.NoError(flags.Set("graph-format", planGraphFormatDOT))
+				return flags
+			}(),
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			var flags applyFlags
+
+			err := flags.parse(tc.flags)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantFlags, flags)
+		})
+	}
+}
+
+// TestDumpStateOnErrorSeparateValue checks that a file passed to --dump-state-on-error
+// without "=" isn't silently ignored, since the flag's value is optional.
+func TestDumpStateOnErrorSeparateValue(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	cmd := NewApplyCmd()
+	require.NoError(cmd.ParseFlags([]string{"--dump-state-on-error", "failed-apply.yaml"}))
+
+	dumpStatePath, err := cmd.Flags().GetString("dump-state-on-error")
+	require.NoError(err)
+	assert.Equal(constants.StateDumpFilename, dumpStatePath)
+	assert.Error(cmd.ValidateArgs(cmd.Flags().Args()))
+}
+
+func TestApplyWithStateLockHeld(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fh := file.NewHandler(afero.NewMemMapFs())
+	require.NoError(fh.WriteJSON(constants.StateLockFilename, state.Lock{
+		Holder:     "other-host:42",
+		AcquiredAt: time.Now().Add(-time.Minute),
+		Heartbeat:  time.Now(),
+	}))
+
+	a := &applyCmd{
+		fileHandler: fh,
+		flags:       applyFlags{forceUnlock: true},
+		log:         logger.NewTest(t),
+	}
+	cmd := NewApplyCmd()
+	cmd.SetContext(context.Background())
+
+	err := a.applyWithStateLock(cmd, stubAttestationFetcher{}, "test")
+	var heldErr *state.LockHeldError
+	require.ErrorAs(err, &heldErr)
+	assert.Equal("other-host:42", heldErr.Holder)
+
+	// a live lock is never removed
+	var lock state.Lock
+	require.NoError(fh.ReadJSON(constants.StateLockFilename, &lock))
+	assert.Equal("other-host:42", lock.Holder)
+}
+
+func TestRunHelmApplyTimeouts(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fh := file.NewHandler(afero.NewMemMapFs())
+	require.NoError(fh.WriteJSON(constants.MasterSecretFilename, uri.MasterSecret{}))
+	cfg := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.Azure)
+	cfg.ReadinessTimeouts = map[string]string{"cert-manager": "20m"}
+
+	helmApplier := &recordingHelmApplier{}
+	a := &applyCmd{
+		fileHandler: fh,
+		flags: applyFlags{
+			helmTimeout:       10 * time.Minute,
+			helmAtomicTimeout: 30 * time.Minute,
+		},
+		log:     logger.NewTest(t),
+		spinner: &nopSpinner{},
+		applier: &stubConstellApplier{helmApplier: helmApplier},
+	}
+
+	cmd := NewApplyCmd()
+	cmd.SetContext(context.Background())
+	cmd.SetOut(&bytes.Buffer{})
+	require.NoError(a.runHelmApply(cmd, cfg, defaultStateFile(cloudprovider.Azure), "test"))
+	assert.Equal(10*time.Minute, helmApplier.options.ApplyTimeout)
+	assert.Equal(30*time.Minute, helmApplier.options.AtomicApplyTimeout)
+	assert.Equal(map[string]time.Duration{"cert-manager": 20 * time.Minute}, helmApplier.options.ReadinessTimeouts)
+}
+
+func TestRunHelmApplyHistoryMax(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fh := file.NewHandler(afero.NewMemMapFs())
+	require.NoError(fh.WriteJSON(constants.MasterSecretFilename, uri.MasterSecret{}))
+	cfg := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.Azure)
+
+	helmApplier := &recordingHelmApplier{}
+	a := &applyCmd{
+		fileHandler: fh,
+		flags:       applyFlags{helmHistoryMax: 3},
+		log:         logger.NewTest(t),
+		spinner:     &nopSpinner{},
+		applier:     &stubConstellApplier{helmApplier: helmApplier},
+	}
+
+	cmd := NewApplyCmd()
+	cmd.SetContext(context.Background())
+	cmd.SetOut(&bytes.Buffer{})
+	require.NoError(a.runHelmApply(cmd, cfg, defaultStateFile(cloudprovider.Azure), "test"))
+	assert.Equal(3, helmApplier.options.HistoryMax)
+}
+
+func TestRunHelmApplyNamespace(t *testing.T) {
+	testCases := map[string]struct {
+		flagNamespace  string
+		stateNamespace string
+		wantNamespace  string
+	}{
+		"default namespace": {
+			wantNamespace: constants.HelmNamespace,
+		},
+		"namespace from flag": {
+			flagNamespace: "constellation-system",
+			wantNamespace: "constellation-system",
+		},
+		"re-apply reads namespace from state": {
+			stateNamespace: "constellation-system",
+			wantNamespace:  "constellation-system",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fh := file.NewHandler(afero.NewMemMapFs())
+			require.NoError(fh.WriteJSON(constants.MasterSecretFilename, uri.MasterSecret{}))
+			cfg := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.Azure)
+			stateFile := defaultStateFile(cloudprovider.Azure)
+			stateFile.ClusterValues.HelmNamespace = tc.stateNamespace
+
+			helmApplier := &recordingHelmApplier{}
+			a := &applyCmd{
+				fileHandler: fh,
+				flags:       applyFlags{helmNamespace: tc.flagNamespace},
+				log:         logger.NewTest(t),
+				spinner:     &nopSpinner{},
+				applier:     &stubConstellApplier{helmApplier: helmApplier},
+			}
+
+			cmd := NewApplyCmd()
+			cmd.SetContext(context.Background())
+			cmd.SetOut(&bytes.Buffer{})
+			require.NoError(a.runHelmApply(cmd, cfg, stateFile, "test"))
+			assert.Equal(tc.wantNamespace, helmApplier.options.Namespace)
+		})
+	}
+}
+
+func TestRunHelmApplyDisabledCharts(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fh := file.NewHandler(afero.NewMemMapFs())
+	require.NoError(fh.WriteJSON(constants.MasterSecretFilename, uri.MasterSecret{}))
+	cfg := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.Azure)
+	cfg.DisabledCharts = []string{"cert-manager", "constellation-csi"}
+
+	helmApplier := &recordingHelmApplier{}
+	a := &applyCmd{
+		fileHandler: fh,
+		log:         logger.NewTest(t),
+		spinner:     &nopSpinner{},
+		applier:     &stubConstellApplier{helmApplier: helmApplier},
+	}
+
+	cmd := NewApplyCmd()
+	cmd.SetContext(context.Background())
+	cmd.SetOut(&bytes.Buffer{})
+	require.NoError(a.runHelmApply(cmd, cfg, defaultStateFile(cloudprovider.Azure), "test"))
+	assert.Equal([]string{"cert-manager", "constellation-csi"}, helmApplier.options.DisabledCharts)
+}
+
+func TestRunHelmApplyNoBackup(t *testing.T) {
+	testCases := map[string]struct {
+		noBackup   bool
+		wantBackup bool
+	}{
+		"backup": {
+			wantBackup: true,
+		},
+		"no backup": {
+			noBackup: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fh := file.NewHandler(afero.NewMemMapFs())
+			require.NoError(fh.WriteJSON(constants.MasterSecretFilename, uri.MasterSecret{}))
+			cfg := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.Azure)
+
+			executor := &recordingRunner{}
+			backupClient := &stubKubernetesUpgrader{}
+			a := &applyCmd{
+				fileHandler: fh,
+				flags:       applyFlags{noBackup: tc.noBackup},
+				log:         logger.NewTest(t),
+				spinner:     &nopSpinner{},
+				applier: &stubConstellApplier{
+					stubKubernetesUpgrader: backupClient,
+					helmApplier:            &upgradingHelmApplier{executor: executor},
+				},
+			}
+
+			cmd := NewApplyCmd()
+			cmd.SetContext(context.Background())
+			cmd.SetOut(&bytes.Buffer{})
+			errOut := &bytes.Buffer{}
+			cmd.SetErr(errOut)
+			require.NoError(a.runHelmApply(cmd, cfg, defaultStateFile(cloudprovider.Azure), "test"))
+
+			assert.Equal(tc.wantBackup, executor.savedCharts)
+			assert.Equal(tc.wantBackup, backupClient.backupCRDsCalled)
+			assert.Equal(tc.wantBackup, backupClient.backupCRsCalled)
+			assert.Equal(tc.noBackup, strings.Contains(errOut.String(), "WARNING"))
+			// the upgrade proceeds either way
+			assert.True(executor.applied)
+		})
+	}
+}
+
+func TestRunHelmApplyPrePullImages(t *testing.T) {
+	images := []string{"registry.example.com/a:v1", "registry.example.com/b:v2"}
+
+	testCases := map[string]struct {
+		prePullImages bool
+		imagesErr     error
+		prePullErr    error
+		wantPrePulled []string
+		wantWarning   bool
+	}{
+		"no pre-pull": {},
+		"pre-pull": {
+			prePullImages: true,
+			wantPrePulled: images,
+		},
+		"pre-pull fails": {
+			prePullImages: true,
+			prePullErr:    errors.New("timed out"),
+			wantPrePulled: images,
+			wantWarning:   true,
+		},
+		"getting images fails": {
+			prePullImages: true,
+			imagesErr:     errors.New("rendering failed"),
+			wantWarning:   true,
 		},
 	}
 
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
 			assert := assert.New(t)
-			var flags applyFlags
+			require := require.New(t)
 
-			err := flags.parse(tc.flags)
+			fh := file.NewHandler(afero.NewMemMapFs())
+			require.NoError(fh.WriteJSON(constants.MasterSecretFilename, uri.MasterSecret{}))
+			cfg := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.Azure)
+
+			executor := &recordingRunner{images: images, imagesErr: tc.imagesErr}
+			applier := &prePullingApplier{
+				stubConstellApplier: &stubConstellApplier{
+					stubKubernetesUpgrader: &stubKubernetesUpgrader{},
+					helmApplier:            &upgradingHelmApplier{executor: executor},
+				},
+				executor:   executor,
+				prePullErr: tc.prePullErr,
+			}
+			a := &applyCmd{
+				fileHandler: fh,
+				flags:       applyFlags{prePullImages: tc.prePullImages, helmTimeout: time.Minute},
+				log:         logger.NewTest(t),
+				spinner:     &nopSpinner{},
+				applier:     applier,
+			}
+
+			cmd := NewApplyCmd()
+			cmd.SetContext(context.Background())
+			cmd.SetOut(&bytes.Buffer{})
+			errOut := &bytes.Buffer{}
+			cmd.SetErr(errOut)
+			require.NoError(a.runHelmApply(cmd, cfg, defaultStateFile(cloudprovider.Azure), "test"))
+
+			assert.Equal(tc.wantPrePulled, applier.prePulled)
+			assert.False(applier.appliedBeforePrePull, "images must be pre-pulled before the Helm charts are applied")
+			assert.Equal(tc.wantWarning, strings.Contains(errOut.String(), "WARNING"))
+			// the charts are applied either way
+			assert.True(executor.applied)
+		})
+	}
+}
+
+func TestRunHelmPhaseNetworkPolicyPreset(t *testing.T) {
+	someErr := errors.New("failed")
+
+	testCases := map[string]struct {
+		preset     string
+		applierErr error
+		wantCalled bool
+		wantErr    bool
+	}{
+		"no preset removes managed policies": {
+			wantCalled: true,
+		},
+		"none": {
+			preset:     kubecmd.NetworkPolicyPresetNone,
+			wantCalled: true,
+		},
+		"baseline": {
+			preset:     kubecmd.NetworkPolicyPresetBaseline,
+			wantCalled: true,
+		},
+		"restricted": {
+			preset:     kubecmd.NetworkPolicyPresetRestricted,
+			wantCalled: true,
+		},
+		"unknown preset": {
+			preset:  "strict",
+			wantErr: true,
+		},
+		"applying policies fails": {
+			preset:     kubecmd.NetworkPolicyPresetBaseline,
+			applierErr: someErr,
+			wantCalled: true,
+			wantErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fh := file.NewHandler(afero.NewMemMapFs())
+			require.NoError(fh.WriteJSON(constants.MasterSecretFilename, uri.MasterSecret{}))
+			require.NoError(fh.Write(constants.AdminConfFilename, []byte{}))
+			cfg := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.Azure)
+			cfg.NetworkPolicyPreset = tc.preset
+
+			kubeUpgrader := &stubKubernetesUpgrader{applyNetworkPoliciesErr: tc.applierErr}
+			a := &applyCmd{
+				fileHandler: fh,
+				log:         logger.NewTest(t),
+				spinner:     &nopSpinner{},
+				applier: &stubConstellApplier{
+					stubKubernetesUpgrader: kubeUpgrader,
+					helmApplier:            &stubHelmApplier{},
+				},
+			}
+
+			cmd := NewApplyCmd()
+			cmd.SetContext(context.Background())
+			cmd.SetOut(&bytes.Buffer{})
+			err := a.runHelmPhase(context.Background(), &applyState{
+				cmd:        cmd,
+				conf:       cfg,
+				stateFile:  defaultStateFile(cloudprovider.Azure),
+				upgradeDir: "test",
+			})
+			assert.Equal(tc.wantCalled, kubeUpgrader.applyNetworkPoliciesCalled)
 			if tc.wantErr {
 				assert.Error(err)
 				return
 			}
 			assert.NoError(err)
-			assert.Equal(tc.wantFlags, flags)
+
+			wantPolicies, err := kubecmd.NetworkPolicies(tc.preset)
+			require.NoError(err)
+			assert.Equal(wantPolicies, kubeUpgrader.appliedNetworkPolicies)
+		})
+	}
+}
+
+func TestRunK8sPhaseDrainSettings(t *testing.T) {
+	testCases := map[string]struct {
+		drainGracePeriod    time.Duration
+		drainTimeout        time.Duration
+		setDrainSettingsErr error
+		wantUpgrade         bool
+		wantErr             bool
+	}{
+		"drain settings are set before upgrading": {
+			drainGracePeriod: 5 * time.Minute,
+			drainTimeout:     time.Hour,
+			wantUpgrade:      true,
+		},
+		"unset drain settings are passed on": {
+			wantUpgrade: true,
+		},
+		"setting drain settings fails": {
+			drainGracePeriod:    5 * time.Minute,
+			setDrainSettingsErr: errors.New("error"),
+			wantErr:             true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			kubeUpgrader := &stubKubernetesUpgrader{setDrainSettingsErr: tc.setDrainSettingsErr}
+			a := &applyCmd{
+				fileHandler: file.NewHandler(afero.NewMemMapFs()),
+				log:         logger.NewTest(t),
+				flags:       applyFlags{drainGracePeriod: tc.drainGracePeriod, drainTimeout: tc.drainTimeout},
+				applier:     &stubConstellApplier{stubKubernetesUpgrader: kubeUpgrader},
+			}
+
+			cmd := NewApplyCmd()
+			cmd.SetContext(context.Background())
+			cmd.SetOut(&bytes.Buffer{})
+			err := a.runK8sPhase(context.Background(), &applyState{
+				cmd:           cmd,
+				conf:          defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.Azure),
+				stateFile:     defaultStateFile(cloudprovider.Azure),
+				kubeConfigSet: true,
+			})
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			assert.Equal([]time.Duration{tc.drainGracePeriod}, kubeUpgrader.drainGracePeriods)
+			assert.Equal([]time.Duration{tc.drainTimeout}, kubeUpgrader.drainTimeouts)
+			assert.Equal(tc.wantUpgrade, kubeUpgrader.calledKubernetesUpgrade)
+		})
+	}
+}
+
+func TestRunNodeImageUpgradeNoUpgradeImage(t *testing.T) {
+	const imageReference = "projects/constellation-images/global/images/v2-17-0-gcp-sev-snp-stable"
+
+	testCases := map[string]struct {
+		noUpgradeImage    bool
+		clusterImage      string
+		clusterReference  string
+		getNodeVersionErr error
+		wantErr           bool
+		wantUpgrade       bool
+	}{
+		"image change is blocked": {
+			noUpgradeImage:   true,
+			clusterImage:     "v2.16.3",
+			clusterReference: "projects/constellation-images/global/images/v2-16-3-gcp-sev-snp-stable",
+			wantErr:          true,
+		},
+		"image reference change is blocked": {
+			noUpgradeImage:   true,
+			clusterImage:     "v2.17.0",
+			clusterReference: "projects/constellation-images/global/images/v2-17-0-gcp-sev-snp-marketplace",
+			wantErr:          true,
+		},
+		"unchanged image proceeds": {
+			noUpgradeImage:   true,
+			clusterImage:     "v2.17.0",
+			clusterReference: imageReference,
+			wantUpgrade:      true,
+		},
+		"getting cluster image fails": {
+			noUpgradeImage:    true,
+			getNodeVersionErr: errors.New("error"),
+			wantErr:           true,
+		},
+		"image change without guard": {
+			clusterImage:     "v2.16.3",
+			clusterReference: "projects/constellation-images/global/images/v2-16-3-gcp-sev-snp-stable",
+			wantUpgrade:      true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			nodeVersion, err := kubecmd.NewNodeVersion(updatev1alpha1.NodeVersion{
+				Spec: updatev1alpha1.NodeVersionSpec{ImageVersion: tc.clusterImage, ImageReference: tc.clusterReference},
+				Status: updatev1alpha1.NodeVersionStatus{
+					Conditions: []metav1.Condition{{Type: updatev1alpha1.ConditionOutdated, Status: metav1.ConditionFalse}},
+				},
+			})
+			require.NoError(err)
+			kubeUpgrader := &stubKubernetesUpgrader{nodeVersion: nodeVersion, getNodeVersionErr: tc.getNodeVersionErr}
+
+			a := &applyCmd{
+				fileHandler:  file.NewHandler(afero.NewMemMapFs()),
+				flags:        applyFlags{noUpgradeImage: tc.noUpgradeImage},
+				log:          logger.NewTest(t),
+				spinner:      &nopSpinner{},
+				applier:      &stubConstellApplier{stubKubernetesUpgrader: kubeUpgrader},
+				imageFetcher: &stubImageFetcher{reference: imageReference},
+			}
+			conf := config.Default()
+			conf.Image = "v2.17.0"
+
+			cmd := NewApplyCmd()
+			cmd.SetContext(context.Background())
+			cmd.SetOut(&bytes.Buffer{})
+			cmd.SetErr(&bytes.Buffer{})
+
+			err = a.runNodeImageUpgrade(cmd, conf, state.New())
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			assert.Equal(tc.wantUpgrade, kubeUpgrader.calledNodeUpgrade)
+		})
+	}
+}
+
+func TestRunNodeGroupImageUpgrade(t *testing.T) {
+	upToDateErr := compatibility.NewInvalidUpgradeError("v2.17.0", "v2.17.0", errors.New("not an upgrade"))
+
+	testCases := map[string]struct {
+		targetGroups          []string
+		nodeGroupErrs         map[string]error
+		nodeVersionErr        error
+		nodeGroupVersions     map[string]string
+		wantUpgradedGroups    []string
+		wantClusterUpgrade    bool
+		wantNodeGroupVersions map[string]string
+		wantErr               bool
+	}{
+		"only targeted groups are upgraded": {
+			targetGroups:          []string{"worker-canary"},
+			wantUpgradedGroups:    []string{"worker-canary"},
+			wantNodeGroupVersions: map[string]string{"worker-canary": "v2.17.0"},
+		},
+		"versions of other targeted groups are kept": {
+			targetGroups:          []string{"worker-canary"},
+			nodeGroupVersions:     map[string]string{"worker-eu": "v2.16.3"},
+			wantUpgradedGroups:    []string{"worker-canary"},
+			wantNodeGroupVersions: map[string]string{"worker-canary": "v2.17.0", "worker-eu": "v2.16.3"},
+		},
+		"group that is up to date isn't recorded": {
+			targetGroups:          []string{"worker-canary", "worker-eu"},
+			nodeGroupErrs:         map[string]error{"worker-eu": upToDateErr},
+			wantUpgradedGroups:    []string{"worker-canary", "worker-eu"},
+			wantNodeGroupVersions: map[string]string{"worker-canary": "v2.17.0"},
+		},
+		"upgrade of group fails": {
+			targetGroups:       []string{"worker-canary"},
+			nodeGroupErrs:      map[string]error{"worker-canary": errors.New("error")},
+			wantUpgradedGroups: []string{"worker-canary"},
+			wantErr:            true,
+		},
+		"cluster upgrade resets node group versions": {
+			nodeGroupVersions:  map[string]string{"worker-canary": "v2.16.3"},
+			wantClusterUpgrade: true,
+		},
+		"skipped cluster upgrade keeps node group versions": {
+			nodeGroupVersions:     map[string]string{"worker-canary": "v2.17.0"},
+			nodeVersionErr:        upToDateErr,
+			wantClusterUpgrade:    true,
+			wantNodeGroupVersions: map[string]string{"worker-canary": "v2.17.0"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			kubeUpgrader := &stubKubernetesUpgrader{
+				nodeGroupVersionErrs: tc.nodeGroupErrs,
+				nodeVersionErr:       tc.nodeVersionErr,
+			}
+			a := &applyCmd{
+				fileHandler:  file.NewHandler(afero.NewMemMapFs()),
+				flags:        applyFlags{targetGroups: tc.targetGroups},
+				log:          logger.NewTest(t),
+				spinner:      &nopSpinner{},
+				applier:      &stubConstellApplier{stubKubernetesUpgrader: kubeUpgrader},
+				imageFetcher: &stubImageFetcher{reference: "projects/constellation-images/global/images/v2-17-0-gcp-sev-snp-stable"},
+			}
+			conf := config.Default()
+			conf.Image = "v2.17.0"
+			stateFile := state.New()
+			stateFile.NodeGroupVersions = tc.nodeGroupVersions
+
+			cmd := NewApplyCmd()
+			cmd.SetContext(context.Background())
+			cmd.SetOut(&bytes.Buffer{})
+			cmd.SetErr(&bytes.Buffer{})
+
+			err := a.runNodeImageUpgrade(cmd, conf, stateFile)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			assert.Equal(tc.wantClusterUpgrade, kubeUpgrader.calledNodeUpgrade)
+			assert.ElementsMatch(tc.wantUpgradedGroups, slices.Collect(maps.Keys(kubeUpgrader.upgradedNodeGroups)))
+			if !tc.wantErr {
+				assert.Equal(tc.wantNodeGroupVersions, stateFile.NodeGroupVersions)
+			}
 		})
 	}
 }
 
+func TestValidateTargetGroups(t *testing.T) {
+	nodeGroups := config.Default().NodeGroups
+	nodeGroups["worker-canary"] = config.NodeGroup{Role: "worker"}
+
+	assert.NoError(t, validateTargetGroups([]string{"worker-canary"}, nodeGroups))
+	assert.NoError(t, validateTargetGroups([]string{constants.DefaultWorkerGroupName, "worker-canary"}, nodeGroups))
+	assert.Error(t, validateTargetGroups([]string{"worker-eu"}, nodeGroups))
+}
+
 func TestBackupHelmCharts(t *testing.T) {
 	testCases := map[string]struct {
 		helmApplier      helm.Applier
@@ -213,6 +1540,131 @@ func TestBackupHelmCharts(t *testing.T) {
 	}
 }
 
+func TestRunTerraformApplyExistingNetwork(t *testing.T) {
+	testCases := map[string]struct {
+		subnetCIDR string
+		wantPlan   bool
+		wantErr    bool
+	}{
+		"no existing network": {
+			wantPlan: true,
+		},
+		"existing network matches node CIDR": {
+			subnetCIDR: "192.0.2.0/24",
+			wantPlan:   true,
+		},
+		"existing network doesn't match node CIDR": {
+			subnetCIDR: "198.51.100.0/24",
+			wantErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			conf := config.Default()
+			conf.RemoveProviderAndAttestationExcept(cloudprovider.Azure)
+			conf.Provider.Azure.SubnetCIDR = tc.subnetCIDR
+			stateFile := defaultStateFile(cloudprovider.Azure)
+			stateFile.Infrastructure.IPCidrNode = "192.0.2.0/24"
+
+			tfApplier := &mockTerraformUpgrader{}
+			tfApplier.On("ValidateCredentials", mock.Anything, conf).Return(nil)
+			if tc.wantPlan {
+				tfApplier.On("WorkingDirIsEmpty").Return(false, nil)
+				tfApplier.On("Plan", mock.Anything, conf).Return(false, nil)
+			}
+			a := &applyCmd{
+				log:     logger.NewTest(t),
+				spinner: &nopSpinner{},
+				newInfraApplier: func(context.Context) (cloudApplier, func(), error) {
+					return tfApplier, func() {}, nil
+				},
+			}
+			cmd := NewApplyCmd()
+			cmd.SetContext(context.Background())
+
+			err := a.runTerraformApply(cmd, conf, stateFile, "test")
+			tfApplier.AssertExpectations(t)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+		})
+	}
+}
+
+func TestRunTerraformApplyDiskEncryptionKey(t *testing.T) {
+	const key = "projects/my-project/locations/europe-west3/keyRings/constellation/cryptoKeys/disks"
+
+	testCases := map[string]struct {
+		configKey string
+		stateKey  string
+		wantPlan  bool
+		wantErr   bool
+	}{
+		"no disk encryption key": {
+			wantPlan: true,
+		},
+		"key matches state": {
+			configKey: key,
+			stateKey:  key,
+			wantPlan:  true,
+		},
+		"key added after creation": {
+			configKey: key,
+			wantErr:   true,
+		},
+		"key removed after creation": {
+			stateKey: key,
+			wantErr:  true,
+		},
+		"key changed after creation": {
+			configKey: key,
+			stateKey:  "projects/my-project/locations/europe-west3/keyRings/constellation/cryptoKeys/other",
+			wantErr:   true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			conf := config.Default()
+			conf.RemoveProviderAndAttestationExcept(cloudprovider.GCP)
+			conf.Provider.GCP.DiskEncryptionKey = tc.configKey
+			stateFile := defaultStateFile(cloudprovider.GCP)
+			stateFile.Infrastructure.GCP.DiskEncryptionKey = tc.stateKey
+
+			tfApplier := &mockTerraformUpgrader{}
+			tfApplier.On("ValidateCredentials", mock.Anything, conf).Return(nil)
+			if tc.wantPlan {
+				tfApplier.On("WorkingDirIsEmpty").Return(false, nil)
+				tfApplier.On("Plan", mock.Anything, conf).Return(false, nil)
+			}
+			a := &applyCmd{
+				log:     logger.NewTest(t),
+				spinner: &nopSpinner{},
+				newInfraApplier: func(context.Context) (cloudApplier, func(), error) {
+					return tfApplier, func() {}, nil
+				},
+			}
+			cmd := NewApplyCmd()
+			cmd.SetContext(context.Background())
+
+			err := a.runTerraformApply(cmd, conf, stateFile, "test")
+			tfApplier.AssertExpectations(t)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+		})
+	}
+}
+
 func TestSkipPhases(t *testing.T) {
 	require := require.New(t)
 	assert := assert.New(t)
@@ -221,6 +1673,9 @@ func TestSkipPhases(t *testing.T) {
 	cmd.Flags().String("workspace", "", "")
 	cmd.Flags().Bool("force", true, "")
 	cmd.Flags().String("tf-log", "NONE", "")
+	cmd.Flags().String("profile", "", "")
+	cmd.Flags().String("config", "", "")
+	cmd.Flags().String("kubeconfig", "", "")
 	cmd.Flags().Bool("debug", false, "")
 
 	require.NoError(cmd.Flags().Set("skip-phases", strings.Join(allPhases(), ",")))
@@ -322,6 +1777,58 @@ func TestValidateInputs(t *testing.T) {
 			flags:              applyFlags{},
 			wantPhases:         newPhases(skipInitPhase),
 		},
+		"[upgrade] azure: unchanged OIDC config": {
+			createConfig: func(require *require.Assertions, fh file.Handler) {
+				cfg := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.Azure)
+				cfg.OIDC = &config.OIDCConfig{IssuerURL: "https://login.example.com", ClientID: "constellation"}
+				require.NoError(fh.WriteYAML(constants.ConfigFilename, cfg))
+			},
+			createState: func(require *require.Assertions, fh file.Handler) {
+				stateFile := defaultStateFile(cloudprovider.Azure)
+				stateFile.ClusterValues.OIDC = &config.OIDCConfig{IssuerURL: "https://login.example.com", ClientID: "constellation"}
+				require.NoError(fh.WriteYAML(constants.StateFilename, stateFile))
+			},
+			createMasterSecret: defaultMasterSecret,
+			createAdminConfig:  defaultAdminConfig,
+			createTfState:      defaultTfState,
+			flags:              applyFlags{},
+			wantPhases:         newPhases(skipInitPhase),
+		},
+		"[upgrade] azure: OIDC config changed after init": {
+			createConfig: func(require *require.Assertions, fh file.Handler) {
+				cfg := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.Azure)
+				cfg.OIDC = &config.OIDCConfig{IssuerURL: "https://login.example.com", ClientID: "constellation"}
+				require.NoError(fh.WriteYAML(constants.ConfigFilename, cfg))
+			},
+			createState:        postInitState(cloudprovider.Azure),
+			createMasterSecret: defaultMasterSecret,
+			createAdminConfig:  defaultAdminConfig,
+			createTfState:      defaultTfState,
+			flags:              applyFlags{},
+			wantErr:            true,
+		},
+		"[upgrade] azure: helm namespace matches state": {
+			createConfig: defaultConfig(cloudprovider.Azure),
+			createState: func(require *require.Assertions, fh file.Handler) {
+				stateFile := defaultStateFile(cloudprovider.Azure)
+				stateFile.ClusterValues.HelmNamespace = "constellation-system"
+				require.NoError(fh.WriteYAML(constants.StateFilename, stateFile))
+			},
+			createMasterSecret: defaultMasterSecret,
+			createAdminConfig:  defaultAdminConfig,
+			createTfState:      defaultTfState,
+			flags:              applyFlags{helmNamespace: "constellation-system"},
+			wantPhases:         newPhases(skipInitPhase),
+		},
+		"[upgrade] azure: helm namespace changed after init": {
+			createConfig:       defaultConfig(cloudprovider.Azure),
+			createState:        postInitState(cloudprovider.Azure),
+			createMasterSecret: defaultMasterSecret,
+			createAdminConfig:  defaultAdminConfig,
+			createTfState:      defaultTfState,
+			flags:              applyFlags{helmNamespace: "constellation-system"},
+			wantErr:            true,
+		},
 		"[upgrade] qemu: all files exist": {
 			createConfig:       defaultConfig(cloudprovider.QEMU),
 			createState:        postInitState(cloudprovider.QEMU),
@@ -522,6 +2029,71 @@ func newPhases(phases ...skipPhase) skipPhases {
 	return skipPhases
 }
 
+// recordingHelmApplier records the options passed to PrepareHelmCharts.
+type recordingHelmApplier struct {
+	stubHelmApplier
+	options helm.Options
+}
+
+func (r *recordingHelmApplier) PrepareHelmCharts(
+	options helm.Options, _ *state.State, _ string, _ uri.MasterSecret,
+) (helm.Applier, bool, error) {
+	r.options = options
+	return stubRunner{}, false, nil
+}
+
+// upgradingHelmApplier returns an executor that includes upgrades.
+type upgradingHelmApplier struct {
+	stubHelmApplier
+	executor *recordingRunner
+}
+
+func (u *upgradingHelmApplier) PrepareHelmCharts(
+	_ helm.Options, _ *state.State, _ string, _ uri.MasterSecret,
+) (helm.Applier, bool, error) {
+	return u.executor, true, nil
+}
+
+// recordingRunner records whether the Helm charts were saved and applied.
+type recordingRunner struct {
+	savedCharts bool
+	applied     bool
+	images      []string
+	imagesErr   error
+}
+
+func (r *recordingRunner) Apply(_ context.Context) error {
+	r.applied = true
+	return nil
+}
+
+func (r *recordingRunner) SaveCharts(_ string, _ file.Handler) error {
+	r.savedCharts = true
+	return nil
+}
+
+func (r *recordingRunner) Images() ([]string, error) {
+	return r.images, r.imagesErr
+}
+
+// prePullingApplier records the pre-pulled images and whether the Helm charts were applied before.
+type prePullingApplier struct {
+	*stubConstellApplier
+	executor             *recordingRunner
+	prePulled            []string
+	appliedBeforePrePull bool
+	prePullErr           error
+}
+
+func (p *prePullingApplier) PrePullImages(ctx context.Context, images []string) error {
+	p.prePulled = images
+	p.appliedBeforePrePull = p.executor.applied
+	if _, ok := ctx.Deadline(); !ok {
+		return errors.New("pre-pull isn't bounded by a timeout")
+	}
+	return p.prePullErr
+}
+
 type stubConstellApplier struct {
 	checkLicenseErr            error
 	masterSecret               uri.MasterSecret
@@ -530,12 +2102,15 @@ type stubConstellApplier struct {
 	generateMeasurementSaltErr error
 	initErr                    error
 	initOutput                 constellation.InitOutput
+	gotInitPayload             constellation.InitPayload
 	*stubKubernetesUpgrader
 	helmApplier
 }
 
 func (s *stubConstellApplier) SetKubeConfig([]byte) error { return nil }
 
+func (s *stubConstellApplier) CheckAPIServerReachable() error { return nil }
+
 func (s *stubConstellApplier) CheckLicense(context.Context, cloudprovider.Provider, bool, string) (int, error) {
 	return 0, s.checkLicenseErr
 }
@@ -548,7 +2123,8 @@ func (s *stubConstellApplier) GenerateMeasurementSalt() ([]byte, error) {
 	return s.measurementSalt, s.generateMeasurementSaltErr
 }
 
-func (s *stubConstellApplier) Init(context.Context, atls.Validator, *state.State, io.Writer, constellation.InitPayload) (constellation.InitOutput, error) {
+func (s *stubConstellApplier) Init(_ context.Context, _ atls.Validator, _ *state.State, _ io.Writer, payload constellation.InitPayload) (constellation.InitOutput, error) {
+	s.gotInitPayload = payload
 	return s.initOutput, s.initErr
 }
 
@@ -0,0 +1,56 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/edgelesssys/constellation/v2/cli/internal/cloudcmd"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// resourceAdopter finds the cloud resources a previous apply created for a cluster without recording them in a Terraform state.
+type resourceAdopter interface {
+	Detect(ctx context.Context, conf *config.Config) ([]cloudcmd.AdoptableResource, error)
+}
+
+// adoptResources looks for the cloud resources of the cluster that exist, but aren't managed by Terraform yet,
+// and adopts them into the Terraform state after confirmation, so Terraform doesn't try to create them again.
+func (a *applyCmd) adoptResources(cmd *cobra.Command, conf *config.Config, terraformClient cloudApplier) error {
+	a.spinner.Start("Looking for existing resources of the cluster ", false)
+	resources, err := a.adopter.Detect(cmd.Context(), conf)
+	a.spinner.Stop()
+	if err != nil {
+		return fmt.Errorf("looking for existing resources of the cluster: %w", err)
+	}
+	if len(resources) == 0 {
+		cmd.Println("No existing resources of the cluster found.")
+		return nil
+	}
+
+	cmd.Println("The following resources of the cluster exist, but aren't managed by Terraform:")
+	for _, resource := range resources {
+		cmd.Printf("  %s\n", resource)
+	}
+	if !a.flags.yes {
+		ok, err := askToConfirm(cmd, "Do you want to adopt these resources instead of creating them?")
+		if err != nil {
+			return fmt.Errorf("asking for confirmation: %w", err)
+		}
+		if !ok {
+			cmd.Println("Adopting the existing resources was aborted.")
+			return errors.New("adopting existing resources aborted by user")
+		}
+	}
+
+	a.log.Debug("Adopting existing resources", "resources", resources)
+	terraformClient.Adopt(resources)
+	return nil
+}
@@ -0,0 +1,140 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/cli/internal/cloudcmd"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunTerraformApplyDetectAndAdopt(t *testing.T) {
+	existing := []cloudcmd.AdoptableResource{
+		{
+			Resource: cloudcmd.Resource{Kind: cloudcmd.ResourceKindNetworkSecurityGroup, ID: "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/networkSecurityGroups/constell-0a1b2c3d"},
+			Address:  "azurerm_network_security_group.security_group",
+		},
+		{
+			Resource: cloudcmd.Resource{Kind: cloudcmd.ResourceKindClusterUID, ID: "0a1b2c3d"},
+			Address:  "random_id.uid",
+		},
+	}
+
+	testCases := map[string]struct {
+		detectAndAdopt   bool
+		yes              bool
+		stdin            string
+		workspaceIsEmpty bool
+		adopter          *stubResourceAdopter
+		wantDetect       bool
+		wantAdopted      []cloudcmd.AdoptableResource
+		wantErr          bool
+	}{
+		"existing resources are adopted": {
+			detectAndAdopt:   true,
+			yes:              true,
+			workspaceIsEmpty: true,
+			adopter:          &stubResourceAdopter{resources: existing},
+			wantDetect:       true,
+			wantAdopted:      existing,
+		},
+		"adoption confirmed": {
+			detectAndAdopt:   true,
+			stdin:            "y\n",
+			workspaceIsEmpty: true,
+			adopter:          &stubResourceAdopter{resources: existing},
+			wantDetect:       true,
+			wantAdopted:      existing,
+		},
+		"adoption declined": {
+			detectAndAdopt:   true,
+			stdin:            "n\n",
+			workspaceIsEmpty: true,
+			adopter:          &stubResourceAdopter{resources: existing},
+			wantDetect:       true,
+			wantErr:          true,
+		},
+		"no existing resources": {
+			detectAndAdopt:   true,
+			yes:              true,
+			workspaceIsEmpty: true,
+			adopter:          &stubResourceAdopter{},
+			wantDetect:       true,
+		},
+		"detection fails": {
+			detectAndAdopt:   true,
+			yes:              true,
+			workspaceIsEmpty: true,
+			adopter:          &stubResourceAdopter{err: errors.New("failed")},
+			wantDetect:       true,
+			wantErr:          true,
+		},
+		"workspace isn't empty": {
+			detectAndAdopt: true,
+			yes:            true,
+			adopter:        &stubResourceAdopter{resources: existing},
+		},
+		"flag not set": {
+			yes:              true,
+			workspaceIsEmpty: true,
+			adopter:          &stubResourceAdopter{resources: existing},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			conf := config.Default()
+			conf.RemoveProviderAndAttestationExcept(cloudprovider.Azure)
+			// All resources of the cluster were adopted, so Terraform has nothing left to create.
+			tfApplier := &stubCloudCreator{workspaceIsEmpty: tc.workspaceIsEmpty}
+			a := &applyCmd{
+				flags:   applyFlags{yes: tc.yes, detectAndAdopt: tc.detectAndAdopt},
+				log:     logger.NewTest(t),
+				spinner: &nopSpinner{},
+				newInfraApplier: func(context.Context) (cloudApplier, func(), error) {
+					return tfApplier, func() {}, nil
+				},
+				adopter: tc.adopter,
+			}
+			cmd := NewApplyCmd()
+			cmd.SetContext(context.Background())
+			cmd.SetIn(bytes.NewBufferString(tc.stdin))
+			cmd.SetOut(&bytes.Buffer{})
+
+			err := a.runTerraformApply(cmd, conf, state.New(), "test")
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			assert.Equal(tc.wantDetect, tc.adopter.called)
+			assert.Equal(tc.wantAdopted, tfApplier.adopted)
+			assert.False(tfApplier.applyCalled)
+		})
+	}
+}
+
+type stubResourceAdopter struct {
+	resources []cloudcmd.AdoptableResource
+	err       error
+	called    bool
+}
+
+func (a *stubResourceAdopter) Detect(context.Context, *config.Config) ([]cloudcmd.AdoptableResource, error) {
+	a.called = true
+	return a.resources, a.err
+}
@@ -0,0 +1,48 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/edgelesssys/constellation/v2/internal/api/versionsapi"
+)
+
+// channelFetcher fetches the latest version of a release channel.
+type channelFetcher interface {
+	FetchVersionLatest(ctx context.Context, latest versionsapi.Latest) (versionsapi.Latest, error)
+}
+
+// channelSource returns the ref and stream of the versions API the given release channel is published to.
+func channelSource(channel string) (ref, stream string, err error) {
+	switch channel {
+	case "stable", "beta":
+		return versionsapi.ReleaseRef, channel, nil
+	case "nightly":
+		return "main", "nightly", nil
+	default:
+		return "", "", fmt.Errorf("unknown channel %q", channel)
+	}
+}
+
+// resolveChannelImage returns the short path of the latest image of the given release channel.
+func resolveChannelImage(ctx context.Context, fetcher channelFetcher, channel string) (string, error) {
+	ref, stream, err := channelSource(channel)
+	if err != nil {
+		return "", err
+	}
+	latest, err := fetcher.FetchVersionLatest(ctx, versionsapi.Latest{
+		Ref:    ref,
+		Stream: stream,
+		Kind:   versionsapi.VersionKindImage,
+	})
+	if err != nil {
+		return "", fmt.Errorf("fetching latest image of channel %s: %w", channel, err)
+	}
+	return latest.ShortPath(), nil
+}
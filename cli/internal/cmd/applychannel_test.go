@@ -0,0 +1,120 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/api/versionsapi"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunImagePhaseChannel(t *testing.T) {
+	versionSource := stubChannelFetcher{latest: map[string]string{
+		"-/stable":     "v2.17.0",
+		"-/beta":       "v2.18.0-rc.1",
+		"main/nightly": "v2.19.0-pre.0.20240603123456-0123456789ab",
+	}}
+
+	testCases := map[string]struct {
+		channel          string
+		fetcher          channelFetcher
+		wantImage        string
+		wantImageVersion string
+		wantErr          bool
+	}{
+		"stable": {
+			channel:          "stable",
+			fetcher:          versionSource,
+			wantImage:        "v2.17.0",
+			wantImageVersion: "v2.17.0",
+		},
+		"beta": {
+			channel:          "beta",
+			fetcher:          versionSource,
+			wantImage:        "stream/beta/v2.18.0-rc.1",
+			wantImageVersion: "v2.18.0-rc.1",
+		},
+		"nightly": {
+			channel:          "nightly",
+			fetcher:          versionSource,
+			wantImage:        "ref/main/stream/nightly/v2.19.0-pre.0.20240603123456-0123456789ab",
+			wantImageVersion: "v2.19.0-pre.0.20240603123456-0123456789ab",
+		},
+		"no channel": {
+			fetcher:          stubChannelFetcher{fetchErr: assert.AnError},
+			wantImageVersion: constants.BinaryVersion().String(),
+		},
+		"fetching latest version fails": {
+			channel: "stable",
+			fetcher: stubChannelFetcher{fetchErr: assert.AnError},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			stateFile := defaultStateFile(cloudprovider.Azure)
+			require.NoError(stateFile.WriteToFile(fileHandler, constants.StateFilename))
+			conf := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.Azure)
+			conf.Channel = tc.channel
+
+			cmd := NewApplyCmd()
+			cmd.SetOut(&bytes.Buffer{})
+			cmd.SetErr(&bytes.Buffer{})
+			kubeUpgrader := &stubKubernetesUpgrader{}
+			a := &applyCmd{
+				fileHandler:    fileHandler,
+				log:            logger.NewTest(t),
+				applier:        &stubConstellApplier{stubKubernetesUpgrader: kubeUpgrader},
+				imageFetcher:   &stubImageFetcher{},
+				channelFetcher: tc.fetcher,
+			}
+			s := &applyState{cmd: cmd, conf: conf, stateFile: stateFile, kubeConfigSet: true}
+
+			err := a.runImagePhase(context.Background(), s)
+			if tc.wantErr {
+				assert.Error(err)
+				assert.False(kubeUpgrader.calledNodeUpgrade)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tc.wantImageVersion, kubeUpgrader.upgradedNodeImage.String())
+
+			gotState, err := state.ReadFromFile(fileHandler, constants.StateFilename)
+			require.NoError(err)
+			assert.Equal(tc.wantImage, gotState.ResolvedImage)
+		})
+	}
+}
+
+type stubChannelFetcher struct {
+	latest   map[string]string
+	fetchErr error
+}
+
+func (f stubChannelFetcher) FetchVersionLatest(_ context.Context, latest versionsapi.Latest) (versionsapi.Latest, error) {
+	if f.fetchErr != nil {
+		return versionsapi.Latest{}, f.fetchErr
+	}
+	latest.Version = f.latest[latest.Ref+"/"+latest.Stream]
+	return latest, nil
+}
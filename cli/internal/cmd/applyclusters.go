@@ -0,0 +1,275 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// defaultMaxParallelClusters is the default number of clusters applied at the same time with --clusters.
+const defaultMaxParallelClusters = 4
+
+// multiClusterFlags are the flags of apply that are handled by the invocation applying all clusters,
+// and are not passed on to the apply of a single cluster.
+var multiClusterFlags = []string{"clusters", "max-parallel-clusters", "workspace"}
+
+// clusterSpecificFlags are the flags of apply whose values refer to a single cluster, like its kubeconfig or master secret.
+// They can't be passed on to the applies of several clusters.
+var clusterSpecificFlags = []string{"kubeconfig", "master-secret-in", "config", "since-state"}
+
+// clusterApplier applies the config of the cluster in a workspace.
+type clusterApplier interface {
+	applyCluster(ctx context.Context, workspace string, out io.Writer) error
+}
+
+// subprocessClusterApplier runs "constellation apply" for a workspace in a child process of the CLI.
+// The paths of an apply, like the Terraform workspace, are relative to the working directory of the process,
+// so every cluster needs a process of its own to be applied at the same time as other clusters.
+type subprocessClusterApplier struct {
+	executable string
+	// args are the flags passed to the apply of every cluster.
+	args []string
+}
+
+// applyCluster runs the apply for workspace and writes its stdout and stderr to out.
+func (a subprocessClusterApplier) applyCluster(ctx context.Context, workspace string, out io.Writer) error {
+	args := append([]string{"apply", "--workspace", workspace}, a.args...)
+	applyCmd := exec.CommandContext(ctx, a.executable, args...)
+	applyCmd.Stdout = out
+	applyCmd.Stderr = out
+	// Spinners of concurrent applies would garble the combined output.
+	applyCmd.Env = append(os.Environ(), constants.EnvVarNoSpinner+"=1")
+	return applyCmd.Run()
+}
+
+// clusterApplyResult is the result of the apply of a single cluster.
+type clusterApplyResult struct {
+	name     string
+	duration time.Duration
+	err      error
+}
+
+type multiClusterApplyCmd struct {
+	fileHandler  file.Handler
+	clustersDir  string
+	maxParallel  int
+	applier      clusterApplier
+	pathPrefixer func(string) string
+	log          debugLog
+	// clusterSpecificFlags are the flags in clusterSpecificFlags set on the command line.
+	clusterSpecificFlags []string
+}
+
+// runMultiClusterApply applies the config of every cluster in the directory given by --clusters.
+// The flags of the invocation, except for the flags in multiClusterFlags, are passed on to the apply of every cluster.
+// The flags in clusterSpecificFlags are only accepted if the directory contains a single cluster.
+func runMultiClusterApply(cmd *cobra.Command, flags applyFlags, log debugLog) error {
+	clustersDir, err := cmd.Flags().GetString("clusters")
+	if err != nil {
+		return fmt.Errorf("getting 'clusters' flag: %w", err)
+	}
+	maxParallel, err := cmd.Flags().GetInt("max-parallel-clusters")
+	if err != nil {
+		return fmt.Errorf("getting 'max-parallel-clusters' flag: %w", err)
+	}
+	if maxParallel < 1 {
+		return errors.New("flag 'max-parallel-clusters' must be at least 1")
+	}
+	if !flags.yes {
+		return errors.New("flag 'clusters' requires 'yes', since the applies of the clusters can't ask for confirmation")
+	}
+	if flags.output == applyOutputNDJSON {
+		return errors.New("flag 'clusters' can't be combined with 'output' ndjson")
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("getting path of the CLI executable: %w", err)
+	}
+
+	m := &multiClusterApplyCmd{
+		fileHandler:  file.NewHandler(afero.NewOsFs()),
+		clustersDir:  clustersDir,
+		maxParallel:  maxParallel,
+		applier:      subprocessClusterApplier{executable: executable, args: forwardedApplyArgs(cmd.Flags())},
+		pathPrefixer: flags.pathPrefixer.PrefixPrintablePath,
+		log:          log,
+	}
+	for _, name := range clusterSpecificFlags {
+		if cmd.Flags().Changed(name) {
+			m.clusterSpecificFlags = append(m.clusterSpecificFlags, name)
+		}
+	}
+	return m.apply(cmd)
+}
+
+// apply applies the config of all clusters in the clusters directory, with at most maxParallel clusters at the same time.
+// The output of every cluster is prefixed with the name of its workspace.
+// An error is returned if the apply of any cluster fails.
+func (m *multiClusterApplyCmd) apply(cmd *cobra.Command) error {
+	names, err := m.findClusters()
+	if err != nil {
+		return err
+	}
+	if len(names) > 1 && len(m.clusterSpecificFlags) > 0 {
+		return fmt.Errorf("flags %s refer to a single cluster and can't be used to apply %d clusters: run apply for each cluster instead",
+			formatFlagNames(m.clusterSpecificFlags), len(names))
+	}
+	m.log.Debug("Applying clusters", "clusters", names, "maxParallel", m.maxParallel)
+
+	var outMux sync.Mutex
+	results := make([]clusterApplyResult, len(names))
+	sem := make(chan struct{}, m.maxParallel)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			out := &prefixWriter{out: cmd.OutOrStdout(), prefix: "[" + name + "] ", mux: &outMux}
+			start := time.Now()
+			err := m.applier.applyCluster(cmd.Context(), filepath.Join(m.clustersDir, name), out)
+			out.flush()
+			results[i] = clusterApplyResult{name: name, duration: time.Since(start), err: err}
+		}()
+	}
+	wg.Wait()
+
+	cmd.Println()
+	var failed int
+	for _, result := range results {
+		if result.err != nil {
+			cmd.Printf("FAIL\t%s (%s): %s\n", result.name, result.duration.Round(time.Second), result.err)
+			failed++
+			continue
+		}
+		cmd.Printf("OK\t%s (%s)\n", result.name, result.duration.Round(time.Second))
+	}
+	cmd.Printf("\n%d applied, %d failed\n", len(results)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d clusters failed to apply", failed, len(results))
+	}
+	return nil
+}
+
+// findClusters returns the names of the subdirectories of the clusters directory that contain a config and a state file.
+func (m *multiClusterApplyCmd) findClusters() ([]string, error) {
+	entries, err := m.fileHandler.ReadDir(m.clustersDir)
+	if err != nil {
+		return nil, fmt.Errorf("reading clusters directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		workspace := filepath.Join(m.clustersDir, entry.Name())
+		if !m.hasFile(workspace, constants.ConfigFilename) || !m.hasFile(workspace, constants.StateFilename) {
+			m.log.Debug(fmt.Sprintf("Skipping %q, it has no config and state file", workspace))
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no clusters found in %q: every cluster needs a directory with a %s and a %s",
+			m.pathPrefixer(m.clustersDir), constants.ConfigFilename, constants.StateFilename)
+	}
+	return names, nil
+}
+
+func (m *multiClusterApplyCmd) hasFile(workspace, name string) bool {
+	_, err := m.fileHandler.Stat(filepath.Join(workspace, name))
+	return err == nil
+}
+
+// forwardedApplyArgs returns the flags set on the command line, except for the flags in multiClusterFlags.
+func forwardedApplyArgs(flags *pflag.FlagSet) []string {
+	var args []string
+	flags.Visit(func(flag *pflag.Flag) {
+		for _, name := range multiClusterFlags {
+			if flag.Name == name {
+				return
+			}
+		}
+		value := flag.Value.String()
+		if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+			value = strings.Join(sliceValue.GetSlice(), ",")
+		}
+		if strings.HasPrefix(flag.Value.Type(), "stringTo") {
+			// Maps are printed as "[KEY=VALUE,...]", but parsed without brackets.
+			value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+		}
+		args = append(args, "--"+flag.Name+"="+value)
+	})
+	return args
+}
+
+// formatFlagNames returns the given flag names as a comma-separated list of quoted flags, e.g., "'kubeconfig', 'config'".
+func formatFlagNames(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = "'" + name + "'"
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// prefixWriter writes complete lines to out, each prefixed with prefix.
+// Writers sharing the same mutex don't interleave their lines.
+type prefixWriter struct {
+	out    io.Writer
+	prefix string
+	mux    *sync.Mutex
+	buf    bytes.Buffer
+}
+
+// Write writes all complete lines of p to the underlying writer. Incomplete lines are buffered.
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		idx := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if idx < 0 {
+			return len(p), nil
+		}
+		if err := w.writeLine(w.buf.Next(idx + 1)); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+// flush writes the remaining incomplete line.
+func (w *prefixWriter) flush() {
+	if w.buf.Len() > 0 {
+		_ = w.writeLine(append(w.buf.Bytes(), '\n'))
+		w.buf.Reset()
+	}
+}
+
+func (w *prefixWriter) writeLine(line []byte) error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	_, err := fmt.Fprintf(w.out, "%s%s", w.prefix, line)
+	return err
+}
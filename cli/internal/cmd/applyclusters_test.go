@@ -0,0 +1,237 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiClusterApply(t *testing.T) {
+	testCases := map[string]struct {
+		clusters             []string
+		failing              map[string]bool
+		maxParallel          int
+		clusterSpecificFlags []string
+		wantApplied          []string
+		wantOutput           []string
+		wantErr              bool
+		wantErrCount         string
+	}{
+		"all clusters succeed": {
+			clusters:    []string{"dev", "prod", "staging"},
+			maxParallel: 2,
+			wantApplied: []string{"dev", "prod", "staging"},
+			wantOutput:  []string{"[dev] applied dev", "OK\tprod", "3 applied, 0 failed"},
+		},
+		"one cluster fails": {
+			clusters:     []string{"a", "b", "c", "d", "e"},
+			failing:      map[string]bool{"c": true},
+			maxParallel:  2,
+			wantApplied:  []string{"a", "b", "c", "d", "e"},
+			wantOutput:   []string{"FAIL\tc", "OK\td", "4 applied, 1 failed"},
+			wantErr:      true,
+			wantErrCount: "1 of 5 clusters failed to apply",
+		},
+		"sequential": {
+			clusters:    []string{"a", "b", "c"},
+			maxParallel: 1,
+			wantApplied: []string{"a", "b", "c"},
+			wantOutput:  []string{"3 applied, 0 failed"},
+		},
+		"no clusters": {
+			maxParallel: 2,
+			wantErr:     true,
+		},
+		"cluster specific flags with several clusters": {
+			clusters:             []string{"dev", "prod"},
+			maxParallel:          2,
+			clusterSpecificFlags: []string{"kubeconfig", "master-secret-in"},
+			wantErr:              true,
+			wantErrCount:         "'kubeconfig', 'master-secret-in' refer to a single cluster",
+		},
+		"cluster specific flags with a single cluster": {
+			clusters:             []string{"dev"},
+			maxParallel:          2,
+			clusterSpecificFlags: []string{"kubeconfig"},
+			wantApplied:          []string{"dev"},
+			wantOutput:           []string{"1 applied, 0 failed"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			for _, cluster := range tc.clusters {
+				require.NoError(fileHandler.Write(filepath.Join("clusters", cluster, constants.ConfigFilename), []byte("config"), file.OptMkdirAll))
+				require.NoError(fileHandler.Write(filepath.Join("clusters", cluster, constants.StateFilename), []byte("state"), file.OptMkdirAll))
+			}
+			// Directories without a config and state file, and files, aren't clusters.
+			require.NoError(fileHandler.Write(filepath.Join("clusters", "no-state", constants.ConfigFilename), []byte("config"), file.OptMkdirAll))
+			require.NoError(fileHandler.Write(filepath.Join("clusters", "README.md"), []byte("readme"), file.OptMkdirAll))
+
+			applier := &stubClusterApplier{failing: tc.failing}
+			m := &multiClusterApplyCmd{
+				fileHandler:          fileHandler,
+				clustersDir:          "clusters",
+				maxParallel:          tc.maxParallel,
+				applier:              applier,
+				pathPrefixer:         func(path string) string { return path },
+				log:                  logger.NewTest(t),
+				clusterSpecificFlags: tc.clusterSpecificFlags,
+			}
+
+			cmd := NewApplyCmd()
+			cmd.SetContext(context.Background())
+			out := &bytes.Buffer{}
+			cmd.SetOut(out)
+
+			err := m.apply(cmd)
+			if tc.wantErr {
+				require.Error(err)
+				assert.Contains(err.Error(), tc.wantErrCount)
+			} else {
+				require.NoError(err)
+			}
+
+			var wantWorkspaces []string
+			for _, cluster := range tc.wantApplied {
+				wantWorkspaces = append(wantWorkspaces, filepath.Join("clusters", cluster))
+			}
+			assert.ElementsMatch(wantWorkspaces, applier.applied)
+			assert.LessOrEqual(applier.maxActive, tc.maxParallel)
+			for _, want := range tc.wantOutput {
+				assert.Contains(out.String(), want)
+			}
+		})
+	}
+}
+
+func TestMultiClusterApplyParallelism(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fileHandler := file.NewHandler(afero.NewMemMapFs())
+	for i := 0; i < 6; i++ {
+		workspace := filepath.Join("clusters", fmt.Sprintf("cluster-%d", i))
+		require.NoError(fileHandler.Write(filepath.Join(workspace, constants.ConfigFilename), []byte("config"), file.OptMkdirAll))
+		require.NoError(fileHandler.Write(filepath.Join(workspace, constants.StateFilename), []byte("state"), file.OptMkdirAll))
+	}
+
+	// The applies block until the number of active applies reached the bound,
+	// so the test only finishes if the clusters are applied in parallel.
+	applier := &stubClusterApplier{waitForActive: 3}
+	m := &multiClusterApplyCmd{
+		fileHandler:  fileHandler,
+		clustersDir:  "clusters",
+		maxParallel:  3,
+		applier:      applier,
+		pathPrefixer: func(path string) string { return path },
+		log:          logger.NewTest(t),
+	}
+	cmd := NewApplyCmd()
+	cmd.SetContext(context.Background())
+	cmd.SetOut(&bytes.Buffer{})
+
+	require.NoError(m.apply(cmd))
+	assert.Len(applier.applied, 6)
+	assert.Equal(3, applier.maxActive)
+}
+
+func TestForwardedApplyArgs(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	cmd := NewApplyCmd()
+	cmd.Flags().String("workspace", "", "")
+	require.NoError(cmd.Flags().Parse([]string{
+		"--clusters", "clusters",
+		"--max-parallel-clusters", "2",
+		"--workspace", "ws",
+		"--yes",
+		"--skip-phases", "helm,image",
+		"--phase-retries", "helm=3",
+		"--helm-history-max", "5",
+	}))
+
+	assert.ElementsMatch([]string{
+		"--yes=true",
+		"--skip-phases=helm,image",
+		"--phase-retries=helm=3",
+		"--helm-history-max=5",
+	}, forwardedApplyArgs(cmd.Flags()))
+}
+
+func TestPrefixWriter(t *testing.T) {
+	assert := assert.New(t)
+
+	out := &bytes.Buffer{}
+	w := &prefixWriter{out: out, prefix: "[a] ", mux: &sync.Mutex{}}
+	_, _ = io.WriteString(w, "first line\nsecond ")
+	assert.Equal("[a] first line\n", out.String())
+	_, _ = io.WriteString(w, "line\nincomplete")
+	w.flush()
+	assert.Equal("[a] first line\n[a] second line\n[a] incomplete\n", out.String())
+}
+
+type stubClusterApplier struct {
+	failing map[string]bool
+	// waitForActive makes every apply wait until the given number of applies ran at the same time.
+	waitForActive int
+
+	mux       sync.Mutex
+	active    int
+	maxActive int
+	applied   []string
+}
+
+func (a *stubClusterApplier) applyCluster(_ context.Context, workspace string, out io.Writer) error {
+	a.mux.Lock()
+	a.active++
+	a.maxActive = max(a.maxActive, a.active)
+	a.applied = append(a.applied, workspace)
+	a.mux.Unlock()
+
+	for a.waitForActive > 0 {
+		a.mux.Lock()
+		reached := a.maxActive >= a.waitForActive
+		a.mux.Unlock()
+		if reached {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	a.mux.Lock()
+	a.active--
+	a.mux.Unlock()
+
+	name := filepath.Base(workspace)
+	fmt.Fprintf(out, "applied %s\n", name)
+	if a.failing[name] {
+		return errors.New("apply failed")
+	}
+	return nil
+}
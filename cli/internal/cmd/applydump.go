@@ -0,0 +1,40 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+	"github.com/spf13/cobra"
+)
+
+// dumpStateOnError writes the in-memory state of a failed apply to the file set with --dump-state-on-error.
+// The state may contain changes of phases that ran before the failure, which aren't written to the state file yet.
+// Secrets are redacted unless --dump-state-full is set.
+// Since the apply already failed, errors writing the dump are only printed as warnings.
+func (a *applyCmd) dumpStateOnError(cmd *cobra.Command, stateFile *state.State) {
+	if a.flags.dumpStatePath == "" || stateFile == nil {
+		return
+	}
+
+	dump := stateFile
+	if !a.flags.dumpStateFull {
+		dump = stateFile.Redacted()
+	}
+	if err := dump.WriteToFile(a.fileHandler, a.flags.dumpStatePath); err != nil {
+		a.wLog.Warn(fmt.Sprintf("Dumping state: %s", err))
+		return
+	}
+
+	path := a.flags.pathPrefixer.PrefixPrintablePath(a.flags.dumpStatePath)
+	if a.flags.dumpStateFull {
+		cmd.PrintErrf("Wrote the state at the time of the error to %s\n", path)
+		return
+	}
+	cmd.PrintErrf("Wrote the state at the time of the error to %s with secrets redacted\n", path)
+}
@@ -0,0 +1,113 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpStateOnError(t *testing.T) {
+	someErr := errors.New("failed")
+
+	testCases := map[string]struct {
+		dumpStatePath string
+		dumpStateFull bool
+		wantDumpPath  string
+		wantRedacted  bool
+	}{
+		"no dump by default": {},
+		"redacted dump": {
+			dumpStatePath: constants.StateDumpFilename,
+			wantDumpPath:  constants.StateDumpFilename,
+			wantRedacted:  true,
+		},
+		"full dump": {
+			dumpStatePath: constants.StateDumpFilename,
+			dumpStateFull: true,
+			wantDumpPath:  constants.StateDumpFilename,
+		},
+		"dump to custom path": {
+			dumpStatePath: "debug/state.yaml",
+			wantDumpPath:  "debug/state.yaml",
+			wantRedacted:  true,
+		},
+		"full dump overwrites state file if requested": {
+			dumpStatePath: constants.StateFilename,
+			dumpStateFull: true,
+			wantDumpPath:  constants.StateFilename,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fh := file.NewHandler(afero.NewMemMapFs())
+			onDisk := defaultStateFile(cloudprovider.GCP)
+			require.NoError(onDisk.WriteToFile(fh, constants.StateFilename))
+
+			a := &applyCmd{
+				fileHandler: fh,
+				flags: applyFlags{
+					dumpStatePath: tc.dumpStatePath,
+					dumpStateFull: tc.dumpStateFull,
+				},
+			}
+			cmd := NewApplyCmd()
+			cmd.SetErr(&bytes.Buffer{})
+
+			// the infrastructure phase updates the in-memory state, then the init phase fails
+			registry, err := newPhaseRegistry(
+				&fakePhase{name: skipInfrastructurePhase, run: func(s *applyState) {
+					s.stateFile.Infrastructure.ClusterEndpoint = "192.0.2.2"
+				}},
+				&fakePhase{name: skipInitPhase, err: someErr},
+			)
+			require.NoError(err)
+			s := &applyState{cmd: cmd, stateFile: defaultStateFile(cloudprovider.GCP)}
+			err = registry.run(context.Background(), s, nil, true)
+			require.ErrorIs(err, someErr)
+
+			a.dumpStateOnError(cmd, s.stateFile)
+
+			if tc.wantDumpPath == "" {
+				_, err := fh.Stat(constants.StateDumpFilename)
+				assert.ErrorIs(err, afero.ErrFileNotFound)
+				return
+			}
+			dump, err := state.ReadFromFile(fh, tc.wantDumpPath)
+			require.NoError(err)
+			assert.Equal("192.0.2.2", dump.Infrastructure.ClusterEndpoint)
+			if tc.wantRedacted {
+				assert.Empty(dump.Infrastructure.InitSecret)
+				assert.Empty(dump.ClusterValues.MeasurementSalt)
+			} else {
+				assert.Equal(s.stateFile.Infrastructure.InitSecret, dump.Infrastructure.InitSecret)
+				assert.Equal(s.stateFile.ClusterValues.MeasurementSalt, dump.ClusterValues.MeasurementSalt)
+			}
+
+			if tc.wantDumpPath != constants.StateFilename {
+				// the canonical state file is left untouched
+				stateFile, err := state.ReadFromFile(fh, constants.StateFilename)
+				require.NoError(err)
+				assert.Equal(onDisk.Infrastructure.ClusterEndpoint, stateFile.Infrastructure.ClusterEndpoint)
+			}
+		})
+	}
+}
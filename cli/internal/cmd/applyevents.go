@@ -0,0 +1,183 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/kubernetes/kubectl"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// defaultEventRetryInterval is the time waited before watching Kubernetes events is retried.
+const defaultEventRetryInterval = 5 * time.Second
+
+// relevantEventReasons are the reasons of the Kubernetes events streamed by --watch-events.
+// They cover pod scheduling and image pulls, which is where a stalled initialization usually hangs.
+var relevantEventReasons = map[string]struct{}{
+	"Scheduled":        {},
+	"FailedScheduling": {},
+	"Pulling":          {},
+	"Pulled":           {},
+	"Failed":           {},
+	"BackOff":          {},
+}
+
+// eventWatcher watches Kubernetes events.
+type eventWatcher interface {
+	WatchEvents(ctx context.Context, resourceVersion string) (watch.Interface, error)
+}
+
+// newKubeEventWatcher creates an eventWatcher for the Kubernetes API server at the given cluster endpoint.
+// The endpoint overrides the server of the kubeconfig, since a custom endpoint may not be resolvable yet.
+// If the endpoint is empty, the server of the kubeconfig is used.
+func newKubeEventWatcher(kubeConfig []byte, clusterEndpoint string) (eventWatcher, error) {
+	if clusterEndpoint == "" {
+		return kubectl.NewFromConfig(kubeConfig)
+	}
+	clientConfig, err := clientcmd.Load(kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("loading kubeconfig: %w", err)
+	}
+	server := "https://" + net.JoinHostPort(clusterEndpoint, strconv.Itoa(constants.KubernetesPort))
+	for _, cluster := range clientConfig.Clusters {
+		cluster.Server = server
+	}
+	kubeConfig, err = clientcmd.Write(*clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("writing kubeconfig: %w", err)
+	}
+	return kubectl.NewFromConfig(kubeConfig)
+}
+
+// eventStreamer writes relevant Kubernetes events to the user.
+type eventStreamer struct {
+	// connect creates the watcher. It fails until the cluster's kubeconfig is available.
+	connect       func() (eventWatcher, error)
+	out           io.Writer
+	log           debugLog
+	retryInterval time.Duration
+}
+
+// stream writes relevant events until ctx is done.
+// Failing connections and watches are retried, since the cluster might not be initialized
+// and the API server might not be reachable yet.
+func (s *eventStreamer) stream(ctx context.Context) {
+	var watcher eventWatcher
+	var resourceVersion string
+	for {
+		if watcher == nil {
+			var err error
+			watcher, err = s.connect()
+			if err != nil {
+				s.log.Debug("Connecting to the Kubernetes API server failed, retrying", "error", err)
+			}
+		}
+		if watcher != nil {
+			w, err := watcher.WatchEvents(ctx, resourceVersion)
+			if err != nil {
+				s.log.Debug("Watching Kubernetes events failed, retrying", "error", err)
+			} else {
+				resourceVersion = s.consume(ctx, w, resourceVersion)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.retryInterval):
+		}
+	}
+}
+
+// consume writes the relevant events of w until w is closed or ctx is done.
+// It returns the resource version to resume watching from.
+func (s *eventStreamer) consume(ctx context.Context, w watch.Interface, resourceVersion string) string {
+	defer w.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return resourceVersion
+		case result, ok := <-w.ResultChan():
+			if !ok {
+				return resourceVersion
+			}
+			if result.Type == watch.Error {
+				// The resource version is most likely too old, start over with the current events.
+				s.log.Debug("Kubernetes event watch returned an error, restarting watch")
+				return ""
+			}
+			event, ok := result.Object.(*corev1.Event)
+			if !ok {
+				continue
+			}
+			resourceVersion = event.ResourceVersion
+			if result.Type != watch.Added {
+				continue
+			}
+			if _, ok := relevantEventReasons[event.Reason]; !ok {
+				continue
+			}
+			fmt.Fprintf(s.out, "Event %s/%s %s: %s\n",
+				event.InvolvedObject.Namespace, event.InvolvedObject.Name, event.Reason, event.Message)
+		}
+	}
+}
+
+// startWatchingEvents streams relevant Kubernetes events of the cluster in the background,
+// until stopWatchingEvents is called on s or the command's context is done.
+// It may be called before the cluster is initialized: events are streamed as soon as
+// init has written the kubeconfig and the API server is reachable.
+func (a *applyCmd) startWatchingEvents(ctx context.Context, s *applyState) {
+	// A kubeconfig set by the user is used as is, instead of connecting to the endpoint in the state file.
+	endpoint := s.stateFile.Infrastructure.ClusterEndpoint
+	if a.flags.kubeConfig != "" {
+		endpoint = ""
+	}
+	connect := func() (eventWatcher, error) {
+		kubeConfig, err := a.flags.readKubeConfig(a.fileHandler)
+		if err != nil {
+			return nil, fmt.Errorf("reading kubeconfig: %w", err)
+		}
+		return a.newEventWatcher(kubeConfig, endpoint)
+	}
+
+	streamer := &eventStreamer{
+		connect:       connect,
+		out:           s.cmd.ErrOrStderr(),
+		log:           a.log,
+		retryInterval: a.eventRetryInterval,
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		streamer.stream(ctx)
+	}()
+	s.stopEventWatch = func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// stopWatchingEvents stops streaming Kubernetes events, if it was started.
+func (s *applyState) stopWatchingEvents() {
+	if s.stopEventWatch != nil {
+		s.stopEventWatch()
+		s.stopEventWatch = nil
+	}
+}
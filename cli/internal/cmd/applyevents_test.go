@@ -0,0 +1,210 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestEventStreamer(t *testing.T) {
+	newEvent := func(resourceVersion, name, reason, message string) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{ResourceVersion: resourceVersion},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Namespace: "kube-system", Name: name},
+			Reason:         reason,
+			Message:        message,
+		}
+	}
+
+	testCases := map[string]struct {
+		watches             [][]watch.Event
+		watchErrs           []error
+		wantOut             string
+		wantResourceVersion []string
+	}{
+		"relevant events are surfaced": {
+			watches: [][]watch.Event{{
+				{Type: watch.Added, Object: newEvent("1", "cilium-abc", "Scheduled", "Successfully assigned kube-system/cilium-abc to node-0")},
+				{Type: watch.Added, Object: newEvent("2", "cilium-abc", "Pulling", `Pulling image "cilium"`)},
+				{Type: watch.Added, Object: newEvent("3", "cilium-abc", "Pulled", `Successfully pulled image "cilium"`)},
+			}},
+			wantOut: "Event kube-system/cilium-abc Scheduled: Successfully assigned kube-system/cilium-abc to node-0\n" +
+				"Event kube-system/cilium-abc Pulling: Pulling image \"cilium\"\n" +
+				"Event kube-system/cilium-abc Pulled: Successfully pulled image \"cilium\"\n",
+			wantResourceVersion: []string{"", "3"},
+		},
+		"irrelevant events are dropped": {
+			watches: [][]watch.Event{{
+				{Type: watch.Added, Object: newEvent("1", "cilium-abc", "Started", "Started container")},
+				{Type: watch.Modified, Object: newEvent("2", "cilium-abc", "Pulling", `Pulling image "cilium"`)},
+				{Type: watch.Added, Object: newEvent("3", "coredns-abc", "FailedScheduling", "0/1 nodes are available")},
+			}},
+			wantOut:             "Event kube-system/coredns-abc FailedScheduling: 0/1 nodes are available\n",
+			wantResourceVersion: []string{"", "3"},
+		},
+		"unreachable API server is retried": {
+			watchErrs: []error{errors.New("connection refused"), errors.New("connection refused")},
+			watches: [][]watch.Event{{
+				{Type: watch.Added, Object: newEvent("7", "cilium-abc", "BackOff", "Back-off pulling image")},
+			}},
+			wantOut:             "Event kube-system/cilium-abc BackOff: Back-off pulling image\n",
+			wantResourceVersion: []string{"", "", "", "7"},
+		},
+		"watch resumes after last event": {
+			watches: [][]watch.Event{
+				{{Type: watch.Added, Object: newEvent("1", "cilium-abc", "Scheduled", "assigned")}},
+				{{Type: watch.Added, Object: newEvent("2", "cilium-abc", "Pulled", "pulled")}},
+			},
+			wantOut:             "Event kube-system/cilium-abc Scheduled: assigned\nEvent kube-system/cilium-abc Pulled: pulled\n",
+			wantResourceVersion: []string{"", "1", "2"},
+		},
+		"watch error restarts watch": {
+			watches: [][]watch.Event{
+				{
+					{Type: watch.Added, Object: newEvent("1", "cilium-abc", "Scheduled", "assigned")},
+					{Type: watch.Error, Object: &metav1.Status{Reason: metav1.StatusReasonExpired}},
+				},
+			},
+			wantOut:             "Event kube-system/cilium-abc Scheduled: assigned\n",
+			wantResourceVersion: []string{"", ""},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			watcher := &fakeEventWatcher{
+				watchErrs: tc.watchErrs,
+				watches:   tc.watches,
+				exhausted: cancel,
+			}
+			out := &bytes.Buffer{}
+			streamer := &eventStreamer{
+				connect:       func() (eventWatcher, error) { return watcher, nil },
+				out:           out,
+				log:           logger.NewTest(t),
+				retryInterval: time.Millisecond,
+			}
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				streamer.stream(ctx)
+			}()
+			select {
+			case <-done:
+			case <-time.After(10 * time.Second):
+				t.Fatal("event streamer did not stop")
+			}
+
+			assert.Equal(tc.wantOut, out.String())
+			assert.Equal(tc.wantResourceVersion, watcher.resourceVersions)
+		})
+	}
+}
+
+func TestStartWatchingEvents(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fh := file.NewHandler(afero.NewMemMapFs())
+
+	watcher := &fakeEventWatcher{
+		watches: [][]watch.Event{{
+			{Type: watch.Added, Object: &corev1.Event{
+				ObjectMeta:     metav1.ObjectMeta{ResourceVersion: "1"},
+				InvolvedObject: corev1.ObjectReference{Namespace: "kube-system", Name: "cilium-abc"},
+				Reason:         "Pulling",
+				Message:        "Pulling image",
+			}},
+		}},
+	}
+	exhausted := make(chan struct{})
+	watcher.exhausted = func() { close(exhausted) }
+
+	var gotKubeConfig []byte
+	var gotEndpoint string
+	a := &applyCmd{
+		fileHandler:        fh,
+		log:                logger.NewTest(t),
+		eventRetryInterval: time.Millisecond,
+		newEventWatcher: func(kubeConfig []byte, clusterEndpoint string) (eventWatcher, error) {
+			gotKubeConfig = kubeConfig
+			gotEndpoint = clusterEndpoint
+			return watcher, nil
+		},
+	}
+	cmd := NewApplyCmd()
+	errOut := &bytes.Buffer{}
+	cmd.SetErr(errOut)
+	s := &applyState{cmd: cmd, stateFile: defaultStateFile(0)}
+
+	// Watching is started before init, so the kubeconfig is only written afterwards.
+	a.startWatchingEvents(context.Background(), s)
+	require.NoError(fh.Write(constants.AdminConfFilename, []byte("kubeconfig")))
+	<-exhausted
+	s.stopWatchingEvents()
+
+	assert.Equal([]byte("kubeconfig"), gotKubeConfig)
+	assert.Equal("192.0.2.1", gotEndpoint)
+	assert.Equal("Event kube-system/cilium-abc Pulling: Pulling image\n", errOut.String())
+	assert.Nil(s.stopEventWatch)
+	// stopping again is a no-op
+	s.stopWatchingEvents()
+}
+
+// fakeEventWatcher first returns the errors in watchErrs, then a watch for every entry of watches,
+// producing the given events. Once all watches are used up, exhausted is called
+// and all further calls fail.
+type fakeEventWatcher struct {
+	watchErrs        []error
+	watches          [][]watch.Event
+	exhausted        func()
+	resourceVersions []string
+}
+
+func (w *fakeEventWatcher) WatchEvents(_ context.Context, resourceVersion string) (watch.Interface, error) {
+	w.resourceVersions = append(w.resourceVersions, resourceVersion)
+	if len(w.watchErrs) > 0 {
+		err := w.watchErrs[0]
+		w.watchErrs = w.watchErrs[1:]
+		return nil, err
+	}
+	if len(w.watches) == 0 {
+		if w.exhausted != nil {
+			w.exhausted()
+			w.exhausted = nil
+		}
+		return nil, errors.New("no more watches")
+	}
+
+	events := w.watches[0]
+	w.watches = w.watches[1:]
+	fake := watch.NewFakeWithChanSize(len(events), false)
+	for _, event := range events {
+		fake.Action(event.Type, event.Object)
+	}
+	fake.Stop()
+	return fake, nil
+}
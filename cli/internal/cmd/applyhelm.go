@@ -17,8 +17,8 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/config"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/constellation/helm"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/kubecmd"
 	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
-	"github.com/edgelesssys/constellation/v2/internal/kms/uri"
 	"github.com/spf13/cobra"
 )
 
@@ -26,8 +26,8 @@ import (
 func (a *applyCmd) runHelmApply(cmd *cobra.Command, conf *config.Config, stateFile *state.State, upgradeDir string,
 ) error {
 	a.log.Debug("Installing or upgrading Helm charts")
-	var masterSecret uri.MasterSecret
-	if err := a.fileHandler.ReadJSON(constants.MasterSecretFilename, &masterSecret); err != nil {
+	masterSecret, err := readMasterSecret(cmd.Context(), a.fileHandler, a.newMasterKeyBackend, stateFile.Infrastructure)
+	if err != nil {
 		return fmt.Errorf("reading master secret: %w", err)
 	}
 
@@ -41,8 +41,14 @@ func (a *applyCmd) runHelmApply(cmd *cobra.Command, conf *config.Config, stateFi
 		Conformance:         a.flags.conformance,
 		HelmWaitMode:        a.flags.helmWaitMode,
 		ApplyTimeout:        a.flags.helmTimeout,
+		AtomicApplyTimeout:  a.flags.helmAtomicTimeout,
+		HistoryMax:          a.flags.helmHistoryMax,
+		Namespace:           helmNamespace(stateFile, a.flags.helmNamespace),
 		AllowDestructive:    helm.DenyDestructive,
 		ServiceCIDR:         conf.ServiceCIDR,
+		DisabledCharts:      conf.DisabledCharts,
+		ReadinessTimeouts:   conf.ReadinessTimeoutDurations(),
+		Tolerations:         conf.KubernetesTolerations(),
 	}
 	if conf.Provider.OpenStack != nil {
 		var deployYawolLoadBalancer bool
@@ -88,9 +94,20 @@ func (a *applyCmd) runHelmApply(cmd *cobra.Command, conf *config.Config, stateFi
 		cmd.PrintErrln(err)
 	}
 
-	a.log.Debug("Backing up Helm charts")
-	if err := a.backupHelmCharts(cmd.Context(), executor, includesUpgrades, upgradeDir); err != nil {
-		return err
+	if a.flags.noBackup {
+		if includesUpgrades {
+			cmd.PrintErrln("WARNING: Skipping the backup of Helm charts, CRDs, and CRs. Rolling back the upgrade won't be possible.")
+		}
+		a.log.Debug("Skipping backup of Helm charts")
+	} else {
+		a.log.Debug("Backing up Helm charts")
+		if err := a.backupHelmCharts(cmd.Context(), executor, includesUpgrades, upgradeDir); err != nil {
+			return err
+		}
+	}
+
+	if a.flags.prePullImages {
+		a.prePullHelmImages(cmd, executor)
 	}
 
 	a.log.Debug("Applying Helm charts")
@@ -112,6 +129,40 @@ func (a *applyCmd) runHelmApply(cmd *cobra.Command, conf *config.Config, stateFi
 	return nil
 }
 
+// helmNamespace returns the namespace the Helm charts are installed to.
+// The namespace recorded in the state file takes precedence over flagNamespace, which in turn takes precedence over the default namespace.
+func helmNamespace(stateFile *state.State, flagNamespace string) string {
+	if stateFile.ClusterValues.HelmNamespace != "" {
+		return stateFile.ClusterValues.HelmNamespace
+	}
+	if flagNamespace != "" {
+		return flagNamespace
+	}
+	return constants.HelmNamespace
+}
+
+// prePullHelmImages pulls the images of the Helm charts on all nodes, so they are available once the charts are applied.
+// Failing to pre-pull the images only slows down applying the charts, so errors are printed as a warning.
+func (a *applyCmd) prePullHelmImages(cmd *cobra.Command, executor helm.Applier) {
+	images, err := executor.Images()
+	if err != nil {
+		cmd.PrintErrf("WARNING: Skipping the pre-pull of images: getting images of Helm charts: %s\n", err)
+		return
+	}
+
+	a.log.Debug("Pre-pulling images", "images", images)
+	a.spinner.Start("Pre-pulling images ", false)
+	ctx, cancel := context.WithTimeout(cmd.Context(), a.flags.helmTimeout)
+	defer cancel()
+	err = a.applier.PrePullImages(ctx, images)
+	a.spinner.Stop()
+	if err != nil {
+		cmd.PrintErrf("WARNING: Pre-pulling images failed, the images are pulled while applying the Helm charts: %s\n", err)
+		return
+	}
+	cmd.Printf("Pre-pulled %d images on all nodes.\n", len(images))
+}
+
 // backupHelmCharts saves the Helm charts for the upgrade to disk and creates a backup of existing CRDs and CRs.
 func (a *applyCmd) backupHelmCharts(
 	ctx context.Context, executor helm.Applier, includesUpgrades bool, upgradeDir string,
@@ -136,3 +187,17 @@ func (a *applyCmd) backupHelmCharts(
 
 	return nil
 }
+
+// applyNetworkPolicyPreset applies the NetworkPolicies of the preset selected in the config.
+// NetworkPolicies of a previously selected preset are removed.
+func (a *applyCmd) applyNetworkPolicyPreset(ctx context.Context, conf *config.Config) error {
+	policies, err := kubecmd.NetworkPolicies(conf.NetworkPolicyPreset)
+	if err != nil {
+		return err
+	}
+	a.log.Debug("Applying NetworkPolicy preset", "preset", conf.NetworkPolicyPreset, "policies", len(policies))
+	if err := a.applier.ApplyNetworkPolicies(ctx, policies); err != nil {
+		return fmt.Errorf("applying NetworkPolicy preset %q: %w", conf.NetworkPolicyPreset, err)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+	"github.com/spf13/cobra"
+)
+
+// Environment variables passed to the post-hook and phase hook commands.
+const (
+	// envVarHookClusterEndpoint holds the cluster endpoint from the state file.
+	envVarHookClusterEndpoint = constants.EnvVarPrefix + "CLUSTER_ENDPOINT"
+	// envVarHookClusterUID holds the cluster UID from the state file.
+	envVarHookClusterUID = constants.EnvVarPrefix + "CLUSTER_UID"
+	// envVarHookKubeconfig holds the path to the cluster's admin kubeconfig.
+	envVarHookKubeconfig = constants.EnvVarPrefix + "KUBECONFIG"
+	// envVarHookApplySucceeded is "true" if the apply succeeded, "false" otherwise.
+	envVarHookApplySucceeded = constants.EnvVarPrefix + "APPLY_SUCCEEDED"
+	// envVarHookPhase holds the name of the phase a phase hook runs for.
+	envVarHookPhase = constants.EnvVarPrefix + "PHASE"
+	// envVarHookStage is "pre" or "post", depending on whether a phase hook runs before or after its phase.
+	envVarHookStage = constants.EnvVarPrefix + "HOOK_STAGE"
+)
+
+// runPostHook runs the user's post-hook command after apply.
+// The hook only runs if apply succeeded, unless --post-hook-always is set.
+// Errors from apply and the hook are both returned to the caller.
+func (a *applyCmd) runPostHook(cmd *cobra.Command, applyErr error) error {
+	if a.flags.postHook == "" {
+		return applyErr
+	}
+	if applyErr != nil && !a.flags.postHookAlways {
+		a.log.Debug("Skipping post-hook since apply failed")
+		return applyErr
+	}
+
+	stateFile, err := state.ReadFromFile(a.fileHandler, constants.StateFilename)
+	if err != nil {
+		// apply might have failed before a state file was written
+		a.log.Debug(fmt.Sprintf("Reading state file for post-hook failed: %q", err))
+		stateFile = state.New()
+	}
+	kubeconfigPath, err := filepath.Abs(a.flags.kubeConfigPath())
+	if err != nil {
+		kubeconfigPath = a.flags.kubeConfigPath()
+	}
+	env := []string{
+		envVarHookClusterEndpoint + "=" + stateFile.Infrastructure.ClusterEndpoint,
+		envVarHookClusterUID + "=" + stateFile.Infrastructure.UID,
+		envVarHookKubeconfig + "=" + kubeconfigPath,
+		envVarHookApplySucceeded + "=" + strconv.FormatBool(applyErr == nil),
+	}
+
+	a.printInfo(cmd, "Running post-hook %q\n", a.flags.postHook)
+	a.log.Debug("Running post-hook", "command", a.flags.postHook, "env", env)
+	hookErr := a.hookRunner.Run(cmd.Context(), a.flags.postHook, env, cmd.OutOrStdout(), cmd.ErrOrStderr())
+	if hookErr != nil {
+		hookErr = wrapHookError("post-hook", hookErr)
+	} else {
+		a.printInfo(cmd, "Post-hook finished successfully\n")
+	}
+
+	return errors.Join(applyErr, hookErr)
+}
+
+// phaseHookStage is the point in time a phase hook runs at.
+type phaseHookStage string
+
+const (
+	// phaseHookPre runs before the phase.
+	phaseHookPre phaseHookStage = "pre"
+	// phaseHookPost runs after the phase finished successfully.
+	phaseHookPost phaseHookStage = "post"
+)
+
+// runPhaseHook runs a hook command configured for a phase in the user's config.
+// The hook is passed the same environment as the post-hook, plus the phase name and hook stage.
+// Hooks of a config loaded from the cluster are never run.
+func (a *applyCmd) runPhaseHook(ctx context.Context, s *applyState, phase skipPhase, stage phaseHookStage, command string) error {
+	if a.flags.configRef != nil {
+		return fmt.Errorf("running %s-%s hook: %w", stage, phase, errClusterConfigPhaseHooks)
+	}
+	kubeconfigPath, err := filepath.Abs(a.flags.kubeConfigPath())
+	if err != nil {
+		kubeconfigPath = a.flags.kubeConfigPath()
+	}
+	env := []string{
+		envVarHookClusterEndpoint + "=" + s.stateFile.Infrastructure.ClusterEndpoint,
+		envVarHookClusterUID + "=" + s.stateFile.Infrastructure.UID,
+		envVarHookKubeconfig + "=" + kubeconfigPath,
+		envVarHookPhase + "=" + string(phase),
+		envVarHookStage + "=" + string(stage),
+	}
+
+	name := fmt.Sprintf("%s-%s hook", stage, phase)
+	a.printInfo(s.cmd, "Running %s %q\n", name, command)
+	a.log.Debug("Running phase hook", "phase", phase, "stage", stage, "command", command, "env", env)
+	if err := a.hookRunner.Run(ctx, command, env, s.cmd.OutOrStdout(), s.cmd.ErrOrStderr()); err != nil {
+		return wrapHookError(name, err)
+	}
+	return nil
+}
+
+// validatePhaseHooks checks that hooks are only configured for known phases,
+// and that the executables of the hook commands can be found using lookPath.
+// Hooks are rejected if the config was loaded from the cluster, i.e., configRef is set.
+func validatePhaseHooks(hooks map[string]config.PhaseHook, configRef *clusterConfigRef, lookPath func(string) (string, error)) error {
+	if len(hooks) > 0 && configRef != nil {
+		return fmt.Errorf("config loaded from %s: %w", configRef, errClusterConfigPhaseHooks)
+	}
+	phases := allPhases()
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(hooks)) {
+		if !slices.Contains(phases, strings.ToLower(name)) {
+			errs = append(errs, fmt.Errorf("phaseHooks: unknown phase %q, must be one of %s", name, formatSkipPhases()))
+			continue
+		}
+		hook := hooks[name]
+		for _, stage := range []phaseHookStage{phaseHookPre, phaseHookPost} {
+			command := hook.Pre
+			if stage == phaseHookPost {
+				command = hook.Post
+			}
+			if command == "" {
+				continue
+			}
+			fields := strings.Fields(command)
+			if len(fields) == 0 {
+				errs = append(errs, fmt.Errorf("phaseHooks.%s.%s: command is empty", name, stage))
+				continue
+			}
+			if _, err := lookPath(fields[0]); err != nil {
+				errs = append(errs, fmt.Errorf("phaseHooks.%s.%s: command %q not found: %w", name, stage, fields[0], err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// wrapHookError adds the exit code of a failed hook command to its error, if available.
+func wrapHookError(name string, err error) error {
+	var exitErr exitCoder
+	if errors.As(err, &exitErr) {
+		return fmt.Errorf("%s exited with code %d: %w", name, exitErr.ExitCode(), err)
+	}
+	return fmt.Errorf("running %s: %w", name, err)
+}
+
+// exitCoder is implemented by errors carrying the exit code of a process, e.g. [*exec.ExitError].
+type exitCoder interface {
+	ExitCode() int
+}
+
+// hookRunner runs user-provided hook commands.
+type hookRunner interface {
+	Run(ctx context.Context, command string, env []string, stdout, stderr io.Writer) error
+}
+
+// shellHookRunner runs hook commands using the system shell.
+type shellHookRunner struct{}
+
+// Run executes command with "sh -c".
+// env is added to the environment of the current process.
+func (shellHookRunner) Run(ctx context.Context, command string, env []string, stdout, stderr io.Writer) error {
+	hookCmd := exec.CommandContext(ctx, "sh", "-c", command)
+	hookCmd.Env = append(os.Environ(), env...)
+	hookCmd.Stdout = stdout
+	hookCmd.Stderr = stderr
+	return hookCmd.Run()
+}
@@ -0,0 +1,339 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunPostHook(t *testing.T) {
+	kubeconfigPath, err := filepath.Abs(constants.AdminConfFilename)
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		postHook       string
+		postHookAlways bool
+		applyErr       error
+		runner         *stubHookRunner
+		noStateFile    bool
+		wantCalled     bool
+		wantEnv        []string
+		wantErr        bool
+		wantExitCode   int
+	}{
+		"no hook configured": {
+			runner: &stubHookRunner{},
+		},
+		"no hook configured, apply failed": {
+			applyErr: assert.AnError,
+			runner:   &stubHookRunner{},
+			wantErr:  true,
+		},
+		"hook runs after successful apply": {
+			postHook:   "argocd app sync",
+			runner:     &stubHookRunner{},
+			wantCalled: true,
+			wantEnv: []string{
+				envVarHookClusterEndpoint + "=192.0.2.1",
+				envVarHookClusterUID + "=123",
+				envVarHookKubeconfig + "=" + kubeconfigPath,
+				envVarHookApplySucceeded + "=true",
+			},
+		},
+		"hook skipped after failed apply": {
+			postHook: "argocd app sync",
+			applyErr: assert.AnError,
+			runner:   &stubHookRunner{},
+			wantErr:  true,
+		},
+		"hook always runs after failed apply": {
+			postHook:       "notify",
+			postHookAlways: true,
+			applyErr:       assert.AnError,
+			runner:         &stubHookRunner{},
+			wantCalled:     true,
+			wantEnv: []string{
+				envVarHookClusterEndpoint + "=192.0.2.1",
+				envVarHookClusterUID + "=123",
+				envVarHookKubeconfig + "=" + kubeconfigPath,
+				envVarHookApplySucceeded + "=false",
+			},
+			wantErr: true,
+		},
+		"hook without state file": {
+			postHook:       "notify",
+			postHookAlways: true,
+			applyErr:       assert.AnError,
+			runner:         &stubHookRunner{},
+			noStateFile:    true,
+			wantCalled:     true,
+			wantEnv: []string{
+				envVarHookClusterEndpoint + "=",
+				envVarHookClusterUID + "=",
+				envVarHookKubeconfig + "=" + kubeconfigPath,
+				envVarHookApplySucceeded + "=false",
+			},
+			wantErr: true,
+		},
+		"hook exits with non-zero code": {
+			postHook:   "exit 3",
+			runner:     &stubHookRunner{err: stubExitError{code: 3}},
+			wantCalled: true,
+			wantEnv: []string{
+				envVarHookClusterEndpoint + "=192.0.2.1",
+				envVarHookClusterUID + "=123",
+				envVarHookKubeconfig + "=" + kubeconfigPath,
+				envVarHookApplySucceeded + "=true",
+			},
+			wantErr:      true,
+			wantExitCode: 3,
+		},
+		"hook cannot be started": {
+			postHook:   "notify",
+			runner:     &stubHookRunner{err: assert.AnError},
+			wantCalled: true,
+			wantEnv: []string{
+				envVarHookClusterEndpoint + "=192.0.2.1",
+				envVarHookClusterUID + "=123",
+				envVarHookKubeconfig + "=" + kubeconfigPath,
+				envVarHookApplySucceeded + "=true",
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			if !tc.noStateFile {
+				require.NoError(defaultStateFile(cloudprovider.GCP).WriteToFile(fileHandler, constants.StateFilename))
+			}
+
+			cmd := NewApplyCmd()
+			cmd.SetContext(context.Background())
+			var errOut bytes.Buffer
+			cmd.SetErr(&errOut)
+			cmd.SetOut(&bytes.Buffer{})
+
+			a := &applyCmd{
+				fileHandler: fileHandler,
+				flags: applyFlags{
+					postHook:       tc.postHook,
+					postHookAlways: tc.postHookAlways,
+				},
+				log:        logger.NewTest(t),
+				hookRunner: tc.runner,
+			}
+
+			err := a.runPostHook(cmd, tc.applyErr)
+			assert.Equal(tc.wantCalled, tc.runner.called)
+			if tc.wantCalled {
+				assert.Equal(tc.postHook, tc.runner.command)
+				assert.ElementsMatch(tc.wantEnv, tc.runner.env)
+			}
+			if tc.applyErr != nil {
+				assert.ErrorIs(err, tc.applyErr)
+			}
+			if tc.wantExitCode != 0 {
+				assert.ErrorContains(err, fmt.Sprintf("exited with code %d", tc.wantExitCode))
+			}
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+		})
+	}
+}
+
+func TestRunPhaseHook(t *testing.T) {
+	kubeconfigPath, err := filepath.Abs(constants.AdminConfFilename)
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		runner       *stubHookRunner
+		wantErr      bool
+		wantExitCode int
+	}{
+		"hook succeeds": {
+			runner: &stubHookRunner{},
+		},
+		"hook fails": {
+			runner:       &stubHookRunner{err: stubExitError{code: 3}},
+			wantErr:      true,
+			wantExitCode: 3,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			cmd := NewApplyCmd()
+			var errOut bytes.Buffer
+			cmd.SetErr(&errOut)
+			cmd.SetOut(&bytes.Buffer{})
+
+			a := &applyCmd{
+				log:        logger.NewTest(t),
+				hookRunner: tc.runner,
+			}
+			s := &applyState{cmd: cmd, stateFile: defaultStateFile(cloudprovider.GCP)}
+
+			err := a.runPhaseHook(context.Background(), s, skipImagePhase, phaseHookPre, "kubectl drain")
+			assert.True(tc.runner.called)
+			assert.Equal("kubectl drain", tc.runner.command)
+			assert.ElementsMatch([]string{
+				envVarHookClusterEndpoint + "=192.0.2.1",
+				envVarHookClusterUID + "=123",
+				envVarHookKubeconfig + "=" + kubeconfigPath,
+				envVarHookPhase + "=image",
+				envVarHookStage + "=pre",
+			}, tc.runner.env)
+			assert.Contains(errOut.String(), `Running pre-image hook "kubectl drain"`)
+			if tc.wantErr {
+				assert.ErrorContains(err, fmt.Sprintf("pre-image hook exited with code %d", tc.wantExitCode))
+				return
+			}
+			assert.NoError(err)
+		})
+	}
+}
+
+func TestRunPhaseHookClusterConfig(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ref, err := parseClusterConfigRef("configmap://gitops/constellation/config.yaml")
+	require.NoError(err)
+	runner := &stubHookRunner{}
+	a := &applyCmd{
+		log:        logger.NewTest(t),
+		hookRunner: runner,
+		flags:      applyFlags{rootFlags: rootFlags{configRef: ref}},
+	}
+	s := &applyState{cmd: NewApplyCmd(), stateFile: defaultStateFile(cloudprovider.GCP)}
+
+	err = a.runPhaseHook(context.Background(), s, skipImagePhase, phaseHookPre, "kubectl drain")
+	assert.ErrorIs(err, errClusterConfigPhaseHooks)
+	assert.False(runner.called)
+}
+
+func TestValidatePhaseHooks(t *testing.T) {
+	lookPath := func(file string) (string, error) {
+		if file == "missing" {
+			return "", errors.New("executable file not found in $PATH")
+		}
+		return "/usr/bin/" + file, nil
+	}
+
+	configMapRef, err := parseClusterConfigRef("configmap://gitops/constellation/config.yaml")
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		hooks     map[string]config.PhaseHook
+		configRef *clusterConfigRef
+		wantErr   bool
+	}{
+		"no hooks": {},
+		"no hooks in config from cluster": {
+			configRef: configMapRef,
+		},
+		"hooks in config from cluster": {
+			hooks: map[string]config.PhaseHook{
+				"image": {Pre: "kubectl drain --all"},
+			},
+			configRef: configMapRef,
+			wantErr:   true,
+		},
+		"valid hooks": {
+			hooks: map[string]config.PhaseHook{
+				"image": {Pre: "kubectl drain --all", Post: "kubectl uncordon --all"},
+				"Helm":  {Post: "./notify.sh"},
+			},
+		},
+		"unknown phase": {
+			hooks: map[string]config.PhaseHook{
+				"unknown": {Pre: "echo"},
+			},
+			wantErr: true,
+		},
+		"pre-hook command not found": {
+			hooks: map[string]config.PhaseHook{
+				"image": {Pre: "missing --flag"},
+			},
+			wantErr: true,
+		},
+		"post-hook command not found": {
+			hooks: map[string]config.PhaseHook{
+				"image": {Pre: "echo", Post: "missing"},
+			},
+			wantErr: true,
+		},
+		"whitespace command": {
+			hooks: map[string]config.PhaseHook{
+				"image": {Pre: "  "},
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := validatePhaseHooks(tc.hooks, tc.configRef, lookPath)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+type stubHookRunner struct {
+	called  bool
+	command string
+	env     []string
+	err     error
+}
+
+func (s *stubHookRunner) Run(_ context.Context, command string, env []string, _, _ io.Writer) error {
+	s.called = true
+	s.command = command
+	s.env = env
+	return s.err
+}
+
+type stubExitError struct {
+	code int
+}
+
+func (e stubExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+func (e stubExitError) ExitCode() int {
+	return e.code
+}
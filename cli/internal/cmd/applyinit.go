@@ -8,6 +8,7 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -20,6 +21,7 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/constellation"
 	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
 	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/kms/kms"
 	"github.com/edgelesssys/constellation/v2/internal/kms/uri"
 	"github.com/spf13/cobra"
 )
@@ -35,10 +37,28 @@ func (a *applyCmd) runInit(cmd *cobra.Command, conf *config.Config, stateFile *s
 		return nil, fmt.Errorf("creating validator: %w", err)
 	}
 
+	var masterKeyBackend kms.KMSBackend
+	if hsmURI, keyName := masterKeyHSM(conf); hsmURI != "" {
+		a.log.Debug("Encrypting master secret with Managed HSM", "uri", hsmURI, "key", keyName)
+		masterKeyBackend, err = a.newMasterKeyBackend(hsmURI, keyName)
+		if err != nil {
+			return nil, fmt.Errorf("creating Managed HSM backend: %w", err)
+		}
+		recordMasterKeyHSM(&stateFile.Infrastructure, hsmURI, keyName)
+	}
+
 	a.log.Debug("Running init RPC")
-	masterSecret, err := a.generateAndPersistMasterSecret(cmd.OutOrStdout())
-	if err != nil {
-		return nil, fmt.Errorf("generating master secret: %w", err)
+	var masterSecret uri.MasterSecret
+	if a.flags.masterSecretIn != "" {
+		masterSecret, err = a.importAndPersistMasterSecret(cmd.Context(), cmd.OutOrStdout(), masterKeyBackend)
+		if err != nil {
+			return nil, fmt.Errorf("importing master secret: %w", err)
+		}
+	} else {
+		masterSecret, err = a.generateAndPersistMasterSecret(cmd.Context(), cmd.OutOrStdout(), masterKeyBackend)
+		if err != nil {
+			return nil, fmt.Errorf("generating master secret: %w", err)
+		}
 	}
 
 	measurementSalt, err := a.applier.GenerateMeasurementSalt()
@@ -46,6 +66,15 @@ func (a *applyCmd) runInit(cmd *cobra.Command, conf *config.Config, stateFile *s
 		return nil, fmt.Errorf("generating measurement salt: %w", err)
 	}
 
+	if conf.IsDebugAccessEnabled() {
+		cmd.PrintErrln("WARNING: Debug access is enabled. The nodes accept SSH connections authenticated with the authorized keys of your config.")
+		cmd.PrintErrln("Holders of these keys can read the memory and disks of the nodes, which breaks the confidentiality of the cluster. DO NOT USE THIS CLUSTER IN PRODUCTION.")
+	}
+	if len(conf.RegistryMirrors) > 0 {
+		cmd.PrintErrln("WARNING: Registry mirrors are configured. Images of the mirrored registries are pulled from the mirrors, which aren't covered by attestation.")
+		cmd.PrintErrln("Pin images by digest to protect them against a compromised mirror.")
+	}
+
 	clusterLogs := &bytes.Buffer{}
 	resp, err := a.applier.Init(
 		cmd.Context(), validator, stateFile, clusterLogs,
@@ -55,6 +84,9 @@ func (a *applyCmd) runInit(cmd *cobra.Command, conf *config.Config, stateFile *s
 			K8sVersion:      conf.KubernetesVersion,
 			ConformanceMode: a.flags.conformance,
 			ServiceCIDR:     conf.ServiceCIDR,
+			OIDC:            conf.OIDC,
+			DebugAccess:     conf.DebugAccess,
+			RegistryMirrors: conf.RegistryMirrors,
 		})
 	if len(clusterLogs.Bytes()) > 0 {
 		if err := a.fileHandler.Write(constants.ErrorLog, clusterLogs.Bytes(), file.OptAppend); err != nil {
@@ -62,8 +94,17 @@ func (a *applyCmd) runInit(cmd *cobra.Command, conf *config.Config, stateFile *s
 		}
 	}
 	if err != nil {
+		var attestationErr *constellation.InitAttestationError
+		var unreachableErr *constellation.InitUnreachableError
 		var nonRetriable *constellation.NonRetriableInitError
-		if errors.As(err, &nonRetriable) {
+		switch {
+		case errors.As(err, &attestationErr):
+			cmd.PrintErrln("The attestation of the first node couldn't be verified.")
+			cmd.PrintErrln("Check that the attestation config in your config file matches the node image.")
+		case errors.As(err, &unreachableErr):
+			cmd.PrintErrf("The first node couldn't be reached at %s.\n", unreachableErr.Endpoint)
+			cmd.PrintErrln("If the node is still booting, retry with a longer --init-timeout.")
+		case errors.As(err, &nonRetriable):
 			cmd.PrintErrln("Cluster initialization failed. This error is not recoverable.")
 			cmd.PrintErrln("Terminate your cluster and try again.")
 			if nonRetriable.LogCollectionErr != nil {
@@ -76,6 +117,26 @@ func (a *applyCmd) runInit(cmd *cobra.Command, conf *config.Config, stateFile *s
 	}
 	a.log.Debug("Initialization request successful")
 
+	if a.flags.masterSecretIn != "" {
+		// The cluster derives its ID from the master secret, so a different ID means the imported secret wasn't used.
+		clusterID, err := deriveClusterID(masterSecret, measurementSalt)
+		if err != nil {
+			return nil, err
+		}
+		if clusterID != resp.ClusterID {
+			cmd.PrintErrf("WARNING: The cluster ID %s doesn't match the ID %s derived from the imported master secret.\n", resp.ClusterID, clusterID)
+			cmd.PrintErrln("Recovering the cluster with the imported master secret won't be possible.")
+		}
+	}
+
+	if err := recordAttestationConfig(stateFile, conf.GetAttestationConfig()); err != nil {
+		return nil, err
+	}
+	// The API server flags are only set during init, so record them to detect changes on re-apply.
+	stateFile.ClusterValues.OIDC = conf.OIDC
+	// Later applies have to find the Helm releases in the namespace they were installed to.
+	stateFile.ClusterValues.HelmNamespace = a.flags.helmNamespace
+
 	a.log.Debug("Buffering init success message")
 	bufferedOutput := &bytes.Buffer{}
 	if err := a.writeInitOutput(stateFile, resp, a.flags.mergeConfigs, bufferedOutput, measurementSalt); err != nil {
@@ -86,18 +147,33 @@ func (a *applyCmd) runInit(cmd *cobra.Command, conf *config.Config, stateFile *s
 }
 
 // generateAndPersistMasterSecret generates a 32 byte master secret and saves it to disk.
-func (a *applyCmd) generateAndPersistMasterSecret(outWriter io.Writer) (uri.MasterSecret, error) {
+// If backend is not nil, the master secret is encrypted with it before it is written.
+func (a *applyCmd) generateAndPersistMasterSecret(ctx context.Context, outWriter io.Writer, backend kms.KMSBackend) (uri.MasterSecret, error) {
 	secret, err := a.applier.GenerateMasterSecret()
 	if err != nil {
 		return uri.MasterSecret{}, fmt.Errorf("generating master secret: %w", err)
 	}
-	if err := a.fileHandler.WriteJSON(constants.MasterSecretFilename, secret, file.OptNone); err != nil {
+	if err := writeMasterSecret(ctx, a.fileHandler, backend, secret); err != nil {
 		return uri.MasterSecret{}, fmt.Errorf("writing master secret: %w", err)
 	}
 	fmt.Fprintf(outWriter, "Your Constellation master secret was successfully written to %q\n", a.flags.pathPrefixer.PrefixPrintablePath(constants.MasterSecretFilename))
 	return secret, nil
 }
 
+// importAndPersistMasterSecret reads the master secret to import and saves it to disk.
+// If backend is not nil, the master secret is encrypted with it before it is written.
+func (a *applyCmd) importAndPersistMasterSecret(ctx context.Context, outWriter io.Writer, backend kms.KMSBackend) (uri.MasterSecret, error) {
+	secret, err := readImportedMasterSecret(a.fileHandler, a.flags.masterSecretIn)
+	if err != nil {
+		return uri.MasterSecret{}, err
+	}
+	if err := writeMasterSecret(ctx, a.fileHandler, backend, secret); err != nil {
+		return uri.MasterSecret{}, fmt.Errorf("writing master secret: %w", err)
+	}
+	fmt.Fprintf(outWriter, "Your imported Constellation master secret was successfully written to %q\n", a.flags.pathPrefixer.PrefixPrintablePath(constants.MasterSecretFilename))
+	return secret, nil
+}
+
 // writeInitOutput writes the output of a cluster initialization to the
 // state- / kubeconfig-file and saves it to disk.
 func (a *applyCmd) writeInitOutput(
@@ -110,6 +186,8 @@ func (a *applyCmd) writeInitOutput(
 		MeasurementSalt: measurementSalt,
 		OwnerID:         initResp.OwnerID,
 		ClusterID:       initResp.ClusterID,
+		OIDC:            stateFile.ClusterValues.OIDC,
+		HelmNamespace:   stateFile.ClusterValues.HelmNamespace,
 	})
 
 	tw := tabwriter.NewWriter(wr, 0, 0, 2, ' ', 0)
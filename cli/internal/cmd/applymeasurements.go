@@ -0,0 +1,66 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation/measurements"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// compareMeasurementsWithUpstream compares the measurements of the config with the signed measurements
+// published for the configured image, to catch accidental edits before the measurements are used.
+// Divergent measurements fail the apply, unless validation is forced, in which case only a warning is printed.
+func (a *applyCmd) compareMeasurementsWithUpstream(cmd *cobra.Command, conf *config.Config) error {
+	if !a.canFetchMeasurements {
+		return errors.New("comparing measurements with the upstream measurements is not supported in the OSS build of the Constellation CLI")
+	}
+
+	attestationCfg := conf.GetAttestationConfig()
+	a.log.Debug("Fetching upstream measurements", "image", conf.Image, "variant", attestationCfg.GetVariant())
+	upstream, err := a.verifyFetcher.FetchAndVerifyMeasurements(cmd.Context(), conf.Image, conf.GetProvider(), attestationCfg.GetVariant(), false)
+	if err != nil {
+		return fmt.Errorf("fetching upstream measurements: %w", err)
+	}
+
+	local := attestationCfg.GetMeasurements()
+	if measurementValuesEqual(upstream, local) {
+		a.log.Debug("Measurements in config match the upstream measurements")
+		return nil
+	}
+
+	measurementsDiff, err := diffYAML("upstream", upstream, "config", local)
+	if err != nil {
+		return err
+	}
+	if !a.flags.force {
+		cmd.PrintErrln(measurementsDiff)
+		return fmt.Errorf("measurements in config differ from the signed upstream measurements of image %s: "+
+			"run \"constellation config fetch-measurements\" to restore them, or use --force to apply them anyway", conf.Image)
+	}
+	cmd.PrintErrf("Warning: measurements in config differ from the signed upstream measurements of image %s:\n%s\n", conf.Image, measurementsDiff)
+	return nil
+}
+
+// measurementValuesEqual returns true if both measurements contain the same expected values at the same indices.
+// In contrast to [measurements.M.EqualTo], the validation options are ignored, since they are meant to be changed by users.
+func measurementValuesEqual(upstream, local measurements.M) bool {
+	if len(upstream) != len(local) {
+		return false
+	}
+	for idx, measurement := range upstream {
+		localMeasurement, ok := local[idx]
+		if !ok || !bytes.Equal(measurement.Expected, localMeasurement.Expected) {
+			return false
+		}
+	}
+	return true
+}
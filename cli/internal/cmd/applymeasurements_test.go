@@ -0,0 +1,120 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation/measurements"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareMeasurementsWithUpstream(t *testing.T) {
+	upstream := measurements.M{
+		4:  measurements.WithAllBytes(0x44, measurements.Enforce, measurements.PCRMeasurementLength),
+		9:  measurements.WithAllBytes(0x99, measurements.Enforce, measurements.PCRMeasurementLength),
+		11: measurements.WithAllBytes(0x11, measurements.Enforce, measurements.PCRMeasurementLength),
+	}
+
+	testCases := map[string]struct {
+		local       measurements.M
+		fetchErr    error
+		force       bool
+		cannotFetch bool
+		wantErr     bool
+		wantWarning bool
+	}{
+		"matching measurements": {
+			local: upstream.Copy(),
+		},
+		"validation options are ignored": {
+			local: measurements.M{
+				4:  measurements.WithAllBytes(0x44, measurements.WarnOnly, measurements.PCRMeasurementLength),
+				9:  measurements.WithAllBytes(0x99, measurements.Enforce, measurements.PCRMeasurementLength),
+				11: measurements.WithAllBytes(0x11, measurements.WarnOnly, measurements.PCRMeasurementLength),
+			},
+		},
+		"divergent measurement value": {
+			local: measurements.M{
+				4:  measurements.WithAllBytes(0x44, measurements.Enforce, measurements.PCRMeasurementLength),
+				9:  measurements.WithAllBytes(0xff, measurements.Enforce, measurements.PCRMeasurementLength),
+				11: measurements.WithAllBytes(0x11, measurements.Enforce, measurements.PCRMeasurementLength),
+			},
+			wantErr: true,
+		},
+		"missing measurement": {
+			local: measurements.M{
+				4: measurements.WithAllBytes(0x44, measurements.Enforce, measurements.PCRMeasurementLength),
+				9: measurements.WithAllBytes(0x99, measurements.Enforce, measurements.PCRMeasurementLength),
+			},
+			wantErr: true,
+		},
+		"divergent measurement value with force": {
+			local: measurements.M{
+				4:  measurements.WithAllBytes(0x44, measurements.Enforce, measurements.PCRMeasurementLength),
+				9:  measurements.WithAllBytes(0xff, measurements.Enforce, measurements.PCRMeasurementLength),
+				11: measurements.WithAllBytes(0x11, measurements.Enforce, measurements.PCRMeasurementLength),
+			},
+			force:       true,
+			wantWarning: true,
+		},
+		"fetching upstream measurements fails": {
+			local:    upstream.Copy(),
+			fetchErr: errors.New("signature verification failed"),
+			wantErr:  true,
+		},
+		"OSS build": {
+			local:       upstream.Copy(),
+			cannotFetch: true,
+			wantErr:     true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			conf := config.Default()
+			conf.RemoveProviderAndAttestationExcept(cloudprovider.Azure)
+			conf.Attestation.AzureSEVSNP.Measurements = tc.local
+
+			cmd := NewApplyCmd()
+			stderr := &bytes.Buffer{}
+			cmd.SetErr(stderr)
+			cmd.SetContext(context.Background())
+
+			a := &applyCmd{
+				flags:                applyFlags{rootFlags: rootFlags{force: tc.force}},
+				log:                  logger.NewTest(t),
+				canFetchMeasurements: !tc.cannotFetch,
+				verifyFetcher:        stubVerifyFetcher{measurements: upstream.Copy(), err: tc.fetchErr},
+			}
+
+			err := a.compareMeasurementsWithUpstream(cmd, conf)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			if tc.wantWarning {
+				assert.Contains(stderr.String(), "Warning: measurements in config differ")
+				assert.Contains(stderr.String(), "-    expected: \"9999")
+				assert.Contains(stderr.String(), "+    expected: ffff")
+			} else {
+				assert.Empty(stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+// applyVerbosity controls how much output apply writes.
+type applyVerbosity int
+
+const (
+	// applyVerbosityNormal prints the progress of every phase.
+	applyVerbosityNormal applyVerbosity = iota
+	// applyVerbosityQuiet only prints warnings, errors, and the result of the run.
+	applyVerbosityQuiet
+	// applyVerbosityDebug additionally writes debug logs.
+	applyVerbosityDebug
+)
+
+// applyResultSucceeded is the result line printed by a successful quiet apply.
+// Errors are printed by cobra, so they are the result line of a failed run.
+const applyResultSucceeded = "Apply succeeded"
+
+// setOutputVerbosity configures the output of cmd for the given verbosity,
+// and returns the writer for the result line.
+// Warnings and errors are written to stderr and are never suppressed.
+// Unless prompts are skipped with --yes, quiet output is held back instead of being discarded,
+// and shown together with the next confirmation prompt, so the user sees what they are asked to confirm.
+func setOutputVerbosity(cmd *cobra.Command, verbosity applyVerbosity, yes bool) io.Writer {
+	resultOut := cmd.OutOrStdout()
+	switch {
+	case verbosity != applyVerbosityQuiet:
+	case yes:
+		cmd.SetOut(io.Discard)
+	default:
+		cmd.SetOut(&heldBackWriter{out: resultOut})
+	}
+	return resultOut
+}
+
+// heldBackWriter holds back output until a confirmation prompt needs to be shown.
+type heldBackWriter struct {
+	out     io.Writer
+	pending bytes.Buffer
+}
+
+// Write holds back p.
+func (w *heldBackWriter) Write(p []byte) (int, error) {
+	return w.pending.Write(p)
+}
+
+// showPrompt writes the output held back since the last prompt, ending with the prompt itself.
+func (w *heldBackWriter) showPrompt() error {
+	_, err := w.pending.WriteTo(w.out)
+	return err
+}
+
+// printApplyResult prints the result line of a quiet apply.
+func printApplyResult(out io.Writer, verbosity applyVerbosity, err error) {
+	if verbosity != applyVerbosityQuiet || err != nil {
+		return
+	}
+	fmt.Fprintln(out, applyResultSucceeded)
+}
+
+// printInfo prints an informational message to stderr, unless apply runs with --quiet.
+func (a *applyCmd) printInfo(cmd *cobra.Command, format string, args ...any) {
+	if a.flags.verbosity == applyVerbosityQuiet {
+		return
+	}
+	cmd.PrintErrf(format, args...)
+}
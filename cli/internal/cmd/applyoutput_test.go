@@ -0,0 +1,137 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/kms/uri"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyOutputVerbosity(t *testing.T) {
+	testCases := map[string]struct {
+		verbosity  applyVerbosity
+		wantStdout string
+		wantStderr string
+	}{
+		"normal": {
+			verbosity:  applyVerbosityNormal,
+			wantStdout: "Successfully upgraded Constellation services.\n",
+			wantStderr: "Post-hook finished successfully\n",
+		},
+		"quiet": {
+			verbosity:  applyVerbosityQuiet,
+			wantStdout: applyResultSucceeded + "\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fh := file.NewHandler(afero.NewMemMapFs())
+			require.NoError(fh.MkdirAll(constants.TerraformWorkingDir))
+			require.NoError(fh.WriteYAML(constants.StateFilename, defaultStateFile(cloudprovider.Azure)))
+			require.NoError(fh.Write(constants.AdminConfFilename, []byte{}))
+			require.NoError(fh.WriteYAML(constants.ConfigFilename, defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.Azure)))
+			require.NoError(fh.WriteJSON(constants.MasterSecretFilename, uri.MasterSecret{}))
+
+			cmd := NewApplyCmd()
+			var stdout, stderr bytes.Buffer
+			cmd.SetOut(&stdout)
+			cmd.SetErr(&stderr)
+			cmd.SetContext(context.Background())
+
+			a := &applyCmd{
+				fileHandler: fh,
+				flags: applyFlags{
+					yes:        true,
+					skipPhases: newPhases(skipInitPhase),
+					postHook:   "notify",
+					verbosity:  tc.verbosity,
+				},
+				log:      logger.NewTest(t),
+				spinner:  &nopSpinner{},
+				merger:   &stubMerger{},
+				progress: nopProgressReporter{},
+				newInfraApplier: func(_ context.Context) (cloudApplier, func(), error) {
+					return &stubTerraformUpgrader{}, func() {}, nil
+				},
+				applier: &stubConstellApplier{
+					stubKubernetesUpgrader: &stubKubernetesUpgrader{currentConfig: config.DefaultForAzureSEVSNP()},
+					helmApplier:            &stubHelmApplier{},
+				},
+				imageFetcher: &stubImageFetcher{},
+				hookRunner:   &stubHookRunner{},
+			}
+
+			resultOut := setOutputVerbosity(cmd, tc.verbosity, a.flags.yes)
+			err := a.runPostHook(cmd, a.apply(cmd, stubAttestationFetcher{}, "test"))
+			printApplyResult(resultOut, tc.verbosity, err)
+			require.NoError(err)
+
+			assert.Contains(stdout.String(), tc.wantStdout)
+			if tc.verbosity == applyVerbosityQuiet {
+				// only the result line is printed
+				assert.Equal(tc.wantStdout, stdout.String())
+				assert.Empty(stderr.String())
+			} else {
+				assert.Contains(stderr.String(), tc.wantStderr)
+				assert.NotContains(stdout.String(), applyResultSucceeded)
+			}
+		})
+	}
+}
+
+func TestQuietApplyShowsPrompts(t *testing.T) {
+	testCases := map[string]struct {
+		yes        bool
+		wantStdout string
+	}{
+		"prompt is shown with the output leading up to it": {
+			wantStdout: "plan: 1 to add\nApply? [y/n]: ",
+		},
+		"output is discarded with --yes": {
+			yes: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			cmd := NewApplyCmd()
+			var stdout bytes.Buffer
+			cmd.SetOut(&stdout)
+			cmd.SetIn(bytes.NewBufferString("y\n"))
+			setOutputVerbosity(cmd, applyVerbosityQuiet, tc.yes)
+
+			cmd.Println("plan: 1 to add")
+			if !tc.yes {
+				ok, err := askToConfirm(cmd, "Apply?")
+				require.NoError(err)
+				assert.True(ok)
+			}
+			// output after the prompt is held back again
+			cmd.Println("Applying...")
+
+			assert.Equal(tc.wantStdout, stdout.String())
+		})
+	}
+}
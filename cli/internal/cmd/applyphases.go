@@ -0,0 +1,467 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+	"github.com/spf13/cobra"
+)
+
+// phase is a single step of the apply process.
+type phase interface {
+	// Name returns the name of the phase, as used by the skip-phases flag.
+	Name() skipPhase
+	// Run executes the phase.
+	Run(ctx context.Context, s *applyState) error
+	// DependsOn returns the phases that have to run before this phase, if they are not skipped.
+	DependsOn() []skipPhase
+}
+
+// applyState is the state shared between the phases of a single apply run.
+type applyState struct {
+	cmd        *cobra.Command
+	conf       *config.Config
+	stateFile  *state.State
+	upgradeDir string
+	// initOutput holds the output of the init phase, which is printed once all phases have finished.
+	initOutput *bytes.Buffer
+	// kubeConfigSet is true once the Kubernetes admin config has been loaded into the applier.
+	kubeConfigSet bool
+	// stopEventWatch stops streaming Kubernetes events, if --watch-events is set.
+	stopEventWatch func()
+	// progress reports the start and end of phases. If nil, progress isn't reported.
+	progress progressReporter
+}
+
+// reporter returns the progress reporter of the run.
+func (s *applyState) reporter() progressReporter {
+	if s.progress == nil {
+		return nopProgressReporter{}
+	}
+	return s.progress
+}
+
+// phaseRegistry holds phases in the order they are executed.
+type phaseRegistry struct {
+	phases []phase
+}
+
+// newPhaseRegistry creates a registry from the given phases.
+// Phase names must be unique, and a phase may only depend on phases registered before it.
+func newPhaseRegistry(phases ...phase) (*phaseRegistry, error) {
+	registered := make(map[skipPhase]struct{}, len(phases))
+	for _, p := range phases {
+		if _, ok := registered[p.Name()]; ok {
+			return nil, fmt.Errorf("phase %q registered more than once", p.Name())
+		}
+		for _, dep := range p.DependsOn() {
+			if _, ok := registered[dep]; !ok {
+				return nil, fmt.Errorf("phase %q depends on %q, which is not registered before it", p.Name(), dep)
+			}
+		}
+		registered[p.Name()] = struct{}{}
+	}
+	return &phaseRegistry{phases: phases}, nil
+}
+
+// names returns the names of all registered phases in execution order, excluding the given phases.
+func (r *phaseRegistry) names(except ...skipPhase) []string {
+	var names []string
+	for _, p := range r.phases {
+		if !slices.Contains(except, p.Name()) {
+			names = append(names, string(p.Name()))
+		}
+	}
+	return names
+}
+
+// run executes all phases that are not skipped in order.
+// If failFast is set, execution stops at the first phase returning an error.
+// Otherwise, phases that don't depend on a failed phase still run, and the errors of all failed phases are returned.
+func (r *phaseRegistry) run(ctx context.Context, s *applyState, skip skipPhases, failFast bool) error {
+	progress := s.reporter()
+	var errs []error
+	// failed holds the phases that failed or didn't run because a phase they depend on failed.
+	failed := make(map[skipPhase]struct{})
+	for _, p := range r.phases {
+		if skip.contains(p.Name()) {
+			progress.phaseSkipped(p.Name())
+			continue
+		}
+		if slices.ContainsFunc(p.DependsOn(), func(dep skipPhase) bool {
+			_, ok := failed[dep]
+			return ok
+		}) {
+			failed[p.Name()] = struct{}{}
+			progress.phaseSkipped(p.Name())
+			continue
+		}
+		progress.phaseStarted(p.Name())
+		err := p.Run(ctx, s)
+		progress.phaseFinished(p.Name(), err)
+		if err == nil {
+			continue
+		}
+		if failFast {
+			return err
+		}
+		failed[p.Name()] = struct{}{}
+		errs = append(errs, fmt.Errorf("%s phase: %w", p.Name(), err))
+	}
+	return errors.Join(errs...)
+}
+
+// withHooks returns a registry that runs the given hooks before and after the phases they are configured for.
+// Hooks are keyed by phase name. Since skipped phases aren't run, their hooks don't run either.
+func (r *phaseRegistry) withHooks(hooks map[string]config.PhaseHook, runHook phaseHookFunc) *phaseRegistry {
+	if len(hooks) == 0 {
+		return r
+	}
+	byPhase := make(map[skipPhase]config.PhaseHook, len(hooks))
+	for name, hook := range hooks {
+		byPhase[skipPhase(strings.ToLower(name))] = hook
+	}
+
+	phases := make([]phase, 0, len(r.phases))
+	for _, p := range r.phases {
+		if hook, ok := byPhase[p.Name()]; ok {
+			p = hookedPhase{phase: p, hook: hook, runHook: runHook}
+		}
+		phases = append(phases, p)
+	}
+	return &phaseRegistry{phases: phases}
+}
+
+// phaseHookFunc runs a hook command for the given phase and stage.
+type phaseHookFunc func(ctx context.Context, s *applyState, phase skipPhase, stage phaseHookStage, command string) error
+
+// hookedPhase runs user-defined hook commands before and after the wrapped phase.
+type hookedPhase struct {
+	phase
+	hook    config.PhaseHook
+	runHook phaseHookFunc
+}
+
+// Run executes the pre-hook, the phase, and the post-hook.
+// Execution stops at the first error.
+func (p hookedPhase) Run(ctx context.Context, s *applyState) error {
+	if p.hook.Pre != "" {
+		if err := p.runHook(ctx, s, p.Name(), phaseHookPre, p.hook.Pre); err != nil {
+			return err
+		}
+	}
+	if err := p.phase.Run(ctx, s); err != nil {
+		return err
+	}
+	if p.hook.Post != "" {
+		return p.runHook(ctx, s, p.Name(), phaseHookPost, p.hook.Post)
+	}
+	return nil
+}
+
+// funcPhase is a phase backed by a function.
+type funcPhase struct {
+	name      skipPhase
+	dependsOn []skipPhase
+	run       func(ctx context.Context, s *applyState) error
+}
+
+// Name returns the name of the phase.
+func (p funcPhase) Name() skipPhase {
+	return p.name
+}
+
+// Run executes the phase.
+func (p funcPhase) Run(ctx context.Context, s *applyState) error {
+	return p.run(ctx, s)
+}
+
+// DependsOn returns the phases that have to run before this phase.
+func (p funcPhase) DependsOn() []skipPhase {
+	return p.dependsOn
+}
+
+// newApplyPhaseRegistry returns the registry of all phases of the apply process.
+func newApplyPhaseRegistry(a *applyCmd) *phaseRegistry {
+	registry, err := newPhaseRegistry(
+		funcPhase{
+			name: skipInfrastructurePhase,
+			run:  a.runInfrastructurePhase,
+		},
+		funcPhase{
+			name:      skipInitPhase,
+			dependsOn: []skipPhase{skipInfrastructurePhase},
+			run:       a.runInitPhase,
+		},
+		funcPhase{
+			name:      skipAttestationConfigPhase,
+			dependsOn: []skipPhase{skipInitPhase},
+			run:       a.runAttestationConfigPhase,
+		},
+		funcPhase{
+			name:      skipCertSANsPhase,
+			dependsOn: []skipPhase{skipInitPhase},
+			run:       a.runCertSANsPhase,
+		},
+		funcPhase{
+			name:      skipHelmPhase,
+			dependsOn: []skipPhase{skipInitPhase},
+			run:       a.runHelmPhase,
+		},
+		funcPhase{
+			name:      skipImagePhase,
+			dependsOn: []skipPhase{skipHelmPhase},
+			run:       a.runImagePhase,
+		},
+		funcPhase{
+			name:      skipK8sPhase,
+			dependsOn: []skipPhase{skipHelmPhase},
+			run:       a.runK8sPhase,
+		},
+	)
+	must(err)
+	return registry
+}
+
+// runInfrastructurePhase checks the current Terraform state and applies migrations if necessary.
+func (a *applyCmd) runInfrastructurePhase(_ context.Context, s *applyState) error {
+	if err := validateResourceName(s.conf, s.stateFile); err != nil {
+		return err
+	}
+	if err := a.runTerraformApply(s.cmd, s.conf, s.stateFile, s.upgradeDir); err != nil {
+		return fmt.Errorf("applying Terraform configuration: %w", err)
+	}
+	if a.flags.printResourceIDs {
+		if err := printResourceIDs(s.cmd.OutOrStdout(), s.stateFile.Infrastructure); err != nil {
+			return fmt.Errorf("printing resource IDs: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateResourceName makes sure the configured name and name template resolve to the name of an existing cluster.
+// If no name template is configured, the default template is used.
+// Changing the name or the template after cluster creation would replace all of the cluster's resources.
+func validateResourceName(conf *config.Config, stateFile *state.State) error {
+	if stateFile.Infrastructure.UID == "" || stateFile.Infrastructure.Name == "" {
+		return nil
+	}
+	name, err := conf.ResourceName(stateFile.Infrastructure.UID)
+	if err != nil {
+		return fmt.Errorf("resolving name template: %w", err)
+	}
+	if name != stateFile.Infrastructure.Name {
+		return fmt.Errorf(
+			"name and name template resolve to %q, but the cluster was created with name %q: neither can be changed after cluster creation",
+			name, stateFile.Infrastructure.Name,
+		)
+	}
+	return nil
+}
+
+// runInitPhase runs the init RPC.
+// If requested, Kubernetes events are streamed from before init is started,
+// so that they are shown as soon as the API server is reachable.
+func (a *applyCmd) runInitPhase(ctx context.Context, s *applyState) error {
+	if a.flags.watchEvents && s.stopEventWatch == nil {
+		a.startWatchingEvents(ctx, s)
+	}
+	output, err := a.runInit(s.cmd, s.conf, s.stateFile)
+	if err != nil {
+		return err
+	}
+	s.initOutput = output
+	return nil
+}
+
+// runAttestationConfigPhase applies the attestation config of the user's config to the cluster.
+func (a *applyCmd) runAttestationConfigPhase(ctx context.Context, s *applyState) error {
+	if err := a.setKubeConfig(ctx, s); err != nil {
+		return err
+	}
+	a.log.Debug("Applying new attestation config to cluster")
+	attestationCfg := s.conf.GetAttestationConfig()
+	if err := a.applyJoinConfig(s.cmd, attestationCfg, s.stateFile.ClusterValues.MeasurementSalt); err != nil {
+		return fmt.Errorf("applying attestation config: %w", err)
+	}
+	if err := recordAttestationConfig(s.stateFile, attestationCfg); err != nil {
+		return err
+	}
+	if err := s.stateFile.WriteToFile(a.fileHandler, constants.StateFilename); err != nil {
+		return fmt.Errorf("writing state file: %w", err)
+	}
+	return nil
+}
+
+// runCertSANsPhase extends the API server cert SANs.
+func (a *applyCmd) runCertSANsPhase(ctx context.Context, s *applyState) error {
+	if err := a.setKubeConfig(ctx, s); err != nil {
+		return err
+	}
+	if err := a.applier.ExtendClusterConfigCertSANs(
+		ctx,
+		s.stateFile.Infrastructure.ClusterEndpoint,
+		s.conf.CustomEndpoint,
+		s.stateFile.Infrastructure.APIServerCertSANs,
+	); err != nil {
+		return fmt.Errorf("extending cert SANs: %w", err)
+	}
+	return nil
+}
+
+// runHelmPhase applies the Helm charts and the NetworkPolicy preset.
+func (a *applyCmd) runHelmPhase(ctx context.Context, s *applyState) error {
+	if err := a.setKubeConfig(ctx, s); err != nil {
+		return err
+	}
+	// The CoreDNS resources created by kubeadm are only taken over if the coredns chart is managed by Constellation.
+	manageCoreDNS := !s.conf.ChartDisabled("coredns")
+	if manageCoreDNS {
+		if err := a.applier.AnnotateCoreDNSResources(ctx); err != nil {
+			return fmt.Errorf("annotating CoreDNS: %w", err)
+		}
+	}
+	if err := a.runHelmApply(s.cmd, s.conf, s.stateFile, s.upgradeDir); err != nil {
+		return err
+	}
+	if err := a.applyNetworkPolicyPreset(ctx, s.conf); err != nil {
+		return err
+	}
+	if manageCoreDNS {
+		if err := a.applier.CleanupCoreDNSResources(ctx); err != nil {
+			return fmt.Errorf("cleaning up CoreDNS: %w", err)
+		}
+	}
+	return nil
+}
+
+// runImagePhase upgrades the node image.
+func (a *applyCmd) runImagePhase(ctx context.Context, s *applyState) error {
+	if err := a.setKubeConfig(ctx, s); err != nil {
+		return err
+	}
+	if s.conf.Channel != "" {
+		image, err := resolveChannelImage(ctx, a.channelFetcher, s.conf.Channel)
+		if err != nil {
+			return err
+		}
+		s.cmd.Printf("Channel %s resolved to image %s\n", s.conf.Channel, image)
+		s.conf.Image = image
+	}
+	if err := a.applier.SetDrainSettings(ctx, a.flags.drainGracePeriod, a.flags.drainTimeout); err != nil {
+		return fmt.Errorf("setting drain settings: %w", err)
+	}
+
+	nodeGroupVersions := maps.Clone(s.stateFile.NodeGroupVersions)
+	if err := a.runNodeImageUpgrade(s.cmd, s.conf, s.stateFile); err != nil {
+		return err
+	}
+	if s.conf.Channel == "" && maps.Equal(nodeGroupVersions, s.stateFile.NodeGroupVersions) {
+		return nil
+	}
+	if s.conf.Channel != "" {
+		s.stateFile.ResolvedImage = s.conf.Image
+	}
+	if err := s.stateFile.WriteToFile(a.fileHandler, constants.StateFilename); err != nil {
+		return fmt.Errorf("writing state file: %w", err)
+	}
+	return nil
+}
+
+// runK8sPhase upgrades the Kubernetes version.
+func (a *applyCmd) runK8sPhase(ctx context.Context, s *applyState) error {
+	if err := a.setKubeConfig(ctx, s); err != nil {
+		return err
+	}
+	if err := a.applier.SetDrainSettings(ctx, a.flags.drainGracePeriod, a.flags.drainTimeout); err != nil {
+		return fmt.Errorf("setting drain settings: %w", err)
+	}
+	return a.runK8sVersionUpgrade(s.cmd, s.conf)
+}
+
+// setKubeConfig loads the Kubernetes admin config into the applier.
+// This is done once, before the first phase that talks to the Kubernetes API.
+// From then on we can assume a valid Kubernetes admin config file exists.
+func (a *applyCmd) setKubeConfig(ctx context.Context, s *applyState) error {
+	if s.kubeConfigSet {
+		return nil
+	}
+	kubeConfig, err := a.flags.readKubeConfig(a.fileHandler)
+	if err != nil {
+		return fmt.Errorf("reading kubeconfig: %w", err)
+	}
+	if err := a.newKubeClients(ctx, kubeConfig, s.stateFile.Infrastructure.ClusterEndpoint); err != nil {
+		return err
+	}
+	s.kubeConfigSet = true
+	return nil
+}
+
+// kubeClientRetry configures retries of reaching the Kubernetes API server.
+// The zero value tries exactly once.
+type kubeClientRetry struct {
+	// timeout bounds the total time spent on retries.
+	timeout time.Duration
+	// interval is the wait time before the first retry. It doubles with every retry, up to maxInterval.
+	interval    time.Duration
+	maxInterval time.Duration
+}
+
+// defaultKubeClientRetry gives a freshly initialized or restarting API server some time to become reachable.
+var defaultKubeClientRetry = kubeClientRetry{
+	timeout:     5 * time.Minute,
+	interval:    time.Second,
+	maxInterval: 30 * time.Second,
+}
+
+// newKubeClients constructs the applier's Kubernetes clients and waits for the API server to be reachable,
+// retrying with exponential backoff until a.kubeClientRetry.timeout is reached.
+// Constructing the clients doesn't contact the cluster, so only the reachability check is retried.
+func (a *applyCmd) newKubeClients(ctx context.Context, kubeConfig []byte, endpoint string) error {
+	if err := a.applier.SetKubeConfig(kubeConfig); err != nil {
+		return fmt.Errorf("creating Kubernetes clients: %w", err)
+	}
+	if a.kubeClientRetry.timeout <= 0 {
+		return a.applier.CheckAPIServerReachable()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, a.kubeClientRetry.timeout)
+	defer cancel()
+
+	interval := a.kubeClientRetry.interval
+	for attempt := 1; ; attempt++ {
+		err := a.applier.CheckAPIServerReachable()
+		if err == nil {
+			return nil
+		}
+		a.log.Debug(fmt.Sprintf("Reaching the Kubernetes API server failed (attempt %d): %s", attempt, err))
+
+		if ctx.Err() == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(interval):
+				interval = min(2*interval, a.kubeClientRetry.maxInterval)
+				continue
+			}
+		}
+		return fmt.Errorf(
+			"reaching Kubernetes API server: giving up after %d attempts in %s, make sure the Kubernetes API server at %q is reachable: %w",
+			attempt, a.kubeClientRetry.timeout, endpoint, err,
+		)
+	}
+}
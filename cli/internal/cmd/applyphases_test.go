@@ -0,0 +1,544 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPhaseRegistry(t *testing.T) {
+	testCases := map[string]struct {
+		phases    []phase
+		wantNames []string
+		wantErr   bool
+	}{
+		"empty registry": {},
+		"phases are kept in order": {
+			phases: []phase{
+				&fakePhase{name: "b"},
+				&fakePhase{name: "a", dependsOn: []skipPhase{"b"}},
+				&fakePhase{name: "c", dependsOn: []skipPhase{"a", "b"}},
+			},
+			wantNames: []string{"b", "a", "c"},
+		},
+		"duplicate phase": {
+			phases: []phase{
+				&fakePhase{name: "a"},
+				&fakePhase{name: "a"},
+			},
+			wantErr: true,
+		},
+		"unknown dependency": {
+			phases: []phase{
+				&fakePhase{name: "a", dependsOn: []skipPhase{"unknown"}},
+			},
+			wantErr: true,
+		},
+		"dependency registered after dependent phase": {
+			phases: []phase{
+				&fakePhase{name: "a", dependsOn: []skipPhase{"b"}},
+				&fakePhase{name: "b"},
+			},
+			wantErr: true,
+		},
+		"phase depends on itself": {
+			phases: []phase{
+				&fakePhase{name: "a", dependsOn: []skipPhase{"a"}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			registry, err := newPhaseRegistry(tc.phases...)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantNames, registry.names())
+		})
+	}
+}
+
+func TestPhaseRegistryRun(t *testing.T) {
+	someErr := errors.New("failed")
+
+	testCases := map[string]struct {
+		skip        []skipPhase
+		failingStep skipPhase
+		wantRun     []skipPhase
+		wantErr     bool
+	}{
+		"run all phases": {
+			wantRun: []skipPhase{"infra", "init", "helm"},
+		},
+		"skip single phase": {
+			skip:    []skipPhase{"init"},
+			wantRun: []skipPhase{"infra", "helm"},
+		},
+		"skip is case insensitive": {
+			skip:    []skipPhase{"HELM"},
+			wantRun: []skipPhase{"infra", "init"},
+		},
+		"skip all phases": {
+			skip: []skipPhase{"infra", "init", "helm"},
+		},
+		"error stops execution": {
+			failingStep: "init",
+			wantRun:     []skipPhase{"infra", "init"},
+			wantErr:     true,
+		},
+		"error in skipped phase is ignored": {
+			skip:        []skipPhase{"init"},
+			failingStep: "init",
+			wantRun:     []skipPhase{"infra", "helm"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var ran []skipPhase
+			newFake := func(name skipPhase, dependsOn ...skipPhase) *fakePhase {
+				p := &fakePhase{name: name, dependsOn: dependsOn, ran: &ran}
+				if name == tc.failingStep {
+					p.err = someErr
+				}
+				return p
+			}
+			registry, err := newPhaseRegistry(
+				newFake("infra"),
+				newFake("init", "infra"),
+				newFake("helm", "init"),
+			)
+			require.NoError(err)
+
+			var skip skipPhases
+			skip.add(tc.skip...)
+
+			err = registry.run(context.Background(), &applyState{}, skip, true)
+			if tc.wantErr {
+				assert.ErrorIs(err, someErr)
+			} else {
+				assert.NoError(err)
+			}
+			assert.Equal(tc.wantRun, ran)
+		})
+	}
+}
+
+func TestPhaseRegistryRunWithoutFailFast(t *testing.T) {
+	attestationErr := errors.New("attestation failed")
+	helmErr := errors.New("helm failed")
+
+	testCases := map[string]struct {
+		skip     []skipPhase
+		failing  map[skipPhase]error
+		wantRun  []skipPhase
+		wantErrs []error
+	}{
+		"no failures": {
+			wantRun: []skipPhase{"infra", "init", "attestationconfig", "helm", "image"},
+		},
+		"independent branch completes": {
+			failing:  map[skipPhase]error{"attestationconfig": attestationErr},
+			wantRun:  []skipPhase{"infra", "init", "attestationconfig", "helm", "image"},
+			wantErrs: []error{attestationErr},
+		},
+		"dependent phases don't run": {
+			failing:  map[skipPhase]error{"helm": helmErr},
+			wantRun:  []skipPhase{"infra", "init", "attestationconfig", "helm"},
+			wantErrs: []error{helmErr},
+		},
+		"all failures are reported": {
+			failing:  map[skipPhase]error{"attestationconfig": attestationErr, "helm": helmErr},
+			wantRun:  []skipPhase{"infra", "init", "attestationconfig", "helm"},
+			wantErrs: []error{attestationErr, helmErr},
+		},
+		"skipped failing phase doesn't block dependent phases": {
+			skip:    []skipPhase{"helm"},
+			failing: map[skipPhase]error{"helm": helmErr},
+			wantRun: []skipPhase{"infra", "init", "attestationconfig", "image"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var ran []skipPhase
+			newFake := func(name skipPhase, dependsOn ...skipPhase) *fakePhase {
+				return &fakePhase{name: name, dependsOn: dependsOn, ran: &ran, err: tc.failing[name]}
+			}
+			// attestationconfig and helm are independent branches after init
+			registry, err := newPhaseRegistry(
+				newFake("infra"),
+				newFake("init", "infra"),
+				newFake("attestationconfig", "init"),
+				newFake("helm", "init"),
+				newFake("image", "helm"),
+			)
+			require.NoError(err)
+
+			var skip skipPhases
+			skip.add(tc.skip...)
+
+			err = registry.run(context.Background(), &applyState{}, skip, false)
+			assert.Equal(tc.wantRun, ran)
+			if len(tc.wantErrs) == 0 {
+				assert.NoError(err)
+				return
+			}
+			require.Error(err)
+			for _, wantErr := range tc.wantErrs {
+				assert.ErrorIs(err, wantErr)
+			}
+			joined, ok := err.(interface{ Unwrap() []error })
+			require.True(ok)
+			assert.Len(joined.Unwrap(), len(tc.wantErrs))
+		})
+	}
+}
+
+func TestPhaseRegistryWithHooks(t *testing.T) {
+	someErr := errors.New("failed")
+
+	testCases := map[string]struct {
+		hooks       map[string]config.PhaseHook
+		skip        []skipPhase
+		failingStep skipPhase
+		wantRun     []skipPhase
+		wantErr     bool
+	}{
+		"no hooks": {
+			wantRun: []skipPhase{"infra", "init", "helm"},
+		},
+		"hooks fire around their phase": {
+			hooks: map[string]config.PhaseHook{
+				"init": {Pre: "drain", Post: "uncordon"},
+				"helm": {Post: "notify"},
+			},
+			wantRun: []skipPhase{"infra", "pre-init", "init", "post-init", "helm", "post-helm"},
+		},
+		"phase names are case insensitive": {
+			hooks: map[string]config.PhaseHook{
+				"INIT": {Pre: "drain"},
+			},
+			wantRun: []skipPhase{"infra", "pre-init", "init", "helm"},
+		},
+		"skipped phase doesn't fire hooks": {
+			hooks: map[string]config.PhaseHook{
+				"init": {Pre: "drain", Post: "uncordon"},
+			},
+			skip:    []skipPhase{"init"},
+			wantRun: []skipPhase{"infra", "helm"},
+		},
+		"failing pre-hook stops execution": {
+			hooks: map[string]config.PhaseHook{
+				"init": {Pre: "drain", Post: "uncordon"},
+			},
+			failingStep: "pre-init",
+			wantRun:     []skipPhase{"infra", "pre-init"},
+			wantErr:     true,
+		},
+		"failing phase skips post-hook": {
+			hooks: map[string]config.PhaseHook{
+				"init": {Pre: "drain", Post: "uncordon"},
+			},
+			failingStep: "init",
+			wantRun:     []skipPhase{"infra", "pre-init", "init"},
+			wantErr:     true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var ran []skipPhase
+			newFake := func(name skipPhase, dependsOn ...skipPhase) *fakePhase {
+				p := &fakePhase{name: name, dependsOn: dependsOn, ran: &ran}
+				if name == tc.failingStep {
+					p.err = someErr
+				}
+				return p
+			}
+			runHook := func(_ context.Context, _ *applyState, phase skipPhase, stage phaseHookStage, _ string) error {
+				step := skipPhase(string(stage) + "-" + string(phase))
+				ran = append(ran, step)
+				if step == tc.failingStep {
+					return someErr
+				}
+				return nil
+			}
+			registry, err := newPhaseRegistry(
+				newFake("infra"),
+				newFake("init", "infra"),
+				newFake("helm", "init"),
+			)
+			require.NoError(err)
+
+			var skip skipPhases
+			skip.add(tc.skip...)
+
+			err = registry.withHooks(tc.hooks, runHook).run(context.Background(), &applyState{}, skip, true)
+			if tc.wantErr {
+				assert.ErrorIs(err, someErr)
+			} else {
+				assert.NoError(err)
+			}
+			assert.Equal(tc.wantRun, ran)
+		})
+	}
+}
+
+func TestApplyPhaseRegistry(t *testing.T) {
+	assert := assert.New(t)
+
+	// the order and names of the phases are part of the CLI interface
+	assert.Equal([]string{
+		"infrastructure", "init", "attestationconfig", "certsans", "helm", "image", "k8s",
+	}, allPhases())
+	assert.Equal([]string{
+		"init", "attestationconfig", "certsans", "helm", "image", "k8s",
+	}, allPhases(skipInfrastructurePhase))
+	assert.Equal([]string{
+		"infrastructure", "init", "attestationconfig", "certsans", "helm",
+	}, allPhases(skipImagePhase, skipK8sPhase))
+}
+
+func TestSetKubeConfig(t *testing.T) {
+	retry := kubeClientRetry{
+		timeout:     time.Second,
+		interval:    time.Millisecond,
+		maxInterval: 10 * time.Millisecond,
+	}
+
+	testCases := map[string]struct {
+		failures         int
+		setKubeConfigErr error
+		retry            kubeClientRetry
+		noKubeConfig     bool
+		kubeConfig       string
+		wantAttempts     int
+		wantErr          bool
+		wantTimeout      bool
+		wantKubeSetup    bool
+	}{
+		"API server reachable on first attempt": {
+			retry:         retry,
+			wantAttempts:  1,
+			wantKubeSetup: true,
+		},
+		"API server unreachable a few times": {
+			failures:      3,
+			retry:         retry,
+			wantAttempts:  4,
+			wantKubeSetup: true,
+		},
+		"API server never reachable": {
+			failures:    -1,
+			retry:       retry,
+			wantErr:     true,
+			wantTimeout: true,
+		},
+		"invalid kubeconfig isn't retried": {
+			setKubeConfigErr: errors.New("invalid kubeconfig"),
+			retry:            retry,
+			wantErr:          true,
+		},
+		"no retries configured": {
+			failures:     1,
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+		"kubeconfig missing": {
+			retry:        retry,
+			noKubeConfig: true,
+			wantErr:      true,
+		},
+		"kubeconfig override": {
+			retry:         retry,
+			noKubeConfig:  true,
+			kubeConfig:    "override.conf",
+			wantAttempts:  1,
+			wantKubeSetup: true,
+		},
+		"kubeconfig override missing": {
+			retry:      retry,
+			kubeConfig: "missing.conf",
+			wantErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fh := file.NewHandler(afero.NewMemMapFs())
+			wantKubeConfig := []byte("kubeconfig")
+			if !tc.noKubeConfig {
+				require.NoError(fh.Write(constants.AdminConfFilename, wantKubeConfig))
+			}
+			if tc.kubeConfig == "override.conf" {
+				wantKubeConfig = []byte(testKubeConfig)
+				require.NoError(fh.Write(tc.kubeConfig, wantKubeConfig))
+			}
+			applier := &flakyKubeConfigApplier{failures: tc.failures, setKubeConfigErr: tc.setKubeConfigErr}
+			a := &applyCmd{
+				fileHandler:     fh,
+				log:             logger.NewTest(t),
+				applier:         applier,
+				kubeClientRetry: tc.retry,
+			}
+			a.flags.kubeConfig = tc.kubeConfig
+			s := &applyState{stateFile: state.New()}
+
+			start := time.Now()
+			err := a.setKubeConfig(context.Background(), s)
+			assert.Equal(tc.wantKubeSetup, s.kubeConfigSet)
+			if tc.wantErr {
+				assert.Error(err)
+				if tc.wantTimeout {
+					assert.GreaterOrEqual(time.Since(start), tc.retry.timeout)
+					assert.Greater(applier.attempts, 1)
+				} else {
+					assert.Equal(tc.wantAttempts, applier.attempts)
+				}
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tc.wantAttempts, applier.attempts)
+			assert.Equal(wantKubeConfig, applier.kubeConfig)
+
+			// the clients are only constructed once
+			require.NoError(a.setKubeConfig(context.Background(), s))
+			assert.Equal(tc.wantAttempts, applier.attempts)
+		})
+	}
+}
+
+// flakyKubeConfigApplier fails to reach the Kubernetes API server the given number of times.
+// A negative number of failures means the API server is never reachable.
+type flakyKubeConfigApplier struct {
+	applier
+	setKubeConfigErr error
+	failures         int
+	attempts         int
+	kubeConfig       []byte
+}
+
+func (a *flakyKubeConfigApplier) SetKubeConfig(kubeConfig []byte) error {
+	a.kubeConfig = kubeConfig
+	return a.setKubeConfigErr
+}
+
+func (a *flakyKubeConfigApplier) CheckAPIServerReachable() error {
+	a.attempts++
+	if a.failures < 0 || a.attempts <= a.failures {
+		return errors.New("API server not reachable")
+	}
+	return nil
+}
+
+func TestValidateResourceName(t *testing.T) {
+	testCases := map[string]struct {
+		nameTemplate string
+		infra        state.Infrastructure
+		wantErr      bool
+	}{
+		"default name template matches existing cluster": {
+			infra: state.Infrastructure{UID: "1a2b3c4d", Name: "constell-1a2b3c4d"},
+		},
+		"name changed after creation without name template": {
+			infra:   state.Infrastructure{UID: "1a2b3c4d", Name: "other-name-1a2b3c4d"},
+			wantErr: true,
+		},
+		"name template removed after creation": {
+			infra:   state.Infrastructure{UID: "1a2b3c4d", Name: "org-constell-1a2b3c4d"},
+			wantErr: true,
+		},
+		"no name in state": {
+			infra: state.Infrastructure{UID: "1a2b3c4d"},
+		},
+		"cluster not created yet": {
+			nameTemplate: "org-{name}-{uid}",
+		},
+		"template matches existing cluster": {
+			nameTemplate: "org-{name}-{uid}",
+			infra:        state.Infrastructure{UID: "1a2b3c4d", Name: "org-constell-1a2b3c4d"},
+		},
+		"template changed after creation": {
+			nameTemplate: "org-{name}-{uid}",
+			infra:        state.Infrastructure{UID: "1a2b3c4d", Name: "constell-1a2b3c4d"},
+			wantErr:      true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			conf := &config.Config{Name: "constell", NameTemplate: tc.nameTemplate}
+			stateFile := state.New()
+			stateFile.Infrastructure = tc.infra
+
+			err := validateResourceName(conf, stateFile)
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+type fakePhase struct {
+	name      skipPhase
+	dependsOn []skipPhase
+	err       error
+	ran       *[]skipPhase
+	// run is called with the apply state when the phase is run, if set.
+	run func(s *applyState)
+}
+
+func (p *fakePhase) Name() skipPhase {
+	return p.name
+}
+
+func (p *fakePhase) Run(_ context.Context, s *applyState) error {
+	if p.ran != nil {
+		*p.ran = append(*p.ran, p.name)
+	}
+	if p.run != nil {
+		p.run(s)
+	}
+	return p.err
+}
+
+func (p *fakePhase) DependsOn() []skipPhase {
+	return p.dependsOn
+}
@@ -0,0 +1,121 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// planGraphFormatText renders the phase graph as an indented tree.
+	planGraphFormatText = "text"
+	// planGraphFormatDOT renders the phase graph in the DOT language of Graphviz.
+	planGraphFormatDOT = "dot"
+)
+
+// showPlanGraph prints the phases apply would run, and their dependencies, without applying anything.
+// With --reconcile or --since-state, unchanged phases are shown as skipped.
+func (a *applyCmd) showPlanGraph(cmd *cobra.Command, conf *config.Config, stateFile *state.State) error {
+	registry := newApplyPhaseRegistry(a)
+	// Keep stdout free for the graph, so it can be piped to other tools.
+	if err := a.reconcilePhases(cmd.ErrOrStderr(), registry, conf, stateFile); err != nil {
+		return err
+	}
+	return writePlanGraph(cmd.OutOrStdout(), registry, a.flags.skipPhases, a.flags.graphFormat)
+}
+
+// writePlanGraph writes the phase graph of the registry in the given format.
+// Every phase is marked as run or skipped, edges point from a phase to the phases depending on it.
+func writePlanGraph(out io.Writer, registry *phaseRegistry, skip skipPhases, format string) error {
+	switch format {
+	case planGraphFormatText:
+		return writePlanGraphText(out, registry, skip)
+	case planGraphFormatDOT:
+		return writePlanGraphDOT(out, registry, skip)
+	default:
+		return fmt.Errorf("invalid graph format %q, must be one of {%s, %s}", format, planGraphFormatText, planGraphFormatDOT)
+	}
+}
+
+// writePlanGraphText writes the phase graph as a tree.
+// A phase depending on multiple phases is listed below each of them, its dependents are only listed below its first occurrence.
+func writePlanGraphText(out io.Writer, registry *phaseRegistry, skip skipPhases) error {
+	dependents := make(map[skipPhase][]skipPhase, len(registry.phases))
+	var roots []skipPhase
+	for _, p := range registry.phases {
+		if len(p.DependsOn()) == 0 {
+			roots = append(roots, p.Name())
+		}
+		for _, dep := range p.DependsOn() {
+			dependents[dep] = append(dependents[dep], p.Name())
+		}
+	}
+
+	var b strings.Builder
+	printed := make(map[skipPhase]bool, len(registry.phases))
+	var writeNode func(phase skipPhase, prefix, childPrefix string)
+	writeNode = func(phase skipPhase, prefix, childPrefix string) {
+		if printed[phase] {
+			fmt.Fprintf(&b, "%s%s (%s, see above)\n", prefix, phase, planGraphStatus(phase, skip))
+			return
+		}
+		printed[phase] = true
+		fmt.Fprintf(&b, "%s%s (%s)\n", prefix, phase, planGraphStatus(phase, skip))
+		children := dependents[phase]
+		for i, child := range children {
+			if i == len(children)-1 {
+				writeNode(child, childPrefix+"└── ", childPrefix+"    ")
+			} else {
+				writeNode(child, childPrefix+"├── ", childPrefix+"│   ")
+			}
+		}
+	}
+	for _, root := range roots {
+		writeNode(root, "", "")
+	}
+
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+// writePlanGraphDOT writes the phase graph as a directed graph in the DOT language.
+// Skipped phases are drawn dashed and gray.
+func writePlanGraphDOT(out io.Writer, registry *phaseRegistry, skip skipPhases) error {
+	var b strings.Builder
+	b.WriteString("digraph apply {\n")
+	for _, p := range registry.phases {
+		status := planGraphStatus(p.Name(), skip)
+		attributes := fmt.Sprintf(`label="%s\n(%s)"`, p.Name(), status)
+		if skip.contains(p.Name()) {
+			attributes += `, style=dashed, color=gray, fontcolor=gray`
+		}
+		fmt.Fprintf(&b, "\t%q [%s];\n", p.Name(), attributes)
+	}
+	for _, p := range registry.phases {
+		for _, dep := range p.DependsOn() {
+			fmt.Fprintf(&b, "\t%q -> %q;\n", dep, p.Name())
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+// planGraphStatus returns whether the phase runs or is skipped.
+func planGraphStatus(phase skipPhase, skip skipPhases) string {
+	if skip.contains(phase) {
+		return "skipped"
+	}
+	return "run"
+}
@@ -0,0 +1,115 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePlanGraphDOT(t *testing.T) {
+	wantEdges := []string{
+		`"infrastructure" -> "init";`,
+		`"init" -> "attestationconfig";`,
+		`"init" -> "certsans";`,
+		`"init" -> "helm";`,
+		`"helm" -> "image";`,
+		`"helm" -> "k8s";`,
+	}
+
+	testCases := map[string]struct {
+		skip        skipPhases
+		wantRun     []skipPhase
+		wantSkipped []skipPhase
+	}{
+		"nothing skipped": {
+			wantRun: []skipPhase{
+				skipInfrastructurePhase, skipInitPhase, skipAttestationConfigPhase,
+				skipCertSANsPhase, skipHelmPhase, skipImagePhase, skipK8sPhase,
+			},
+		},
+		"initialized cluster": {
+			skip: newPhases(skipInitPhase),
+			wantRun: []skipPhase{
+				skipInfrastructurePhase, skipAttestationConfigPhase,
+				skipCertSANsPhase, skipHelmPhase, skipImagePhase, skipK8sPhase,
+			},
+			wantSkipped: []skipPhase{skipInitPhase},
+		},
+		"upgrade without infrastructure and helm": {
+			skip:        newPhases(skipInfrastructurePhase, skipInitPhase, skipHelmPhase),
+			wantRun:     []skipPhase{skipAttestationConfigPhase, skipCertSANsPhase, skipImagePhase, skipK8sPhase},
+			wantSkipped: []skipPhase{skipInfrastructurePhase, skipInitPhase, skipHelmPhase},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var out bytes.Buffer
+			require.NoError(writePlanGraph(&out, newApplyPhaseRegistry(&applyCmd{}), tc.skip, planGraphFormatDOT))
+			dot := out.String()
+
+			assert.Contains(dot, "digraph apply {")
+			for _, phase := range tc.wantRun {
+				assert.Contains(dot, `"`+string(phase)+`" [label="`+string(phase)+`\n(run)"];`)
+			}
+			for _, phase := range tc.wantSkipped {
+				assert.Contains(dot, `"`+string(phase)+`" [label="`+string(phase)+`\n(skipped)", style=dashed, color=gray, fontcolor=gray];`)
+			}
+			// dependencies are shown independent of the skipped phases
+			for _, edge := range wantEdges {
+				assert.Contains(dot, edge)
+			}
+		})
+	}
+}
+
+func TestWritePlanGraphText(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var out bytes.Buffer
+	require.NoError(writePlanGraph(&out, newApplyPhaseRegistry(&applyCmd{}), newPhases(skipInitPhase, skipK8sPhase), planGraphFormatText))
+	assert.Equal(`infrastructure (run)
+└── init (skipped)
+    ├── attestationconfig (run)
+    ├── certsans (run)
+    └── helm (run)
+        ├── image (run)
+        └── k8s (skipped)
+`, out.String())
+
+	assert.Error(writePlanGraph(&out, newApplyPhaseRegistry(&applyCmd{}), nil, "svg"))
+}
+
+func TestWritePlanGraphTextSharedDependents(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	registry, err := newPhaseRegistry(
+		&fakePhase{name: "a"},
+		&fakePhase{name: "b"},
+		&fakePhase{name: "c", dependsOn: []skipPhase{"a", "b"}},
+		&fakePhase{name: "d", dependsOn: []skipPhase{"c"}},
+	)
+	require.NoError(err)
+
+	var out bytes.Buffer
+	require.NoError(writePlanGraph(&out, registry, nil, planGraphFormatText))
+	assert.Equal(`a (run)
+└── c (run)
+    └── d (run)
+b (run)
+└── c (run, see above)
+`, out.String())
+}
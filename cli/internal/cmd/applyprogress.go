@@ -0,0 +1,149 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// applyOutputNDJSON streams the progress of apply as newline-delimited JSON events.
+const applyOutputNDJSON = "ndjson"
+
+// progressEventType is the type of a progress event.
+type progressEventType string
+
+const (
+	// progressEventPhaseStarted is emitted when a phase starts running.
+	progressEventPhaseStarted progressEventType = "phase_started"
+	// progressEventPhaseProgress is emitted when a running phase reaches a new step.
+	progressEventPhaseProgress progressEventType = "phase_progress"
+	// progressEventPhaseFinished is emitted when a phase succeeded, failed, or was skipped.
+	progressEventPhaseFinished progressEventType = "phase_finished"
+	// progressEventApplyFinished is the last event of an apply run.
+	progressEventApplyFinished progressEventType = "apply_finished"
+)
+
+// progressStatus is the outcome of a phase or of the apply run.
+type progressStatus string
+
+const (
+	progressStatusSucceeded progressStatus = "succeeded"
+	progressStatusFailed    progressStatus = "failed"
+	progressStatusSkipped   progressStatus = "skipped"
+)
+
+// progressEvent is a single event of the apply progress.
+type progressEvent struct {
+	// Sequence numbers events of a run, starting at 1.
+	Sequence  uint64            `json:"sequence"`
+	Timestamp time.Time         `json:"timestamp"`
+	Type      progressEventType `json:"type"`
+	Phase     skipPhase         `json:"phase,omitempty"`
+	Status    progressStatus    `json:"status,omitempty"`
+	Message   string            `json:"message,omitempty"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// progressReporter reports the progress of an apply run.
+type progressReporter interface {
+	phaseStarted(phase skipPhase)
+	// phaseProgress reports a new step of the currently running phase.
+	phaseProgress(message string)
+	phaseFinished(phase skipPhase, err error)
+	phaseSkipped(phase skipPhase)
+	applyFinished(err error)
+}
+
+// nopProgressReporter discards all progress.
+type nopProgressReporter struct{}
+
+func (nopProgressReporter) phaseStarted(skipPhase)         {}
+func (nopProgressReporter) phaseProgress(string)           {}
+func (nopProgressReporter) phaseFinished(skipPhase, error) {}
+func (nopProgressReporter) phaseSkipped(skipPhase)         {}
+func (nopProgressReporter) applyFinished(error)            {}
+
+// ndjsonProgressReporter writes every progress event as a single JSON line as soon as it happens.
+type ndjsonProgressReporter struct {
+	mux     sync.Mutex
+	out     io.Writer
+	now     func() time.Time
+	seq     uint64
+	current skipPhase
+}
+
+// newNDJSONProgressReporter creates a progress reporter writing to out.
+func newNDJSONProgressReporter(out io.Writer) *ndjsonProgressReporter {
+	return &ndjsonProgressReporter{out: out, now: time.Now}
+}
+
+func (r *ndjsonProgressReporter) phaseStarted(phase skipPhase) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.current = phase
+	r.emit(progressEvent{Type: progressEventPhaseStarted, Phase: phase})
+}
+
+func (r *ndjsonProgressReporter) phaseProgress(message string) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.emit(progressEvent{Type: progressEventPhaseProgress, Phase: r.current, Message: message})
+}
+
+func (r *ndjsonProgressReporter) phaseFinished(phase skipPhase, err error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.current = ""
+	r.emit(withStatus(progressEvent{Type: progressEventPhaseFinished, Phase: phase}, err))
+}
+
+func (r *ndjsonProgressReporter) phaseSkipped(phase skipPhase) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.emit(progressEvent{Type: progressEventPhaseFinished, Phase: phase, Status: progressStatusSkipped})
+}
+
+func (r *ndjsonProgressReporter) applyFinished(err error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.emit(withStatus(progressEvent{Type: progressEventApplyFinished}, err))
+}
+
+// emit writes the event. The caller must hold the lock.
+// Progress is only informational, so write errors are ignored.
+func (r *ndjsonProgressReporter) emit(event progressEvent) {
+	r.seq++
+	event.Sequence = r.seq
+	event.Timestamp = r.now().UTC()
+	// json.Encoder terminates every value with a newline.
+	_ = json.NewEncoder(r.out).Encode(event)
+}
+
+func withStatus(event progressEvent, err error) progressEvent {
+	event.Status = progressStatusSucceeded
+	if err != nil {
+		event.Status = progressStatusFailed
+		event.Error = err.Error()
+	}
+	return event
+}
+
+// progressSpinner reports the messages of a spinner as progress of the current phase.
+type progressSpinner struct {
+	spinnerInterf
+	progress progressReporter
+}
+
+// Start reports the text as progress and starts the spinner.
+func (s progressSpinner) Start(text string, showDots bool) {
+	s.progress.phaseProgress(strings.TrimSpace(text))
+	s.spinnerInterf.Start(text, showDots)
+}
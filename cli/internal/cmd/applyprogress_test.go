@@ -0,0 +1,155 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNDJSONProgressReporter(t *testing.T) {
+	someErr := errors.New("failed")
+
+	testCases := map[string]struct {
+		skip        []skipPhase
+		failingStep skipPhase
+		wantEvents  []progressEvent
+		wantErr     bool
+	}{
+		"skip-heavy run": {
+			skip: []skipPhase{skipInfrastructurePhase, skipInitPhase, skipAttestationConfigPhase, skipImagePhase, skipK8sPhase},
+			wantEvents: []progressEvent{
+				{Type: progressEventPhaseFinished, Phase: skipInfrastructurePhase, Status: progressStatusSkipped},
+				{Type: progressEventPhaseFinished, Phase: skipInitPhase, Status: progressStatusSkipped},
+				{Type: progressEventPhaseFinished, Phase: skipAttestationConfigPhase, Status: progressStatusSkipped},
+				{Type: progressEventPhaseStarted, Phase: skipCertSANsPhase},
+				{Type: progressEventPhaseProgress, Phase: skipCertSANsPhase, Message: "running certsans"},
+				{Type: progressEventPhaseFinished, Phase: skipCertSANsPhase, Status: progressStatusSucceeded},
+				{Type: progressEventPhaseStarted, Phase: skipHelmPhase},
+				{Type: progressEventPhaseProgress, Phase: skipHelmPhase, Message: "running helm"},
+				{Type: progressEventPhaseFinished, Phase: skipHelmPhase, Status: progressStatusSucceeded},
+				{Type: progressEventPhaseFinished, Phase: skipImagePhase, Status: progressStatusSkipped},
+				{Type: progressEventPhaseFinished, Phase: skipK8sPhase, Status: progressStatusSkipped},
+				{Type: progressEventApplyFinished, Status: progressStatusSucceeded},
+			},
+		},
+		"failing phase": {
+			skip:        []skipPhase{skipInfrastructurePhase, skipInitPhase, skipAttestationConfigPhase, skipCertSANsPhase},
+			failingStep: skipHelmPhase,
+			wantEvents: []progressEvent{
+				{Type: progressEventPhaseFinished, Phase: skipInfrastructurePhase, Status: progressStatusSkipped},
+				{Type: progressEventPhaseFinished, Phase: skipInitPhase, Status: progressStatusSkipped},
+				{Type: progressEventPhaseFinished, Phase: skipAttestationConfigPhase, Status: progressStatusSkipped},
+				{Type: progressEventPhaseFinished, Phase: skipCertSANsPhase, Status: progressStatusSkipped},
+				{Type: progressEventPhaseStarted, Phase: skipHelmPhase},
+				{Type: progressEventPhaseProgress, Phase: skipHelmPhase, Message: "running helm"},
+				{Type: progressEventPhaseFinished, Phase: skipHelmPhase, Status: progressStatusFailed, Error: "failed"},
+				{Type: progressEventApplyFinished, Status: progressStatusFailed, Error: "failed"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			out := &bytes.Buffer{}
+			start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+			now := start
+			reporter := newNDJSONProgressReporter(out)
+			reporter.now = func() time.Time {
+				now = now.Add(time.Second)
+				return now
+			}
+
+			newFake := func(name skipPhase, dependsOn ...skipPhase) *fakePhase {
+				p := &fakePhase{name: name, dependsOn: dependsOn, run: func(s *applyState) {
+					s.reporter().phaseProgress("running " + string(name))
+				}}
+				if name == tc.failingStep {
+					p.err = someErr
+				}
+				return p
+			}
+			registry, err := newPhaseRegistry(
+				newFake(skipInfrastructurePhase),
+				newFake(skipInitPhase, skipInfrastructurePhase),
+				newFake(skipAttestationConfigPhase, skipInitPhase),
+				newFake(skipCertSANsPhase, skipInitPhase),
+				newFake(skipHelmPhase, skipInitPhase),
+				newFake(skipImagePhase, skipHelmPhase),
+				newFake(skipK8sPhase, skipHelmPhase),
+			)
+			require.NoError(err)
+
+			var skip skipPhases
+			skip.add(tc.skip...)
+			err = registry.run(context.Background(), &applyState{progress: reporter}, skip, true)
+			if tc.wantErr {
+				assert.ErrorIs(err, someErr)
+			} else {
+				assert.NoError(err)
+			}
+			reporter.applyFinished(err)
+
+			var events []progressEvent
+			scanner := bufio.NewScanner(out)
+			for scanner.Scan() {
+				// every line is a JSON object on its own, with the common fields always set
+				var fields map[string]any
+				require.NoError(json.Unmarshal(scanner.Bytes(), &fields))
+				assert.Contains(fields, "sequence")
+				assert.Contains(fields, "timestamp")
+				assert.Contains(fields, "type")
+
+				var event progressEvent
+				require.NoError(json.Unmarshal(scanner.Bytes(), &event))
+				events = append(events, event)
+			}
+			require.NoError(scanner.Err())
+			require.Len(events, len(tc.wantEvents))
+
+			for i, event := range events {
+				assert.Equal(uint64(i+1), event.Sequence)
+				assert.Equal(start.Add(time.Duration(i+1)*time.Second), event.Timestamp)
+				event.Sequence = 0
+				event.Timestamp = time.Time{}
+				assert.Equal(tc.wantEvents[i], event)
+			}
+		})
+	}
+}
+
+func TestProgressSpinner(t *testing.T) {
+	assert := assert.New(t)
+
+	out := &bytes.Buffer{}
+	reporter := newNDJSONProgressReporter(out)
+	spinner := progressSpinner{spinnerInterf: &nopSpinner{&bytes.Buffer{}}, progress: reporter}
+
+	reporter.phaseStarted(skipHelmPhase)
+	spinner.Start("Installing Kubernetes components ", false)
+	spinner.Stop()
+
+	var event progressEvent
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	assert.Len(lines, 2)
+	assert.NoError(json.Unmarshal(lines[1], &event))
+	assert.Equal(progressEventPhaseProgress, event.Type)
+	assert.Equal(skipHelmPhase, event.Phase)
+	assert.Equal("Installing Kubernetes components", event.Message)
+}
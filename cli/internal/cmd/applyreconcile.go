@@ -0,0 +1,257 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+)
+
+// phaseInputs returns the inputs the given phase applies to the cluster.
+// Phases without inputs, like the init phase, return false and are never skipped by --reconcile.
+func phaseInputs(phase skipPhase, conf *config.Config, stateFile *state.State, flags applyFlags) (any, bool) {
+	switch phase {
+	case skipInfrastructurePhase:
+		return struct {
+			Name                 string
+			NameTemplate         string
+			DebugCluster         *bool
+			CustomEndpoint       string
+			InternalLoadBalancer bool
+			Tags                 map[string]string
+			UserData             string
+			TerraformBackend     *config.TerraformBackendConfig
+			Provider             config.ProviderConfig
+			NodeGroups           map[string]config.NodeGroup
+		}{
+			Name:                 conf.Name,
+			NameTemplate:         conf.NameTemplate,
+			DebugCluster:         conf.DebugCluster,
+			CustomEndpoint:       conf.CustomEndpoint,
+			InternalLoadBalancer: conf.InternalLoadBalancer,
+			Tags:                 conf.Tags,
+			UserData:             conf.UserData,
+			TerraformBackend:     conf.TerraformBackend,
+			Provider:             conf.Provider,
+			NodeGroups:           conf.NodeGroups,
+		}, true
+	case skipAttestationConfigPhase:
+		return struct {
+			Attestation     config.AttestationConfig
+			MeasurementSalt []byte
+		}{
+			Attestation:     conf.Attestation,
+			MeasurementSalt: stateFile.ClusterValues.MeasurementSalt,
+		}, true
+	case skipCertSANsPhase:
+		// The SANs are sorted and deduplicated when the state file is read,
+		// so the order Terraform returned them in mustn't change the fingerprint.
+		sans := slices.Clone(stateFile.Infrastructure.APIServerCertSANs)
+		slices.Sort(sans)
+		return struct {
+			ClusterEndpoint   string
+			CustomEndpoint    string
+			APIServerCertSANs []string
+		}{
+			ClusterEndpoint:   stateFile.Infrastructure.ClusterEndpoint,
+			CustomEndpoint:    conf.CustomEndpoint,
+			APIServerCertSANs: slices.Compact(sans),
+		}, true
+	case skipHelmPhase:
+		// The API server cert SANs aren't part of the Helm values, they are applied by the certsans phase.
+		infrastructure := stateFile.Infrastructure
+		infrastructure.APIServerCertSANs = nil
+		return struct {
+			Provider            config.ProviderConfig
+			AttestationVariant  string
+			KubernetesVersion   string
+			MicroserviceVersion string
+			ServiceCIDR         string
+			NetworkPolicyPreset string
+			DebugCluster        *bool
+			DisabledCharts      []string
+			ReadinessTimeouts   map[string]string
+			Tolerations         []config.Toleration
+			Namespace           string
+			Conformance         bool
+			Infrastructure      state.Infrastructure
+		}{
+			Provider:            conf.Provider,
+			AttestationVariant:  conf.GetAttestationConfig().GetVariant().String(),
+			KubernetesVersion:   string(conf.KubernetesVersion),
+			MicroserviceVersion: conf.MicroserviceVersion.String(),
+			ServiceCIDR:         conf.ServiceCIDR,
+			NetworkPolicyPreset: conf.NetworkPolicyPreset,
+			DebugCluster:        conf.DebugCluster,
+			DisabledCharts:      conf.DisabledCharts,
+			ReadinessTimeouts:   conf.ReadinessTimeouts,
+			Tolerations:         conf.Tolerations,
+			Namespace:           helmNamespace(stateFile, flags.helmNamespace),
+			Conformance:         flags.conformance,
+			Infrastructure:      infrastructure,
+		}, true
+	case skipImagePhase:
+		if conf.Channel != "" {
+			// The latest image of the channel may change at any time, so the phase is never reconciled.
+			return nil, false
+		}
+		if len(flags.targetGroups) > 0 {
+			// Upgrading single node groups doesn't move the cluster to the image, so the phase is never reconciled.
+			return nil, false
+		}
+		return struct{ Image string }{Image: conf.Image}, true
+	case skipK8sPhase:
+		return struct{ KubernetesVersion string }{KubernetesVersion: string(conf.KubernetesVersion)}, true
+	default:
+		return nil, false
+	}
+}
+
+// phaseFingerprint returns a hash of the inputs of the given phase.
+// An empty fingerprint is returned for phases without inputs.
+func phaseFingerprint(phase skipPhase, conf *config.Config, stateFile *state.State, flags applyFlags) (string, error) {
+	inputs, ok := phaseInputs(phase, conf, stateFile, flags)
+	if !ok {
+		return "", nil
+	}
+	// encoding/json sorts map keys, so equal inputs always result in the same fingerprint.
+	data, err := json.Marshal(inputs)
+	if err != nil {
+		return "", fmt.Errorf("marshaling inputs of phase %s: %w", phase, err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// coveredPhases lists phases whose inputs are applied by another phase as well.
+// The init RPC sets up the cluster with the configured image and Kubernetes version.
+var coveredPhases = map[skipPhase][]skipPhase{
+	skipInitPhase: {skipImagePhase, skipK8sPhase},
+}
+
+// recordPhaseFingerprints stores the fingerprints of a successful phase, and the phases it covers, in the state file.
+func (a *applyCmd) recordPhaseFingerprints(_ context.Context, s *applyState, phase skipPhase) error {
+	recorded := false
+	for _, p := range append([]skipPhase{phase}, coveredPhases[phase]...) {
+		fingerprint, err := phaseFingerprint(p, s.conf, s.stateFile, a.flags)
+		if err != nil {
+			return err
+		}
+		if fingerprint == "" {
+			continue
+		}
+		if s.stateFile.PhaseFingerprints == nil {
+			s.stateFile.PhaseFingerprints = make(map[string]string)
+		}
+		s.stateFile.PhaseFingerprints[string(p)] = fingerprint
+		recorded = true
+	}
+	if !recorded {
+		return nil
+	}
+	if err := s.stateFile.WriteToFile(a.fileHandler, constants.StateFilename); err != nil {
+		return fmt.Errorf("writing state file: %w", err)
+	}
+	return nil
+}
+
+// reconcilePhases skips the phases whose inputs haven't changed, if --reconcile or --since-state is set.
+// With --reconcile, the inputs are compared with the current state file, with --since-state with the given baseline.
+func (a *applyCmd) reconcilePhases(out io.Writer, registry *phaseRegistry, conf *config.Config, stateFile *state.State) error {
+	switch {
+	case a.flags.reconcile:
+		return a.skipUnchangedPhases(out, registry, conf, stateFile, stateFile.PhaseFingerprints)
+	case a.flags.sinceState != "":
+		baseline, err := a.baselineFingerprints()
+		if err != nil {
+			return err
+		}
+		return a.skipUnchangedPhases(out, registry, conf, stateFile, baseline)
+	default:
+		return nil
+	}
+}
+
+// baselineFingerprints returns the phase fingerprints recorded in the baseline state file set by --since-state.
+func (a *applyCmd) baselineFingerprints() (map[string]string, error) {
+	baseline, err := state.ReadFromFile(a.fileHandler, a.flags.sinceState)
+	if err != nil {
+		return nil, fmt.Errorf("reading baseline state file: %w", err)
+	}
+	if len(baseline.PhaseFingerprints) == 0 {
+		return nil, fmt.Errorf("baseline state file %q doesn't record the inputs of any phase, it must be written by an apply of this CLI version or later",
+			a.flags.pathPrefixer.PrefixPrintablePath(a.flags.sinceState))
+	}
+	return baseline.PhaseFingerprints, nil
+}
+
+// skipUnchangedPhases adds all phases whose inputs haven't changed since the run that recorded the given fingerprints to the skipped phases,
+// and reports which phases are reconciled.
+func (a *applyCmd) skipUnchangedPhases(
+	out io.Writer, registry *phaseRegistry, conf *config.Config, stateFile *state.State, recorded map[string]string,
+) error {
+	var reconciled []string
+	for _, name := range registry.names() {
+		phase := skipPhase(name)
+		if a.flags.skipPhases.contains(phase) {
+			continue
+		}
+		fingerprint, err := phaseFingerprint(phase, conf, stateFile, a.flags)
+		if err != nil {
+			return err
+		}
+		if fingerprint != "" && recorded[name] == fingerprint {
+			a.log.Debug(fmt.Sprintf("Inputs of phase %s are unchanged, skipping it", phase))
+			a.flags.skipPhases.add(phase)
+			continue
+		}
+		reconciled = append(reconciled, name)
+	}
+
+	if len(reconciled) == 0 {
+		fmt.Fprintln(out, "All phases are up to date, nothing to reconcile")
+		return nil
+	}
+	fmt.Fprintf(out, "Reconciling phases with changed inputs: %s\n", strings.Join(reconciled, ", "))
+	return nil
+}
+
+// withFingerprints returns a registry that records the fingerprint of every phase after it succeeded.
+func (r *phaseRegistry) withFingerprints(record recordFingerprintFunc) *phaseRegistry {
+	phases := make([]phase, 0, len(r.phases))
+	for _, p := range r.phases {
+		phases = append(phases, fingerprintedPhase{phase: p, record: record})
+	}
+	return &phaseRegistry{phases: phases}
+}
+
+// recordFingerprintFunc records the fingerprint of a successful phase.
+type recordFingerprintFunc func(ctx context.Context, s *applyState, phase skipPhase) error
+
+// fingerprintedPhase records the fingerprint of the wrapped phase once it succeeded.
+type fingerprintedPhase struct {
+	phase
+	record recordFingerprintFunc
+}
+
+// Run executes the phase and records its fingerprint on success.
+func (p fingerprintedPhase) Run(ctx context.Context, s *applyState) error {
+	if err := p.phase.Run(ctx, s); err != nil {
+		return err
+	}
+	return p.record(ctx, s, p.Name())
+}
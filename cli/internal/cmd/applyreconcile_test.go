@@ -0,0 +1,319 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation/measurements"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/edgelesssys/constellation/v2/internal/semver"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcile(t *testing.T) {
+	testCases := map[string]struct {
+		mutate         func(conf *config.Config, stateFile *state.State)
+		noFingerprints bool
+		wantRun        []skipPhase
+		wantOutput     string
+	}{
+		"nothing changed": {
+			wantOutput: "All phases are up to date",
+		},
+		"image changed": {
+			mutate: func(conf *config.Config, _ *state.State) {
+				conf.Image = "v9.9.9"
+			},
+			wantRun:    []skipPhase{skipImagePhase},
+			wantOutput: "Reconciling phases with changed inputs: image",
+		},
+		"attestation config changed": {
+			mutate: func(conf *config.Config, _ *state.State) {
+				conf.Attestation.GCPSEVSNP.Measurements[4] = measurements.WithAllBytes(0x55, measurements.Enforce, measurements.PCRMeasurementLength)
+			},
+			wantRun:    []skipPhase{skipAttestationConfigPhase},
+			wantOutput: "Reconciling phases with changed inputs: attestationconfig",
+		},
+		"cert SANs changed": {
+			mutate: func(_ *config.Config, stateFile *state.State) {
+				stateFile.Infrastructure.APIServerCertSANs = append(stateFile.Infrastructure.APIServerCertSANs, "example.com")
+			},
+			wantRun:    []skipPhase{skipCertSANsPhase},
+			wantOutput: "Reconciling phases with changed inputs: certsans",
+		},
+		"microservice version changed": {
+			mutate: func(conf *config.Config, _ *state.State) {
+				conf.MicroserviceVersion = semver.NewFromInt(9, 9, 9, "")
+			},
+			wantRun:    []skipPhase{skipHelmPhase},
+			wantOutput: "Reconciling phases with changed inputs: helm",
+		},
+		"node groups changed": {
+			mutate: func(conf *config.Config, _ *state.State) {
+				group := conf.NodeGroups[constants.DefaultWorkerGroupName]
+				group.InitialCount++
+				conf.NodeGroups[constants.DefaultWorkerGroupName] = group
+			},
+			wantRun:    []skipPhase{skipInfrastructurePhase},
+			wantOutput: "Reconciling phases with changed inputs: infrastructure",
+		},
+		"no fingerprints recorded": {
+			noFingerprints: true,
+			wantRun: []skipPhase{
+				skipInfrastructurePhase, skipAttestationConfigPhase, skipCertSANsPhase,
+				skipHelmPhase, skipImagePhase, skipK8sPhase,
+			},
+			wantOutput: "Reconciling phases with changed inputs: infrastructure, attestationconfig, certsans, helm, image, k8s",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			conf := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.GCP)
+			stateFile := defaultStateFile(cloudprovider.GCP)
+			a := &applyCmd{
+				fileHandler: fileHandler,
+				log:         logger.NewTest(t),
+				// the init phase is skipped for initialized clusters
+				flags: applyFlags{reconcile: true, skipPhases: newPhases(skipInitPhase)},
+			}
+			s := &applyState{conf: conf, stateFile: stateFile}
+
+			if !tc.noFingerprints {
+				for _, phase := range allPhases() {
+					require.NoError(a.recordPhaseFingerprints(context.Background(), s, skipPhase(phase)))
+				}
+			}
+			if tc.mutate != nil {
+				tc.mutate(conf, stateFile)
+			}
+
+			var ran []skipPhase
+			var phases []phase
+			for _, name := range allPhases() {
+				phases = append(phases, &fakePhase{name: skipPhase(name), ran: &ran})
+			}
+			registry, err := newPhaseRegistry(phases...)
+			require.NoError(err)
+
+			cmd := NewApplyCmd()
+			var out bytes.Buffer
+			cmd.SetOut(&out)
+			require.NoError(a.skipUnchangedPhases(cmd.OutOrStderr(), registry, conf, stateFile, stateFile.PhaseFingerprints))
+			assert.Contains(out.String(), tc.wantOutput)
+
+			require.NoError(registry.withFingerprints(a.recordPhaseFingerprints).run(context.Background(), s, a.flags.skipPhases, true))
+			assert.Equal(tc.wantRun, ran)
+
+			// fingerprints of reconciled phases are persisted, so a second run has nothing to do
+			persisted, err := state.ReadFromFile(fileHandler, constants.StateFilename)
+			require.NoError(err)
+			a.flags.skipPhases = newPhases(skipInitPhase)
+			out.Reset()
+			require.NoError(a.skipUnchangedPhases(cmd.OutOrStderr(), registry, conf, persisted, persisted.PhaseFingerprints))
+			assert.Contains(out.String(), "All phases are up to date")
+		})
+	}
+}
+
+func TestApplySinceState(t *testing.T) {
+	const baselinePath = "baseline-state.yaml"
+
+	testCases := map[string]struct {
+		mutate         func(conf *config.Config, stateFile *state.State)
+		noBaseline     bool
+		noFingerprints bool
+		wantRun        []skipPhase
+		wantOutput     string
+		wantErr        bool
+	}{
+		"baseline matches": {
+			wantOutput: "All phases are up to date",
+		},
+		"image changed since baseline": {
+			mutate: func(conf *config.Config, _ *state.State) {
+				conf.Image = "v9.9.9"
+			},
+			wantRun:    []skipPhase{skipImagePhase},
+			wantOutput: "Reconciling phases with changed inputs: image",
+		},
+		"cert SANs changed since baseline": {
+			mutate: func(_ *config.Config, stateFile *state.State) {
+				stateFile.Infrastructure.APIServerCertSANs = append(stateFile.Infrastructure.APIServerCertSANs, "example.com")
+			},
+			wantRun:    []skipPhase{skipCertSANsPhase},
+			wantOutput: "Reconciling phases with changed inputs: certsans",
+		},
+		"baseline doesn't exist": {
+			noBaseline: true,
+			wantErr:    true,
+		},
+		"baseline without fingerprints": {
+			noFingerprints: true,
+			wantErr:        true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			conf := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.GCP)
+			stateFile := defaultStateFile(cloudprovider.GCP)
+			a := &applyCmd{
+				fileHandler: fileHandler,
+				log:         logger.NewTest(t),
+				// the init phase is skipped for initialized clusters
+				flags: applyFlags{sinceState: baselinePath, skipPhases: newPhases(skipInitPhase)},
+			}
+			s := &applyState{conf: conf, stateFile: stateFile}
+
+			if !tc.noFingerprints {
+				for _, phase := range allPhases() {
+					require.NoError(a.recordPhaseFingerprints(context.Background(), s, skipPhase(phase)))
+				}
+			}
+			if !tc.noBaseline {
+				require.NoError(stateFile.WriteToFile(fileHandler, baselinePath))
+			}
+			if tc.mutate != nil {
+				tc.mutate(conf, stateFile)
+			}
+			// The current state file is up to date with the desired config,
+			// so only the baseline decides which phases run.
+			if !tc.noFingerprints {
+				for _, phase := range allPhases() {
+					require.NoError(a.recordPhaseFingerprints(context.Background(), s, skipPhase(phase)))
+				}
+			}
+
+			var ran []skipPhase
+			var phases []phase
+			for _, name := range allPhases() {
+				phases = append(phases, &fakePhase{name: skipPhase(name), ran: &ran})
+			}
+			registry, err := newPhaseRegistry(phases...)
+			require.NoError(err)
+
+			cmd := NewApplyCmd()
+			var out bytes.Buffer
+			cmd.SetOut(&out)
+			err = a.reconcilePhases(cmd.OutOrStderr(), registry, conf, stateFile)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.Contains(out.String(), tc.wantOutput)
+
+			require.NoError(registry.run(context.Background(), s, a.flags.skipPhases, true))
+			assert.Equal(tc.wantRun, ran)
+		})
+	}
+}
+
+func TestRecordPhaseFingerprints(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fileHandler := file.NewHandler(afero.NewMemMapFs())
+	a := &applyCmd{fileHandler: fileHandler, log: logger.NewTest(t)}
+	s := &applyState{
+		conf:      defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.GCP),
+		stateFile: defaultStateFile(cloudprovider.GCP),
+	}
+
+	// init has no inputs of its own, but covers the image and Kubernetes version
+	require.NoError(a.recordPhaseFingerprints(context.Background(), s, skipInitPhase))
+	assert.NotContains(s.stateFile.PhaseFingerprints, string(skipInitPhase))
+	assert.Contains(s.stateFile.PhaseFingerprints, string(skipImagePhase))
+	assert.Contains(s.stateFile.PhaseFingerprints, string(skipK8sPhase))
+
+	persisted, err := state.ReadFromFile(fileHandler, constants.StateFilename)
+	require.NoError(err)
+	assert.Equal(s.stateFile.PhaseFingerprints, persisted.PhaseFingerprints)
+}
+
+// TestPhaseInputsCoverConfig fails if a config field is added without deciding whether it's an input of a phase.
+// Otherwise, --reconcile and --since-state would skip phases although the field changed.
+func TestPhaseInputsCoverConfig(t *testing.T) {
+	// notPhaseInputs are the config fields that don't change what a phase applies to the cluster.
+	notPhaseInputs := map[string]string{
+		"Version":                             "version of the config format",
+		"PhaseHooks":                          "run around the phases by the CLI",
+		"UnsafeAllowDisablingProtectedCharts": "only relaxes the validation of disabledCharts",
+		"OIDC":                                "only applied by init and can't be changed afterward",
+		"DebugAccess":                         "only applied by init and can't be changed afterward",
+		"RegistryMirrors":                     "only applied by init and can't be changed afterward",
+	}
+	debugCluster := true
+	phaseInputs := map[string]func(conf *config.Config){
+		"Image":                func(conf *config.Config) { conf.Image = "v9.9.9" },
+		"Channel":              func(conf *config.Config) { conf.Channel = "stable" },
+		"Name":                 func(conf *config.Config) { conf.Name = "other" },
+		"NameTemplate":         func(conf *config.Config) { conf.NameTemplate = "{{.Name}}-{{.UID}}" },
+		"KubernetesVersion":    func(conf *config.Config) { conf.KubernetesVersion = "v1.99.0" },
+		"MicroserviceVersion":  func(conf *config.Config) { conf.MicroserviceVersion = semver.NewFromInt(9, 9, 9, "") },
+		"DebugCluster":         func(conf *config.Config) { conf.DebugCluster = &debugCluster },
+		"CustomEndpoint":       func(conf *config.Config) { conf.CustomEndpoint = "example.com" },
+		"InternalLoadBalancer": func(conf *config.Config) { conf.InternalLoadBalancer = true },
+		"ServiceCIDR":          func(conf *config.Config) { conf.ServiceCIDR = "10.0.0.0/8" },
+		"Tags":                 func(conf *config.Config) { conf.Tags = map[string]string{"team": "platform"} },
+		"UserData":             func(conf *config.Config) { conf.UserData = "#!/bin/sh\n" },
+		"NetworkPolicyPreset":  func(conf *config.Config) { conf.NetworkPolicyPreset = "restricted" },
+		"DisabledCharts":       func(conf *config.Config) { conf.DisabledCharts = []string{"coredns"} },
+		"ReadinessTimeouts":    func(conf *config.Config) { conf.ReadinessTimeouts = map[string]string{"cilium": "20m"} },
+		"Tolerations":          func(conf *config.Config) { conf.Tolerations = []config.Toleration{{Operator: "Exists"}} },
+		"TerraformBackend":     func(conf *config.Config) { conf.TerraformBackend = &config.TerraformBackendConfig{Type: "gcs"} },
+		"Provider":             func(conf *config.Config) { conf.Provider.GCP.Region = "europe-west1" },
+		"NodeGroups":           func(conf *config.Config) { conf.NodeGroups["extra"] = config.NodeGroup{Role: "worker"} },
+		"Attestation":          func(conf *config.Config) { conf.Attestation = config.AttestationConfig{} },
+	}
+
+	fingerprints := func(conf *config.Config) map[skipPhase]string {
+		res := make(map[skipPhase]string)
+		for _, phase := range allPhases() {
+			fingerprint, err := phaseFingerprint(skipPhase(phase), conf, defaultStateFile(cloudprovider.GCP), applyFlags{})
+			require.NoError(t, err)
+			res[skipPhase(phase)] = fingerprint
+		}
+		return res
+	}
+
+	fields := reflect.VisibleFields(reflect.TypeOf(config.Config{}))
+	for _, field := range fields {
+		t.Run(field.Name, func(t *testing.T) {
+			if _, ok := notPhaseInputs[field.Name]; ok {
+				return
+			}
+			mutate, ok := phaseInputs[field.Name]
+			require.True(t, ok, "config field %s must be added to the inputs of the phases applying it, or be listed as not being a phase input", field.Name)
+
+			conf := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.GCP)
+			before := fingerprints(conf)
+			mutate(conf)
+			assert.NotEqual(t, before, fingerprints(conf), "changing %s must change the fingerprint of a phase", field.Name)
+		})
+	}
+}
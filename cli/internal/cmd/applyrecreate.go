@@ -0,0 +1,96 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+	"github.com/spf13/cobra"
+)
+
+// recreatingChange is a change of the config that can't be applied to the existing cluster,
+// but deletes the cluster's nodes or cloud resources and creates them again.
+type recreatingChange struct {
+	// field is the config field that changed.
+	field string
+	// from is the value the cluster was created or last applied with.
+	from string
+	// to is the value of the config.
+	to string
+}
+
+// String returns the change formatted for the user.
+func (c recreatingChange) String() string {
+	return fmt.Sprintf("%s: %q -> %q", c.field, c.from, c.to)
+}
+
+// detectRecreatingChanges compares the config with the values recorded in the state file of an existing cluster,
+// and returns the changes that recreate the cluster.
+func detectRecreatingChanges(conf *config.Config, stateFile *state.State) []recreatingChange {
+	var changes []recreatingChange
+
+	// The attestation variant determines the CVM type of all nodes.
+	if stateFile.Attestation != nil {
+		recorded := (&config.Config{Attestation: *stateFile.Attestation}).GetAttestationConfig().GetVariant()
+		configured := conf.GetAttestationConfig().GetVariant()
+		if !recorded.Equal(configured) {
+			changes = append(changes, recreatingChange{field: "attestation", from: recorded.String(), to: configured.String()})
+		}
+	}
+
+	// All cloud resources of the cluster are created in the resource group or project.
+	if azure := stateFile.Infrastructure.Azure; azure != nil && conf.Provider.Azure != nil &&
+		azure.ResourceGroup != "" && azure.ResourceGroup != conf.Provider.Azure.ResourceGroup {
+		changes = append(changes, recreatingChange{field: "provider.azure.resourceGroup", from: azure.ResourceGroup, to: conf.Provider.Azure.ResourceGroup})
+	}
+	if gcp := stateFile.Infrastructure.GCP; gcp != nil && conf.Provider.GCP != nil &&
+		gcp.ProjectID != "" && gcp.ProjectID != conf.Provider.GCP.Project {
+		changes = append(changes, recreatingChange{field: "provider.gcp.project", from: gcp.ProjectID, to: conf.Provider.GCP.Project})
+	}
+
+	return changes
+}
+
+// confirmRecreatingChanges pauses before config changes that delete and recreate the cluster.
+// The changes are applied after confirmation, or without one if --force is set.
+// If confirmations are skipped with --yes, the changes are rejected unless --force is set.
+func (a *applyCmd) confirmRecreatingChanges(cmd *cobra.Command, conf *config.Config, stateFile *state.State) error {
+	if a.flags.allowClusterRecreation {
+		return nil
+	}
+	changes := detectRecreatingChanges(conf, stateFile)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	cmd.PrintErrln("WARNING: The following config changes can't be applied to the existing cluster.")
+	cmd.PrintErrln("Applying them deletes the cluster and creates it again, which destroys all of its workloads and data:")
+	for _, change := range changes {
+		cmd.PrintErrf("  %s\n", change)
+	}
+
+	switch {
+	case a.flags.force:
+		a.log.Debug("Applying changes that recreate the cluster, since --force is set")
+		return nil
+	case a.flags.yes:
+		return errors.New("config changes recreate the cluster: set --force to apply them without confirmation, or revert them")
+	}
+
+	ok, err := askToConfirm(cmd, "Do you want to delete and recreate the cluster?")
+	if err != nil {
+		return fmt.Errorf("asking for confirmation: %w", err)
+	}
+	if !ok {
+		cmd.Println("The cluster wasn't changed.")
+		return errors.New("recreating the cluster aborted by user")
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectRecreatingChanges(t *testing.T) {
+	// newCluster returns a config, and the state of a cluster created with it.
+	newCluster := func(t *testing.T) (*config.Config, *state.State) {
+		conf := config.Default()
+		conf.RemoveProviderAndAttestationExcept(cloudprovider.Azure)
+		conf.Provider.Azure.ResourceGroup = "constell-rg"
+		stateFile := state.New().SetInfrastructure(state.Infrastructure{
+			UID:   "0a1b2c3d",
+			Azure: &state.Azure{ResourceGroup: "constell-rg"},
+		})
+		require.NoError(t, recordAttestationConfig(stateFile, conf.GetAttestationConfig()))
+		return conf, stateFile
+	}
+
+	testCases := map[string]struct {
+		mutate      func(conf *config.Config, stateFile *state.State)
+		wantChanges []recreatingChange
+	}{
+		"nothing changed": {},
+		"attestation variant changed": {
+			mutate: func(conf *config.Config, _ *state.State) {
+				conf.Attestation = config.AttestationConfig{AzureTDX: config.DefaultForAzureTDX()}
+			},
+			wantChanges: []recreatingChange{{field: "attestation", from: "azure-sev-snp", to: "azure-tdx"}},
+		},
+		"resource group changed": {
+			mutate: func(conf *config.Config, _ *state.State) {
+				conf.Provider.Azure.ResourceGroup = "other-rg"
+			},
+			wantChanges: []recreatingChange{{field: "provider.azure.resourceGroup", from: "constell-rg", to: "other-rg"}},
+		},
+		"no attestation config recorded": {
+			mutate: func(conf *config.Config, stateFile *state.State) {
+				conf.Attestation = config.AttestationConfig{AzureTDX: config.DefaultForAzureTDX()}
+				stateFile.Attestation = nil
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			conf, stateFile := newCluster(t)
+			if tc.mutate != nil {
+				tc.mutate(conf, stateFile)
+			}
+			assert.Equal(t, tc.wantChanges, detectRecreatingChanges(conf, stateFile))
+		})
+	}
+}
+
+func TestConfirmRecreatingChanges(t *testing.T) {
+	testCases := map[string]struct {
+		flags   applyFlags
+		stdin   string
+		wantErr bool
+	}{
+		"blocked without force": {
+			flags:   applyFlags{yes: true},
+			wantErr: true,
+		},
+		"allowed with force": {
+			flags: applyFlags{yes: true, rootFlags: rootFlags{force: true}},
+		},
+		"confirmed": {
+			stdin: "y\n",
+		},
+		"declined": {
+			stdin:   "n\n",
+			wantErr: true,
+		},
+		"pause disabled": {
+			flags: applyFlags{yes: true, allowClusterRecreation: true},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			conf := config.Default()
+			conf.RemoveProviderAndAttestationExcept(cloudprovider.Azure)
+			stateFile := state.New()
+			require.NoError(recordAttestationConfig(stateFile, conf.GetAttestationConfig()))
+			conf.Attestation = config.AttestationConfig{AzureTDX: config.DefaultForAzureTDX()}
+
+			a := &applyCmd{flags: tc.flags, log: logger.NewTest(t)}
+			cmd := NewApplyCmd()
+			cmd.SetIn(bytes.NewBufferString(tc.stdin))
+			cmd.SetOut(&bytes.Buffer{})
+			errOut := &bytes.Buffer{}
+			cmd.SetErr(errOut)
+
+			err := a.confirmRecreatingChanges(cmd, conf, stateFile)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			if !tc.flags.allowClusterRecreation {
+				assert.Contains(errOut.String(), `attestation: "azure-sev-snp" -> "azure-tdx"`)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+)
+
+// resourceIDs are the identifiers of the cloud resources of a cluster, as printed by --print-resource-ids.
+// Only the values of the cluster's cloud provider are set.
+type resourceIDs struct {
+	UID       string                `json:"uid"`
+	Name      string                `json:"name"`
+	Azure     *azureResourceIDs     `json:"azure,omitempty"`
+	GCP       *gcpResourceIDs       `json:"gcp,omitempty"`
+	OpenStack *openStackResourceIDs `json:"openstack,omitempty"`
+}
+
+type azureResourceIDs struct {
+	SubscriptionID           string `json:"subscriptionID"`
+	ResourceGroup            string `json:"resourceGroup"`
+	LoadBalancerName         string `json:"loadBalancerName"`
+	NetworkSecurityGroupName string `json:"networkSecurityGroupName"`
+	UserAssignedIdentity     string `json:"userAssignedIdentity"`
+	VirtualNetworkID         string `json:"virtualNetworkID,omitempty"`
+	SubnetID                 string `json:"subnetID,omitempty"`
+}
+
+type gcpResourceIDs struct {
+	ProjectID         string `json:"projectID"`
+	NetworkID         string `json:"networkID,omitempty"`
+	SubnetworkID      string `json:"subnetworkID,omitempty"`
+	DiskEncryptionKey string `json:"diskEncryptionKey,omitempty"`
+}
+
+type openStackResourceIDs struct {
+	NetworkID string `json:"networkID"`
+	SubnetID  string `json:"subnetID"`
+}
+
+// newResourceIDs collects the identifiers of the cloud resources recorded in the infrastructure state.
+func newResourceIDs(infra state.Infrastructure) resourceIDs {
+	ids := resourceIDs{
+		UID:  infra.UID,
+		Name: infra.Name,
+	}
+	if infra.Azure != nil {
+		ids.Azure = &azureResourceIDs{
+			SubscriptionID:           infra.Azure.SubscriptionID,
+			ResourceGroup:            infra.Azure.ResourceGroup,
+			LoadBalancerName:         infra.Azure.LoadBalancerName,
+			NetworkSecurityGroupName: infra.Azure.NetworkSecurityGroupName,
+			UserAssignedIdentity:     infra.Azure.UserAssignedIdentity,
+			VirtualNetworkID:         infra.Azure.VirtualNetworkID,
+			SubnetID:                 infra.Azure.SubnetID,
+		}
+	}
+	if infra.GCP != nil {
+		ids.GCP = &gcpResourceIDs{
+			ProjectID:         infra.GCP.ProjectID,
+			NetworkID:         infra.GCP.NetworkID,
+			SubnetworkID:      infra.GCP.SubnetworkID,
+			DiskEncryptionKey: infra.GCP.DiskEncryptionKey,
+		}
+	}
+	if infra.OpenStack != nil {
+		ids.OpenStack = &openStackResourceIDs{
+			NetworkID: infra.OpenStack.NetworkID,
+			SubnetID:  infra.OpenStack.SubnetID,
+		}
+	}
+	return ids
+}
+
+// printResourceIDs writes the identifiers of the cloud resources recorded in the infrastructure state to out as indented JSON.
+func printResourceIDs(out io.Writer, infra state.Infrastructure) error {
+	raw, err := json.MarshalIndent(newResourceIDs(infra), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, string(raw))
+	return err
+}
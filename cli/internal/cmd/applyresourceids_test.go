@@ -0,0 +1,96 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintResourceIDs(t *testing.T) {
+	testCases := map[string]struct {
+		infra    state.Infrastructure
+		wantJSON string
+	}{
+		"azure": {
+			infra: func() state.Infrastructure {
+				infra := defaultStateFile(cloudprovider.Azure).Infrastructure
+				infra.Azure.VirtualNetworkID = "test-vnet"
+				infra.Azure.SubnetID = "test-subnet"
+				return infra
+			}(),
+			wantJSON: `{
+				"uid": "123",
+				"name": "kubernetes-123",
+				"azure": {
+					"subscriptionID": "test-sub",
+					"resourceGroup": "test-resource-group",
+					"loadBalancerName": "test-lb",
+					"networkSecurityGroupName": "test-nsg",
+					"userAssignedIdentity": "test-uami",
+					"virtualNetworkID": "test-vnet",
+					"subnetID": "test-subnet"
+				}
+			}`,
+		},
+		"gcp": {
+			infra: func() state.Infrastructure {
+				infra := defaultStateFile(cloudprovider.GCP).Infrastructure
+				infra.GCP.NetworkID = "projects/test-project/global/networks/test-network"
+				infra.GCP.SubnetworkID = "projects/test-project/regions/europe-west3/subnetworks/test-subnetwork"
+				return infra
+			}(),
+			wantJSON: `{
+				"uid": "123",
+				"name": "kubernetes-123",
+				"gcp": {
+					"projectID": "test-project",
+					"networkID": "projects/test-project/global/networks/test-network",
+					"subnetworkID": "projects/test-project/regions/europe-west3/subnetworks/test-subnetwork"
+				}
+			}`,
+		},
+		"openstack": {
+			infra: func() state.Infrastructure {
+				infra := defaultStateFile(cloudprovider.OpenStack).Infrastructure
+				infra.OpenStack = &state.OpenStack{NetworkID: "test-network", SubnetID: "test-subnet"}
+				return infra
+			}(),
+			wantJSON: `{
+				"uid": "123",
+				"name": "kubernetes-123",
+				"openstack": {
+					"networkID": "test-network",
+					"subnetID": "test-subnet"
+				}
+			}`,
+		},
+		"aws": {
+			infra: defaultStateFile(cloudprovider.AWS).Infrastructure,
+			wantJSON: `{
+				"uid": "123",
+				"name": "kubernetes-123"
+			}`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			out := &bytes.Buffer{}
+			require.NoError(printResourceIDs(out, tc.infra))
+			assert.JSONEq(tc.wantJSON, out.String())
+		})
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultPhaseRetryInterval is the time waited before a failed phase is retried.
+const defaultPhaseRetryInterval = 10 * time.Second
+
+// phaseRetries configures how often failed phases are retried.
+// Retries of whole phases are independent of the retries of single calls to cloud or Kubernetes APIs within a phase.
+type phaseRetries struct {
+	// maxRetries is the number of retries of phases without an override.
+	maxRetries int
+	// perPhase overrides maxRetries for single phases.
+	perPhase map[skipPhase]int
+}
+
+// forPhase returns the number of retries of the given phase.
+// The init phase is never retried, since the init RPC can't be repeated on a partially initialized cluster.
+func (r phaseRetries) forPhase(phase skipPhase) int {
+	if phase == skipInitPhase {
+		return 0
+	}
+	if retries, ok := r.perPhase[phase]; ok {
+		return retries
+	}
+	return r.maxRetries
+}
+
+// parsePhaseRetries validates the per-phase retry overrides passed as PHASE=RETRIES.
+func parsePhaseRetries(maxRetries int, overrides map[string]int, skip skipPhases) (phaseRetries, error) {
+	if maxRetries < 0 {
+		return phaseRetries{}, fmt.Errorf("--max-retries-per-phase must be 0 or greater, got %d", maxRetries)
+	}
+	retries := phaseRetries{maxRetries: maxRetries}
+	for rawPhase, phaseRetryCount := range overrides {
+		phase := skipPhase(strings.ToLower(rawPhase))
+		switch {
+		case !slices.Contains(allPhases(), string(phase)):
+			return retries, fmt.Errorf("--phase-retries: invalid phase %s", rawPhase)
+		case phase == skipInitPhase:
+			return retries, fmt.Errorf("--phase-retries: the %s phase can't be retried", phase)
+		case skip.contains(phase):
+			return retries, fmt.Errorf("--phase-retries has no effect if the %s phase is skipped", phase)
+		case phaseRetryCount < 0:
+			return retries, fmt.Errorf("--phase-retries: retries of the %s phase must be 0 or greater, got %d", phase, phaseRetryCount)
+		}
+		if retries.perPhase == nil {
+			retries.perPhase = make(map[skipPhase]int, len(overrides))
+		}
+		retries.perPhase[phase] = phaseRetryCount
+	}
+	return retries, nil
+}
+
+// withRetries returns a registry that retries failed phases as configured, waiting interval before every retry.
+// Retries stop once ctx is done, so they never exceed the timeout of the apply run.
+func (r *phaseRegistry) withRetries(retries phaseRetries, interval time.Duration, wLog warnLog) *phaseRegistry {
+	phases := make([]phase, 0, len(r.phases))
+	for _, p := range r.phases {
+		if maxRetries := retries.forPhase(p.Name()); maxRetries > 0 {
+			p = retriedPhase{phase: p, maxRetries: maxRetries, interval: interval, wLog: wLog}
+		}
+		phases = append(phases, p)
+	}
+	return &phaseRegistry{phases: phases}
+}
+
+// retriedPhase runs the wrapped phase again if it fails, up to maxRetries times.
+type retriedPhase struct {
+	phase
+	maxRetries int
+	interval   time.Duration
+	wLog       warnLog
+}
+
+// Run executes the phase until it succeeds, the retries are exhausted, or ctx is done.
+func (p retriedPhase) Run(ctx context.Context, s *applyState) error {
+	for attempt := 1; ; attempt++ {
+		err := p.phase.Run(ctx, s)
+		if err == nil {
+			return nil
+		}
+		if attempt > p.maxRetries {
+			return fmt.Errorf("phase %s failed after %d attempts: %w", p.Name(), attempt, err)
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+
+		p.wLog.Warn(fmt.Sprintf("Phase %s failed (attempt %d of %d), retrying in %s: %s", p.Name(), attempt, p.maxRetries+1, p.interval, err))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(p.interval):
+		}
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhaseRegistryWithRetries(t *testing.T) {
+	someErr := errors.New("failed")
+
+	testCases := map[string]struct {
+		retries   phaseRetries
+		failures  map[skipPhase]int
+		cancelCtx bool
+		wantRun   []skipPhase
+		wantErr   bool
+	}{
+		"no retries": {
+			wantRun: []skipPhase{skipInfrastructurePhase, skipInitPhase, skipHelmPhase},
+		},
+		"phase fails twice and succeeds within retry budget": {
+			retries:  phaseRetries{maxRetries: 3},
+			failures: map[skipPhase]int{skipHelmPhase: 2},
+			wantRun:  []skipPhase{skipInfrastructurePhase, skipInitPhase, skipHelmPhase, skipHelmPhase, skipHelmPhase},
+		},
+		"retries exhausted": {
+			retries:  phaseRetries{maxRetries: 1},
+			failures: map[skipPhase]int{skipHelmPhase: 2},
+			wantRun:  []skipPhase{skipInfrastructurePhase, skipInitPhase, skipHelmPhase, skipHelmPhase},
+			wantErr:  true,
+		},
+		"per-phase override": {
+			retries:  phaseRetries{maxRetries: 0, perPhase: map[skipPhase]int{skipHelmPhase: 3}},
+			failures: map[skipPhase]int{skipHelmPhase: 2},
+			wantRun:  []skipPhase{skipInfrastructurePhase, skipInitPhase, skipHelmPhase, skipHelmPhase, skipHelmPhase},
+		},
+		"per-phase override disables retries": {
+			retries:  phaseRetries{maxRetries: 3, perPhase: map[skipPhase]int{skipInfrastructurePhase: 0}},
+			failures: map[skipPhase]int{skipInfrastructurePhase: 1},
+			wantRun:  []skipPhase{skipInfrastructurePhase},
+			wantErr:  true,
+		},
+		"init phase isn't retried": {
+			retries:  phaseRetries{maxRetries: 3},
+			failures: map[skipPhase]int{skipInitPhase: 1},
+			wantRun:  []skipPhase{skipInfrastructurePhase, skipInitPhase},
+			wantErr:  true,
+		},
+		"no retries once context is done": {
+			retries:   phaseRetries{maxRetries: 3},
+			failures:  map[skipPhase]int{skipInfrastructurePhase: 1},
+			cancelCtx: true,
+			wantRun:   []skipPhase{skipInfrastructurePhase},
+			wantErr:   true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.cancelCtx {
+				cancel()
+			}
+
+			var ran []skipPhase
+			newFake := func(name skipPhase, dependsOn ...skipPhase) *fakePhase {
+				p := &fakePhase{name: name, dependsOn: dependsOn, ran: &ran}
+				failures := tc.failures[name]
+				p.run = func(*applyState) {
+					if failures > 0 {
+						failures--
+						p.err = someErr
+					} else {
+						p.err = nil
+					}
+				}
+				return p
+			}
+			registry, err := newPhaseRegistry(
+				newFake(skipInfrastructurePhase),
+				newFake(skipInitPhase, skipInfrastructurePhase),
+				newFake(skipHelmPhase, skipInitPhase),
+			)
+			require.NoError(err)
+
+			wLog := &warnLogger{cmd: NewApplyCmd(), log: logger.NewTest(t)}
+			err = registry.withRetries(tc.retries, 0, wLog).run(ctx, &applyState{}, nil, true)
+			if tc.wantErr {
+				assert.ErrorIs(err, someErr)
+			} else {
+				assert.NoError(err)
+			}
+			assert.Equal(tc.wantRun, ran)
+		})
+	}
+}
@@ -30,12 +30,35 @@ func (a *applyCmd) runTerraformApply(cmd *cobra.Command, conf *config.Config, st
 	}
 	defer removeClient()
 
+	// Fail early if the cloud provider credentials are missing or invalid,
+	// before any resources are modified
+	a.log.Debug("Validating cloud provider credentials")
+	if err := terraformClient.ValidateCredentials(cmd.Context(), conf); err != nil {
+		return err
+	}
+
+	if err := validateExistingNetworkCIDR(conf, stateFile); err != nil {
+		return err
+	}
+	if err := validateDiskEncryptionKey(conf, stateFile); err != nil {
+		return err
+	}
+
 	// Check if we are creating a new cluster by checking if the Terraform workspace is empty
 	isNewCluster, err := terraformClient.WorkingDirIsEmpty()
 	if err != nil {
 		return fmt.Errorf("checking if Terraform workspace is empty: %w", err)
 	}
 
+	// Resources of a previous, failed apply can only be adopted before Terraform manages any resources of the cluster
+	if a.flags.detectAndAdopt {
+		if !isNewCluster {
+			a.log.Debug("Terraform workspace isn't empty, skipping the adoption of existing resources")
+		} else if err := a.adoptResources(cmd, conf, terraformClient); err != nil {
+			return err
+		}
+	}
+
 	if changesRequired, err := a.planTerraformChanges(cmd, conf, terraformClient); err != nil {
 		return fmt.Errorf("planning Terraform migrations: %w", err)
 	} else if !changesRequired {
@@ -65,6 +88,32 @@ func (a *applyCmd) runTerraformApply(cmd *cobra.Command, conf *config.Config, st
 	return nil
 }
 
+// validateExistingNetworkCIDR checks that the CIDR range of the existing network the nodes are attached to
+// matches the node CIDR of an already created cluster.
+// The node CIDR is configured in the cluster's network plugin, so it can't change after the cluster was created.
+func validateExistingNetworkCIDR(conf *config.Config, stateFile *state.State) error {
+	cidr := conf.ExistingNetworkCIDR()
+	if cidr == "" || stateFile.Infrastructure.IPCidrNode == "" || cidr == stateFile.Infrastructure.IPCidrNode {
+		return nil
+	}
+	return fmt.Errorf("CIDR range %s of the existing network doesn't match the node CIDR %s of the cluster: the node CIDR can't be changed after the cluster was created",
+		cidr, stateFile.Infrastructure.IPCidrNode)
+}
+
+// validateDiskEncryptionKey checks that the key the node disks are encrypted with matches the key of an already created cluster.
+// Terraform ignores changes to the disks of the instance templates, so a changed key would silently not be applied.
+func validateDiskEncryptionKey(conf *config.Config, stateFile *state.State) error {
+	if conf.Provider.GCP == nil || stateFile.Infrastructure.GCP == nil {
+		return nil
+	}
+	key := conf.Provider.GCP.DiskEncryptionKey
+	if key == stateFile.Infrastructure.GCP.DiskEncryptionKey {
+		return nil
+	}
+	return fmt.Errorf("disk encryption key %q doesn't match the key %q the cluster was created with: the disk encryption key can't be changed after the cluster was created",
+		key, stateFile.Infrastructure.GCP.DiskEncryptionKey)
+}
+
 // planTerraformChanges checks if any changes to the Terraform state are required.
 // If no state exists, this function will return true and the caller should create a new state.
 func (a *applyCmd) planTerraformChanges(cmd *cobra.Command, conf *config.Config, terraformClient cloudApplier) (bool, error) {
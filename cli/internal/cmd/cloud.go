@@ -19,10 +19,12 @@ import (
 )
 
 type cloudApplier interface {
+	ValidateCredentials(ctx context.Context, conf *config.Config) error
 	Plan(ctx context.Context, conf *config.Config) (bool, error)
 	Apply(ctx context.Context, csp cloudprovider.Provider, variant variant.Variant, rollback cloudcmd.RollbackBehavior) (state.Infrastructure, error)
 	RestoreWorkspace() error
 	WorkingDirIsEmpty() (bool, error)
+	Adopt(resources []cloudcmd.AdoptableResource)
 }
 
 type cloudIAMCreator interface {
@@ -29,15 +29,23 @@ func TestMain(m *testing.M) {
 }
 
 type stubCloudCreator struct {
-	state               state.Infrastructure
-	planCalled          bool
-	planDiff            bool
-	planErr             error
-	applyCalled         bool
-	applyErr            error
-	restoreErr          error
-	workspaceIsEmpty    bool
-	workspaceIsEmptyErr error
+	state                     state.Infrastructure
+	validateCredentialsCalled bool
+	validateCredentialsErr    error
+	planCalled                bool
+	planDiff                  bool
+	planErr                   error
+	applyCalled               bool
+	applyErr                  error
+	restoreErr                error
+	workspaceIsEmpty          bool
+	workspaceIsEmptyErr       error
+	adopted                   []cloudcmd.AdoptableResource
+}
+
+func (c *stubCloudCreator) ValidateCredentials(_ context.Context, _ *config.Config) error {
+	c.validateCredentialsCalled = true
+	return c.validateCredentialsErr
 }
 
 func (c *stubCloudCreator) Plan(_ context.Context, _ *config.Config) (bool, error) {
@@ -58,6 +66,10 @@ func (c *stubCloudCreator) WorkingDirIsEmpty() (bool, error) {
 	return c.workspaceIsEmpty, c.workspaceIsEmptyErr
 }
 
+func (c *stubCloudCreator) Adopt(resources []cloudcmd.AdoptableResource) {
+	c.adopted = append(c.adopted, resources...)
+}
+
 type stubCloudTerminator struct {
 	called       bool
 	terminateErr error
@@ -37,6 +37,14 @@ type rootFlags struct {
 	tfLogLevel   terraform.LogLevel
 	debug        bool
 	force        bool
+	// profile is the name of the config profile whose overlay is merged into the config file.
+	profile string
+	// configRef references the ConfigMap or Secret in the cluster the config is loaded from.
+	// If it is nil, the config file of the workspace is used.
+	configRef *clusterConfigRef
+	// kubeConfig is the path of the kubeconfig used to connect to the cluster.
+	// If it is empty, the admin kubeconfig of the workspace is used.
+	kubeConfig string
 }
 
 // parse flags into the rootFlags struct.
@@ -67,9 +75,46 @@ func (f *rootFlags) parse(flags *pflag.FlagSet) error {
 	if err != nil {
 		errs = errors.Join(err, fmt.Errorf("getting 'force' flag: %w", err))
 	}
+
+	f.profile, err = flags.GetString("profile")
+	if err != nil {
+		errs = errors.Join(err, fmt.Errorf("getting 'profile' flag: %w", err))
+	}
+
+	configRef, err := flags.GetString("config")
+	if err != nil {
+		errs = errors.Join(err, fmt.Errorf("getting 'config' flag: %w", err))
+	}
+	if configRef != "" {
+		f.configRef, err = parseClusterConfigRef(configRef)
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("parsing 'config' flag: %w", err))
+		}
+		if f.profile != "" {
+			errs = errors.Join(errs, errors.New("--profile can't be combined with --config"))
+		}
+	}
+
+	f.kubeConfig, err = flags.GetString("kubeconfig")
+	if err != nil {
+		errs = errors.Join(err, fmt.Errorf("getting 'kubeconfig' flag: %w", err))
+	}
 	return errs
 }
 
+// requireConfigFile returns an error if the config isn't read from the plain config file of the workspace.
+// Commands that write the config file don't support profiles, since the merged config would overwrite the base config file,
+// nor configs stored in the cluster, since they only modify the config file.
+func (f *rootFlags) requireConfigFile() error {
+	if f.profile != "" {
+		return errors.New("--profile can't be used with commands that write the config file")
+	}
+	if f.configRef != nil {
+		return errors.New("--config can't be used with commands that write the config file")
+	}
+	return nil
+}
+
 func must(err error) {
 	if err != nil {
 		panic(err)
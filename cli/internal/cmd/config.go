@@ -22,9 +22,14 @@ func NewConfigCmd() *cobra.Command {
 
 	cmd.AddCommand(newConfigGenerateCmd())
 	cmd.AddCommand(newConfigFetchMeasurementsCmd())
+	cmd.AddCommand(newConfigFetchAttestationFeedCmd())
 	cmd.AddCommand(newConfigInstanceTypesCmd())
 	cmd.AddCommand(newConfigKubernetesVersionsCmd())
+	cmd.AddCommand(newConfigLintCmd())
 	cmd.AddCommand(newConfigMigrateCmd())
+	cmd.AddCommand(newConfigGetCmd())
+	cmd.AddCommand(newConfigSetCmd())
+	cmd.AddCommand(newConfigValidateCmd())
 
 	return cmd
 }
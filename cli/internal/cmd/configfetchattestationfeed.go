@@ -0,0 +1,172 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/edgelesssys/constellation/v2/internal/api/attestationconfigapi"
+	"github.com/edgelesssys/constellation/v2/internal/api/fetcher"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/feed"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/variant"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/sigstore"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func newConfigFetchAttestationFeedCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fetch-attestation-feed",
+		Short: "Fetch measurements and TCB versions from an attestation feed of a cloud provider",
+		Long: "Fetch measurements and TCB versions from an attestation feed of a cloud provider.\n\n" +
+			"The expected values for the configured image and attestation variant are shown and written to the config after confirmation. " +
+			"The signature of the feed is verified with the given public key. Fetched feeds are cached locally for 24 hours.",
+		Args: cobra.ExactArgs(0),
+		RunE: runConfigFetchAttestationFeed,
+	}
+	cmd.Flags().String("url", "", "URL of the attestation feed (required)")
+	cmd.Flags().String("public-key", "", "path to the PEM encoded cosign public key the feed is signed with (required)")
+	cmd.Flags().BoolP("yes", "y", false, "update the config without further confirmation")
+	must(cmd.MarkFlagRequired("url"))
+	must(cmd.MarkFlagRequired("public-key"))
+	return cmd
+}
+
+type fetchAttestationFeedFlags struct {
+	rootFlags
+	feedURL       string
+	publicKeyPath string
+	yes           bool
+}
+
+func (f *fetchAttestationFeedFlags) parse(flags *pflag.FlagSet) error {
+	if err := f.rootFlags.parse(flags); err != nil {
+		return err
+	}
+
+	var err error
+	f.feedURL, err = flags.GetString("url")
+	if err != nil {
+		return fmt.Errorf("getting 'url' flag: %w", err)
+	}
+	f.publicKeyPath, err = flags.GetString("public-key")
+	if err != nil {
+		return fmt.Errorf("getting 'public-key' flag: %w", err)
+	}
+	f.yes, err = flags.GetBool("yes")
+	if err != nil {
+		return fmt.Errorf("getting 'yes' flag: %w", err)
+	}
+	return nil
+}
+
+type attestationFeedFetcher interface {
+	Fetch(ctx context.Context, feedURL string, attestationVariant variant.Variant, image string) (feed.Entry, error)
+}
+
+type configFetchAttestationFeedCmd struct {
+	flags       fetchAttestationFeedFlags
+	fileHandler file.Handler
+	log         debugLog
+}
+
+func runConfigFetchAttestationFeed(cmd *cobra.Command, _ []string) error {
+	log, err := newCLILogger(cmd)
+	if err != nil {
+		return fmt.Errorf("creating logger: %w", err)
+	}
+	c := &configFetchAttestationFeedCmd{
+		fileHandler: file.NewHandler(afero.NewOsFs()),
+		log:         log,
+	}
+	if err := c.flags.parse(cmd.Flags()); err != nil {
+		return err
+	}
+	if err := c.flags.requireConfigFile(); err != nil {
+		return err
+	}
+
+	publicKey, err := c.fileHandler.Read(c.flags.publicKeyPath)
+	if err != nil {
+		return fmt.Errorf("reading public key: %w", err)
+	}
+	verifier, err := sigstore.NewCosignVerifier(publicKey)
+	if err != nil {
+		return fmt.Errorf("creating cosign verifier: %w", err)
+	}
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return fmt.Errorf("getting cache directory: %w", err)
+	}
+	feedClient := feed.NewClient(fetcher.NewHTTPClient(), verifier, c.fileHandler, filepath.Join(userCacheDir, "constellation", "attestation-feeds"))
+
+	return c.fetchAttestationFeed(cmd, feedClient, attestationconfigapi.NewFetcher())
+}
+
+func (c *configFetchAttestationFeedCmd) fetchAttestationFeed(
+	cmd *cobra.Command, feedFetcher attestationFeedFetcher, configFetcher attestationconfigapi.Fetcher,
+) error {
+	c.log.Debug(fmt.Sprintf("Loading configuration file from %q", c.flags.pathPrefixer.PrefixPrintablePath(constants.ConfigFilename)))
+	conf, err := config.New(c.fileHandler, constants.ConfigFilename, configFetcher, c.flags.force)
+	var configValidationErr *config.ValidationError
+	if errors.As(err, &configValidationErr) {
+		cmd.PrintErrln(configValidationErr.LongMessage())
+	}
+	if err != nil {
+		return err
+	}
+
+	attestationVariant := conf.GetAttestationConfig().GetVariant()
+	c.log.Debug("Fetching attestation feed", "url", c.flags.feedURL, "variant", attestationVariant, "image", conf.Image)
+	entry, err := feedFetcher.Fetch(cmd.Context(), c.flags.feedURL, attestationVariant, conf.Image)
+	if err != nil {
+		return fmt.Errorf("fetching attestation feed: %w", err)
+	}
+	if len(entry.Measurements) == 0 && entry.SEVSNPVersion == nil {
+		return fmt.Errorf("attestation feed contains no expected values for attestation variant %s and image %s", attestationVariant, conf.Image)
+	}
+
+	cmd.Printf("Attestation feed %s provides the following values for %s and image %s:\n", c.flags.feedURL, attestationVariant, conf.Image)
+	if len(entry.Measurements) > 0 {
+		cmd.Printf("Measurements:\n%s", entry.Measurements.String())
+	}
+	if entry.SEVSNPVersion != nil {
+		cmd.Printf("Minimum TCB: bootloader %d, TEE %d, SNP %d, microcode %d\n",
+			entry.SEVSNPVersion.Bootloader, entry.SEVSNPVersion.TEE, entry.SEVSNPVersion.SNP, entry.SEVSNPVersion.Microcode)
+	}
+
+	if !c.flags.yes {
+		ok, err := askToConfirm(cmd, fmt.Sprintf("Update the attestation config in %s with these values?", c.flags.pathPrefixer.PrefixPrintablePath(constants.ConfigFilename)))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			cmd.Println("The config wasn't updated.")
+			return nil
+		}
+	}
+
+	if len(entry.Measurements) > 0 {
+		conf.UpdateMeasurements(entry.Measurements)
+	}
+	if entry.SEVSNPVersion != nil {
+		conf.UpdateSEVSNPVersions(*entry.SEVSNPVersion)
+	}
+	if err := c.fileHandler.WriteYAML(constants.ConfigFilename, conf, file.OptOverwrite); err != nil {
+		return err
+	}
+	cmd.Println("Successfully updated the attestation config from the attestation feed.")
+	return nil
+}
@@ -0,0 +1,152 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/api/attestationconfigapi"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/feed"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/measurements"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/variant"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigFetchAttestationFeed(t *testing.T) {
+	feedMeasurements := measurements.M{
+		4:  measurements.WithAllBytes(0xaa, measurements.Enforce, measurements.PCRMeasurementLength),
+		9:  measurements.WithAllBytes(0xbb, measurements.Enforce, measurements.PCRMeasurementLength),
+		12: measurements.WithAllBytes(0xcc, measurements.Enforce, measurements.PCRMeasurementLength),
+	}
+	feedTCB := attestationconfigapi.SEVSNPVersion{Bootloader: 3, TEE: 0, SNP: 8, Microcode: 115}
+	image := constants.BinaryVersion().String()
+
+	testCases := map[string]struct {
+		entries        []feed.Entry
+		verifyErr      error
+		yes            bool
+		stdin          string
+		wantUpdate     bool
+		wantCacheWrite bool
+		wantErr        bool
+	}{
+		"config is updated without confirmation": {
+			entries:        []feed.Entry{{Variant: variant.AzureSEVSNP{}.String(), Image: image, Measurements: feedMeasurements, SEVSNPVersion: &feedTCB}},
+			yes:            true,
+			wantUpdate:     true,
+			wantCacheWrite: true,
+		},
+		"config is updated after confirmation": {
+			entries:        []feed.Entry{{Variant: variant.AzureSEVSNP{}.String(), Image: image, Measurements: feedMeasurements, SEVSNPVersion: &feedTCB}},
+			stdin:          "y\n",
+			wantUpdate:     true,
+			wantCacheWrite: true,
+		},
+		"confirmation declined": {
+			entries:        []feed.Entry{{Variant: variant.AzureSEVSNP{}.String(), Image: image, Measurements: feedMeasurements, SEVSNPVersion: &feedTCB}},
+			stdin:          "n\n",
+			wantCacheWrite: true,
+		},
+		"no entry for image": {
+			entries:        []feed.Entry{{Variant: variant.AzureSEVSNP{}.String(), Image: "v999.0.0", Measurements: feedMeasurements}},
+			yes:            true,
+			wantCacheWrite: true,
+			wantErr:        true,
+		},
+		"entry without expected values": {
+			entries:        []feed.Entry{{Variant: variant.AzureSEVSNP{}.String(), Image: image}},
+			yes:            true,
+			wantCacheWrite: true,
+			wantErr:        true,
+		},
+		"invalid signature": {
+			entries:   []feed.Entry{{Variant: variant.AzureSEVSNP{}.String(), Image: image, Measurements: feedMeasurements, SEVSNPVersion: &feedTCB}},
+			verifyErr: errors.New("invalid signature"),
+			yes:       true,
+			wantErr:   true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			rawFeed, err := json.Marshal(feed.Feed{Entries: tc.entries})
+			require.NoError(err)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/feed.json":
+					_, _ = w.Write(rawFeed)
+				case "/feed.json.sig":
+					_, _ = w.Write([]byte("signature"))
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			fs := afero.NewMemMapFs()
+			fileHandler := file.NewHandler(fs)
+			gotConfig := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.Azure)
+			wantConfig := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.Azure)
+			require.NoError(fileHandler.WriteYAML(constants.ConfigFilename, gotConfig))
+			if tc.wantUpdate {
+				wantConfig.UpdateMeasurements(feedMeasurements)
+				wantConfig.UpdateSEVSNPVersions(feedTCB)
+			}
+
+			cmd := newConfigFetchAttestationFeedCmd()
+			cmd.SetOut(&bytes.Buffer{})
+			cmd.SetErr(&bytes.Buffer{})
+			cmd.SetIn(bytes.NewBufferString(tc.stdin))
+			cmd.SetContext(context.Background())
+
+			c := &configFetchAttestationFeedCmd{
+				fileHandler: fileHandler,
+				log:         logger.NewTest(t),
+				flags: fetchAttestationFeedFlags{
+					feedURL: server.URL + "/feed.json",
+					yes:     tc.yes,
+				},
+			}
+			feedClient := feed.NewClient(server.Client(), &stubCosignVerifier{verifyError: tc.verifyErr}, fileHandler, "cache")
+
+			err = c.fetchAttestationFeed(cmd, feedClient, stubAttestationFetcher{})
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+
+			var writtenConfig config.Config
+			require.NoError(fileHandler.ReadYAML(constants.ConfigFilename, &writtenConfig))
+			assert.Equal(wantConfig.Attestation, writtenConfig.Attestation)
+
+			cacheFiles, err := afero.ReadDir(fs, "cache")
+			if tc.wantCacheWrite {
+				require.NoError(err)
+				assert.Len(cacheFiles, 1)
+			} else {
+				assert.Empty(cacheFiles)
+			}
+		})
+	}
+}
@@ -69,6 +69,9 @@ func TestParseFetchMeasurementsFlags(t *testing.T) {
 			cmd.Flags().Bool("force", false, "")
 			cmd.Flags().Bool("debug", false, "")
 			cmd.Flags().String("tf-log", "NONE", "")
+			cmd.Flags().String("profile", "", "")
+			cmd.Flags().String("config", "", "")
+			cmd.Flags().String("kubeconfig", "", "")
 
 			if tc.urlFlag != "" {
 				require.NoError(cmd.Flags().Set("url", tc.urlFlag))
@@ -195,11 +198,12 @@ func TestConfigFetchMeasurements(t *testing.T) {
 }
 
 type stubVerifyFetcher struct {
-	err error
+	measurements measurements.M
+	err          error
 }
 
 func (f stubVerifyFetcher) FetchAndVerifyMeasurements(_ context.Context, _ string, _ cloudprovider.Provider, _ variant.Variant, _ bool) (measurements.M, error) {
-	return nil, f.err
+	return f.measurements, f.err
 }
 
 type stubAttestationFetcher struct{}
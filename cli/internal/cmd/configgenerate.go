@@ -7,6 +7,8 @@ SPDX-License-Identifier: AGPL-3.0-only
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -17,10 +19,23 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
 	"github.com/edgelesssys/constellation/v2/internal/file"
 	"github.com/edgelesssys/constellation/v2/internal/versions"
+	"github.com/siderolabs/talos/pkg/machinery/config/encoder"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"golang.org/x/mod/semver"
+	"sigs.k8s.io/yaml"
+)
+
+// configFormat is the serialization format of a generated config file.
+type configFormat string
+
+const (
+	// configFormatYAML writes the config as commented YAML.
+	configFormatYAML configFormat = "yaml"
+	// configFormatJSON writes the config as JSON.
+	// JSON has no notion of comments, so the field documentation is dropped.
+	configFormatJSON configFormat = "json"
 )
 
 func newConfigGenerateCmd() *cobra.Command {
@@ -38,6 +53,9 @@ func newConfigGenerateCmd() *cobra.Command {
 	cmd.Flags().StringP("kubernetes", "k", semver.MajorMinor(string(config.Default().KubernetesVersion)), "Kubernetes version to use in format MAJOR.MINOR")
 	cmd.Flags().StringP("attestation", "a", "", fmt.Sprintf("attestation variant to use %s. If not specified, the default for the cloud provider is used", printFormattedSlice(variant.GetAvailableAttestationVariants())))
 	cmd.Flags().StringSliceP("tags", "t", nil, "additional tags for created resources given a list of key=value")
+	cmd.Flags().String("format", string(configFormatYAML), "output format of the config file {yaml|json}\n"+
+		"Documentation comments are only included in the yaml format and are dropped for json.")
+	cmd.Flags().String("config-schema-out", "", "write the JSON schema of the config file to this path, e.g., for validation and completion in editors")
 
 	return cmd
 }
@@ -47,6 +65,8 @@ type generateFlags struct {
 	k8sVersion         versions.ValidK8sVersion
 	attestationVariant variant.Variant
 	tags               cloudprovider.Tags
+	format             configFormat
+	schemaOut          string
 }
 
 func (f *generateFlags) parse(flags *pflag.FlagSet) error {
@@ -72,6 +92,25 @@ func (f *generateFlags) parse(flags *pflag.FlagSet) error {
 	}
 	f.tags = tags
 
+	format, err := flags.GetString("format")
+	if err != nil {
+		return fmt.Errorf("getting 'format' flag: %w", err)
+	}
+	switch configFormat(strings.ToLower(format)) {
+	case configFormatYAML:
+		f.format = configFormatYAML
+	case configFormatJSON:
+		f.format = configFormatJSON
+	default:
+		return fmt.Errorf("invalid format %q, must be one of {yaml|json}", format)
+	}
+
+	schemaOut, err := flags.GetString("config-schema-out")
+	if err != nil {
+		return fmt.Errorf("getting 'config-schema-out' flag: %w", err)
+	}
+	f.schemaOut = schemaOut
+
 	return nil
 }
 
@@ -93,6 +132,9 @@ func runConfigGenerate(cmd *cobra.Command, args []string) error {
 	if err := cg.flags.parse(cmd.Flags()); err != nil {
 		return fmt.Errorf("parsing flags: %w", err)
 	}
+	if err := cg.flags.requireConfigFile(); err != nil {
+		return err
+	}
 	log.Debug("Using flags", "k8sVersion", cg.flags.k8sVersion, "attestationVariant", cg.flags.attestationVariant)
 
 	return cg.configGenerate(cmd, fileHandler, provider, args[0])
@@ -108,14 +150,24 @@ func (cg *configGenerateCmd) configGenerate(cmd *cobra.Command, fileHandler file
 	}
 	conf.KubernetesVersion = cg.flags.k8sVersion
 	conf.Tags = cg.flags.tags
-	cg.log.Debug("Writing YAML data to configuration file")
-	if err := fileHandler.WriteYAML(constants.ConfigFilename, conf, file.OptMkdirAll); err != nil {
+	if err := writeConfig(fileHandler, conf, cg.flags.format, cg.log); err != nil {
 		return fmt.Errorf("writing config file: %w", err)
 	}
 
 	cmd.Println("Config file written to", cg.flags.pathPrefixer.PrefixPrintablePath(constants.ConfigFilename))
 	cmd.Println("Please fill in your CSP-specific configuration before proceeding.")
 
+	if cg.flags.schemaOut != "" {
+		schema, err := config.JSONSchema()
+		if err != nil {
+			return fmt.Errorf("generating config schema: %w", err)
+		}
+		if err := fileHandler.Write(cg.flags.schemaOut, schema, file.OptMkdirAll); err != nil {
+			return fmt.Errorf("writing config schema: %w", err)
+		}
+		cmd.Println("Config schema written to", cg.flags.pathPrefixer.PrefixPrintablePath(cg.flags.schemaOut))
+	}
+
 	// State-file creation
 	stateFile := state.New()
 	switch provider {
@@ -135,6 +187,44 @@ func (cg *configGenerateCmd) configGenerate(cmd *cobra.Command, fileHandler file
 	return nil
 }
 
+// writeConfig writes the config to the config file in the given format.
+// JSON is a subset of YAML, so a JSON config file can be read by all commands
+// without any further changes.
+func writeConfig(fileHandler file.Handler, conf *config.Config, format configFormat, log debugLog) error {
+	switch format {
+	case configFormatJSON:
+		log.Debug("Writing JSON data to configuration file")
+		data, err := marshalConfigJSON(conf)
+		if err != nil {
+			return err
+		}
+		return fileHandler.Write(constants.ConfigFilename, data, file.OptMkdirAll)
+	default:
+		log.Debug("Writing YAML data to configuration file")
+		return fileHandler.WriteYAML(constants.ConfigFilename, conf, file.OptMkdirAll)
+	}
+}
+
+// marshalConfigJSON marshals the config to indented JSON.
+// The config types only define YAML tags and custom YAML marshalers,
+// so the config is first encoded as YAML and then converted to JSON.
+func marshalConfigJSON(conf *config.Config) ([]byte, error) {
+	yamlData, err := encoder.NewEncoder(conf, encoder.WithComments(encoder.CommentsDisabled)).Encode()
+	if err != nil {
+		return nil, fmt.Errorf("encoding config as YAML: %w", err)
+	}
+	jsonData, err := yaml.YAMLToJSON(yamlData)
+	if err != nil {
+		return nil, fmt.Errorf("converting config to JSON: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, jsonData, "", "\t"); err != nil {
+		return nil, fmt.Errorf("indenting JSON config: %w", err)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
 // createConfigWithAttestationVariant creates a config file for the given provider.
 func createConfigWithAttestationVariant(provider cloudprovider.Provider, rawProvider string, attestationVariant variant.Variant) (*config.Config, error) {
 	conf := config.Default().WithOpenStackProviderDefaults(provider, rawProvider)
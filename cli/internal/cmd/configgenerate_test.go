@@ -7,6 +7,7 @@ SPDX-License-Identifier: AGPL-3.0-only
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
@@ -22,6 +23,7 @@ import (
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/xeipuuv/gojsonschema"
 	"golang.org/x/mod/semver"
 )
 
@@ -183,6 +185,149 @@ func TestConfigGenerateDefaultExists(t *testing.T) {
 	require.Error(cg.configGenerate(cmd, fileHandler, cloudprovider.Unknown, ""))
 }
 
+func TestConfigGenerateJSON(t *testing.T) {
+	testCases := map[string]struct {
+		provider    cloudprovider.Provider
+		rawProvider string
+	}{
+		"aws": {
+			provider: cloudprovider.AWS,
+		},
+		"azure": {
+			provider: cloudprovider.Azure,
+		},
+		"gcp": {
+			provider: cloudprovider.GCP,
+		},
+		"qemu": {
+			provider: cloudprovider.QEMU,
+		},
+		"stackit": {
+			provider:    cloudprovider.OpenStack,
+			rawProvider: "stackit",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			generate := func(format configFormat) file.Handler {
+				fileHandler := file.NewHandler(afero.NewMemMapFs())
+				cg := &configGenerateCmd{
+					log: logger.NewTest(t),
+					flags: generateFlags{
+						attestationVariant: variant.Dummy{},
+						k8sVersion:         versions.Default,
+						format:             format,
+					},
+				}
+				require.NoError(cg.configGenerate(newConfigGenerateCmd(), fileHandler, tc.provider, tc.rawProvider))
+				return fileHandler
+			}
+			yamlHandler := generate(configFormatYAML)
+			jsonHandler := generate(configFormatJSON)
+
+			rawJSON, err := jsonHandler.Read(constants.ConfigFilename)
+			require.NoError(err)
+			assert.True(json.Valid(rawJSON))
+
+			// Both formats have to result in the same config and the same validation result.
+			yamlConf, yamlErr := config.New(yamlHandler, constants.ConfigFilename, stubAttestationFetcher{}, true)
+			jsonConf, jsonErr := config.New(jsonHandler, constants.ConfigFilename, stubAttestationFetcher{}, true)
+			require.NotNil(yamlConf)
+			require.NotNil(jsonConf)
+			assert.Equal(yamlConf, jsonConf)
+			if yamlErr != nil {
+				require.Error(jsonErr)
+				assert.Equal(yamlErr.Error(), jsonErr.Error())
+			} else {
+				assert.NoError(jsonErr)
+			}
+		})
+	}
+}
+
+func TestConfigGenerateSchema(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fileHandler := file.NewHandler(afero.NewMemMapFs())
+	cg := &configGenerateCmd{
+		log: logger.NewTest(t),
+		flags: generateFlags{
+			attestationVariant: variant.Dummy{},
+			k8sVersion:         versions.Default,
+			format:             configFormatJSON,
+			schemaOut:          "schema/constellation-conf.schema.json",
+		},
+	}
+	require.NoError(cg.configGenerate(newConfigGenerateCmd(), fileHandler, cloudprovider.GCP, ""))
+
+	schema, err := fileHandler.Read("schema/constellation-conf.schema.json")
+	require.NoError(err)
+	rawConfig, err := fileHandler.Read(constants.ConfigFilename)
+	require.NoError(err)
+
+	// The generated config has to be valid according to the generated schema.
+	result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(schema), gojsonschema.NewBytesLoader(rawConfig))
+	require.NoError(err)
+	assert.True(result.Valid(), result.Errors())
+}
+
+func TestParseFormatFlag(t *testing.T) {
+	testCases := map[string]struct {
+		formatFlag string
+		wantFormat configFormat
+		wantErr    bool
+	}{
+		"default": {
+			wantFormat: configFormatYAML,
+		},
+		"yaml": {
+			formatFlag: "yaml",
+			wantFormat: configFormatYAML,
+		},
+		"json": {
+			formatFlag: "JSON",
+			wantFormat: configFormatJSON,
+		},
+		"invalid": {
+			formatFlag: "toml",
+			wantErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			cmd := newConfigGenerateCmd()
+			cmd.Flags().String("workspace", "", "")
+			cmd.Flags().String("tf-log", "NONE", "")
+			cmd.Flags().String("profile", "", "")
+			cmd.Flags().String("config", "", "")
+			cmd.Flags().String("kubeconfig", "", "")
+			cmd.Flags().Bool("debug", false, "")
+			cmd.Flags().Bool("force", false, "")
+			if tc.formatFlag != "" {
+				require.NoError(cmd.Flags().Set("format", tc.formatFlag))
+			}
+
+			var flags generateFlags
+			err := flags.parse(cmd.Flags())
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tc.wantFormat, flags.format)
+		})
+	}
+}
+
 func TestNoValidProviderAttestationCombination(t *testing.T) {
 	assert := assert.New(t)
 	tests := []struct {
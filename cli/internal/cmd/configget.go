@@ -0,0 +1,57 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func newConfigGetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "get KEY",
+		Short: "Print a single field of the configuration file",
+		Long: "Print a single field of the configuration file.\n\n" +
+			"KEY is the dotted path of the field, e.g. provider.aws.region. Fields that aren't scalar values are printed as YAML.",
+		Example: "  constellation config get provider.aws.region",
+		Args:    cobra.ExactArgs(1),
+		RunE:    runConfigGet,
+	}
+	return cmd
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	return configGet(cmd, file.NewHandler(afero.NewOsFs()), args[0])
+}
+
+func configGet(cmd *cobra.Command, fileHandler file.Handler, key string) error {
+	path, err := parseConfigKey(key)
+	if err != nil {
+		return err
+	}
+	var conf config.Config
+	if err := fileHandler.ReadYAML(constants.ConfigFilename, &conf); err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	field, err := lookupConfigField(&conf, path)
+	if err != nil {
+		return err
+	}
+
+	out, err := yaml.Marshal(field.Interface())
+	if err != nil {
+		return fmt.Errorf("marshalling %q: %w", key, err)
+	}
+	cmd.Print(string(out))
+	return nil
+}
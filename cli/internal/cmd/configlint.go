@@ -0,0 +1,67 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/edgelesssys/constellation/v2/internal/api/attestationconfigapi"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+func newConfigLintCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Check the configuration file for best practices",
+		Long: "Check the configuration file for best practices.\n\n" +
+			"Advisories are tagged with their severity, but never make the configuration invalid. " +
+			"Use 'constellation config validate' to check that the configuration is valid.",
+		Args: cobra.ExactArgs(0),
+		RunE: runConfigLint,
+	}
+	return cmd
+}
+
+type configLintCmd struct {
+	fileHandler file.Handler
+	flags       rootFlags
+	log         debugLog
+}
+
+func runConfigLint(cmd *cobra.Command, _ []string) error {
+	log, err := newCLILogger(cmd)
+	if err != nil {
+		return fmt.Errorf("creating logger: %w", err)
+	}
+	c := &configLintCmd{
+		fileHandler: file.NewHandler(afero.NewOsFs()),
+		log:         log,
+	}
+	if err := c.flags.parse(cmd.Flags()); err != nil {
+		return err
+	}
+	return c.lint(cmd, attestationconfigapi.NewFetcher())
+}
+
+func (c *configLintCmd) lint(cmd *cobra.Command, fetcher attestationconfigapi.Fetcher) error {
+	configPath := c.flags.pathPrefixer.PrefixPrintablePath(constants.ConfigFilename)
+	c.log.Debug("Linting config", "path", configPath)
+	result, err := config.LintFile(c.fileHandler, constants.ConfigFilename, c.flags.profile, fetcher, c.flags.force)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	for _, finding := range result.Findings {
+		cmd.Println(finding.String())
+	}
+	cmd.Printf("%s: %d warnings, %d infos\n", configPath, result.Count(config.SeverityWarning), result.Count(config.SeverityInfo))
+	return nil
+}
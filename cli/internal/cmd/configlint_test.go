@@ -0,0 +1,93 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigLint(t *testing.T) {
+	testCases := map[string]struct {
+		modifyConfig func(*config.Config)
+		noConfig     bool
+		wantOut      string
+		wantErr      bool
+	}{
+		"no advisories": {
+			modifyConfig: func(*config.Config) {},
+			wantOut:      "constellation-conf.yaml: 0 warnings, 0 infos\n",
+		},
+		"several advisories": {
+			modifyConfig: func(c *config.Config) {
+				debug := true
+				c.DebugCluster = &debug
+				c.Name = "prod"
+				group := c.NodeGroups[constants.DefaultControlPlaneGroupName]
+				group.InitialCount = 1
+				c.NodeGroups[constants.DefaultControlPlaneGroupName] = group
+			},
+			wantOut: "warning: debugCluster: debug clusters aren't secure and must not be used in production\n" +
+				"warning: nodeGroups: 1 control-plane nodes aren't highly available, use at least 3 so the cluster tolerates the failure of a node\n" +
+				"warning: debugCluster: debugging is enabled in a config that looks like it's meant for production\n" +
+				"constellation-conf.yaml: 3 warnings, 0 infos\n",
+		},
+		"validation errors aren't reported": {
+			modifyConfig: func(c *config.Config) {
+				group := c.NodeGroups[constants.DefaultWorkerGroupName]
+				group.InitialCount = -1
+				c.NodeGroups[constants.DefaultWorkerGroupName] = group
+			},
+			wantOut: "constellation-conf.yaml: 0 warnings, 0 infos\n",
+		},
+		"missing config file": {
+			noConfig: true,
+			wantErr:  true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			if !tc.noConfig {
+				conf := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.GCP)
+				tc.modifyConfig(conf)
+				require.NoError(fileHandler.WriteYAML(constants.ConfigFilename, conf))
+			}
+
+			cmd := NewConfigCmd()
+			out := &bytes.Buffer{}
+			cmd.SetOut(out)
+			cmd.SetErr(&bytes.Buffer{})
+			cmd.SetContext(context.Background())
+			c := &configLintCmd{
+				fileHandler: fileHandler,
+				log:         logger.NewTest(t),
+			}
+			err := c.lint(cmd, stubAttestationFetcher{})
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantOut, out.String())
+		})
+	}
+}
@@ -0,0 +1,288 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/edgelesssys/constellation/v2/internal/api/attestationconfigapi"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func newConfigSetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set KEY=VALUE",
+		Short: "Set a single field of the configuration file",
+		Long: "Set a single field of the configuration file.\n\n" +
+			"KEY is the dotted path of the field, e.g. provider.aws.region or nodeGroups.worker_default.initialCount.\n" +
+			"VALUE is converted to the type of the field. The changed configuration is validated before it is written.",
+		Example: "  constellation config set provider.aws.region=eu-west-1\n" +
+			"  constellation config set nodeGroups.worker_default.initialCount=3",
+		Args: cobra.ExactArgs(1),
+		RunE: runConfigSet,
+	}
+	return cmd
+}
+
+type configSetCmd struct {
+	fileHandler file.Handler
+	flags       rootFlags
+	log         debugLog
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	log, err := newCLILogger(cmd)
+	if err != nil {
+		return fmt.Errorf("creating logger: %w", err)
+	}
+	c := &configSetCmd{
+		fileHandler: file.NewHandler(afero.NewOsFs()),
+		log:         log,
+	}
+	if err := c.flags.parse(cmd.Flags()); err != nil {
+		return err
+	}
+	if err := c.flags.requireConfigFile(); err != nil {
+		return err
+	}
+	return c.set(cmd, args[0], attestationconfigapi.NewFetcher())
+}
+
+func (c *configSetCmd) set(cmd *cobra.Command, assignment string, fetcher attestationconfigapi.Fetcher) error {
+	key, value, ok := strings.Cut(assignment, "=")
+	if !ok {
+		return fmt.Errorf("invalid argument %q, must be of the form KEY=VALUE", assignment)
+	}
+	path, err := parseConfigKey(key)
+	if err != nil {
+		return err
+	}
+	field, err := lookupConfigField(&config.Config{}, path)
+	if err != nil {
+		return err
+	}
+	valueNode, err := coerceConfigValue(field.Type(), value)
+	if err != nil {
+		return fmt.Errorf("setting %q: %w", key, err)
+	}
+
+	raw, err := c.fileHandler.Read(constants.ConfigFilename)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	// The config is edited as YAML node tree, so comments and field order are preserved.
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return fmt.Errorf("parsing config file: %w", err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 {
+		return errors.New("parsing config file: config file is empty")
+	}
+	if err := setYAMLNode(doc.Content[0], path, valueNode); err != nil {
+		return fmt.Errorf("setting %q: %w", key, err)
+	}
+	updated, err := yaml.Marshal(&doc)
+	if err != nil {
+		return fmt.Errorf("marshalling config: %w", err)
+	}
+
+	c.log.Debug("Validating updated config")
+	validationFS := file.NewHandler(afero.NewMemMapFs())
+	if err := validationFS.Write(constants.ConfigFilename, updated); err != nil {
+		return err
+	}
+	if _, err := config.New(validationFS, constants.ConfigFilename, fetcher, c.flags.force); err != nil {
+		var configValidationErr *config.ValidationError
+		if errors.As(err, &configValidationErr) {
+			cmd.PrintErrln(configValidationErr.LongMessage())
+		}
+		return fmt.Errorf("validating config with %q set to %q: %w", key, value, err)
+	}
+
+	if err := c.fileHandler.Write(constants.ConfigFilename, updated, file.OptOverwrite); err != nil {
+		return fmt.Errorf("writing config file: %w", err)
+	}
+	cmd.Printf("Set %s to %s in %s\n", key, value, c.flags.pathPrefixer.PrefixPrintablePath(constants.ConfigFilename))
+	return nil
+}
+
+// parseConfigKey splits a dotted config key into its path elements.
+func parseConfigKey(key string) ([]string, error) {
+	path := strings.Split(key, ".")
+	for _, element := range path {
+		if element == "" {
+			return nil, fmt.Errorf("invalid config key %q", key)
+		}
+	}
+	return path, nil
+}
+
+// lookupConfigField returns the field of conf at the given path of YAML keys.
+// Unset pointers and map entries along the path are returned as zero values of their type.
+func lookupConfigField(conf *config.Config, path []string) (reflect.Value, error) {
+	v := reflect.ValueOf(conf).Elem()
+	for i, element := range path {
+		for v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v = reflect.Zero(v.Type().Elem())
+			} else {
+				v = v.Elem()
+			}
+		}
+
+		switch v.Kind() {
+		case reflect.Struct:
+			field, ok := yamlFieldByName(v.Type(), element)
+			if !ok {
+				return reflect.Value{}, fmt.Errorf("unknown config key %q", strings.Join(path[:i+1], "."))
+			}
+			v = v.FieldByIndex(field.Index)
+		case reflect.Map:
+			mapKey, err := coerceMapKey(v.Type().Key(), element)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("invalid config key %q: %w", strings.Join(path[:i+1], "."), err)
+			}
+			entry := v.MapIndex(mapKey)
+			if !entry.IsValid() {
+				entry = reflect.Zero(v.Type().Elem())
+			}
+			v = entry
+		default:
+			return reflect.Value{}, fmt.Errorf("config key %q has no field %q", strings.Join(path[:i], "."), element)
+		}
+	}
+	return v, nil
+}
+
+// yamlFieldByName returns the field of the struct type t with the given YAML name.
+func yamlFieldByName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		yamlName, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if yamlName == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func coerceMapKey(t reflect.Type, key string) (reflect.Value, error) {
+	switch t.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(key).Convert(t), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(key, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("expected an integer key: %w", err)
+		}
+		return reflect.ValueOf(i).Convert(t), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(key, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("expected a non-negative integer key: %w", err)
+		}
+		return reflect.ValueOf(u).Convert(t), nil
+	default:
+		return reflect.Value{}, fmt.Errorf("unsupported map key type %s", t)
+	}
+}
+
+// coerceConfigValue converts value to a YAML node matching the type t of a config field.
+// Values of types with custom YAML unmarshalling and of non-scalar types are parsed as YAML.
+func coerceConfigValue(t reflect.Type, value string) (*yaml.Node, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()) {
+		return parseYAMLValue(value)
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}, nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("expected a boolean value, got %q", value)
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(b)}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, t.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("expected an integer value, got %q", value)
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.FormatInt(i, 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, t.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("expected a non-negative integer value, got %q", value)
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.FormatUint(u, 10)}, nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, t.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("expected a number value, got %q", value)
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: strconv.FormatFloat(f, 'g', -1, t.Bits())}, nil
+	default:
+		return parseYAMLValue(value)
+	}
+}
+
+func parseYAMLValue(value string) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &doc); err != nil {
+		return nil, fmt.Errorf("parsing value as YAML: %w", err)
+	}
+	if len(doc.Content) != 1 {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+	return doc.Content[0], nil
+}
+
+// setYAMLNode sets the value at path in the YAML mapping node to value.
+// Missing mappings along the path are created.
+func setYAMLNode(node *yaml.Node, path []string, value *yaml.Node) error {
+	for i, element := range path {
+		if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+			*node = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		if node.Kind != yaml.MappingNode {
+			return fmt.Errorf("%q is not a mapping", strings.Join(path[:i], "."))
+		}
+
+		var next *yaml.Node
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if node.Content[j].Value == element {
+				next = node.Content[j+1]
+				break
+			}
+		}
+		if next == nil {
+			next = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: element}, next)
+		}
+		node = next
+	}
+
+	// keep comments attached to the replaced value
+	value.HeadComment, value.LineComment, value.FootComment = node.HeadComment, node.LineComment, node.FootComment
+	*node = *value
+	return nil
+}
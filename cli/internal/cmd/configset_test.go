@@ -0,0 +1,182 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigSet(t *testing.T) {
+	testCases := map[string]struct {
+		assignment string
+		wantConfig func(*config.Config)
+		wantErr    bool
+	}{
+		"nested field": {
+			assignment: "provider.gcp.region=europe-west1",
+			wantConfig: func(c *config.Config) { c.Provider.GCP.Region = "europe-west1" },
+		},
+		"field in map": {
+			assignment: "nodeGroups.worker_default.instanceType=n2d-standard-16",
+			wantConfig: func(c *config.Config) {
+				group := c.NodeGroups[constants.DefaultWorkerGroupName]
+				group.InstanceType = "n2d-standard-16"
+				c.NodeGroups[constants.DefaultWorkerGroupName] = group
+			},
+		},
+		"integer value": {
+			assignment: "nodeGroups.worker_default.initialCount=5",
+			wantConfig: func(c *config.Config) {
+				group := c.NodeGroups[constants.DefaultWorkerGroupName]
+				group.InitialCount = 5
+				c.NodeGroups[constants.DefaultWorkerGroupName] = group
+			},
+		},
+		"boolean value": {
+			assignment: "debugCluster=true",
+			wantConfig: func(c *config.Config) { debug := true; c.DebugCluster = &debug },
+		},
+		"string value that looks like a number": {
+			assignment: "name=1234",
+			wantConfig: func(c *config.Config) { c.Name = "1234" },
+		},
+		"invalid integer": {
+			assignment: "nodeGroups.worker_default.initialCount=five",
+			wantErr:    true,
+		},
+		"invalid boolean": {
+			assignment: "debugCluster=maybe",
+			wantErr:    true,
+		},
+		"value rejected by validation": {
+			assignment: "nodeGroups.worker_default.initialCount=-1",
+			wantErr:    true,
+		},
+		"unknown key": {
+			assignment: "provider.gcp.unknown=foo",
+			wantErr:    true,
+		},
+		"key into scalar field": {
+			assignment: "name.first=foo",
+			wantErr:    true,
+		},
+		"missing value": {
+			assignment: "provider.gcp.region",
+			wantErr:    true,
+		},
+		"empty key element": {
+			assignment: "provider..region=europe-west1",
+			wantErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			conf := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.GCP)
+			require.NoError(fileHandler.WriteYAML(constants.ConfigFilename, conf))
+			before, err := fileHandler.Read(constants.ConfigFilename)
+			require.NoError(err)
+
+			cmd := NewConfigCmd()
+			cmd.SetOut(&bytes.Buffer{})
+			cmd.SetErr(&bytes.Buffer{})
+			c := &configSetCmd{
+				fileHandler: fileHandler,
+				log:         logger.NewTest(t),
+			}
+			err = c.set(cmd, tc.assignment, stubAttestationFetcher{})
+
+			after, readErr := fileHandler.Read(constants.ConfigFilename)
+			require.NoError(readErr)
+			if tc.wantErr {
+				assert.Error(err)
+				assert.Equal(before, after)
+				return
+			}
+			require.NoError(err)
+
+			var got config.Config
+			require.NoError(fileHandler.ReadYAMLStrict(constants.ConfigFilename, &got))
+			want := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.GCP)
+			tc.wantConfig(want)
+			assert.Equal(want, &got)
+			// the documentation comments of the config are preserved
+			assert.Equal(strings.Count(string(before), "#"), strings.Count(string(after), "#"))
+		})
+	}
+}
+
+func TestConfigGet(t *testing.T) {
+	testCases := map[string]struct {
+		key     string
+		wantOut string
+		wantErr bool
+	}{
+		"nested field": {
+			key:     "provider.gcp.zone",
+			wantOut: "test-zone\n",
+		},
+		"integer field in map": {
+			key:     "nodeGroups.control_plane_default.initialCount",
+			wantOut: "3\n",
+		},
+		"boolean field": {
+			key:     "debugCluster",
+			wantOut: "false\n",
+		},
+		"struct": {
+			key:     "nodeGroups.worker_default",
+			wantOut: "role: worker\nzone: europe-west3-b\ninstanceType: n2d-standard-4\nstateDiskSizeGB: 30\nstateDiskType: pd-ssd\ninitialCount: 1\n",
+		},
+		"unset provider": {
+			key:     "provider.aws.region",
+			wantOut: "\"\"\n",
+		},
+		"unknown key": {
+			key:     "provider.gcp.unknown",
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			conf := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.GCP)
+			require.NoError(fileHandler.WriteYAML(constants.ConfigFilename, conf))
+
+			cmd := NewConfigCmd()
+			out := &bytes.Buffer{}
+			cmd.SetOut(out)
+
+			err := configGet(cmd, fileHandler, tc.key)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tc.wantOut, out.String())
+		})
+	}
+}
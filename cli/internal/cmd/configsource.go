@@ -0,0 +1,138 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/edgelesssys/constellation/v2/internal/api/attestationconfigapi"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/kubernetes/kubectl"
+	"github.com/spf13/afero"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// clusterConfigKindConfigMap references a config stored in a ConfigMap.
+	clusterConfigKindConfigMap = "configmap"
+	// clusterConfigKindSecret references a config stored in a Secret.
+	clusterConfigKindSecret = "secret"
+)
+
+// errClusterConfigPhaseHooks is returned for phase hooks in a config loaded from the cluster.
+// Hooks run commands on the machine of the CLI, so they must only come from a local config file.
+var errClusterConfigPhaseHooks = errors.New("phaseHooks run commands on this machine and aren't allowed in a config loaded from the cluster: move them to a local config file")
+
+// clusterConfigRef references the key of a ConfigMap or Secret in the cluster the config is stored in.
+type clusterConfigRef struct {
+	kind      string
+	namespace string
+	name      string
+	key       string
+}
+
+// parseClusterConfigRef parses a reference of the form KIND://NAMESPACE/NAME/KEY,
+// where KIND is either "configmap" or "secret".
+func parseClusterConfigRef(ref string) (*clusterConfigRef, error) {
+	kind, path, ok := strings.Cut(ref, "://")
+	if !ok || (kind != clusterConfigKindConfigMap && kind != clusterConfigKindSecret) {
+		return nil, fmt.Errorf("invalid config reference %q: must start with %s:// or %s://", ref, clusterConfigKindConfigMap, clusterConfigKindSecret)
+	}
+	parts := strings.Split(path, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return nil, fmt.Errorf("invalid config reference %q: must be of the form %s://NAMESPACE/NAME/KEY", ref, kind)
+	}
+	return &clusterConfigRef{kind: kind, namespace: parts[0], name: parts[1], key: parts[2]}, nil
+}
+
+// String returns the reference in the form it was passed by the user.
+func (r *clusterConfigRef) String() string {
+	return fmt.Sprintf("%s://%s/%s/%s", r.kind, r.namespace, r.name, r.key)
+}
+
+// clusterConfigGetter reads the ConfigMaps and Secrets a config can be stored in.
+type clusterConfigGetter interface {
+	GetConfigMap(ctx context.Context, namespace, name string) (*corev1.ConfigMap, error)
+	GetSecret(ctx context.Context, namespace, name string) (*corev1.Secret, error)
+}
+
+// read returns the config stored under the referenced key.
+func (r *clusterConfigRef) read(ctx context.Context, client clusterConfigGetter) ([]byte, error) {
+	switch r.kind {
+	case clusterConfigKindConfigMap:
+		configMap, err := client.GetConfigMap(ctx, r.namespace, r.name)
+		if err != nil {
+			return nil, fmt.Errorf("getting ConfigMap %s/%s: %w", r.namespace, r.name, err)
+		}
+		if data, ok := configMap.Data[r.key]; ok {
+			return []byte(data), nil
+		}
+		if data, ok := configMap.BinaryData[r.key]; ok {
+			return data, nil
+		}
+		return nil, fmt.Errorf("no key %q in ConfigMap %s/%s", r.key, r.namespace, r.name)
+	case clusterConfigKindSecret:
+		secret, err := client.GetSecret(ctx, r.namespace, r.name)
+		if err != nil {
+			return nil, fmt.Errorf("getting Secret %s/%s: %w", r.namespace, r.name, err)
+		}
+		data, ok := secret.Data[r.key]
+		if !ok {
+			return nil, fmt.Errorf("no key %q in Secret %s/%s", r.key, r.namespace, r.name)
+		}
+		return data, nil
+	default:
+		return nil, fmt.Errorf("unknown config reference kind %q", r.kind)
+	}
+}
+
+// loadConfig loads and validates the config.
+// If a config reference is set, the config is read from the cluster using the kubeconfig of the workspace, or the one set with --kubeconfig.
+// Otherwise, the config file of the workspace is read, merged with the overlay of the profile.
+func (f *rootFlags) loadConfig(ctx context.Context, fileHandler file.Handler, fetcher attestationconfigapi.Fetcher) (*config.Config, error) {
+	if f.configRef == nil {
+		return config.NewWithProfile(fileHandler, constants.ConfigFilename, f.profile, fetcher, f.force)
+	}
+
+	kubeConfig, err := f.readKubeConfig(fileHandler)
+	if err != nil {
+		return nil, fmt.Errorf("reading kubeconfig to load config from %s: %w", f.configRef, err)
+	}
+	client, err := kubectl.NewFromConfig(kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("setting up kubernetes client: %w", err)
+	}
+	return loadClusterConfig(ctx, client, f.configRef, fetcher, f.force)
+}
+
+// loadClusterConfig reads the referenced config from the cluster and validates it like a config file.
+func loadClusterConfig(ctx context.Context, client clusterConfigGetter, ref *clusterConfigRef, fetcher attestationconfigapi.Fetcher, force bool) (*config.Config, error) {
+	data, err := ref.read(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("reading config from %s: %w", ref, err)
+	}
+
+	// The config is parsed from an in-memory file, so it is loaded and validated exactly like a config file.
+	configFS := file.NewHandler(afero.NewMemMapFs())
+	if err := configFS.Write(constants.ConfigFilename, data); err != nil {
+		return nil, err
+	}
+	conf, err := config.New(configFS, constants.ConfigFilename, fetcher, force)
+	if err != nil {
+		return conf, fmt.Errorf("loading config from %s: %w", ref, err)
+	}
+	// Anyone who can edit the ConfigMap or Secret could otherwise run commands on this machine.
+	if len(conf.PhaseHooks) > 0 {
+		return nil, fmt.Errorf("loading config from %s: %w", ref, errClusterConfigPhaseHooks)
+	}
+	return conf, nil
+}
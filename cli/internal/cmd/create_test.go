@@ -194,6 +194,18 @@ func TestCreate(t *testing.T) {
 			getCreatorErr: assert.AnError,
 			wantErr:       true,
 		},
+		"invalid credentials": {
+			setupFs: fsWithDefaultConfig,
+			creator: &stubCloudCreator{
+				state:                  infraState,
+				planDiff:               true,
+				validateCredentialsErr: assert.AnError,
+				workspaceIsEmpty:       true,
+			},
+			provider: cloudprovider.GCP,
+			yesFlag:  true,
+			wantErr:  true,
+		},
 		"plan error": {
 			setupFs: fsWithDefaultConfig,
 			creator: &stubCloudCreator{
@@ -245,9 +257,15 @@ func TestCreate(t *testing.T) {
 					assert.True(tc.creator.planCalled)
 					assert.False(tc.creator.applyCalled)
 				}
+				if tc.creator.validateCredentialsErr != nil {
+					assert.ErrorIs(err, tc.creator.validateCredentialsErr)
+					assert.False(tc.creator.planCalled)
+					assert.False(tc.creator.applyCalled)
+				}
 			} else {
 				assert.NoError(err)
 
+				assert.True(tc.creator.validateCredentialsCalled)
 				assert.True(tc.creator.planCalled)
 				assert.True(tc.creator.applyCalled)
 
@@ -258,7 +276,7 @@ func TestCreate(t *testing.T) {
 					InitSecret:        []byte{},
 				}
 				require.NoError(fileHandler.ReadYAML(constants.StateFilename, &gotState))
-				assert.Equal("v1", gotState.Version)
+				assert.Equal("v2", gotState.Version)
 				assert.Equal(expectedState, gotState.Infrastructure)
 
 			}
@@ -69,6 +69,9 @@ func (f *iamCreateFlags) parse(flags *pflag.FlagSet) error {
 	if err != nil {
 		return fmt.Errorf("getting 'update-config' flag: %w", err)
 	}
+	if f.updateConfig {
+		return f.requireConfigFile()
+	}
 	return nil
 }
 
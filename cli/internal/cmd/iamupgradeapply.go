@@ -106,7 +106,7 @@ func runIAMUpgradeApply(cmd *cobra.Command, _ []string) error {
 }
 
 func (i iamUpgradeApplyCmd) iamUpgradeApply(cmd *cobra.Command, iamUpgrader iamUpgrader, upgradeDir string) error {
-	conf, err := config.New(i.fileHandler, constants.ConfigFilename, i.configFetcher, i.flags.force)
+	conf, err := i.flags.loadConfig(cmd.Context(), i.fileHandler, i.configFetcher)
 	var configValidationErr *config.ValidationError
 	if errors.As(err, &configValidationErr) {
 		cmd.PrintErrln(configValidationErr.LongMessage())
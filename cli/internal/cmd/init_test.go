@@ -9,6 +9,8 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
@@ -16,6 +18,7 @@ import (
 
 	"github.com/edgelesssys/constellation/v2/bootstrapper/initproto"
 	"github.com/edgelesssys/constellation/v2/cli/internal/cmd/pathprefix"
+	"github.com/edgelesssys/constellation/v2/internal/attestation"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/measurements"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/gcpshared"
@@ -24,7 +27,9 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/constellation"
 	"github.com/edgelesssys/constellation/v2/internal/constellation/helm"
 	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+	"github.com/edgelesssys/constellation/v2/internal/crypto"
 	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/kms/kms/cluster"
 	"github.com/edgelesssys/constellation/v2/internal/kms/uri"
 	"github.com/edgelesssys/constellation/v2/internal/logger"
 	"github.com/edgelesssys/constellation/v2/internal/semver"
@@ -84,6 +89,7 @@ func TestInitialize(t *testing.T) {
 		ClusterID:  "clusterID",
 	}
 	serviceAccPath := "/test/service-account.json"
+	const debugAccessKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIHO6fX0WwmfN84U0OmpPNix0WMZySwwHKx5vcU21QqMj admin@example.com"
 
 	testCases := map[string]struct {
 		provider                cloudprovider.Provider
@@ -94,6 +100,10 @@ func TestInitialize(t *testing.T) {
 		initErr                 error
 		retriable               bool
 		masterSecretShouldExist bool
+		helmNamespace           string
+		wantDebugAccess         *config.DebugAccessConfig
+		wantRegistryMirrors     []config.RegistryMirror
+		wantErrOut              string
 		wantErr                 bool
 	}{
 		"initialize some gcp instances": {
@@ -108,6 +118,32 @@ func TestInitialize(t *testing.T) {
 			stateFile:  preInitStateFile(cloudprovider.Azure),
 			initOutput: testInitOutput,
 		},
+		"initialize with helm namespace": {
+			provider:      cloudprovider.Azure,
+			stateFile:     preInitStateFile(cloudprovider.Azure),
+			initOutput:    testInitOutput,
+			helmNamespace: "constellation-system",
+		},
+		"initialize with debug access": {
+			provider:  cloudprovider.Azure,
+			stateFile: preInitStateFile(cloudprovider.Azure),
+			configMutator: func(c *config.Config) {
+				c.DebugAccess = &config.DebugAccessConfig{Enabled: true, AuthorizedKeys: []string{debugAccessKey}}
+			},
+			initOutput:      testInitOutput,
+			wantDebugAccess: &config.DebugAccessConfig{Enabled: true, AuthorizedKeys: []string{debugAccessKey}},
+			wantErrOut:      "WARNING: Debug access is enabled.",
+		},
+		"initialize with registry mirrors": {
+			provider:  cloudprovider.Azure,
+			stateFile: preInitStateFile(cloudprovider.Azure),
+			configMutator: func(c *config.Config) {
+				c.RegistryMirrors = []config.RegistryMirror{{Registry: "docker.io", Endpoints: []string{"https://mirror.example.com"}}}
+			},
+			initOutput:          testInitOutput,
+			wantRegistryMirrors: []config.RegistryMirror{{Registry: "docker.io", Endpoints: []string{"https://mirror.example.com"}}},
+			wantErrOut:          "WARNING: Registry mirrors are configured.",
+		},
 		"initialize some qemu instances": {
 			provider:   cloudprovider.QEMU,
 			stateFile:  preInitStateFile(cloudprovider.QEMU),
@@ -129,6 +165,22 @@ func TestInitialize(t *testing.T) {
 			masterSecretShouldExist: true,
 			wantErr:                 true,
 		},
+		"attestation of first node fails": {
+			provider:                cloudprovider.QEMU,
+			stateFile:               preInitStateFile(cloudprovider.QEMU),
+			initErr:                 &constellation.InitAttestationError{Err: &constellation.NonRetriableInitError{Err: assert.AnError}},
+			masterSecretShouldExist: true,
+			wantErrOut:              "attestation of the first node couldn't be verified",
+			wantErr:                 true,
+		},
+		"first node unreachable": {
+			provider:                cloudprovider.QEMU,
+			stateFile:               preInitStateFile(cloudprovider.QEMU),
+			initErr:                 &constellation.InitUnreachableError{Endpoint: "192.0.2.1:9000", Err: assert.AnError},
+			masterSecretShouldExist: true,
+			wantErrOut:              "couldn't be reached at 192.0.2.1:9000",
+			wantErr:                 true,
+		},
 		"invalid state file": {
 			provider:      cloudprovider.GCP,
 			stateFile:     &state.State{Version: "invalid"},
@@ -222,29 +274,31 @@ func TestInitialize(t *testing.T) {
 			defer cancel()
 			cmd.SetContext(ctx)
 
+			applier := &stubConstellApplier{
+				masterSecret: uri.MasterSecret{
+					Key:  bytes.Repeat([]byte{0x01}, 32),
+					Salt: bytes.Repeat([]byte{0x02}, 32),
+				},
+				measurementSalt: bytes.Repeat([]byte{0x03}, 32),
+				initErr:         tc.initErr,
+				initOutput:      tc.initOutput,
+				stubKubernetesUpgrader: &stubKubernetesUpgrader{
+					// On init, no attestation config exists yet
+					getClusterAttestationConfigErr: k8serrors.NewNotFound(schema.GroupResource{}, ""),
+				},
+				helmApplier: &stubHelmApplier{},
+			}
 			i := &applyCmd{
 				fileHandler: fileHandler,
 				flags: applyFlags{
-					rootFlags:  rootFlags{force: true},
-					skipPhases: newPhases(skipInfrastructurePhase),
+					rootFlags:     rootFlags{force: true},
+					skipPhases:    newPhases(skipInfrastructurePhase),
+					helmNamespace: tc.helmNamespace,
 				},
 				log:     logger.NewTest(t),
 				spinner: &nopSpinner{},
 				merger:  &stubMerger{},
-				applier: &stubConstellApplier{
-					masterSecret: uri.MasterSecret{
-						Key:  bytes.Repeat([]byte{0x01}, 32),
-						Salt: bytes.Repeat([]byte{0x02}, 32),
-					},
-					measurementSalt: bytes.Repeat([]byte{0x03}, 32),
-					initErr:         tc.initErr,
-					initOutput:      tc.initOutput,
-					stubKubernetesUpgrader: &stubKubernetesUpgrader{
-						// On init, no attestation config exists yet
-						getClusterAttestationConfigErr: k8serrors.NewNotFound(schema.GroupResource{}, ""),
-					},
-					helmApplier: &stubHelmApplier{},
-				},
+				applier: applier,
 			}
 
 			err := i.apply(cmd, stubAttestationFetcher{}, "test")
@@ -252,7 +306,10 @@ func TestInitialize(t *testing.T) {
 			if tc.wantErr {
 				assert.Error(err)
 				fmt.Println(err)
-				if !tc.retriable {
+				if tc.wantErrOut != "" {
+					assert.Contains(errOut.String(), tc.wantErrOut)
+					assert.NotContains(errOut.String(), "This error is not recoverable")
+				} else if !tc.retriable {
 					assert.Contains(errOut.String(), "This error is not recoverable")
 				} else {
 					assert.Empty(errOut.String())
@@ -270,6 +327,14 @@ func TestInitialize(t *testing.T) {
 			assert.NoError(fileHandler.ReadJSON(constants.MasterSecretFilename, &secret))
 			assert.NotEmpty(secret.Key)
 			assert.NotEmpty(secret.Salt)
+			stateFile, err := state.ReadFromFile(fileHandler, constants.StateFilename)
+			require.NoError(err)
+			assert.Equal(tc.helmNamespace, stateFile.ClusterValues.HelmNamespace)
+			assert.Equal(tc.wantDebugAccess, applier.gotInitPayload.DebugAccess)
+			assert.Equal(tc.wantRegistryMirrors, applier.gotInitPayload.RegistryMirrors)
+			if tc.wantErrOut != "" {
+				assert.Contains(errOut.String(), tc.wantErrOut)
+			}
 		})
 	}
 }
@@ -305,6 +370,10 @@ func (s stubRunner) SaveCharts(_ string, _ file.Handler) error {
 	return s.saveChartsErr
 }
 
+func (s stubRunner) Images() ([]string, error) {
+	return nil, nil
+}
+
 func TestWriteOutput(t *testing.T) {
 	assert := assert.New(t)
 	require := require.New(t)
@@ -351,7 +420,7 @@ func TestWriteOutput(t *testing.T) {
 	measurementSalt := []byte{0x41}
 
 	expectedStateFile := &state.State{
-		Version: state.Version1,
+		Version: state.Version2,
 		ClusterValues: state.ClusterValues{
 			ClusterID:       clusterID,
 			OwnerID:         ownerID,
@@ -471,7 +540,7 @@ func TestGenerateMasterSecret(t *testing.T) {
 				log:         logger.NewTest(t),
 				applier:     constellation.NewApplier(logger.NewTest(t), &nopSpinner{}, constellation.ApplyContextCLI, nil),
 			}
-			secret, err := i.generateAndPersistMasterSecret(&out)
+			secret, err := i.generateAndPersistMasterSecret(context.Background(), &out, nil)
 
 			if tc.wantErr {
 				assert.Error(err)
@@ -560,3 +629,127 @@ func defaultConfigWithExpectedMeasurements(t *testing.T, conf *config.Config, cs
 
 	return conf
 }
+
+func TestImportMasterSecret(t *testing.T) {
+	importedSecret := uri.MasterSecret{
+		Key:  bytes.Repeat([]byte{0x04}, 32),
+		Salt: bytes.Repeat([]byte{0x05}, 32),
+	}
+	measurementSalt := bytes.Repeat([]byte{0x03}, 32)
+
+	// Derive the cluster ID the way the bootstrapper does it from the imported master secret.
+	clusterKMS, err := cluster.New(importedSecret.Key, importedSecret.Salt)
+	require.NoError(t, err)
+	measurementSecret, err := clusterKMS.GetDEK(context.Background(), crypto.DEKPrefix+crypto.MeasurementSecretKeyID, crypto.DerivedKeyLengthDefault)
+	require.NoError(t, err)
+	clusterID, err := attestation.DeriveClusterID(measurementSecret, measurementSalt)
+	require.NoError(t, err)
+	derivedClusterID := hex.EncodeToString(clusterID)
+
+	testCases := map[string]struct {
+		secretFile     any
+		clusterID      string
+		wantWarning    bool
+		wantErr        bool
+		wantSecretFile bool
+	}{
+		"derived cluster ID matches": {
+			secretFile:     importedSecret,
+			clusterID:      derivedClusterID,
+			wantSecretFile: true,
+		},
+		"cluster ID doesn't match": {
+			secretFile:     importedSecret,
+			clusterID:      hex.EncodeToString(bytes.Repeat([]byte{0x06}, 32)),
+			wantWarning:    true,
+			wantSecretFile: true,
+		},
+		"key too short": {
+			secretFile: uri.MasterSecret{Key: bytes.Repeat([]byte{0x04}, 8), Salt: importedSecret.Salt},
+			wantErr:    true,
+		},
+		"salt with wrong length": {
+			secretFile: uri.MasterSecret{Key: importedSecret.Key, Salt: bytes.Repeat([]byte{0x05}, 16)},
+			wantErr:    true,
+		},
+		"malformed file": {
+			secretFile: map[string]string{"key": "not base64", "salt": "not base64"},
+			wantErr:    true,
+		},
+		"missing file": {
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			cmd := NewInitCmd()
+			var out bytes.Buffer
+			cmd.SetOut(&out)
+			var errOut bytes.Buffer
+			cmd.SetErr(&errOut)
+			cmd.SetContext(context.Background())
+
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			conf := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.QEMU)
+			require.NoError(fileHandler.WriteYAML(constants.ConfigFilename, conf, file.OptNone))
+			require.NoError(preInitStateFile(cloudprovider.QEMU).WriteToFile(fileHandler, constants.StateFilename))
+			if tc.secretFile != nil {
+				require.NoError(fileHandler.WriteJSON("imported-secret.json", tc.secretFile, file.OptNone))
+			}
+
+			a := &applyCmd{
+				fileHandler: fileHandler,
+				flags: applyFlags{
+					rootFlags:      rootFlags{force: true},
+					yes:            true,
+					skipPhases:     newPhases(skipInfrastructurePhase),
+					masterSecretIn: "imported-secret.json",
+				},
+				log:     logger.NewTest(t),
+				spinner: &nopSpinner{},
+				merger:  &stubMerger{},
+				applier: &stubConstellApplier{
+					measurementSalt:         measurementSalt,
+					generateMasterSecretErr: errors.New("master secret must not be generated"),
+					initOutput: constellation.InitOutput{
+						Kubeconfig: func() []byte {
+							kubeconfig, err := clientcmd.Write(k8sclientapi.Config{
+								Clusters: map[string]*k8sclientapi.Cluster{"cluster": {Server: "https://192.0.2.1:6443"}},
+							})
+							require.NoError(err)
+							return kubeconfig
+						}(),
+						ClusterID: tc.clusterID,
+					},
+					stubKubernetesUpgrader: &stubKubernetesUpgrader{
+						getClusterAttestationConfigErr: k8serrors.NewNotFound(schema.GroupResource{}, ""),
+					},
+					helmApplier: &stubHelmApplier{},
+				},
+			}
+
+			err := a.apply(cmd, stubAttestationFetcher{}, "test")
+			_, statErr := fileHandler.Stat(constants.MasterSecretFilename)
+			assert.Equal(tc.wantSecretFile, statErr == nil)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			var secret uri.MasterSecret
+			require.NoError(fileHandler.ReadJSON(constants.MasterSecretFilename, &secret))
+			assert.Equal(importedSecret, secret)
+			stateFile, err := state.ReadFromFile(fileHandler, constants.StateFilename)
+			require.NoError(err)
+			assert.Equal(tc.clusterID, stateFile.ClusterValues.ClusterID)
+			assert.Equal(measurementSalt, []byte(stateFile.ClusterValues.MeasurementSalt))
+			assert.Contains(errOut.String(), "WARNING: The cluster will be initialized with the master secret")
+			assert.Equal(tc.wantWarning, strings.Contains(errOut.String(), "doesn't match the ID"))
+		})
+	}
+}
@@ -0,0 +1,45 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// kubeConfigPath returns the path of the kubeconfig used to connect to the cluster.
+func (f *rootFlags) kubeConfigPath() string {
+	if f.kubeConfig != "" {
+		return f.kubeConfig
+	}
+	return constants.AdminConfFilename
+}
+
+// readKubeConfig reads the kubeconfig used to connect to the cluster.
+// A kubeconfig set with --kubeconfig is preferred over the admin kubeconfig of the workspace,
+// and must parse and contain at least one cluster.
+func (f *rootFlags) readKubeConfig(fileHandler file.Handler) ([]byte, error) {
+	if f.kubeConfig == "" {
+		return fileHandler.Read(constants.AdminConfFilename)
+	}
+
+	kubeConfig, err := fileHandler.Read(f.kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("reading kubeconfig %q: %w", f.pathPrefixer.PrefixPrintablePath(f.kubeConfig), err)
+	}
+	clientConfig, err := clientcmd.Load(kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("parsing kubeconfig %q: %w", f.pathPrefixer.PrefixPrintablePath(f.kubeConfig), err)
+	}
+	if len(clientConfig.Clusters) == 0 {
+		return nil, fmt.Errorf("kubeconfig %q doesn't define any cluster", f.pathPrefixer.PrefixPrintablePath(f.kubeConfig))
+	}
+	return kubeConfig, nil
+}
@@ -0,0 +1,90 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKubeConfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: constell
+  cluster:
+    server: https://192.0.2.1:6443
+`
+
+func TestReadKubeConfig(t *testing.T) {
+	testCases := map[string]struct {
+		files          map[string]string
+		kubeConfig     string
+		wantKubeConfig string
+		wantPath       string
+		wantErr        bool
+	}{
+		"workspace kubeconfig": {
+			files:          map[string]string{constants.AdminConfFilename: "admin"},
+			wantKubeConfig: "admin",
+			wantPath:       constants.AdminConfFilename,
+		},
+		"override is preferred": {
+			files: map[string]string{
+				constants.AdminConfFilename: "admin",
+				"other/kubeconfig":          testKubeConfig,
+			},
+			kubeConfig:     "other/kubeconfig",
+			wantKubeConfig: testKubeConfig,
+			wantPath:       "other/kubeconfig",
+		},
+		"override missing": {
+			files:      map[string]string{constants.AdminConfFilename: "admin"},
+			kubeConfig: "other/kubeconfig",
+			wantErr:    true,
+		},
+		"override doesn't parse": {
+			files:      map[string]string{"other/kubeconfig": "clusters: ["},
+			kubeConfig: "other/kubeconfig",
+			wantErr:    true,
+		},
+		"override without clusters": {
+			files:      map[string]string{"other/kubeconfig": "apiVersion: v1\nkind: Config\n"},
+			kubeConfig: "other/kubeconfig",
+			wantErr:    true,
+		},
+		"workspace kubeconfig missing": {
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			for path, content := range tc.files {
+				require.NoError(fileHandler.Write(path, []byte(content), file.OptMkdirAll))
+			}
+			flags := rootFlags{kubeConfig: tc.kubeConfig}
+
+			kubeConfig, err := flags.readKubeConfig(fileHandler)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantKubeConfig, string(kubeConfig))
+			assert.Equal(tc.wantPath, flags.kubeConfigPath())
+		})
+	}
+}
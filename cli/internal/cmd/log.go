@@ -8,6 +8,7 @@ package cmd
 
 import (
 	"log/slog"
+	"os"
 
 	"github.com/edgelesssys/constellation/v2/internal/logger"
 	"github.com/spf13/cobra"
@@ -27,5 +28,14 @@ func newCLILogger(cmd *cobra.Command) (debugLog, error) {
 		logLvl = slog.LevelDebug
 	}
 
-	return logger.NewTextLogger(logLvl), nil
+	// The log-format flag is a persistent flag of the root command, so it's missing if a command is run on its own.
+	logFormat := logger.FormatText
+	if flag := cmd.Flags().Lookup("log-format"); flag != nil {
+		logFormat, err = logger.ParseFormat(flag.Value.String())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return logger.New(os.Stderr, logFormat, logLvl), nil
 }
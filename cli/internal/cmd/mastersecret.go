@@ -0,0 +1,208 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/edgelesssys/constellation/v2/internal/attestation"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+	"github.com/edgelesssys/constellation/v2/internal/crypto"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/kms/kms"
+	"github.com/edgelesssys/constellation/v2/internal/kms/kms/azure"
+	"github.com/edgelesssys/constellation/v2/internal/kms/uri"
+	"github.com/spf13/cobra"
+)
+
+// masterKeyBackend is a KMS backend the master secret can be encrypted with.
+type masterKeyBackend interface {
+	kms.KMSBackend
+	// ValidatePermissions checks that the backend's key can be used for encryption and decryption.
+	ValidatePermissions(ctx context.Context) error
+}
+
+// newMasterKeyBackendFunc creates a master key backend for the given key of an Azure Managed HSM.
+type newMasterKeyBackendFunc func(hsmURI, keyName string) (masterKeyBackend, error)
+
+// newManagedHSMBackend creates a master key backend for an Azure Managed HSM,
+// authenticating with the default Azure credentials of the user.
+func newManagedHSMBackend(hsmURI, keyName string) (masterKeyBackend, error) {
+	credential, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("loading Azure credentials: %w", err)
+	}
+	return azure.NewManagedHSM(hsmURI, keyName, credential, nil)
+}
+
+// encryptedMasterSecret is the content of the master secret file, if the master secret is encrypted by a KMS backend.
+type encryptedMasterSecret struct {
+	// Ciphertext is the JSON encoded master secret, encrypted by the KMS backend.
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// masterKeyHSM returns the Managed HSM URI and key name configured for the master secret.
+// Both are empty if the master secret should not be encrypted.
+func masterKeyHSM(conf *config.Config) (hsmURI, keyName string) {
+	if conf.Provider.Azure == nil {
+		return "", ""
+	}
+	return conf.Provider.Azure.MasterKeyHSMURI, conf.Provider.Azure.MasterKeyName
+}
+
+// recordedMasterKeyHSM returns the Managed HSM URI and key name the master secret of the cluster is encrypted with,
+// as recorded in the infrastructure state during initialization.
+// Both are empty if the master secret is stored unencrypted.
+func recordedMasterKeyHSM(infra state.Infrastructure) (hsmURI, keyName string) {
+	if infra.Azure == nil {
+		return "", ""
+	}
+	return infra.Azure.MasterKeyHSMURI, infra.Azure.MasterKeyName
+}
+
+// recordMasterKeyHSM records the Managed HSM URI and key name the master secret is encrypted with in the infrastructure state.
+func recordMasterKeyHSM(infra *state.Infrastructure, hsmURI, keyName string) {
+	if infra.Azure == nil {
+		infra.Azure = &state.Azure{}
+	}
+	infra.Azure.MasterKeyHSMURI = hsmURI
+	infra.Azure.MasterKeyName = keyName
+}
+
+// writeMasterSecret writes the master secret to the master secret file.
+// If backend is not nil, the master secret is encrypted with it.
+func writeMasterSecret(ctx context.Context, fileHandler file.Handler, backend kms.KMSBackend, secret uri.MasterSecret) error {
+	if backend == nil {
+		return fileHandler.WriteJSON(constants.MasterSecretFilename, secret, file.OptNone)
+	}
+
+	plaintext, err := json.Marshal(secret)
+	if err != nil {
+		return fmt.Errorf("marshalling master secret: %w", err)
+	}
+	ciphertext, err := backend.Encrypt(ctx, plaintext)
+	if err != nil {
+		return fmt.Errorf("encrypting master secret: %w", err)
+	}
+	return fileHandler.WriteJSON(constants.MasterSecretFilename, encryptedMasterSecret{Ciphertext: ciphertext}, file.OptNone)
+}
+
+// readMasterSecret reads the master secret from the master secret file.
+// If the infrastructure state records a Managed HSM, the master secret is decrypted with its key.
+func readMasterSecret(
+	ctx context.Context, fileHandler file.Handler, newBackend newMasterKeyBackendFunc, infra state.Infrastructure,
+) (uri.MasterSecret, error) {
+	var secret uri.MasterSecret
+	hsmURI, keyName := recordedMasterKeyHSM(infra)
+	if hsmURI == "" {
+		if err := fileHandler.ReadJSON(constants.MasterSecretFilename, &secret); err != nil {
+			return uri.MasterSecret{}, err
+		}
+		return secret, nil
+	}
+
+	var encrypted encryptedMasterSecret
+	if err := fileHandler.ReadJSON(constants.MasterSecretFilename, &encrypted); err != nil {
+		return uri.MasterSecret{}, err
+	}
+	backend, err := newBackend(hsmURI, keyName)
+	if err != nil {
+		return uri.MasterSecret{}, fmt.Errorf("creating Managed HSM backend: %w", err)
+	}
+	plaintext, err := backend.Decrypt(ctx, encrypted.Ciphertext)
+	if err != nil {
+		return uri.MasterSecret{}, fmt.Errorf("decrypting master secret: %w", err)
+	}
+	if err := json.Unmarshal(plaintext, &secret); err != nil {
+		return uri.MasterSecret{}, fmt.Errorf("unmarshalling master secret: %w", err)
+	}
+	return secret, nil
+}
+
+// readImportedMasterSecret reads a master secret to import from the given file.
+// The file must have the format of an unencrypted master secret file written by the CLI.
+func readImportedMasterSecret(fileHandler file.Handler, path string) (uri.MasterSecret, error) {
+	var secret uri.MasterSecret
+	if err := fileHandler.ReadJSON(path, &secret); err != nil {
+		return uri.MasterSecret{}, fmt.Errorf("reading master secret from %q: %w", path, err)
+	}
+	if len(secret.Key) < crypto.MasterSecretLengthMin {
+		return uri.MasterSecret{}, fmt.Errorf("master secret key from %q must be at least %d bytes long, got %d bytes", path, crypto.MasterSecretLengthMin, len(secret.Key))
+	}
+	if len(secret.Salt) != crypto.RNGLengthDefault {
+		return uri.MasterSecret{}, fmt.Errorf("master secret salt from %q must be %d bytes long, got %d bytes", path, crypto.RNGLengthDefault, len(secret.Salt))
+	}
+	return secret, nil
+}
+
+// deriveClusterID derives the hex encoded ID of a cluster initialized with the given master secret and measurement salt.
+// The bootstrapper derives the ID the same way.
+func deriveClusterID(secret uri.MasterSecret, measurementSalt []byte) (string, error) {
+	measurementSecret, err := crypto.DeriveKey(secret.Key, secret.Salt, []byte(crypto.DEKPrefix+crypto.MeasurementSecretKeyID), crypto.DerivedKeyLengthDefault)
+	if err != nil {
+		return "", fmt.Errorf("deriving measurement secret: %w", err)
+	}
+	clusterID, err := attestation.DeriveClusterID(measurementSecret, measurementSalt)
+	if err != nil {
+		return "", fmt.Errorf("deriving cluster ID: %w", err)
+	}
+	return hex.EncodeToString(clusterID), nil
+}
+
+// confirmMasterSecretImport checks the master secret to import and asks the user to confirm the import.
+func (a *applyCmd) confirmMasterSecretImport(cmd *cobra.Command) error {
+	if _, err := readImportedMasterSecret(a.fileHandler, a.flags.masterSecretIn); err != nil {
+		return err
+	}
+	cmd.PrintErrf("WARNING: The cluster will be initialized with the master secret from %q instead of a newly generated one.\n", a.flags.masterSecretIn)
+	cmd.PrintErrln("Anyone with access to this master secret can decrypt the state of the cluster.")
+	cmd.PrintErrln("Only import master secrets that were generated securely and aren't used by another cluster.")
+	if a.flags.yes {
+		return nil
+	}
+	ok, err := askToConfirm(cmd, "Do you want to import the master secret?")
+	if err != nil {
+		return fmt.Errorf("asking for confirmation: %w", err)
+	}
+	if !ok {
+		return errors.New("aborted by user")
+	}
+	return nil
+}
+
+// validateMasterKeyHSM checks that the Managed HSM configured for the master secret can be used.
+// The Managed HSM can't be changed after the cluster was initialized, since the existing master secret is encrypted with it.
+func (a *applyCmd) validateMasterKeyHSM(ctx context.Context, conf *config.Config, stateFile *state.State) error {
+	hsmURI, keyName := masterKeyHSM(conf)
+	recordedHSMURI, recordedKeyName := recordedMasterKeyHSM(stateFile.Infrastructure)
+	if stateFile.ClusterValues.ClusterID != "" && (hsmURI != recordedHSMURI || keyName != recordedKeyName) {
+		return fmt.Errorf(
+			"master key HSM %q with key %q doesn't match HSM %q with key %q the cluster was initialized with",
+			hsmURI, keyName, recordedHSMURI, recordedKeyName,
+		)
+	}
+	if hsmURI == "" {
+		return nil
+	}
+
+	a.log.Debug("Validating access to master key HSM", "uri", hsmURI, "key", keyName)
+	backend, err := a.newMasterKeyBackend(hsmURI, keyName)
+	if err != nil {
+		return fmt.Errorf("creating Managed HSM backend: %w", err)
+	}
+	if err := backend.ValidatePermissions(ctx); err != nil {
+		return fmt.Errorf("validating access to Managed HSM %q: %w", hsmURI, err)
+	}
+	return nil
+}
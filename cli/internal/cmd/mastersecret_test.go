@@ -0,0 +1,246 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/kms/uri"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testHSMURI = "https://test-hsm.managedhsm.azure.net/"
+
+func TestWriteReadMasterSecret(t *testing.T) {
+	someErr := errors.New("failed")
+	secret := uri.MasterSecret{Key: []byte("constellation-master-secret"), Salt: []byte("constellation-32Byte-length-salt")}
+	hsmInfra := state.Infrastructure{Azure: &state.Azure{MasterKeyHSMURI: testHSMURI, MasterKeyName: "master-key"}}
+
+	testCases := map[string]struct {
+		writeBackend  *stubMasterKeyBackend
+		readBackend   *stubMasterKeyBackend
+		newBackendErr error
+		infra         state.Infrastructure
+		wantWriteErr  bool
+		wantReadErr   bool
+		wantEncrypted bool
+	}{
+		"unencrypted": {},
+		"encrypted with HSM": {
+			writeBackend:  &stubMasterKeyBackend{},
+			readBackend:   &stubMasterKeyBackend{},
+			infra:         hsmInfra,
+			wantEncrypted: true,
+		},
+		"encryption fails": {
+			writeBackend: &stubMasterKeyBackend{encryptErr: someErr},
+			wantWriteErr: true,
+		},
+		"decryption fails": {
+			writeBackend:  &stubMasterKeyBackend{},
+			readBackend:   &stubMasterKeyBackend{decryptErr: someErr},
+			infra:         hsmInfra,
+			wantReadErr:   true,
+			wantEncrypted: true,
+		},
+		"creating backend fails": {
+			writeBackend:  &stubMasterKeyBackend{},
+			newBackendErr: someErr,
+			infra:         hsmInfra,
+			wantReadErr:   true,
+			wantEncrypted: true,
+		},
+		"unencrypted secret read with HSM": {
+			readBackend: &stubMasterKeyBackend{},
+			infra:       hsmInfra,
+			wantReadErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fh := file.NewHandler(afero.NewMemMapFs())
+			var writeBackend masterKeyBackend
+			if tc.writeBackend != nil {
+				writeBackend = tc.writeBackend
+			}
+			err := writeMasterSecret(context.Background(), fh, writeBackend, secret)
+			if tc.wantWriteErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			var stored uri.MasterSecret
+			require.NoError(fh.ReadJSON(constants.MasterSecretFilename, &stored))
+			assert.Equal(!tc.wantEncrypted, bytes.Equal(secret.Key, stored.Key))
+
+			newBackend := func(hsmURI, keyName string) (masterKeyBackend, error) {
+				assert.Equal(testHSMURI, hsmURI)
+				assert.Equal("master-key", keyName)
+				return tc.readBackend, tc.newBackendErr
+			}
+			got, err := readMasterSecret(context.Background(), fh, newBackend, tc.infra)
+			if tc.wantReadErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(secret, got)
+		})
+	}
+}
+
+func TestValidateMasterKeyHSM(t *testing.T) {
+	someErr := errors.New("failed")
+	hsmConfig := func() *config.Config {
+		conf := config.Default()
+		conf.RemoveProviderAndAttestationExcept(cloudprovider.Azure)
+		conf.Provider.Azure.MasterKeyHSMURI = testHSMURI
+		conf.Provider.Azure.MasterKeyName = "master-key"
+		return conf
+	}
+	noHSMConfig := func() *config.Config {
+		conf := config.Default()
+		conf.RemoveProviderAndAttestationExcept(cloudprovider.Azure)
+		return conf
+	}
+	initializedState := func(hsmURI, keyName string) *state.State {
+		stateFile := state.New()
+		stateFile.ClusterValues = state.ClusterValues{ClusterID: "deadbeef"}
+		stateFile.Infrastructure.Azure = &state.Azure{MasterKeyHSMURI: hsmURI, MasterKeyName: keyName}
+		return stateFile
+	}
+
+	testCases := map[string]struct {
+		conf           *config.Config
+		stateFile      *state.State
+		backend        *stubMasterKeyBackend
+		newBackendErr  error
+		wantValidation bool
+		wantErr        bool
+	}{
+		"no HSM configured": {
+			conf:      noHSMConfig(),
+			stateFile: state.New(),
+		},
+		"HSM accessible": {
+			conf:           hsmConfig(),
+			stateFile:      state.New(),
+			backend:        &stubMasterKeyBackend{},
+			wantValidation: true,
+		},
+		"HSM permission denied": {
+			conf:           hsmConfig(),
+			stateFile:      state.New(),
+			backend:        &stubMasterKeyBackend{validateErr: someErr},
+			wantValidation: true,
+			wantErr:        true,
+		},
+		"invalid HSM": {
+			conf:          hsmConfig(),
+			stateFile:     state.New(),
+			newBackendErr: someErr,
+			wantErr:       true,
+		},
+		"initialized cluster with same HSM": {
+			conf:           hsmConfig(),
+			stateFile:      initializedState(testHSMURI, "master-key"),
+			backend:        &stubMasterKeyBackend{},
+			wantValidation: true,
+		},
+		"initialized cluster without HSM": {
+			conf:      noHSMConfig(),
+			stateFile: initializedState("", ""),
+		},
+		"HSM added after initialization": {
+			conf:      hsmConfig(),
+			stateFile: initializedState("", ""),
+			backend:   &stubMasterKeyBackend{},
+			wantErr:   true,
+		},
+		"HSM removed after initialization": {
+			conf:      noHSMConfig(),
+			stateFile: initializedState(testHSMURI, "master-key"),
+			wantErr:   true,
+		},
+		"HSM key changed after initialization": {
+			conf:      hsmConfig(),
+			stateFile: initializedState(testHSMURI, "other-key"),
+			backend:   &stubMasterKeyBackend{},
+			wantErr:   true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			a := &applyCmd{
+				log: logger.NewTest(t),
+				newMasterKeyBackend: func(hsmURI, keyName string) (masterKeyBackend, error) {
+					assert.Equal(testHSMURI, hsmURI)
+					assert.Equal("master-key", keyName)
+					return tc.backend, tc.newBackendErr
+				},
+			}
+
+			err := a.validateMasterKeyHSM(context.Background(), tc.conf, tc.stateFile)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			if tc.backend != nil {
+				assert.Equal(tc.wantValidation, tc.backend.validated)
+			}
+		})
+	}
+}
+
+// stubMasterKeyBackend "encrypts" by inverting all bits.
+type stubMasterKeyBackend struct {
+	encryptErr  error
+	decryptErr  error
+	validateErr error
+	validated   bool
+}
+
+func (b *stubMasterKeyBackend) Encrypt(_ context.Context, plaintext []byte) ([]byte, error) {
+	return invertBits(plaintext), b.encryptErr
+}
+
+func (b *stubMasterKeyBackend) Decrypt(_ context.Context, ciphertext []byte) ([]byte, error) {
+	return invertBits(ciphertext), b.decryptErr
+}
+
+func (b *stubMasterKeyBackend) ValidatePermissions(_ context.Context) error {
+	b.validated = true
+	return b.validateErr
+}
+
+func invertBits(data []byte) []byte {
+	out := make([]byte, len(data))
+	for i := range data {
+		out[i] = ^data[i]
+	}
+	return out
+}
@@ -59,6 +59,9 @@ func runUp(cmd *cobra.Command, _ []string) error {
 	if err := m.flags.parse(cmd.Flags()); err != nil {
 		return err
 	}
+	if err := m.flags.requireConfigFile(); err != nil {
+		return err
+	}
 
 	return m.up(cmd)
 }
@@ -69,6 +69,8 @@ type recoverCmd struct {
 	log           debugLog
 	configFetcher attestationconfigapi.Fetcher
 	flags         recoverFlags
+
+	newMasterKeyBackend newMasterKeyBackendFunc
 }
 
 func runRecover(cmd *cobra.Command, _ []string) error {
@@ -80,7 +82,7 @@ func runRecover(cmd *cobra.Command, _ []string) error {
 	newDialer := func(validator atls.Validator) *dialer.Dialer {
 		return dialer.New(nil, validator, &net.Dialer{})
 	}
-	r := &recoverCmd{log: log, configFetcher: attestationconfigapi.NewFetcher()}
+	r := &recoverCmd{log: log, configFetcher: attestationconfigapi.NewFetcher(), newMasterKeyBackend: newManagedHSMBackend}
 	if err := r.flags.parse(cmd.Flags()); err != nil {
 		return err
 	}
@@ -92,14 +94,8 @@ func (r *recoverCmd) recover(
 	cmd *cobra.Command, fileHandler file.Handler, interval time.Duration,
 	doer recoverDoerInterface, newDialer func(validator atls.Validator) *dialer.Dialer,
 ) error {
-	var masterSecret uri.MasterSecret
-	r.log.Debug(fmt.Sprintf("Loading master secret file from %q", r.flags.pathPrefixer.PrefixPrintablePath(constants.MasterSecretFilename)))
-	if err := fileHandler.ReadJSON(constants.MasterSecretFilename, &masterSecret); err != nil {
-		return err
-	}
-
 	r.log.Debug(fmt.Sprintf("Loading configuration file from %q", r.flags.pathPrefixer.PrefixPrintablePath(constants.ConfigFilename)))
-	conf, err := config.New(fileHandler, constants.ConfigFilename, r.configFetcher, r.flags.force)
+	conf, err := r.flags.loadConfig(cmd.Context(), fileHandler, r.configFetcher)
 	var configValidationErr *config.ValidationError
 	if errors.As(err, &configValidationErr) {
 		cmd.PrintErrln(configValidationErr.LongMessage())
@@ -121,6 +117,12 @@ func (r *recoverCmd) recover(
 		return fmt.Errorf("validating state file: %w", err)
 	}
 
+	r.log.Debug(fmt.Sprintf("Loading master secret file from %q", r.flags.pathPrefixer.PrefixPrintablePath(constants.MasterSecretFilename)))
+	masterSecret, err := readMasterSecret(cmd.Context(), fileHandler, r.newMasterKeyBackend, stateFile.Infrastructure)
+	if err != nil {
+		return err
+	}
+
 	endpoint, err := r.parseEndpoint(stateFile)
 	if err != nil {
 		return err
@@ -0,0 +1,24 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewRotateCmd returns a new cobra.Command for the rotate command.
+func NewRotateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Rotate secrets of your Constellation cluster",
+		Long:  "Rotate secrets of your Constellation cluster.",
+		Args:  cobra.ExactArgs(0),
+	}
+
+	cmd.AddCommand(newRotateMeasurementSaltCmd())
+	return cmd
+}
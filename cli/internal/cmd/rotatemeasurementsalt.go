@@ -0,0 +1,268 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/kubecmd"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+	"github.com/edgelesssys/constellation/v2/internal/crypto"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+)
+
+// saltAbortTimeout is the time given to restore the old measurement salt after a failed or interrupted rollout.
+const saltAbortTimeout = 5 * time.Minute
+
+func newRotateMeasurementSaltCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "measurement-salt",
+		Short: "Rotate the measurement salt of a Constellation cluster",
+		Long: "Rotate the measurement salt of a Constellation cluster.\n\n" +
+			"A new salt is generated and distributed to the join service, so nodes joining the cluster afterwards derive their cluster ID from the new salt. " +
+			"The cluster ID derived from the new salt is written to the state file together with the salt once the rollout succeeded. " +
+			"Nodes that are already running keep the old cluster ID, so they must be replaced to pass \"constellation verify\" against the new ID. " +
+			"If the rollout fails or is interrupted, the old salt is restored in the cluster.",
+		Args: cobra.NoArgs,
+		RunE: runRotateMeasurementSalt,
+	}
+	cmd.Flags().BoolP("yes", "y", false, "rotate the measurement salt without further confirmation")
+	cmd.Flags().Duration("timeout", 10*time.Minute, "maximum time to wait for the rollout of the new salt")
+	cmd.Flags().Duration("lock-timeout", 0, "time to wait for the state lock held by another command to be released")
+	cmd.Flags().Bool("force-unlock", false, "remove a stale state lock left behind by a crashed command\n"+
+		"Locks of commands that are still running are never removed.")
+	return cmd
+}
+
+type rotateMeasurementSaltFlags struct {
+	rootFlags
+	yes         bool
+	timeout     time.Duration
+	lockTimeout time.Duration
+	forceUnlock bool
+}
+
+func (f *rotateMeasurementSaltFlags) parse(flags *pflag.FlagSet) error {
+	if err := f.rootFlags.parse(flags); err != nil {
+		return err
+	}
+
+	var err error
+	f.yes, err = flags.GetBool("yes")
+	if err != nil {
+		return fmt.Errorf("getting 'yes' flag: %w", err)
+	}
+	f.timeout, err = flags.GetDuration("timeout")
+	if err != nil {
+		return fmt.Errorf("getting 'timeout' flag: %w", err)
+	}
+	f.lockTimeout, err = flags.GetDuration("lock-timeout")
+	if err != nil {
+		return fmt.Errorf("getting 'lock-timeout' flag: %w", err)
+	}
+	f.forceUnlock, err = flags.GetBool("force-unlock")
+	if err != nil {
+		return fmt.Errorf("getting 'force-unlock' flag: %w", err)
+	}
+	return nil
+}
+
+func runRotateMeasurementSalt(cmd *cobra.Command, _ []string) error {
+	log, err := newCLILogger(cmd)
+	if err != nil {
+		return fmt.Errorf("creating logger: %w", err)
+	}
+	spinner, err := newSpinnerOrStderr(cmd)
+	if err != nil {
+		return fmt.Errorf("creating spinner: %w", err)
+	}
+	defer spinner.Stop()
+	fileHandler := file.NewHandler(afero.NewOsFs())
+
+	r := &rotateMeasurementSaltCmd{
+		log:         log,
+		fileHandler: fileHandler,
+		spinner:     spinner,
+		newSalt: func() ([]byte, error) {
+			return crypto.GenerateRandomBytes(crypto.RNGLengthDefault)
+		},
+		newMasterKeyBackend: newManagedHSMBackend,
+	}
+	if err := r.flags.parse(cmd.Flags()); err != nil {
+		return err
+	}
+	r.log.Debug("Using flags", "yes", r.flags.yes, "timeout", r.flags.timeout, "lockTimeout", r.flags.lockTimeout, "forceUnlock", r.flags.forceUnlock)
+
+	kubeConfig, err := r.flags.readKubeConfig(fileHandler)
+	if err != nil {
+		return fmt.Errorf("reading kubeconfig: %w", err)
+	}
+	kubeClient, err := kubecmd.New(kubeConfig, log)
+	if err != nil {
+		return fmt.Errorf("setting up kubernetes client: %w", err)
+	}
+
+	locker := state.NewLocker(fileHandler, constants.StateLockFilename, stateLockHolder())
+	if err := locker.Acquire(cmd.Context(), r.flags.lockTimeout, r.flags.forceUnlock); err != nil {
+		return err
+	}
+	rotateErr := r.rotate(cmd, &kubeSaltRollout{kubeClient: kubeClient})
+	if err := locker.Release(); err != nil {
+		return errors.Join(rotateErr, err)
+	}
+	return rotateErr
+}
+
+type rotateMeasurementSaltCmd struct {
+	log         debugLog
+	fileHandler file.Handler
+	spinner     spinnerInterf
+	flags       rotateMeasurementSaltFlags
+	newSalt     func() ([]byte, error)
+	// newMasterKeyBackend decrypts the master secret, which is needed to derive the new cluster ID, if it is encrypted by a Managed HSM.
+	newMasterKeyBackend newMasterKeyBackendFunc
+}
+
+// rotate generates a new measurement salt, rolls it out to the cluster, and writes it to the state file,
+// together with the cluster ID nodes derive from it. The ID is measured into PCR 15 of joining nodes and checked by "constellation verify".
+// The state file keeps the old salt and ID unless both the rollout and writing the state file succeed.
+// If anything fails after the rollout was started, the old salt is restored in the cluster.
+func (r *rotateMeasurementSaltCmd) rotate(cmd *cobra.Command, coordinator saltRolloutCoordinator) error {
+	stateFile, err := state.ReadFromFile(r.fileHandler, constants.StateFilename)
+	if err != nil {
+		return fmt.Errorf("reading state file: %w", err)
+	}
+	oldSalt := stateFile.ClusterValues.MeasurementSalt
+	if len(oldSalt) == 0 {
+		return errors.New("the state file doesn't contain a measurement salt, initialize the cluster with \"constellation apply\" first")
+	}
+
+	clusterSalt, err := coordinator.CurrentSalt(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("getting measurement salt of the cluster: %w", err)
+	}
+	if !bytes.Equal(clusterSalt, oldSalt) {
+		return fmt.Errorf("the measurement salt of the cluster doesn't match the salt in %s, refusing to rotate it",
+			r.flags.pathPrefixer.PrefixPrintablePath(constants.StateFilename))
+	}
+
+	masterSecret, err := readMasterSecret(cmd.Context(), r.fileHandler, r.newMasterKeyBackend, stateFile.Infrastructure)
+	if err != nil {
+		return fmt.Errorf("reading master secret to derive the new cluster ID: %w", err)
+	}
+
+	if !r.flags.yes {
+		cmd.Println("You are about to rotate the measurement salt of your Constellation cluster.")
+		cmd.Println("Nodes joining the cluster afterwards derive a new cluster ID from the new salt.")
+		cmd.Println("Running nodes keep the old cluster ID and fail \"constellation verify\" until they are replaced.")
+		ok, err := askToConfirm(cmd, "Do you want to continue?")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			cmd.Println("The rotation of the measurement salt was aborted.")
+			return nil
+		}
+	}
+
+	newSalt, err := r.newSalt()
+	if err != nil {
+		return fmt.Errorf("generating measurement salt: %w", err)
+	}
+	if len(newSalt) != len(oldSalt) {
+		return fmt.Errorf("generated measurement salt has length %d, expected %d", len(newSalt), len(oldSalt))
+	}
+	newClusterID, err := deriveClusterID(masterSecret, newSalt)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), r.flags.timeout)
+	defer cancel()
+	r.spinner.Start("Rolling out new measurement salt", false)
+	err = coordinator.Rollout(ctx, newSalt)
+	r.spinner.Stop()
+	if err != nil {
+		return r.abort(cmd, coordinator, oldSalt, fmt.Errorf("rolling out measurement salt: %w", err))
+	}
+
+	oldClusterID := stateFile.ClusterValues.ClusterID
+	stateFile.ClusterValues.MeasurementSalt = newSalt
+	stateFile.ClusterValues.ClusterID = newClusterID
+	if err := stateFile.WriteToFile(r.fileHandler, constants.StateFilename); err != nil {
+		return r.abort(cmd, coordinator, oldSalt, fmt.Errorf("writing state file: %w", err))
+	}
+
+	cmd.Println("Measurement salt rotated successfully.")
+	cmd.Printf("The cluster ID of joining nodes changed from %s to %s.\n", oldClusterID, newClusterID)
+	cmd.Println("Replace the running nodes, so all nodes of the cluster share the new cluster ID.")
+	cmd.Printf("Until then, verify the running nodes with \"constellation verify --cluster-id %s\".\n", oldClusterID)
+	return nil
+}
+
+// abort restores the old measurement salt in the cluster after the rotation failed with rotateErr.
+// The old salt is restored even if the context of cmd is canceled, e.g. because the user interrupted the rotation.
+func (r *rotateMeasurementSaltCmd) abort(cmd *cobra.Command, coordinator saltRolloutCoordinator, oldSalt []byte, rotateErr error) error {
+	cmd.PrintErrln("Rotating the measurement salt failed, restoring the old salt")
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(cmd.Context()), saltAbortTimeout)
+	defer cancel()
+	r.spinner.Start("Restoring old measurement salt", false)
+	defer r.spinner.Stop()
+	if err := coordinator.Abort(ctx, oldSalt); err != nil {
+		return errors.Join(rotateErr, fmt.Errorf("restoring old measurement salt, rerun the rotation to bring the cluster and %s back in sync: %w",
+			r.flags.pathPrefixer.PrefixPrintablePath(constants.StateFilename), err))
+	}
+	return rotateErr
+}
+
+// saltRolloutCoordinator distributes a measurement salt to the nodes of the cluster.
+type saltRolloutCoordinator interface {
+	// CurrentSalt returns the measurement salt the cluster currently distributes to joining nodes.
+	CurrentSalt(ctx context.Context) ([]byte, error)
+	// Rollout distributes the salt, so all nodes joining the cluster afterwards derive their cluster ID from it.
+	Rollout(ctx context.Context, salt []byte) error
+	// Abort restores the old salt after a failed or interrupted rollout.
+	Abort(ctx context.Context, oldSalt []byte) error
+}
+
+// kubeSaltRollout rolls out the measurement salt by updating the join-config and restarting the join service,
+// which only reads the salt on startup.
+type kubeSaltRollout struct {
+	kubeClient interface {
+		GetMeasurementSalt(ctx context.Context) ([]byte, error)
+		ApplyMeasurementSalt(ctx context.Context, measurementSalt []byte) error
+		RestartJoinService(ctx context.Context) error
+	}
+}
+
+// CurrentSalt returns the measurement salt stored in the join-config.
+func (k *kubeSaltRollout) CurrentSalt(ctx context.Context) ([]byte, error) {
+	return k.kubeClient.GetMeasurementSalt(ctx)
+}
+
+// Rollout writes the salt to the join-config and waits until all join service pods use it.
+func (k *kubeSaltRollout) Rollout(ctx context.Context, salt []byte) error {
+	if err := k.kubeClient.ApplyMeasurementSalt(ctx, salt); err != nil {
+		return err
+	}
+	return k.kubeClient.RestartJoinService(ctx)
+}
+
+// Abort writes the old salt back to the join-config and restarts the join service,
+// since some of its pods may already have loaded the new salt.
+func (k *kubeSaltRollout) Abort(ctx context.Context, oldSalt []byte) error {
+	return k.Rollout(ctx, oldSalt)
+}
@@ -0,0 +1,217 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/kms/uri"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotateMeasurementSalt(t *testing.T) {
+	oldSalt := []byte(defaultStateFile(cloudprovider.Azure).ClusterValues.MeasurementSalt)
+	newSalt := bytes.Repeat([]byte{0x42}, len(oldSalt))
+	masterSecret := uri.MasterSecret{Key: bytes.Repeat([]byte{0x01}, 32), Salt: bytes.Repeat([]byte{0x02}, 32)}
+	oldClusterID := defaultStateFile(cloudprovider.Azure).ClusterValues.ClusterID
+	newClusterID, err := deriveClusterID(masterSecret, newSalt)
+	require.NoError(t, err)
+	require.NotEqual(t, oldClusterID, newClusterID)
+
+	testCases := map[string]struct {
+		coordinator   *stubSaltRolloutCoordinator
+		newSaltErr    error
+		noSecret      bool
+		stdin         string
+		yes           bool
+		cancelCtx     bool
+		wantErr       bool
+		wantStateSalt []byte
+		wantClusterID string
+		wantRollouts  [][]byte
+		wantAborted   bool
+	}{
+		"rotation succeeds": {
+			coordinator:   &stubSaltRolloutCoordinator{currentSalt: oldSalt},
+			yes:           true,
+			wantStateSalt: newSalt,
+			wantClusterID: newClusterID,
+			wantRollouts:  [][]byte{newSalt},
+		},
+		"rotation confirmed by user": {
+			coordinator:   &stubSaltRolloutCoordinator{currentSalt: oldSalt},
+			stdin:         "y\n",
+			wantStateSalt: newSalt,
+			wantClusterID: newClusterID,
+			wantRollouts:  [][]byte{newSalt},
+		},
+		"rotation declined by user": {
+			coordinator:   &stubSaltRolloutCoordinator{currentSalt: oldSalt},
+			stdin:         "n\n",
+			wantStateSalt: oldSalt,
+			wantClusterID: oldClusterID,
+		},
+		"rollout fails": {
+			coordinator:   &stubSaltRolloutCoordinator{currentSalt: oldSalt, rolloutErr: errors.New("rollout error")},
+			yes:           true,
+			wantErr:       true,
+			wantStateSalt: oldSalt,
+			wantClusterID: oldClusterID,
+			wantRollouts:  [][]byte{newSalt},
+			wantAborted:   true,
+		},
+		"rollout interrupted": {
+			coordinator:   &stubSaltRolloutCoordinator{currentSalt: oldSalt},
+			yes:           true,
+			cancelCtx:     true,
+			wantErr:       true,
+			wantStateSalt: oldSalt,
+			wantClusterID: oldClusterID,
+			wantRollouts:  [][]byte{newSalt},
+			wantAborted:   true,
+		},
+		"abort fails": {
+			coordinator: &stubSaltRolloutCoordinator{
+				currentSalt: oldSalt,
+				rolloutErr:  errors.New("rollout error"),
+				abortErr:    errors.New("abort error"),
+			},
+			yes:           true,
+			wantErr:       true,
+			wantStateSalt: oldSalt,
+			wantClusterID: oldClusterID,
+			wantRollouts:  [][]byte{newSalt},
+			wantAborted:   true,
+		},
+		"cluster salt differs from state": {
+			coordinator:   &stubSaltRolloutCoordinator{currentSalt: newSalt},
+			yes:           true,
+			wantErr:       true,
+			wantStateSalt: oldSalt,
+			wantClusterID: oldClusterID,
+		},
+		"getting cluster salt fails": {
+			coordinator:   &stubSaltRolloutCoordinator{currentSaltErr: errors.New("get error")},
+			yes:           true,
+			wantErr:       true,
+			wantStateSalt: oldSalt,
+			wantClusterID: oldClusterID,
+		},
+		"master secret missing": {
+			coordinator:   &stubSaltRolloutCoordinator{currentSalt: oldSalt},
+			noSecret:      true,
+			yes:           true,
+			wantErr:       true,
+			wantStateSalt: oldSalt,
+			wantClusterID: oldClusterID,
+		},
+		"generating salt fails": {
+			coordinator:   &stubSaltRolloutCoordinator{currentSalt: oldSalt},
+			newSaltErr:    errors.New("rng error"),
+			yes:           true,
+			wantErr:       true,
+			wantStateSalt: oldSalt,
+			wantClusterID: oldClusterID,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			require.NoError(defaultStateFile(cloudprovider.Azure).WriteToFile(fileHandler, constants.StateFilename))
+			if !tc.noSecret {
+				require.NoError(fileHandler.WriteJSON(constants.MasterSecretFilename, masterSecret))
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.cancelCtx {
+				tc.coordinator.onRollout = cancel
+			}
+			cmd := newRotateMeasurementSaltCmd()
+			cmd.SetContext(ctx)
+			cmd.SetOut(&bytes.Buffer{})
+			cmd.SetErr(&bytes.Buffer{})
+			cmd.SetIn(bytes.NewBufferString(tc.stdin))
+
+			r := &rotateMeasurementSaltCmd{
+				log:         logger.NewTest(t),
+				fileHandler: fileHandler,
+				spinner:     &nopSpinner{},
+				flags:       rotateMeasurementSaltFlags{yes: tc.yes, timeout: time.Minute},
+				newSalt: func() ([]byte, error) {
+					return newSalt, tc.newSaltErr
+				},
+			}
+
+			err := r.rotate(cmd, tc.coordinator)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+
+			stateFile, err := state.ReadFromFile(fileHandler, constants.StateFilename)
+			require.NoError(err)
+			assert.Equal(tc.wantStateSalt, []byte(stateFile.ClusterValues.MeasurementSalt))
+			assert.Equal(tc.wantClusterID, stateFile.ClusterValues.ClusterID)
+			assert.Equal(tc.wantRollouts, tc.coordinator.rollouts)
+			if tc.wantAborted {
+				assert.Equal(oldSalt, tc.coordinator.restoredSalt)
+			} else {
+				assert.Nil(tc.coordinator.restoredSalt)
+			}
+		})
+	}
+}
+
+type stubSaltRolloutCoordinator struct {
+	currentSalt    []byte
+	currentSaltErr error
+	rolloutErr     error
+	abortErr       error
+	// onRollout is called during the rollout, e.g. to interrupt it.
+	onRollout func()
+
+	rollouts     [][]byte
+	restoredSalt []byte
+}
+
+func (s *stubSaltRolloutCoordinator) CurrentSalt(context.Context) ([]byte, error) {
+	return s.currentSalt, s.currentSaltErr
+}
+
+func (s *stubSaltRolloutCoordinator) Rollout(ctx context.Context, salt []byte) error {
+	s.rollouts = append(s.rollouts, salt)
+	if s.onRollout != nil {
+		s.onRollout()
+		return ctx.Err()
+	}
+	return s.rolloutErr
+}
+
+func (s *stubSaltRolloutCoordinator) Abort(ctx context.Context, oldSalt []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.restoredSalt = oldSalt
+	return s.abortErr
+}
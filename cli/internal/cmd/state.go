@@ -0,0 +1,25 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewStateCmd returns a new cobra.Command for the state command.
+func NewStateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "state",
+		Short: "Modify the state file of your Constellation cluster",
+		Long:  "Modify the state file of your Constellation cluster.",
+		Args:  cobra.ExactArgs(0),
+	}
+
+	cmd.AddCommand(newStateSetEndpointCmd())
+	cmd.AddCommand(newStateGCCmd())
+	return cmd
+}
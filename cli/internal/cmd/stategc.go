@@ -0,0 +1,181 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/edgelesssys/constellation/v2/cli/internal/cloudcmd"
+	"github.com/edgelesssys/constellation/v2/internal/api/attestationconfigapi"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+)
+
+func newStateGCCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Delete orphaned cloud resources recorded in the state file",
+		Long: "Delete orphaned cloud resources recorded in the state file.\n\n" +
+			"A resource is orphaned if it's recorded in the state file and still exists, but is no longer managed by Terraform, " +
+			"for example after a failed apply replaced it. Existing networks configured in the config file are never deleted.\n" +
+			"By default, the orphaned resources are only listed. Use --dry-run=false to delete them.",
+		Args: cobra.ExactArgs(0),
+		RunE: runStateGC,
+	}
+	cmd.Flags().Bool("dry-run", true, "only list the orphaned resources without deleting them")
+	cmd.Flags().BoolP("yes", "y", false, "delete the orphaned resources without further confirmation")
+	cmd.Flags().Duration("lock-timeout", 0, "time to wait for the state lock held by another command to be released")
+	cmd.Flags().Bool("force-unlock", false, "remove a stale state lock left behind by a crashed command\n"+
+		"Locks of commands that are still running are never removed.")
+	return cmd
+}
+
+type stateGCFlags struct {
+	rootFlags
+	dryRun      bool
+	yes         bool
+	lockTimeout time.Duration
+	forceUnlock bool
+}
+
+func (f *stateGCFlags) parse(flags *pflag.FlagSet) error {
+	if err := f.rootFlags.parse(flags); err != nil {
+		return err
+	}
+
+	var err error
+	f.dryRun, err = flags.GetBool("dry-run")
+	if err != nil {
+		return fmt.Errorf("getting 'dry-run' flag: %w", err)
+	}
+	f.yes, err = flags.GetBool("yes")
+	if err != nil {
+		return fmt.Errorf("getting 'yes' flag: %w", err)
+	}
+	f.lockTimeout, err = flags.GetDuration("lock-timeout")
+	if err != nil {
+		return fmt.Errorf("getting 'lock-timeout' flag: %w", err)
+	}
+	f.forceUnlock, err = flags.GetBool("force-unlock")
+	if err != nil {
+		return fmt.Errorf("getting 'force-unlock' flag: %w", err)
+	}
+	return nil
+}
+
+// resourceCollector finds and deletes orphaned cloud resources.
+type resourceCollector interface {
+	Plan(ctx context.Context, conf *config.Config, tfWorkspace string, recorded state.Infrastructure) ([]cloudcmd.Resource, error)
+	Delete(ctx context.Context, conf *config.Config, recorded state.Infrastructure, resources []cloudcmd.Resource) error
+}
+
+type stateGCCmd struct {
+	log           debugLog
+	spinner       spinnerInterf
+	fileHandler   file.Handler
+	flags         stateGCFlags
+	configFetcher attestationconfigapi.Fetcher
+	collector     resourceCollector
+}
+
+func runStateGC(cmd *cobra.Command, _ []string) error {
+	log, err := newCLILogger(cmd)
+	if err != nil {
+		return fmt.Errorf("creating logger: %w", err)
+	}
+	spinner, err := newSpinnerOrStderr(cmd)
+	if err != nil {
+		return fmt.Errorf("creating spinner: %w", err)
+	}
+	defer spinner.Stop()
+	fileHandler := file.NewHandler(afero.NewOsFs())
+
+	s := &stateGCCmd{
+		log:           log,
+		spinner:       spinner,
+		fileHandler:   fileHandler,
+		configFetcher: attestationconfigapi.NewFetcher(),
+		collector:     cloudcmd.NewResourceCollector(),
+	}
+	if err := s.flags.parse(cmd.Flags()); err != nil {
+		return err
+	}
+	s.log.Debug("Using flags", "dryRun", s.flags.dryRun, "yes", s.flags.yes,
+		"lockTimeout", s.flags.lockTimeout, "forceUnlock", s.flags.forceUnlock)
+
+	locker := state.NewLocker(fileHandler, constants.StateLockFilename, stateLockHolder())
+	if err := locker.Acquire(cmd.Context(), s.flags.lockTimeout, s.flags.forceUnlock); err != nil {
+		return err
+	}
+	gcErr := s.gc(cmd)
+	if err := locker.Release(); err != nil {
+		return errors.Join(gcErr, err)
+	}
+	return gcErr
+}
+
+// gc lists the orphaned resources recorded in the state file.
+// Without --dry-run, the resources are deleted after confirmation.
+func (s *stateGCCmd) gc(cmd *cobra.Command) error {
+	conf, err := s.flags.loadConfig(cmd.Context(), s.fileHandler, s.configFetcher)
+	if err != nil {
+		return fmt.Errorf("loading config file: %w", err)
+	}
+	stateFile, err := state.ReadFromFile(s.fileHandler, constants.StateFilename)
+	if err != nil {
+		return fmt.Errorf("reading state file: %w", err)
+	}
+
+	s.spinner.Start("Looking for orphaned resources ", false)
+	orphans, err := s.collector.Plan(cmd.Context(), conf, constants.TerraformWorkingDir, stateFile.Infrastructure)
+	s.spinner.Stop()
+	if err != nil {
+		return fmt.Errorf("looking for orphaned resources: %w", err)
+	}
+	if len(orphans) == 0 {
+		cmd.Println("No orphaned resources found.")
+		return nil
+	}
+
+	cmd.Println("The following resources are recorded in the state file, but are no longer managed by Terraform:")
+	for _, resource := range orphans {
+		cmd.Printf("  %s\n", resource)
+	}
+	if s.flags.dryRun {
+		cmd.Println("Run with --dry-run=false to delete them.")
+		return nil
+	}
+
+	if !s.flags.yes {
+		ok, err := askToConfirm(cmd, "Do you want to delete these resources?")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			cmd.Println("Deleting the orphaned resources was aborted.")
+			return nil
+		}
+	}
+
+	s.spinner.Start("Deleting orphaned resources ", false)
+	err = s.collector.Delete(cmd.Context(), conf, stateFile.Infrastructure, orphans)
+	s.spinner.Stop()
+	if err != nil {
+		return fmt.Errorf("deleting orphaned resources: %w", err)
+	}
+	cmd.Printf("Deleted %d orphaned resources.\n", len(orphans))
+	return nil
+}
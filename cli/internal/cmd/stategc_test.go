@@ -0,0 +1,135 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/cli/internal/cloudcmd"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateGC(t *testing.T) {
+	orphans := []cloudcmd.Resource{
+		{Kind: cloudcmd.ResourceKindSubnet, ID: "projects/p/regions/r/subnetworks/old"},
+		{Kind: cloudcmd.ResourceKindVirtualNetwork, ID: "projects/p/global/networks/old"},
+	}
+
+	testCases := map[string]struct {
+		flags       stateGCFlags
+		stdin       string
+		collector   *stubResourceCollector
+		wantDeleted []cloudcmd.Resource
+		wantOutput  string
+		wantErr     bool
+	}{
+		"dry run lists orphans": {
+			flags:      stateGCFlags{dryRun: true},
+			collector:  &stubResourceCollector{orphans: orphans},
+			wantOutput: "subnet projects/p/regions/r/subnetworks/old",
+		},
+		"no orphans": {
+			flags:      stateGCFlags{yes: true},
+			collector:  &stubResourceCollector{},
+			wantOutput: "No orphaned resources found.",
+		},
+		"orphans deleted": {
+			flags:       stateGCFlags{yes: true},
+			collector:   &stubResourceCollector{orphans: orphans},
+			wantDeleted: orphans,
+			wantOutput:  "Deleted 2 orphaned resources.",
+		},
+		"deletion confirmed by user": {
+			stdin:       "y\n",
+			collector:   &stubResourceCollector{orphans: orphans},
+			wantDeleted: orphans,
+		},
+		"deletion declined by user": {
+			stdin:      "n\n",
+			collector:  &stubResourceCollector{orphans: orphans},
+			wantOutput: "Deleting the orphaned resources was aborted.",
+		},
+		"planning fails": {
+			flags:     stateGCFlags{yes: true},
+			collector: &stubResourceCollector{planErr: errors.New("failed")},
+			wantErr:   true,
+		},
+		"deletion fails": {
+			flags:       stateGCFlags{yes: true},
+			collector:   &stubResourceCollector{orphans: orphans, deleteErr: errors.New("failed")},
+			wantDeleted: orphans,
+			wantErr:     true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			require.NoError(defaultStateFile(cloudprovider.GCP).WriteToFile(fileHandler, constants.StateFilename))
+			require.NoError(fileHandler.WriteYAML(constants.ConfigFilename, defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.GCP)))
+
+			cmd := newStateGCCmd()
+			cmd.SetContext(context.Background())
+			out := &bytes.Buffer{}
+			cmd.SetOut(out)
+			cmd.SetErr(&bytes.Buffer{})
+			cmd.SetIn(bytes.NewBufferString(tc.stdin))
+
+			s := &stateGCCmd{
+				log:           logger.NewTest(t),
+				spinner:       &nopSpinner{},
+				fileHandler:   fileHandler,
+				flags:         tc.flags,
+				configFetcher: stubAttestationFetcher{},
+				collector:     tc.collector,
+			}
+
+			err := s.gc(cmd)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			assert.Equal(constants.TerraformWorkingDir, tc.collector.tfWorkspace)
+			assert.Equal(tc.wantDeleted, tc.collector.deleted)
+			assert.Contains(out.String(), tc.wantOutput)
+		})
+	}
+}
+
+type stubResourceCollector struct {
+	orphans   []cloudcmd.Resource
+	planErr   error
+	deleteErr error
+
+	tfWorkspace string
+	deleted     []cloudcmd.Resource
+}
+
+func (s *stubResourceCollector) Plan(_ context.Context, _ *config.Config, tfWorkspace string, _ state.Infrastructure) ([]cloudcmd.Resource, error) {
+	s.tfWorkspace = tfWorkspace
+	return s.orphans, s.planErr
+}
+
+func (s *stubResourceCollector) Delete(_ context.Context, _ *config.Config, _ state.Infrastructure, resources []cloudcmd.Resource) error {
+	s.deleted = resources
+	return s.deleteErr
+}
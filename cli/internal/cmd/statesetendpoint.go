@@ -0,0 +1,265 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/edgelesssys/constellation/v2/internal/api/attestationconfigapi"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/constellation"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+)
+
+func newStateSetEndpointCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set-endpoint ENDPOINT",
+		Short: "Update the cluster endpoint in the state file",
+		Long: "Update the cluster endpoint in the state file after the load balancer of the cluster changed its IP address or DNS name.\n\n" +
+			"The server address of the kubeconfig of the workspace is updated to the new endpoint as well.\n" +
+			"The in-cluster endpoint is updated to the same endpoint if it matched the old cluster endpoint, unless --in-cluster-endpoint is set.",
+		Args: cobra.ExactArgs(1),
+		RunE: runStateSetEndpoint,
+	}
+	cmd.Flags().String("in-cluster-endpoint", "", "endpoint the cluster uses to reach its own API server, if it differs from ENDPOINT")
+	cmd.Flags().Bool("refresh-cert-sans", false, "add the new endpoints to the API server certificate SANs of the cluster, like the certsans phase of apply")
+	cmd.Flags().BoolP("yes", "y", false, "update the endpoint without further confirmation")
+	cmd.Flags().Duration("lock-timeout", 0, "time to wait for the state lock held by another command to be released")
+	cmd.Flags().Bool("force-unlock", false, "remove a stale state lock left behind by a crashed command\n"+
+		"Locks of commands that are still running are never removed.")
+	return cmd
+}
+
+type stateSetEndpointFlags struct {
+	rootFlags
+	inClusterEndpoint string
+	refreshCertSANs   bool
+	yes               bool
+	lockTimeout       time.Duration
+	forceUnlock       bool
+}
+
+func (f *stateSetEndpointFlags) parse(flags *pflag.FlagSet) error {
+	if err := f.rootFlags.parse(flags); err != nil {
+		return err
+	}
+
+	var err error
+	f.inClusterEndpoint, err = flags.GetString("in-cluster-endpoint")
+	if err != nil {
+		return fmt.Errorf("getting 'in-cluster-endpoint' flag: %w", err)
+	}
+	f.refreshCertSANs, err = flags.GetBool("refresh-cert-sans")
+	if err != nil {
+		return fmt.Errorf("getting 'refresh-cert-sans' flag: %w", err)
+	}
+	f.yes, err = flags.GetBool("yes")
+	if err != nil {
+		return fmt.Errorf("getting 'yes' flag: %w", err)
+	}
+	f.lockTimeout, err = flags.GetDuration("lock-timeout")
+	if err != nil {
+		return fmt.Errorf("getting 'lock-timeout' flag: %w", err)
+	}
+	f.forceUnlock, err = flags.GetBool("force-unlock")
+	if err != nil {
+		return fmt.Errorf("getting 'force-unlock' flag: %w", err)
+	}
+	return nil
+}
+
+// certSANsExtender adds SANs to the API server certificate of the cluster.
+type certSANsExtender interface {
+	ExtendClusterConfigCertSANs(ctx context.Context, clusterEndpoint, customEndpoint string, additionalAPIServerCertSANs []string) error
+}
+
+type stateSetEndpointCmd struct {
+	log           debugLog
+	fileHandler   file.Handler
+	flags         stateSetEndpointFlags
+	configFetcher attestationconfigapi.Fetcher
+	// newCertSANsExtender creates a client for the cluster using the given kubeconfig.
+	newCertSANsExtender func(kubeConfig []byte) (certSANsExtender, error)
+}
+
+func runStateSetEndpoint(cmd *cobra.Command, args []string) error {
+	log, err := newCLILogger(cmd)
+	if err != nil {
+		return fmt.Errorf("creating logger: %w", err)
+	}
+	spinner, err := newSpinnerOrStderr(cmd)
+	if err != nil {
+		return fmt.Errorf("creating spinner: %w", err)
+	}
+	defer spinner.Stop()
+	fileHandler := file.NewHandler(afero.NewOsFs())
+
+	s := &stateSetEndpointCmd{
+		log:           log,
+		fileHandler:   fileHandler,
+		configFetcher: attestationconfigapi.NewFetcher(),
+		newCertSANsExtender: func(kubeConfig []byte) (certSANsExtender, error) {
+			applier := constellation.NewApplier(log, spinner, constellation.ApplyContextCLI, nil)
+			if err := applier.SetKubeConfig(kubeConfig); err != nil {
+				return nil, err
+			}
+			return applier, nil
+		},
+	}
+	if err := s.flags.parse(cmd.Flags()); err != nil {
+		return err
+	}
+	s.log.Debug("Using flags", "inClusterEndpoint", s.flags.inClusterEndpoint, "refreshCertSANs", s.flags.refreshCertSANs,
+		"yes", s.flags.yes, "lockTimeout", s.flags.lockTimeout, "forceUnlock", s.flags.forceUnlock)
+
+	locker := state.NewLocker(fileHandler, constants.StateLockFilename, stateLockHolder())
+	if err := locker.Acquire(cmd.Context(), s.flags.lockTimeout, s.flags.forceUnlock); err != nil {
+		return err
+	}
+	setErr := s.setEndpoint(cmd, args[0])
+	if err := locker.Release(); err != nil {
+		return errors.Join(setErr, err)
+	}
+	return setErr
+}
+
+// setEndpoint validates the new endpoints and writes them to the state file and the kubeconfig.
+// With --refresh-cert-sans, the new endpoints are added to the API server certificate SANs afterwards.
+func (s *stateSetEndpointCmd) setEndpoint(cmd *cobra.Command, endpoint string) error {
+	if err := state.ValidateEndpoint(endpoint); err != nil {
+		return fmt.Errorf("validating cluster endpoint: %w", err)
+	}
+	if s.flags.inClusterEndpoint != "" {
+		if err := state.ValidateEndpoint(s.flags.inClusterEndpoint); err != nil {
+			return fmt.Errorf("validating in-cluster endpoint: %w", err)
+		}
+	}
+
+	stateFile, err := state.ReadFromFile(s.fileHandler, constants.StateFilename)
+	if err != nil {
+		return fmt.Errorf("reading state file: %w", err)
+	}
+	oldEndpoint, oldInClusterEndpoint := stateFile.Infrastructure.ClusterEndpoint, stateFile.Infrastructure.InClusterEndpoint
+	if oldEndpoint == "" {
+		return errors.New("the state file doesn't contain a cluster endpoint, create the cluster with \"constellation apply\" first")
+	}
+
+	inClusterEndpoint := s.flags.inClusterEndpoint
+	if inClusterEndpoint == "" {
+		inClusterEndpoint = oldInClusterEndpoint
+		if oldInClusterEndpoint == oldEndpoint {
+			inClusterEndpoint = endpoint
+		}
+	}
+	if endpoint == oldEndpoint && inClusterEndpoint == oldInClusterEndpoint {
+		cmd.Println("The state file already contains the given endpoints.")
+		return s.refreshCertSANs(cmd, stateFile)
+	}
+
+	if !s.flags.yes {
+		cmd.Printf("The cluster endpoint will be changed from %s to %s.\n", oldEndpoint, endpoint)
+		if inClusterEndpoint != oldInClusterEndpoint {
+			cmd.Printf("The in-cluster endpoint will be changed from %s to %s.\n", oldInClusterEndpoint, inClusterEndpoint)
+		}
+		ok, err := askToConfirm(cmd, "Do you want to continue?")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			cmd.Println("Updating the endpoint was aborted.")
+			return nil
+		}
+	}
+
+	stateFile.Infrastructure.ClusterEndpoint = endpoint
+	stateFile.Infrastructure.InClusterEndpoint = inClusterEndpoint
+	if err := stateFile.WriteToFile(s.fileHandler, constants.StateFilename); err != nil {
+		return fmt.Errorf("writing state file: %w", err)
+	}
+	if err := s.updateKubeConfig(endpoint); err != nil {
+		return err
+	}
+	cmd.Printf("Cluster endpoint updated to %s.\n", endpoint)
+
+	return s.refreshCertSANs(cmd, stateFile)
+}
+
+// updateKubeConfig points the server address of the kubeconfig of the workspace to the endpoint.
+// Workspaces without a kubeconfig are left unchanged.
+func (s *stateSetEndpointCmd) updateKubeConfig(endpoint string) error {
+	kubeConfig, err := s.fileHandler.Read(constants.AdminConfFilename)
+	if errors.Is(err, fs.ErrNotExist) {
+		s.log.Debug("No kubeconfig found, skipping update of the server address")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading kubeconfig: %w", err)
+	}
+
+	config, err := clientcmd.Load(kubeConfig)
+	if err != nil {
+		return fmt.Errorf("loading kubeconfig: %w", err)
+	}
+	for _, cluster := range config.Clusters {
+		server, err := url.Parse(cluster.Server)
+		if err != nil {
+			return fmt.Errorf("parsing kubeconfig server URL: %w", err)
+		}
+		server.Host = net.JoinHostPort(endpoint, server.Port())
+		cluster.Server = server.String()
+	}
+	kubeConfig, err = clientcmd.Write(*config)
+	if err != nil {
+		return fmt.Errorf("writing kubeconfig: %w", err)
+	}
+	if err := s.fileHandler.Write(constants.AdminConfFilename, kubeConfig, file.OptOverwrite); err != nil {
+		return fmt.Errorf("writing kubeconfig: %w", err)
+	}
+	return nil
+}
+
+// refreshCertSANs adds the endpoints of the state file to the API server certificate SANs, if requested.
+func (s *stateSetEndpointCmd) refreshCertSANs(cmd *cobra.Command, stateFile *state.State) error {
+	if !s.flags.refreshCertSANs {
+		return nil
+	}
+
+	conf, err := s.flags.loadConfig(cmd.Context(), s.fileHandler, s.configFetcher)
+	if err != nil {
+		return fmt.Errorf("loading config file: %w", err)
+	}
+	kubeConfig, err := s.flags.readKubeConfig(s.fileHandler)
+	if err != nil {
+		return fmt.Errorf("reading kubeconfig: %w", err)
+	}
+	extender, err := s.newCertSANsExtender(kubeConfig)
+	if err != nil {
+		return fmt.Errorf("setting up kubernetes client: %w", err)
+	}
+	if err := extender.ExtendClusterConfigCertSANs(
+		cmd.Context(),
+		stateFile.Infrastructure.ClusterEndpoint,
+		conf.CustomEndpoint,
+		stateFile.Infrastructure.APIServerCertSANs,
+	); err != nil {
+		return fmt.Errorf("extending cert SANs: %w", err)
+	}
+	cmd.Println("API server certificate SANs updated.")
+	return nil
+}
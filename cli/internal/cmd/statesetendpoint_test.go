@@ -0,0 +1,287 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestStateSetEndpoint(t *testing.T) {
+	const kubeConfig = `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: https://192.0.2.1:6443
+  name: constell
+contexts:
+- context:
+    cluster: constell
+    user: admin
+  name: admin@constell
+current-context: admin@constell
+users:
+- name: admin
+  user:
+    token: secret
+`
+	const customKubeConfig = `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: https://203.0.113.9:6443
+  name: custom
+contexts:
+- context:
+    cluster: custom
+    user: admin
+  name: admin@custom
+current-context: admin@custom
+users:
+- name: admin
+  user:
+    token: custom-secret
+`
+
+	testCases := map[string]struct {
+		endpoint              string
+		flags                 stateSetEndpointFlags
+		stdin                 string
+		noKubeConfig          bool
+		stateFile             *state.State
+		extendErr             error
+		wantErr               bool
+		wantEndpoint          string
+		wantInClusterEndpoint string
+		wantServer            string
+		wantSANsRefresh       bool
+		wantKubeConfig        string
+	}{
+		"endpoint updated": {
+			endpoint:              "198.51.100.7",
+			flags:                 stateSetEndpointFlags{yes: true},
+			wantEndpoint:          "198.51.100.7",
+			wantInClusterEndpoint: "198.51.100.7",
+			wantServer:            "https://198.51.100.7:6443",
+		},
+		"DNS name as endpoint": {
+			endpoint:              "api.constellation.example.com",
+			flags:                 stateSetEndpointFlags{yes: true},
+			wantEndpoint:          "api.constellation.example.com",
+			wantInClusterEndpoint: "api.constellation.example.com",
+			wantServer:            "https://api.constellation.example.com:6443",
+		},
+		"separate in-cluster endpoint": {
+			endpoint:              "198.51.100.7",
+			flags:                 stateSetEndpointFlags{yes: true, inClusterEndpoint: "10.9.0.1"},
+			wantEndpoint:          "198.51.100.7",
+			wantInClusterEndpoint: "10.9.0.1",
+			wantServer:            "https://198.51.100.7:6443",
+		},
+		"differing in-cluster endpoint is kept": {
+			endpoint: "198.51.100.7",
+			flags:    stateSetEndpointFlags{yes: true},
+			stateFile: func() *state.State {
+				s := defaultStateFile(cloudprovider.GCP)
+				s.Infrastructure.InClusterEndpoint = "10.9.0.1"
+				return s
+			}(),
+			wantEndpoint:          "198.51.100.7",
+			wantInClusterEndpoint: "10.9.0.1",
+			wantServer:            "https://198.51.100.7:6443",
+		},
+		"update confirmed by user": {
+			endpoint:              "198.51.100.7",
+			stdin:                 "y\n",
+			wantEndpoint:          "198.51.100.7",
+			wantInClusterEndpoint: "198.51.100.7",
+			wantServer:            "https://198.51.100.7:6443",
+		},
+		"update declined by user": {
+			endpoint:              "198.51.100.7",
+			stdin:                 "n\n",
+			wantEndpoint:          "192.0.2.1",
+			wantInClusterEndpoint: "192.0.2.1",
+			wantServer:            "https://192.0.2.1:6443",
+		},
+		"no kubeconfig in workspace": {
+			endpoint:              "198.51.100.7",
+			flags:                 stateSetEndpointFlags{yes: true},
+			noKubeConfig:          true,
+			wantEndpoint:          "198.51.100.7",
+			wantInClusterEndpoint: "198.51.100.7",
+		},
+		"invalid endpoint": {
+			endpoint:              "https://198.51.100.7:6443",
+			flags:                 stateSetEndpointFlags{yes: true},
+			wantErr:               true,
+			wantEndpoint:          "192.0.2.1",
+			wantInClusterEndpoint: "192.0.2.1",
+			wantServer:            "https://192.0.2.1:6443",
+		},
+		"invalid in-cluster endpoint": {
+			endpoint:              "198.51.100.7",
+			flags:                 stateSetEndpointFlags{yes: true, inClusterEndpoint: "not a host"},
+			wantErr:               true,
+			wantEndpoint:          "192.0.2.1",
+			wantInClusterEndpoint: "192.0.2.1",
+			wantServer:            "https://192.0.2.1:6443",
+		},
+		"cluster not created yet": {
+			endpoint: "198.51.100.7",
+			flags:    stateSetEndpointFlags{yes: true},
+			stateFile: func() *state.State {
+				s := defaultStateFile(cloudprovider.GCP)
+				s.Infrastructure.ClusterEndpoint = ""
+				s.Infrastructure.InClusterEndpoint = ""
+				return s
+			}(),
+			wantErr:    true,
+			wantServer: "https://192.0.2.1:6443",
+		},
+		"cert SANs refreshed": {
+			endpoint:              "198.51.100.7",
+			flags:                 stateSetEndpointFlags{yes: true, refreshCertSANs: true},
+			wantEndpoint:          "198.51.100.7",
+			wantInClusterEndpoint: "198.51.100.7",
+			wantServer:            "https://198.51.100.7:6443",
+			wantSANsRefresh:       true,
+		},
+		"cert SANs refreshed for unchanged endpoint": {
+			endpoint:              "192.0.2.1",
+			flags:                 stateSetEndpointFlags{refreshCertSANs: true},
+			wantEndpoint:          "192.0.2.1",
+			wantInClusterEndpoint: "192.0.2.1",
+			wantServer:            "https://192.0.2.1:6443",
+			wantSANsRefresh:       true,
+		},
+		"cert SANs refreshed with kubeconfig from flag": {
+			endpoint: "198.51.100.7",
+			flags: stateSetEndpointFlags{
+				rootFlags:       rootFlags{kubeConfig: "custom-kubeconfig"},
+				yes:             true,
+				refreshCertSANs: true,
+			},
+			wantEndpoint:          "198.51.100.7",
+			wantInClusterEndpoint: "198.51.100.7",
+			wantServer:            "https://198.51.100.7:6443",
+			wantSANsRefresh:       true,
+			wantKubeConfig:        customKubeConfig,
+		},
+		"refreshing cert SANs fails": {
+			endpoint:              "198.51.100.7",
+			flags:                 stateSetEndpointFlags{yes: true, refreshCertSANs: true},
+			extendErr:             errors.New("extend error"),
+			wantErr:               true,
+			wantEndpoint:          "198.51.100.7",
+			wantInClusterEndpoint: "198.51.100.7",
+			wantServer:            "https://198.51.100.7:6443",
+			wantSANsRefresh:       true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			stateFile := tc.stateFile
+			if stateFile == nil {
+				stateFile = defaultStateFile(cloudprovider.GCP)
+			}
+			require.NoError(stateFile.WriteToFile(fileHandler, constants.StateFilename))
+			require.NoError(fileHandler.WriteYAML(constants.ConfigFilename, defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.GCP)))
+			if !tc.noKubeConfig {
+				require.NoError(fileHandler.Write(constants.AdminConfFilename, []byte(kubeConfig)))
+			}
+			if tc.flags.kubeConfig != "" {
+				require.NoError(fileHandler.Write(tc.flags.kubeConfig, []byte(customKubeConfig)))
+			}
+
+			cmd := newStateSetEndpointCmd()
+			cmd.SetContext(context.Background())
+			cmd.SetOut(&bytes.Buffer{})
+			cmd.SetErr(&bytes.Buffer{})
+			cmd.SetIn(bytes.NewBufferString(tc.stdin))
+
+			extender := &stubCertSANsExtender{err: tc.extendErr}
+			var gotKubeConfig []byte
+			s := &stateSetEndpointCmd{
+				log:           logger.NewTest(t),
+				fileHandler:   fileHandler,
+				flags:         tc.flags,
+				configFetcher: stubAttestationFetcher{},
+				newCertSANsExtender: func(kubeConfig []byte) (certSANsExtender, error) {
+					gotKubeConfig = kubeConfig
+					return extender, nil
+				},
+			}
+
+			err := s.setEndpoint(cmd, tc.endpoint)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+
+			gotState, err := state.ReadFromFile(fileHandler, constants.StateFilename)
+			require.NoError(err)
+			assert.Equal(tc.wantEndpoint, gotState.Infrastructure.ClusterEndpoint)
+			assert.Equal(tc.wantInClusterEndpoint, gotState.Infrastructure.InClusterEndpoint)
+
+			if !tc.noKubeConfig {
+				raw, err := fileHandler.Read(constants.AdminConfFilename)
+				require.NoError(err)
+				gotKubeConfig, err := clientcmd.Load(raw)
+				require.NoError(err)
+				assert.Equal(tc.wantServer, gotKubeConfig.Clusters["constell"].Server)
+				assert.Equal("secret", gotKubeConfig.AuthInfos["admin"].Token)
+			}
+
+			if !tc.wantSANsRefresh {
+				assert.Nil(extender.clusterEndpoints)
+				return
+			}
+			assert.Equal([]string{tc.wantEndpoint}, extender.clusterEndpoints)
+			assert.Equal(stateFile.Infrastructure.APIServerCertSANs, extender.sans)
+			wantKubeConfig := []byte(tc.wantKubeConfig)
+			if tc.wantKubeConfig == "" {
+				// the kubeconfig of the workspace, pointing to the new endpoint
+				wantKubeConfig, err = fileHandler.Read(constants.AdminConfFilename)
+				require.NoError(err)
+			}
+			assert.Equal(wantKubeConfig, gotKubeConfig)
+		})
+	}
+}
+
+type stubCertSANsExtender struct {
+	err error
+
+	clusterEndpoints []string
+	sans             []string
+}
+
+func (s *stubCertSANsExtender) ExtendClusterConfigCertSANs(_ context.Context, clusterEndpoint, _ string, additionalAPIServerCertSANs []string) error {
+	s.clusterEndpoints = append(s.clusterEndpoints, clusterEndpoint)
+	s.sans = additionalAPIServerCertSANs
+	return s.err
+}
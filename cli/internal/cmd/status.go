@@ -15,7 +15,6 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/api/attestationconfigapi"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/variant"
 	"github.com/edgelesssys/constellation/v2/internal/config"
-	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/constellation/helm"
 	"github.com/edgelesssys/constellation/v2/internal/constellation/kubecmd"
 	"github.com/edgelesssys/constellation/v2/internal/file"
@@ -46,7 +45,12 @@ func runStatus(cmd *cobra.Command, _ []string) error {
 
 	fileHandler := file.NewHandler(afero.NewOsFs())
 
-	kubeConfig, err := fileHandler.Read(constants.AdminConfFilename)
+	s := statusCmd{log: log, fileHandler: fileHandler}
+	if err := s.flags.parse(cmd.Flags()); err != nil {
+		return err
+	}
+
+	kubeConfig, err := s.flags.readKubeConfig(fileHandler)
 	if err != nil {
 		return fmt.Errorf("reading kubeconfig: %w", err)
 	}
@@ -65,10 +69,6 @@ func runStatus(cmd *cobra.Command, _ []string) error {
 		return fmt.Errorf("setting up kubernetes client: %w", err)
 	}
 
-	s := statusCmd{log: log, fileHandler: fileHandler}
-	if err := s.flags.parse(cmd.Flags()); err != nil {
-		return err
-	}
 	return s.status(cmd, helmVersionGetter, kubeClient, fetcher)
 }
 
@@ -83,7 +83,7 @@ func (s *statusCmd) status(
 	cmd *cobra.Command, getHelmVersions func() (fmt.Stringer, error),
 	kubeClient kubeCmd, fetcher attestationconfigapi.Fetcher,
 ) error {
-	conf, err := config.New(s.fileHandler, constants.ConfigFilename, fetcher, s.flags.force)
+	conf, err := s.flags.loadConfig(cmd.Context(), s.fileHandler, fetcher)
 	var configValidationErr *config.ValidationError
 	if errors.As(err, &configValidationErr) {
 		cmd.PrintErrln(configValidationErr.LongMessage())
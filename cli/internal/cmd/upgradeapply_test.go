@@ -10,6 +10,7 @@ import (
 	"bytes"
 	"context"
 	"testing"
+	"time"
 
 	"github.com/edgelesssys/constellation/v2/cli/internal/cloudcmd"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/variant"
@@ -25,9 +26,11 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/semver"
 	"github.com/edgelesssys/constellation/v2/internal/versions"
 	"github.com/spf13/afero"
+	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	networkingv1 "k8s.io/api/networking/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 )
 
@@ -60,7 +63,14 @@ func TestUpgradeApply(t *testing.T) {
 			fhAssertions: func(require *require.Assertions, assert *assert.Assertions, fh file.Handler) {
 				gotState, err := state.ReadFromFile(fh, constants.StateFilename)
 				require.NoError(err)
-				assert.Equal("v1", gotState.Version)
+				assert.Equal("v2", gotState.Version)
+				// the applied attestation config is recorded in the state file
+				require.NotNil(gotState.Attestation)
+				assert.NotNil(gotState.Attestation.AzureSEVSNP)
+				gotState.Attestation = nil
+				// fingerprints of the successful phases are recorded for --reconcile
+				assert.Contains(gotState.PhaseFingerprints, string(skipHelmPhase))
+				gotState.PhaseFingerprints = nil
 				assert.Equal(defaultStateFile(cloudprovider.Azure), gotState)
 			},
 		},
@@ -125,6 +135,19 @@ func TestUpgradeApply(t *testing.T) {
 			stdin:             "no\n",
 			fh:                fsWithStateFileAndTfState,
 		},
+		"invalid cloud credentials": {
+			kubeUpgrader: &stubKubernetesUpgrader{
+				currentConfig: config.DefaultForAzureSEVSNP(),
+			},
+			helmUpgrader: &mockApplier{}, // mocks ensure that no methods are called
+			terraformUpgrader: &stubTerraformUpgrader{
+				validateCredentialsErr: assert.AnError,
+				terraformDiff:          true,
+			},
+			wantErr: true,
+			flags:   applyFlags{yes: true, skipPhases: skipPhases{skipInitPhase: struct{}{}}},
+			fh:      fsWithStateFileAndTfState,
+		},
 		"plan terraform error": {
 			kubeUpgrader: &stubKubernetesUpgrader{
 				currentConfig: config.DefaultForAzureSEVSNP(),
@@ -285,11 +308,22 @@ type stubKubernetesUpgrader struct {
 	currentConfig                  config.AttestationCfg
 	getClusterAttestationConfigErr error
 	calledNodeUpgrade              bool
+	upgradedNodeImage              semver.Semver
+	upgradedNodeGroups             map[string]semver.Semver
+	nodeGroupVersionErrs           map[string]error
 	calledKubernetesUpgrade        bool
+	drainGracePeriods              []time.Duration
+	drainTimeouts                  []time.Duration
+	setDrainSettingsErr            error
+	nodeVersion                    kubecmd.NodeVersion
+	getNodeVersionErr              error
 	backupCRDsErr                  error
 	backupCRDsCalled               bool
 	backupCRsErr                   error
 	backupCRsCalled                bool
+	appliedNetworkPolicies         []networkingv1.NetworkPolicy
+	applyNetworkPoliciesCalled     bool
+	applyNetworkPoliciesErr        error
 }
 
 func (u *stubKubernetesUpgrader) BackupCRDs(_ context.Context, _ file.Handler, _ string) ([]apiextensionsv1.CustomResourceDefinition, error) {
@@ -302,16 +336,45 @@ func (u *stubKubernetesUpgrader) BackupCRs(_ context.Context, _ file.Handler, _
 	return u.backupCRsErr
 }
 
-func (u *stubKubernetesUpgrader) UpgradeNodeImage(_ context.Context, _ semver.Semver, _ string, _ bool) error {
+func (u *stubKubernetesUpgrader) UpgradeNodeImage(_ context.Context, imageVersion semver.Semver, _ string, _ bool) error {
 	u.calledNodeUpgrade = true
+	u.upgradedNodeImage = imageVersion
 	return u.nodeVersionErr
 }
 
+func (u *stubKubernetesUpgrader) UpgradeNodeGroupImage(_ context.Context, nodeGroup string, imageVersion semver.Semver, _ string, _ bool) error {
+	if u.upgradedNodeGroups == nil {
+		u.upgradedNodeGroups = map[string]semver.Semver{}
+	}
+	u.upgradedNodeGroups[nodeGroup] = imageVersion
+	return u.nodeGroupVersionErrs[nodeGroup]
+}
+
 func (u *stubKubernetesUpgrader) UpgradeKubernetesVersion(_ context.Context, _ versions.ValidK8sVersion, _ bool) error {
 	u.calledKubernetesUpgrade = true
 	return u.kubernetesVersionErr
 }
 
+func (u *stubKubernetesUpgrader) SetDrainSettings(_ context.Context, gracePeriod, timeout time.Duration) error {
+	u.drainGracePeriods = append(u.drainGracePeriods, gracePeriod)
+	u.drainTimeouts = append(u.drainTimeouts, timeout)
+	return u.setDrainSettingsErr
+}
+
+func (u *stubKubernetesUpgrader) GetConstellationVersion(_ context.Context) (kubecmd.NodeVersion, error) {
+	return u.nodeVersion, u.getNodeVersionErr
+}
+
+func (u *stubKubernetesUpgrader) ApplyNetworkPolicies(_ context.Context, policies []networkingv1.NetworkPolicy) error {
+	u.applyNetworkPoliciesCalled = true
+	u.appliedNetworkPolicies = policies
+	return u.applyNetworkPoliciesErr
+}
+
+func (u *stubKubernetesUpgrader) PrePullImages(_ context.Context, _ []string) error {
+	return nil
+}
+
 func (u *stubKubernetesUpgrader) ApplyJoinConfig(_ context.Context, _ config.AttestationCfg, _ []byte) error {
 	return nil
 }
@@ -325,10 +388,15 @@ func (u *stubKubernetesUpgrader) ExtendClusterConfigCertSANs(_ context.Context,
 }
 
 type stubTerraformUpgrader struct {
-	terraformDiff        bool
-	planTerraformErr     error
-	applyTerraformErr    error
-	rollbackWorkspaceErr error
+	terraformDiff          bool
+	validateCredentialsErr error
+	planTerraformErr       error
+	applyTerraformErr      error
+	rollbackWorkspaceErr   error
+}
+
+func (u stubTerraformUpgrader) ValidateCredentials(_ context.Context, _ *config.Config) error {
+	return u.validateCredentialsErr
 }
 
 func (u stubTerraformUpgrader) Plan(_ context.Context, _ *config.Config) (bool, error) {
@@ -347,10 +415,17 @@ func (u stubTerraformUpgrader) WorkingDirIsEmpty() (bool, error) {
 	return false, nil
 }
 
+func (u stubTerraformUpgrader) Adopt(_ []cloudcmd.AdoptableResource) {}
+
 type mockTerraformUpgrader struct {
 	mock.Mock
 }
 
+func (m *mockTerraformUpgrader) ValidateCredentials(ctx context.Context, conf *config.Config) error {
+	args := m.Called(ctx, conf)
+	return args.Error(0)
+}
+
 func (m *mockTerraformUpgrader) Plan(ctx context.Context, conf *config.Config) (bool, error) {
 	args := m.Called(ctx, conf)
 	return args.Bool(0), args.Error(1)
@@ -371,6 +446,10 @@ func (m *mockTerraformUpgrader) WorkingDirIsEmpty() (bool, error) {
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *mockTerraformUpgrader) Adopt(resources []cloudcmd.AdoptableResource) {
+	m.Called(resources)
+}
+
 type mockApplier struct {
 	mock.Mock
 }
@@ -401,3 +480,32 @@ func (f *stubImageFetcher) FetchReference(_ context.Context,
 ) (string, error) {
 	return f.reference, f.fetchReferenceErr
 }
+
+func TestUpgradeApplyFlags(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	flags := newUpgradeApplyCmd().Flags()
+	// Register persistent flags
+	flags.String("workspace", "", "")
+	flags.String("tf-log", "NONE", "")
+	flags.String("profile", "", "")
+	flags.String("config", "", "")
+	flags.String("kubeconfig", "", "")
+	flags.Bool("force", false, "")
+	flags.Bool("debug", false, "")
+
+	// The alias must accept every flag of apply, since both run the same code.
+	NewApplyCmd().Flags().VisitAll(func(flag *pflag.Flag) {
+		assert.NotNil(flags.Lookup(flag.Name), "flag %q isn't registered for upgrade apply", flag.Name)
+	})
+
+	require.NoError(flags.Parse([]string{"--yes", "--helm-history-max=3", "--skip-phases=infrastructure", "--drain-grace-period=30s", "--drain-timeout=1h"}))
+	var parsed applyFlags
+	require.NoError(parsed.parse(flags))
+	assert.True(parsed.yes)
+	assert.Equal(3, parsed.helmHistoryMax)
+	assert.Equal(skipPhases{skipInfrastructurePhase: struct{}{}}, parsed.skipPhases)
+	assert.Equal(30*time.Second, parsed.drainGracePeriod)
+	assert.Equal(time.Hour, parsed.drainTimeout)
+}
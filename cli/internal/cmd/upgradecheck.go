@@ -74,6 +74,11 @@ func (f *upgradeCheckFlags) parse(flags *pflag.FlagSet) error {
 		return fmt.Errorf("getting 'update-config' flag: %w", err)
 	}
 	f.updateConfig = updateConfig
+	if f.updateConfig {
+		if err := f.requireConfigFile(); err != nil {
+			return err
+		}
+	}
 
 	f.ref, err = flags.GetString("ref")
 	if err != nil {
@@ -115,7 +120,7 @@ func runUpgradeCheck(cmd *cobra.Command, _ []string) error {
 	}
 	defer cleanUp()
 
-	kubeConfig, err := fileHandler.Read(constants.AdminConfFilename)
+	kubeConfig, err := flags.readKubeConfig(fileHandler)
 	if err != nil {
 		return fmt.Errorf("reading kubeconfig: %w", err)
 	}
@@ -170,7 +175,7 @@ type upgradeCheckCmd struct {
 
 // upgradePlan plans an upgrade of a Constellation cluster.
 func (u *upgradeCheckCmd) upgradeCheck(cmd *cobra.Command, fetcher attestationconfigapi.Fetcher) error {
-	conf, err := config.New(u.fileHandler, constants.ConfigFilename, fetcher, u.flags.force)
+	conf, err := u.flags.loadConfig(cmd.Context(), u.fileHandler, fetcher)
 	var configValidationErr *config.ValidationError
 	if errors.As(err, &configValidationErr) {
 		cmd.PrintErrln(configValidationErr.LongMessage())
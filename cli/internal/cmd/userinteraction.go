@@ -23,6 +23,7 @@ var ErrInvalidInput = errors.New("user made invalid input")
 func askToConfirm(cmd *cobra.Command, question string) (bool, error) {
 	reader := bufio.NewReader(cmd.InOrStdin())
 	cmd.Printf("%s [y/n]: ", question)
+	showPrompt(cmd)
 	for i := 0; i < 3; i++ {
 		resp, err := reader.ReadString('\n')
 		if err != nil {
@@ -36,6 +37,15 @@ func askToConfirm(cmd *cobra.Command, question string) (bool, error) {
 			return true, nil
 		}
 		cmd.Printf("Type 'y' or 'yes' to confirm, or abort action with 'n' or 'no': ")
+		showPrompt(cmd)
 	}
 	return false, ErrInvalidInput
 }
+
+// showPrompt makes sure a prompt written to the output of cmd is visible,
+// even if the output is held back, e.g., by apply --quiet.
+func showPrompt(cmd *cobra.Command) {
+	if out, ok := cmd.OutOrStdout().(*heldBackWriter); ok {
+		_ = out.showPrompt()
+	}
+}
@@ -11,18 +11,26 @@ import (
 	"context"
 	"crypto/sha256"
 	"crypto/sha512"
+	"crypto/tls"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
+	"maps"
 	"net"
+	"net/http"
+	"os"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/edgelesssys/constellation/v2/internal/api/attestationconfigapi"
 	"github.com/edgelesssys/constellation/v2/internal/atls"
+	"github.com/edgelesssys/constellation/v2/internal/attestation"
 	azuretdx "github.com/edgelesssys/constellation/v2/internal/attestation/azure/tdx"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/choose"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/measurements"
@@ -38,6 +46,7 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/verify"
 	"github.com/edgelesssys/constellation/v2/verify/verifyproto"
 
+	snpabi "github.com/google/go-sev-guest/abi"
 	"github.com/google/go-sev-guest/proto/sevsnp"
 	"github.com/google/go-tdx-guest/abi"
 	"github.com/google/go-tdx-guest/proto/tdx"
@@ -49,6 +58,9 @@ import (
 	"google.golang.org/grpc"
 )
 
+// prodProfile is the config profile of production clusters, for which verify enables strict checks by default.
+const prodProfile = "prod"
+
 // NewVerifyCmd returns a new cobra.Command for the verify command.
 func NewVerifyCmd() *cobra.Command {
 	cmd := &cobra.Command{
@@ -60,17 +72,109 @@ func NewVerifyCmd() *cobra.Command {
 		RunE: runVerify,
 	}
 	cmd.Flags().String("cluster-id", "", "expected cluster identifier")
-	cmd.Flags().StringP("output", "o", "", "print the attestation document in the output format {json|raw}")
+	cmd.Flags().StringP("output", "o", "", "print the attestation document in the output format {json|raw}, or the verification result in the output format {sarif}")
 	cmd.Flags().StringP("node-endpoint", "e", "", "endpoint of the node to verify, passed as HOST[:PORT]")
+	cmd.Flags().StringSlice("endpoints", nil, "candidate endpoints of the cluster for regional failover, passed as HOST[:PORT]\n"+
+		"The endpoints are tried in the given order and the first one that responds is verified")
+	cmd.Flags().StringSlice("require-chip-id", nil, "hex-encoded chip ID the node's SEV-SNP attestation report must contain\n"+
+		"Can be specified multiple times to allow any of the given chips")
+	cmd.Flags().Bool("require-no-debug", false, "reject SEV-SNP attestation reports whose guest policy allows debugging the guest\n"+
+		"Enabled by default with --profile "+prodProfile+". Use --require-no-debug=false to disable it for development clusters.")
+	cmd.Flags().Uint32("expected-report-version", 0, "reject SEV-SNP attestation reports whose format has a different version\n"+
+		"If not set, reports of any version supported by the CLI are accepted.")
+	cmd.Flags().String("attestation-config-out", "", "write the attestation config used for verification to the given file")
+	cmd.Flags().Bool("tcb-report", false, "print the TCB versions of the node's SEV-SNP attestation report and compare them to the configured minimums")
+	cmd.Flags().StringSlice("pcr", nil, "override the expected value of a PCR, passed as INDEX=HEX, e.g. 4=<64 hex characters>\n"+
+		"Overridden PCRs are enforced. Can be specified multiple times")
+	cmd.Flags().String("kernel-cmdline", "", "expected kernel command line of the node, checked against the measurement of the kernel command line in PCR 9")
+	cmd.Flags().String("initrd-digest", "", "hex-encoded SHA-256 digest of the node's initrd, which is measured into PCR 9 together with the kernel command line")
+	cmd.Flags().String("client-cert", "", "path to a PEM encoded client certificate to authenticate to node endpoints that require mutual TLS")
+	cmd.Flags().String("client-key", "", "path to the PEM encoded private key of the client certificate")
+	cmd.Flags().String("node-ca-cert", "", "path to a PEM encoded CA certificate to verify the TLS certificate of the node endpoint\n"+
+		"If not set, the certificate isn't verified, since the node is authenticated by its attestation")
+	cmd.Flags().String("sni", "", "server name to send in the TLS handshake with the node endpoint, for reverse proxies that route connections by SNI\n"+
+		"Defaults to the host of the endpoint. Requires --client-cert, since the attestation is otherwise requested without TLS")
+	cmd.Flags().Bool("allow-tcb-recovery", false, "accept SEV-SNP reports whose TCB versions are below the configured minimums while the node's firmware is being updated to the published versions\n"+
+		"Only use this after a security errata. Verification fails if the node's TCB versions already meet the minimums")
+	cmd.Flags().Duration("max-clock-skew", defaultMaxClockSkew, "maximum difference between the local clock and the time of "+constants.CDNRepositoryURL+" before verifying the attestation\n"+
+		"Verification fails above this value, since certificate validity and report freshness checks depend on the local clock. Set to 0 to skip the check")
+	cmd.Flags().Duration("max-report-age", 0, "reject attestation reports that may be older than this duration, to defend against replayed reports\n"+
+		"The age of a report is bounded by the time between sending the nonce challenge to the node and receiving its report. Set to 0 to skip the check")
+	cmd.Flags().String("report-format", reportFormatText, "format of the verification result {text|junit}\n"+
+		"With junit, a JUnit XML report with a test case for the node is written to stdout instead of the attestation document")
+	cmd.Flags().String("evidence-out", "", "write the evidence of a successful verification as signed in-toto attestation to the given file\n"+
+		"The attestation is a DSSE envelope whose subject is the SHA-256 digest of the node's attestation document")
+	cmd.Flags().String("evidence-signing-key", "", "path to the encrypted cosign private key the evidence is signed with\n"+
+		"The password of the key is read from the environment variable "+envVarCosignPassword)
+	cmd.Flags().Bool("explain", false, "print each verification step with its outcome and the values involved to stderr")
+	cmd.Flags().Bool("fail-on-warning", false, "fail the verification if any warning is printed, e.g., for measurements with warnOnly set that don't match\n"+
+		"The attestation is still checked completely, and all warnings are listed in the error.")
+	cmd.Flags().String("transport", reportTransportGRPC, "how the attestation is obtained from the node {grpc|http|file}\n"+
+		"With grpc, it's requested from the verification service of the node. With http, it's requested with a GET request to the URL passed as endpoint,\n"+
+		"with the hex encoded nonce as query parameter \"nonce\". With file, a saved attestation is read from the path passed as endpoint")
+	cmd.Flags().Bool("continuous", false, "verify the node repeatedly until the command is interrupted, e.g., to monitor a cluster\n"+
+		"Failed verifications are printed and don't stop the command")
+	cmd.Flags().Duration("interval", defaultVerifyInterval, "time between two verifications with --continuous")
+	cmd.Flags().String("metrics-addr", "", "serve Prometheus metrics of the verifications with --continuous on the given address, passed as [HOST]:PORT\n"+
+		"The metrics are served at the path /metrics")
+	cmd.MarkFlagsRequiredTogether("client-cert", "client-key")
+	cmd.MarkFlagsRequiredTogether("evidence-out", "evidence-signing-key")
+	cmd.MarkFlagsRequiredTogether("kernel-cmdline", "initrd-digest")
+	cmd.MarkFlagsMutuallyExclusive("node-endpoint", "endpoints")
+
+	cmd.AddCommand(newVerifyBatchCmd())
 	return cmd
 }
 
 type verifyFlags struct {
 	rootFlags
-	endpoint  string
+	endpoint string
+	// endpoints are candidate endpoints that are tried in order until one responds.
+	endpoints []string
 	ownerID   string
 	clusterID string
 	output    string
+	chipIDs   [][]byte
+	// requireNoDebug rejects SEV-SNP reports whose guest policy allows debugging.
+	requireNoDebug bool
+	// expectedReportVersion is the required version of the SEV-SNP report format. 0 accepts any version.
+	expectedReportVersion uint32
+	// attestationConfigOut is the path the effective attestation config is written to.
+	attestationConfigOut string
+	tcbReport            bool
+	// pcrOverrides are expected PCR values that replace the values of the config.
+	pcrOverrides map[uint32][]byte
+	// kernelCmdline is the expected kernel command line. If it is empty, the kernel command line isn't checked.
+	kernelCmdline string
+	initrdDigest  []byte
+	// clientCert and clientKey are the paths of the client certificate used for mutual TLS.
+	// If they are empty, the attestation is requested over an unencrypted connection.
+	clientCert string
+	clientKey  string
+	nodeCACert string
+	// sni is the server name sent in the TLS handshake. If it is empty, the host of the endpoint is used.
+	sni string
+	// allowTCBRecovery accepts nodes whose TCB update after a security errata is still in progress.
+	allowTCBRecovery bool
+	reportFormat     string
+	// maxClockSkew is the maximum skew of the local clock. If it is 0, the clock isn't checked.
+	maxClockSkew time.Duration
+	// maxReportAge is the maximum age of the attestation report. If it is 0, the age isn't checked.
+	maxReportAge time.Duration
+	// evidenceOut is the path the signed in-toto attestation of a successful verification is written to.
+	evidenceOut        string
+	evidenceSigningKey string
+	// explain prints the steps of the verification.
+	explain bool
+	// failOnWarning fails a verification that printed warnings.
+	failOnWarning bool
+	// transport is how the attestation is obtained from the node.
+	transport string
+	// continuous verifies the node every interval until the command is interrupted.
+	continuous bool
+	interval   time.Duration
+	// metricsAddr is the address the metrics of continuous verification are served on. If it is empty, no metrics are served.
+	metricsAddr string
 }
 
 func (f *verifyFlags) parse(flags *pflag.FlagSet) error {
@@ -87,17 +191,228 @@ func (f *verifyFlags) parse(flags *pflag.FlagSet) error {
 	if err != nil {
 		return fmt.Errorf("getting 'node-endpoint' flag: %w", err)
 	}
+	f.endpoints, err = flags.GetStringSlice("endpoints")
+	if err != nil {
+		return fmt.Errorf("getting 'endpoints' flag: %w", err)
+	}
 	f.clusterID, err = flags.GetString("cluster-id")
 	if err != nil {
 		return fmt.Errorf("getting 'cluster-id' flag: %w", err)
 	}
+	chipIDs, err := flags.GetStringSlice("require-chip-id")
+	if err != nil {
+		return fmt.Errorf("getting 'require-chip-id' flag: %w", err)
+	}
+	f.chipIDs, err = parseChipIDs(chipIDs)
+	if err != nil {
+		return fmt.Errorf("parsing 'require-chip-id' flag: %w", err)
+	}
+	f.requireNoDebug, err = flags.GetBool("require-no-debug")
+	if err != nil {
+		return fmt.Errorf("getting 'require-no-debug' flag: %w", err)
+	}
+	if f.profile == prodProfile && !flags.Changed("require-no-debug") {
+		f.requireNoDebug = true
+	}
+	f.expectedReportVersion, err = flags.GetUint32("expected-report-version")
+	if err != nil {
+		return fmt.Errorf("getting 'expected-report-version' flag: %w", err)
+	}
+	f.attestationConfigOut, err = flags.GetString("attestation-config-out")
+	if err != nil {
+		return fmt.Errorf("getting 'attestation-config-out' flag: %w", err)
+	}
+	f.tcbReport, err = flags.GetBool("tcb-report")
+	if err != nil {
+		return fmt.Errorf("getting 'tcb-report' flag: %w", err)
+	}
+	pcrs, err := flags.GetStringSlice("pcr")
+	if err != nil {
+		return fmt.Errorf("getting 'pcr' flag: %w", err)
+	}
+	f.pcrOverrides, err = parsePCROverrides(pcrs)
+	if err != nil {
+		return fmt.Errorf("parsing 'pcr' flag: %w", err)
+	}
+	f.kernelCmdline, err = flags.GetString("kernel-cmdline")
+	if err != nil {
+		return fmt.Errorf("getting 'kernel-cmdline' flag: %w", err)
+	}
+	initrdDigest, err := flags.GetString("initrd-digest")
+	if err != nil {
+		return fmt.Errorf("getting 'initrd-digest' flag: %w", err)
+	}
+	if initrdDigest != "" {
+		f.initrdDigest, err = hex.DecodeString(strings.TrimPrefix(initrdDigest, "0x"))
+		if err != nil {
+			return fmt.Errorf("decoding 'initrd-digest' flag: %w", err)
+		}
+		if len(f.initrdDigest) != sha256.Size {
+			return fmt.Errorf("flag 'initrd-digest' has length %d, expected %d bytes", len(f.initrdDigest), sha256.Size)
+		}
+	}
+	f.clientCert, err = flags.GetString("client-cert")
+	if err != nil {
+		return fmt.Errorf("getting 'client-cert' flag: %w", err)
+	}
+	f.clientKey, err = flags.GetString("client-key")
+	if err != nil {
+		return fmt.Errorf("getting 'client-key' flag: %w", err)
+	}
+	f.nodeCACert, err = flags.GetString("node-ca-cert")
+	if err != nil {
+		return fmt.Errorf("getting 'node-ca-cert' flag: %w", err)
+	}
+	f.allowTCBRecovery, err = flags.GetBool("allow-tcb-recovery")
+	if err != nil {
+		return fmt.Errorf("getting 'allow-tcb-recovery' flag: %w", err)
+	}
+	if f.nodeCACert != "" && f.clientCert == "" {
+		return errors.New("flag 'node-ca-cert' requires 'client-cert' and 'client-key'")
+	}
+	f.sni, err = flags.GetString("sni")
+	if err != nil {
+		return fmt.Errorf("getting 'sni' flag: %w", err)
+	}
+	if f.sni != "" && f.clientCert == "" {
+		return errors.New("flag 'sni' requires 'client-cert' and 'client-key'")
+	}
+	f.maxClockSkew, err = flags.GetDuration("max-clock-skew")
+	if err != nil {
+		return fmt.Errorf("getting 'max-clock-skew' flag: %w", err)
+	}
+	if f.maxClockSkew < 0 {
+		return errors.New("flag 'max-clock-skew' must not be negative")
+	}
+	f.maxReportAge, err = flags.GetDuration("max-report-age")
+	if err != nil {
+		return fmt.Errorf("getting 'max-report-age' flag: %w", err)
+	}
+	if f.maxReportAge < 0 {
+		return errors.New("flag 'max-report-age' must not be negative")
+	}
+	f.evidenceOut, err = flags.GetString("evidence-out")
+	if err != nil {
+		return fmt.Errorf("getting 'evidence-out' flag: %w", err)
+	}
+	f.evidenceSigningKey, err = flags.GetString("evidence-signing-key")
+	if err != nil {
+		return fmt.Errorf("getting 'evidence-signing-key' flag: %w", err)
+	}
+	f.explain, err = flags.GetBool("explain")
+	if err != nil {
+		return fmt.Errorf("getting 'explain' flag: %w", err)
+	}
+	f.failOnWarning, err = flags.GetBool("fail-on-warning")
+	if err != nil {
+		return fmt.Errorf("getting 'fail-on-warning' flag: %w", err)
+	}
+	reportFormat, err := flags.GetString("report-format")
+	if err != nil {
+		return fmt.Errorf("getting 'report-format' flag: %w", err)
+	}
+	f.reportFormat, err = parseReportFormat(reportFormat)
+	if err != nil {
+		return err
+	}
+	if f.reportFormat == reportFormatJUnit && f.output != "" {
+		return errors.New("flag 'report-format' junit can't be combined with 'output'")
+	}
+	transport, err := flags.GetString("transport")
+	if err != nil {
+		return fmt.Errorf("getting 'transport' flag: %w", err)
+	}
+	f.transport, err = parseReportTransport(transport)
+	if err != nil {
+		return err
+	}
+	if f.transport != reportTransportGRPC && (f.clientCert != "" || f.sni != "") {
+		return fmt.Errorf("flags 'client-cert' and 'sni' can only be used with transport %s", reportTransportGRPC)
+	}
+	if f.transport == reportTransportFile && f.maxReportAge > 0 {
+		return errors.New("flag 'max-report-age' can't be used with transport file, since the age of a saved attestation is unknown")
+	}
+	if f.transport == reportTransportFile && f.evidenceOut != "" {
+		return errors.New("flag 'evidence-out' can't be used with transport file, since a saved attestation isn't bound to the nonce of the verification")
+	}
+	f.continuous, err = flags.GetBool("continuous")
+	if err != nil {
+		return fmt.Errorf("getting 'continuous' flag: %w", err)
+	}
+	f.interval, err = flags.GetDuration("interval")
+	if err != nil {
+		return fmt.Errorf("getting 'interval' flag: %w", err)
+	}
+	if f.interval <= 0 {
+		return errors.New("flag 'interval' must be positive")
+	}
+	f.metricsAddr, err = flags.GetString("metrics-addr")
+	if err != nil {
+		return fmt.Errorf("getting 'metrics-addr' flag: %w", err)
+	}
+	if !f.continuous && (flags.Changed("interval") || f.metricsAddr != "") {
+		return errors.New("flags 'interval' and 'metrics-addr' can only be used with 'continuous'")
+	}
 	return nil
 }
 
+// parseChipIDs decodes the hex-encoded chip IDs and checks their length.
+func parseChipIDs(encoded []string) ([][]byte, error) {
+	var chipIDs [][]byte
+	for _, id := range encoded {
+		chipID, err := hex.DecodeString(strings.TrimPrefix(id, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("decoding chip ID %q: %w", id, err)
+		}
+		if len(chipID) != snpabi.ChipIDSize {
+			return nil, fmt.Errorf("chip ID %q has length %d, expected %d bytes", id, len(chipID), snpabi.ChipIDSize)
+		}
+		chipIDs = append(chipIDs, chipID)
+	}
+	return chipIDs, nil
+}
+
+// pcrCount is the number of PCRs of a TPM.
+const pcrCount = 24
+
+// parsePCROverrides decodes expected PCR values passed as INDEX=HEX and checks their index and length.
+func parsePCROverrides(encoded []string) (map[uint32][]byte, error) {
+	if len(encoded) == 0 {
+		return nil, nil
+	}
+	overrides := make(map[uint32][]byte, len(encoded))
+	for _, override := range encoded {
+		rawIdx, rawValue, ok := strings.Cut(override, "=")
+		if !ok {
+			return nil, fmt.Errorf("PCR override %q is not of the form INDEX=HEX", override)
+		}
+		idx, err := strconv.ParseUint(rawIdx, 10, 32)
+		if err != nil || idx >= pcrCount {
+			return nil, fmt.Errorf("PCR index %q is not in the range 0-%d", rawIdx, pcrCount-1)
+		}
+		value, err := hex.DecodeString(strings.TrimPrefix(rawValue, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("decoding value of PCR %d: %w", idx, err)
+		}
+		if len(value) != measurements.PCRMeasurementLength {
+			return nil, fmt.Errorf("value of PCR %d has length %d, expected %d bytes", idx, len(value), measurements.PCRMeasurementLength)
+		}
+		if _, ok := overrides[uint32(idx)]; ok {
+			return nil, fmt.Errorf("PCR %d is overridden more than once", idx)
+		}
+		overrides[uint32(idx)] = value
+	}
+	return overrides, nil
+}
+
 type verifyCmd struct {
 	fileHandler file.Handler
 	flags       verifyFlags
-	log         debugLog
+	// signingKeyPassword is the password of the key the evidence is signed with.
+	signingKeyPassword []byte
+	log                debugLog
+	// warnings are the warnings printed during the verification.
+	warnings verifyWarnings
 }
 
 func runVerify(cmd *cobra.Command, _ []string) error {
@@ -107,45 +422,91 @@ func runVerify(cmd *cobra.Command, _ []string) error {
 	}
 
 	fileHandler := file.NewHandler(afero.NewOsFs())
-	verifyClient := &constellationVerifier{
-		dialer: dialer.New(nil, nil, &net.Dialer{}),
-		log:    log,
-	}
-
 	v := &verifyCmd{
-		fileHandler: fileHandler,
-		log:         log,
+		fileHandler:        fileHandler,
+		signingKeyPassword: []byte(os.Getenv(envVarCosignPassword)),
+		log:                log,
 	}
 	if err := v.flags.parse(cmd.Flags()); err != nil {
 		return err
 	}
-	v.log.Debug("Using flags", "clusterID", v.flags.clusterID, "endpoint", v.flags.endpoint, "ownerID", v.flags.ownerID)
+	v.log.Debug("Using flags", "clusterID", v.flags.clusterID, "endpoint", v.flags.endpoint, "endpoints", v.flags.endpoints, "ownerID", v.flags.ownerID)
+
+	var reportSource reportFetcher
+	switch v.flags.transport {
+	case reportTransportHTTP:
+		reportSource = &httpReportFetcher{client: &http.Client{Timeout: time.Minute}, log: log}
+	case reportTransportFile:
+		reportSource = &fileReportFetcher{fileHandler: fileHandler}
+	default:
+		tlsConfig, err := loadMutualTLSConfig(fileHandler, v.flags.clientCert, v.flags.clientKey, v.flags.nodeCACert)
+		if err != nil {
+			return err
+		}
+		reportSource = &grpcReportFetcher{
+			dialer:     dialer.New(nil, nil, &net.Dialer{}),
+			tlsConfig:  tlsConfig,
+			serverName: v.flags.sni,
+			log:        log,
+		}
+	}
+	verifyClient := &constellationVerifier{
+		fetcher:      reportSource,
+		maxReportAge: v.flags.maxReportAge,
+		now:          time.Now,
+		log:          log,
+	}
+
+	if v.flags.maxClockSkew > 0 {
+		timeSource := &httpDateTimeSource{client: &http.Client{Timeout: 10 * time.Second}, url: constants.CDNRepositoryURL}
+		if err := checkClockSkew(cmd, log, &v.warnings, timeSource, time.Now, v.flags.maxClockSkew); err != nil {
+			return err
+		}
+	}
 
 	fetcher := attestationconfigapi.NewFetcher()
-	return v.verify(cmd, verifyClient, fetcher)
-}
+	if !v.flags.continuous {
+		return v.verify(cmd, verifyClient, fetcher)
+	}
 
-func (c *verifyCmd) verify(cmd *cobra.Command, verifyClient verifyClient, configFetcher attestationconfigapi.Fetcher) error {
-	c.log.Debug(fmt.Sprintf("Loading configuration file from %q", c.flags.pathPrefixer.PrefixPrintablePath(constants.ConfigFilename)))
-	conf, err := config.New(c.fileHandler, constants.ConfigFilename, configFetcher, c.flags.force)
-	var configValidationErr *config.ValidationError
-	if errors.As(err, &configValidationErr) {
-		cmd.PrintErrln(configValidationErr.LongMessage())
+	metrics := newVerifyMetrics()
+	if v.flags.metricsAddr != "" {
+		stopServer, err := serveVerifyMetrics(v.flags.metricsAddr, metrics, log)
+		if err != nil {
+			return err
+		}
+		defer stopServer()
+		cmd.PrintErrf("Serving metrics on http://%s/metrics\n", v.flags.metricsAddr)
 	}
-	if err != nil {
-		return fmt.Errorf("loading config file: %w", err)
+	// Warnings of the clock skew check apply to every verification.
+	initialWarnings := slices.Clone(v.warnings.messages)
+	verifyOnce := func() error {
+		v.warnings = verifyWarnings{messages: slices.Clone(initialWarnings)}
+		return v.verify(cmd, verifyClient, fetcher)
 	}
+	return verifyContinuously(cmd, verifyOnce, v.flags.interval, metrics, time.Now)
+}
 
+func (c *verifyCmd) verify(cmd *cobra.Command, verifyClient verifyClient, configFetcher attestationconfigapi.Fetcher) error {
 	stateFile, err := state.ReadFromFile(c.fileHandler, constants.StateFilename)
 	if err != nil {
 		stateFile = state.New() // A state file is only required if the user has not provided IP or ID flags
 	}
 
+	conf, err := c.loadConfig(cmd, stateFile, configFetcher)
+	if err != nil {
+		return err
+	}
+
+	if c.flags.transport == reportTransportFile {
+		c.warnings.warn(cmd, "WARNING: Verifying a saved attestation. Its freshness can't be checked, since it isn't bound to a nonce chosen for this verification.")
+	}
+
 	ownerID, clusterID, err := c.validateIDFlags(cmd, stateFile)
 	if err != nil {
 		return err
 	}
-	endpoint, err := c.validateEndpointFlag(cmd, stateFile)
+	endpoints, err := c.validateEndpointFlags(cmd, stateFile)
 	if err != nil {
 		return err
 	}
@@ -162,11 +523,88 @@ func (c *verifyCmd) verify(cmd *cobra.Command, verifyClient verifyClient, config
 		return fmt.Errorf("updating expected PCRs: %w", err)
 	}
 
+	if len(c.flags.pcrOverrides) > 0 {
+		if attConfig.GetVariant().Equal(variant.QEMUTDX{}) {
+			return fmt.Errorf("--pcr is only supported for vTPM based attestation variants, got %s", attConfig.GetVariant())
+		}
+		c.log.Debug("Overriding expected PCRs", "pcrs", slices.Sorted(maps.Keys(c.flags.pcrOverrides)))
+		applyPCROverrides(attConfig.GetMeasurements(), c.flags.pcrOverrides)
+	}
+
+	if c.flags.kernelCmdline != "" && attConfig.GetVariant().Equal(variant.QEMUTDX{}) {
+		return fmt.Errorf("--kernel-cmdline is only supported for vTPM based attestation variants, got %s", attConfig.GetVariant())
+	}
+
+	if len(c.flags.chipIDs) > 0 && !isSNPVariant(attConfig.GetVariant()) {
+		return fmt.Errorf("--require-chip-id is only supported for SEV-SNP attestation variants, got %s", attConfig.GetVariant())
+	}
+	if c.flags.requireNoDebug && !isSNPVariant(attConfig.GetVariant()) {
+		return fmt.Errorf("--require-no-debug is only supported for SEV-SNP attestation variants, got %s", attConfig.GetVariant())
+	}
+	if c.flags.expectedReportVersion != 0 && !isSNPVariant(attConfig.GetVariant()) {
+		return fmt.Errorf("--expected-report-version is only supported for SEV-SNP attestation variants, got %s", attConfig.GetVariant())
+	}
+	if c.flags.tcbReport && !isSNPVariant(attConfig.GetVariant()) {
+		return fmt.Errorf("--tcb-report is only supported for SEV-SNP attestation variants, got %s", attConfig.GetVariant())
+	}
+	if c.flags.tcbReport && c.flags.output == "sarif" {
+		return errors.New("--tcb-report can't be combined with --output sarif")
+	}
+	if c.flags.tcbReport && c.flags.reportFormat == reportFormatJUnit {
+		return errors.New("--tcb-report can't be combined with --report-format junit")
+	}
+
+	// The config is exported before contacting the node, so it is also available if verification fails.
+	if c.flags.attestationConfigOut != "" {
+		c.log.Debug(fmt.Sprintf("Writing attestation config to %q", c.flags.attestationConfigOut))
+		if err := c.fileHandler.WriteYAML(c.flags.attestationConfigOut, attConfig, file.OptOverwrite); err != nil {
+			return fmt.Errorf("writing attestation config: %w", err)
+		}
+	}
+
+	// In recovery mode, the report is validated without minimum TCB versions.
+	// Its TCB versions are checked against the versions published for the variant after the report was retrieved.
+	validatorConfig := attConfig
+	var recoveryTarget *verify.TCBVersion
+	if c.flags.allowTCBRecovery {
+		validatorConfig, err = withoutTCBMinimums(attConfig)
+		if err != nil {
+			return err
+		}
+		target, err := tcbRecoveryTarget(cmd.Context(), configFetcher, attConfig)
+		if err != nil {
+			return err
+		}
+		recoveryTarget = &target
+	}
+
 	c.log.Debug(fmt.Sprintf("Creating aTLS Validator for %q", conf.GetAttestationConfig().GetVariant()))
-	validator, err := choose.Validator(attConfig, warnLogger{cmd: cmd, log: c.log})
+	var validatorLog attestation.Logger = recordingWarnLog{warnLog: warnLogger{cmd: cmd, log: c.log}, warnings: &c.warnings}
+	// The verification steps are always recorded, since the output includes values only known to the validator.
+	tracer := &traceLogger{warnLog: validatorLog}
+	validatorLog = tracer
+	validator, err := choose.Validator(validatorConfig, validatorLog)
 	if err != nil {
 		return fmt.Errorf("creating aTLS validator: %w", err)
 	}
+	if c.flags.kernelCmdline != "" {
+		cmdlineValidator, ok := validator.(kernelCmdlineValidator)
+		if !ok {
+			return fmt.Errorf("validator for %s can't check the kernel command line", attConfig.GetVariant())
+		}
+		if err := cmdlineValidator.SetExpectedKernelCmdline(c.flags.kernelCmdline, c.flags.initrdDigest); err != nil {
+			return fmt.Errorf("setting expected kernel command line: %w", err)
+		}
+	}
+
+	// The signing key is read before contacting the node, so a missing key doesn't waste a verification.
+	var signingKey []byte
+	if c.flags.evidenceSigningKey != "" {
+		signingKey, err = c.fileHandler.Read(c.flags.evidenceSigningKey)
+		if err != nil {
+			return fmt.Errorf("reading evidence signing key: %w", err)
+		}
+	}
 
 	nonce, err := crypto.GenerateRandomBytes(32)
 	if err != nil {
@@ -174,22 +612,53 @@ func (c *verifyCmd) verify(cmd *cobra.Command, verifyClient verifyClient, config
 	}
 	c.log.Debug(fmt.Sprintf("Generated random nonce: %x", nonce))
 
-	rawAttestationDoc, err := verifyClient.Verify(
-		cmd.Context(),
-		endpoint,
-		&verifyproto.GetAttestationRequest{
-			Nonce: nonce,
-		},
-		validator,
-	)
+	start := time.Now()
+	endpoint, rawAttestationDoc, err := c.verifyEndpoints(cmd, verifyClient, endpoints, nonce, validator, attConfig, recoveryTarget)
+	// The trace is written to stderr, so the output on stdout can still be processed, and also if verification failed.
+	if c.flags.explain {
+		if err := writeTrace(cmd.ErrOrStderr(), tracer.steps); err != nil {
+			return fmt.Errorf("printing verification steps: %w", err)
+		}
+	}
+	if err == nil && c.flags.failOnWarning {
+		err = c.warnings.err()
+	}
+	if err == nil && c.flags.evidenceOut != "" {
+		evidence := verificationEvidence{
+			endpoint:           endpoint,
+			clusterID:          clusterID,
+			attestationVariant: attConfig.GetVariant().String(),
+			nonce:              nonce,
+			measurements:       attConfig.GetMeasurements(),
+			rawAttestationDoc:  rawAttestationDoc,
+			verifiedAt:         time.Now(),
+		}
+		if err := c.writeEvidence(cmd.Context(), evidence, signingKey); err != nil {
+			return err
+		}
+	}
+	if c.flags.reportFormat == reportFormatJUnit {
+		if err := writeJUnit(cmd.OutOrStdout(), "verify", []verifyResult{{name: endpoint, duration: time.Since(start), err: err}}); err != nil {
+			return err
+		}
+		cmd.PrintErrln("Verification OK")
+		return nil
+	}
+	if c.flags.output == "sarif" {
+		if err := writeSARIF(cmd.OutOrStdout(), endpoint, err); err != nil {
+			return err
+		}
+		cmd.PrintErrln("Verification OK")
+		return nil
+	}
 	if err != nil {
-		return fmt.Errorf("verifying: %w", err)
+		return err
 	}
 
 	var attDocOutput string
 	switch c.flags.output {
 	case "json":
-		attDocOutput, err = formatJSON(cmd.Context(), rawAttestationDoc, attConfig, c.log)
+		attDocOutput, err = formatJSON(cmd.Context(), rawAttestationDoc, attConfig, vcekSourceFromTrace(tracer.steps), c.log)
 		if err != nil {
 			return fmt.Errorf("printing attestation document: %w", err)
 		}
@@ -198,7 +667,7 @@ func (c *verifyCmd) verify(cmd *cobra.Command, verifyClient verifyClient, config
 		attDocOutput = fmt.Sprintf("Attestation Document:\n%s\n", rawAttestationDoc)
 
 	case "":
-		attDocOutput, err = formatDefault(cmd.Context(), rawAttestationDoc, attConfig, c.log)
+		attDocOutput, err = formatDefault(cmd.Context(), rawAttestationDoc, attConfig, vcekSourceFromTrace(tracer.steps), c.log)
 		if err != nil {
 			return fmt.Errorf("printing attestation document: %w", err)
 		}
@@ -208,11 +677,112 @@ func (c *verifyCmd) verify(cmd *cobra.Command, verifyClient verifyClient, config
 	}
 
 	cmd.Println(attDocOutput)
+	if c.flags.tcbReport {
+		tcbReport, err := formatTCBReport(rawAttestationDoc, attConfig)
+		if err != nil {
+			return fmt.Errorf("printing TCB report: %w", err)
+		}
+		cmd.Println(tcbReport)
+	}
 	cmd.PrintErrln("Verification OK")
 
 	return nil
 }
 
+// writeEvidence writes the evidence of a successful verification as signed in-toto attestation to the evidence file.
+func (c *verifyCmd) writeEvidence(ctx context.Context, evidence verificationEvidence, signingKey []byte) error {
+	envelope, err := signVerificationEvidence(ctx, evidence, signingKey, c.signingKeyPassword)
+	if err != nil {
+		return fmt.Errorf("creating verification evidence: %w", err)
+	}
+	c.log.Debug(fmt.Sprintf("Writing verification evidence to %q", c.flags.evidenceOut))
+	if err := c.fileHandler.Write(c.flags.evidenceOut, envelope, file.OptOverwrite); err != nil {
+		return fmt.Errorf("writing verification evidence: %w", err)
+	}
+	return nil
+}
+
+// verifyNode retrieves the attestation document of the node at endpoint, verifies it,
+// and runs the additional checks requested by the flags.
+// If recoveryTarget is set, the node must be recovering from a TCB update to the target versions.
+func (c *verifyCmd) verifyNode(
+	cmd *cobra.Command, verifyClient verifyClient, endpoint string, nonce []byte, validator atls.Validator, attConfig config.AttestationCfg,
+	recoveryTarget *verify.TCBVersion,
+) ([]byte, error) {
+	rawAttestationDoc, err := verifyClient.Verify(
+		cmd.Context(),
+		endpoint,
+		&verifyproto.GetAttestationRequest{
+			Nonce: nonce,
+		},
+		validator,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("verifying: %w", err)
+	}
+
+	if len(c.flags.chipIDs) > 0 {
+		if err := verifyChipID(rawAttestationDoc, attConfig.GetVariant(), c.flags.chipIDs); err != nil {
+			return nil, &verifyFailure{ruleID: sarifRuleChipIDMismatch, err: err}
+		}
+		c.log.Debug("Chip ID of the attestation report matches an allowed chip ID")
+	}
+	if c.flags.requireNoDebug {
+		if err := verifyNoDebug(rawAttestationDoc, attConfig.GetVariant()); err != nil {
+			return nil, &verifyFailure{ruleID: sarifRuleDebugEnabled, err: err}
+		}
+		c.log.Debug("Guest policy of the attestation report doesn't allow debugging")
+	}
+	if c.flags.expectedReportVersion != 0 {
+		if err := verifyReportVersion(rawAttestationDoc, attConfig.GetVariant(), c.flags.expectedReportVersion); err != nil {
+			return nil, &verifyFailure{ruleID: sarifRuleReportVersionMismatch, err: err}
+		}
+		c.log.Debug("Format version of the attestation report matches the expected version")
+	}
+	if len(c.flags.pcrOverrides) > 0 {
+		if err := verifyPCROverrides(rawAttestationDoc, attConfig.GetVariant(), c.flags.pcrOverrides); err != nil {
+			return nil, &verifyFailure{ruleID: sarifRuleMeasurementMismatch, err: err}
+		}
+		c.log.Debug("PCRs of the attestation document match the overridden values")
+	}
+	if c.flags.kernelCmdline != "" {
+		if err := verifyKernelCmdline(rawAttestationDoc, attConfig.GetVariant(), c.flags.kernelCmdline, c.flags.initrdDigest); err != nil {
+			return nil, &verifyFailure{ruleID: sarifRuleMeasurementMismatch, err: err}
+		}
+		c.log.Debug("Kernel command line PCR of the attestation document matches the expected kernel command line")
+	}
+	if recoveryTarget != nil {
+		report, err := verifyTCBRecovery(rawAttestationDoc, attConfig, *recoveryTarget)
+		if err != nil {
+			return nil, &verifyFailure{ruleID: sarifRuleTCBTooOld, err: err}
+		}
+		c.log.Debug("Accepted SEV-SNP report of a node recovering from a TCB update", "reportedTCB", report.ReportedTCB, "currentTCB", report.CurrentTCB, "committedTCB", report.CommittedTCB)
+		c.warnings.warn(cmd, "WARNING: --allow-tcb-recovery is set. The node's reported TCB versions are below the configured minimums.")
+		cmd.PrintErrln("WARNING: The node was accepted because its firmware is being updated to the published versions. Verify the node again without --allow-tcb-recovery once the update is committed.")
+	}
+	return rawAttestationDoc, nil
+}
+
+// loadConfig loads the config file, or the config stored in the cluster if --config is set.
+// If there is no config file, the attestation config recorded in the state file is used instead.
+func (c *verifyCmd) loadConfig(cmd *cobra.Command, stateFile *state.State, configFetcher attestationconfigapi.Fetcher) (*config.Config, error) {
+	if _, err := c.fileHandler.Stat(constants.ConfigFilename); errors.Is(err, fs.ErrNotExist) && stateFile.Attestation != nil && c.flags.configRef == nil {
+		cmd.PrintErrf("No config file found, using attestation config from %q.\n", c.flags.pathPrefixer.PrefixPrintablePath(constants.StateFilename))
+		return &config.Config{Attestation: *stateFile.Attestation}, nil
+	}
+
+	c.log.Debug(fmt.Sprintf("Loading configuration file from %q", c.flags.pathPrefixer.PrefixPrintablePath(constants.ConfigFilename)))
+	conf, err := c.flags.loadConfig(cmd.Context(), c.fileHandler, configFetcher)
+	var configValidationErr *config.ValidationError
+	if errors.As(err, &configValidationErr) {
+		cmd.PrintErrln(configValidationErr.LongMessage())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading config file: %w", err)
+	}
+	return conf, nil
+}
+
 func (c *verifyCmd) validateIDFlags(cmd *cobra.Command, stateFile *state.State) (ownerID, clusterID string, err error) {
 	ownerID, clusterID = c.flags.ownerID, c.flags.clusterID
 	if c.flags.clusterID == "" {
@@ -233,21 +803,194 @@ func (c *verifyCmd) validateIDFlags(cmd *cobra.Command, stateFile *state.State)
 	return ownerID, clusterID, nil
 }
 
-func (c *verifyCmd) validateEndpointFlag(cmd *cobra.Command, stateFile *state.State) (string, error) {
-	endpoint := c.flags.endpoint
-	if endpoint == "" {
-		cmd.PrintErrf("Using endpoint from %q. Specify --node-endpoint to override this.\n", c.flags.pathPrefixer.PrefixPrintablePath(constants.StateFilename))
-		endpoint = stateFile.Infrastructure.ClusterEndpoint
+// validateEndpointFlags returns the endpoints to verify in the order they are tried.
+// The endpoints of the http and file transports are URLs and paths, which must be passed explicitly.
+func (c *verifyCmd) validateEndpointFlags(cmd *cobra.Command, stateFile *state.State) ([]string, error) {
+	endpoints := c.flags.endpoints
+	if c.flags.transport != "" && c.flags.transport != reportTransportGRPC {
+		if len(endpoints) == 0 && c.flags.endpoint == "" {
+			return nil, fmt.Errorf("transport %s requires --node-endpoint or --endpoints", c.flags.transport)
+		}
+		if len(endpoints) == 0 {
+			endpoints = []string{c.flags.endpoint}
+		}
+		return endpoints, nil
+	}
+	if len(endpoints) == 0 {
+		endpoint := c.flags.endpoint
+		if endpoint == "" {
+			cmd.PrintErrf("Using endpoint from %q. Specify --node-endpoint to override this.\n", c.flags.pathPrefixer.PrefixPrintablePath(constants.StateFilename))
+			endpoint = stateFile.Infrastructure.ClusterEndpoint
+		}
+		endpoints = []string{endpoint}
+	}
+
+	validated := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		endpoint, err := addPortIfMissing(endpoint, constants.VerifyServiceNodePortGRPC)
+		if err != nil {
+			return nil, fmt.Errorf("validating endpoint argument: %w", err)
+		}
+		validated = append(validated, endpoint)
+	}
+	return validated, nil
+}
+
+// verifyChipID checks that the SEV-SNP report in the attestation document was generated by one of the allowed chips.
+func verifyChipID(rawAttestationDoc []byte, attestationVariant variant.Variant, allowedChipIDs [][]byte) error {
+	doc, err := unmarshalAttDoc(rawAttestationDoc, attestationVariant)
+	if err != nil {
+		return fmt.Errorf("unmarshalling attestation document: %w", err)
+	}
+	var instanceInfo snp.InstanceInfo
+	if err := json.Unmarshal(doc.InstanceInfo, &instanceInfo); err != nil {
+		return fmt.Errorf("unmarshalling instance info: %w", err)
+	}
+	chipID, err := verify.ChipID(instanceInfo.AttestationReport)
+	if err != nil {
+		return fmt.Errorf("getting chip ID from SNP report: %w", err)
+	}
+
+	for _, allowed := range allowedChipIDs {
+		if bytes.Equal(chipID, allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("chip ID %x of the attestation report does not match any of the required chip IDs", chipID)
+}
+
+// verifyNoDebug checks that the guest policy of the SEV-SNP report in the attestation document doesn't allow debugging the guest.
+func verifyNoDebug(rawAttestationDoc []byte, attestationVariant variant.Variant) error {
+	doc, err := unmarshalAttDoc(rawAttestationDoc, attestationVariant)
+	if err != nil {
+		return fmt.Errorf("unmarshalling attestation document: %w", err)
+	}
+	var instanceInfo snp.InstanceInfo
+	if err := json.Unmarshal(doc.InstanceInfo, &instanceInfo); err != nil {
+		return fmt.Errorf("unmarshalling instance info: %w", err)
 	}
-	endpoint, err := addPortIfMissing(endpoint, constants.VerifyServiceNodePortGRPC)
+	debug, err := verify.DebugEnabled(instanceInfo.AttestationReport)
 	if err != nil {
-		return "", fmt.Errorf("validating endpoint argument: %w", err)
+		return fmt.Errorf("getting guest policy from SNP report: %w", err)
+	}
+	if debug {
+		return errors.New("the guest policy of the attestation report allows debugging the guest, the host can decrypt its memory")
+	}
+	return nil
+}
+
+// verifyReportVersion checks that the SEV-SNP report in the attestation document has the expected format version.
+func verifyReportVersion(rawAttestationDoc []byte, attestationVariant variant.Variant, expected uint32) error {
+	doc, err := unmarshalAttDoc(rawAttestationDoc, attestationVariant)
+	if err != nil {
+		return fmt.Errorf("unmarshalling attestation document: %w", err)
+	}
+	var instanceInfo snp.InstanceInfo
+	if err := json.Unmarshal(doc.InstanceInfo, &instanceInfo); err != nil {
+		return fmt.Errorf("unmarshalling instance info: %w", err)
+	}
+	version, err := verify.ReportVersion(instanceInfo.AttestationReport)
+	if err != nil {
+		return fmt.Errorf("getting version from SNP report: %w", err)
+	}
+	if version != expected {
+		return fmt.Errorf("the attestation report has format version %d, but version %d is expected", version, expected)
+	}
+	return nil
+}
+
+// applyPCROverrides replaces the expected values of the given PCRs and enforces them.
+func applyPCROverrides(m measurements.M, overrides map[uint32][]byte) {
+	for idx, value := range overrides {
+		m[idx] = measurements.Measurement{Expected: value, ValidationOpt: measurements.Enforce}
 	}
-	return endpoint, nil
+}
+
+// vcekSourceFromTrace returns the source of the VCEK certificate the last attestation report was verified with,
+// as recorded in the verification steps, or an empty source if the validator doesn't record it.
+func vcekSourceFromTrace(steps []attestation.TraceStep) snp.VCEKSource {
+	for i := len(steps) - 1; i >= 0; i-- {
+		if steps[i].Name != snp.VCEKSourceTraceStep || steps[i].Err != nil {
+			continue
+		}
+		for j := 0; j+1 < len(steps[i].Values); j += 2 {
+			if source, ok := steps[i].Values[j+1].(snp.VCEKSource); ok && steps[i].Values[j] == snp.VCEKSourceTraceKey {
+				return source
+			}
+		}
+	}
+	return ""
+}
+
+// kernelCmdlineValidator is a validator that can check the kernel command line measured into the vTPM.
+type kernelCmdlineValidator interface {
+	SetExpectedKernelCmdline(cmdline string, initrdDigest []byte) error
+}
+
+// verifyKernelCmdline checks that the kernel command line PCR quoted in the attestation document reflects the expected kernel command line.
+func verifyKernelCmdline(rawAttestationDoc []byte, attestationVariant variant.Variant, cmdline string, initrdDigest []byte) error {
+	doc, err := unmarshalAttDoc(rawAttestationDoc, attestationVariant)
+	if err != nil {
+		return fmt.Errorf("unmarshalling attestation document: %w", err)
+	}
+	quoteIdx, err := vtpm.GetSHA256QuoteIndex(doc.Attestation.Quotes)
+	if err != nil {
+		return fmt.Errorf("get SHA256 quote index: %w", err)
+	}
+	return vtpm.ValidateKernelCmdline(doc.Attestation.Quotes[quoteIdx].Pcrs.Pcrs, cmdline, initrdDigest)
+}
+
+// verifyPCROverrides checks that the PCRs quoted in the attestation document match the overridden values.
+func verifyPCROverrides(rawAttestationDoc []byte, attestationVariant variant.Variant, overrides map[uint32][]byte) error {
+	doc, err := unmarshalAttDoc(rawAttestationDoc, attestationVariant)
+	if err != nil {
+		return fmt.Errorf("unmarshalling attestation document: %w", err)
+	}
+	quoteIdx, err := vtpm.GetSHA256QuoteIndex(doc.Attestation.Quotes)
+	if err != nil {
+		return fmt.Errorf("get SHA256 quote index: %w", err)
+	}
+	actualPCRs := doc.Attestation.Quotes[quoteIdx].Pcrs.Pcrs
+
+	var errs []error
+	for _, idx := range slices.Sorted(maps.Keys(overrides)) {
+		actual, ok := actualPCRs[idx]
+		if !ok {
+			errs = append(errs, fmt.Errorf("PCR %d not found in quote", idx))
+			continue
+		}
+		if !bytes.Equal(actual, overrides[idx]) {
+			errs = append(errs, fmt.Errorf("PCR %d has value %x, expected %x", idx, actual, overrides[idx]))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// formatTCBReport returns the TCB versions of the SNP report in the attestation document
+// compared to the minimum versions of the attestation config.
+func formatTCBReport(rawAttestationDoc []byte, attestationCfg config.AttestationCfg) (string, error) {
+	doc, err := unmarshalAttDoc(rawAttestationDoc, attestationCfg.GetVariant())
+	if err != nil {
+		return "", fmt.Errorf("unmarshalling attestation document: %w", err)
+	}
+	var instanceInfo snp.InstanceInfo
+	if err := json.Unmarshal(doc.InstanceInfo, &instanceInfo); err != nil {
+		return "", fmt.Errorf("unmarshalling instance info: %w", err)
+	}
+	report, err := verify.NewTCBReport(instanceInfo.AttestationReport, attestationCfg)
+	if err != nil {
+		return "", fmt.Errorf("parsing SNP report: %w", err)
+	}
+	return report.FormatString(&strings.Builder{}), nil
+}
+
+// isSNPVariant returns true if the attestation variant is based on an SEV-SNP report.
+func isSNPVariant(v variant.Variant) bool {
+	return v.Equal(variant.AzureSEVSNP{}) || v.Equal(variant.AWSSEVSNP{}) || v.Equal(variant.GCPSEVSNP{})
 }
 
 // formatJSON returns the json formatted attestation doc.
-func formatJSON(ctx context.Context, docString []byte, attestationCfg config.AttestationCfg, log debugLog,
+func formatJSON(ctx context.Context, docString []byte, attestationCfg config.AttestationCfg, vcekSource snp.VCEKSource, log debugLog,
 ) (string, error) {
 	doc, err := unmarshalAttDoc(docString, attestationCfg.GetVariant())
 	if err != nil {
@@ -256,7 +999,7 @@ func formatJSON(ctx context.Context, docString []byte, attestationCfg config.Att
 
 	switch attestationCfg.GetVariant() {
 	case variant.AWSSEVSNP{}, variant.AzureSEVSNP{}, variant.GCPSEVSNP{}:
-		return snpFormatJSON(ctx, doc.InstanceInfo, attestationCfg, log)
+		return snpFormatJSON(ctx, doc.InstanceInfo, attestationCfg, vcekSource, log)
 	case variant.AzureTDX{}:
 		return tdxFormatJSON(doc.InstanceInfo, attestationCfg)
 	default:
@@ -264,7 +1007,7 @@ func formatJSON(ctx context.Context, docString []byte, attestationCfg config.Att
 	}
 }
 
-func snpFormatJSON(ctx context.Context, instanceInfoRaw []byte, attestationCfg config.AttestationCfg, log debugLog,
+func snpFormatJSON(ctx context.Context, instanceInfoRaw []byte, attestationCfg config.AttestationCfg, vcekSource snp.VCEKSource, log debugLog,
 ) (string, error) {
 	var instanceInfo snp.InstanceInfo
 	if err := json.Unmarshal(instanceInfoRaw, &instanceInfo); err != nil {
@@ -274,6 +1017,7 @@ func snpFormatJSON(ctx context.Context, instanceInfoRaw []byte, attestationCfg c
 	if err != nil {
 		return "", fmt.Errorf("parsing SNP report: %w", err)
 	}
+	report.VCEKSource = vcekSource
 
 	jsonBytes, err := json.Marshal(report)
 	return string(jsonBytes), err
@@ -304,7 +1048,7 @@ func tdxFormatJSON(instanceInfoRaw []byte, attestationCfg config.AttestationCfg)
 }
 
 // format returns the formatted attestation doc.
-func formatDefault(ctx context.Context, docString []byte, attestationCfg config.AttestationCfg, log debugLog,
+func formatDefault(ctx context.Context, docString []byte, attestationCfg config.AttestationCfg, vcekSource snp.VCEKSource, log debugLog,
 ) (string, error) {
 	b := &strings.Builder{}
 	b.WriteString("Attestation Document:\n")
@@ -319,9 +1063,7 @@ func formatDefault(ctx context.Context, docString []byte, attestationCfg config.
 	}
 
 	// If we have a non SNP variant, print only the PCRs
-	if !(attestationCfg.GetVariant().Equal(variant.AzureSEVSNP{}) ||
-		attestationCfg.GetVariant().Equal(variant.AWSSEVSNP{}) ||
-		attestationCfg.GetVariant().Equal(variant.GCPSEVSNP{})) {
+	if !isSNPVariant(attestationCfg.GetVariant()) {
 		return b.String(), nil
 	}
 
@@ -335,6 +1077,7 @@ func formatDefault(ctx context.Context, docString []byte, attestationCfg config.
 	if err != nil {
 		return "", fmt.Errorf("parsing SNP report: %w", err)
 	}
+	report.VCEKSource = vcekSource
 
 	return report.FormatString(b)
 }
@@ -368,31 +1111,36 @@ func parseQuotes(b *strings.Builder, quotes []*tpmProto.Quote, expectedPCRs meas
 }
 
 type constellationVerifier struct {
-	dialer grpcInsecureDialer
-	log    debugLog
+	// fetcher obtains the attestation document of the node.
+	fetcher reportFetcher
+	// maxReportAge is the maximum time between sending the nonce challenge and receiving the attestation report.
+	// If it's 0, the age of the report isn't checked.
+	maxReportAge time.Duration
+	now          func() time.Time
+	log          debugLog
 }
 
 // Verify retrieves an attestation statement from the Constellation and verifies it using the validator.
+// The attestation is validated independently of the transport it was retrieved with.
 func (v *constellationVerifier) Verify(
 	ctx context.Context, endpoint string, req *verifyproto.GetAttestationRequest, validator atls.Validator,
 ) ([]byte, error) {
-	v.log.Debug(fmt.Sprintf("Dialing endpoint: %q", endpoint))
-	conn, err := v.dialer.DialInsecure(endpoint)
-	if err != nil {
-		return nil, fmt.Errorf("dialing init server: %w", err)
+	var requested time.Time
+	if v.maxReportAge > 0 {
+		requested = v.now()
 	}
-	defer conn.Close()
-
-	client := verifyproto.NewAPIClient(conn)
-
-	v.log.Debug("Sending attestation request")
-	resp, err := client.GetAttestation(ctx, req)
+	attestation, nonce, err := v.fetcher.FetchReport(ctx, endpoint, req.Nonce)
 	if err != nil {
-		return nil, fmt.Errorf("getting attestation: %w", err)
+		return nil, err
+	}
+	if v.maxReportAge > 0 {
+		if err := checkReportAge(requested, v.now(), v.maxReportAge); err != nil {
+			return nil, &verifyFailure{ruleID: sarifRuleStaleReport, err: err}
+		}
 	}
 
 	v.log.Debug("Verifying attestation")
-	signedData, err := validator.Validate(ctx, resp.Attestation, req.Nonce)
+	signedData, err := validator.Validate(ctx, attestation, nonce)
 	if err != nil {
 		return nil, fmt.Errorf("validating attestation: %w", err)
 	}
@@ -401,15 +1149,16 @@ func (v *constellationVerifier) Verify(
 		return nil, errors.New("signed data in attestation does not match expected user data")
 	}
 
-	return resp.Attestation, nil
+	return attestation, nil
 }
 
 type verifyClient interface {
 	Verify(ctx context.Context, endpoint string, req *verifyproto.GetAttestationRequest, validator atls.Validator) ([]byte, error)
 }
 
-type grpcInsecureDialer interface {
+type grpcVerifyDialer interface {
 	DialInsecure(endpoint string) (conn *grpc.ClientConn, err error)
+	DialTLS(endpoint string, tlsConfig *tls.Config) (conn *grpc.ClientConn, err error)
 }
 
 // writeIndentfln writes a formatted string to the builder with the given indentation level
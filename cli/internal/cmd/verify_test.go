@@ -14,14 +14,20 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/edgelesssys/constellation/v2/internal/atls"
+	"github.com/edgelesssys/constellation/v2/internal/attestation"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/measurements"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/snp"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/snp/testdata"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/variant"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/vtpm"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
 	"github.com/edgelesssys/constellation/v2/internal/config"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
@@ -30,7 +36,11 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/grpc/dialer"
 	"github.com/edgelesssys/constellation/v2/internal/grpc/testdialer"
 	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/edgelesssys/constellation/v2/internal/verify"
 	"github.com/edgelesssys/constellation/v2/verify/verifyproto"
+	snpabi "github.com/google/go-sev-guest/abi"
+	"github.com/google/go-sev-guest/kds"
+	"github.com/google/go-tpm-tools/proto/attest"
 	tpmProto "github.com/google/go-tpm-tools/proto/tpm"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
@@ -220,6 +230,761 @@ func TestVerify(t *testing.T) {
 	}
 }
 
+func TestVerifyRequireChipID(t *testing.T) {
+	zeroBase64 := base64.StdEncoding.EncodeToString([]byte("00000000000000000000000000000000"))
+
+	// the embedded report is followed by certificates, which are not part of the report
+	report := testdata.AttestationReport[:snpabi.ReportSize]
+	reportChipID, err := verify.ChipID(report)
+	require.NoError(t, err)
+	otherChipID := bytes.Repeat([]byte{0xab}, len(reportChipID))
+
+	instanceInfo, err := json.Marshal(snp.InstanceInfo{AttestationReport: report})
+	require.NoError(t, err)
+	attDoc, err := json.Marshal(vtpm.AttestationDocument{
+		Attestation:  &attest.Attestation{},
+		InstanceInfo: instanceInfo,
+	})
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		provider cloudprovider.Provider
+		chipIDs  [][]byte
+		wantErr  bool
+	}{
+		"matching chip ID": {
+			provider: cloudprovider.Azure,
+			chipIDs:  [][]byte{reportChipID},
+		},
+		"matching chip ID in allow-list": {
+			provider: cloudprovider.Azure,
+			chipIDs:  [][]byte{otherChipID, reportChipID},
+		},
+		"non-matching chip ID": {
+			provider: cloudprovider.Azure,
+			chipIDs:  [][]byte{otherChipID},
+			wantErr:  true,
+		},
+		"non-SNP variant": {
+			provider: cloudprovider.QEMU,
+			chipIDs:  [][]byte{reportChipID},
+			wantErr:  true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			cmd := NewVerifyCmd()
+			out := &bytes.Buffer{}
+			cmd.SetErr(out)
+			cmd.SetOut(&bytes.Buffer{})
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			cfg := defaultConfigWithExpectedMeasurements(t, config.Default(), tc.provider)
+			require.NoError(fileHandler.WriteYAML(constants.ConfigFilename, cfg))
+
+			v := &verifyCmd{
+				fileHandler: fileHandler,
+				log:         logger.NewTest(t),
+				flags: verifyFlags{
+					clusterID: zeroBase64,
+					endpoint:  "192.0.2.1:1234",
+					output:    "raw",
+					chipIDs:   tc.chipIDs,
+				},
+			}
+			err := v.verify(cmd, &stubVerifyClient{attestationDoc: attDoc}, stubAttestationFetcher{})
+			if tc.wantErr {
+				assert.Error(err)
+				assert.NotContains(out.String(), "OK")
+				return
+			}
+			assert.NoError(err)
+			assert.Contains(out.String(), "OK")
+		})
+	}
+}
+
+func TestVerifyRequireNoDebug(t *testing.T) {
+	zeroBase64 := base64.StdEncoding.EncodeToString([]byte("00000000000000000000000000000000"))
+
+	attDocWithPolicy := func(debug bool) []byte {
+		// the embedded report is followed by certificates, which are not part of the report
+		report := bytes.Clone(testdata.AttestationReport[:snpabi.ReportSize])
+		// the guest policy is a little-endian uint64 at offset 0x08, debugging is allowed by bit 19
+		if debug {
+			report[0x0A] |= 0x08
+		} else {
+			report[0x0A] &^= 0x08
+		}
+		instanceInfo, err := json.Marshal(snp.InstanceInfo{AttestationReport: report})
+		require.NoError(t, err)
+		attDoc, err := json.Marshal(vtpm.AttestationDocument{
+			Attestation:  &attest.Attestation{},
+			InstanceInfo: instanceInfo,
+		})
+		require.NoError(t, err)
+		return attDoc
+	}
+
+	testCases := map[string]struct {
+		provider       cloudprovider.Provider
+		requireNoDebug bool
+		debug          bool
+		wantErr        bool
+	}{
+		"debug disabled": {
+			provider:       cloudprovider.Azure,
+			requireNoDebug: true,
+		},
+		"debug enabled": {
+			provider:       cloudprovider.Azure,
+			requireNoDebug: true,
+			debug:          true,
+			wantErr:        true,
+		},
+		"debug enabled without check": {
+			provider: cloudprovider.Azure,
+			debug:    true,
+		},
+		"non-SNP variant": {
+			provider:       cloudprovider.QEMU,
+			requireNoDebug: true,
+			wantErr:        true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			cmd := NewVerifyCmd()
+			out := &bytes.Buffer{}
+			cmd.SetErr(out)
+			cmd.SetOut(&bytes.Buffer{})
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			cfg := defaultConfigWithExpectedMeasurements(t, config.Default(), tc.provider)
+			require.NoError(fileHandler.WriteYAML(constants.ConfigFilename, cfg))
+
+			v := &verifyCmd{
+				fileHandler: fileHandler,
+				log:         logger.NewTest(t),
+				flags: verifyFlags{
+					clusterID:      zeroBase64,
+					endpoint:       "192.0.2.1:1234",
+					output:         "raw",
+					requireNoDebug: tc.requireNoDebug,
+				},
+			}
+			err := v.verify(cmd, &stubVerifyClient{attestationDoc: attDocWithPolicy(tc.debug)}, stubAttestationFetcher{})
+			if tc.wantErr {
+				assert.Error(err)
+				assert.NotContains(out.String(), "OK")
+				return
+			}
+			assert.NoError(err)
+			assert.Contains(out.String(), "OK")
+		})
+	}
+}
+
+func TestParseVerifyFlagsRequireNoDebug(t *testing.T) {
+	testCases := map[string]struct {
+		profile            string
+		requireNoDebug     string
+		wantRequireNoDebug bool
+	}{
+		"default": {},
+		"flag set": {
+			requireNoDebug:     "true",
+			wantRequireNoDebug: true,
+		},
+		"prod profile": {
+			profile:            prodProfile,
+			wantRequireNoDebug: true,
+		},
+		"prod profile with check disabled": {
+			profile:        prodProfile,
+			requireNoDebug: "false",
+		},
+		"other profile": {
+			profile: "dev",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			flags := NewVerifyCmd().Flags()
+			// Register persistent flags
+			flags.String("workspace", "", "")
+			flags.String("tf-log", "NONE", "")
+			flags.String("profile", "", "")
+			flags.String("config", "", "")
+			flags.String("kubeconfig", "", "")
+			flags.Bool("force", false, "")
+			flags.Bool("debug", false, "")
+			require.NoError(flags.Set("profile", tc.profile))
+			if tc.requireNoDebug != "" {
+				require.NoError(flags.Set("require-no-debug", tc.requireNoDebug))
+			}
+
+			var f verifyFlags
+			require.NoError(f.parse(flags))
+			assert.Equal(tc.wantRequireNoDebug, f.requireNoDebug)
+		})
+	}
+}
+
+func TestVerifyExpectedReportVersion(t *testing.T) {
+	zeroBase64 := base64.StdEncoding.EncodeToString([]byte("00000000000000000000000000000000"))
+
+	// the embedded report is followed by certificates, which are not part of the report
+	report := testdata.AttestationReport[:snpabi.ReportSize]
+	reportVersion, err := verify.ReportVersion(report)
+	require.NoError(t, err)
+	instanceInfo, err := json.Marshal(snp.InstanceInfo{AttestationReport: report})
+	require.NoError(t, err)
+	attDoc, err := json.Marshal(vtpm.AttestationDocument{
+		Attestation:  &attest.Attestation{},
+		InstanceInfo: instanceInfo,
+	})
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		provider        cloudprovider.Provider
+		expectedVersion uint32
+		wantErr         bool
+	}{
+		"expected version": {
+			provider:        cloudprovider.Azure,
+			expectedVersion: reportVersion,
+		},
+		"other version": {
+			provider:        cloudprovider.Azure,
+			expectedVersion: reportVersion + 1,
+			wantErr:         true,
+		},
+		"no version pinned": {
+			provider: cloudprovider.Azure,
+		},
+		"non-SNP variant": {
+			provider:        cloudprovider.QEMU,
+			expectedVersion: reportVersion,
+			wantErr:         true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			cmd := NewVerifyCmd()
+			out := &bytes.Buffer{}
+			cmd.SetErr(out)
+			cmd.SetOut(&bytes.Buffer{})
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			cfg := defaultConfigWithExpectedMeasurements(t, config.Default(), tc.provider)
+			require.NoError(fileHandler.WriteYAML(constants.ConfigFilename, cfg))
+
+			v := &verifyCmd{
+				fileHandler: fileHandler,
+				log:         logger.NewTest(t),
+				flags: verifyFlags{
+					clusterID:             zeroBase64,
+					endpoint:              "192.0.2.1:1234",
+					output:                "raw",
+					expectedReportVersion: tc.expectedVersion,
+				},
+			}
+			err := v.verify(cmd, &stubVerifyClient{attestationDoc: attDoc}, stubAttestationFetcher{})
+			if tc.wantErr {
+				assert.Error(err)
+				assert.NotContains(out.String(), "OK")
+				return
+			}
+			assert.NoError(err)
+			assert.Contains(out.String(), "OK")
+		})
+	}
+}
+
+func TestVerifyTCBReport(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	report := testdata.AttestationReport[:snpabi.ReportSize]
+	instanceInfo, err := json.Marshal(snp.InstanceInfo{AttestationReport: report})
+	require.NoError(err)
+	attDoc, err := json.Marshal(vtpm.AttestationDocument{
+		Attestation:  &attest.Attestation{},
+		InstanceInfo: instanceInfo,
+	})
+	require.NoError(err)
+
+	cmd := NewVerifyCmd()
+	out := &bytes.Buffer{}
+	cmd.SetOut(out)
+	cmd.SetErr(&bytes.Buffer{})
+	fileHandler := file.NewHandler(afero.NewMemMapFs())
+	cfg := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.Azure)
+	require.NoError(fileHandler.WriteYAML(constants.ConfigFilename, cfg))
+
+	v := &verifyCmd{
+		fileHandler: fileHandler,
+		log:         logger.NewTest(t),
+		flags: verifyFlags{
+			clusterID: base64.StdEncoding.EncodeToString([]byte("00000000000000000000000000000000")),
+			endpoint:  "192.0.2.1:1234",
+			output:    "raw",
+			tcbReport: true,
+		},
+	}
+	require.NoError(v.verify(cmd, &stubVerifyClient{attestationDoc: attDoc}, stubAttestationFetcher{}))
+
+	parsed, err := snpabi.ReportToProto(report)
+	require.NoError(err)
+	reported := kds.DecomposeTCBVersion(kds.TCBVersion(parsed.ReportedTcb))
+	launch := kds.DecomposeTCBVersion(kds.TCBVersion(parsed.LaunchTcb))
+	// the minimums are the "latest" versions returned by the fetcher
+	for _, want := range []struct {
+		name                      string
+		reported, launch, minimum uint8
+	}{
+		{"Secure Processor bootloader SVN", reported.BlSpl, launch.BlSpl, testCfg.Bootloader},
+		{"Secure Processor operating system SVN", reported.TeeSpl, launch.TeeSpl, testCfg.TEE},
+		{"SEV-SNP firmware SVN", reported.SnpSpl, launch.SnpSpl, testCfg.SNP},
+		{"Microcode SVN", reported.UcodeSpl, launch.UcodeSpl, testCfg.Microcode},
+	} {
+		assert.Contains(out.String(), fmt.Sprintf("%s: reported %d, launch %d, minimum %d", want.name, want.reported, want.launch, want.minimum))
+	}
+}
+
+func TestVerifyTCBReportNonSNPVariant(t *testing.T) {
+	require := require.New(t)
+
+	cmd := NewVerifyCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	fileHandler := file.NewHandler(afero.NewMemMapFs())
+	cfg := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.QEMU)
+	require.NoError(fileHandler.WriteYAML(constants.ConfigFilename, cfg))
+
+	v := &verifyCmd{
+		fileHandler: fileHandler,
+		log:         logger.NewTest(t),
+		flags: verifyFlags{
+			clusterID: base64.StdEncoding.EncodeToString([]byte("00000000000000000000000000000000")),
+			endpoint:  "192.0.2.1:1234",
+			tcbReport: true,
+		},
+	}
+	require.Error(v.verify(cmd, &stubVerifyClient{}, stubAttestationFetcher{}))
+}
+
+func TestVerifyAttestationConfigOut(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	clusterID := base64.StdEncoding.EncodeToString([]byte("00000000000000000000000000000000"))
+	cmd := NewVerifyCmd()
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetOut(&bytes.Buffer{})
+	fileHandler := file.NewHandler(afero.NewMemMapFs())
+	cfg := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.Azure)
+	require.NoError(fileHandler.WriteYAML(constants.ConfigFilename, cfg))
+	stateFile := defaultStateFile(cloudprovider.Azure)
+	stateFile.Infrastructure.Azure.AttestationURL = "https://192.0.2.1:8080/maa"
+	require.NoError(stateFile.WriteToFile(fileHandler, constants.StateFilename))
+
+	v := &verifyCmd{
+		fileHandler: fileHandler,
+		log:         logger.NewTest(t),
+		flags: verifyFlags{
+			clusterID:            clusterID,
+			endpoint:             "192.0.2.1:1234",
+			output:               "raw",
+			attestationConfigOut: "effective-attestation.yaml",
+		},
+	}
+	require.NoError(v.verify(cmd, &stubVerifyClient{}, stubAttestationFetcher{}))
+
+	wantMeasurements := cfg.Attestation.AzureSEVSNP.Measurements.Copy()
+	require.NoError(updateInitMeasurements(&config.AzureSEVSNP{Measurements: wantMeasurements}, stateFile.ClusterValues.OwnerID, clusterID))
+
+	var exported config.AzureSEVSNP
+	require.NoError(fileHandler.ReadYAML("effective-attestation.yaml", &exported))
+	// the cluster ID flag is reflected in the expected measurements
+	assert.Equal(wantMeasurements, exported.Measurements)
+	// the MAA URL is taken from the state file
+	assert.Equal("https://192.0.2.1:8080/maa", exported.FirmwareSignerConfig.MAAURL)
+	// "latest" versions are resolved using the fetched attestation config
+	assert.False(exported.BootloaderVersion.WantLatest)
+	assert.Equal(testCfg.Bootloader, exported.BootloaderVersion.Value)
+}
+
+func TestVerifyAttestationConfigFromState(t *testing.T) {
+	clusterID := base64.StdEncoding.EncodeToString([]byte("00000000000000000000000000000000"))
+	fileConfig := func() *config.Config {
+		cfg := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.Azure)
+		cfg.Attestation.AzureSEVSNP.Measurements[4] = measurements.WithAllBytes(0xAA, measurements.Enforce, measurements.PCRMeasurementLength)
+		return cfg
+	}
+	stateAttestation := func() *config.AttestationConfig {
+		attestation := fileConfig().Attestation
+		attestation.AzureSEVSNP.Measurements[4] = measurements.WithAllBytes(0xBB, measurements.Enforce, measurements.PCRMeasurementLength)
+		return &attestation
+	}
+
+	testCases := map[string]struct {
+		configFile       *config.Config
+		stateAttestation *config.AttestationConfig
+		wantPCR4         byte
+		wantErr          bool
+	}{
+		"attestation config from state file": {
+			stateAttestation: stateAttestation(),
+			wantPCR4:         0xBB,
+		},
+		"config file takes precedence": {
+			configFile:       fileConfig(),
+			stateAttestation: stateAttestation(),
+			wantPCR4:         0xAA,
+		},
+		"neither config file nor attestation config in state file": {
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			cmd := NewVerifyCmd()
+			cmd.SetErr(&bytes.Buffer{})
+			cmd.SetOut(&bytes.Buffer{})
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			if tc.configFile != nil {
+				require.NoError(fileHandler.WriteYAML(constants.ConfigFilename, tc.configFile))
+			}
+			stateFile := defaultStateFile(cloudprovider.Azure)
+			stateFile.Attestation = tc.stateAttestation
+			require.NoError(stateFile.WriteToFile(fileHandler, constants.StateFilename))
+
+			v := &verifyCmd{
+				fileHandler: fileHandler,
+				log:         logger.NewTest(t),
+				flags: verifyFlags{
+					clusterID:            clusterID,
+					endpoint:             "192.0.2.1:1234",
+					output:               "raw",
+					attestationConfigOut: "effective-attestation.yaml",
+				},
+			}
+			err := v.verify(cmd, &stubVerifyClient{}, stubAttestationFetcher{})
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			var exported config.AzureSEVSNP
+			require.NoError(fileHandler.ReadYAML("effective-attestation.yaml", &exported))
+			assert.Equal(bytes.Repeat([]byte{tc.wantPCR4}, measurements.PCRMeasurementLength), exported.Measurements[4].Expected)
+		})
+	}
+}
+
+func TestParseChipIDs(t *testing.T) {
+	validID := strings.Repeat("ab", 64)
+
+	testCases := map[string]struct {
+		ids     []string
+		want    [][]byte
+		wantErr bool
+	}{
+		"no IDs": {},
+		"valid IDs": {
+			ids:  []string{validID, "0x" + strings.Repeat("01", 64)},
+			want: [][]byte{bytes.Repeat([]byte{0xab}, 64), bytes.Repeat([]byte{0x01}, 64)},
+		},
+		"invalid hex": {
+			ids:     []string{strings.Repeat("zz", 64)},
+			wantErr: true,
+		},
+		"wrong length": {
+			ids:     []string{"abcd"},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			got, err := parseChipIDs(tc.ids)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.want, got)
+		})
+	}
+}
+
+func TestParsePCROverrides(t *testing.T) {
+	value := strings.Repeat("ab", 32)
+
+	testCases := map[string]struct {
+		pcrs    []string
+		want    map[uint32][]byte
+		wantErr bool
+	}{
+		"no overrides": {},
+		"valid overrides": {
+			pcrs: []string{"4=" + value, "23=0x" + strings.Repeat("01", 32)},
+			want: map[uint32][]byte{
+				4:  bytes.Repeat([]byte{0xab}, 32),
+				23: bytes.Repeat([]byte{0x01}, 32),
+			},
+		},
+		"missing value": {
+			pcrs:    []string{"4"},
+			wantErr: true,
+		},
+		"index out of range": {
+			pcrs:    []string{"24=" + value},
+			wantErr: true,
+		},
+		"negative index": {
+			pcrs:    []string{"-1=" + value},
+			wantErr: true,
+		},
+		"invalid hex": {
+			pcrs:    []string{"4=" + strings.Repeat("zz", 32)},
+			wantErr: true,
+		},
+		"wrong length": {
+			pcrs:    []string{"4=abcd"},
+			wantErr: true,
+		},
+		"duplicate index": {
+			pcrs:    []string{"4=" + value, "4=" + value},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			got, err := parsePCROverrides(tc.pcrs)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.want, got)
+		})
+	}
+}
+
+func TestVerifyPCROverrides(t *testing.T) {
+	zeroBase64 := base64.StdEncoding.EncodeToString([]byte("00000000000000000000000000000000"))
+	quotedPCR4 := bytes.Repeat([]byte{0x04}, 32)
+
+	// the embedded report is followed by certificates, which are not part of the report
+	instanceInfo, err := json.Marshal(snp.InstanceInfo{AttestationReport: testdata.AttestationReport[:snpabi.ReportSize]})
+	require.NoError(t, err)
+	attDoc, err := json.Marshal(vtpm.AttestationDocument{
+		Attestation: &attest.Attestation{
+			Quotes: []*tpmProto.Quote{{
+				Pcrs: &tpmProto.PCRs{
+					Hash: tpmProto.HashAlgo_SHA256,
+					Pcrs: map[uint32][]byte{4: quotedPCR4},
+				},
+			}},
+		},
+		InstanceInfo: instanceInfo,
+	})
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		provider     cloudprovider.Provider
+		pcrOverrides map[uint32][]byte
+		wantErr      bool
+	}{
+		"matching override": {
+			provider:     cloudprovider.Azure,
+			pcrOverrides: map[uint32][]byte{4: quotedPCR4},
+		},
+		"wrong override": {
+			provider:     cloudprovider.Azure,
+			pcrOverrides: map[uint32][]byte{4: bytes.Repeat([]byte{0xff}, 32)},
+			wantErr:      true,
+		},
+		"overridden PCR not quoted": {
+			provider:     cloudprovider.Azure,
+			pcrOverrides: map[uint32][]byte{9: quotedPCR4},
+			wantErr:      true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			cmd := NewVerifyCmd()
+			out := &bytes.Buffer{}
+			cmd.SetErr(out)
+			cmd.SetOut(&bytes.Buffer{})
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			cfg := defaultConfigWithExpectedMeasurements(t, config.Default(), tc.provider)
+			require.NoError(fileHandler.WriteYAML(constants.ConfigFilename, cfg))
+
+			v := &verifyCmd{
+				fileHandler: fileHandler,
+				log:         logger.NewTest(t),
+				flags: verifyFlags{
+					clusterID:            zeroBase64,
+					endpoint:             "192.0.2.1:1234",
+					output:               "raw",
+					pcrOverrides:         tc.pcrOverrides,
+					attestationConfigOut: "attestation-config.yaml",
+				},
+			}
+			err := v.verify(cmd, &stubVerifyClient{attestationDoc: attDoc}, stubAttestationFetcher{})
+
+			// the overrides replace the expected values of the config
+			var attConfig config.AzureSEVSNP
+			require.NoError(fileHandler.ReadYAML("attestation-config.yaml", &attConfig))
+			for idx, value := range tc.pcrOverrides {
+				assert.Equal(measurements.Measurement{Expected: value, ValidationOpt: measurements.Enforce}, attConfig.Measurements[idx])
+			}
+
+			if tc.wantErr {
+				assert.Error(err)
+				assert.NotContains(out.String(), "OK")
+				return
+			}
+			assert.NoError(err)
+			assert.Contains(out.String(), "OK")
+		})
+	}
+}
+
+func TestVerifyKernelCmdline(t *testing.T) {
+	zeroBase64 := base64.StdEncoding.EncodeToString([]byte("00000000000000000000000000000000"))
+	const cmdline = "console=ttyS0 constel.csp=azure"
+	initrdDigest := bytes.Repeat([]byte{0x01}, 32)
+	quotedPCR9, err := vtpm.PredictKernelCmdlinePCR(cmdline, initrdDigest)
+	require.NoError(t, err)
+
+	instanceInfo, err := json.Marshal(snp.InstanceInfo{AttestationReport: testdata.AttestationReport[:snpabi.ReportSize]})
+	require.NoError(t, err)
+	attDoc, err := json.Marshal(vtpm.AttestationDocument{
+		Attestation: &attest.Attestation{
+			Quotes: []*tpmProto.Quote{{
+				Pcrs: &tpmProto.PCRs{
+					Hash: tpmProto.HashAlgo_SHA256,
+					Pcrs: map[uint32][]byte{vtpm.PCRIndexKernelCmdline: quotedPCR9},
+				},
+			}},
+		},
+		InstanceInfo: instanceInfo,
+	})
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		cmdline string
+		wantErr bool
+	}{
+		"matching kernel command line": {
+			cmdline: cmdline,
+		},
+		"modified kernel command line": {
+			cmdline: cmdline + " init=/bin/sh",
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			cmd := NewVerifyCmd()
+			out := &bytes.Buffer{}
+			cmd.SetErr(out)
+			cmd.SetOut(&bytes.Buffer{})
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			cfg := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.Azure)
+			require.NoError(fileHandler.WriteYAML(constants.ConfigFilename, cfg))
+
+			v := &verifyCmd{
+				fileHandler: fileHandler,
+				log:         logger.NewTest(t),
+				flags: verifyFlags{
+					clusterID:     zeroBase64,
+					endpoint:      "192.0.2.1:1234",
+					output:        "raw",
+					kernelCmdline: tc.cmdline,
+					initrdDigest:  initrdDigest,
+				},
+			}
+			err := v.verify(cmd, &stubVerifyClient{attestationDoc: attDoc}, stubAttestationFetcher{})
+			if tc.wantErr {
+				var cmdlineErr *vtpm.KernelCmdlineError
+				assert.ErrorAs(err, &cmdlineErr)
+				assert.Equal(sarifRuleMeasurementMismatch, sarifRuleID(err))
+				assert.NotContains(out.String(), "OK")
+				return
+			}
+			assert.NoError(err)
+			assert.Contains(out.String(), "OK")
+		})
+	}
+}
+
+func TestVCEKSourceFromTrace(t *testing.T) {
+	sourceStep := func(source snp.VCEKSource) attestation.TraceStep {
+		return attestation.TraceStep{Name: snp.VCEKSourceTraceStep, Values: []any{snp.VCEKSourceTraceKey, source}}
+	}
+
+	testCases := map[string]struct {
+		steps []attestation.TraceStep
+		want  snp.VCEKSource
+	}{
+		"no steps": {},
+		"no VCEK source recorded": {
+			steps: []attestation.TraceStep{{Name: "parse report"}},
+		},
+		"VCEK source recorded": {
+			steps: []attestation.TraceStep{{Name: "parse report"}, sourceStep(snp.VCEKSourceKDS)},
+			want:  snp.VCEKSourceKDS,
+		},
+		"last verified report wins": {
+			steps: []attestation.TraceStep{sourceStep(snp.VCEKSourceKDS), sourceStep(snp.VCEKSourceTHIM)},
+			want:  snp.VCEKSourceTHIM,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, vcekSourceFromTrace(tc.steps))
+		})
+	}
+}
+
 func TestFormatDefault(t *testing.T) {
 	testCases := map[string]struct {
 		doc     []byte
@@ -235,7 +1000,7 @@ func TestFormatDefault(t *testing.T) {
 
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
-			_, err := formatDefault(context.Background(), tc.doc, tc.attCfg, logger.NewTest(t))
+			_, err := formatDefault(context.Background(), tc.doc, tc.attCfg, "", logger.NewTest(t))
 			if tc.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -250,7 +1015,11 @@ func TestVerifyClient(t *testing.T) {
 		attestationDoc atls.FakeAttestationDoc
 		nonce          []byte
 		attestationErr error
+		unreachable    bool
+		maxReportAge   time.Duration
+		reportAge      time.Duration
 		wantErr        bool
+		wantStale      bool
 	}{
 		"success": {
 			attestationDoc: atls.FakeAttestationDoc{
@@ -284,6 +1053,43 @@ func TestVerifyClient(t *testing.T) {
 			nonce:   []byte("nonce"),
 			wantErr: true,
 		},
+		"endpoint unreachable": {
+			attestationDoc: atls.FakeAttestationDoc{
+				UserData: []byte(constants.ConstellationVerifyServiceUserData),
+				Nonce:    []byte("nonce"),
+			},
+			nonce:       []byte("nonce"),
+			unreachable: true,
+			wantErr:     true,
+		},
+		"fresh report": {
+			attestationDoc: atls.FakeAttestationDoc{
+				UserData: []byte(constants.ConstellationVerifyServiceUserData),
+				Nonce:    []byte("nonce"),
+			},
+			nonce:        []byte("nonce"),
+			maxReportAge: time.Minute,
+			reportAge:    30 * time.Second,
+		},
+		"stale report": {
+			attestationDoc: atls.FakeAttestationDoc{
+				UserData: []byte(constants.ConstellationVerifyServiceUserData),
+				Nonce:    []byte("nonce"),
+			},
+			nonce:        []byte("nonce"),
+			maxReportAge: time.Minute,
+			reportAge:    2 * time.Minute,
+			wantErr:      true,
+			wantStale:    true,
+		},
+		"report age not checked": {
+			attestationDoc: atls.FakeAttestationDoc{
+				UserData: []byte(constants.ConstellationVerifyServiceUserData),
+				Nonce:    []byte("nonce"),
+			},
+			nonce:     []byte("nonce"),
+			reportAge: time.Hour,
+		},
 	}
 
 	for name, tc := range testCases {
@@ -293,9 +1099,12 @@ func TestVerifyClient(t *testing.T) {
 
 			attestation, err := json.Marshal(tc.attestationDoc)
 			require.NoError(err)
+			// The clock advances by the age of the report while the node creates it.
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
 			verifyAPI := &stubVerifyAPI{
 				attestation:    &verifyproto.GetAttestationResponse{Attestation: attestation},
 				attestationErr: tc.attestationErr,
+				onRequest:      func() { now = now.Add(tc.reportAge) },
 			}
 
 			netDialer := testdialer.NewBufconnDialer()
@@ -308,15 +1117,27 @@ func TestVerifyClient(t *testing.T) {
 			go verifyServer.Serve(listener)
 			defer verifyServer.GracefulStop()
 
-			verifier := &constellationVerifier{dialer: dialer, log: logger.NewTest(t)}
+			verifier := &constellationVerifier{
+				fetcher:      &grpcReportFetcher{dialer: dialer, log: logger.NewTest(t)},
+				maxReportAge: tc.maxReportAge,
+				now:          func() time.Time { return now },
+				log:          logger.NewTest(t),
+			}
 			request := &verifyproto.GetAttestationRequest{
 				Nonce: tc.nonce,
 			}
 
+			if tc.unreachable {
+				addr = net.JoinHostPort("192.0.2.2", strconv.Itoa(constants.VerifyServiceNodePortGRPC))
+			}
 			_, err = verifier.Verify(context.Background(), addr, request, atls.NewFakeValidator(variant.Dummy{}))
 
 			if tc.wantErr {
 				assert.Error(err)
+				var unreachableErr *endpointUnreachableError
+				assert.Equal(tc.unreachable, errors.As(err, &unreachableErr))
+				var staleErr *staleReportError
+				assert.Equal(tc.wantStale, errors.As(err, &staleErr))
 			} else {
 				assert.NoError(err)
 			}
@@ -325,22 +1146,27 @@ func TestVerifyClient(t *testing.T) {
 }
 
 type stubVerifyClient struct {
-	verifyErr error
-	endpoint  string
+	attestationDoc []byte
+	verifyErr      error
+	endpoint       string
 }
 
 func (c *stubVerifyClient) Verify(_ context.Context, endpoint string, _ *verifyproto.GetAttestationRequest, _ atls.Validator) ([]byte, error) {
 	c.endpoint = endpoint
-	return nil, c.verifyErr
+	return c.attestationDoc, c.verifyErr
 }
 
 type stubVerifyAPI struct {
 	attestation    *verifyproto.GetAttestationResponse
 	attestationErr error
+	onRequest      func()
 	verifyproto.UnimplementedAPIServer
 }
 
 func (a stubVerifyAPI) GetAttestation(context.Context, *verifyproto.GetAttestationRequest) (*verifyproto.GetAttestationResponse, error) {
+	if a.onRequest != nil {
+		a.onRequest()
+	}
 	return a.attestation, a.attestationErr
 }
 
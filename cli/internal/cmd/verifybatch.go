@@ -0,0 +1,399 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/api/attestationconfigapi"
+	"github.com/edgelesssys/constellation/v2/internal/attestation"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/snp"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/google/go-sev-guest/abi"
+	"github.com/google/go-sev-guest/kds"
+	"github.com/google/go-sev-guest/validate"
+	"github.com/google/go-sev-guest/verify"
+	"github.com/google/go-sev-guest/verify/trust"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func newVerifyBatchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "batch",
+		Short: "Verify a directory of saved SEV-SNP attestation reports offline",
+		Long: "Verify a directory of saved SEV-SNP attestation reports offline against the attestation config.\n\n" +
+			"Each report is read from a file with the extension \".bin\" (raw) or \".hex\" (hex encoded).\n" +
+			"The certificates for a report are read from a PEM file of the same name with the extension \".pem\",\n" +
+			"containing the VCEK or VLEK certificate and, optionally, the ASK certificate.\n" +
+			"The ARK is always taken from the attestation config.",
+		Args: cobra.NoArgs,
+		RunE: runVerifyBatch,
+	}
+	cmd.Flags().String("dir", "", "directory containing the attestation reports")
+	must(cmd.MarkFlagRequired("dir"))
+	must(cmd.MarkFlagDirname("dir"))
+	cmd.Flags().String("report-format", reportFormatText, "format of the verification results {text|junit}\n"+
+		"With junit, a JUnit XML report with one test case per report is written to stdout")
+	return cmd
+}
+
+type verifyBatchFlags struct {
+	rootFlags
+	dir          string
+	reportFormat string
+}
+
+func (f *verifyBatchFlags) parse(flags *pflag.FlagSet) error {
+	if err := f.rootFlags.parse(flags); err != nil {
+		return err
+	}
+
+	var err error
+	f.dir, err = flags.GetString("dir")
+	if err != nil {
+		return fmt.Errorf("getting 'dir' flag: %w", err)
+	}
+	reportFormat, err := flags.GetString("report-format")
+	if err != nil {
+		return fmt.Errorf("getting 'report-format' flag: %w", err)
+	}
+	f.reportFormat, err = parseReportFormat(reportFormat)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type verifyBatchCmd struct {
+	fileHandler file.Handler
+	flags       verifyBatchFlags
+	log         debugLog
+}
+
+func runVerifyBatch(cmd *cobra.Command, _ []string) error {
+	log, err := newCLILogger(cmd)
+	if err != nil {
+		return fmt.Errorf("creating logger: %w", err)
+	}
+
+	v := &verifyBatchCmd{
+		fileHandler: file.NewHandler(afero.NewOsFs()),
+		log:         log,
+	}
+	if err := v.flags.parse(cmd.Flags()); err != nil {
+		return err
+	}
+	v.log.Debug("Using flags", "dir", v.flags.dir, "reportFormat", v.flags.reportFormat)
+
+	return v.verifyBatch(cmd, attestationconfigapi.NewFetcher())
+}
+
+func (v *verifyBatchCmd) verifyBatch(cmd *cobra.Command, configFetcher attestationconfigapi.Fetcher) error {
+	v.log.Debug(fmt.Sprintf("Loading configuration file from %q", v.flags.pathPrefixer.PrefixPrintablePath(constants.ConfigFilename)))
+	conf, err := v.flags.loadConfig(cmd.Context(), v.fileHandler, configFetcher)
+	var configValidationErr *config.ValidationError
+	if errors.As(err, &configValidationErr) {
+		cmd.PrintErrln(configValidationErr.LongMessage())
+	}
+	if err != nil {
+		return fmt.Errorf("loading config file: %w", err)
+	}
+
+	return v.verifyReports(cmd, conf.GetAttestationConfig())
+}
+
+// verifyReports verifies all reports in the batch directory and prints the results in the configured report format.
+// An error is returned if any report fails verification.
+func (v *verifyBatchCmd) verifyReports(cmd *cobra.Command, attestationCfg config.AttestationCfg) error {
+	policy, err := newOfflineSNPPolicy(attestationCfg)
+	if err != nil {
+		return err
+	}
+
+	entries, err := v.fileHandler.ReadDir(v.flags.dir)
+	if err != nil {
+		return fmt.Errorf("reading report directory: %w", err)
+	}
+
+	var results []verifyResult
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".bin" && ext != ".hex") {
+			continue
+		}
+
+		reportPath := filepath.Join(v.flags.dir, entry.Name())
+		v.log.Debug(fmt.Sprintf("Verifying report %q", reportPath))
+		start := time.Now()
+		measurement, err := v.verifyReportFile(reportPath, policy, warnLogger{cmd: cmd, log: v.log})
+		results = append(results, verifyResult{name: entry.Name(), duration: time.Since(start), err: err, measurement: measurement})
+	}
+
+	if len(results) == 0 {
+		return fmt.Errorf("no reports found in %q", v.flags.pathPrefixer.PrefixPrintablePath(v.flags.dir))
+	}
+	flagMeasurementOutliers(results)
+	if v.flags.reportFormat == reportFormatJUnit {
+		return writeJUnit(cmd.OutOrStdout(), "verify batch", results)
+	}
+
+	var passed, failed int
+	for _, result := range results {
+		if result.err != nil {
+			cmd.Printf("FAIL\t%s: %s\n", result.name, result.err)
+			failed++
+			continue
+		}
+		cmd.Printf("PASS\t%s\n", result.name)
+		passed++
+	}
+	cmd.Printf("\n%d passed, %d failed\n", passed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d reports failed verification", failed, passed+failed)
+	}
+	return nil
+}
+
+// verifyReportFile loads the report at reportPath and its certificates, and verifies it against policy.
+// The launch measurement of the verified report is returned.
+func (v *verifyBatchCmd) verifyReportFile(reportPath string, policy offlineSNPPolicy, log warnLog) ([]byte, error) {
+	rawReport, err := v.fileHandler.Read(reportPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading report: %w", err)
+	}
+	if filepath.Ext(reportPath) == ".hex" {
+		rawReport, err = hex.DecodeString(strings.TrimSpace(string(rawReport)))
+		if err != nil {
+			return nil, fmt.Errorf("decoding hex report: %w", err)
+		}
+	}
+	if len(rawReport) < abi.ReportSize {
+		return nil, fmt.Errorf("report is %d bytes, expected at least %d bytes", len(rawReport), abi.ReportSize)
+	}
+
+	certPath := strings.TrimSuffix(reportPath, filepath.Ext(reportPath)) + ".pem"
+	rawCerts, err := v.fileHandler.Read(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading certificates: %w", err)
+	}
+	reportSigner, certChain, err := splitReportCerts(rawCerts)
+	if err != nil {
+		return nil, fmt.Errorf("parsing certificates: %w", err)
+	}
+
+	instanceInfo := snp.InstanceInfo{
+		AttestationReport: rawReport[:abi.ReportSize],
+		ReportSigner:      reportSigner,
+		CertChain:         certChain,
+	}
+	if err := policy.verify(instanceInfo, log); err != nil {
+		return nil, err
+	}
+	report, err := abi.ReportToProto(instanceInfo.AttestationReport)
+	if err != nil {
+		return nil, fmt.Errorf("parsing attestation report: %w", err)
+	}
+	return report.GetMeasurement(), nil
+}
+
+// flagMeasurementOutliers checks that all verified reports share the same launch measurement,
+// and fails the reports whose measurement differs from the one reported by most nodes.
+// Nodes running a different image than the rest of the cluster indicate drift or tampering.
+func flagMeasurementOutliers(results []verifyResult) {
+	counts := make(map[string]int)
+	var verified int
+	for _, result := range results {
+		if result.err == nil {
+			counts[string(result.measurement)]++
+			verified++
+		}
+	}
+	if len(counts) < 2 {
+		return
+	}
+
+	// Ties are broken by the order of the reports, so the result is deterministic.
+	var majority string
+	for _, result := range results {
+		if result.err == nil && counts[string(result.measurement)] > counts[majority] {
+			majority = string(result.measurement)
+		}
+	}
+	for i, result := range results {
+		if result.err == nil && string(result.measurement) != majority {
+			results[i].err = &verifyFailure{
+				ruleID: sarifRuleMeasurementMismatch,
+				err: fmt.Errorf("image measurement %x differs from measurement %x reported by %d of %d verified reports",
+					result.measurement, []byte(majority), counts[majority], verified),
+			}
+		}
+	}
+}
+
+// splitReportCerts splits a PEM bundle into the report signer (VCEK/VLEK) and the remaining certificate chain.
+func splitReportCerts(rawCerts []byte) (reportSigner, certChain []byte, err error) {
+	rest := bytes.TrimSpace(rawCerts)
+	var block *pem.Block
+	for block, rest = pem.Decode(rest); block != nil; block, rest = pem.Decode(rest) {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parsing certificate: %w", err)
+		}
+		switch {
+		case cert.Subject.CommonName == "SEV-VCEK" || cert.Subject.CommonName == "SEV-VLEK":
+			if reportSigner != nil {
+				return nil, nil, errors.New("more than one report signer certificate")
+			}
+			reportSigner = pem.EncodeToMemory(block)
+		case strings.HasPrefix(cert.Subject.CommonName, "ARK-"):
+			// The ARK of any product line is taken from the attestation config.
+			continue
+		default:
+			certChain = append(certChain, pem.EncodeToMemory(block)...)
+		}
+	}
+	if len(bytes.TrimSpace(rest)) != 0 {
+		return nil, nil, errors.New("remaining data is not a valid PEM certificate")
+	}
+	if reportSigner == nil {
+		return nil, nil, errors.New("no VCEK or VLEK certificate found")
+	}
+	return reportSigner, certChain, nil
+}
+
+// offlineSNPPolicy holds the parts of an SEV-SNP attestation config required to verify saved reports.
+type offlineSNPPolicy struct {
+	ark             *x509.Certificate
+	ask             *x509.Certificate
+	minimumTCB      kds.TCBParts
+	minMicrocodeSVN *uint8
+	vmpl            *uint8
+}
+
+func newOfflineSNPPolicy(attestationCfg config.AttestationCfg) (offlineSNPPolicy, error) {
+	var policy offlineSNPPolicy
+	var ark, ask config.Certificate
+	switch cfg := attestationCfg.(type) {
+	case *config.AzureSEVSNP:
+		ark, ask = cfg.AMDRootKey, cfg.AMDSigningKey
+		policy.minimumTCB = snpTCBParts(cfg.BootloaderVersion, cfg.TEEVersion, cfg.SNPVersion, cfg.MicrocodeVersion)
+		policy.minMicrocodeSVN = cfg.MinMicrocodeSVN
+		policy.vmpl = cfg.VMPL
+	case *config.AWSSEVSNP:
+		ark, ask = cfg.AMDRootKey, cfg.AMDSigningKey
+		policy.minimumTCB = snpTCBParts(cfg.BootloaderVersion, cfg.TEEVersion, cfg.SNPVersion, cfg.MicrocodeVersion)
+		policy.minMicrocodeSVN = cfg.MinMicrocodeSVN
+		policy.vmpl = cfg.VMPL
+	case *config.GCPSEVSNP:
+		ark, ask = cfg.AMDRootKey, cfg.AMDSigningKey
+		policy.minimumTCB = snpTCBParts(cfg.BootloaderVersion, cfg.TEEVersion, cfg.SNPVersion, cfg.MicrocodeVersion)
+		policy.minMicrocodeSVN = cfg.MinMicrocodeSVN
+		policy.vmpl = cfg.VMPL
+	default:
+		return offlineSNPPolicy{}, fmt.Errorf("offline report verification is not supported for attestation variant %s", attestationCfg.GetVariant())
+	}
+
+	if ark.Equal(config.Certificate{}) {
+		return offlineSNPPolicy{}, errors.New("no AMD root key configured")
+	}
+	policy.ark = (*x509.Certificate)(&ark)
+	if !ask.Equal(config.Certificate{}) {
+		policy.ask = (*x509.Certificate)(&ask)
+	}
+	return policy, nil
+}
+
+func snpTCBParts(bootloader, tee, snp, microcode config.AttestationVersion[uint8]) kds.TCBParts {
+	return kds.TCBParts{
+		BlSpl:    bootloader.Value,
+		TeeSpl:   tee.Value,
+		SnpSpl:   snp.Value,
+		UcodeSpl: microcode.Value,
+	}
+}
+
+// verify checks the signature and certificate chain of the report and validates it against the policy.
+// No certificates are fetched from AMD KDS.
+func (p offlineSNPPolicy) verify(instanceInfo snp.InstanceInfo, log warnLog) error {
+	att, err := instanceInfo.AttestationWithCerts(offlineHTTPSGetter{}, snp.NewCertificateChain(p.ask, p.ark), log)
+	if err != nil {
+		return fmt.Errorf("parsing attestation report: %w", err)
+	}
+
+	ask, err := x509.ParseCertificate(att.CertificateChain.AskCert)
+	if err != nil {
+		return fmt.Errorf("parsing ASK certificate: %w", err)
+	}
+	ark, err := x509.ParseCertificate(att.CertificateChain.ArkCert)
+	if err != nil {
+		return fmt.Errorf("parsing ARK certificate: %w", err)
+	}
+	productCerts := &trust.ProductCerts{Ask: ask, Ark: ark}
+	if att.CertificateChain.VlekCert != nil {
+		// When using a VLEK signer, the intermediate certificate has to be stored in Asvk instead of Ask.
+		productCerts = &trust.ProductCerts{Asvk: ask, Ark: ark}
+	}
+	productLine := kds.ProductLine(att.Product)
+	err = verify.SnpAttestation(att, &verify.Options{
+		DisableCertFetching: true,
+		TrustedRoots: map[string][]*trust.AMDRootCerts{
+			productLine: {{Product: productLine, ProductCerts: productCerts}},
+		},
+	})
+	attestation.Trace(log, "validate certificate chain", err)
+	if err != nil {
+		return fmt.Errorf("verifying SNP attestation: %w", err)
+	}
+
+	err = validate.SnpAttestation(att, &validate.Options{
+		GuestPolicy: abi.SnpPolicy{
+			Debug: false,
+			SMT:   true,
+		},
+		MinimumLaunchTCB:          p.minimumTCB,
+		PermitProvisionalFirmware: true,
+	})
+	attestation.Trace(log, "check TCB and guest policy", err,
+		"launchTCB", kds.DecomposeTCBVersion(kds.TCBVersion(att.Report.GetLaunchTcb())), "minimumLaunchTCB", p.minimumTCB,
+		"policy", fmt.Sprintf("%#x", att.Report.GetPolicy()),
+	)
+	if err != nil {
+		return fmt.Errorf("validating SNP attestation: %w", err)
+	}
+
+	err = snp.ValidateMinMicrocodeSVN(att.Report, p.minMicrocodeSVN)
+	attestation.Trace(log, "check microcode SVN", err, "microcodeSVN", snp.MicrocodeSVN(att.Report))
+	if err != nil {
+		return fmt.Errorf("validating microcode SVN: %w", err)
+	}
+	err = snp.ValidateVMPL(att.Report, p.vmpl)
+	attestation.Trace(log, "check VMPL", err, "vmpl", att.Report.GetVmpl())
+	if err != nil {
+		return fmt.Errorf("validating VMPL: %w", err)
+	}
+	return nil
+}
+
+// offlineHTTPSGetter refuses all requests, since reports are verified offline.
+type offlineHTTPSGetter struct{}
+
+// Get always returns an error.
+func (offlineHTTPSGetter) Get(url string) ([]byte, error) {
+	return nil, fmt.Errorf("refusing to fetch %q during offline verification", url)
+}
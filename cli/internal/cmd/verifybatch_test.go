@@ -0,0 +1,263 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation/snp/testdata"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyReports(t *testing.T) {
+	certs := append(append([]byte{}, testdata.AzureThimVCEK...), testdata.CertChain...)
+	tamperedReport := bytes.Clone(testdata.AttestationReport)
+	tamperedReport[0x50] ^= 0xFF
+	tooHighSVN := uint8(255)
+
+	testCases := map[string]struct {
+		files       map[string][]byte
+		minSVN      *uint8
+		ark         *x509.Certificate
+		wantOutput  []string
+		wantErr     bool
+		wantSummary string
+	}{
+		"all reports pass": {
+			files: map[string][]byte{
+				"raw.bin": testdata.AttestationReport,
+				"raw.pem": certs,
+				"enc.hex": []byte(hex.EncodeToString(testdata.AttestationReport) + "\n"),
+				"enc.pem": certs,
+				"notes":   []byte("ignored"),
+			},
+			wantOutput:  []string{"PASS\traw.bin", "PASS\tenc.hex"},
+			wantSummary: "2 passed, 0 failed",
+		},
+		"tampered report fails": {
+			files: map[string][]byte{
+				"valid.bin":    testdata.AttestationReport,
+				"valid.pem":    certs,
+				"tampered.bin": tamperedReport,
+				"tampered.pem": certs,
+			},
+			wantOutput:  []string{"PASS\tvalid.bin", "FAIL\ttampered.bin"},
+			wantSummary: "1 passed, 1 failed",
+			wantErr:     true,
+		},
+		"missing certificates": {
+			files: map[string][]byte{
+				"valid.bin": testdata.AttestationReport,
+			},
+			wantOutput:  []string{"FAIL\tvalid.bin"},
+			wantSummary: "0 passed, 1 failed",
+			wantErr:     true,
+		},
+		"truncated report": {
+			files: map[string][]byte{
+				"short.bin": testdata.AttestationReport[:100],
+				"short.pem": certs,
+			},
+			wantOutput:  []string{"FAIL\tshort.bin"},
+			wantSummary: "0 passed, 1 failed",
+			wantErr:     true,
+		},
+		"microcode SVN below minimum": {
+			files: map[string][]byte{
+				"valid.bin": testdata.AttestationReport,
+				"valid.pem": certs,
+			},
+			minSVN:      &tooHighSVN,
+			wantOutput:  []string{"FAIL\tvalid.bin"},
+			wantSummary: "0 passed, 1 failed",
+			wantErr:     true,
+		},
+		"configured ARK of another product line": {
+			files: map[string][]byte{
+				"valid.bin": testdata.AttestationReport,
+				"valid.pem": certs,
+			},
+			ark:         newTestCA(t, "ARK-Genoa").cert,
+			wantOutput:  []string{"FAIL\tvalid.bin", "configured ARK certificate is for product Genoa, but the attestation report is from product Milan"},
+			wantSummary: "0 passed, 1 failed",
+			wantErr:     true,
+		},
+		"no reports": {
+			files: map[string][]byte{
+				"notes": []byte("ignored"),
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			for name, content := range tc.files {
+				require.NoError(fileHandler.Write(filepath.Join("reports", name), content, file.OptMkdirAll))
+			}
+
+			cfg := config.DefaultForAzureSEVSNP()
+			cfg.BootloaderVersion = config.AttestationVersion[uint8]{Value: 0}
+			cfg.TEEVersion = config.AttestationVersion[uint8]{Value: 0}
+			cfg.SNPVersion = config.AttestationVersion[uint8]{Value: 0}
+			cfg.MicrocodeVersion = config.AttestationVersion[uint8]{Value: 0}
+			cfg.MinMicrocodeSVN = tc.minSVN
+			if tc.ark != nil {
+				cfg.AMDRootKey = config.Certificate(*tc.ark)
+			}
+
+			cmd := newVerifyBatchCmd()
+			var out bytes.Buffer
+			cmd.SetOut(&out)
+			cmd.SetErr(&bytes.Buffer{})
+
+			v := &verifyBatchCmd{
+				fileHandler: fileHandler,
+				flags:       verifyBatchFlags{dir: "reports"},
+				log:         logger.NewTest(t),
+			}
+
+			err := v.verifyReports(cmd, cfg)
+			for _, line := range tc.wantOutput {
+				assert.Contains(out.String(), line)
+			}
+			if tc.wantSummary != "" {
+				assert.Contains(out.String(), tc.wantSummary)
+			}
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+		})
+	}
+}
+
+func TestFlagMeasurementOutliers(t *testing.T) {
+	image := bytes.Repeat([]byte{0xAA}, 48)
+	otherImage := bytes.Repeat([]byte{0xBB}, 48)
+	failed := errors.New("failed")
+
+	testCases := map[string]struct {
+		results      []verifyResult
+		wantOutliers []string
+	}{
+		"identical measurements": {
+			results: []verifyResult{
+				{name: "node-0", measurement: image},
+				{name: "node-1", measurement: image},
+				{name: "node-2", measurement: image},
+			},
+		},
+		"one divergent measurement": {
+			results: []verifyResult{
+				{name: "node-0", measurement: image},
+				{name: "node-1", measurement: otherImage},
+				{name: "node-2", measurement: image},
+			},
+			wantOutliers: []string{"node-1"},
+		},
+		"failed reports are ignored": {
+			results: []verifyResult{
+				{name: "node-0", measurement: image},
+				{name: "node-1", err: failed},
+				{name: "node-2", err: failed},
+			},
+		},
+		"tie is broken by report order": {
+			results: []verifyResult{
+				{name: "node-0", measurement: otherImage},
+				{name: "node-1", measurement: image},
+			},
+			wantOutliers: []string{"node-1"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			previousErrs := make(map[string]error)
+			for _, result := range tc.results {
+				previousErrs[result.name] = result.err
+			}
+
+			flagMeasurementOutliers(tc.results)
+
+			var outliers []string
+			for _, result := range tc.results {
+				if result.err != nil && previousErrs[result.name] == nil {
+					outliers = append(outliers, result.name)
+					assert.ErrorContains(result.err, "image measurement")
+					assert.Equal(sarifRuleMeasurementMismatch, sarifRuleID(result.err))
+					continue
+				}
+				assert.Equal(previousErrs[result.name], result.err)
+			}
+			assert.Equal(tc.wantOutliers, outliers)
+		})
+	}
+}
+
+func TestSplitReportCerts(t *testing.T) {
+	vcek := newTestCA(t, "SEV-VCEK").certPEM
+	ask := newTestCA(t, "SEV-Genoa").certPEM
+	arkMilan := newTestCA(t, "ARK-Milan").certPEM
+	arkGenoa := newTestCA(t, "ARK-Genoa").certPEM
+
+	testCases := map[string]struct {
+		certs         [][]byte
+		wantCertChain []byte
+		wantErr       bool
+	}{
+		"ARK of Milan is dropped": {
+			certs:         [][]byte{vcek, ask, arkMilan},
+			wantCertChain: ask,
+		},
+		"ARK of Genoa is dropped": {
+			certs:         [][]byte{vcek, ask, arkGenoa},
+			wantCertChain: ask,
+		},
+		"no report signer": {
+			certs:   [][]byte{ask, arkGenoa},
+			wantErr: true,
+		},
+		"more than one report signer": {
+			certs:   [][]byte{vcek, vcek},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			reportSigner, certChain, err := splitReportCerts(bytes.Join(tc.certs, nil))
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(vcek, reportSigner)
+			assert.Equal(tc.wantCertChain, certChain)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// defaultMaxClockSkew is the clock skew above which verify refuses to run.
+	defaultMaxClockSkew = 5 * time.Minute
+	// clockSkewWarnThreshold is the clock skew above which verify warns about the local clock.
+	clockSkewWarnThreshold = 30 * time.Second
+)
+
+// timeSource returns the current time of a clock trusted more than the local one.
+type timeSource interface {
+	Now(ctx context.Context) (time.Time, error)
+}
+
+// httpDateTimeSource reads the current time from the Date header of a response of an HTTPS server.
+type httpDateTimeSource struct {
+	client *http.Client
+	url    string
+}
+
+// Now returns the time of the server.
+// The Date header only has a resolution of one second, and the server sets it somewhere during the round trip,
+// so the time is accurate to about one second plus half of the round trip time.
+func (s *httpDateTimeSource) Now(ctx context.Context) (time.Time, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, s.url, http.NoBody)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("creating request: %w", err)
+	}
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("requesting %s: %w", s.url, err)
+	}
+	defer resp.Body.Close()
+	roundTrip := time.Since(start)
+
+	date := resp.Header.Get("Date")
+	if date == "" {
+		return time.Time{}, fmt.Errorf("response of %s has no Date header", s.url)
+	}
+	serverTime, err := http.ParseTime(date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing Date header of %s: %w", s.url, err)
+	}
+	return serverTime.Add(roundTrip / 2), nil
+}
+
+// clockSkewError is returned if the local clock differs too much from the trusted time source.
+type clockSkewError struct {
+	skew    time.Duration
+	maxSkew time.Duration
+}
+
+// Error returns the error message.
+func (e *clockSkewError) Error() string {
+	return fmt.Sprintf("the local clock is off by %s, which exceeds the maximum of %s: "+
+		"certificate validity and report freshness checks of the attestation would fail, synchronize your clock or increase --max-clock-skew",
+		e.skew.Round(time.Second), e.maxSkew)
+}
+
+// checkClockSkew compares the local clock against the trusted time source before the attestation is verified.
+// A skew above clockSkewWarnThreshold prints a warning, which is recorded in warnings, a skew above maxSkew is an error.
+// If the time source can't be reached, the check is skipped, so verify still works without internet access.
+func checkClockSkew(cmd *cobra.Command, log debugLog, warnings *verifyWarnings, source timeSource, localNow func() time.Time, maxSkew time.Duration) error {
+	trustedNow, err := source.Now(cmd.Context())
+	if err != nil {
+		log.Debug("Skipping clock skew check", "error", err)
+		return nil
+	}
+
+	skew := localNow().Sub(trustedNow)
+	if skew < 0 {
+		skew = -skew
+	}
+	log.Debug("Checked clock skew", "skew", skew)
+	if skew > maxSkew {
+		return &clockSkewError{skew: skew, maxSkew: maxSkew}
+	}
+	if skew > clockSkewWarnThreshold {
+		warnings.warn(cmd, fmt.Sprintf("Warning: the local clock is off by %s. If the verification fails because of invalid certificates or stale reports, synchronize your clock.",
+			skew.Round(time.Second)))
+	}
+	return nil
+}
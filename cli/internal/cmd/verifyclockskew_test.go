@@ -0,0 +1,140 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckClockSkew(t *testing.T) {
+	trustedNow := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		source      timeSource
+		localNow    time.Time
+		wantWarning bool
+		wantErr     bool
+	}{
+		"clocks in sync": {
+			source:   stubTimeSource{now: trustedNow},
+			localNow: trustedNow.Add(2 * time.Second),
+		},
+		"small skew warns": {
+			source:      stubTimeSource{now: trustedNow},
+			localNow:    trustedNow.Add(2 * time.Minute),
+			wantWarning: true,
+		},
+		"local clock behind warns": {
+			source:      stubTimeSource{now: trustedNow},
+			localNow:    trustedNow.Add(-2 * time.Minute),
+			wantWarning: true,
+		},
+		"large skew fails": {
+			source:   stubTimeSource{now: trustedNow},
+			localNow: trustedNow.Add(2 * time.Hour),
+			wantErr:  true,
+		},
+		"local clock far behind fails": {
+			source:   stubTimeSource{now: trustedNow},
+			localNow: trustedNow.Add(-24 * time.Hour),
+			wantErr:  true,
+		},
+		"unreachable time source is skipped": {
+			source:   stubTimeSource{err: errors.New("no internet access")},
+			localNow: trustedNow.Add(24 * time.Hour),
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			cmd := NewVerifyCmd()
+			cmd.SetContext(context.Background())
+			var errOut bytes.Buffer
+			cmd.SetErr(&errOut)
+
+			err := checkClockSkew(cmd, logger.NewTest(t), &verifyWarnings{}, tc.source, func() time.Time { return tc.localNow }, 5*time.Minute)
+			if tc.wantErr {
+				var skewErr *clockSkewError
+				assert.ErrorAs(err, &skewErr)
+				return
+			}
+			assert.NoError(err)
+			if tc.wantWarning {
+				assert.Contains(errOut.String(), "Warning: the local clock is off by 2m0s")
+			} else {
+				assert.Empty(errOut.String())
+			}
+		})
+	}
+}
+
+func TestHTTPDateTimeSource(t *testing.T) {
+	serverTime := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		date    string
+		wantErr bool
+	}{
+		"valid date": {
+			date: serverTime.Format(http.TimeFormat),
+		},
+		"no date": {
+			wantErr: true,
+		},
+		"invalid date": {
+			date:    "yesterday",
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(http.MethodHead, r.Method)
+				// The server sets the Date header unless it is explicitly removed.
+				w.Header()["Date"] = nil
+				if tc.date != "" {
+					w.Header().Set("Date", tc.date)
+				}
+			}))
+			defer server.Close()
+
+			source := &httpDateTimeSource{client: server.Client(), url: server.URL}
+			now, err := source.Now(context.Background())
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.WithinDuration(serverTime, now, time.Second)
+		})
+	}
+}
+
+type stubTimeSource struct {
+	now time.Time
+	err error
+}
+
+func (s stubTimeSource) Now(context.Context) (time.Time, error) {
+	return s.now, s.err
+}
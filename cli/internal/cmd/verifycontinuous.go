@@ -0,0 +1,111 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spf13/cobra"
+)
+
+// defaultVerifyInterval is the time between two verifications of verify --continuous.
+const defaultVerifyInterval = time.Minute
+
+const (
+	attestationResultPass = "pass"
+	attestationResultFail = "fail"
+)
+
+// verifyMetrics are the Prometheus metrics of continuous verification.
+type verifyMetrics struct {
+	registry     *prometheus.Registry
+	attestations *prometheus.CounterVec
+	lastSuccess  prometheus.Gauge
+}
+
+func newVerifyMetrics() *verifyMetrics {
+	m := &verifyMetrics{
+		registry: prometheus.NewRegistry(),
+		attestations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "constellation_attestation_total",
+			Help: "Number of verifications of the attestation of the node, by result.",
+		}, []string{"result"}),
+		lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "constellation_attestation_last_success_timestamp_seconds",
+			Help: "Unix time of the last successful verification of the attestation of the node.",
+		}),
+	}
+	m.registry.MustRegister(m.attestations, m.lastSuccess)
+	// Export both results from the start, so that rates can be computed before the first failure.
+	m.attestations.WithLabelValues(attestationResultPass)
+	m.attestations.WithLabelValues(attestationResultFail)
+	return m
+}
+
+// record counts the result of a verification finished at the given time.
+func (m *verifyMetrics) record(verifyErr error, now time.Time) {
+	if verifyErr != nil {
+		m.attestations.WithLabelValues(attestationResultFail).Inc()
+		return
+	}
+	m.attestations.WithLabelValues(attestationResultPass).Inc()
+	m.lastSuccess.Set(float64(now.Unix()))
+}
+
+// handler returns an HTTP handler serving the metrics at /metrics.
+func (m *verifyMetrics) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	return mux
+}
+
+// serveVerifyMetrics serves the metrics on the given address until the returned function is called.
+func serveVerifyMetrics(addr string, metrics *verifyMetrics, log debugLog) (func(), error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listening for metrics requests: %w", err)
+	}
+	server := &http.Server{Handler: metrics.handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Debug("Serving metrics failed", "error", err)
+		}
+	}()
+	return func() { _ = server.Close() }, nil
+}
+
+// verifyContinuously calls verifyOnce every interval until the context of the command is canceled.
+// A failed verification is printed and counted, but doesn't stop the loop.
+func verifyContinuously(cmd *cobra.Command, verifyOnce func() error, interval time.Duration, metrics *verifyMetrics, now func() time.Time) error {
+	ctx := cmd.Context()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := verifyOnce()
+		if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+			// The verification was aborted by the interrupt, so its result says nothing about the node.
+			return nil
+		}
+		metrics.record(err, now())
+		if err != nil {
+			cmd.PrintErrf("Verification failed: %s\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
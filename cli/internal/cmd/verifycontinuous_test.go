@@ -0,0 +1,171 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyContinuouslyMetrics(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cmd := &cobra.Command{}
+	cmd.SetContext(ctx)
+	errOut := &bytes.Buffer{}
+	cmd.SetErr(errOut)
+
+	results := []error{nil, errors.New("measurements don't match"), nil}
+	calls := 0
+	verifyOnce := func() error {
+		err := results[calls]
+		calls++
+		if calls == len(results) {
+			cancel()
+		}
+		return err
+	}
+	lastSuccess := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+	metrics := newVerifyMetrics()
+	err := verifyContinuously(cmd, verifyOnce, time.Millisecond, metrics, func() time.Time { return lastSuccess })
+	require.NoError(err)
+	assert.Equal(len(results), calls)
+	assert.Contains(errOut.String(), "Verification failed: measurements don't match")
+
+	server := httptest.NewServer(metrics.handler())
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/metrics")
+	require.NoError(err)
+	defer resp.Body.Close()
+	require.Equal(http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(err)
+
+	assert.Contains(string(body), `constellation_attestation_total{result="pass"} 2`)
+	assert.Contains(string(body), `constellation_attestation_total{result="fail"} 1`)
+	assert.Contains(string(body), "constellation_attestation_last_success_timestamp_seconds 1.7920656e+09")
+}
+
+func TestVerifyContinuouslyInterrupted(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := &cobra.Command{}
+	cmd.SetContext(ctx)
+	cmd.SetErr(&bytes.Buffer{})
+
+	verifyOnce := func() error {
+		cancel()
+		return context.Canceled
+	}
+
+	metrics := newVerifyMetrics()
+	assert.NoError(verifyContinuously(cmd, verifyOnce, time.Hour, metrics, time.Now))
+	assert.Zero(metricValue(t, metrics, attestationResultFail))
+}
+
+func TestParseVerifyFlagsContinuous(t *testing.T) {
+	testCases := map[string]struct {
+		continuous  bool
+		interval    string
+		metricsAddr string
+		wantErr     bool
+	}{
+		"default": {},
+		"continuous": {
+			continuous: true,
+		},
+		"continuous with metrics": {
+			continuous:  true,
+			interval:    "10s",
+			metricsAddr: "localhost:9090",
+		},
+		"metrics without continuous": {
+			metricsAddr: "localhost:9090",
+			wantErr:     true,
+		},
+		"interval without continuous": {
+			interval: "10s",
+			wantErr:  true,
+		},
+		"zero interval": {
+			continuous: true,
+			interval:   "0s",
+			wantErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			flags := NewVerifyCmd().Flags()
+			// Register persistent flags
+			flags.String("workspace", "", "")
+			flags.String("tf-log", "NONE", "")
+			flags.String("profile", "", "")
+			flags.String("config", "", "")
+			flags.String("kubeconfig", "", "")
+			flags.Bool("force", false, "")
+			flags.Bool("debug", false, "")
+			if tc.continuous {
+				require.NoError(flags.Set("continuous", "true"))
+			}
+			if tc.interval != "" {
+				require.NoError(flags.Set("interval", tc.interval))
+			}
+			if tc.metricsAddr != "" {
+				require.NoError(flags.Set("metrics-addr", tc.metricsAddr))
+			}
+
+			var f verifyFlags
+			err := f.parse(flags)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tc.continuous, f.continuous)
+			assert.Equal(tc.metricsAddr, f.metricsAddr)
+		})
+	}
+}
+
+func metricValue(t *testing.T, metrics *verifyMetrics, result string) float64 {
+	t.Helper()
+	families, err := metrics.registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "constellation_attestation_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "result" && label.GetValue() == result {
+					return metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	t.Fatalf("no counter for result %q", result)
+	return 0
+}
@@ -0,0 +1,64 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/edgelesssys/constellation/v2/internal/atls"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/verify"
+	"github.com/spf13/cobra"
+)
+
+// endpointUnreachableError is returned by a verifyClient if no attestation could be requested from the endpoint,
+// because it didn't respond.
+type endpointUnreachableError struct {
+	endpoint string
+	err      error
+}
+
+// Error returns the error message.
+func (e *endpointUnreachableError) Error() string {
+	return fmt.Sprintf("endpoint %s is unreachable: %s", e.endpoint, e.err)
+}
+
+// Unwrap returns the error of the connection attempt.
+func (e *endpointUnreachableError) Unwrap() error {
+	return e.err
+}
+
+// verifyEndpoints verifies the first of the candidate endpoints that responds and returns the endpoint it used.
+// Only unreachable endpoints are skipped. If an endpoint responds, but its attestation can't be verified,
+// the verification fails, so that a failover endpoint can't hide a node that doesn't pass verification.
+func (c *verifyCmd) verifyEndpoints(
+	cmd *cobra.Command, verifyClient verifyClient, endpoints []string, nonce []byte, validator atls.Validator, attConfig config.AttestationCfg,
+	recoveryTarget *verify.TCBVersion,
+) (string, []byte, error) {
+	var unreachableErrs []error
+	for i, endpoint := range endpoints {
+		rawAttestationDoc, err := c.verifyNode(cmd, verifyClient, endpoint, nonce, validator, attConfig, recoveryTarget)
+		var unreachableErr *endpointUnreachableError
+		if !errors.As(err, &unreachableErr) {
+			if err == nil && len(endpoints) > 1 {
+				cmd.PrintErrf("Verified endpoint %s\n", endpoint)
+			}
+			return endpoint, rawAttestationDoc, err
+		}
+
+		c.log.Debug("Endpoint is unreachable", "endpoint", endpoint, "error", err)
+		unreachableErrs = append(unreachableErrs, err)
+		if i < len(endpoints)-1 {
+			cmd.PrintErrf("Endpoint %s is unreachable, trying %s\n", endpoint, endpoints[i+1])
+		}
+	}
+	if len(unreachableErrs) == 1 {
+		return endpoints[0], nil, unreachableErrs[0]
+	}
+	return endpoints[len(endpoints)-1], nil, fmt.Errorf("none of the endpoints responded: %w", errors.Join(unreachableErrs...))
+}
@@ -0,0 +1,140 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/atls"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/edgelesssys/constellation/v2/verify/verifyproto"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyEndpoints(t *testing.T) {
+	unreachable := func(endpoint string) error {
+		return &endpointUnreachableError{endpoint: endpoint, err: errors.New("connection refused")}
+	}
+
+	testCases := map[string]struct {
+		endpoints     []string
+		errs          map[string]error
+		wantTried     []string
+		wantEndpoint  string
+		wantFailover  bool
+		wantErr       bool
+		wantReachable bool
+	}{
+		"first endpoint responds": {
+			endpoints:    []string{"192.0.2.1", "198.51.100.1:1234"},
+			wantTried:    []string{"192.0.2.1:30081"},
+			wantEndpoint: "192.0.2.1:30081",
+		},
+		"first endpoint unreachable, second succeeds": {
+			endpoints:    []string{"192.0.2.1", "198.51.100.1:1234"},
+			errs:         map[string]error{"192.0.2.1:30081": unreachable("192.0.2.1:30081")},
+			wantTried:    []string{"192.0.2.1:30081", "198.51.100.1:1234"},
+			wantEndpoint: "198.51.100.1:1234",
+			wantFailover: true,
+		},
+		"all endpoints unreachable": {
+			endpoints: []string{"192.0.2.1", "198.51.100.1:1234"},
+			errs: map[string]error{
+				"192.0.2.1:30081":   unreachable("192.0.2.1:30081"),
+				"198.51.100.1:1234": unreachable("198.51.100.1:1234"),
+			},
+			wantTried:    []string{"192.0.2.1:30081", "198.51.100.1:1234"},
+			wantEndpoint: "198.51.100.1:1234",
+			wantFailover: true,
+			wantErr:      true,
+		},
+		"failed attestation doesn't fail over": {
+			endpoints:     []string{"192.0.2.1", "198.51.100.1:1234"},
+			errs:          map[string]error{"192.0.2.1:30081": errors.New("measurement validation failed")},
+			wantTried:     []string{"192.0.2.1:30081"},
+			wantEndpoint:  "192.0.2.1:30081",
+			wantErr:       true,
+			wantReachable: true,
+		},
+		"invalid endpoint": {
+			endpoints: []string{"192.0.2.1", ""},
+			wantErr:   true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			cmd := NewVerifyCmd()
+			var stdout, stderr bytes.Buffer
+			cmd.SetOut(&stdout)
+			cmd.SetErr(&stderr)
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			require.NoError(fileHandler.WriteYAML(constants.ConfigFilename, defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.GCP)))
+
+			client := &stubFailoverVerifyClient{errs: tc.errs}
+			v := &verifyCmd{
+				fileHandler: fileHandler,
+				log:         logger.NewTest(t),
+				flags: verifyFlags{
+					clusterID:    base64.StdEncoding.EncodeToString([]byte("00000000000000000000000000000000")),
+					endpoints:    tc.endpoints,
+					reportFormat: reportFormatJUnit,
+				},
+			}
+			err := v.verify(cmd, client, stubAttestationFetcher{})
+			assert.Equal(tc.wantTried, client.tried)
+			assert.Equal(tc.wantFailover, bytes.Contains(stderr.Bytes(), []byte("is unreachable, trying")))
+			if tc.wantEndpoint == "" {
+				assert.Error(err)
+				return
+			}
+
+			report := parseJUnit(t, stdout.Bytes())
+			require.Len(report.TestSuites, 1)
+			require.Len(report.TestSuites[0].TestCases, 1)
+			testCase := report.TestSuites[0].TestCases[0]
+			assert.Equal(tc.wantEndpoint, testCase.Name)
+			if tc.wantErr {
+				assert.Error(err)
+				require.NotNil(testCase.Failure)
+				var unreachableErr *endpointUnreachableError
+				assert.Equal(!tc.wantReachable, errors.As(err, &unreachableErr))
+				return
+			}
+			assert.NoError(err)
+			assert.Nil(testCase.Failure)
+			assert.Contains(stderr.String(), "Verified endpoint "+tc.wantEndpoint)
+		})
+	}
+}
+
+// stubFailoverVerifyClient fails the verification of the endpoints in errs and records the tried endpoints.
+type stubFailoverVerifyClient struct {
+	errs  map[string]error
+	tried []string
+}
+
+func (c *stubFailoverVerifyClient) Verify(_ context.Context, endpoint string, _ *verifyproto.GetAttestationRequest, _ atls.Validator) ([]byte, error) {
+	c.tried = append(c.tried, endpoint)
+	if err := c.errs[endpoint]; err != nil {
+		return nil, err
+	}
+	return []byte("attestation"), nil
+}
@@ -0,0 +1,144 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation/measurements"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/sigstore"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+const (
+	// envVarCosignPassword is the environment variable holding the password of the key the evidence is signed with.
+	envVarCosignPassword = "COSIGN_PASSWORD"
+
+	// inTotoStatementType is the type of an in-toto attestation statement.
+	inTotoStatementType = "https://in-toto.io/Statement/v1"
+	// inTotoPayloadType is the DSSE payload type of an in-toto attestation statement.
+	inTotoPayloadType = "application/vnd.in-toto+json"
+	// verificationPredicateType is the type of the predicate describing the verification of a node.
+	verificationPredicateType = "https://constellation.edgeless.systems/attestation/verification/v1"
+	// verificationResultPassed is the result of a successful verification.
+	verificationResultPassed = "PASSED"
+)
+
+// inTotoStatement is an in-toto attestation statement, see https://github.com/in-toto/attestation/blob/main/spec/v1/statement.md.
+type inTotoStatement struct {
+	Type          string                `json:"_type"`
+	Subject       []inTotoSubject       `json:"subject"`
+	PredicateType string                `json:"predicateType"`
+	Predicate     verificationPredicate `json:"predicate"`
+}
+
+// inTotoSubject is an artifact an in-toto statement makes claims about, identified by its digests.
+type inTotoSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// verificationPredicate describes the verification of the attestation document of a node.
+type verificationPredicate struct {
+	Verifier           verificationVerifier `json:"verifier"`
+	VerifiedAt         time.Time            `json:"verifiedAt"`
+	Endpoint           string               `json:"endpoint"`
+	ClusterID          string               `json:"clusterID,omitempty"`
+	AttestationVariant string               `json:"attestationVariant"`
+	Nonce              string               `json:"nonce"`
+	Measurements       measurements.M       `json:"measurements,omitempty"`
+	Result             string               `json:"result"`
+}
+
+// verificationVerifier identifies the CLI that verified the node.
+type verificationVerifier struct {
+	ID      string `json:"id"`
+	Version string `json:"version"`
+}
+
+// verificationEvidence is the evidence of the successful verification of a node.
+type verificationEvidence struct {
+	endpoint           string
+	clusterID          string
+	attestationVariant string
+	nonce              []byte
+	measurements       measurements.M
+	rawAttestationDoc  []byte
+	verifiedAt         time.Time
+}
+
+// newVerificationStatement returns an in-toto statement whose subject is the verified attestation document.
+func newVerificationStatement(evidence verificationEvidence) inTotoStatement {
+	digest := sha256.Sum256(evidence.rawAttestationDoc)
+	return inTotoStatement{
+		Type: inTotoStatementType,
+		Subject: []inTotoSubject{{
+			Name:   "attestation-document",
+			Digest: map[string]string{"sha256": hex.EncodeToString(digest[:])},
+		}},
+		PredicateType: verificationPredicateType,
+		Predicate: verificationPredicate{
+			Verifier: verificationVerifier{
+				ID:      "constellation-cli",
+				Version: constants.BinaryVersion().String(),
+			},
+			VerifiedAt:         evidence.verifiedAt.UTC(),
+			Endpoint:           evidence.endpoint,
+			ClusterID:          evidence.clusterID,
+			AttestationVariant: evidence.attestationVariant,
+			Nonce:              hex.EncodeToString(evidence.nonce),
+			Measurements:       evidence.measurements,
+			Result:             verificationResultPassed,
+		},
+	}
+}
+
+// signVerificationEvidence returns the in-toto statement of the evidence in a DSSE envelope,
+// signed with the encrypted cosign private key.
+func signVerificationEvidence(ctx context.Context, evidence verificationEvidence, privateKey, password []byte) ([]byte, error) {
+	statement, err := json.Marshal(newVerificationStatement(evidence))
+	if err != nil {
+		return nil, fmt.Errorf("marshalling in-toto statement: %w", err)
+	}
+	envelopeSigner, err := dsse.NewEnvelopeSigner(cosignEnvelopeSigner{privateKey: privateKey, password: password})
+	if err != nil {
+		return nil, fmt.Errorf("creating envelope signer: %w", err)
+	}
+	envelope, err := envelopeSigner.SignPayload(ctx, inTotoPayloadType, statement)
+	if err != nil {
+		return nil, fmt.Errorf("signing in-toto statement: %w", err)
+	}
+	return json.MarshalIndent(envelope, "", "  ")
+}
+
+// cosignEnvelopeSigner signs DSSE envelopes with an encrypted cosign private key.
+type cosignEnvelopeSigner struct {
+	privateKey []byte
+	password   []byte
+}
+
+// Sign signs the pre-authentication encoding of the envelope.
+func (s cosignEnvelopeSigner) Sign(_ context.Context, data []byte) ([]byte, error) {
+	sig, err := sigstore.SignContent(s.password, s.privateKey, data)
+	if err != nil {
+		return nil, err
+	}
+	// The envelope signer encodes the signature itself.
+	return base64.StdEncoding.DecodeString(string(sig))
+}
+
+// KeyID returns an empty key ID, since the key is identified by the public key the verifier uses.
+func (s cosignEnvelopeSigner) KeyID() (string, error) {
+	return "", nil
+}
@@ -0,0 +1,52 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation"
+)
+
+// traceLogger is a validator logger that records the verification steps of the validator for --explain.
+type traceLogger struct {
+	warnLog
+	steps []attestation.TraceStep
+}
+
+// Trace records a verification step.
+func (l *traceLogger) Trace(step attestation.TraceStep) {
+	l.steps = append(l.steps, step)
+}
+
+// writeTrace writes the recorded verification steps with their outcome and the values involved.
+func writeTrace(w io.Writer, steps []attestation.TraceStep) error {
+	if len(steps) == 0 {
+		_, err := fmt.Fprintln(w, "No verification steps were recorded.")
+		return err
+	}
+
+	if _, err := fmt.Fprintln(w, "Verification steps:"); err != nil {
+		return err
+	}
+	for i, step := range steps {
+		outcome := "OK"
+		if step.Err != nil {
+			outcome = fmt.Sprintf("FAILED: %s", step.Err)
+		}
+		if _, err := fmt.Fprintf(w, "%3d. %s: %s\n", i+1, step.Name, outcome); err != nil {
+			return err
+		}
+		for j := 0; j+1 < len(step.Values); j += 2 {
+			if _, err := fmt.Fprintf(w, "       %v: %+v\n", step.Values[j], step.Values[j+1]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
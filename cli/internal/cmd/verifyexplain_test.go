@@ -0,0 +1,99 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation/snp"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/snp/testdata"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceEmbeddedReport(t *testing.T) {
+	tooHighSVN := uint8(255)
+
+	testCases := map[string]struct {
+		minSVN     *uint8
+		wantSteps  []string
+		wantFailed string
+		wantErr    bool
+	}{
+		"report is valid": {
+			wantSteps: []string{
+				"parse report",
+				"fetch VCEK",
+				"fetch certificate chain",
+				"validate certificate chain",
+				"check TCB and guest policy",
+				"check microcode SVN",
+				"check VMPL",
+			},
+		},
+		"microcode SVN below minimum": {
+			minSVN: &tooHighSVN,
+			wantSteps: []string{
+				"parse report",
+				"fetch VCEK",
+				"fetch certificate chain",
+				"validate certificate chain",
+				"check TCB and guest policy",
+				"check microcode SVN",
+			},
+			wantFailed: "check microcode SVN",
+			wantErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			cfg := config.DefaultForAzureSEVSNP()
+			cfg.BootloaderVersion = config.AttestationVersion[uint8]{Value: 0}
+			cfg.TEEVersion = config.AttestationVersion[uint8]{Value: 0}
+			cfg.SNPVersion = config.AttestationVersion[uint8]{Value: 0}
+			cfg.MicrocodeVersion = config.AttestationVersion[uint8]{Value: 0}
+			cfg.MinMicrocodeSVN = tc.minSVN
+			policy, err := newOfflineSNPPolicy(cfg)
+			require.NoError(err)
+
+			tracer := &traceLogger{warnLog: warnLogger{cmd: NewVerifyCmd(), log: logger.NewTest(t)}}
+			err = policy.verify(snp.InstanceInfo{
+				AttestationReport: testdata.AttestationReport,
+				ReportSigner:      testdata.AzureThimVCEK,
+				CertChain:         testdata.CertChain,
+			}, tracer)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+
+			var steps []string
+			for _, step := range tracer.steps {
+				steps = append(steps, step.Name)
+				if step.Name == tc.wantFailed {
+					assert.Error(step.Err, step.Name)
+				} else {
+					assert.NoError(step.Err, step.Name)
+				}
+			}
+			assert.Equal(tc.wantSteps, steps)
+
+			var out bytes.Buffer
+			require.NoError(writeTrace(&out, tracer.steps))
+			assert.Contains(out.String(), "  1. parse report: OK\n")
+			assert.Contains(out.String(), "       vmpl: 0\n")
+		})
+	}
+}
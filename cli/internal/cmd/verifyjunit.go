@@ -0,0 +1,140 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// reportFormatText prints the verification results as human readable text.
+	reportFormatText = "text"
+	// reportFormatJUnit writes the verification results as JUnit XML report, with one test case per node or report.
+	reportFormatJUnit = "junit"
+)
+
+// parseReportFormat checks that format is a supported report format.
+func parseReportFormat(format string) (string, error) {
+	switch format {
+	case reportFormatText, reportFormatJUnit:
+		return format, nil
+	default:
+		return "", fmt.Errorf("invalid report format %q, must be one of {%s, %s}", format, reportFormatText, reportFormatJUnit)
+	}
+}
+
+// verifyResult is the result of the verification of a single node or attestation report.
+type verifyResult struct {
+	// name identifies the verified node or report.
+	name     string
+	duration time.Duration
+	// err is the reason the verification failed, or nil if it succeeded.
+	err error
+	// measurement is the launch measurement of the image the node reported, if it was verified.
+	measurement []byte
+}
+
+// newJUnitTestSuites returns a JUnit report of the verification results, with one test case per result.
+// The failure class of a failed verification is reported as the type of its failure.
+func newJUnitTestSuites(suiteName string, results []verifyResult) junitTestSuites {
+	suite := junitTestSuite{
+		Name:      suiteName,
+		Tests:     len(results),
+		TestCases: make([]junitTestCase, 0, len(results)),
+	}
+	var total time.Duration
+	for _, result := range results {
+		testCase := junitTestCase{
+			Name:      result.name,
+			ClassName: suiteName,
+			Time:      junitSeconds(result.duration),
+		}
+		if result.err != nil {
+			suite.Failures++
+			testCase.Failure = &junitFailure{
+				Message: result.err.Error(),
+				Type:    sarifRuleID(result.err),
+				Text:    fmt.Sprintf("Verification of %s failed: %s", result.name, result.err),
+			}
+		}
+		total += result.duration
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+	suite.Time = junitSeconds(total)
+
+	return junitTestSuites{
+		Name:       "constellation verify",
+		Tests:      suite.Tests,
+		Failures:   suite.Failures,
+		Time:       suite.Time,
+		TestSuites: []junitTestSuite{suite},
+	}
+}
+
+// writeJUnit writes the verification results as JUnit XML report to out.
+// An error is returned if any verification failed, so a failed verification still fails the command.
+func writeJUnit(out io.Writer, suiteName string, results []verifyResult) error {
+	var verifyErr error
+	for _, result := range results {
+		if result.err != nil {
+			verifyErr = errors.Join(verifyErr, fmt.Errorf("verifying %s: %w", result.name, result.err))
+		}
+	}
+
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return errors.Join(verifyErr, fmt.Errorf("writing JUnit report: %w", err))
+	}
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(newJUnitTestSuites(suiteName, results)); err != nil {
+		return errors.Join(verifyErr, fmt.Errorf("writing JUnit report: %w", err))
+	}
+	if _, err := io.WriteString(out, "\n"); err != nil {
+		return errors.Join(verifyErr, fmt.Errorf("writing JUnit report: %w", err))
+	}
+	return verifyErr
+}
+
+// junitSeconds formats d as seconds, the unit of durations in JUnit reports.
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// junitTestSuites is the root element of a JUnit XML report.
+type junitTestSuites struct {
+	XMLName    xml.Name         `xml:"testsuites"`
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Time       string           `xml:"time,attr"`
+	TestSuites []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
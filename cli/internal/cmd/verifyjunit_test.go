@@ -0,0 +1,255 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation/snp"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/snp/testdata"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/vtpm"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	snpabi "github.com/google/go-sev-guest/abi"
+	"github.com/google/go-tpm-tools/proto/attest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	testCases := map[string]struct {
+		results      []verifyResult
+		wantFailures map[string]string
+		wantErr      bool
+	}{
+		"all nodes pass": {
+			results: []verifyResult{
+				{name: "192.0.2.1:30081", duration: time.Second},
+				{name: "192.0.2.2:30081", duration: 2 * time.Second},
+			},
+			wantFailures: map[string]string{},
+		},
+		"mix of passed and failed nodes": {
+			results: []verifyResult{
+				{name: "192.0.2.1:30081", duration: time.Second},
+				{
+					name: "192.0.2.2:30081",
+					err:  fmt.Errorf("validating attestation: %w", &snp.VMPLError{Reported: 1, Expected: 0}),
+				},
+				{name: "192.0.2.3:30081"},
+				{
+					name: "192.0.2.4:30081",
+					err:  errors.New("measurement validation failed: untrusted measurement value 0404 at index 4"),
+				},
+			},
+			wantFailures: map[string]string{
+				"192.0.2.2:30081": sarifRuleVMPLMismatch,
+				"192.0.2.4:30081": sarifRuleMeasurementMismatch,
+			},
+			wantErr: true,
+		},
+		"failure reason is escaped": {
+			results: []verifyResult{
+				{name: "node", err: errors.New(`unexpected value <"a" & 'b'>`)},
+			},
+			wantFailures: map[string]string{"node": sarifRuleAttestationFailure},
+			wantErr:      true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var out bytes.Buffer
+			err := writeJUnit(&out, "verify", tc.results)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+
+			report := parseJUnit(t, out.Bytes())
+			require.Len(report.TestSuites, 1)
+			suite := report.TestSuites[0]
+			assert.Equal(len(tc.results), report.Tests)
+			assert.Equal(len(tc.wantFailures), report.Failures)
+			assert.Equal(len(tc.results), suite.Tests)
+			assert.Equal(len(tc.wantFailures), suite.Failures)
+
+			require.Len(suite.TestCases, len(tc.results))
+			for i, testCase := range suite.TestCases {
+				assert.Equal(tc.results[i].name, testCase.Name)
+				wantType, wantFailure := tc.wantFailures[testCase.Name]
+				if !wantFailure {
+					assert.Nil(testCase.Failure)
+					continue
+				}
+				require.NotNil(testCase.Failure)
+				assert.Equal(wantType, testCase.Failure.Type)
+				assert.Equal(tc.results[i].err.Error(), testCase.Failure.Message)
+				assert.Contains(testCase.Failure.Text, tc.results[i].err.Error())
+			}
+		})
+	}
+}
+
+func TestVerifyReportsJUnit(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	certs := append(append([]byte{}, testdata.AzureThimVCEK...), testdata.CertChain...)
+	tamperedReport := bytes.Clone(testdata.AttestationReport)
+	tamperedReport[0x50] ^= 0xFF
+
+	fileHandler := file.NewHandler(afero.NewMemMapFs())
+	for name, content := range map[string][]byte{
+		"valid.bin":    testdata.AttestationReport,
+		"valid.pem":    certs,
+		"tampered.bin": tamperedReport,
+		"tampered.pem": certs,
+		"missing.bin":  testdata.AttestationReport,
+	} {
+		require.NoError(fileHandler.Write(filepath.Join("reports", name), content, file.OptMkdirAll))
+	}
+
+	cfg := config.DefaultForAzureSEVSNP()
+	cfg.BootloaderVersion = config.AttestationVersion[uint8]{Value: 0}
+	cfg.TEEVersion = config.AttestationVersion[uint8]{Value: 0}
+	cfg.SNPVersion = config.AttestationVersion[uint8]{Value: 0}
+	cfg.MicrocodeVersion = config.AttestationVersion[uint8]{Value: 0}
+
+	cmd := newVerifyBatchCmd()
+	var stdout, stderr bytes.Buffer
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+
+	v := &verifyBatchCmd{
+		fileHandler: fileHandler,
+		flags:       verifyBatchFlags{dir: "reports", reportFormat: reportFormatJUnit},
+		log:         logger.NewTest(t),
+	}
+	assert.Error(v.verifyReports(cmd, cfg))
+	assert.NotContains(stderr.String(), "PASS")
+
+	report := parseJUnit(t, stdout.Bytes())
+	require.Len(report.TestSuites, 1)
+	suite := report.TestSuites[0]
+	assert.Equal(3, suite.Tests)
+	assert.Equal(2, suite.Failures)
+
+	failed := map[string]bool{}
+	for _, testCase := range suite.TestCases {
+		failed[testCase.Name] = testCase.Failure != nil
+	}
+	assert.Equal(map[string]bool{"valid.bin": false, "tampered.bin": true, "missing.bin": true}, failed)
+}
+
+func TestVerifyJUnit(t *testing.T) {
+	instanceInfo, err := json.Marshal(snp.InstanceInfo{AttestationReport: testdata.AttestationReport[:snpabi.ReportSize]})
+	require.NoError(t, err)
+	attDoc, err := json.Marshal(vtpm.AttestationDocument{Attestation: &attest.Attestation{}, InstanceInfo: instanceInfo})
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		verifyErr   error
+		wantFailure bool
+	}{
+		"verification succeeds": {},
+		"verification fails": {
+			verifyErr:   errors.New("signed data in attestation does not match expected user data"),
+			wantFailure: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			cmd := NewVerifyCmd()
+			var stdout, stderr bytes.Buffer
+			cmd.SetOut(&stdout)
+			cmd.SetErr(&stderr)
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			require.NoError(fileHandler.WriteYAML(constants.ConfigFilename, defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.Azure)))
+
+			v := &verifyCmd{
+				fileHandler: fileHandler,
+				log:         logger.NewTest(t),
+				flags: verifyFlags{
+					clusterID:    base64.StdEncoding.EncodeToString([]byte("00000000000000000000000000000000")),
+					endpoint:     "192.0.2.1:1234",
+					reportFormat: reportFormatJUnit,
+				},
+			}
+			err := v.verify(cmd, &stubVerifyClient{attestationDoc: attDoc, verifyErr: tc.verifyErr}, stubAttestationFetcher{})
+
+			report := parseJUnit(t, stdout.Bytes())
+			require.Len(report.TestSuites, 1)
+			require.Len(report.TestSuites[0].TestCases, 1)
+			testCase := report.TestSuites[0].TestCases[0]
+			assert.Equal("192.0.2.1:1234", testCase.Name)
+			if !tc.wantFailure {
+				assert.NoError(err)
+				assert.Nil(testCase.Failure)
+				assert.Contains(stderr.String(), "OK")
+				return
+			}
+			assert.Error(err)
+			assert.NotContains(stderr.String(), "OK")
+			require.NotNil(testCase.Failure)
+			assert.Contains(testCase.Failure.Message, tc.verifyErr.Error())
+		})
+	}
+}
+
+func TestParseReportFormat(t *testing.T) {
+	testCases := map[string]struct {
+		format  string
+		wantErr bool
+	}{
+		"text":    {format: reportFormatText},
+		"junit":   {format: reportFormatJUnit},
+		"empty":   {format: "", wantErr: true},
+		"unknown": {format: "sarif", wantErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			format, err := parseReportFormat(tc.format)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.format, format)
+		})
+	}
+}
+
+// parseJUnit parses a JUnit XML report and checks that it is a well-formed XML document.
+func parseJUnit(t *testing.T, raw []byte) junitTestSuites {
+	t.Helper()
+	require.True(t, bytes.HasPrefix(raw, []byte(xml.Header)), "report must start with an XML header")
+	var report junitTestSuites
+	require.NoError(t, xml.Unmarshal(raw, &report))
+	return report
+}
@@ -0,0 +1,60 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/edgelesssys/constellation/v2/internal/file"
+)
+
+// loadMutualTLSConfig loads the client certificate used to request an attestation from node endpoints
+// that only accept authorized verifiers. It returns nil if no client certificate is configured.
+//
+// The node's TLS certificate is only verified if a CA certificate is given.
+// Otherwise, the node is authenticated by its attestation alone, as it is for unencrypted connections.
+func loadMutualTLSConfig(fileHandler file.Handler, certPath, keyPath, caCertPath string) (*tls.Config, error) {
+	if certPath == "" {
+		return nil, nil
+	}
+
+	certPEM, err := fileHandler.Read(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading client certificate: %w", err)
+	}
+	keyPEM, err := fileHandler.Read(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading client key: %w", err)
+	}
+	clientCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("loading client certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{clientCert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if caCertPath == "" {
+		tlsConfig.InsecureSkipVerify = true // the node is verified by its attestation
+		return tlsConfig, nil
+	}
+
+	caCertPEM, err := fileHandler.Read(caCertPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading node CA certificate: %w", err)
+	}
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(caCertPEM) {
+		return nil, errors.New("node CA certificate doesn't contain a PEM encoded certificate")
+	}
+	tlsConfig.RootCAs = rootCAs
+	return tlsConfig, nil
+}
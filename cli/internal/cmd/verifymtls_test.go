@@ -0,0 +1,343 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/atls"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/variant"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/grpc/dialer"
+	"github.com/edgelesssys/constellation/v2/internal/grpc/testdialer"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/edgelesssys/constellation/v2/verify/verifyproto"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+func TestVerifyClientMutualTLS(t *testing.T) {
+	nodeCA := newTestCA(t, "node CA")
+	clientCA := newTestCA(t, "client CA")
+	otherCA := newTestCA(t, "other CA")
+	serverCertPEM, serverKeyPEM := nodeCA.issue(t, "192.0.2.1", false)
+	clientCertPEM, clientKeyPEM := clientCA.issue(t, "verifier", true)
+	untrustedCertPEM, untrustedKeyPEM := otherCA.issue(t, "verifier", true)
+
+	testCases := map[string]struct {
+		clientCert []byte
+		clientKey  []byte
+		nodeCACert []byte
+		wantErr    bool
+	}{
+		"client certificate": {
+			clientCert: clientCertPEM,
+			clientKey:  clientKeyPEM,
+		},
+		"client certificate and node CA": {
+			clientCert: clientCertPEM,
+			clientKey:  clientKeyPEM,
+			nodeCACert: nodeCA.certPEM,
+		},
+		"no client certificate": {
+			wantErr: true,
+		},
+		"untrusted client certificate": {
+			clientCert: untrustedCertPEM,
+			clientKey:  untrustedKeyPEM,
+			wantErr:    true,
+		},
+		"node certificate from other CA": {
+			clientCert: clientCertPEM,
+			clientKey:  clientKeyPEM,
+			nodeCACert: otherCA.certPEM,
+			wantErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			var certPath, keyPath, caCertPath string
+			if tc.clientCert != nil {
+				certPath, keyPath = "client.crt", "client.key"
+				require.NoError(fileHandler.Write(certPath, tc.clientCert))
+				require.NoError(fileHandler.Write(keyPath, tc.clientKey))
+			}
+			if tc.nodeCACert != nil {
+				caCertPath = "node-ca.crt"
+				require.NoError(fileHandler.Write(caCertPath, tc.nodeCACert))
+			}
+			tlsConfig, err := loadMutualTLSConfig(fileHandler, certPath, keyPath, caCertPath)
+			require.NoError(err)
+
+			serverCert, err := tls.X509KeyPair(serverCertPEM, serverKeyPEM)
+			require.NoError(err)
+			clientCAs := x509.NewCertPool()
+			require.True(clientCAs.AppendCertsFromPEM(clientCA.certPEM))
+			serverCreds := credentials.NewTLS(&tls.Config{
+				Certificates: []tls.Certificate{serverCert},
+				ClientCAs:    clientCAs,
+				ClientAuth:   tls.RequireAndVerifyClientCert,
+				MinVersion:   tls.VersionTLS12,
+			})
+
+			attestation, err := json.Marshal(atls.FakeAttestationDoc{
+				UserData: []byte(constants.ConstellationVerifyServiceUserData),
+				Nonce:    []byte("nonce"),
+			})
+			require.NoError(err)
+			verifyAPI := &stubVerifyAPI{attestation: &verifyproto.GetAttestationResponse{Attestation: attestation}}
+
+			netDialer := testdialer.NewBufconnDialer()
+			verifyServer := grpc.NewServer(grpc.Creds(serverCreds))
+			verifyproto.RegisterAPIServer(verifyServer, verifyAPI)
+			addr := net.JoinHostPort("192.0.2.1", strconv.Itoa(constants.VerifyServiceNodePortGRPC))
+			go verifyServer.Serve(netDialer.GetListener(addr))
+			defer verifyServer.Stop()
+
+			verifier := &constellationVerifier{
+				fetcher: &grpcReportFetcher{
+					dialer:    dialer.New(nil, nil, netDialer),
+					tlsConfig: tlsConfig,
+					log:       logger.NewTest(t),
+				},
+				log: logger.NewTest(t),
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_, err = verifier.Verify(ctx, addr, &verifyproto.GetAttestationRequest{Nonce: []byte("nonce")}, atls.NewFakeValidator(variant.Dummy{}))
+
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
+
+func TestVerifyClientSNI(t *testing.T) {
+	testCases := map[string]struct {
+		serverName     string
+		endpointHost   string
+		wantServerName string
+	}{
+		"sni override": {
+			serverName:     "node.constellation.example.com",
+			endpointHost:   "localhost",
+			wantServerName: "node.constellation.example.com",
+		},
+		"sni defaults to endpoint host": {
+			endpointHost:   "localhost",
+			wantServerName: "localhost",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			attestation, err := json.Marshal(atls.FakeAttestationDoc{
+				UserData: []byte(constants.ConstellationVerifyServiceUserData),
+				Nonce:    []byte("nonce"),
+			})
+			require.NoError(err)
+			verifyServer := grpc.NewServer()
+			verifyproto.RegisterAPIServer(verifyServer, &stubVerifyAPI{attestation: &verifyproto.GetAttestationResponse{Attestation: attestation}})
+
+			// The proxy in front of the node routes connections by the SNI of the client.
+			server := httptest.NewUnstartedServer(verifyServer)
+			server.EnableHTTP2 = true
+			serverNames := make(chan string, 1)
+			server.TLS = &tls.Config{
+				GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+					serverNames <- hello.ServerName
+					return nil, nil
+				},
+			}
+			server.StartTLS()
+			defer server.Close()
+			_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+			require.NoError(err)
+
+			verifier := &constellationVerifier{
+				fetcher: &grpcReportFetcher{
+					dialer:     dialer.New(nil, nil, &net.Dialer{}),
+					tlsConfig:  &tls.Config{InsecureSkipVerify: true, MinVersion: tls.VersionTLS12},
+					serverName: tc.serverName,
+					log:        logger.NewTest(t),
+				},
+				log: logger.NewTest(t),
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_, err = verifier.Verify(ctx, net.JoinHostPort(tc.endpointHost, port),
+				&verifyproto.GetAttestationRequest{Nonce: []byte("nonce")}, atls.NewFakeValidator(variant.Dummy{}))
+			require.NoError(err)
+			assert.Equal(tc.wantServerName, <-serverNames)
+		})
+	}
+}
+
+func TestLoadMutualTLSConfig(t *testing.T) {
+	ca := newTestCA(t, "client CA")
+	certPEM, keyPEM := ca.issue(t, "verifier", true)
+
+	testCases := map[string]struct {
+		files      map[string][]byte
+		certPath   string
+		keyPath    string
+		caCertPath string
+		wantNil    bool
+		wantErr    bool
+	}{
+		"no client certificate": {
+			wantNil: true,
+		},
+		"client certificate": {
+			files:    map[string][]byte{"client.crt": certPEM, "client.key": keyPEM},
+			certPath: "client.crt",
+			keyPath:  "client.key",
+		},
+		"client certificate and node CA": {
+			files:      map[string][]byte{"client.crt": certPEM, "client.key": keyPEM, "ca.crt": ca.certPEM},
+			certPath:   "client.crt",
+			keyPath:    "client.key",
+			caCertPath: "ca.crt",
+		},
+		"missing key": {
+			files:    map[string][]byte{"client.crt": certPEM},
+			certPath: "client.crt",
+			keyPath:  "client.key",
+			wantErr:  true,
+		},
+		"key doesn't match certificate": {
+			files:    map[string][]byte{"client.crt": certPEM, "client.key": pemEncodeKey(t, newTestKey(t))},
+			certPath: "client.crt",
+			keyPath:  "client.key",
+			wantErr:  true,
+		},
+		"invalid node CA": {
+			files:      map[string][]byte{"client.crt": certPEM, "client.key": keyPEM, "ca.crt": []byte("not a certificate")},
+			certPath:   "client.crt",
+			keyPath:    "client.key",
+			caCertPath: "ca.crt",
+			wantErr:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			for path, content := range tc.files {
+				require.NoError(fileHandler.Write(path, content))
+			}
+
+			tlsConfig, err := loadMutualTLSConfig(fileHandler, tc.certPath, tc.keyPath, tc.caCertPath)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			if tc.wantNil {
+				assert.Nil(tlsConfig)
+				return
+			}
+			assert.Len(tlsConfig.Certificates, 1)
+			assert.Equal(tc.caCertPath == "", tlsConfig.InsecureSkipVerify)
+			assert.Equal(tc.caCertPath != "", tlsConfig.RootCAs != nil)
+		})
+	}
+}
+
+type testCA struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key := newTestKey(t)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM encoded certificate and key signed by the CA.
+// Server certificates are issued for the IP address name.
+func (ca *testCA) issue(t *testing.T, name string, client bool) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key := newTestKey(t)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if client {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	} else {
+		template.IPAddresses = []net.IP{net.ParseIP(name)}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pemEncodeKey(t, key)
+}
+
+func newTestKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func pemEncodeKey(t *testing.T, key *ecdsa.PrivateKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
@@ -0,0 +1,37 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+)
+
+// staleReportError is returned if the attestation report of a node is older than the maximum report age.
+//
+// The attestation reports of the supported variants carry no timestamp the CLI can trust.
+// Instead, the report is bound to the nonce of the request, so it can't have been created before the request was sent.
+// The time between sending the nonce challenge and receiving the report is therefore an upper bound of the report's age.
+type staleReportError struct {
+	age    time.Duration
+	maxAge time.Duration
+}
+
+// Error returns the error message.
+func (e *staleReportError) Error() string {
+	return fmt.Sprintf("the attestation report was received %s after the nonce challenge was sent, which exceeds the maximum report age of %s",
+		e.age.Round(time.Millisecond), e.maxAge)
+}
+
+// checkReportAge returns a staleReportError if the report received at received for the challenge sent at requested
+// may be older than maxAge.
+func checkReportAge(requested, received time.Time, maxAge time.Duration) error {
+	if age := received.Sub(requested); age > maxAge {
+		return &staleReportError{age: age, maxAge: maxAge}
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation/snp"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/vtpm"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+)
+
+// SARIF rule IDs for the failure classes of verify.
+const (
+	sarifRuleMeasurementMismatch   = "measurement-mismatch"
+	sarifRuleTCBTooOld             = "tcb-too-old"
+	sarifRuleExpiredVCEK           = "expired-vcek"
+	sarifRuleChipIDMismatch        = "chip-id-mismatch"
+	sarifRuleVMPLMismatch          = "vmpl-mismatch"
+	sarifRuleDebugEnabled          = "debug-enabled"
+	sarifRuleReportVersionMismatch = "report-version-mismatch"
+	sarifRuleStaleReport           = "stale-report"
+	sarifRuleAttestationFailure    = "attestation-failure"
+)
+
+// sarifRules are the rules reported by verify, in the order of their rule index.
+var sarifRules = []sarifReportingDescriptor{
+	{
+		ID:               sarifRuleMeasurementMismatch,
+		Name:             "MeasurementMismatch",
+		ShortDescription: sarifMessage{Text: "A measurement of the node doesn't match its expected value."},
+		Help:             sarifMessage{Text: "Check that the measurements in the attestation config match the node image, or that the node hasn't been tampered with."},
+	},
+	{
+		ID:               sarifRuleTCBTooOld,
+		Name:             "TCBTooOld",
+		ShortDescription: sarifMessage{Text: "The TCB of the node's SEV-SNP firmware is lower than the configured minimum."},
+		Help:             sarifMessage{Text: "Update the firmware of the host, or lower the minimum TCB versions in the attestation config."},
+	},
+	{
+		ID:               sarifRuleExpiredVCEK,
+		Name:             "ExpiredVCEK",
+		ShortDescription: sarifMessage{Text: "The VCEK or VLEK certificate of the node's SEV-SNP report has expired or isn't valid yet."},
+		Help:             sarifMessage{Text: "Fetch a current report signing certificate for the node from AMD KDS or the cloud provider."},
+	},
+	{
+		ID:               sarifRuleChipIDMismatch,
+		Name:             "ChipIDMismatch",
+		ShortDescription: sarifMessage{Text: "The node's SEV-SNP report wasn't generated by a required chip."},
+		Help:             sarifMessage{Text: "Check the chip IDs passed with --require-chip-id."},
+	},
+	{
+		ID:               sarifRuleVMPLMismatch,
+		Name:             "VMPLMismatch",
+		ShortDescription: sarifMessage{Text: "The node's SEV-SNP report wasn't issued from the expected VMPL."},
+		Help:             sarifMessage{Text: "Check the VMPL configured in the attestation config."},
+	},
+	{
+		ID:               sarifRuleDebugEnabled,
+		Name:             "DebugEnabled",
+		ShortDescription: sarifMessage{Text: "The guest policy of the node's SEV-SNP report allows debugging the guest."},
+		Help:             sarifMessage{Text: "The host can decrypt the memory of debug-enabled guests. Don't use them in production."},
+	},
+	{
+		ID:               sarifRuleReportVersionMismatch,
+		Name:             "ReportVersionMismatch",
+		ShortDescription: sarifMessage{Text: "The node's SEV-SNP report has a different format version than expected."},
+		Help:             sarifMessage{Text: "Check the version passed with --expected-report-version. The format may have changed with a firmware update."},
+	},
+	{
+		ID:               sarifRuleStaleReport,
+		Name:             "StaleReport",
+		ShortDescription: sarifMessage{Text: "The node's attestation report may be older than the maximum report age."},
+		Help:             sarifMessage{Text: "Check the latency to the node, or increase --max-report-age."},
+	},
+	{
+		ID:               sarifRuleAttestationFailure,
+		Name:             "AttestationFailure",
+		ShortDescription: sarifMessage{Text: "The node's attestation couldn't be verified."},
+		Help:             sarifMessage{Text: "See the message of the result for the cause of the failure."},
+	},
+}
+
+// verifyFailure is a failed check of verify whose failure class is known.
+type verifyFailure struct {
+	ruleID string
+	err    error
+}
+
+// Error returns the error message.
+func (e *verifyFailure) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *verifyFailure) Unwrap() error {
+	return e.err
+}
+
+// sarifRuleID returns the ID of the SARIF rule for the given verification error.
+// Errors of the attestation libraries aren't always wrapped, so their messages are matched as a fallback.
+func sarifRuleID(err error) string {
+	var failure *verifyFailure
+	if errors.As(err, &failure) {
+		return failure.ruleID
+	}
+	var microcodeErr *snp.MicrocodeSVNError
+	if errors.As(err, &microcodeErr) {
+		return sarifRuleTCBTooOld
+	}
+	var vmplErr *snp.VMPLError
+	if errors.As(err, &vmplErr) {
+		return sarifRuleVMPLMismatch
+	}
+	var cmdlineErr *vtpm.KernelCmdlineError
+	if errors.As(err, &cmdlineErr) {
+		return sarifRuleMeasurementMismatch
+	}
+
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "measurement validation failed"):
+		return sarifRuleMeasurementMismatch
+	case strings.Contains(msg, "certificate has expired or is not yet valid"):
+		return sarifRuleExpiredVCEK
+	case strings.Contains(msg, "found unauthorized debug capability"):
+		return sarifRuleDebugEnabled
+	case strings.Contains(msg, "TCB") && strings.Contains(msg, "is lower than the"),
+		strings.Contains(msg, "is less than the required minimum"):
+		return sarifRuleTCBTooOld
+	default:
+		return sarifRuleAttestationFailure
+	}
+}
+
+// newSARIFLog returns a SARIF log with a result for the failed verification of the node at endpoint.
+// If verifyErr is nil, the log contains no results.
+func newSARIFLog(endpoint string, verifyErr error) sarifLog {
+	results := []sarifResult{}
+	if verifyErr != nil {
+		ruleID := sarifRuleID(verifyErr)
+		ruleIndex := 0
+		for i, rule := range sarifRules {
+			if rule.ID == ruleID {
+				ruleIndex = i
+			}
+		}
+		results = append(results, sarifResult{
+			RuleID:    ruleID,
+			RuleIndex: ruleIndex,
+			Level:     "error",
+			Message:   sarifMessage{Text: fmt.Sprintf("Verification of node %s failed: %s", endpoint, verifyErr)},
+			Locations: []sarifLocation{{
+				// The expected values of the failed checks are defined by the attestation config.
+				PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: constants.ConfigFilename}},
+				LogicalLocations: []sarifLogicalLocation{{Name: endpoint, Kind: "resource"}},
+			}},
+		})
+	}
+
+	return sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifToolComponent{
+				Name:           "constellation verify",
+				InformationURI: "https://docs.edgeless.systems/constellation/workflows/verify-cluster",
+				Version:        constants.BinaryVersion().String(),
+				Rules:          sarifRules,
+			}},
+			Results: results,
+		}},
+	}
+}
+
+// writeSARIF writes the result of the verification of the node at endpoint as SARIF log to out.
+// verifyErr is returned, so a failed verification still fails the command.
+func writeSARIF(out io.Writer, endpoint string, verifyErr error) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(newSARIFLog(endpoint, verifyErr)); err != nil {
+		return errors.Join(verifyErr, fmt.Errorf("writing SARIF log: %w", err))
+	}
+	return verifyErr
+}
+
+// sarifLog is the top-level object of a SARIF 2.1.0 log.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifToolComponent `json:"driver"`
+}
+
+type sarifToolComponent struct {
+	Name           string                     `json:"name"`
+	InformationURI string                     `json:"informationUri,omitempty"`
+	Version        string                     `json:"version,omitempty"`
+	Rules          []sarifReportingDescriptor `json:"rules"`
+}
+
+type sarifReportingDescriptor struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name,omitempty"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	Help             sarifMessage `json:"help"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifLogicalLocation struct {
+	Name string `json:"name"`
+	Kind string `json:"kind,omitempty"`
+}
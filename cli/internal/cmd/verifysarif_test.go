@@ -0,0 +1,198 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation/snp"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/snp/testdata"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/vtpm"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	snpabi "github.com/google/go-sev-guest/abi"
+	"github.com/google/go-tpm-tools/proto/attest"
+	tpmProto "github.com/google/go-tpm-tools/proto/tpm"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifySARIF(t *testing.T) {
+	zeroBase64 := base64.StdEncoding.EncodeToString([]byte("00000000000000000000000000000000"))
+
+	instanceInfo, err := json.Marshal(snp.InstanceInfo{AttestationReport: testdata.AttestationReport[:snpabi.ReportSize]})
+	require.NoError(t, err)
+	attDoc, err := json.Marshal(vtpm.AttestationDocument{
+		Attestation: &attest.Attestation{
+			Quotes: []*tpmProto.Quote{{
+				Pcrs: &tpmProto.PCRs{
+					Hash: tpmProto.HashAlgo_SHA256,
+					Pcrs: map[uint32][]byte{4: bytes.Repeat([]byte{0x04}, 32)},
+				},
+			}},
+		},
+		InstanceInfo: instanceInfo,
+	})
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		verifyErr    error
+		pcrOverrides map[uint32][]byte
+		chipIDs      [][]byte
+		wantRuleID   string
+	}{
+		"verification succeeds": {},
+		"measurement mismatch": {
+			verifyErr: fmt.Errorf("validating attestation: %w",
+				fmt.Errorf("measurement validation failed:\n%w", errors.Join(errors.New("untrusted measurement value 0404 at index 4")))),
+			wantRuleID: sarifRuleMeasurementMismatch,
+		},
+		"PCR override mismatch": {
+			pcrOverrides: map[uint32][]byte{4: bytes.Repeat([]byte{0xff}, 32)},
+			wantRuleID:   sarifRuleMeasurementMismatch,
+		},
+		"TCB too old": {
+			verifyErr: fmt.Errorf("validating attestation: %w",
+				errors.New("the report's reported TCB {BlSpl:2 TeeSpl:0 SnpSpl:6 UcodeSpl:93} is lower than the policy minimum TCB {BlSpl:3 TeeSpl:0 SnpSpl:8 UcodeSpl:115} in at least one component")),
+			wantRuleID: sarifRuleTCBTooOld,
+		},
+		"microcode SVN too old": {
+			verifyErr:  fmt.Errorf("validating attestation: %w", &snp.MicrocodeSVNError{Reported: 93, Minimum: 115}),
+			wantRuleID: sarifRuleTCBTooOld,
+		},
+		"expired VCEK": {
+			// go-sev-guest doesn't wrap certificate errors
+			verifyErr: fmt.Errorf("validating attestation: %w", fmt.Errorf("error verifying VCEK certificate: %v (true)",
+				x509.CertificateInvalidError{Reason: x509.Expired, Detail: "current time 2026-10-15T00:00:00Z is after 2025-10-15T00:00:00Z"})),
+			wantRuleID: sarifRuleExpiredVCEK,
+		},
+		"chip ID mismatch": {
+			chipIDs:    [][]byte{bytes.Repeat([]byte{0xff}, snpabi.ChipIDSize)},
+			wantRuleID: sarifRuleChipIDMismatch,
+		},
+		"VMPL mismatch": {
+			verifyErr:  fmt.Errorf("validating attestation: %w", &snp.VMPLError{Reported: 1, Expected: 0}),
+			wantRuleID: sarifRuleVMPLMismatch,
+		},
+		"kernel command line mismatch": {
+			verifyErr:  fmt.Errorf("validating kernel command line: %w", &vtpm.KernelCmdlineError{Cmdline: "console=ttyS0"}),
+			wantRuleID: sarifRuleMeasurementMismatch,
+		},
+		"unclassified failure": {
+			verifyErr:  errors.New("signed data in attestation does not match expected user data"),
+			wantRuleID: sarifRuleAttestationFailure,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			cmd := NewVerifyCmd()
+			var stdout, stderr bytes.Buffer
+			cmd.SetOut(&stdout)
+			cmd.SetErr(&stderr)
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			cfg := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.Azure)
+			require.NoError(fileHandler.WriteYAML(constants.ConfigFilename, cfg))
+
+			v := &verifyCmd{
+				fileHandler: fileHandler,
+				log:         logger.NewTest(t),
+				flags: verifyFlags{
+					clusterID:    zeroBase64,
+					endpoint:     "192.0.2.1:1234",
+					output:       "sarif",
+					pcrOverrides: tc.pcrOverrides,
+					chipIDs:      tc.chipIDs,
+				},
+			}
+			err := v.verify(cmd, &stubVerifyClient{attestationDoc: attDoc, verifyErr: tc.verifyErr}, stubAttestationFetcher{})
+
+			var log sarifLog
+			require.NoError(json.Unmarshal(stdout.Bytes(), &log))
+			validateSARIFLog(t, log)
+			results := log.Runs[0].Results
+
+			if tc.wantRuleID == "" {
+				assert.NoError(err)
+				assert.Empty(results)
+				assert.Contains(stderr.String(), "OK")
+				return
+			}
+			assert.Error(err)
+			assert.NotContains(stderr.String(), "OK")
+			require.Len(results, 1)
+			assert.Equal(tc.wantRuleID, results[0].RuleID)
+			assert.Contains(results[0].Message.Text, "192.0.2.1:1234")
+		})
+	}
+}
+
+func TestVerifySARIFWithTCBReport(t *testing.T) {
+	cmd := NewVerifyCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	fileHandler := file.NewHandler(afero.NewMemMapFs())
+	require.NoError(t, fileHandler.WriteYAML(constants.ConfigFilename, defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.Azure)))
+
+	v := &verifyCmd{
+		fileHandler: fileHandler,
+		log:         logger.NewTest(t),
+		flags: verifyFlags{
+			clusterID: base64.StdEncoding.EncodeToString([]byte("00000000000000000000000000000000")),
+			endpoint:  "192.0.2.1:1234",
+			output:    "sarif",
+			tcbReport: true,
+		},
+	}
+	assert.Error(t, v.verify(cmd, &stubVerifyClient{}, stubAttestationFetcher{}))
+}
+
+// validateSARIFLog checks that log fulfills the requirements of the SARIF 2.1.0 specification
+// for the properties verify writes.
+func validateSARIFLog(t *testing.T, log sarifLog) {
+	t.Helper()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	assert.Equal("2.1.0", log.Version)
+	assert.NotEmpty(log.Schema)
+	require.Len(log.Runs, 1)
+	driver := log.Runs[0].Tool.Driver
+	assert.NotEmpty(driver.Name)
+
+	ruleIDs := make(map[string]struct{}, len(driver.Rules))
+	for _, rule := range driver.Rules {
+		assert.NotEmpty(rule.ID)
+		assert.NotContains(ruleIDs, rule.ID, "rule IDs must be unique")
+		ruleIDs[rule.ID] = struct{}{}
+		assert.NotEmpty(rule.ShortDescription.Text)
+	}
+
+	for _, result := range log.Runs[0].Results {
+		require.Less(result.RuleIndex, len(driver.Rules))
+		assert.Equal(driver.Rules[result.RuleIndex].ID, result.RuleID, "ruleIndex must reference the rule of ruleId")
+		assert.Contains([]string{"none", "note", "warning", "error"}, result.Level)
+		assert.NotEmpty(result.Message.Text)
+		require.NotEmpty(result.Locations)
+		for _, location := range result.Locations {
+			assert.NotEmpty(location.PhysicalLocation.ArtifactLocation.URI)
+		}
+	}
+}
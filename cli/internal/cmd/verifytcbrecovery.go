@@ -0,0 +1,87 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/edgelesssys/constellation/v2/internal/api/attestationconfigapi"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/snp"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/verify"
+)
+
+// tcbRecoveryTarget returns the TCB versions the provider published for the attestation variant.
+// A platform is only accepted as recovering if it already runs firmware of at least these versions.
+func tcbRecoveryTarget(ctx context.Context, configFetcher attestationconfigapi.Fetcher, attConfig config.AttestationCfg) (verify.TCBVersion, error) {
+	published, err := configFetcher.FetchLatestVersion(ctx, attConfig.GetVariant())
+	if err != nil {
+		return verify.TCBVersion{}, fmt.Errorf("fetching published TCB versions of %s: %w", attConfig.GetVariant(), err)
+	}
+	return verify.TCBVersion{
+		Bootloader: published.Bootloader,
+		TEE:        published.TEE,
+		SNP:        published.SNP,
+		Microcode:  published.Microcode,
+	}, nil
+}
+
+// withoutTCBMinimums returns a copy of the SEV-SNP attestation config without minimum TCB versions.
+// The copy is used to validate the attestation report of a node that is recovering from a TCB update,
+// the TCB versions are checked by verifyTCBRecovery instead.
+func withoutTCBMinimums(attConfig config.AttestationCfg) (config.AttestationCfg, error) {
+	clearMinimums := func(bootloader, tee, snp, microcode *config.AttestationVersion[uint8]) {
+		for _, v := range []*config.AttestationVersion[uint8]{bootloader, tee, snp, microcode} {
+			v.Value = 0
+			v.WantLatest = false
+		}
+	}
+	switch cfg := attConfig.(type) {
+	case *config.AzureSEVSNP:
+		relaxed := *cfg
+		clearMinimums(&relaxed.BootloaderVersion, &relaxed.TEEVersion, &relaxed.SNPVersion, &relaxed.MicrocodeVersion)
+		return &relaxed, nil
+	case *config.AWSSEVSNP:
+		relaxed := *cfg
+		clearMinimums(&relaxed.BootloaderVersion, &relaxed.TEEVersion, &relaxed.SNPVersion, &relaxed.MicrocodeVersion)
+		return &relaxed, nil
+	case *config.GCPSEVSNP:
+		relaxed := *cfg
+		clearMinimums(&relaxed.BootloaderVersion, &relaxed.TEEVersion, &relaxed.SNPVersion, &relaxed.MicrocodeVersion)
+		return &relaxed, nil
+	default:
+		return nil, fmt.Errorf("--allow-tcb-recovery is only supported for SEV-SNP attestation variants, got %s", attConfig.GetVariant())
+	}
+}
+
+// verifyTCBRecovery checks that the SEV-SNP report of the attestation document is in the middle of a TCB update to the target versions.
+// Reports that already meet the minimum TCB versions are rejected as well, so --allow-tcb-recovery isn't left on once the recovery is finished.
+func verifyTCBRecovery(rawAttestationDoc []byte, attConfig config.AttestationCfg, target verify.TCBVersion) (verify.TCBReport, error) {
+	doc, err := unmarshalAttDoc(rawAttestationDoc, attConfig.GetVariant())
+	if err != nil {
+		return verify.TCBReport{}, fmt.Errorf("unmarshalling attestation document: %w", err)
+	}
+	var instanceInfo snp.InstanceInfo
+	if err := json.Unmarshal(doc.InstanceInfo, &instanceInfo); err != nil {
+		return verify.TCBReport{}, fmt.Errorf("unmarshalling instance info: %w", err)
+	}
+	report, err := verify.NewTCBReport(instanceInfo.AttestationReport, attConfig)
+	if err != nil {
+		return verify.TCBReport{}, fmt.Errorf("parsing SNP report: %w", err)
+	}
+
+	switch {
+	case report.MeetsMinimum():
+		return report, errors.New("the node's TCB versions meet the configured minimums, remove --allow-tcb-recovery")
+	case !report.InRecovery(target):
+		return report, errors.New("the node's TCB versions are below the configured minimums, and the node isn't updating its firmware to the published versions")
+	}
+	return report, nil
+}
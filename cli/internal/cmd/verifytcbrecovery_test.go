@@ -0,0 +1,199 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation/snp"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/snp/testdata"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/vtpm"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	snpabi "github.com/google/go-sev-guest/abi"
+	"github.com/google/go-sev-guest/kds"
+	"github.com/google/go-tpm-tools/proto/attest"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyTCBRecovery(t *testing.T) {
+	// the minimums of the default config are the "latest" versions returned by the fetcher
+	published := kds.TCBParts{BlSpl: testCfg.Bootloader, TeeSpl: testCfg.TEE, SnpSpl: testCfg.SNP, UcodeSpl: testCfg.Microcode}
+	outdated := published
+	outdated.UcodeSpl--
+
+	testCases := map[string]struct {
+		provider         cloudprovider.Provider
+		reported         kds.TCBParts
+		current          kds.TCBParts
+		committed        kds.TCBParts
+		allowTCBRecovery bool
+		output           string
+		wantErr          bool
+	}{
+		"recovering node is accepted": {
+			provider:         cloudprovider.Azure,
+			reported:         outdated,
+			current:          published,
+			committed:        outdated,
+			allowTCBRecovery: true,
+		},
+		"outdated node is reported as TCB too old": {
+			provider:         cloudprovider.Azure,
+			reported:         outdated,
+			current:          outdated,
+			committed:        outdated,
+			allowTCBRecovery: true,
+			output:           "sarif",
+			wantErr:          true,
+		},
+		"outdated node isn't accepted": {
+			provider:         cloudprovider.Azure,
+			reported:         outdated,
+			current:          outdated,
+			committed:        outdated,
+			allowTCBRecovery: true,
+			wantErr:          true,
+		},
+		"committed update isn't accepted": {
+			provider:         cloudprovider.Azure,
+			reported:         outdated,
+			current:          published,
+			committed:        published,
+			allowTCBRecovery: true,
+			wantErr:          true,
+		},
+		"up to date node fails with recovery enabled": {
+			provider:         cloudprovider.Azure,
+			reported:         published,
+			current:          published,
+			committed:        published,
+			allowTCBRecovery: true,
+			wantErr:          true,
+		},
+		"up to date node without recovery": {
+			provider:  cloudprovider.Azure,
+			reported:  published,
+			current:   published,
+			committed: published,
+		},
+		"non-SNP variant": {
+			provider:         cloudprovider.QEMU,
+			reported:         outdated,
+			current:          published,
+			committed:        outdated,
+			allowTCBRecovery: true,
+			wantErr:          true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			attDoc := snpAttestationDocWithTCB(t, tc.reported, tc.current, tc.committed)
+
+			cmd := NewVerifyCmd()
+			out := &bytes.Buffer{}
+			errOut := &bytes.Buffer{}
+			cmd.SetOut(out)
+			cmd.SetErr(errOut)
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			cfg := defaultConfigWithExpectedMeasurements(t, config.Default(), tc.provider)
+			require.NoError(fileHandler.WriteYAML(constants.ConfigFilename, cfg))
+
+			v := &verifyCmd{
+				fileHandler: fileHandler,
+				log:         logger.NewTest(t),
+				flags: verifyFlags{
+					clusterID:        base64.StdEncoding.EncodeToString([]byte("00000000000000000000000000000000")),
+					endpoint:         "192.0.2.1:1234",
+					output:           "raw",
+					allowTCBRecovery: tc.allowTCBRecovery,
+				},
+			}
+			if tc.output != "" {
+				v.flags.output = tc.output
+			}
+			err := v.verify(cmd, &stubVerifyClient{attestationDoc: attDoc}, stubAttestationFetcher{})
+			if tc.output == "sarif" {
+				assert.Contains(out.String(), `"ruleId": "`+sarifRuleTCBTooOld+`"`)
+			}
+			if tc.wantErr {
+				assert.Error(err)
+				assert.NotContains(errOut.String(), "OK")
+				return
+			}
+			require.NoError(err)
+			assert.Contains(errOut.String(), "OK")
+			assert.Equal(tc.allowTCBRecovery, bytes.Contains(errOut.Bytes(), []byte("WARNING: --allow-tcb-recovery")))
+		})
+	}
+}
+
+func TestWithoutTCBMinimums(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	cfg := config.DefaultForAzureSEVSNP()
+	cfg.BootloaderVersion = config.AttestationVersion[uint8]{Value: 3}
+	cfg.MicrocodeVersion = config.AttestationVersion[uint8]{Value: 115}
+	wantCfg := *cfg
+
+	relaxed, err := withoutTCBMinimums(cfg)
+	require.NoError(err)
+	relaxedCfg, ok := relaxed.(*config.AzureSEVSNP)
+	require.True(ok)
+	assert.Zero(relaxedCfg.BootloaderVersion)
+	assert.Zero(relaxedCfg.TEEVersion)
+	assert.Zero(relaxedCfg.SNPVersion)
+	assert.Zero(relaxedCfg.MicrocodeVersion)
+	assert.Equal(cfg.Measurements, relaxedCfg.Measurements)
+	// without --allow-tcb-recovery, the validator enforces the unmodified minimums
+	assert.Equal(wantCfg, *cfg)
+
+	_, err = withoutTCBMinimums(&config.QEMUVTPM{})
+	assert.Error(err)
+}
+
+// snpAttestationDocWithTCB returns an attestation document with an SEV-SNP report of the given TCB versions.
+// The launch TCB is equal to the reported TCB.
+func snpAttestationDocWithTCB(t *testing.T, reported, current, committed kds.TCBParts) []byte {
+	t.Helper()
+	report, err := snpabi.ReportToProto(testdata.AttestationReport[:snpabi.ReportSize])
+	require.NoError(t, err)
+	for tcb, parts := range map[*uint64]kds.TCBParts{
+		&report.ReportedTcb:  reported,
+		&report.LaunchTcb:    reported,
+		&report.CurrentTcb:   current,
+		&report.CommittedTcb: committed,
+	} {
+		version, err := kds.ComposeTCBParts(parts)
+		require.NoError(t, err)
+		*tcb = uint64(version)
+	}
+	rawReport, err := snpabi.ReportToAbiBytes(report)
+	require.NoError(t, err)
+
+	instanceInfo, err := json.Marshal(snp.InstanceInfo{AttestationReport: rawReport})
+	require.NoError(t, err)
+	attDoc, err := json.Marshal(vtpm.AttestationDocument{
+		Attestation:  &attest.Attestation{},
+		InstanceInfo: instanceInfo,
+	})
+	require.NoError(t, err)
+	return attDoc
+}
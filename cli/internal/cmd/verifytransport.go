@@ -0,0 +1,175 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/verify/verifyproto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// reportTransportGRPC requests the attestation from the verification service of a live node.
+	reportTransportGRPC = "grpc"
+	// reportTransportHTTP requests the attestation from an HTTP endpoint, e.g., a proxy in front of the nodes.
+	reportTransportHTTP = "http"
+	// reportTransportFile reads an attestation saved to a file, for offline verification.
+	reportTransportFile = "file"
+
+	// maxHTTPAttestationSize limits the size of an attestation document received over HTTP.
+	maxHTTPAttestationSize = 1 << 20
+)
+
+// parseReportTransport checks that transport is a supported report transport.
+func parseReportTransport(transport string) (string, error) {
+	switch transport {
+	case reportTransportGRPC, reportTransportHTTP, reportTransportFile:
+		return transport, nil
+	default:
+		return "", fmt.Errorf("invalid report transport %q, must be one of {%s, %s, %s}",
+			transport, reportTransportGRPC, reportTransportHTTP, reportTransportFile)
+	}
+}
+
+// reportFetcher obtains the attestation document of a node.
+type reportFetcher interface {
+	// FetchReport returns the attestation document of the node at endpoint, and the nonce it is bound to.
+	// Live transports request a document bound to the given nonce.
+	FetchReport(ctx context.Context, endpoint string, nonce []byte) (attestation, boundNonce []byte, err error)
+}
+
+// grpcReportFetcher requests the attestation from the verification service of a node.
+type grpcReportFetcher struct {
+	dialer grpcVerifyDialer
+	// tlsConfig is used to connect to the node endpoint over mutual TLS.
+	// If it's nil, an unencrypted connection is used.
+	tlsConfig *tls.Config
+	// serverName overrides the SNI sent in the TLS handshake, which defaults to the host of the endpoint.
+	serverName string
+	log        debugLog
+}
+
+// FetchReport requests an attestation document bound to nonce from the node at endpoint.
+func (f *grpcReportFetcher) FetchReport(ctx context.Context, endpoint string, nonce []byte) ([]byte, []byte, error) {
+	var conn *grpc.ClientConn
+	var err error
+	if f.tlsConfig != nil {
+		tlsConfig := f.tlsConfig.Clone()
+		tlsConfig.ServerName = f.serverName
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName, _, err = net.SplitHostPort(endpoint)
+			if err != nil {
+				return nil, nil, fmt.Errorf("getting host of endpoint %q: %w", endpoint, err)
+			}
+		}
+		f.log.Debug(fmt.Sprintf("Dialing endpoint with mutual TLS: %q, server name %q", endpoint, tlsConfig.ServerName))
+		conn, err = f.dialer.DialTLS(endpoint, tlsConfig)
+	} else {
+		f.log.Debug(fmt.Sprintf("Dialing endpoint: %q", endpoint))
+		conn, err = f.dialer.DialInsecure(endpoint)
+	}
+	if err != nil {
+		return nil, nil, &endpointUnreachableError{endpoint: endpoint, err: fmt.Errorf("dialing init server: %w", err)}
+	}
+	defer conn.Close()
+
+	client := verifyproto.NewAPIClient(conn)
+	f.log.Debug("Sending attestation request")
+	resp, err := client.GetAttestation(ctx, &verifyproto.GetAttestationRequest{Nonce: nonce})
+	if code := status.Code(err); code == codes.Unavailable || code == codes.DeadlineExceeded {
+		return nil, nil, &endpointUnreachableError{endpoint: endpoint, err: fmt.Errorf("getting attestation: %w", err)}
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting attestation: %w", err)
+	}
+	return resp.Attestation, nonce, nil
+}
+
+// httpReportFetcher requests the attestation with a GET request to the URL of the endpoint.
+// The nonce is passed hex encoded in the query parameter "nonce",
+// and the response body must be the attestation document.
+type httpReportFetcher struct {
+	client *http.Client
+	log    debugLog
+}
+
+// FetchReport requests an attestation document bound to nonce from the URL endpoint.
+func (f *httpReportFetcher) FetchReport(ctx context.Context, endpoint string, nonce []byte) ([]byte, []byte, error) {
+	reqURL, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing endpoint URL: %w", err)
+	}
+	query := reqURL.Query()
+	query.Set("nonce", hex.EncodeToString(nonce))
+	reqURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL.String(), http.NoBody)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating request: %w", err)
+	}
+	f.log.Debug(fmt.Sprintf("Requesting attestation from %q", endpoint))
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, nil, &endpointUnreachableError{endpoint: endpoint, err: fmt.Errorf("getting attestation: %w", err)}
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return nil, nil, &endpointUnreachableError{endpoint: endpoint, err: fmt.Errorf("getting attestation: %s", resp.Status)}
+	default:
+		return nil, nil, fmt.Errorf("getting attestation: unexpected status %s", resp.Status)
+	}
+
+	attestation, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPAttestationSize+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading attestation: %w", err)
+	}
+	if len(attestation) > maxHTTPAttestationSize {
+		return nil, nil, fmt.Errorf("attestation exceeds the maximum size of %d bytes", maxHTTPAttestationSize)
+	}
+	return attestation, nonce, nil
+}
+
+// savedReport is an attestation document saved for offline verification, together with the nonce it was requested with.
+type savedReport struct {
+	Nonce       []byte `json:"nonce"`
+	Attestation []byte `json:"attestation"`
+}
+
+// fileReportFetcher reads a saved attestation from the file at the path of the endpoint.
+// The attestation is bound to the nonce it was requested with, not to the nonce of the verification,
+// so its freshness can't be checked.
+type fileReportFetcher struct {
+	fileHandler file.Handler
+}
+
+// FetchReport reads the saved attestation document at endpoint and returns it with the nonce it is bound to.
+func (f *fileReportFetcher) FetchReport(_ context.Context, endpoint string, _ []byte) ([]byte, []byte, error) {
+	var report savedReport
+	if err := f.fileHandler.ReadJSON(endpoint, &report); err != nil {
+		return nil, nil, fmt.Errorf("reading saved attestation: %w", err)
+	}
+	if len(report.Attestation) == 0 {
+		return nil, nil, fmt.Errorf("saved attestation %q doesn't contain an attestation document", endpoint)
+	}
+	if len(report.Nonce) == 0 {
+		return nil, nil, fmt.Errorf("saved attestation %q doesn't contain the nonce it was requested with", endpoint)
+	}
+	return report.Attestation, report.Nonce, nil
+}
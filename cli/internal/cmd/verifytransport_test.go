@@ -0,0 +1,156 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/atls"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/variant"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/grpc/dialer"
+	"github.com/edgelesssys/constellation/v2/internal/grpc/testdialer"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/edgelesssys/constellation/v2/verify/verifyproto"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestVerifyReportTransports(t *testing.T) {
+	nonce := []byte("nonce")
+	savedNonce := []byte("saved nonce")
+	embeddedReport := func(t *testing.T, nonce []byte) []byte {
+		doc, err := json.Marshal(atls.FakeAttestationDoc{
+			UserData: []byte(constants.ConstellationVerifyServiceUserData),
+			Nonce:    nonce,
+		})
+		require.NoError(t, err)
+		return doc
+	}
+
+	testCases := map[string]struct {
+		// newFetcher sets up the transport to return the report and returns the fetcher and endpoint to use.
+		newFetcher      func(t *testing.T, report []byte) (reportFetcher, string)
+		reportNonce     []byte
+		wantErr         bool
+		wantUnreachable bool
+	}{
+		"grpc": {
+			newFetcher: func(t *testing.T, report []byte) (reportFetcher, string) {
+				netDialer := testdialer.NewBufconnDialer()
+				server := grpc.NewServer()
+				verifyproto.RegisterAPIServer(server, &stubVerifyAPI{attestation: &verifyproto.GetAttestationResponse{Attestation: report}})
+				addr := net.JoinHostPort("192.0.2.1", strconv.Itoa(constants.VerifyServiceNodePortGRPC))
+				go server.Serve(netDialer.GetListener(addr))
+				t.Cleanup(server.GracefulStop)
+				return &grpcReportFetcher{dialer: dialer.New(nil, nil, netDialer), log: logger.NewTest(t)}, addr
+			},
+			reportNonce: nonce,
+		},
+		"http": {
+			newFetcher: func(t *testing.T, report []byte) (reportFetcher, string) {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Query().Get("nonce") != hex.EncodeToString(nonce) {
+						w.WriteHeader(http.StatusBadRequest)
+						return
+					}
+					_, _ = w.Write(report)
+				}))
+				t.Cleanup(server.Close)
+				return &httpReportFetcher{client: server.Client(), log: logger.NewTest(t)}, server.URL + "/attestation"
+			},
+			reportNonce: nonce,
+		},
+		"http endpoint unavailable": {
+			newFetcher: func(t *testing.T, _ []byte) (reportFetcher, string) {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}))
+				t.Cleanup(server.Close)
+				return &httpReportFetcher{client: server.Client(), log: logger.NewTest(t)}, server.URL
+			},
+			reportNonce:     nonce,
+			wantErr:         true,
+			wantUnreachable: true,
+		},
+		"http request rejected": {
+			newFetcher: func(t *testing.T, _ []byte) (reportFetcher, string) {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					w.WriteHeader(http.StatusForbidden)
+				}))
+				t.Cleanup(server.Close)
+				return &httpReportFetcher{client: server.Client(), log: logger.NewTest(t)}, server.URL
+			},
+			reportNonce: nonce,
+			wantErr:     true,
+		},
+		"file": {
+			newFetcher: func(t *testing.T, report []byte) (reportFetcher, string) {
+				fileHandler := file.NewHandler(afero.NewMemMapFs())
+				require.NoError(t, fileHandler.WriteJSON("report.json", savedReport{Nonce: savedNonce, Attestation: report}))
+				return &fileReportFetcher{fileHandler: fileHandler}, "report.json"
+			},
+			reportNonce: savedNonce,
+		},
+		"file without nonce": {
+			newFetcher: func(t *testing.T, report []byte) (reportFetcher, string) {
+				fileHandler := file.NewHandler(afero.NewMemMapFs())
+				require.NoError(t, fileHandler.WriteJSON("report.json", savedReport{Attestation: report}))
+				return &fileReportFetcher{fileHandler: fileHandler}, "report.json"
+			},
+			wantErr: true,
+		},
+		"file doesn't exist": {
+			newFetcher: func(*testing.T, []byte) (reportFetcher, string) {
+				return &fileReportFetcher{fileHandler: file.NewHandler(afero.NewMemMapFs())}, "report.json"
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			report := embeddedReport(t, tc.reportNonce)
+			fetcher, endpoint := tc.newFetcher(t, report)
+			verifier := &constellationVerifier{fetcher: fetcher, log: logger.NewTest(t)}
+
+			attestation, err := verifier.Verify(context.Background(), endpoint,
+				&verifyproto.GetAttestationRequest{Nonce: nonce}, atls.NewFakeValidator(variant.Dummy{}))
+			if tc.wantErr {
+				assert.Error(err)
+				var unreachableErr *endpointUnreachableError
+				assert.Equal(tc.wantUnreachable, errors.As(err, &unreachableErr))
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(report, attestation)
+		})
+	}
+}
+
+func TestParseReportTransport(t *testing.T) {
+	for _, transport := range []string{reportTransportGRPC, reportTransportHTTP, reportTransportFile} {
+		got, err := parseReportTransport(transport)
+		assert.NoError(t, err)
+		assert.Equal(t, transport, got)
+	}
+	_, err := parseReportTransport("smtp")
+	assert.Error(t, err)
+}
@@ -0,0 +1,47 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// verifyWarnings records the warnings printed during a verification.
+// Warnings don't change the verdict of the verification, unless --fail-on-warning is set.
+type verifyWarnings struct {
+	messages []string
+}
+
+// warn prints the warning to stderr and records it.
+func (w *verifyWarnings) warn(cmd *cobra.Command, msg string) {
+	cmd.PrintErrln(msg)
+	w.messages = append(w.messages, msg)
+}
+
+// err returns an error listing the recorded warnings, or nil if no warning was recorded.
+func (w *verifyWarnings) err() error {
+	if len(w.messages) == 0 {
+		return nil
+	}
+	return fmt.Errorf("verification printed %d warning(s), which fail it since --fail-on-warning is set:\n  %s",
+		len(w.messages), strings.Join(w.messages, "\n  "))
+}
+
+// recordingWarnLog records the warnings of a validator in addition to logging them.
+type recordingWarnLog struct {
+	warnLog
+	warnings *verifyWarnings
+}
+
+// Warn logs the warning and records it.
+func (l recordingWarnLog) Warn(msg string, args ...any) {
+	l.warnLog.Warn(msg, args...)
+	l.warnings.messages = append(l.warnings.messages, strings.TrimSpace(fmt.Sprintf("Warning: %s %s", msg, fmt.Sprint(args...))))
+}
@@ -0,0 +1,97 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyFailOnWarning(t *testing.T) {
+	testCases := map[string]struct {
+		transport     string
+		endpoint      string
+		failOnWarning bool
+		wantErr       bool
+	}{
+		"warning without fail-on-warning": {
+			transport: reportTransportFile,
+			endpoint:  "attestation.json",
+		},
+		"warning with fail-on-warning": {
+			transport:     reportTransportFile,
+			endpoint:      "attestation.json",
+			failOnWarning: true,
+			wantErr:       true,
+		},
+		"no warning with fail-on-warning": {
+			transport:     reportTransportGRPC,
+			endpoint:      "192.0.2.1:1234",
+			failOnWarning: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			cmd := NewVerifyCmd()
+			errOut := &bytes.Buffer{}
+			cmd.SetErr(errOut)
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			cfg := defaultConfigWithExpectedMeasurements(t, config.Default(), cloudprovider.GCP)
+			require.NoError(fileHandler.WriteYAML(constants.ConfigFilename, cfg))
+
+			v := &verifyCmd{
+				fileHandler: fileHandler,
+				log:         logger.NewTest(t),
+				flags: verifyFlags{
+					clusterID:     base64.StdEncoding.EncodeToString([]byte("00000000000000000000000000000000")),
+					endpoint:      tc.endpoint,
+					output:        "raw",
+					transport:     tc.transport,
+					failOnWarning: tc.failOnWarning,
+				},
+			}
+			err := v.verify(cmd, &stubVerifyClient{}, stubAttestationFetcher{})
+			if tc.wantErr {
+				assert.ErrorContains(err, "saved attestation")
+				return
+			}
+			assert.NoError(err)
+			assert.Contains(errOut.String(), "Verification OK")
+		})
+	}
+}
+
+func TestRecordingWarnLog(t *testing.T) {
+	assert := assert.New(t)
+
+	cmd := NewVerifyCmd()
+	errOut := &bytes.Buffer{}
+	cmd.SetErr(errOut)
+	warnings := &verifyWarnings{}
+	log := recordingWarnLog{warnLog: warnLogger{cmd: cmd, log: logger.NewTest(t)}, warnings: warnings}
+
+	log.Info("not recorded")
+	assert.NoError(warnings.err())
+
+	log.Warn("PCR 4 doesn't match", "warnOnly")
+	assert.Equal("Warning: PCR 4 doesn't match warnOnly\n", errOut.String())
+	assert.ErrorContains(warnings.err(), "Warning: PCR 4 doesn't match warnOnly")
+}
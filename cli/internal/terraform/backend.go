@@ -0,0 +1,52 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package terraform
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// terraformBackendFile is the file the backend configuration is written to in the Terraform workspace.
+const terraformBackendFile = "backend.tf"
+
+// Backend is a remote backend Terraform stores its state in, instead of the local workspace.
+type Backend struct {
+	// Type of the backend, e.g., "s3", "gcs", or "azurerm".
+	Type string
+	// Config are the arguments of the backend, e.g., "bucket" and "key" for "s3".
+	Config map[string]string
+}
+
+// String returns the backend formatted as Terraform configuration block.
+func (b *Backend) String() string {
+	keys := make([]string, 0, len(b.Config))
+	for key := range b.Config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	f := hclwrite.NewEmptyFile()
+	backend := f.Body().AppendNewBlock("terraform", nil).Body().AppendNewBlock("backend", []string{b.Type}).Body()
+	for _, key := range keys {
+		backend.SetAttributeValue(key, cty.StringVal(b.Config[key]))
+	}
+	return string(f.Bytes())
+}
+
+// writeBackend writes the backend configuration into the workspace, or removes it if backend is nil.
+func (c *Client) writeBackend() error {
+	pathToBackendFile := filepath.Join(c.workingDir, terraformBackendFile)
+	if c.backend == nil {
+		return ignoreFileNotFoundErr(c.file.Remove(pathToBackendFile))
+	}
+	return c.file.Write(pathToBackendFile, []byte(c.backend.String()), file.OptOverwrite)
+}
@@ -0,0 +1,127 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package terraform
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackendString(t *testing.T) {
+	testCases := map[string]struct {
+		backend *Backend
+		want    string
+	}{
+		"s3": {
+			backend: &Backend{
+				Type: "s3",
+				Config: map[string]string{
+					"region": "eu-central-1",
+					"bucket": "constellation-state",
+					"key":    "prod/terraform.tfstate",
+				},
+			},
+			want: `terraform {
+  backend "s3" {
+    bucket = "constellation-state"
+    key    = "prod/terraform.tfstate"
+    region = "eu-central-1"
+  }
+}
+`,
+		},
+		"gcs": {
+			backend: &Backend{
+				Type: "gcs",
+				Config: map[string]string{
+					"bucket": "constellation-state",
+					"prefix": "prod",
+				},
+			},
+			want: `terraform {
+  backend "gcs" {
+    bucket = "constellation-state"
+    prefix = "prod"
+  }
+}
+`,
+		},
+		"azurerm": {
+			backend: &Backend{
+				Type: "azurerm",
+				Config: map[string]string{
+					"resource_group_name":  "constellation-state",
+					"storage_account_name": "constellationstate",
+					"container_name":       "tfstate",
+					"key":                  "prod.terraform.tfstate",
+				},
+			},
+			want: `terraform {
+  backend "azurerm" {
+    container_name       = "tfstate"
+    key                  = "prod.terraform.tfstate"
+    resource_group_name  = "constellation-state"
+    storage_account_name = "constellationstate"
+  }
+}
+`,
+		},
+		"values are escaped": {
+			backend: &Backend{
+				Type: "s3",
+				Config: map[string]string{
+					"key": `"${quoted}"`,
+				},
+			},
+			want: `terraform {
+  backend "s3" {
+    key = "\"$${quoted}\""
+  }
+}
+`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.backend.String())
+		})
+	}
+}
+
+func TestPrepareWorkspaceBackend(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	c := &Client{
+		tf:         &stubTerraform{},
+		file:       file.NewHandler(afero.NewMemMapFs()),
+		workingDir: "unittest",
+	}
+	templateDir := path.Join(constants.TerraformEmbeddedDir, strings.ToLower(cloudprovider.QEMU.String()))
+	backendFile := filepath.Join(c.workingDir, terraformBackendFile)
+	backend := &Backend{Type: "gcs", Config: map[string]string{"bucket": "constellation-state"}}
+
+	require.NoError(c.WithBackend(backend).PrepareWorkspace(templateDir, &QEMUVariables{}))
+	content, err := c.file.Read(backendFile)
+	require.NoError(err)
+	assert.Equal(backend.String(), string(content))
+
+	// Removing the backend from the config stores the state in the workspace again.
+	require.NoError(c.WithBackend(nil).PrepareWorkspace(templateDir, &QEMUVariables{}))
+	_, err = c.file.Stat(backendFile)
+	assert.ErrorIs(err, afero.ErrFileNotFound)
+}
@@ -48,11 +48,17 @@ const (
 	terraformUpgradePlanFile = "plan.zip"
 )
 
+// initOptions are the options Terraform initializes the workspace with.
+// If the backend configuration changed, e.g., from the local workspace to a remote backend,
+// the existing state is copied to the new backend without asking for confirmation.
+var initOptions = []tfexec.InitOption{tfexec.ForceCopy(true)}
+
 // Client manages interaction with Terraform.
 type Client struct {
 	tf tfInterface
 
 	manualStateMigrations []StateMigration
+	backend               *Backend
 	file                  file.Handler
 	workingDir            string
 	remove                func()
@@ -83,6 +89,13 @@ func (c *Client) WithManualStateMigration(migration StateMigration) *Client {
 	return c
 }
 
+// WithBackend configures the remote backend Terraform stores the state in when preparing the workspace.
+// If backend is nil, the state is stored in the local workspace.
+func (c *Client) WithBackend(backend *Backend) *Client {
+	c.backend = backend
+	return c
+}
+
 // ShowIAM reads the state of Constellation IAM resources from Terraform.
 func (c *Client) ShowIAM(ctx context.Context, provider cloudprovider.Provider) (IAMOutput, error) {
 	tfState, err := c.tf.Show(ctx)
@@ -275,9 +288,39 @@ func (c *Client) ShowInfrastructure(ctx context.Context, provider cloudprovider.
 			return state.Infrastructure{}, errors.New("invalid type in ip_cidr_pod output: not a string")
 		}
 
+		networkIDOutput, ok := tfState.Values.Outputs["network_id"]
+		if !ok {
+			return state.Infrastructure{}, errors.New("no network_id output found")
+		}
+		networkID, ok := networkIDOutput.Value.(string)
+		if !ok {
+			return state.Infrastructure{}, errors.New("invalid type in network_id output: not a string")
+		}
+
+		subnetworkIDOutput, ok := tfState.Values.Outputs["subnetwork_id"]
+		if !ok {
+			return state.Infrastructure{}, errors.New("no subnetwork_id output found")
+		}
+		subnetworkID, ok := subnetworkIDOutput.Value.(string)
+		if !ok {
+			return state.Infrastructure{}, errors.New("invalid type in subnetwork_id output: not a string")
+		}
+
+		diskEncryptionKeyOutput, ok := tfState.Values.Outputs["disk_encryption_key"]
+		if !ok {
+			return state.Infrastructure{}, errors.New("no disk_encryption_key output found")
+		}
+		diskEncryptionKey, ok := diskEncryptionKeyOutput.Value.(string)
+		if !ok {
+			return state.Infrastructure{}, errors.New("invalid type in disk_encryption_key output: not a string")
+		}
+
 		res.GCP = &state.GCP{
-			ProjectID: gcpProject,
-			IPCidrPod: cidrPods,
+			ProjectID:         gcpProject,
+			IPCidrPod:         cidrPods,
+			NetworkID:         networkID,
+			SubnetworkID:      subnetworkID,
+			DiskEncryptionKey: diskEncryptionKey,
 		}
 	case cloudprovider.Azure:
 		attestationURLOutput, ok := tfState.Values.Outputs["attestation_url"]
@@ -332,6 +375,25 @@ func (c *Client) ShowInfrastructure(ctx context.Context, provider cloudprovider.
 		if !ok {
 			return state.Infrastructure{}, errors.New("invalid type in loadbalancer_name output: not a string")
 		}
+
+		virtualNetworkIDOutput, ok := tfState.Values.Outputs["virtual_network_id"]
+		if !ok {
+			return state.Infrastructure{}, errors.New("no virtual_network_id output found")
+		}
+		virtualNetworkID, ok := virtualNetworkIDOutput.Value.(string)
+		if !ok {
+			return state.Infrastructure{}, errors.New("invalid type in virtual_network_id output: not a string")
+		}
+
+		subnetIDOutput, ok := tfState.Values.Outputs["subnet_id"]
+		if !ok {
+			return state.Infrastructure{}, errors.New("no subnet_id output found")
+		}
+		subnetID, ok := subnetIDOutput.Value.(string)
+		if !ok {
+			return state.Infrastructure{}, errors.New("invalid type in subnet_id output: not a string")
+		}
+
 		res.Azure = &state.Azure{
 			ResourceGroup:            rg,
 			SubscriptionID:           subscriptionID,
@@ -339,6 +401,8 @@ func (c *Client) ShowInfrastructure(ctx context.Context, provider cloudprovider.
 			NetworkSecurityGroupName: networkSGName,
 			LoadBalancerName:         loadBalancerName,
 			AttestationURL:           attestationURL,
+			VirtualNetworkID:         virtualNetworkID,
+			SubnetID:                 subnetID,
 		}
 	case cloudprovider.OpenStack:
 		networkIDOutput, ok := tfState.Values.Outputs["network_id"]
@@ -371,6 +435,9 @@ func (c *Client) PrepareWorkspace(path string, vars Variables) error {
 	if err := prepareWorkspace(path, c.file, c.workingDir); err != nil {
 		return fmt.Errorf("prepare workspace: %w", err)
 	}
+	if err := c.writeBackend(); err != nil {
+		return fmt.Errorf("write backend configuration: %w", err)
+	}
 
 	return c.writeVars(vars)
 }
@@ -399,7 +466,7 @@ func (c *Client) Plan(ctx context.Context, logLevel LogLevel) (bool, error) {
 		return false, fmt.Errorf("set terraform log level %s: %w", logLevel.String(), err)
 	}
 
-	if err := c.tf.Init(ctx); err != nil {
+	if err := c.tf.Init(ctx, initOptions...); err != nil {
 		return false, fmt.Errorf("terraform init: %w", err)
 	}
 
@@ -439,7 +506,7 @@ func (c *Client) Destroy(ctx context.Context, logLevel LogLevel) error {
 		return fmt.Errorf("set terraform log level %s: %w", logLevel.String(), err)
 	}
 
-	if err := c.tf.Init(ctx); err != nil {
+	if err := c.tf.Init(ctx, initOptions...); err != nil {
 		return fmt.Errorf("terraform init: %w", err)
 	}
 	return c.tf.Destroy(ctx)
@@ -460,7 +527,7 @@ func (c *Client) apply(ctx context.Context, logLevel LogLevel) error {
 		return fmt.Errorf("set terraform log level %s: %w", logLevel.String(), err)
 	}
 
-	if err := c.tf.Init(ctx); err != nil {
+	if err := c.tf.Init(ctx, initOptions...); err != nil {
 		return fmt.Errorf("terraform init: %w", err)
 	}
 
@@ -617,7 +684,8 @@ type tfInterface interface {
 	TFMigrator
 }
 
-// TFMigrator is an interface for manual terraform state migrations (terraform state mv).
+// TFMigrator is an interface for manual terraform state migrations (terraform state mv, terraform import).
 type TFMigrator interface {
 	StateMv(ctx context.Context, src, dst string, opts ...tfexec.StateMvCmdOption) error
+	Import(ctx context.Context, address, id string, opts ...tfexec.ImportOption) error
 }
@@ -275,6 +275,12 @@ func TestCreateCluster(t *testing.T) {
 					"loadbalancer_name": {
 						Value: "test_lb_name",
 					},
+					"virtual_network_id": {
+						Value: "test_vnet_id",
+					},
+					"subnet_id": {
+						Value: "test_subnet_id",
+					},
 					"name": {
 						Value: "constell-12345abc",
 					},
@@ -496,6 +502,8 @@ func TestCreateCluster(t *testing.T) {
 			assert.Equal("192.0.2.103/32", infraState.IPCidrNode)
 			if tc.provider == cloudprovider.Azure {
 				assert.Equal(tc.expectedAttestationURL, infraState.Azure.AttestationURL)
+				assert.Equal("test_vnet_id", infraState.Azure.VirtualNetworkID)
+				assert.Equal("test_subnet_id", infraState.Azure.SubnetID)
 			}
 		})
 	}
@@ -1314,6 +1322,7 @@ type stubTerraform struct {
 	planJSONErr     error
 	showPlanFileErr error
 	stateMvErr      error
+	importErr       error
 	showState       *tfjson.State
 }
 
@@ -1353,6 +1362,10 @@ func (s *stubTerraform) StateMv(_ context.Context, _, _ string, _ ...tfexec.Stat
 	return s.stateMvErr
 }
 
+func (s *stubTerraform) Import(_ context.Context, _, _ string, _ ...tfexec.ImportOption) error {
+	return s.importErr
+}
+
 func getTfjsonState(values map[string]any) *tfjson.State {
 	state := tfjson.State{
 		Values: &tfjson.StateValues{
@@ -50,6 +50,8 @@ func VariablesFromBytes[T any](b []byte, vars *T) error {
 type AWSClusterVariables struct {
 	// Name of the cluster.
 	Name string `hcl:"name" cty:"name"`
+	// NameTemplate is the (optional) template for the base name of the created resources.
+	NameTemplate string `hcl:"name_template" cty:"name_template"`
 	// Region is the AWS region to use.
 	Region string `hcl:"region" cty:"region"`
 	// Zone is the AWS zone to use in the given region.
@@ -72,6 +74,8 @@ type AWSClusterVariables struct {
 	InternalLoadBalancer bool `hcl:"internal_load_balancer" cty:"internal_load_balancer"`
 	// AdditionalTags describes (optional) additional tags that should be applied to created resources.
 	AdditionalTags cloudprovider.Tags `hcl:"additional_tags" cty:"additional_tags"`
+	// UserData is the (optional) user data script run by the instances on boot.
+	UserData string `hcl:"user_data" cty:"user_data"`
 }
 
 // GetCreateMAA gets the CreateMAA variable.
@@ -123,6 +127,8 @@ func (v *AWSIAMVariables) String() string {
 type GCPClusterVariables struct {
 	// Name of the cluster.
 	Name string `hcl:"name" cty:"name"`
+	// NameTemplate is the (optional) template for the base name of the created resources.
+	NameTemplate string `hcl:"name_template" cty:"name_template"`
 	// Project is the ID of the GCP project to use.
 	Project string `hcl:"project" cty:"project"`
 	// Region is the GCP region to use.
@@ -143,6 +149,16 @@ type GCPClusterVariables struct {
 	CCTechnology string `hcl:"cc_technology" cty:"cc_technology"`
 	// AdditionalLables are (optional) additional labels that should be applied to created resources.
 	AdditionalLabels cloudprovider.Tags `hcl:"additional_labels" cty:"additional_labels"`
+	// UserData is the (optional) user data script run by the instances on boot.
+	UserData string `hcl:"user_data" cty:"user_data"`
+	// NetworkID is the (optional) ID of an existing network to attach the nodes to.
+	NetworkID string `hcl:"network_id" cty:"network_id"`
+	// SubnetworkID is the (optional) ID of an existing subnetwork to attach the nodes to.
+	SubnetworkID string `hcl:"subnetwork_id" cty:"subnetwork_id"`
+	// SubnetworkCIDR is the CIDR range of the existing subnetwork.
+	SubnetworkCIDR string `hcl:"subnetwork_cidr" cty:"subnetwork_cidr"`
+	// DiskEncryptionKey is the (optional) Cloud KMS key the boot and state disks of the nodes are encrypted with.
+	DiskEncryptionKey string `hcl:"disk_encryption_key" cty:"disk_encryption_key"`
 }
 
 // GetCreateMAA gets the CreateMAA variable.
@@ -197,6 +213,8 @@ type AzureClusterVariables struct {
 	SubscriptionID string `hcl:"subscription_id" cty:"subscription_id"`
 	// Name of the cluster.
 	Name string `hcl:"name" cty:"name"`
+	// NameTemplate is the (optional) template for the base name of the created resources.
+	NameTemplate string `hcl:"name_template" cty:"name_template"`
 	// ImageID is the ID of the Azure image to use.
 	ImageID string `hcl:"image_id" cty:"image_id"`
 	// CreateMAA sets whether a Microsoft Azure attestation provider should be created.
@@ -223,6 +241,14 @@ type AzureClusterVariables struct {
 	MarketplaceImage *AzureMarketplaceImageVariables `hcl:"marketplace_image" cty:"marketplace_image"`
 	// AdditionalTags are (optional) additional tags that get applied to created resources.
 	AdditionalTags cloudprovider.Tags `hcl:"additional_tags" cty:"additional_tags"`
+	// UserData is the (optional) user data script run by the instances on boot.
+	UserData string `hcl:"user_data" cty:"user_data"`
+	// VirtualNetworkID is the (optional) resource ID of an existing virtual network to attach the nodes to.
+	VirtualNetworkID string `hcl:"virtual_network_id" cty:"virtual_network_id"`
+	// SubnetID is the (optional) resource ID of an existing subnet to attach the nodes to.
+	SubnetID string `hcl:"subnet_id" cty:"subnet_id"`
+	// SubnetCIDR is the CIDR range of the existing subnet.
+	SubnetCIDR string `hcl:"subnet_cidr" cty:"subnet_cidr"`
 }
 
 // GetCreateMAA gets the CreateMAA variable.
@@ -289,6 +315,8 @@ func (v *AzureIAMVariables) String() string {
 type OpenStackClusterVariables struct {
 	// Name of the cluster.
 	Name string `hcl:"name" cty:"name"`
+	// NameTemplate is the (optional) template for the base name of the created resources.
+	NameTemplate string `hcl:"name_template" cty:"name_template"`
 	// NodeGroups is a map of node groups to create.
 	NodeGroups map[string]OpenStackNodeGroup `hcl:"node_groups" cty:"node_groups"`
 	// Cloud is the name of the OpenStack cloud to use when reading the "clouds.yaml" configuration file. If empty, environment variables are used.
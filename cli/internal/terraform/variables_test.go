@@ -45,10 +45,12 @@ func TestAWSClusterVariables(t *testing.T) {
 		Debug:                  true,
 		EnableSNP:              true,
 		CustomEndpoint:         "example.com",
+		UserData:               "#!/bin/sh\necho hello\n",
 	}
 
 	// test that the variables are correctly rendered
 	want := `name                                    = "cluster-name"
+name_template                           = ""
 region                                  = "eu-central-1"
 zone                                    = "eu-central-1a"
 image_id                                = "ami-0123456789abcdef"
@@ -77,6 +79,7 @@ node_groups = {
 custom_endpoint        = "example.com"
 internal_load_balancer = false
 additional_tags        = null
+user_data              = "#!/bin/sh\necho hello\n"
 `
 	got := vars.String()
 	assert.Equal(t, strings.Fields(want), strings.Fields(got)) // to ignore whitespace differences
@@ -122,17 +125,22 @@ func TestGCPClusterVariables(t *testing.T) {
 				DiskType:        "pd-ssd",
 			},
 		},
-		CustomEndpoint: "example.com",
-		CCTechnology:   "SEV_SNP",
+		CustomEndpoint:    "example.com",
+		CCTechnology:      "SEV_SNP",
+		NetworkID:         "projects/my-project/global/networks/my-network",
+		SubnetworkID:      "projects/my-project/regions/eu-central-1/subnetworks/my-subnetwork",
+		SubnetworkCIDR:    "10.1.0.0/24",
+		DiskEncryptionKey: "projects/my-project/locations/eu-central-1/keyRings/my-ring/cryptoKeys/my-key",
 	}
 
 	// test that the variables are correctly rendered
-	want := `name     = "cluster-name"
-project  = "my-project"
-region   = "eu-central-1"
-zone     = "eu-central-1a"
-image_id = "image-0123456789abcdef"
-debug    = true
+	want := `name          = "cluster-name"
+name_template = ""
+project       = "my-project"
+region        = "eu-central-1"
+zone          = "eu-central-1a"
+image_id      = "image-0123456789abcdef"
+debug         = true
 node_groups = {
   control_plane_default = {
     disk_size     = 30
@@ -155,6 +163,11 @@ custom_endpoint        = "example.com"
 internal_load_balancer = false
 cc_technology          = "SEV_SNP"
 additional_labels        = null
+user_data                = ""
+network_id               = "projects/my-project/global/networks/my-network"
+subnetwork_id            = "projects/my-project/regions/eu-central-1/subnetworks/my-subnetwork"
+subnetwork_cidr          = "10.1.0.0/24"
+disk_encryption_key      = "projects/my-project/locations/eu-central-1/keyRings/my-ring/cryptoKeys/my-key"
 `
 	got := vars.String()
 	assert.Equal(t, strings.Fields(want), strings.Fields(got)) // to ignore whitespace differences
@@ -210,6 +223,7 @@ func TestAzureClusterVariables(t *testing.T) {
 	// test that the variables are correctly rendered
 	want := `subscription_id        = "01234567-cdef-0123-4567-89abcdef0123"
 name                   = "cluster-name"
+name_template          = ""
 image_id               = "image-0123456789abcdef"
 create_maa             = true
 debug                  = true
@@ -236,6 +250,10 @@ marketplace_image = {
   version   = "2.13.0"
 }
 additional_tags = null
+user_data       = ""
+virtual_network_id = ""
+subnet_id          = ""
+subnet_cidr        = ""
 `
 	got := vars.String()
 	assert.Equal(t, strings.Fields(want), strings.Fields(got)) // to ignore whitespace differences
@@ -282,7 +300,8 @@ func TestOpenStackClusterVariables(t *testing.T) {
 	}
 
 	// test that the variables are correctly rendered
-	want := `name = "cluster-name"
+	want := `name          = "cluster-name"
+name_template = ""
 node_groups = {
   control_plane_default = {
     flavor_id       = "flavor-0123456789abcdef"
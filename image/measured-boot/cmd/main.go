@@ -11,6 +11,8 @@ import (
 	"crypto"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"os"
@@ -27,7 +29,7 @@ const (
 	ukiPath = "/boot/EFI/BOOT/BOOTX64.EFI"
 )
 
-func precalculatePCRs(fs afero.Fs, dissectToolchain, imageFile string) (*measure.Simulator, error) {
+func precalculatePCRs(fs afero.Fs, dissectToolchain, imageFile string, mode measure.Mode, initdataFile string) (*measure.Simulator, error) {
 	dir, err := afero.TempDir(fs, "", "con-measure")
 	if err != nil {
 		return nil, err
@@ -66,7 +68,16 @@ func precalculatePCRs(fs afero.Fs, dissectToolchain, imageFile string) (*measure
 		return nil, err
 	}
 
+	if mode == measure.ModeCoCo {
+		if err := precalculatePCR8(simulator, fs, initdataFile); err != nil {
+			return nil, err
+		}
+	}
+
 	fmt.Fprintf(os.Stderr, "PCR[ 4]: %x\n", simulator.Bank[4])
+	if mode == measure.ModeCoCo {
+		fmt.Fprintf(os.Stderr, "PCR[ 8]: %x\n", simulator.Bank[8])
+	}
 	fmt.Fprintf(os.Stderr, "PCR[ 9]: %x\n", simulator.Bank[9])
 	fmt.Fprintf(os.Stderr, "PCR[11]: %x\n", simulator.Bank[11])
 	// TODO(malt3): with systemd-stub >= 254, PCR[12] will
@@ -168,6 +179,19 @@ func precalculatePCR9(simulator *measure.Simulator, fs afero.Fs, ukiFile string)
 	return measure.PredictPCR9(simulator, cmdlineBytes, initrdDigestBytes)
 }
 
+func precalculatePCR8(simulator *measure.Simulator, fs afero.Fs, initdataFile string) error {
+	initdata, err := afero.ReadFile(fs, initdataFile)
+	if err != nil {
+		return fmt.Errorf("failed to read Kata initdata: %v", err)
+	}
+
+	if err := measure.DescribeKataInitdata(os.Stderr, sha256.Sum256(initdata)); err != nil {
+		return err
+	}
+
+	return measure.PredictPCR8(simulator, initdata)
+}
+
 func precalculatePCR11(simulator *measure.Simulator, ukiSections []pesection.PESection) error {
 	if err := measure.DescribeUKISections(os.Stderr, ukiSections); err != nil {
 		return err
@@ -193,6 +217,21 @@ func loadToolchain(key, fallback string) string {
 	return absolutePath
 }
 
+// parseMode parses the measurement mode and checks that the initdata is given exactly for the "coco" mode.
+func parseMode(modeName, initdataFile string) (measure.Mode, error) {
+	mode, err := measure.ParseMode(modeName)
+	if err != nil {
+		return "", err
+	}
+	if mode == measure.ModeCoCo && initdataFile == "" {
+		return "", errors.New("mode \"coco\" requires the Kata initdata file to be set with -initdata")
+	}
+	if mode != measure.ModeCoCo && initdataFile != "" {
+		return "", fmt.Errorf("-initdata is only supported in mode %q", measure.ModeCoCo)
+	}
+	return mode, nil
+}
+
 func writeOutput(fs afero.Fs, outputFile string, simulator *measure.Simulator) error {
 	out, err := fs.Create(outputFile)
 	if err != nil {
@@ -204,18 +243,31 @@ func writeOutput(fs afero.Fs, outputFile string, simulator *measure.Simulator) e
 }
 
 func main() {
-	if len(os.Args) != 3 {
-		fmt.Fprintln(os.Stderr, "Usage: measured-boot-precalc <image-file> <output-file>")
+	modeName := flag.String("mode", string(measure.ModeStandard), "boot chain to compute the measurements for, one of \"standard\" or \"coco\" (confidential containers)")
+	initdataFile := flag.String("initdata", "", "Kata initdata file measured by images in \"coco\" mode")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: measured-boot-precalc [-mode standard|coco] [-initdata <file>] <image-file> <output-file>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
 		os.Exit(1)
 	}
 
-	imageFile := os.Args[1]
-	outputFile := os.Args[2]
+	imageFile := flag.Arg(0)
+	outputFile := flag.Arg(1)
+
+	mode, err := parseMode(*modeName, *initdataFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
 	fs := afero.NewOsFs()
 	dissectToolchain := loadToolchain("DISSECT_TOOLCHAIN", "systemd-dissect")
 
-	simulator, err := precalculatePCRs(fs, dissectToolchain, imageFile)
+	simulator, err := precalculatePCRs(fs, dissectToolchain, imageFile, mode, *initdataFile)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
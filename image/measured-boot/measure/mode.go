@@ -0,0 +1,33 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package measure
+
+import "fmt"
+
+// Mode selects the boot chain the measurements are computed for.
+type Mode string
+
+const (
+	// ModeStandard computes the measurements of a regular Constellation node image.
+	ModeStandard Mode = "standard"
+	// ModeCoCo computes the measurements of a node image running confidential containers (Kata).
+	// In addition to the standard boot chain, the Kata agent measures its initdata into PCR 8.
+	ModeCoCo Mode = "coco"
+)
+
+// ParseMode parses the name of a measurement mode.
+// An empty name selects the standard mode.
+func ParseMode(name string) (Mode, error) {
+	switch Mode(name) {
+	case "", ModeStandard:
+		return ModeStandard, nil
+	case ModeCoCo:
+		return ModeCoCo, nil
+	default:
+		return "", fmt.Errorf("unknown measurement mode %q, must be one of %q, %q", name, ModeStandard, ModeCoCo)
+	}
+}
@@ -0,0 +1,34 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package measure
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+)
+
+// DescribeKataInitdata describes the expected measurements for the initdata of the Kata agent.
+func DescribeKataInitdata(w io.Writer, initdataDigest [32]byte) error {
+	if _, err := fmt.Fprintf(w, "Kata initdata:\n"); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "  initdata (digest %x)\n", initdataDigest); err != nil {
+		return err
+	}
+	return nil
+}
+
+// PredictPCR8 predicts the PCR8 value of a confidential containers (Kata) node based on the initdata of the Kata agent.
+// The initdata carries the agent policy and configuration and is measured before the agent starts, so PCR8 stays zero in the standard mode.
+func PredictPCR8(simulator *Simulator, initdata []byte) error {
+	// Kata Containers initdata
+	// https://github.com/kata-containers/kata-containers/blob/main/docs/design/kata-initdata.md
+	// the initdata document is hashed as-is and measured
+	initdataDigest := sha256.Sum256(initdata)
+	return simulator.ExtendPCR(8, initdataDigest, nil, fmt.Sprintf("EV_EVENT_TAG: Kata initdata (digest %x)", initdataDigest))
+}
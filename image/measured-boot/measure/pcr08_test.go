@@ -0,0 +1,83 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package measure
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPredictPCR8(t *testing.T) {
+	assert := assert.New(t)
+
+	sim := NewDefaultSimulator()
+
+	initdata := []byte("version = \"0.1.0\"\nalgorithm = \"sha256\"\n")
+
+	out := bytes.NewBuffer(nil)
+	assert.NoError(DescribeKataInitdata(out, sha256.Sum256(initdata)))
+	assert.Equal("Kata initdata:\n"+
+		"  initdata (digest 677304ed23ec2f17d776f30f5d5f8a64d2f55dd6942fce1ec88d7baa638963e8)\n",
+		out.String())
+
+	assert.NoError(PredictPCR8(sim, initdata))
+	assert.Equal(PCR256{
+		0x2b, 0xaa, 0xdd, 0xae, 0xd8, 0xb2, 0x65, 0x72,
+		0x74, 0xaa, 0x4e, 0xf6, 0xf4, 0x96, 0xa2, 0xd4,
+		0xff, 0x37, 0x5c, 0xc3, 0x7c, 0x1a, 0x78, 0xcb,
+		0xb4, 0x84, 0xc1, 0x35, 0x77, 0x83, 0xd6, 0xe4,
+	}, sim.Bank[8])
+}
+
+func TestCoCoDiffersFromStandard(t *testing.T) {
+	assert := assert.New(t)
+
+	cmdline := []byte("console=tty0\x00")
+	initrdDigest := [32]byte{}
+
+	standard := NewDefaultSimulator()
+	assert.NoError(PredictPCR9(standard, cmdline, initrdDigest))
+
+	coco := NewDefaultSimulator()
+	assert.NoError(PredictPCR9(coco, cmdline, initrdDigest))
+	assert.NoError(PredictPCR8(coco, []byte("algorithm = \"sha256\"\n")))
+
+	// Only the Kata initdata PCR differs, the shared boot chain is measured the same way.
+	assert.Equal(ZeroPCR256(), standard.Bank[8])
+	assert.NotEqual(standard.Bank[8], coco.Bank[8])
+	assert.Equal(standard.Bank[9], coco.Bank[9])
+}
+
+func TestParseMode(t *testing.T) {
+	testCases := map[string]struct {
+		name     string
+		wantMode Mode
+		wantErr  bool
+	}{
+		"empty":    {name: "", wantMode: ModeStandard},
+		"standard": {name: "standard", wantMode: ModeStandard},
+		"coco":     {name: "coco", wantMode: ModeCoCo},
+		"unknown":  {name: "kata", wantErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			mode, err := ParseMode(tc.name)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantMode, mode)
+		})
+	}
+}
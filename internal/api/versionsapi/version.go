@@ -357,7 +357,7 @@ func ValidateRef(ref string) error {
 
 // ValidateStream checks if the given stream is a valid stream for the given ref.
 func ValidateStream(ref, stream string) error {
-	validReleaseStreams := []string{"stable", "console", "debug"}
+	validReleaseStreams := []string{"stable", "beta", "console", "debug"}
 	validStreams := []string{"nightly", "console", "debug"}
 
 	if ref == ReleaseRef {
@@ -440,7 +440,7 @@ func parseShortPath(shortPath string) (ref, stream, version string, err error) {
 	if shortPathReleaseRegex.MatchString(shortPath) {
 		matches := shortPathReleaseRegex.FindStringSubmatch(shortPath)
 		stream := matches[1]
-		if err := ValidateStream(ref, stream); err != nil {
+		if err := ValidateStream(ReleaseRef, stream); err != nil {
 			return "", "", "", err
 		}
 		version := matches[2]
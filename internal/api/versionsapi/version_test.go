@@ -800,6 +800,11 @@ func TestParseShortPath(t *testing.T) {
 			wantStream:  "debug",
 			wantVersion: "v9.9.9",
 		},
+		"stream/beta/v9.9.9-rc.1": {
+			wantRef:     ReleaseRef,
+			wantStream:  "beta",
+			wantVersion: "v9.9.9-rc.1",
+		},
 		"ref/foo/stream/debug/v9.9.9": {
 			wantRef:     "foo",
 			wantStream:  "debug",
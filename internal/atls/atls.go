@@ -54,9 +54,19 @@ func CreateAttestationServerTLSConfig(issuer Issuer, validators []Validator) (*t
 // If no validators are set, the server's attestation document will not be verified.
 // If issuer is nil, the client will be unable to perform mutual aTLS.
 func CreateAttestationClientTLSConfig(issuer Issuer, validators []Validator) (*tls.Config, error) {
-	clientNonce, err := crypto.GenerateRandomBytes(crypto.RNGLengthDefault)
+	return CreateAttestationClientTLSConfigWithNonceSource(issuer, validators, RandomNonceSource{})
+}
+
+// CreateAttestationClientTLSConfigWithNonceSource creates a tls.Config object like CreateAttestationClientTLSConfig,
+// but takes the nonce the server's attestation document is bound to from nonceSource.
+// The nonce must be NonceLength bytes long.
+func CreateAttestationClientTLSConfigWithNonceSource(issuer Issuer, validators []Validator, nonceSource NonceSource) (*tls.Config, error) {
+	clientNonce, err := nonceSource.Nonce()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("getting nonce: %w", err)
+	}
+	if len(clientNonce) != NonceLength {
+		return nil, fmt.Errorf("invalid nonce length: expected %d bytes, got %d", NonceLength, len(clientNonce))
 	}
 	clientConn := &clientConnection{
 		issuer:      issuer,
@@ -74,6 +84,22 @@ func CreateAttestationClientTLSConfig(issuer Issuer, validators []Validator) (*t
 	}, nil
 }
 
+// NonceLength is the length of the nonces used in aTLS handshakes.
+const NonceLength = crypto.RNGLengthDefault
+
+// NonceSource provides the nonces a client sends in aTLS handshakes.
+type NonceSource interface {
+	Nonce() ([]byte, error)
+}
+
+// RandomNonceSource generates random nonces using crypto/rand.
+type RandomNonceSource struct{}
+
+// Nonce returns a new random nonce.
+func (RandomNonceSource) Nonce() ([]byte, error) {
+	return crypto.GenerateRandomBytes(NonceLength)
+}
+
 // Issuer issues an attestation document.
 type Issuer interface {
 	variant.Getter
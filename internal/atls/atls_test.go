@@ -7,12 +7,14 @@ SPDX-License-Identifier: AGPL-3.0-only
 package atls
 
 import (
+	"bytes"
 	"context"
 	"encoding/asn1"
 	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 
 	"github.com/edgelesssys/constellation/v2/internal/attestation/variant"
@@ -324,3 +326,97 @@ func TestServerConnectionConcurrency(t *testing.T) {
 		assert.NoError(<-errChan)
 	}
 }
+
+func TestNonceSource(t *testing.T) {
+	oid := fakeOID{asn1.ObjectIdentifier{1, 3, 9900, 1}}
+
+	testCases := map[string]struct {
+		nonceSource   *stubNonceSource
+		wantConfigErr bool
+	}{
+		"nonce is used in handshake": {
+			nonceSource: &stubNonceSource{nonce: bytes.Repeat([]byte{0x42}, NonceLength)},
+		},
+		"nonce too short": {
+			nonceSource:   &stubNonceSource{nonce: bytes.Repeat([]byte{0x42}, NonceLength-1)},
+			wantConfigErr: true,
+		},
+		"nonce too long": {
+			nonceSource:   &stubNonceSource{nonce: bytes.Repeat([]byte{0x42}, NonceLength+1)},
+			wantConfigErr: true,
+		},
+		"nonce source fails": {
+			nonceSource:   &stubNonceSource{err: errors.New("failed")},
+			wantConfigErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			clientConfig, err := CreateAttestationClientTLSConfigWithNonceSource(nil, []Validator{NewFakeValidator(oid)}, tc.nonceSource)
+			if tc.wantConfigErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			issuer := &recordingIssuer{FakeIssuer: NewFakeIssuer(oid)}
+			serverConfig, err := CreateAttestationServerTLSConfig(issuer, nil)
+			require.NoError(err)
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = io.WriteString(w, "hello")
+			}))
+			server.TLS = serverConfig
+			server.StartTLS()
+			defer server.Close()
+
+			client := http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig}}
+			req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, http.NoBody)
+			require.NoError(err)
+			resp, err := client.Do(req)
+			require.NoError(err)
+			resp.Body.Close()
+			client.CloseIdleConnections()
+
+			assert.Equal([][]byte{tc.nonceSource.nonce}, issuer.nonces)
+		})
+	}
+}
+
+func TestRandomNonceSource(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	nonce1, err := RandomNonceSource{}.Nonce()
+	require.NoError(err)
+	nonce2, err := RandomNonceSource{}.Nonce()
+	require.NoError(err)
+	assert.Len(nonce1, NonceLength)
+	assert.NotEqual(nonce1, nonce2)
+}
+
+type stubNonceSource struct {
+	nonce []byte
+	err   error
+}
+
+func (s *stubNonceSource) Nonce() ([]byte, error) {
+	return s.nonce, s.err
+}
+
+// recordingIssuer records the nonces it issues attestation documents for.
+type recordingIssuer struct {
+	*FakeIssuer
+	mux    sync.Mutex
+	nonces [][]byte
+}
+
+func (i *recordingIssuer) Issue(ctx context.Context, userData []byte, nonce []byte) ([]byte, error) {
+	i.mux.Lock()
+	defer i.mux.Unlock()
+	i.nonces = append(i.nonces, nonce)
+	return i.FakeIssuer.Issue(ctx, userData, nonce)
+}
@@ -58,6 +58,29 @@ func (NOPLogger) Info(string, ...interface{}) {}
 // Warn is a no-op.
 func (NOPLogger) Warn(string, ...interface{}) {}
 
+// Tracer is implemented by a [Logger] that records the individual steps of validating an attestation document,
+// e.g., to explain the validation to users.
+type Tracer interface {
+	Trace(step TraceStep)
+}
+
+// TraceStep is a single step of validating an attestation document.
+type TraceStep struct {
+	// Name of the step, e.g., "compare measurements".
+	Name string
+	// Values involved in the step, as alternating keys and values.
+	Values []any
+	// Err is the error the step failed with, or nil if it succeeded.
+	Err error
+}
+
+// Trace records a step of validating an attestation document, if log is a [Tracer].
+func Trace(log Logger, name string, err error, values ...any) {
+	if tracer, ok := log.(Tracer); ok {
+		tracer.Trace(TraceStep{Name: name, Values: values, Err: err})
+	}
+}
+
 // DeriveClusterID derives the cluster ID from a salt and secret value.
 func DeriveClusterID(secret, salt []byte) ([]byte, error) {
 	return crypto.DeriveKey(secret, salt, []byte(crypto.DEKPrefix+clusterIDContext), crypto.DerivedKeyLengthDefault)
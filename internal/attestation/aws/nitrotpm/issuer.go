@@ -8,7 +8,9 @@ package nitrotpm
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 
@@ -33,7 +35,7 @@ func NewIssuer(log attestation.Logger) *Issuer {
 		Issuer: vtpm.NewIssuer(
 			vtpm.OpenVTPM,
 			getAttestationKey,
-			getInstanceInfo(imds.New(imds.Options{})),
+			getInstanceInfo(imds.New(imds.Options{}), newNitroTPMAttester()),
 			log,
 		),
 	}
@@ -49,18 +51,47 @@ func getAttestationKey(tpm io.ReadWriter) (*tpmclient.Key, error) {
 	return tpmAk, nil
 }
 
-// getInstanceInfo returns information about the current instance using the aws Metadata SDK.
+// getInstanceInfo returns information about the current instance using the aws Metadata SDK,
+// and the Nitro attestation document bound to the attestation key of the TPM, if the instance supports it.
 // The returned bytes will be written into the attestation document.
-func getInstanceInfo(client awsMetaData) func(context.Context, io.ReadWriteCloser, []byte) ([]byte, error) {
-	return func(ctx context.Context, _ io.ReadWriteCloser, _ []byte) ([]byte, error) {
+func getInstanceInfo(client awsMetaData, attester nitroAttester) func(context.Context, io.ReadWriteCloser, []byte) ([]byte, error) {
+	return func(ctx context.Context, tpm io.ReadWriteCloser, _ []byte) ([]byte, error) {
 		ec2InstanceIdentityOutput, err := client.GetInstanceIdentityDocument(ctx, &imds.GetInstanceIdentityDocumentInput{})
 		if err != nil {
 			return nil, fmt.Errorf("fetching instance identity document: %w", err)
 		}
-		return json.Marshal(ec2InstanceIdentityOutput.InstanceIdentityDocument)
+		info := instanceInfo{InstanceIdentityDocument: ec2InstanceIdentityOutput.InstanceIdentityDocument}
+
+		// The attestation key is derived deterministically from the endorsement hierarchy,
+		// so this is the same key the TPM attestation is created with.
+		ak, err := getAttestationKey(tpm)
+		if err != nil {
+			return nil, err
+		}
+		defer ak.Close()
+		akPub, err := ak.PublicArea().Encode()
+		if err != nil {
+			return nil, fmt.Errorf("encoding public area of attestation key: %w", err)
+		}
+		akDigest := sha256.Sum256(akPub)
+
+		info.NitroAttestationDocument, err = attester.Attest(ctx, tpm, akDigest[:])
+		if err != nil && !errors.Is(err, errNitroAttestationUnsupported) {
+			return nil, fmt.Errorf("requesting Nitro attestation document: %w", err)
+		}
+		return json.Marshal(info)
 	}
 }
 
+// errNitroAttestationUnsupported is returned by a nitroAttester if the instance can't issue Nitro attestation documents.
+// The instance info is then sent without a document, which is only rejected if the validator requires one.
+var errNitroAttestationUnsupported = errors.New("nitro attestation isn't supported on this instance")
+
+// nitroAttester requests an attestation document signed by the Nitro Hypervisor.
+type nitroAttester interface {
+	Attest(ctx context.Context, tpm io.ReadWriter, userData []byte) ([]byte, error)
+}
+
 type awsMetaData interface {
 	GetInstanceIdentityDocument(context.Context, *imds.GetInstanceIdentityDocumentInput, ...func(*imds.Options)) (*imds.GetInstanceIdentityDocumentOutput, error)
 }
@@ -7,14 +7,22 @@ SPDX-License-Identifier: AGPL-3.0-only
 package nitrotpm
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha512"
+	"encoding/json"
 	"errors"
+	"io"
 	"os"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
 	"github.com/aws/smithy-go/middleware"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/measurements"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/simulator"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/vtpm"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/fxamacker/cbor/v2"
 	tpmclient "github.com/google/go-tpm-tools/client"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -97,7 +105,8 @@ func TestGetInstanceInfo(t *testing.T) {
 			assert.NoError(err)
 			defer tpm.Close()
 
-			instanceInfoFunc := getInstanceInfo(&tc.client)
+			// The simulated TPM doesn't implement the NSM command, so no Nitro attestation document is attached.
+			instanceInfoFunc := getInstanceInfo(&tc.client, newNitroTPMAttester())
 			assert.NotNil(instanceInfoFunc)
 
 			info, err := instanceInfoFunc(context.Background(), tpm, nil)
@@ -112,6 +121,122 @@ func TestGetInstanceInfo(t *testing.T) {
 	}
 }
 
+func TestGetInstanceInfoNitroAttestation(t *testing.T) {
+	cgo := os.Getenv("CGO_ENABLED")
+	if cgo == "0" {
+		t.Skip("skipping test because CGO is disabled and tpm simulator requires it")
+	}
+	testCases := map[string]struct {
+		nsm               *stubNSM
+		wantDoc           bool
+		wantErr           bool
+		wantValidationErr bool
+	}{
+		"document attached": {
+			nsm:     &stubNSM{},
+			wantDoc: true,
+		},
+		"nitro attestation unsupported": {
+			wantValidationErr: true,
+		},
+		"NSM returns error": {
+			nsm:     &stubNSM{nsmErr: "InvalidArgument"},
+			wantErr: true,
+		},
+		"exchange fails": {
+			nsm:     &stubNSM{exchangeErr: errors.New("failed")},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			pki := newTestNitroPKI(t)
+			// Without a stubbed NSM, the request is sent to the simulated TPM, which isn't a NitroTPM.
+			attester := newNitroTPMAttester()
+			if tc.nsm != nil {
+				tc.nsm.t = t
+				tc.nsm.pki = pki
+				tc.nsm.pcrs = map[uint32][]byte{4: bytes.Repeat([]byte{0x04}, sha512.Size384)}
+				attester.exchange = tc.nsm.exchange
+			}
+
+			openTPM, tpmCloser := simulator.NewSimulatedTPMOpenFunc()
+			defer tpmCloser.Close()
+			issuer := vtpm.NewIssuer(
+				openTPM,
+				getAttestationKey,
+				getInstanceInfo(&stubMetadataAPI{}, attester),
+				nil,
+			)
+			rawAttDoc, err := issuer.Issue(context.Background(), []byte("user data"), []byte("nonce"))
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			var attDoc vtpm.AttestationDocument
+			require.NoError(json.Unmarshal(rawAttDoc, &attDoc))
+			var info instanceInfo
+			require.NoError(json.Unmarshal(attDoc.InstanceInfo, &info))
+			assert.Equal(tc.wantDoc, len(info.NitroAttestationDocument) > 0)
+
+			v := NewValidator(&config.AWSNitroTPM{
+				NitroAttestation: &config.NitroAttestation{
+					RootCertificate: config.Certificate(*pki.root),
+					Measurements: measurements.M{
+						4: measurements.WithAllBytes(0x04, measurements.Enforce, sha512.Size384),
+					},
+				},
+			}, nil)
+			v.getDescribeClient = func(_ context.Context, _ string) (awsMetadataAPI, error) {
+				return &stubDescribeAPI{describeImagesTPMSupport: "v2.0"}, nil
+			}
+			err = v.validateCVM(attDoc, nil)
+			if tc.wantValidationErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+		})
+	}
+}
+
+// stubNSM answers NSM requests like the NSM of a NitroTPM instance.
+// Attestation documents are signed with a test PKI.
+type stubNSM struct {
+	t           *testing.T
+	pki         *testNitroPKI
+	pcrs        map[uint32][]byte
+	nsmErr      string
+	exchangeErr error
+}
+
+func (n *stubNSM) exchange(_ io.ReadWriter, rawRequest []byte) ([]byte, error) {
+	if n.exchangeErr != nil {
+		return nil, n.exchangeErr
+	}
+	var request nsmRequest
+	require.NoError(n.t, cbor.Unmarshal(rawRequest, &request))
+	require.NotNil(n.t, request.Attestation)
+
+	response := nsmResponse{Error: n.nsmErr}
+	if n.nsmErr == "" {
+		doc := n.pki.document(n.t)
+		doc.PCRs = n.pcrs
+		doc.UserData = request.Attestation.UserData
+		response.Attestation = &nsmAttestationResponse{Document: n.pki.sign(n.t, doc)}
+	}
+	rawResponse, err := cbor.Marshal(response)
+	require.NoError(n.t, err)
+	// The response is read from an NV index, so it's followed by the unused bytes of the index.
+	return append(rawResponse, make([]byte, 64)...), nil
+}
+
 type stubMetadataAPI struct {
 	instanceDoc imds.InstanceIdentityDocument
 	instanceErr error
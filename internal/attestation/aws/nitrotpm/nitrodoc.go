@@ -0,0 +1,149 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package nitrotpm
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha512"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+	"github.com/fxamacker/cbor/v2"
+)
+
+const (
+	// coseAlgES384 is the COSE algorithm identifier of ECDSA with SHA-384.
+	coseAlgES384 = -35
+	// nitroDigestSHA384 is the digest algorithm of the PCRs of a Nitro attestation document.
+	nitroDigestSHA384 = "SHA384"
+)
+
+// instanceInfo is the instance information attached to the attestation document.
+type instanceInfo struct {
+	imds.InstanceIdentityDocument
+	// NitroAttestationDocument is the COSE_Sign1 encoded attestation document signed by the Nitro Hypervisor.
+	// Its user data holds the SHA-256 digest of the public area of the attestation key.
+	NitroAttestationDocument []byte `json:"nitroAttestationDocument,omitempty"`
+}
+
+// nitroDocument is the payload of an attestation document signed by the Nitro Hypervisor.
+// See https://docs.aws.amazon.com/enclaves/latest/user/verify-root.html for the format.
+type nitroDocument struct {
+	ModuleID    string            `cbor:"module_id"`
+	Timestamp   uint64            `cbor:"timestamp"`
+	Digest      string            `cbor:"digest"`
+	PCRs        map[uint32][]byte `cbor:"nitrotpm_pcrs"`
+	Certificate []byte            `cbor:"certificate"`
+	CABundle    [][]byte          `cbor:"cabundle"`
+	PublicKey   []byte            `cbor:"public_key,omitempty"`
+	UserData    []byte            `cbor:"user_data,omitempty"`
+	Nonce       []byte            `cbor:"nonce,omitempty"`
+}
+
+// coseSign1 is a COSE_Sign1 message as defined in RFC 9052.
+type coseSign1 struct {
+	_           struct{} `cbor:",toarray"`
+	Protected   []byte
+	Unprotected cbor.RawMessage
+	Payload     []byte
+	Signature   []byte
+}
+
+// coseHeader holds the protected header parameters of a COSE_Sign1 message.
+type coseHeader struct {
+	Alg int `cbor:"1,keyasint"`
+}
+
+// verifyNitroDocument verifies the signature of a Nitro attestation document
+// and its certificate chain to the given root certificate, and returns the payload of the document.
+func verifyNitroDocument(raw []byte, root *x509.Certificate) (*nitroDocument, error) {
+	var msg coseSign1
+	if err := cbor.Unmarshal(raw, &msg); err != nil {
+		return nil, fmt.Errorf("unmarshaling COSE_Sign1 message: %w", err)
+	}
+	var header coseHeader
+	if err := cbor.Unmarshal(msg.Protected, &header); err != nil {
+		return nil, fmt.Errorf("unmarshaling protected header: %w", err)
+	}
+	if header.Alg != coseAlgES384 {
+		return nil, fmt.Errorf("unsupported signature algorithm %d, expected ES384 (%d)", header.Alg, coseAlgES384)
+	}
+	var doc nitroDocument
+	if err := cbor.Unmarshal(msg.Payload, &doc); err != nil {
+		return nil, fmt.Errorf("unmarshaling payload: %w", err)
+	}
+	if doc.Digest != nitroDigestSHA384 {
+		return nil, fmt.Errorf("unsupported PCR digest %q, expected %q", doc.Digest, nitroDigestSHA384)
+	}
+
+	leaf, err := verifyNitroCertChain(&doc, root)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyES384(leaf, msg); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// verifyNitroCertChain verifies the signing certificate of the document against the given root,
+// using the CA bundle of the document as intermediates. The chain must be valid at the time the document was created.
+func verifyNitroCertChain(doc *nitroDocument, root *x509.Certificate) (*x509.Certificate, error) {
+	leaf, err := x509.ParseCertificate(doc.Certificate)
+	if err != nil {
+		return nil, fmt.Errorf("parsing signing certificate: %w", err)
+	}
+	intermediates := x509.NewCertPool()
+	for i, der := range doc.CABundle {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("parsing certificate %d of the CA bundle: %w", i, err)
+		}
+		intermediates.AddCert(cert)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   time.UnixMilli(int64(doc.Timestamp)),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, fmt.Errorf("verifying certificate chain: %w", err)
+	}
+	return leaf, nil
+}
+
+// verifyES384 verifies the ES384 signature of a COSE_Sign1 message with the public key of the given certificate.
+func verifyES384(cert *x509.Certificate, msg coseSign1) error {
+	pub, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok || pub.Curve != elliptic.P384() {
+		return errors.New("signing certificate doesn't hold a P-384 ECDSA key")
+	}
+	// The signature is the concatenation of r and s, each padded to the size of the curve.
+	size := (pub.Curve.Params().BitSize + 7) / 8
+	if len(msg.Signature) != 2*size {
+		return fmt.Errorf("invalid signature length %d, expected %d", len(msg.Signature), 2*size)
+	}
+	sigStructure, err := cbor.Marshal([]any{"Signature1", msg.Protected, []byte{}, msg.Payload})
+	if err != nil {
+		return fmt.Errorf("marshaling signature structure: %w", err)
+	}
+	digest := sha512.Sum384(sigStructure)
+	r := new(big.Int).SetBytes(msg.Signature[:size])
+	s := new(big.Int).SetBytes(msg.Signature[size:])
+	if !ecdsa.Verify(pub, digest[:], r, s) {
+		return errors.New("invalid signature of the attestation document")
+	}
+	return nil
+}
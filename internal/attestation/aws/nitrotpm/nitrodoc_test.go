@@ -0,0 +1,330 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package nitrotpm
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/measurements"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/vtpm"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/google/go-tpm-tools/proto/attest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyNitroDocument(t *testing.T) {
+	pki := newTestNitroPKI(t)
+	otherPKI := newTestNitroPKI(t)
+	doc := pki.document(t)
+
+	testCases := map[string]struct {
+		raw     []byte
+		root    *x509.Certificate
+		wantErr bool
+	}{
+		"valid document": {
+			raw:  pki.sign(t, doc),
+			root: pki.root,
+		},
+		"chain to other root": {
+			raw:     pki.sign(t, doc),
+			root:    otherPKI.root,
+			wantErr: true,
+		},
+		"certificate not valid at document creation": {
+			raw: pki.sign(t, func() nitroDocument {
+				doc := pki.document(t)
+				doc.Timestamp = uint64(time.Now().Add(48 * time.Hour).UnixMilli())
+				return doc
+			}()),
+			root:    pki.root,
+			wantErr: true,
+		},
+		"missing intermediate": {
+			raw: pki.sign(t, func() nitroDocument {
+				doc := pki.document(t)
+				doc.CABundle = [][]byte{pki.root.Raw}
+				return doc
+			}()),
+			root:    pki.root,
+			wantErr: true,
+		},
+		"signed by other key": {
+			raw: func() []byte {
+				signer := *pki
+				signer.leafKey = otherPKI.leafKey
+				return signer.sign(t, doc)
+			}(),
+			root:    pki.root,
+			wantErr: true,
+		},
+		"modified payload": {
+			raw: func() []byte {
+				var msg coseSign1
+				require.NoError(t, cbor.Unmarshal(pki.sign(t, doc), &msg))
+				modified := doc
+				modified.PCRs = map[uint32][]byte{0: bytes.Repeat([]byte{0xFF}, sha512.Size384)}
+				payload, err := cbor.Marshal(modified)
+				require.NoError(t, err)
+				msg.Payload = payload
+				raw, err := cbor.Marshal(msg)
+				require.NoError(t, err)
+				return raw
+			}(),
+			root:    pki.root,
+			wantErr: true,
+		},
+		"unsupported digest": {
+			raw: pki.sign(t, func() nitroDocument {
+				doc := pki.document(t)
+				doc.Digest = "SHA256"
+				return doc
+			}()),
+			root:    pki.root,
+			wantErr: true,
+		},
+		"invalid encoding": {
+			raw:     []byte("not a COSE_Sign1 message"),
+			root:    pki.root,
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			out, err := verifyNitroDocument(tc.raw, tc.root)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(doc.PCRs, out.PCRs)
+		})
+	}
+}
+
+func TestValidateNitroAttestation(t *testing.T) {
+	pki := newTestNitroPKI(t)
+	akPub := []byte("attestation key")
+	akDigest := sha256.Sum256(akPub)
+	pcr0 := bytes.Repeat([]byte{0x01}, sha512.Size384)
+	pcr4 := bytes.Repeat([]byte{0x04}, sha512.Size384)
+
+	attDocWith := func(nitroDoc []byte) vtpm.AttestationDocument {
+		info, err := json.Marshal(instanceInfo{
+			InstanceIdentityDocument: imds.InstanceIdentityDocument{ImageID: "ami-tpm-enabled"},
+			NitroAttestationDocument: nitroDoc,
+		})
+		require.NoError(t, err)
+		return vtpm.AttestationDocument{
+			Attestation:  &attest.Attestation{AkPub: akPub},
+			InstanceInfo: info,
+		}
+	}
+	signedDoc := func(userData []byte) []byte {
+		doc := pki.document(t)
+		doc.PCRs = map[uint32][]byte{0: pcr0, 4: pcr4}
+		doc.UserData = userData
+		return pki.sign(t, doc)
+	}
+
+	testCases := map[string]struct {
+		attDoc       vtpm.AttestationDocument
+		measurements measurements.M
+		wantErr      bool
+	}{
+		"PCRs match": {
+			attDoc: attDocWith(signedDoc(akDigest[:])),
+			measurements: measurements.M{
+				0: measurements.WithAllBytes(0x01, measurements.Enforce, sha512.Size384),
+				4: measurements.WithAllBytes(0x04, measurements.Enforce, sha512.Size384),
+			},
+		},
+		"PCR mismatch": {
+			attDoc: attDocWith(signedDoc(akDigest[:])),
+			measurements: measurements.M{
+				0: measurements.WithAllBytes(0x01, measurements.Enforce, sha512.Size384),
+				4: measurements.WithAllBytes(0x05, measurements.Enforce, sha512.Size384),
+			},
+			wantErr: true,
+		},
+		"PCR mismatch with warnOnly": {
+			attDoc: attDocWith(signedDoc(akDigest[:])),
+			measurements: measurements.M{
+				4: measurements.WithAllBytes(0x05, measurements.WarnOnly, sha512.Size384),
+			},
+		},
+		"wildcard PCR matches any value": {
+			attDoc: attDocWith(signedDoc(akDigest[:])),
+			measurements: measurements.M{
+				0: measurements.WithAllBytes(0x01, measurements.Enforce, sha512.Size384),
+				4: {Any: true, ValidationOpt: measurements.Enforce},
+			},
+		},
+		"PCR in set": {
+			attDoc: attDocWith(signedDoc(akDigest[:])),
+			measurements: measurements.M{
+				0: measurements.WithAllBytes(0x01, measurements.Enforce, sha512.Size384),
+				4: {
+					OneOf:         [][]byte{bytes.Repeat([]byte{0x05}, sha512.Size384), pcr4},
+					ValidationOpt: measurements.Enforce,
+				},
+			},
+		},
+		"PCR not in set": {
+			attDoc: attDocWith(signedDoc(akDigest[:])),
+			measurements: measurements.M{
+				0: measurements.WithAllBytes(0x01, measurements.Enforce, sha512.Size384),
+				4: {
+					OneOf:         [][]byte{bytes.Repeat([]byte{0x05}, sha512.Size384), bytes.Repeat([]byte{0x06}, sha512.Size384)},
+					ValidationOpt: measurements.Enforce,
+				},
+			},
+			wantErr: true,
+		},
+		"missing PCR": {
+			attDoc: attDocWith(signedDoc(akDigest[:])),
+			measurements: measurements.M{
+				9: measurements.WithAllBytes(0x09, measurements.Enforce, sha512.Size384),
+			},
+			wantErr: true,
+		},
+		"document not bound to attestation key": {
+			attDoc: attDocWith(signedDoc([]byte("other key"))),
+			measurements: measurements.M{
+				0: measurements.WithAllBytes(0x01, measurements.Enforce, sha512.Size384),
+			},
+			wantErr: true,
+		},
+		"missing document": {
+			attDoc: attDocWith(nil),
+			measurements: measurements.M{
+				0: measurements.WithAllBytes(0x01, measurements.Enforce, sha512.Size384),
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			v := NewValidator(&config.AWSNitroTPM{
+				NitroAttestation: &config.NitroAttestation{
+					RootCertificate: config.Certificate(*pki.root),
+					Measurements:    tc.measurements,
+				},
+			}, nil)
+			v.getDescribeClient = func(_ context.Context, _ string) (awsMetadataAPI, error) {
+				return &stubDescribeAPI{describeImagesTPMSupport: "v2.0"}, nil
+			}
+
+			err := v.validateCVM(tc.attDoc, nil)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+		})
+	}
+}
+
+// testNitroPKI is a certificate hierarchy mimicking the one of the Nitro Hypervisor:
+// a root, an intermediate, and a leaf signing the attestation documents.
+type testNitroPKI struct {
+	root         *x509.Certificate
+	intermediate *x509.Certificate
+	leaf         *x509.Certificate
+	leafKey      *ecdsa.PrivateKey
+}
+
+func newTestNitroPKI(t *testing.T) *testNitroPKI {
+	t.Helper()
+	rootKey, root := newTestNitroCert(t, "root", nil, nil)
+	intermediateKey, intermediate := newTestNitroCert(t, "intermediate", root, rootKey)
+	leafKey, leaf := newTestNitroCert(t, "leaf", intermediate, intermediateKey)
+	return &testNitroPKI{root: root, intermediate: intermediate, leaf: leaf, leafKey: leafKey}
+}
+
+func newTestNitroCert(t *testing.T, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*ecdsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil || name != "leaf",
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return key, cert
+}
+
+// document returns the payload of a valid attestation document.
+func (p *testNitroPKI) document(t *testing.T) nitroDocument {
+	t.Helper()
+	return nitroDocument{
+		ModuleID:    "i-1234567890abcdef0-tpm",
+		Timestamp:   uint64(time.Now().UnixMilli()),
+		Digest:      nitroDigestSHA384,
+		PCRs:        map[uint32][]byte{0: bytes.Repeat([]byte{0x00}, sha512.Size384)},
+		Certificate: p.leaf.Raw,
+		CABundle:    [][]byte{p.root.Raw, p.intermediate.Raw},
+	}
+}
+
+// sign returns the given payload as COSE_Sign1 message signed with the leaf key.
+func (p *testNitroPKI) sign(t *testing.T, doc nitroDocument) []byte {
+	t.Helper()
+	protected, err := cbor.Marshal(coseHeader{Alg: coseAlgES384})
+	require.NoError(t, err)
+	payload, err := cbor.Marshal(doc)
+	require.NoError(t, err)
+	sigStructure, err := cbor.Marshal([]any{"Signature1", protected, []byte{}, payload})
+	require.NoError(t, err)
+	digest := sha512.Sum384(sigStructure)
+	r, s, err := ecdsa.Sign(rand.Reader, p.leafKey, digest[:])
+	require.NoError(t, err)
+	signature := make([]byte, 2*sha512.Size384)
+	r.FillBytes(signature[:sha512.Size384])
+	s.FillBytes(signature[sha512.Size384:])
+
+	raw, err := cbor.Marshal(coseSign1{
+		Protected:   protected,
+		Unprotected: cbor.RawMessage{0xA0}, // empty map
+		Payload:     payload,
+		Signature:   signature,
+	})
+	require.NoError(t, err)
+	return raw
+}
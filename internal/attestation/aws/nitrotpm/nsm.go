@@ -0,0 +1,191 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package nitrotpm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// The NitroTPM forwards requests to the Nitro Security Module (NSM) of the instance.
+// A request is written to an NV index, processed by a vendor-specific TPM command,
+// which replaces it with the response of the NSM.
+const (
+	// nsmRequestCommand is the vendor-specific command of the NitroTPM that processes the NSM request in an NV index.
+	nsmRequestCommand = tpmutil.Command(0x20000001)
+	// nsmNVIndex is the NV index the NSM request and response are exchanged through.
+	nsmNVIndex = tpmutil.Handle(0x01000010)
+	// tpmaCCCommandIndex masks the command index of the TPMA_CC attributes of a command.
+	tpmaCCCommandIndex = 0xffff
+	// tpmaCCVendor is set in the TPMA_CC attributes of vendor-specific commands.
+	tpmaCCVendor = 1 << 29
+)
+
+// nsmRequest is a request to the NSM, encoded as defined by the NSM API.
+type nsmRequest struct {
+	Attestation *nsmAttestationRequest `cbor:"Attestation,omitempty"`
+}
+
+// nsmAttestationRequest requests an attestation document with the given user data from the NSM.
+type nsmAttestationRequest struct {
+	UserData  []byte `cbor:"user_data"`
+	Nonce     []byte `cbor:"nonce"`
+	PublicKey []byte `cbor:"public_key"`
+}
+
+// nsmResponse is a response of the NSM. Exactly one of the fields is set.
+type nsmResponse struct {
+	Attestation *nsmAttestationResponse `cbor:"Attestation,omitempty"`
+	Error       string                  `cbor:"Error,omitempty"`
+}
+
+// nsmAttestationResponse holds the COSE_Sign1 encoded attestation document issued by the NSM.
+type nsmAttestationResponse struct {
+	Document []byte `cbor:"document"`
+}
+
+// nitroTPMAttester requests the attestation document from the NSM through the NitroTPM.
+type nitroTPMAttester struct {
+	// exchange sends the CBOR encoded request to the NSM and returns its CBOR encoded response.
+	exchange func(tpm io.ReadWriter, request []byte) ([]byte, error)
+}
+
+func newNitroTPMAttester() nitroTPMAttester {
+	return nitroTPMAttester{exchange: exchangeNSMRequest}
+}
+
+// Attest returns a Nitro attestation document with the given user data.
+func (a nitroTPMAttester) Attest(_ context.Context, tpm io.ReadWriter, userData []byte) ([]byte, error) {
+	request, err := cbor.Marshal(nsmRequest{Attestation: &nsmAttestationRequest{UserData: userData}})
+	if err != nil {
+		return nil, fmt.Errorf("encoding NSM request: %w", err)
+	}
+	rawResponse, err := a.exchange(tpm, request)
+	if err != nil {
+		return nil, err
+	}
+
+	// The response is followed by the unused bytes of the NV index.
+	var response nsmResponse
+	if _, err := cbor.UnmarshalFirst(rawResponse, &response); err != nil {
+		return nil, fmt.Errorf("decoding NSM response: %w", err)
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("NSM returned error %q", response.Error)
+	}
+	if response.Attestation == nil || len(response.Attestation.Document) == 0 {
+		return nil, errors.New("NSM response contains no attestation document")
+	}
+	return response.Attestation.Document, nil
+}
+
+// exchangeNSMRequest writes the request to the NSM NV index of the NitroTPM, lets the NitroTPM process it,
+// and reads the response from the NV index. The NV index is as large as the TPM allows.
+// errNitroAttestationUnsupported is returned if the TPM doesn't implement the NSM command, i.e., it isn't a NitroTPM.
+func exchangeNSMRequest(tpm io.ReadWriter, request []byte) ([]byte, error) {
+	supported, err := supportsNSMCommand(tpm)
+	if err != nil {
+		return nil, fmt.Errorf("querying commands of the TPM: %w", err)
+	}
+	if !supported {
+		return nil, errNitroAttestationUnsupported
+	}
+
+	nvIndexSize, err := tpmProperty(tpm, tpm2.NVIndexMax)
+	if err != nil {
+		return nil, fmt.Errorf("querying maximum NV index size: %w", err)
+	}
+	nvBufferSize, err := tpmProperty(tpm, tpm2.NVMaxBufferSize)
+	if err != nil {
+		return nil, fmt.Errorf("querying maximum NV buffer size: %w", err)
+	}
+	if len(request) > int(nvIndexSize) {
+		return nil, fmt.Errorf("NSM request of %d bytes exceeds the maximum NV index size of %d bytes", len(request), nvIndexSize)
+	}
+
+	attributes := tpm2.AttrOwnerRead | tpm2.AttrOwnerWrite | tpm2.AttrNoDA
+	if err := tpm2.NVDefineSpace(tpm, tpm2.HandleOwner, nsmNVIndex, "", "", nil, attributes, uint16(nvIndexSize)); err != nil {
+		return nil, fmt.Errorf("defining NSM NV index: %w", err)
+	}
+	defer func() {
+		_ = tpm2.NVUndefineSpace(tpm, "", tpm2.HandleOwner, nsmNVIndex)
+	}()
+
+	for offset := 0; offset < len(request); offset += int(nvBufferSize) {
+		chunk := request[offset:min(offset+int(nvBufferSize), len(request))]
+		if err := tpm2.NVWrite(tpm, tpm2.HandleOwner, nsmNVIndex, "", chunk, uint16(offset)); err != nil {
+			return nil, fmt.Errorf("writing NSM request: %w", err)
+		}
+	}
+
+	_, code, err := tpmutil.RunCommand(tpm, tpm2.TagNoSessions, nsmRequestCommand, nsmNVIndex)
+	if err != nil {
+		return nil, fmt.Errorf("sending NSM request: %w", err)
+	}
+	if code != tpmutil.RCSuccess {
+		return nil, fmt.Errorf("sending NSM request: TPM returned response code 0x%x", code)
+	}
+
+	response, err := tpm2.NVReadEx(tpm, nsmNVIndex, tpm2.HandleOwner, "", int(nvBufferSize))
+	if err != nil {
+		return nil, fmt.Errorf("reading NSM response: %w", err)
+	}
+	return response, nil
+}
+
+// supportsNSMCommand returns whether the TPM implements the vendor-specific NSM command.
+func supportsNSMCommand(tpm io.ReadWriter) (bool, error) {
+	// The legacy TPM library can't decode the command capability, so the response is decoded here.
+	resp, code, err := tpmutil.RunCommand(tpm, tpm2.TagNoSessions, tpm2.CmdGetCapability,
+		tpm2.CapabilityCommands, uint32(nsmRequestCommand), uint32(1))
+	if err != nil {
+		return false, err
+	}
+	if code != tpmutil.RCSuccess {
+		return false, fmt.Errorf("TPM returned response code 0x%x", code)
+	}
+	var moreData uint8
+	var capability tpm2.Capability
+	var count uint32
+	buf := bytes.NewBuffer(resp)
+	if err := tpmutil.UnpackBuf(buf, &moreData, &capability, &count); err != nil {
+		return false, fmt.Errorf("decoding capability: %w", err)
+	}
+	if count == 0 {
+		return false, nil
+	}
+	// The TPM returns the attributes of the first command with a code at least as high as the requested one.
+	var attributes uint32
+	if err := tpmutil.UnpackBuf(buf, &attributes); err != nil {
+		return false, fmt.Errorf("decoding command attributes: %w", err)
+	}
+	return attributes&tpmaCCVendor != 0 &&
+		attributes&tpmaCCCommandIndex == uint32(nsmRequestCommand)&tpmaCCCommandIndex, nil
+}
+
+// tpmProperty returns the value of the given fixed property of the TPM.
+func tpmProperty(tpm io.ReadWriter, property tpm2.TPMProp) (uint32, error) {
+	props, _, err := tpm2.GetCapability(tpm, tpm2.CapabilityTPMProperties, 1, uint32(property))
+	if err != nil {
+		return 0, err
+	}
+	if len(props) == 0 {
+		return 0, fmt.Errorf("TPM has no property 0x%x", uint32(property))
+	}
+	prop, ok := props[0].(tpm2.TaggedProperty)
+	if !ok || prop.Tag != property {
+		return 0, fmt.Errorf("TPM has no property 0x%x", uint32(property))
+	}
+	return prop.Value, nil
+}
@@ -7,9 +7,13 @@ SPDX-License-Identifier: AGPL-3.0-only
 package nitrotpm
 
 import (
+	"bytes"
 	"context"
 	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	awsConfig "github.com/aws/aws-sdk-go-v2/config"
@@ -28,15 +32,24 @@ type Validator struct {
 	variant.AWSNitroTPM
 	*vtpm.Validator
 	getDescribeClient func(context.Context, string) (awsMetadataAPI, error)
+	// nitroAttestation configures the validation of the Nitro attestation document. If nil, the document isn't validated.
+	nitroAttestation *config.NitroAttestation
+	log              attestation.Logger
 }
 
 // NewValidator create a new Validator structure and returns it.
 func NewValidator(cfg *config.AWSNitroTPM, log attestation.Logger) *Validator {
-	v := &Validator{}
+	if log == nil {
+		log = &attestation.NOPLogger{}
+	}
+	v := &Validator{
+		nitroAttestation: cfg.NitroAttestation,
+		log:              log,
+	}
 	v.Validator = vtpm.NewValidator(
 		cfg.Measurements,
 		getTrustedKey,
-		v.tpmEnabled,
+		v.validateCVM,
 		log,
 	)
 	v.getDescribeClient = getEC2Client
@@ -55,6 +68,53 @@ func getTrustedKey(_ context.Context, attDoc vtpm.AttestationDocument, _ []byte)
 	return pubArea.Key()
 }
 
+// validateCVM checks that the virtual machine has the TPM enabled,
+// and validates the Nitro attestation document of the node, if configured.
+func (v *Validator) validateCVM(attDoc vtpm.AttestationDocument, state *attest.MachineState) error {
+	if err := v.tpmEnabled(attDoc, state); err != nil {
+		return err
+	}
+	if v.nitroAttestation == nil {
+		return nil
+	}
+	return v.validateNitroAttestation(attDoc)
+}
+
+// validateNitroAttestation validates the Nitro attestation document attached to the instance info.
+// The document must be signed by a certificate chaining up to the configured root, hold the expected PCR values,
+// and be bound to the attestation key of the TPM attestation by its user data.
+func (v *Validator) validateNitroAttestation(attDoc vtpm.AttestationDocument) error {
+	var info instanceInfo
+	if err := json.Unmarshal(attDoc.InstanceInfo, &info); err != nil {
+		return fmt.Errorf("unmarshaling instance info: %w", err)
+	}
+	if len(info.NitroAttestationDocument) == 0 {
+		return errors.New("instance info contains no Nitro attestation document")
+	}
+	root := x509.Certificate(v.nitroAttestation.RootCertificate)
+	doc, err := verifyNitroDocument(info.NitroAttestationDocument, &root)
+	if err != nil {
+		return fmt.Errorf("verifying Nitro attestation document: %w", err)
+	}
+
+	if attDoc.Attestation == nil {
+		return errors.New("attestation document contains no TPM attestation")
+	}
+	akDigest := sha256.Sum256(attDoc.Attestation.AkPub)
+	if !bytes.Equal(doc.UserData, akDigest[:]) {
+		return errors.New("the Nitro attestation document isn't bound to the attestation key")
+	}
+
+	warnings, errs := v.nitroAttestation.Measurements.Compare(doc.PCRs)
+	for _, warning := range warnings {
+		v.log.Warn(warning)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("validating PCRs of the Nitro attestation document: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
 // tpmEnabled verifies if the virtual machine has the tpm2.0 feature enabled.
 func (v *Validator) tpmEnabled(attestation vtpm.AttestationDocument, _ *attest.MachineState) error {
 	// https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/verify-nitrotpm-support-on-ami.html
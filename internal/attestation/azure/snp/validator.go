@@ -80,7 +80,7 @@ func NewValidator(cfg *config.AzureSEVSNP, log attestation.Logger) *Validator {
 		maa:                  newMAAClient(),
 		config:               cfg,
 		log:                  log,
-		getter:               trust.DefaultHTTPSGetter(),
+		getter:               snp.NewKDSGetter(trust.DefaultHTTPSGetter(), (*x509.Certificate)(&cfg.AMDRootKey)),
 		attestationVerifier:  attestationVerifierImpl{},
 		attestationValidator: attestationValidatorImpl{},
 	}
@@ -111,30 +111,53 @@ func (v *Validator) getTrustedKey(ctx context.Context, attDoc vtpm.AttestationDo
 		return nil, fmt.Errorf("unmarshalling instanceInfo: %w", err)
 	}
 
-	att, err := instanceInfo.AttestationWithCerts(v.getter, cachedCerts, v.log)
-	if err != nil {
-		return nil, fmt.Errorf("parsing attestation report: %w", err)
-	}
-
-	verifyOpts, err := getVerifyOpts(att)
-	if err != nil {
-		return nil, fmt.Errorf("getting verify options: %w", err)
+	verifyAttestation := func(att *spb.Attestation) error {
+		verifyOpts, err := getVerifyOpts(att)
+		if err != nil {
+			return fmt.Errorf("getting verify options: %w", err)
+		}
+		err = v.attestationVerifier.SNPAttestation(att, verifyOpts)
+		attestation.Trace(v.log, "validate certificate chain", err)
+		if err != nil {
+			return fmt.Errorf("verifying SNP attestation: %w", err)
+		}
+		return nil
 	}
 
-	if err := v.attestationVerifier.SNPAttestation(att, verifyOpts); err != nil {
-		return nil, fmt.Errorf("verifying SNP attestation: %w", err)
+	var att *spb.Attestation
+	var source snp.VCEKSource
+	if v.config.PrimaryVCEKSource == "" {
+		var err error
+		att, err = instanceInfo.AttestationWithCerts(v.getter, cachedCerts, v.log)
+		if err != nil {
+			return nil, fmt.Errorf("parsing attestation report: %w", err)
+		}
+		if err := verifyAttestation(att); err != nil {
+			return nil, err
+		}
+		source = instanceInfo.DefaultVCEKSource()
+	} else {
+		// Accept a VCEK certificate from either AMD KDS or Azure THIM, trying the configured source first.
+		var err error
+		att, source, err = instanceInfo.VerifyWithVCEKFallback(
+			snp.VCEKSource(v.config.PrimaryVCEKSource), v.getter, cachedCerts, verifyAttestation, v.log,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("verifying attestation report: %w", err)
+		}
 	}
+	v.log.Info(fmt.Sprintf("Verified attestation report using VCEK certificate from %s", source))
+	attestation.Trace(v.log, snp.VCEKSourceTraceStep, nil, snp.VCEKSourceTraceKey, source)
 
 	// Checks if the attestation report matches the given constraints.
 	// Some constraints are implicitly checked by validate.SnpAttestation:
 	// - the report is not expired
-	if err := v.attestationValidator.SNPAttestation(att, &validate.Options{
+	validateOpts := &validate.Options{
 		GuestPolicy: abi.SnpPolicy{
 			Debug: false, // Debug means the VM can be decrypted by the host for debugging purposes and thus is not allowed.
 			SMT:   true,  // Allow Simultaneous Multi-Threading (SMT). Normally, we would want to disable SMT
 			// but Azure does not allow to disable it.
 		},
-		VMPL: new(int), // Checks that Virtual Machine Privilege Level (VMPL) is 0.
 		// This checks that the reported TCB version is equal or greater than the minimum specified in the config.
 		MinimumTCB: kds.TCBParts{
 			BlSpl:    v.config.BootloaderVersion.Value, // Bootloader
@@ -158,9 +181,27 @@ func (v *Validator) getTrustedKey(ctx context.Context, attDoc vtpm.AttestationDo
 		// custom check of the MAA-specific values later. Right now, this is a double check, since a custom MAA check
 		// is performed either way.
 		RequireIDBlock: v.config.FirmwareSignerConfig.EnforcementPolicy == idkeydigest.Equal,
-	}); err != nil {
+	}
+	err := v.attestationValidator.SNPAttestation(att, validateOpts)
+	attestation.Trace(v.log, "check TCB and guest policy", err,
+		"reportedTCB", kds.DecomposeTCBVersion(kds.TCBVersion(att.Report.GetReportedTcb())), "minimumTCB", validateOpts.MinimumTCB,
+		"policy", fmt.Sprintf("%#x", att.Report.GetPolicy()),
+	)
+	if err != nil {
 		return nil, fmt.Errorf("validating SNP attestation: %w", err)
 	}
+	// The microcode SVN may be pinned separately from the aggregate TCB.
+	err = snp.ValidateMinMicrocodeSVN(att.Report, v.config.MinMicrocodeSVN)
+	attestation.Trace(v.log, "check microcode SVN", err, "microcodeSVN", snp.MicrocodeSVN(att.Report))
+	if err != nil {
+		return nil, fmt.Errorf("validating microcode SVN: %w", err)
+	}
+	// The report must be issued from the configured VMPL to prevent forgeries by lower privileged guest code.
+	err = snp.ValidateVMPL(att.Report, v.config.VMPL)
+	attestation.Trace(v.log, "check VMPL", err, "vmpl", att.Report.GetVmpl())
+	if err != nil {
+		return nil, fmt.Errorf("validating VMPL: %w", err)
+	}
 	// Custom check of the IDKeyDigests, taking care of the WarnOnly / MAAFallback cases,
 	// but also double-checking the IDKeyDigests if the enforcement policy is set to Equal.
 	if instanceInfo.Azure == nil {
@@ -250,11 +291,14 @@ func getVerifyOpts(att *spb.Attestation) (*verify.Options, error) {
 		return nil, fmt.Errorf("parsing ARK certificate: %w", err)
 	}
 
+	// The ASK and ARK are trusted for the product of the report, which determines the expected VCEK product name.
+	productLine := kds.ProductLine(att.Product)
+
 	verifyOpts := &verify.Options{
 		TrustedRoots: map[string][]*trust.AMDRootCerts{
-			"Milan": {
+			productLine: {
 				{
-					Product: "Milan",
+					Product: productLine,
 					ProductCerts: &trust.ProductCerts{
 						Ask: ask,
 						Ark: ark,
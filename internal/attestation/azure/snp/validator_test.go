@@ -21,6 +21,7 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/attestation"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/idkeydigest"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/simulator"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/snp"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/snp/testdata"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/vtpm"
 	"github.com/edgelesssys/constellation/v2/internal/config"
@@ -248,6 +249,7 @@ func TestTrustedKeyFromSNP(t *testing.T) {
 		getter               *stubHTTPSGetter
 		verifier             *stubAttestationVerifier
 		validator            *stubAttestationValidator
+		wantVCEKSource       snp.VCEKSource
 		wantErr              bool
 		assertion            func(*assert.Assertions, error)
 	}{
@@ -264,6 +266,7 @@ func TestTrustedKeyFromSNP(t *testing.T) {
 				&urlResponseMatcher{},
 				nil,
 			),
+			wantVCEKSource: snp.VCEKSourceTHIM,
 		},
 		"certificate fetch error": {
 			report:               defaultReport,
@@ -297,6 +300,7 @@ func TestTrustedKeyFromSNP(t *testing.T) {
 				},
 				nil,
 			),
+			wantVCEKSource: snp.VCEKSourceKDS,
 		},
 		"fetch vcek": {
 			report:               defaultReport,
@@ -313,6 +317,7 @@ func TestTrustedKeyFromSNP(t *testing.T) {
 				},
 				nil,
 			),
+			wantVCEKSource: snp.VCEKSourceKDS,
 		},
 		"fetch certchain": {
 			report:               defaultReport,
@@ -329,6 +334,7 @@ func TestTrustedKeyFromSNP(t *testing.T) {
 				},
 				nil,
 			),
+			wantVCEKSource: snp.VCEKSourceTHIM,
 		},
 		"invalid report signature": {
 			report: reportTransformer(defaultReport, func(r *spb.Report) {
@@ -456,6 +462,7 @@ func TestTrustedKeyFromSNP(t *testing.T) {
 				&urlResponseMatcher{},
 				nil,
 			),
+			wantVCEKSource: snp.VCEKSourceTHIM,
 		},
 		"launch tcb < minimum launch tcb": {
 			report: reportTransformer(defaultReport, func(r *spb.Report) {
@@ -641,10 +648,11 @@ func TestTrustedKeyFromSNP(t *testing.T) {
 				EnforcementPolicy:  tc.enforcementPolicy,
 			}
 
+			tracer := &stubTracer{Logger: logger.NewTest(t)}
 			validator := &Validator{
 				hclValidator:         &stubAttestationKey{},
 				config:               defaultCfg,
-				log:                  logger.NewTest(t),
+				log:                  tracer,
 				getter:               tc.getter,
 				attestationVerifier:  tc.verifier,
 				attestationValidator: tc.validator,
@@ -659,11 +667,25 @@ func TestTrustedKeyFromSNP(t *testing.T) {
 			} else {
 				assert.NoError(err)
 				assert.NotNil(key)
+				assert.Contains(tracer.steps, attestation.TraceStep{
+					Name:   snp.VCEKSourceTraceStep,
+					Values: []any{snp.VCEKSourceTraceKey, tc.wantVCEKSource},
+				})
 			}
 		})
 	}
 }
 
+// stubTracer records the traced validation steps.
+type stubTracer struct {
+	attestation.Logger
+	steps []attestation.TraceStep
+}
+
+func (t *stubTracer) Trace(step attestation.TraceStep) {
+	t.steps = append(t.steps, step)
+}
+
 type stubAttestationVerifier struct {
 	skipCheck bool // whether the verification function should be called
 }
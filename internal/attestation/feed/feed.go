@@ -0,0 +1,194 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+/*
+Package feed implements a client for attestation feeds of cloud providers.
+
+Cloud providers publish the expected measurements and minimum TCB versions of their
+confidential images in a JSON document, the feed. The feed is signed with a cosign key of the
+provider, and the base64 encoded signature is published next to the feed at "<feed URL>.sig".
+
+Fetched feeds are cached locally together with their signature. The signature of a cached feed
+is verified again whenever it is read, so a modified cache is detected.
+*/
+package feed
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/api/attestationconfigapi"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/measurements"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/variant"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/sigstore"
+)
+
+// DefaultCacheTTL is the duration a cached feed is used before it is fetched again.
+const DefaultCacheTTL = 24 * time.Hour
+
+// Feed is an attestation feed of a cloud provider.
+type Feed struct {
+	// Entries are the expectations for the provider's confidential images.
+	Entries []Entry `json:"entries"`
+}
+
+// Entry holds the expected values for attesting an image with an attestation variant.
+type Entry struct {
+	// Variant is the attestation variant the entry applies to, e.g. "azure-sev-snp".
+	Variant string `json:"variant"`
+	// Image is the Constellation image the entry applies to, e.g. "v2.16.0".
+	Image string `json:"image"`
+	// Measurements are the expected measurements of the image.
+	Measurements measurements.M `json:"measurements"`
+	// SEVSNPVersion is the minimum TCB version of SEV-SNP variants. It's nil for other variants.
+	SEVSNPVersion *attestationconfigapi.SEVSNPVersion `json:"sevSNPVersion,omitempty"`
+}
+
+// HTTPClient is an interface for http clients.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client fetches attestation feeds and caches them locally.
+type Client struct {
+	httpClient  HTTPClient
+	verifier    sigstore.Verifier
+	fileHandler file.Handler
+	cacheDir    string
+	cacheTTL    time.Duration
+	now         func() time.Time
+}
+
+// NewClient returns a new client that verifies feeds with the given verifier
+// and caches them in cacheDir for [DefaultCacheTTL].
+func NewClient(httpClient HTTPClient, verifier sigstore.Verifier, fileHandler file.Handler, cacheDir string) *Client {
+	return &Client{
+		httpClient:  httpClient,
+		verifier:    verifier,
+		fileHandler: fileHandler,
+		cacheDir:    cacheDir,
+		cacheTTL:    DefaultCacheTTL,
+		now:         time.Now,
+	}
+}
+
+// Fetch returns the entry of the feed at feedURL for the given attestation variant and image.
+// A cached feed is used if it isn't older than the cache TTL, otherwise the feed is fetched and cached.
+func (c *Client) Fetch(ctx context.Context, feedURL string, attestationVariant variant.Variant, image string) (Entry, error) {
+	feed, err := c.readCache(feedURL)
+	if err != nil {
+		feed, err = c.fetch(ctx, feedURL)
+		if err != nil {
+			return Entry{}, err
+		}
+	}
+
+	for _, entry := range feed.Entries {
+		if entry.Variant == attestationVariant.String() && entry.Image == image {
+			return entry, nil
+		}
+	}
+	return Entry{}, &NotFoundError{feedURL: feedURL, variant: attestationVariant.String(), image: image}
+}
+
+// fetch fetches and verifies the feed and its signature, and caches both.
+func (c *Client) fetch(ctx context.Context, feedURL string) (Feed, error) {
+	rawFeed, err := c.get(ctx, feedURL)
+	if err != nil {
+		return Feed{}, fmt.Errorf("fetching feed: %w", err)
+	}
+	signature, err := c.get(ctx, feedURL+".sig")
+	if err != nil {
+		return Feed{}, fmt.Errorf("fetching feed signature: %w", err)
+	}
+	feed, err := c.parseAndVerify(rawFeed, signature)
+	if err != nil {
+		return Feed{}, err
+	}
+
+	if err := c.writeCache(feedURL, cacheEntry{FetchedAt: c.now(), Feed: rawFeed, Signature: signature}); err != nil {
+		return Feed{}, fmt.Errorf("caching feed: %w", err)
+	}
+	return feed, nil
+}
+
+func (c *Client) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("requesting %s returned status code %d", url, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (c *Client) parseAndVerify(rawFeed, signature []byte) (Feed, error) {
+	if err := c.verifier.VerifySignature(rawFeed, signature); err != nil {
+		return Feed{}, fmt.Errorf("verifying feed signature: %w", err)
+	}
+	var feed Feed
+	if err := json.Unmarshal(rawFeed, &feed); err != nil {
+		return Feed{}, fmt.Errorf("decoding feed: %w", err)
+	}
+	return feed, nil
+}
+
+// cacheEntry is a feed cached on disk.
+type cacheEntry struct {
+	FetchedAt time.Time `json:"fetchedAt"`
+	Feed      []byte    `json:"feed"`
+	Signature []byte    `json:"signature"`
+}
+
+// readCache returns the cached feed for feedURL.
+// An error is returned if the feed isn't cached, the cache expired, or the signature of the cached feed is invalid.
+func (c *Client) readCache(feedURL string) (Feed, error) {
+	var cached cacheEntry
+	if err := c.fileHandler.ReadJSON(c.cachePath(feedURL), &cached); err != nil {
+		return Feed{}, err
+	}
+	if c.now().Sub(cached.FetchedAt) > c.cacheTTL {
+		return Feed{}, errors.New("cached feed expired")
+	}
+	return c.parseAndVerify(cached.Feed, cached.Signature)
+}
+
+func (c *Client) writeCache(feedURL string, cached cacheEntry) error {
+	return c.fileHandler.WriteJSON(c.cachePath(feedURL), cached, file.OptMkdirAll, file.OptOverwrite, file.OptAtomic)
+}
+
+// cachePath returns the path of the cache file of feedURL.
+func (c *Client) cachePath(feedURL string) string {
+	hash := sha256.Sum256([]byte(feedURL))
+	return filepath.Join(c.cacheDir, hex.EncodeToString(hash[:])+".json")
+}
+
+// NotFoundError is returned if the feed contains no entry for the requested variant and image.
+type NotFoundError struct {
+	feedURL string
+	variant string
+	image   string
+}
+
+// Error returns the error message.
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("feed %s has no entry for attestation variant %s and image %s", e.feedURL, e.variant, e.image)
+}
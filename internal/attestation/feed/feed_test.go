@@ -0,0 +1,193 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package feed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/api/attestationconfigapi"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/measurements"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/variant"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func TestFetch(t *testing.T) {
+	azureEntry := Entry{
+		Variant: variant.AzureSEVSNP{}.String(),
+		Image:   "v2.16.0",
+		Measurements: measurements.M{
+			4: measurements.WithAllBytes(0x44, measurements.Enforce, measurements.PCRMeasurementLength),
+			9: measurements.WithAllBytes(0x99, measurements.Enforce, measurements.PCRMeasurementLength),
+		},
+		SEVSNPVersion: &attestationconfigapi.SEVSNPVersion{Bootloader: 3, TEE: 0, SNP: 8, Microcode: 115},
+	}
+	gcpEntry := Entry{
+		Variant: variant.GCPSEVES{}.String(),
+		Image:   "v2.16.0",
+		Measurements: measurements.M{
+			4: measurements.WithAllBytes(0x11, measurements.Enforce, measurements.PCRMeasurementLength),
+		},
+	}
+	rawFeed, err := json.Marshal(Feed{Entries: []Entry{azureEntry, gcpEntry}})
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		variant        variant.Variant
+		image          string
+		signature      string
+		cached         *cacheEntry
+		wantEntry      Entry
+		wantRequests   int
+		wantNotFound   bool
+		wantErr        bool
+		wantCacheWrite bool
+	}{
+		"entry is fetched and cached": {
+			variant:        variant.AzureSEVSNP{},
+			image:          "v2.16.0",
+			signature:      "valid",
+			wantEntry:      azureEntry,
+			wantRequests:   2,
+			wantCacheWrite: true,
+		},
+		"entry without TCB": {
+			variant:        variant.GCPSEVES{},
+			image:          "v2.16.0",
+			signature:      "valid",
+			wantEntry:      gcpEntry,
+			wantRequests:   2,
+			wantCacheWrite: true,
+		},
+		"cached feed is used": {
+			variant:   variant.AzureSEVSNP{},
+			image:     "v2.16.0",
+			signature: "invalid",
+			cached:    &cacheEntry{FetchedAt: time.Now().Add(-time.Hour), Feed: rawFeed, Signature: []byte("valid")},
+			wantEntry: azureEntry,
+		},
+		"expired cache is refreshed": {
+			variant:        variant.AzureSEVSNP{},
+			image:          "v2.16.0",
+			signature:      "valid",
+			cached:         &cacheEntry{FetchedAt: time.Now().Add(-2 * DefaultCacheTTL), Feed: rawFeed, Signature: []byte("valid")},
+			wantEntry:      azureEntry,
+			wantRequests:   2,
+			wantCacheWrite: true,
+		},
+		"cache with invalid signature is refreshed": {
+			variant:        variant.AzureSEVSNP{},
+			image:          "v2.16.0",
+			signature:      "valid",
+			cached:         &cacheEntry{FetchedAt: time.Now(), Feed: rawFeed, Signature: []byte("tampered")},
+			wantEntry:      azureEntry,
+			wantRequests:   2,
+			wantCacheWrite: true,
+		},
+		"invalid signature": {
+			variant:      variant.AzureSEVSNP{},
+			image:        "v2.16.0",
+			signature:    "invalid",
+			wantRequests: 2,
+			wantErr:      true,
+		},
+		"no entry for image": {
+			variant:        variant.AzureSEVSNP{},
+			image:          "v2.15.0",
+			signature:      "valid",
+			wantRequests:   2,
+			wantNotFound:   true,
+			wantCacheWrite: true,
+		},
+		"no entry for variant": {
+			variant:        variant.AWSSEVSNP{},
+			image:          "v2.16.0",
+			signature:      "valid",
+			wantRequests:   2,
+			wantNotFound:   true,
+			wantCacheWrite: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				switch r.URL.Path {
+				case "/feed.json":
+					_, _ = w.Write(rawFeed)
+				case "/feed.json.sig":
+					_, _ = w.Write([]byte(tc.signature))
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+			feedURL := server.URL + "/feed.json"
+
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			client := NewClient(server.Client(), stubVerifier{validSignature: []byte("valid")}, fileHandler, "cache")
+			if tc.cached != nil {
+				require.NoError(fileHandler.WriteJSON(client.cachePath(feedURL), tc.cached, file.OptMkdirAll))
+			}
+
+			entry, err := client.Fetch(context.Background(), feedURL, tc.variant, tc.image)
+			assert.Equal(tc.wantRequests, requests)
+			if tc.wantNotFound {
+				var notFoundErr *NotFoundError
+				assert.ErrorAs(err, &notFoundErr)
+			} else if tc.wantErr {
+				assert.Error(err)
+			} else {
+				require.NoError(err)
+				assert.Equal(tc.wantEntry, entry)
+			}
+
+			var cached cacheEntry
+			cacheErr := fileHandler.ReadJSON(client.cachePath(feedURL), &cached)
+			if !tc.wantCacheWrite {
+				if tc.cached == nil {
+					assert.Error(cacheErr)
+				}
+				return
+			}
+			require.NoError(cacheErr)
+			assert.Equal(rawFeed, cached.Feed)
+			assert.Equal([]byte("valid"), cached.Signature)
+			assert.WithinDuration(time.Now(), cached.FetchedAt, time.Minute)
+		})
+	}
+}
+
+type stubVerifier struct {
+	validSignature []byte
+}
+
+func (v stubVerifier) VerifySignature(_, signature []byte) error {
+	if !bytes.Equal(signature, v.validSignature) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
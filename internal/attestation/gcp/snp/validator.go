@@ -52,7 +52,7 @@ func NewValidator(cfg *config.GCPSEVSNP, log attestation.Logger) (*Validator, er
 
 	v := &Validator{
 		cfg:             cfg,
-		reportValidator: &gcpValidator{httpsGetter: trust.DefaultHTTPSGetter(), verifier: &reportVerifierImpl{}, validator: &reportValidatorImpl{}},
+		reportValidator: &gcpValidator{httpsGetter: snp.NewKDSGetter(trust.DefaultHTTPSGetter(), (*x509.Certificate)(&cfg.AMDRootKey)), verifier: &reportVerifierImpl{}, validator: &reportValidatorImpl{}},
 		gceKeyGetter:    getGCEKey,
 		log:             log,
 	}
@@ -121,9 +121,9 @@ func (r *reportVerifierImpl) SnpAttestation(att *sevsnp.Attestation, opts *verif
 // validate the report by checking if it has a valid VCEK signature.
 // The certificate chain ARK -> ASK -> VCEK is also validated.
 // Checks that the report's userData matches the connection's userData.
-func (a *gcpValidator) validate(attestation vtpm.AttestationDocument, ask *x509.Certificate, ark *x509.Certificate, reportData [64]byte, config *config.GCPSEVSNP, log attestation.Logger) error {
+func (a *gcpValidator) validate(attDoc vtpm.AttestationDocument, ask *x509.Certificate, ark *x509.Certificate, reportData [64]byte, config *config.GCPSEVSNP, log attestation.Logger) error {
 	var info snp.InstanceInfo
-	if err := json.Unmarshal(attestation.InstanceInfo, &info); err != nil {
+	if err := json.Unmarshal(attDoc.InstanceInfo, &info); err != nil {
 		return fmt.Errorf("unmarshalling instance info: %w", err)
 	}
 
@@ -139,7 +139,9 @@ func (a *gcpValidator) validate(attestation vtpm.AttestationDocument, ask *x509.
 		return fmt.Errorf("getting verify options: %w", err)
 	}
 
-	if err := a.verifier.SnpAttestation(att, verifyOpts); err != nil {
+	err = a.verifier.SnpAttestation(att, verifyOpts)
+	attestation.Trace(log, "validate certificate chain", err)
+	if err != nil {
 		return fmt.Errorf("verifying SNP attestation: %w", err)
 	}
 
@@ -151,7 +153,6 @@ func (a *gcpValidator) validate(attestation vtpm.AttestationDocument, ask *x509.
 			SMT:   true,  // Allow Simultaneous Multi-Threading (SMT). Normally, we would want to disable SMT
 			// but GCP machines are currently facing issues if it's disabled
 		},
-		VMPL: new(int), // Checks that Virtual Machine Privilege Level (VMPL) is 0.
 		// This checks that the reported LaunchTCB version is equal or greater than the minimum specified in the config.
 		// We don't specify Options.MinimumTCB as it only restricts the allowed TCB for Current_ and Reported_TCB.
 		// Because we allow Options.ProvisionalFirmware, there is not security gained in also checking Current_ and Reported_TCB.
@@ -170,9 +171,26 @@ func (a *gcpValidator) validate(attestation vtpm.AttestationDocument, ask *x509.
 	// Checks if the attestation report matches the given constraints.
 	// Some constraints are implicitly checked by validate.SnpAttestation:
 	// - the report is not expired
-	if err := a.validator.SnpAttestation(att, validateOpts); err != nil {
+	err = a.validator.SnpAttestation(att, validateOpts)
+	attestation.Trace(log, "check TCB and guest policy", err,
+		"launchTCB", kds.DecomposeTCBVersion(kds.TCBVersion(att.Report.GetLaunchTcb())), "minimumLaunchTCB", validateOpts.MinimumLaunchTCB,
+		"policy", fmt.Sprintf("%#x", att.Report.GetPolicy()),
+	)
+	if err != nil {
 		return fmt.Errorf("validating SNP attestation: %w", err)
 	}
+	// The microcode SVN may be pinned separately from the aggregate TCB.
+	err = snp.ValidateMinMicrocodeSVN(att.Report, config.MinMicrocodeSVN)
+	attestation.Trace(log, "check microcode SVN", err, "microcodeSVN", snp.MicrocodeSVN(att.Report))
+	if err != nil {
+		return fmt.Errorf("validating microcode SVN: %w", err)
+	}
+	// The report must be issued from the configured VMPL to prevent forgeries by lower privileged guest code.
+	err = snp.ValidateVMPL(att.Report, config.VMPL)
+	attestation.Trace(log, "check VMPL", err, "vmpl", att.Report.GetVmpl())
+	if err != nil {
+		return fmt.Errorf("validating VMPL: %w", err)
+	}
 
 	return nil
 }
@@ -187,12 +205,15 @@ func getVerifyOpts(att *sevsnp.Attestation) (*verify.Options, error) {
 		return nil, fmt.Errorf("parsing ARK certificate: %w", err)
 	}
 
+	// The ASK and ARK are trusted for the product of the report, which determines the expected VCEK product name.
+	productLine := kds.ProductLine(att.Product)
+
 	verifyOpts := &verify.Options{
 		DisableCertFetching: true,
 		TrustedRoots: map[string][]*trust.AMDRootCerts{
-			"Milan": {
+			productLine: {
 				{
-					Product: "Milan",
+					Product: productLine,
 					ProductCerts: &trust.ProductCerts{
 						Ask: ask,
 						Ark: ark,
@@ -24,6 +24,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -220,6 +221,9 @@ func (m *M) EqualTo(other M) bool {
 		if !bytes.Equal(v.Expected, otherExpected) {
 			return false
 		}
+		if v.Any != other[k].Any || !slices.EqualFunc(v.OneOf, other[k].OneOf, bytes.Equal) {
+			return false
+		}
 		if v.ValidationOpt != other[k].ValidationOpt {
 			return false
 		}
@@ -230,6 +234,7 @@ func (m *M) EqualTo(other M) bool {
 // Compare compares the expected measurements to the given list of measurements.
 // It returns a list of warnings for non matching measurements for WarnOnly entries,
 // and a list of errors for non matching measurements for Enforce entries.
+// Wildcard entries match any value, and entries with a set of values match any value of the set.
 func (m M) Compare(other map[uint32][]byte) (warnings []string, errs []error) {
 	// Get list of indices in expected measurements
 	var mIndices []uint32
@@ -241,7 +246,7 @@ func (m M) Compare(other map[uint32][]byte) (warnings []string, errs []error) {
 	})
 
 	for _, idx := range mIndices {
-		if !bytes.Equal(m[idx].Expected, other[idx]) {
+		if !m[idx].Matches(other[idx]) {
 			msg := fmt.Sprintf("untrusted measurement value %x at index %d", other[idx], idx)
 			if len(other[idx]) == 0 {
 				msg = fmt.Sprintf("missing measurement value for index %d", idx)
@@ -277,10 +282,8 @@ func (m *M) SetEnforced(enforced []uint32) error {
 
 	// set all measurements to warn only
 	for idx, measurement := range *m {
-		newM[idx] = Measurement{
-			Expected:      measurement.Expected,
-			ValidationOpt: WarnOnly,
-		}
+		measurement.ValidationOpt = WarnOnly
+		newM[idx] = measurement
 	}
 
 	// set enforced measurements from list
@@ -316,11 +319,21 @@ func (m *M) UnmarshalJSON(b []byte) error {
 
 // UnmarshalYAML unmarshals measurements from yaml.
 // This function enforces all measurements to be of equal length.
+// Measurement indices may be given as integers or as quoted strings,
+// since configs in JSON syntax always use string keys.
 func (m *M) UnmarshalYAML(unmarshal func(any) error) error {
-	newM := make(map[uint32]Measurement)
-	if err := unmarshal(&newM); err != nil {
+	rawM := make(map[string]Measurement)
+	if err := unmarshal(&rawM); err != nil {
 		return err
 	}
+	newM := make(map[uint32]Measurement, len(rawM))
+	for rawIdx, measurement := range rawM {
+		idx, err := strconv.ParseUint(rawIdx, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid measurement index %q: %w", rawIdx, err)
+		}
+		newM[uint32(idx)] = measurement
+	}
 
 	// check if all measurements are of equal length
 	if err := checkLength(newM); err != nil {
@@ -335,7 +348,7 @@ func (m *M) UnmarshalYAML(unmarshal func(any) error) error {
 func (m M) String() string {
 	var returnString string
 	for i, measurement := range m {
-		returnString = strings.Join([]string{returnString, fmt.Sprintf("%d: 0x%s", i, hex.EncodeToString(measurement.Expected))}, ",")
+		returnString = strings.Join([]string{returnString, fmt.Sprintf("%d: %s", i, measurement.valueString())}, ",")
 	}
 	return returnString
 }
@@ -384,14 +397,57 @@ func (m *M) fromImageMeasurementsV2(
 }
 
 // Measurement wraps expected PCR value and whether it is enforced.
+// A measurement either pins a single value (Expected), accepts one of a set of values (OneOf),
+// or accepts any value (Any).
 type Measurement struct {
 	// Expected measurement value.
 	// 32 bytes for vTPM attestation, 48 for TDX.
+	// Empty if the measurement accepts one of a set of values or any value.
 	Expected []byte `json:"expected" yaml:"expected"`
+	// OneOf are the accepted values of a measurement that may take one of several values.
+	OneOf [][]byte `json:"oneOf,omitempty" yaml:"oneOf,omitempty"`
+	// Any accepts any value of the measurement, i.e., the measurement isn't compared.
+	Any bool `json:"any,omitempty" yaml:"any,omitempty"`
 	// ValidationOpt indicates how measurement mismatches should be handled.
 	ValidationOpt MeasurementValidationOption `json:"warnOnly" yaml:"warnOnly"`
 }
 
+// Matches returns true if actual is an accepted value of the measurement.
+func (m Measurement) Matches(actual []byte) bool {
+	switch {
+	case m.Any:
+		return true
+	case len(m.OneOf) > 0:
+		return slices.ContainsFunc(m.OneOf, func(value []byte) bool { return bytes.Equal(value, actual) })
+	default:
+		return bytes.Equal(m.Expected, actual)
+	}
+}
+
+// Len returns the length of the accepted values of the measurement, or 0 if any value is accepted.
+func (m Measurement) Len() int {
+	if len(m.OneOf) > 0 {
+		return len(m.OneOf[0])
+	}
+	return len(m.Expected)
+}
+
+// valueString returns a human readable representation of the accepted values.
+func (m Measurement) valueString() string {
+	switch {
+	case m.Any:
+		return "*"
+	case len(m.OneOf) > 0:
+		values := make([]string, 0, len(m.OneOf))
+		for _, value := range m.OneOf {
+			values = append(values, "0x"+hex.EncodeToString(value))
+		}
+		return "{" + strings.Join(values, "|") + "}"
+	default:
+		return "0x" + hex.EncodeToString(m.Expected)
+	}
+}
+
 // MeasurementValidationOption indicates how measurement mismatches should be handled.
 type MeasurementValidationOption bool
 
@@ -423,12 +479,9 @@ func (m *Measurement) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
-// MarshalJSON writes out a Measurement with Expected encoded as a hex string.
+// MarshalJSON writes out a Measurement with the accepted values encoded as hex strings.
 func (m Measurement) MarshalJSON() ([]byte, error) {
-	return json.Marshal(encodedMeasurement{
-		Expected: hex.EncodeToString(m.Expected[:]),
-		WarnOnly: m.ValidationOpt,
-	})
+	return json.Marshal(m.encode())
 }
 
 // UnmarshalYAML reads a Measurement either as yaml object,
@@ -452,31 +505,70 @@ func (m *Measurement) UnmarshalYAML(unmarshal func(any) error) error {
 	return nil
 }
 
-// MarshalYAML writes out a Measurement with Expected encoded as a hex string.
+// MarshalYAML writes out a Measurement with the accepted values encoded as hex strings.
 func (m Measurement) MarshalYAML() (any, error) {
-	return encodedMeasurement{
-		Expected: hex.EncodeToString(m.Expected[:]),
+	return m.encode(), nil
+}
+
+func (m Measurement) encode() encodedMeasurement {
+	eM := encodedMeasurement{
+		Expected: hex.EncodeToString(m.Expected),
+		Any:      m.Any,
 		WarnOnly: m.ValidationOpt,
-	}, nil
+	}
+	for _, value := range m.OneOf {
+		eM.OneOf = append(eM.OneOf, hex.EncodeToString(value))
+	}
+	return eM
 }
 
-// unmarshal parses a hex or base64 encoded Measurement.
+// unmarshal parses a hex encoded Measurement.
+// Exactly one of a pinned value (expected), a set of values (oneOf), or a wildcard (any) must be given.
 func (m *Measurement) unmarshal(eM encodedMeasurement) error {
-	expected, err := hex.DecodeString(eM.Expected)
-	if err != nil {
-		return fmt.Errorf("decoding measurement: %w", err)
+	var given int
+	for _, isSet := range []bool{eM.Expected != "", len(eM.OneOf) > 0, eM.Any} {
+		if isSet {
+			given++
+		}
 	}
-
-	if len(expected) != 32 && len(expected) != 48 {
-		return fmt.Errorf("invalid measurement: invalid length: %d", len(expected))
+	if given != 1 {
+		return errors.New("invalid measurement: exactly one of expected, oneOf, or any must be set")
 	}
 
-	m.Expected = expected
-	m.ValidationOpt = eM.WarnOnly
+	*m = Measurement{Any: eM.Any, ValidationOpt: eM.WarnOnly}
+	if eM.Expected != "" {
+		expected, err := decodeMeasurementValue(eM.Expected)
+		if err != nil {
+			return err
+		}
+		m.Expected = expected
+	}
+	for _, rawValue := range eM.OneOf {
+		value, err := decodeMeasurementValue(rawValue)
+		if err != nil {
+			return fmt.Errorf("oneOf: %w", err)
+		}
+		if len(m.OneOf) > 0 && len(value) != len(m.OneOf[0]) {
+			return fmt.Errorf("invalid measurement: oneOf: inconsistent length: expected %d, got %d", len(m.OneOf[0]), len(value))
+		}
+		m.OneOf = append(m.OneOf, value)
+	}
 
 	return nil
 }
 
+// decodeMeasurementValue decodes a hex encoded measurement value and checks its length.
+func decodeMeasurementValue(rawValue string) ([]byte, error) {
+	value, err := hex.DecodeString(rawValue)
+	if err != nil {
+		return nil, fmt.Errorf("decoding measurement: %w", err)
+	}
+	if len(value) != 32 && len(value) != 48 {
+		return nil, fmt.Errorf("invalid measurement: invalid length: %d", len(value))
+	}
+	return value, nil
+}
+
 // WithAllBytes returns a measurement value where all bytes are set to b. Takes a dynamic length as input.
 // Expected are either 32 bytes (PCRMeasurementLength) or 48 bytes (TDXMeasurementLength).
 // Over inputs are possible in this function, but potentially rejected elsewhere.
@@ -533,20 +625,26 @@ func DefaultsFor(provider cloudprovider.Provider, attestationVariant variant.Var
 	}
 }
 
+// checkLength checks that all measurements are of equal length. Wildcards have no length and are skipped.
 func checkLength(m map[uint32]Measurement) error {
 	var length int
 	for idx, measurement := range m {
+		if measurement.Any {
+			continue
+		}
 		if length == 0 {
-			length = len(measurement.Expected)
-		} else if len(measurement.Expected) != length {
-			return fmt.Errorf("inconsistent measurement length: index %d: expected %d, got %d", idx, length, len(measurement.Expected))
+			length = measurement.Len()
+		} else if measurement.Len() != length {
+			return fmt.Errorf("inconsistent measurement length: index %d: expected %d, got %d", idx, length, measurement.Len())
 		}
 	}
 	return nil
 }
 
 type encodedMeasurement struct {
-	Expected string                      `json:"expected" yaml:"expected"`
+	Expected string                      `json:"expected,omitempty" yaml:"expected,omitempty"`
+	OneOf    []string                    `json:"oneOf,omitempty" yaml:"oneOf,omitempty"`
+	Any      bool                        `json:"any,omitempty" yaml:"any,omitempty"`
 	WarnOnly MeasurementValidationOption `json:"warnOnly" yaml:"warnOnly"`
 }
 
@@ -48,6 +48,18 @@ func TestMarshal(t *testing.T) {
 			wantYAML: "expected: \"0102030400000000000000000000000000000000000000000000000000000000\"\nwarnOnly: true",
 			wantJSON: `{"expected":"0102030400000000000000000000000000000000000000000000000000000000","warnOnly":true}`,
 		},
+		"wildcard": {
+			m:        Measurement{Any: true},
+			wantYAML: "any: true\nwarnOnly: false",
+			wantJSON: `{"any":true,"warnOnly":false}`,
+		},
+		"set": {
+			m: Measurement{
+				OneOf: [][]byte{bytes.Repeat([]byte{0x00}, PCRMeasurementLength), bytes.Repeat([]byte{0x01}, PCRMeasurementLength)},
+			},
+			wantYAML: "oneOf:\n- \"0000000000000000000000000000000000000000000000000000000000000000\"\n- \"0101010101010101010101010101010101010101010101010101010101010101\"\nwarnOnly: false",
+			wantJSON: `{"oneOf":["0000000000000000000000000000000000000000000000000000000000000000","0101010101010101010101010101010101010101010101010101010101010101"],"warnOnly":false}`,
+		},
 	}
 
 	for name, tc := range testCases {
@@ -117,6 +129,23 @@ func TestUnmarshal(t *testing.T) {
 				},
 			},
 		},
+		"quoted indices": {
+			inputYAML: "\"2\":\n expected: \"0000000000000000000000000000000000000000000000000000000000000000\"\n\"3\":\n expected: \"0102030400000000000000000000000000000000000000000000000000000000\"",
+			inputJSON: `{"2":{"expected":"0000000000000000000000000000000000000000000000000000000000000000"},"3":{"expected":"0102030400000000000000000000000000000000000000000000000000000000"}}`,
+			wantMeasurements: M{
+				2: {
+					Expected: []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+				},
+				3: {
+					Expected: []byte{1, 2, 3, 4, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+				},
+			},
+		},
+		"invalid index": {
+			inputYAML: "foo:\n expected: \"0000000000000000000000000000000000000000000000000000000000000000\"",
+			inputJSON: `{"foo":{"expected":"0000000000000000000000000000000000000000000000000000000000000000"}}`,
+			wantErr:   true,
+		},
 		"invalid base64": {
 			inputYAML: "2:\n expected: \"This is not base64\"\n3:\n expected: \"AQIDBAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=\"",
 			inputJSON: `{"2":{"expected":"This is not base64"},"3":{"expected":"AQIDBAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}}`,
@@ -137,6 +166,40 @@ func TestUnmarshal(t *testing.T) {
 			inputJSON: `{"2":{"expected":"AA=="},"3":{"expected":"AQIDBAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=="}}`,
 			wantErr:   true,
 		},
+		"wildcard and set": {
+			inputYAML: "2:\n any: true\n3:\n oneOf:\n - \"0000000000000000000000000000000000000000000000000000000000000000\"\n - \"0101010101010101010101010101010101010101010101010101010101010101\"\n4:\n expected: \"0000000000000000000000000000000000000000000000000000000000000000\"",
+			inputJSON: `{"2":{"any":true},"3":{"oneOf":["0000000000000000000000000000000000000000000000000000000000000000","0101010101010101010101010101010101010101010101010101010101010101"]},"4":{"expected":"0000000000000000000000000000000000000000000000000000000000000000"}}`,
+			wantMeasurements: M{
+				2: {Any: true},
+				3: {OneOf: [][]byte{bytes.Repeat([]byte{0x00}, PCRMeasurementLength), bytes.Repeat([]byte{0x01}, PCRMeasurementLength)}},
+				4: {Expected: bytes.Repeat([]byte{0x00}, PCRMeasurementLength)},
+			},
+		},
+		"wildcard with expected value": {
+			inputYAML: "2:\n any: true\n expected: \"0000000000000000000000000000000000000000000000000000000000000000\"",
+			inputJSON: `{"2":{"any":true,"expected":"0000000000000000000000000000000000000000000000000000000000000000"}}`,
+			wantErr:   true,
+		},
+		"set with expected value": {
+			inputYAML: "2:\n oneOf:\n - \"0000000000000000000000000000000000000000000000000000000000000000\"\n expected: \"0000000000000000000000000000000000000000000000000000000000000000\"",
+			inputJSON: `{"2":{"oneOf":["0000000000000000000000000000000000000000000000000000000000000000"],"expected":"0000000000000000000000000000000000000000000000000000000000000000"}}`,
+			wantErr:   true,
+		},
+		"no value": {
+			inputYAML: "2:\n warnOnly: true",
+			inputJSON: `{"2":{"warnOnly":true}}`,
+			wantErr:   true,
+		},
+		"set with mixed length": {
+			inputYAML: "2:\n oneOf:\n - \"0000000000000000000000000000000000000000000000000000000000000000\"\n - \"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000\"",
+			inputJSON: `{"2":{"oneOf":["0000000000000000000000000000000000000000000000000000000000000000","000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000"]}}`,
+			wantErr:   true,
+		},
+		"set length differs from other measurements": {
+			inputYAML: "2:\n oneOf:\n - \"000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000\"\n3:\n expected: \"0000000000000000000000000000000000000000000000000000000000000000\"",
+			inputJSON: `{"2":{"oneOf":["000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000"]},"3":{"expected":"0000000000000000000000000000000000000000000000000000000000000000"}}`,
+			wantErr:   true,
+		},
 		"invalid format": {
 			inputYAML: "1:\n expected:\n  someKey: 12\n  anotherKey: 34",
 			inputJSON: `{"1":{"expected":{"someKey":12,"anotherKey":34}}}`,
@@ -215,6 +278,21 @@ func TestEncodeM(t *testing.T) {
 11:
     expected: "0000000000000000000000000000000000000000000000000000000000000000"
     warnOnly: false
+`,
+		},
+		"wildcard and set": {
+			m: M{
+				1: {Any: true, ValidationOpt: WarnOnly},
+				2: {OneOf: [][]byte{bytes.Repeat([]byte{0x00}, PCRMeasurementLength), bytes.Repeat([]byte{0x01}, PCRMeasurementLength)}},
+			},
+			want: `1:
+    any: true
+    warnOnly: true
+2:
+    oneOf:
+        - "0000000000000000000000000000000000000000000000000000000000000000"
+        - "0101010101010101010101010101010101010101010101010101010101010101"
+    warnOnly: false
 `,
 		},
 	}
@@ -1018,6 +1096,44 @@ func TestMeasurementsCompare(t *testing.T) {
 			wantErrs:     1,
 			wantWarnings: 0,
 		},
+		"wildcard matches any value": {
+			expected: M{
+				0: WithAllBytes(0x00, Enforce, PCRMeasurementLength),
+				1: {Any: true, ValidationOpt: Enforce},
+				2: {Any: true, ValidationOpt: Enforce},
+			},
+			actual: map[uint32][]byte{
+				0: bytes.Repeat([]byte{0x00}, PCRMeasurementLength),
+				1: bytes.Repeat([]byte{0xFF}, PCRMeasurementLength),
+			},
+			wantErrs:     0,
+			wantWarnings: 0,
+		},
+		"set matches any of its values": {
+			expected: M{
+				0: {OneOf: [][]byte{bytes.Repeat([]byte{0x00}, PCRMeasurementLength), bytes.Repeat([]byte{0x01}, PCRMeasurementLength)}, ValidationOpt: Enforce},
+				1: {OneOf: [][]byte{bytes.Repeat([]byte{0x00}, PCRMeasurementLength), bytes.Repeat([]byte{0x01}, PCRMeasurementLength)}, ValidationOpt: Enforce},
+			},
+			actual: map[uint32][]byte{
+				0: bytes.Repeat([]byte{0x00}, PCRMeasurementLength),
+				1: bytes.Repeat([]byte{0x01}, PCRMeasurementLength),
+			},
+			wantErrs:     0,
+			wantWarnings: 0,
+		},
+		"values outside of a set cause errors and warnings": {
+			expected: M{
+				0: {OneOf: [][]byte{bytes.Repeat([]byte{0x00}, PCRMeasurementLength), bytes.Repeat([]byte{0x01}, PCRMeasurementLength)}, ValidationOpt: Enforce},
+				1: {OneOf: [][]byte{bytes.Repeat([]byte{0x00}, PCRMeasurementLength)}, ValidationOpt: WarnOnly},
+				2: {OneOf: [][]byte{bytes.Repeat([]byte{0x00}, PCRMeasurementLength)}, ValidationOpt: Enforce},
+			},
+			actual: map[uint32][]byte{
+				0: bytes.Repeat([]byte{0xFF}, PCRMeasurementLength),
+				1: bytes.Repeat([]byte{0xFF}, PCRMeasurementLength),
+			},
+			wantErrs:     2,
+			wantWarnings: 1,
+		},
 		"missing measurements cause warnings": {
 			expected: M{
 				0: WithAllBytes(0x00, Enforce, PCRMeasurementLength),
@@ -111,6 +111,8 @@ func ApplyOverrides(in M, csp cloudprovider.Provider, attestationVariant string)
 				m = Measurement{}
 			}
 			m.Expected = i.Value
+			m.OneOf = nil
+			m.Any = false
 			out[i.Index] = m
 		}
 		for _, i := range override.MustEnforce {
@@ -0,0 +1,139 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package snp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/google/go-sev-guest/kds"
+	"github.com/google/go-sev-guest/verify/trust"
+)
+
+const (
+	// kdsHost is the host of the AMD Key Distribution Service (KDS).
+	kdsHost = "kdsintf.amd.com"
+	// kdsCertChainFile is the last element of the KDS URLs of the ASK/ASVK and ARK certificate chains.
+	kdsCertChainFile = "cert_chain"
+)
+
+// arkFingerprints are the SHA-256 fingerprints of the DER-encoded AMD Root Keys (ARK), keyed by product line.
+// ARKs don't expire, so they can be pinned in the binary.
+// Only the ARKs embedded in go-sev-guest are pinned. The ARKs of other product lines, e.g. Genoa and Turin,
+// are trusted through the ARK set in the attestation config, which is passed to [NewKDSGetter].
+var arkFingerprints = map[string]string{
+	"Milan": "69d063b45344d26a2e94e1f4210de49ef555308287d4c174445c95639a540bcd",
+}
+
+// KDSGetter is a [trust.HTTPSGetter] for the AMD KDS.
+// It verifies the ARK of every product certificate chain it retrieves against a pinned fingerprint,
+// and caches the verified chains, so they're only retrieved once per product line and report signer.
+// All other requests are passed to the underlying getter unchanged.
+type KDSGetter struct {
+	getter trust.HTTPSGetter
+	// pins are the SHA-256 fingerprints of the trusted ARKs, keyed by product line.
+	pins map[string][sha256.Size]byte
+
+	mux    sync.Mutex
+	chains map[string][]byte
+}
+
+// NewKDSGetter returns a KDSGetter that retrieves the certificate chains using getter.
+// The ARKs are pinned to built-in fingerprints. trustedARKs pins the ARK of further product lines,
+// e.g. an ARK set in the attestation config for a CPU generation released after the CLI.
+// An ARK pinned this way replaces the built-in pin of its product line.
+func NewKDSGetter(getter trust.HTTPSGetter, trustedARKs ...*x509.Certificate) *KDSGetter {
+	pins := make(map[string][sha256.Size]byte, len(arkFingerprints)+len(trustedARKs))
+	for productLine, fingerprint := range arkFingerprints {
+		var pin [sha256.Size]byte
+		if _, err := hex.Decode(pin[:], []byte(fingerprint)); err != nil {
+			panic(fmt.Sprintf("invalid ARK fingerprint for product %s: %v", productLine, err))
+		}
+		pins[productLine] = pin
+	}
+	for _, ark := range trustedARKs {
+		if ark == nil || len(ark.Raw) == 0 {
+			continue
+		}
+		if productLine, ok := strings.CutPrefix(ark.Subject.CommonName, "ARK-"); ok {
+			pins[productLine] = sha256.Sum256(ark.Raw)
+		}
+	}
+	return &KDSGetter{getter: getter, pins: pins, chains: make(map[string][]byte)}
+}
+
+// Get retrieves the content of the given URL.
+// Product certificate chains are returned from the cache, or retrieved and verified if they aren't cached yet.
+func (g *KDSGetter) Get(rawURL string) ([]byte, error) {
+	productLine, ok := certChainProductLine(rawURL)
+	if !ok {
+		return g.getter.Get(rawURL)
+	}
+
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	if chain, ok := g.chains[rawURL]; ok {
+		return bytes.Clone(chain), nil
+	}
+
+	chain, err := g.getter.Get(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.verifyCertChain(productLine, chain); err != nil {
+		return nil, fmt.Errorf("verifying certificate chain of product %s from AMD KDS: %w", productLine, err)
+	}
+	g.chains[rawURL] = bytes.Clone(chain)
+	return chain, nil
+}
+
+// verifyCertChain checks that the ARK of a PEM-encoded ASK/ASVK and ARK certificate chain is the pinned ARK of the product line,
+// and that it signed the ASK/ASVK.
+func (g *KDSGetter) verifyCertChain(productLine string, chain []byte) error {
+	pin, ok := g.pins[productLine]
+	if !ok {
+		return fmt.Errorf("no trusted ARK is pinned for product %s: set the ARK of the product in the attestation config", productLine)
+	}
+
+	askDER, arkDER, err := kds.ParseProductCertChain(chain)
+	if err != nil {
+		return fmt.Errorf("parsing certificate chain: %w", err)
+	}
+	if fingerprint := sha256.Sum256(arkDER); fingerprint != pin {
+		return fmt.Errorf("ARK has fingerprint %x, but the pinned ARK of product %s has fingerprint %x", fingerprint, productLine, pin)
+	}
+
+	ark, err := x509.ParseCertificate(arkDER)
+	if err != nil {
+		return fmt.Errorf("parsing ARK certificate: %w", err)
+	}
+	ask, err := x509.ParseCertificate(askDER)
+	if err != nil {
+		return fmt.Errorf("parsing ASK certificate: %w", err)
+	}
+	if err := ask.CheckSignatureFrom(ark); err != nil {
+		return fmt.Errorf("ASK isn't signed by the ARK: %w", err)
+	}
+	return nil
+}
+
+// certChainProductLine returns the product line of an AMD KDS URL of a product certificate chain,
+// e.g. "Milan" for "https://kdsintf.amd.com/vcek/v1/Milan/cert_chain".
+func certChainProductLine(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host != kdsHost || path.Base(u.Path) != kdsCertChainFile {
+		return "", false
+	}
+	return path.Base(path.Dir(u.Path)), true
+}
@@ -0,0 +1,137 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package snp
+
+import (
+	"crypto/x509"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation/snp/testdata"
+	"github.com/google/go-sev-guest/abi"
+	"github.com/google/go-sev-guest/kds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKDSGetter(t *testing.T) {
+	genoa := mustTestOnlyCertChain(t, "Genoa-B1")
+	tampered := mustTestOnlyCertChain(t, "Genoa-B1")
+	genoaChainURL := kds.ProductCertChainURL(abi.VcekReportSigner, "Genoa")
+	milanChainURL := kds.ProductCertChainURL(abi.VcekReportSigner, "Milan")
+
+	testCases := map[string]struct {
+		trustedARKs []*x509.Certificate
+		url         string
+		responses   map[string][]byte
+		wantErr     bool
+	}{
+		"pinned ARK": {
+			trustedARKs: []*x509.Certificate{genoa.Ark},
+			url:         genoaChainURL,
+			responses:   map[string][]byte{genoaChainURL: append(certToPEM(genoa.Ask), certToPEM(genoa.Ark)...)},
+		},
+		"built-in pin": {
+			url:       milanChainURL,
+			responses: map[string][]byte{milanChainURL: testdata.CertChain},
+		},
+		"tampered ARK": {
+			trustedARKs: []*x509.Certificate{genoa.Ark},
+			url:         genoaChainURL,
+			responses:   map[string][]byte{genoaChainURL: append(certToPEM(tampered.Ask), certToPEM(tampered.Ark)...)},
+			wantErr:     true,
+		},
+		"ASK not signed by ARK": {
+			trustedARKs: []*x509.Certificate{genoa.Ark},
+			url:         genoaChainURL,
+			responses:   map[string][]byte{genoaChainURL: append(certToPEM(tampered.Ask), certToPEM(genoa.Ark)...)},
+			wantErr:     true,
+		},
+		"no pinned ARK for product": {
+			url:       genoaChainURL,
+			responses: map[string][]byte{genoaChainURL: append(certToPEM(genoa.Ask), certToPEM(genoa.Ark)...)},
+			wantErr:   true,
+		},
+		"configured ARK replaces built-in pin": {
+			trustedARKs: []*x509.Certificate{mustTestOnlyCertChain(t, "Milan-B1").Ark},
+			url:         milanChainURL,
+			responses:   map[string][]byte{milanChainURL: testdata.CertChain},
+			wantErr:     true,
+		},
+		"other requests aren't verified": {
+			url:       "https://kdsintf.amd.com/vcek/v1/Genoa/0102?blSPL=1",
+			responses: map[string][]byte{"https://kdsintf.amd.com/vcek/v1/Genoa/0102?blSPL=1": []byte("vcek")},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			kdsServer := &stubKDS{responses: tc.responses}
+			getter := NewKDSGetter(kdsServer, tc.trustedARKs...)
+
+			got, err := getter.Get(tc.url)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tc.responses[tc.url], got)
+		})
+	}
+}
+
+func TestKDSGetterCache(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	genoa := mustTestOnlyCertChain(t, "Genoa-B1")
+	vcekChainURL := kds.ProductCertChainURL(abi.VcekReportSigner, "Genoa")
+	vlekChainURL := kds.ProductCertChainURL(abi.VlekReportSigner, "Genoa")
+	chain := append(certToPEM(genoa.Ask), certToPEM(genoa.Ark)...)
+	kdsServer := &stubKDS{responses: map[string][]byte{vcekChainURL: chain, vlekChainURL: chain}}
+	getter := NewKDSGetter(kdsServer, genoa.Ark)
+
+	for i := 0; i < 3; i++ {
+		got, err := getter.Get(vcekChainURL)
+		require.NoError(err)
+		assert.Equal(chain, got)
+	}
+	assert.Equal(1, kdsServer.requests[vcekChainURL])
+
+	// Chains are cached per report signer.
+	_, err := getter.Get(vlekChainURL)
+	require.NoError(err)
+	assert.Equal(1, kdsServer.requests[vlekChainURL])
+
+	// Rejected chains aren't cached.
+	unpinnedGetter := NewKDSGetter(kdsServer)
+	_, err = unpinnedGetter.Get(vcekChainURL)
+	assert.Error(err)
+	_, err = unpinnedGetter.Get(vcekChainURL)
+	assert.Error(err)
+	assert.Equal(3, kdsServer.requests[vcekChainURL])
+}
+
+// stubKDS serves fixed responses and counts the requests per URL.
+type stubKDS struct {
+	responses map[string][]byte
+	requests  map[string]int
+}
+
+func (s *stubKDS) Get(url string) ([]byte, error) {
+	if s.requests == nil {
+		s.requests = make(map[string]int)
+	}
+	s.requests[url]++
+	response, ok := s.responses[url]
+	if !ok {
+		return nil, assert.AnError
+	}
+	return response, nil
+}
@@ -14,6 +14,7 @@ import (
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/edgelesssys/constellation/v2/internal/attestation"
 	"github.com/google/go-sev-guest/abi"
@@ -26,13 +27,55 @@ import (
 
 var errNoPemBlocks = errors.New("no PEM blocks found")
 
-// Product returns the SEV product info currently supported by Constellation's SNP attestation.
+// Product returns the default SEV product info of Constellation's SNP attestation.
+// It is used if the product can't be derived from the certificates of an attestation report.
 func Product() *spb.SevProduct {
 	// sevProduct is the product info of the SEV platform as reported through CPUID[EAX=1].
 	// It may become necessary in the future to differentiate among CSP vendors.
 	return &spb.SevProduct{Name: spb.SevProduct_SEV_PRODUCT_MILAN, Stepping: 0} // Milan-B0
 }
 
+// productOfLine returns the SEV product info of a product line supported by Constellation's SNP attestation.
+func productOfLine(productLine string) (*spb.SevProduct, bool) {
+	switch productLine {
+	case "Milan":
+		return Product(), true
+	case "Genoa":
+		return &spb.SevProduct{Name: spb.SevProduct_SEV_PRODUCT_GENOA}, true
+	default:
+		return nil, false
+	}
+}
+
+// certRole is the role of an AMD certificate in the certificate chain of an attestation report.
+type certRole string
+
+const (
+	certRoleASK certRole = "ASK"
+	certRoleARK certRole = "ARK"
+)
+
+// certProductLine returns the product line and role of an AMD ASK/ASVK or ARK certificate.
+// An empty product line is returned for certificates of unsupported products or other issuers.
+func certProductLine(cert *x509.Certificate) (string, certRole) {
+	// https://www.amd.com/content/dam/amd/en/documents/epyc-technical-docs/specifications/57230.pdf
+	// Table 6 and 7
+	var productLine string
+	var role certRole
+	switch cn := cert.Subject.CommonName; {
+	case strings.HasPrefix(cn, "ARK-"):
+		productLine, role = strings.TrimPrefix(cn, "ARK-"), certRoleARK
+	case strings.HasPrefix(cn, "SEV-VLEK-"):
+		productLine, role = strings.TrimPrefix(cn, "SEV-VLEK-"), certRoleASK
+	case strings.HasPrefix(cn, "SEV-"):
+		productLine, role = strings.TrimPrefix(cn, "SEV-"), certRoleASK
+	}
+	if _, ok := productOfLine(productLine); !ok {
+		return "", ""
+	}
+	return productLine, role
+}
+
 // GetExtendedReport retrieves the extended SNP report from the CVM.
 func GetExtendedReport(reportData [64]byte) (report, certChain []byte, err error) {
 	qp, err := client.GetLeveledQuoteProvider()
@@ -75,10 +118,31 @@ type AzureInstanceInfo struct {
 	MAAToken string
 }
 
+// VCEKSource is a source of the VCEK certificate used to verify an attestation report.
+type VCEKSource string
+
+const (
+	// VCEKSourceTHIM is the VCEK certificate provided by the issuer, e.g. retrieved from Azure THIM.
+	VCEKSourceTHIM VCEKSource = "THIM"
+	// VCEKSourceKDS is the VCEK certificate retrieved from AMD KDS.
+	VCEKSourceKDS VCEKSource = "KDS"
+)
+
+const (
+	// VCEKSourceTraceStep is the name of the trace step recording the source of the VCEK certificate
+	// an attestation report was verified with.
+	VCEKSourceTraceStep = "select VCEK certificate"
+	// VCEKSourceTraceKey is the key of the [VCEKSource] in the values of the [VCEKSourceTraceStep].
+	VCEKSourceTraceKey = "source"
+)
+
 // addReportSigner parses the reportSigner certificate (VCEK/VLEK) from a and adds it to the attestation proto att.
 // If reportSigner is empty and a VLEK is required, an error is returned.
-// If reportSigner is empty and a VCEK is required, the VCEK is retrieved from AMD KDS.
-func (a *InstanceInfo) addReportSigner(att *spb.Attestation, report *spb.Report, productName string, getter trust.HTTPSGetter, logger attestation.Logger) (abi.ReportSigner, error) {
+// If a VCEK is required, it is taken from vcekSource. If vcekSource is empty,
+// the reportSigner is used if present and the VCEK is retrieved from AMD KDS otherwise.
+func (a *InstanceInfo) addReportSigner(
+	att *spb.Attestation, report *spb.Report, productName string, vcekSource VCEKSource, getter trust.HTTPSGetter, logger attestation.Logger,
+) (abi.ReportSigner, error) {
 	// If the VCEK certificate is present, parse it and format it.
 	reportSigner, err := a.ParseReportSigner()
 	if err != nil {
@@ -100,9 +164,13 @@ func (a *InstanceInfo) addReportSigner(att *spb.Attestation, report *spb.Report,
 	case abi.VcekReportSigner:
 		var vcekData []byte
 
-		// If no VCEK is present, fetch it from AMD.
-		if reportSigner == nil {
-			logger.Info("VCEK certificate not present, falling back to retrieving it from AMD KDS")
+		if vcekSource == VCEKSourceTHIM && reportSigner == nil {
+			return abi.NoneReportSigner, errors.New("VCEK certificate from THIM required but not present")
+		}
+
+		// If no VCEK is present, or the VCEK was explicitly requested from AMD KDS, fetch it from AMD.
+		if reportSigner == nil || vcekSource == VCEKSourceKDS {
+			logger.Info("Retrieving VCEK certificate from AMD KDS")
 			vcekURL := kds.VCEKCertURL(productName, report.GetChipId(), kds.TCBVersion(report.GetReportedTcb()))
 			vcekData, err = getter.Get(vcekURL)
 			if err != nil {
@@ -126,25 +194,113 @@ func (a *InstanceInfo) addReportSigner(att *spb.Attestation, report *spb.Report,
 func (a *InstanceInfo) AttestationWithCerts(getter trust.HTTPSGetter,
 	fallbackCerts CertificateChain, logger attestation.Logger,
 ) (*spb.Attestation, error) {
+	return a.attestationWithCerts(getter, fallbackCerts, "", logger)
+}
+
+// VerifyWithVCEKFallback returns the attestation report with the VCEK certificate from the primary source,
+// if it is verified successfully by verify. Otherwise, the VCEK certificate from the other source is tried.
+// This allows verifying reports in environments where only one of AMD KDS and Azure THIM is reachable or up to date.
+// The source of the VCEK certificate that was verified successfully is returned alongside the attestation.
+// The remaining certificates are retrieved as described in [InstanceInfo.AttestationWithCerts].
+func (a *InstanceInfo) VerifyWithVCEKFallback(primary VCEKSource, getter trust.HTTPSGetter,
+	fallbackCerts CertificateChain, verify func(*spb.Attestation) error, logger attestation.Logger,
+) (*spb.Attestation, VCEKSource, error) {
+	var secondary VCEKSource
+	switch primary {
+	case VCEKSourceTHIM:
+		secondary = VCEKSourceKDS
+	case VCEKSourceKDS:
+		secondary = VCEKSourceTHIM
+	default:
+		return nil, "", fmt.Errorf("unknown VCEK source %q", primary)
+	}
+
+	var errs []error
+	for _, source := range []VCEKSource{primary, secondary} {
+		att, err := a.attestationWithCerts(getter, fallbackCerts, source, logger)
+		if err == nil {
+			err = verify(att)
+		}
+		if err == nil {
+			return att, source, nil
+		}
+		logger.Warn(fmt.Sprintf("Verifying attestation report with VCEK certificate from %s failed: %v", source, err))
+		errs = append(errs, fmt.Errorf("using VCEK certificate from %s: %w", source, err))
+	}
+	return nil, "", errors.Join(errs...)
+}
+
+func (a *InstanceInfo) attestationWithCerts(getter trust.HTTPSGetter,
+	fallbackCerts CertificateChain, vcekSource VCEKSource, logger attestation.Logger,
+) (*spb.Attestation, error) {
+	att, signerInfo, err := a.parseReport()
+	attestation.Trace(logger, "parse report", err,
+		"version", att.GetReport().GetVersion(), "vmpl", att.GetReport().GetVmpl(), "policy", fmt.Sprintf("%#x", att.GetReport().GetPolicy()),
+		"product", kds.ProductLine(att.GetProduct()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	productName := kds.ProductLine(att.Product)
+
+	// Add VCEK/VLEK to attestation object.
+	signingInfo, err := a.addReportSigner(att, att.Report, productName, vcekSource, getter, logger)
+	attestation.Trace(logger, fmt.Sprintf("fetch %s", signerInfo.SigningKey), err, "source", reportSignerSource(signerInfo.SigningKey, vcekSource, a.ReportSigner))
+	if err != nil {
+		return nil, fmt.Errorf("adding report signer: %w", err)
+	}
+
+	err = a.addCertChain(att, productName, signingInfo, fallbackCerts, getter, logger)
+	attestation.Trace(logger, "fetch certificate chain", err)
+	if err != nil {
+		return nil, err
+	}
+	return att, nil
+}
+
+// parseReport parses the attestation report and determines the SEV product of the CVM that issued it.
+func (a *InstanceInfo) parseReport() (*spb.Attestation, abi.SignerInfo, error) {
 	report, err := abi.ReportToProto(a.AttestationReport)
 	if err != nil {
-		return nil, fmt.Errorf("converting report to proto: %w", err)
+		return nil, abi.SignerInfo{}, fmt.Errorf("converting report to proto: %w", err)
 	}
 
-	productName := kds.ProductLine(Product())
+	signerInfo, err := abi.ParseSignerInfo(report.GetSignerInfo())
+	if err != nil {
+		return nil, abi.SignerInfo{}, fmt.Errorf("parsing signer info: %w", err)
+	}
+	product, err := a.product(signerInfo.SigningKey)
+	if err != nil {
+		return nil, signerInfo, fmt.Errorf("determining SEV product: %w", err)
+	}
 
-	att := &spb.Attestation{
+	return &spb.Attestation{
 		Report:           report,
 		CertificateChain: &spb.CertificateChain{},
-		Product:          Product(),
+		Product:          product,
+	}, signerInfo, nil
+}
+
+// DefaultVCEKSource returns the VCEK source [InstanceInfo.AttestationWithCerts] uses: the issuer's certificate if present, AMD KDS otherwise.
+func (a *InstanceInfo) DefaultVCEKSource() VCEKSource {
+	if len(a.ReportSigner) == 0 {
+		return VCEKSourceKDS
 	}
+	return VCEKSourceTHIM
+}
 
-	// Add VCEK/VLEK to attestation object.
-	signingInfo, err := a.addReportSigner(att, report, productName, getter, logger)
-	if err != nil {
-		return nil, fmt.Errorf("adding report signer: %w", err)
+// reportSignerSource returns where the certificate of the report signer is taken from.
+func reportSignerSource(signingKey abi.ReportSigner, vcekSource VCEKSource, reportSigner []byte) string {
+	if signingKey == abi.VcekReportSigner && (len(reportSigner) == 0 || vcekSource == VCEKSourceKDS) {
+		return "AMD KDS"
 	}
+	return "issuer"
+}
 
+// addCertChain adds the ASK and ARK certificates to the attestation.
+func (a *InstanceInfo) addCertChain(att *spb.Attestation, productName string, signingInfo abi.ReportSigner,
+	fallbackCerts CertificateChain, getter trust.HTTPSGetter, logger attestation.Logger,
+) error {
 	// If a certificate chain was pre-fetched by the Issuer, parse it and format it.
 	// Make sure to only use the ask, since using an ark from the Issuer would invalidate security guarantees.
 	ask, _, err := a.ParseCertChain()
@@ -157,11 +313,20 @@ func (a *InstanceInfo) AttestationWithCerts(getter trust.HTTPSGetter,
 	}
 
 	// If a cached ASK or an ARK from the Constellation config is present, use it.
+	// A cached ASK of another product is outdated and retrieved again, but an ARK of another product
+	// can't be replaced without giving up the root of trust pinned in the config.
 	if att.CertificateChain.AskCert == nil && fallbackCerts.ask != nil {
-		logger.Info("Using cached ASK certificate")
-		att.CertificateChain.AskCert = fallbackCerts.ask.Raw
+		if askProductLine, _ := certProductLine(fallbackCerts.ask); askProductLine != "" && askProductLine != productName {
+			logger.Info(fmt.Sprintf("Ignoring cached ASK certificate of product %s", askProductLine))
+		} else {
+			logger.Info("Using cached ASK certificate")
+			att.CertificateChain.AskCert = fallbackCerts.ask.Raw
+		}
 	}
 	if fallbackCerts.ark != nil {
+		if arkProductLine, _ := certProductLine(fallbackCerts.ark); arkProductLine != "" && arkProductLine != productName {
+			return fmt.Errorf("configured ARK certificate is for product %s, but the attestation report is from product %s", arkProductLine, productName)
+		}
 		logger.Info("Using cached ARK certificate")
 		att.CertificateChain.ArkCert = fallbackCerts.ark.Raw
 	}
@@ -175,7 +340,7 @@ func (a *InstanceInfo) AttestationWithCerts(getter trust.HTTPSGetter,
 		))
 		kdsCertChain, err := trust.GetProductChain(productName, signingInfo, getter)
 		if err != nil {
-			return nil, fmt.Errorf("retrieving certificate chain from AMD KDS: %w", err)
+			return fmt.Errorf("retrieving certificate chain from AMD KDS: %w", err)
 		}
 		if att.CertificateChain.AskCert == nil && kdsCertChain.Ask != nil {
 			logger.Info("Using ASK certificate from AMD KDS")
@@ -187,7 +352,113 @@ func (a *InstanceInfo) AttestationWithCerts(getter trust.HTTPSGetter,
 		}
 	}
 
-	return att, nil
+	return nil
+}
+
+// product returns the SEV product of the CVM that issued the attestation report.
+// Attestation reports don't contain the product, so it is derived from the product name of the report signer
+// and the certificate chain provided by the issuer. If neither is present, the default [Product] is used.
+func (a *InstanceInfo) product(signingKey abi.ReportSigner) (*spb.SevProduct, error) {
+	var signerProductLine string
+	// Parsing errors of the report signer are reported when adding it to the attestation.
+	if reportSigner, err := a.ParseReportSigner(); err == nil && reportSigner != nil {
+		exts, err := kds.CertificateExtensions(reportSigner, signingKey)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s certificate extensions: %w", signingKey, err)
+		}
+		signerProduct, err := kds.ParseProductName(exts.ProductName, signingKey)
+		if err != nil {
+			return nil, fmt.Errorf("parsing product name of %s certificate: %w", signingKey, err)
+		}
+		signerProductLine = kds.ProductLine(signerProduct)
+	}
+
+	var chainProductLine string
+	if ask, ark, err := a.ParseCertChain(); err == nil {
+		for _, cert := range []*x509.Certificate{ask, ark} {
+			if cert != nil {
+				chainProductLine, _ = certProductLine(cert)
+			}
+		}
+	}
+
+	productLine := signerProductLine
+	if productLine == "" {
+		productLine = chainProductLine
+	}
+	if signerProductLine != "" && chainProductLine != "" && signerProductLine != chainProductLine {
+		return nil, fmt.Errorf("%s certificate is for product %s, but the certificate chain is for product %s",
+			signingKey, signerProductLine, chainProductLine)
+	}
+	if productLine == "" {
+		return Product(), nil
+	}
+	product, ok := productOfLine(productLine)
+	if !ok {
+		return nil, fmt.Errorf("unsupported SEV product %s", productLine)
+	}
+	return product, nil
+}
+
+// MicrocodeSVNError is returned if the microcode SVN of an attestation report
+// is lower than the configured minimum.
+// It is kept separate from errors of the aggregate TCB validation,
+// so callers can tell the two failure cases apart.
+type MicrocodeSVNError struct {
+	// Reported is the microcode SVN of the report's reported TCB.
+	Reported uint8
+	// Minimum is the lowest acceptable microcode SVN.
+	Minimum uint8
+}
+
+// Error returns the error message.
+func (e *MicrocodeSVNError) Error() string {
+	return fmt.Sprintf("reported microcode SVN %d is lower than the minimum required microcode SVN %d", e.Reported, e.Minimum)
+}
+
+// MicrocodeSVN returns the microcode SVN of the report's reported TCB.
+func MicrocodeSVN(report *spb.Report) uint8 {
+	return kds.DecomposeTCBVersion(kds.TCBVersion(report.GetReportedTcb())).UcodeSpl
+}
+
+// ValidateMinMicrocodeSVN checks that the microcode SVN of the report's reported TCB
+// is equal or greater than minSVN.
+// If minSVN is nil, no check is performed.
+func ValidateMinMicrocodeSVN(report *spb.Report, minSVN *uint8) error {
+	if minSVN == nil {
+		return nil
+	}
+	if reported := MicrocodeSVN(report); reported < *minSVN {
+		return &MicrocodeSVNError{Reported: reported, Minimum: *minSVN}
+	}
+	return nil
+}
+
+// VMPLError is returned if the report was not issued from the expected
+// Virtual Machine Privilege Level (VMPL).
+type VMPLError struct {
+	// Reported is the VMPL the report was issued from.
+	Reported uint32
+	// Expected is the VMPL the report is required to be issued from.
+	Expected uint8
+}
+
+// Error returns the error message.
+func (e *VMPLError) Error() string {
+	return fmt.Sprintf("report VMPL %d is not %d", e.Reported, e.Expected)
+}
+
+// ValidateVMPL checks that the report was issued from the given VMPL.
+// If vmpl is nil, the report is required to be issued from VMPL 0.
+func ValidateVMPL(report *spb.Report, vmpl *uint8) error {
+	var expected uint8
+	if vmpl != nil {
+		expected = *vmpl
+	}
+	if reported := report.GetVmpl(); reported != uint32(expected) {
+		return &VMPLError{Reported: reported, Expected: expected}
+	}
+	return nil
 }
 
 // CertificateChain stores an AMD signing key (ASK) and AMD root key (ARK) certificate.
@@ -229,12 +500,10 @@ func (a *InstanceInfo) ParseCertChain() (ask, ark *x509.Certificate, retErr erro
 			return
 		}
 
-		// https://www.amd.com/content/dam/amd/en/documents/epyc-technical-docs/specifications/57230.pdf
-		// Table 6 and 7
-		switch cert.Subject.CommonName {
-		case "SEV-Milan", "SEV-VLEK-Milan":
+		switch _, role := certProductLine(cert); role {
+		case certRoleASK:
 			ask = cert
-		case "ARK-Milan":
+		case certRoleARK:
 			ark = cert
 		default:
 			retErr = fmt.Errorf("parse certificate %d: unexpected subject CN %s", i, cert.Subject.CommonName)
@@ -249,6 +518,13 @@ func (a *InstanceInfo) ParseCertChain() (ask, ark *x509.Certificate, retErr erro
 		retErr = errNoPemBlocks
 	case len(rest) != 0:
 		retErr = fmt.Errorf("remaining PEM block is not a valid certificate: %s", rest)
+	case ask != nil && ark != nil:
+		askProductLine, _ := certProductLine(ask)
+		arkProductLine, _ := certProductLine(ark)
+		if askProductLine != arkProductLine {
+			ask, ark = nil, nil
+			retErr = fmt.Errorf("ASK certificate is for product %s, but ARK certificate is for product %s", askProductLine, arkProductLine)
+		}
 	}
 
 	return
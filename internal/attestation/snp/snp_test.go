@@ -9,16 +9,22 @@ package snp
 import (
 	"crypto/x509"
 	"encoding/hex"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/edgelesssys/constellation/v2/internal/attestation/snp/testdata"
 	"github.com/edgelesssys/constellation/v2/internal/config"
 	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/google/go-sev-guest/abi"
 	"github.com/google/go-sev-guest/kds"
+	spb "github.com/google/go-sev-guest/proto/sevsnp"
+	test "github.com/google/go-sev-guest/testing"
+	"github.com/google/go-sev-guest/verify"
 	"github.com/google/go-sev-guest/verify/trust"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -30,6 +36,9 @@ func TestParseCertChain(t *testing.T) {
 	askOnly := strings.Split(string(defaultCertChain), "-----END CERTIFICATE-----")[0] + "-----END CERTIFICATE-----"
 	arkOnly := strings.Split(string(defaultCertChain), "-----END CERTIFICATE-----")[1] + "-----END CERTIFICATE-----"
 
+	milan := mustTestOnlyCertChain(t, "Milan-B1")
+	genoa := mustTestOnlyCertChain(t, "Genoa-B1")
+
 	testCases := map[string]struct {
 		certChain []byte
 		wantAsk   bool
@@ -42,6 +51,15 @@ func TestParseCertChain(t *testing.T) {
 			wantAsk:   true,
 			wantArk:   true,
 		},
+		"genoa": {
+			certChain: append(certToPEM(genoa.Ask), certToPEM(genoa.Ark)...),
+			wantAsk:   true,
+			wantArk:   true,
+		},
+		"ask and ark of different products": {
+			certChain: append(certToPEM(genoa.Ask), certToPEM(milan.Ark)...),
+			wantErr:   true,
+		},
 		"more than two certificates": {
 			certChain: append(defaultCertChain, defaultCertChain...),
 			wantErr:   true,
@@ -296,6 +314,310 @@ func TestAttestationWithCerts(t *testing.T) {
 	}
 }
 
+// TestAttestationWithCertsProduct tests that the certificate chain of the report's SEV product is selected.
+func TestAttestationWithCertsProduct(t *testing.T) {
+	milan := mustTestOnlyCertChain(t, "Milan-B1")
+	genoa := mustTestOnlyCertChain(t, "Genoa-B1")
+
+	testCases := map[string]struct {
+		signer        *test.AmdSigner
+		reportSigner  []byte
+		certChain     []byte
+		fallbackCerts CertificateChain
+		getter        trust.HTTPSGetter
+		wantProduct   spb.SevProduct_SevProductName
+		wantArk       *x509.Certificate
+		wantErr       bool
+	}{
+		"milan report": {
+			signer:        milan,
+			reportSigner:  certToPEM(milan.Vcek),
+			certChain:     certToPEM(milan.Ask),
+			fallbackCerts: NewCertificateChain(nil, milan.Ark),
+			getter:        newStubHTTPSGetter(nil, assert.AnError),
+			wantProduct:   spb.SevProduct_SEV_PRODUCT_MILAN,
+			wantArk:       milan.Ark,
+		},
+		"genoa report": {
+			signer:        genoa,
+			reportSigner:  certToPEM(genoa.Vcek),
+			certChain:     certToPEM(genoa.Ask),
+			fallbackCerts: NewCertificateChain(nil, genoa.Ark),
+			getter:        newStubHTTPSGetter(nil, assert.AnError),
+			wantProduct:   spb.SevProduct_SEV_PRODUCT_GENOA,
+			wantArk:       genoa.Ark,
+		},
+		"genoa certificate chain retrieved from AMD KDS": {
+			signer:       genoa,
+			reportSigner: certToPEM(genoa.Vcek),
+			getter: test.SimpleGetter(map[string][]byte{
+				kds.ProductCertChainURL(abi.VcekReportSigner, "Genoa"): append(certToPEM(genoa.Ask), certToPEM(genoa.Ark)...),
+			}),
+			wantProduct: spb.SevProduct_SEV_PRODUCT_GENOA,
+			wantArk:     genoa.Ark,
+		},
+		"cached ASK of another product is ignored": {
+			signer:        genoa,
+			reportSigner:  certToPEM(genoa.Vcek),
+			fallbackCerts: NewCertificateChain(milan.Ask, genoa.Ark),
+			getter: test.SimpleGetter(map[string][]byte{
+				kds.ProductCertChainURL(abi.VcekReportSigner, "Genoa"): append(certToPEM(genoa.Ask), certToPEM(genoa.Ark)...),
+			}),
+			wantProduct: spb.SevProduct_SEV_PRODUCT_GENOA,
+			wantArk:     genoa.Ark,
+		},
+		"report signer and certificate chain of different products": {
+			signer:        genoa,
+			reportSigner:  certToPEM(genoa.Vcek),
+			certChain:     certToPEM(milan.Ask),
+			fallbackCerts: NewCertificateChain(nil, genoa.Ark),
+			getter:        newStubHTTPSGetter(nil, assert.AnError),
+			wantErr:       true,
+		},
+		"configured ARK of another product": {
+			signer:        genoa,
+			reportSigner:  certToPEM(genoa.Vcek),
+			certChain:     certToPEM(genoa.Ask),
+			fallbackCerts: NewCertificateChain(nil, milan.Ark),
+			getter:        newStubHTTPSGetter(nil, assert.AnError),
+			wantErr:       true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			instanceInfo := InstanceInfo{
+				AttestationReport: mustSignedReport(t, tc.signer),
+				ReportSigner:      tc.reportSigner,
+				CertChain:         tc.certChain,
+			}
+
+			defer trust.ClearProductCertCache()
+			att, err := instanceInfo.AttestationWithCerts(tc.getter, tc.fallbackCerts, logger.NewTest(t))
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tc.wantProduct, att.Product.Name)
+			assert.Equal(tc.wantArk.Raw, att.CertificateChain.ArkCert)
+			assert.Equal(tc.signer.Ask.Raw, att.CertificateChain.AskCert)
+
+			// The validators trust the ASK and ARK for the product line of the attestation.
+			productLine := kds.ProductLine(att.Product)
+			err = verify.SnpAttestation(att, &verify.Options{
+				DisableCertFetching: true,
+				TrustedRoots: map[string][]*trust.AMDRootCerts{
+					productLine: {{
+						Product:      productLine,
+						ProductCerts: &trust.ProductCerts{Ask: tc.signer.Ask, Ark: tc.signer.Ark},
+					}},
+				},
+			})
+			assert.NoError(err)
+		})
+	}
+}
+
+func TestVerifyWithVCEKFallback(t *testing.T) {
+	thimVCEK, err := (&InstanceInfo{ReportSigner: testdata.AzureThimVCEK}).ParseReportSigner()
+	require.NoError(t, err)
+	kdsVCEK := testdata.AmdKdsVCEK
+	testdataArk, _ := mustCertChainToPem(t, testdata.CertChain)
+
+	acceptAll := func(*spb.Attestation) error { return nil }
+	// AzureThimVCEK and AmdKdsVCEK are the same certificate, so reject by attempt instead of content.
+	rejectFirst := func() func(*spb.Attestation) error {
+		var calls int
+		return func(*spb.Attestation) error {
+			calls++
+			if calls == 1 {
+				return errors.New("VCEK certificate not trusted")
+			}
+			return nil
+		}
+	}
+	rejectAll := func(*spb.Attestation) error { return errors.New("VCEK certificate not trusted") }
+
+	kdsGetter := func() *stubHTTPSGetter {
+		return newStubHTTPSGetter(&urlResponseMatcher{vcekResponse: kdsVCEK, wantVcekRequest: true}, nil)
+	}
+
+	testCases := map[string]struct {
+		primary    VCEKSource
+		getter     *stubHTTPSGetter
+		verify     func(*spb.Attestation) error
+		wantSource VCEKSource
+		wantVCEK   []byte
+		wantErr    bool
+	}{
+		"primary THIM succeeds": {
+			primary:    VCEKSourceTHIM,
+			getter:     newStubHTTPSGetter(&urlResponseMatcher{}, nil),
+			verify:     acceptAll,
+			wantSource: VCEKSourceTHIM,
+			wantVCEK:   thimVCEK.Raw,
+		},
+		"primary KDS succeeds": {
+			primary:    VCEKSourceKDS,
+			getter:     kdsGetter(),
+			verify:     acceptAll,
+			wantSource: VCEKSourceKDS,
+			wantVCEK:   kdsVCEK,
+		},
+		"fallback to KDS if THIM VCEK fails verification": {
+			primary:    VCEKSourceTHIM,
+			getter:     kdsGetter(),
+			verify:     rejectFirst(),
+			wantSource: VCEKSourceKDS,
+			wantVCEK:   kdsVCEK,
+		},
+		"fallback to THIM if KDS is unreachable": {
+			primary:    VCEKSourceKDS,
+			getter:     newStubHTTPSGetter(&urlResponseMatcher{}, nil),
+			verify:     acceptAll,
+			wantSource: VCEKSourceTHIM,
+			wantVCEK:   thimVCEK.Raw,
+		},
+		"both sources fail": {
+			primary: VCEKSourceTHIM,
+			getter:  kdsGetter(),
+			verify:  rejectAll,
+			wantErr: true,
+		},
+		"unknown source": {
+			primary: "unknown",
+			getter:  kdsGetter(),
+			verify:  acceptAll,
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			instanceInfo := InstanceInfo{
+				AttestationReport: testdata.AttestationReport,
+				CertChain:         testdata.CertChain,
+				ReportSigner:      testdata.AzureThimVCEK,
+			}
+
+			defer trust.ClearProductCertCache()
+			att, source, err := instanceInfo.VerifyWithVCEKFallback(
+				tc.primary, tc.getter, CertificateChain{ark: testdataArk}, tc.verify, logger.NewTest(t),
+			)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tc.wantSource, source)
+			assert.Equal(tc.wantVCEK, att.CertificateChain.VcekCert)
+		})
+	}
+}
+
+func TestValidateMinMicrocodeSVN(t *testing.T) {
+	report, err := abi.ReportToProto(testdata.AttestationReport)
+	require.NoError(t, err)
+	reportedSVN := MicrocodeSVN(report)
+	require.NotZero(t, reportedSVN, "embedded report should have a non-zero microcode SVN")
+
+	svn := func(v uint8) *uint8 { return &v }
+
+	testCases := map[string]struct {
+		minSVN  *uint8
+		wantErr bool
+	}{
+		"no minimum": {},
+		"zero minimum": {
+			minSVN: svn(0),
+		},
+		"minimum below reported": {
+			minSVN: svn(reportedSVN - 1),
+		},
+		"minimum equal to reported": {
+			minSVN: svn(reportedSVN),
+		},
+		"minimum above reported": {
+			minSVN:  svn(reportedSVN + 1),
+			wantErr: true,
+		},
+		"maximum minimum": {
+			minSVN:  svn(255),
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			err := ValidateMinMicrocodeSVN(report, tc.minSVN)
+			if !tc.wantErr {
+				assert.NoError(err)
+				return
+			}
+			var svnErr *MicrocodeSVNError
+			assert.ErrorAs(err, &svnErr)
+			assert.Equal(reportedSVN, svnErr.Reported)
+			assert.Equal(*tc.minSVN, svnErr.Minimum)
+		})
+	}
+}
+
+func TestValidateVMPL(t *testing.T) {
+	report, err := abi.ReportToProto(testdata.AttestationReport)
+	require.NoError(t, err)
+	reportedVMPL := report.GetVmpl()
+	require.Less(t, reportedVMPL, uint32(3), "embedded report should be issued from a VMPL lower than 3")
+
+	vmpl := func(v uint32) *uint8 {
+		u := uint8(v)
+		return &u
+	}
+
+	testCases := map[string]struct {
+		vmpl     *uint8
+		wantVMPL uint8
+		wantErr  bool
+	}{
+		"default is VMPL 0": {
+			wantVMPL: 0,
+			wantErr:  reportedVMPL != 0,
+		},
+		"expected VMPL": {
+			vmpl: vmpl(reportedVMPL),
+		},
+		"different VMPL": {
+			vmpl:     vmpl(reportedVMPL + 1),
+			wantVMPL: uint8(reportedVMPL + 1),
+			wantErr:  true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			err := ValidateVMPL(report, tc.vmpl)
+			if !tc.wantErr {
+				assert.NoError(err)
+				return
+			}
+			var vmplErr *VMPLError
+			assert.ErrorAs(err, &vmplErr)
+			assert.Equal(reportedVMPL, vmplErr.Reported)
+			assert.Equal(tc.wantVMPL, vmplErr.Expected)
+		})
+	}
+}
+
 func mustCertChainToPem(t *testing.T, certchain []byte) (ark, ask *x509.Certificate) {
 	t.Helper()
 	a := InstanceInfo{CertChain: certchain}
@@ -304,6 +626,29 @@ func mustCertChainToPem(t *testing.T, certchain []byte) (ark, ask *x509.Certific
 	return ark, ask
 }
 
+// mustTestOnlyCertChain creates a fake AMD certificate chain for the given product name, e.g. "Genoa-B1".
+func mustTestOnlyCertChain(t *testing.T, productName string) *test.AmdSigner {
+	t.Helper()
+	signer, err := test.DefaultTestOnlyCertChain(productName, time.Now())
+	require.NoError(t, err)
+	return signer
+}
+
+// mustSignedReport returns a raw attestation report signed by the VCEK of signer.
+func mustSignedReport(t *testing.T, signer *test.AmdSigner) []byte {
+	t.Helper()
+	raw := test.CreateRawReport(&test.TestReportOptions{})
+	report := raw[:abi.ReportSize]
+	r, s, err := signer.Sign(abi.SignedComponent(report))
+	require.NoError(t, err)
+	require.NoError(t, abi.SetSignature(r, s, report))
+	return report
+}
+
+func certToPEM(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
+
 type stubHTTPSGetter struct {
 	urlResponseMatcher *urlResponseMatcher // maps responses to requested URLs
 	err                error
@@ -14,6 +14,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"slices"
 
 	"github.com/google/go-sev-guest/proto/sevsnp"
@@ -161,10 +162,18 @@ type Validator struct {
 	expected      measurements.M
 	getTrustedKey GetTPMTrustedAttestationPublicKey
 	validateCVM   ValidateCVM
+	// kernelCmdline is the expected kernel command line, if it is checked.
+	kernelCmdline *expectedKernelCmdline
 
 	log attestation.Logger
 }
 
+// expectedKernelCmdline is a kernel command line and the digest of the initrd it is measured together with.
+type expectedKernelCmdline struct {
+	cmdline      string
+	initrdDigest []byte
+}
+
 // NewValidator returns a new Validator.
 func NewValidator(expected measurements.M, getTrustedKey GetTPMTrustedAttestationPublicKey,
 	validateCVM ValidateCVM, log attestation.Logger,
@@ -180,6 +189,16 @@ func NewValidator(expected measurements.M, getTrustedKey GetTPMTrustedAttestatio
 	}
 }
 
+// SetExpectedKernelCmdline makes the Validator check that the kernel command line PCR reflects the given kernel command line.
+// Since the EFI stub measures the initrd into the same PCR, the SHA-256 digest of the initrd is required as well.
+func (v *Validator) SetExpectedKernelCmdline(cmdline string, initrdDigest []byte) error {
+	if _, err := PredictKernelCmdlinePCR(cmdline, initrdDigest); err != nil {
+		return err
+	}
+	v.kernelCmdline = &expectedKernelCmdline{cmdline: cmdline, initrdDigest: initrdDigest}
+	return nil
+}
+
 // Validate a TPM based attestation.
 func (v *Validator) Validate(ctx context.Context, attDocRaw []byte, nonce []byte) (userData []byte, err error) {
 	v.log.Info("Validating attestation document")
@@ -201,7 +220,9 @@ func (v *Validator) Validate(ctx context.Context, attDocRaw []byte, nonce []byte
 			},
 		},
 	}
-	if err := json.Unmarshal(attDocRaw, &attDoc); err != nil {
+	err = json.Unmarshal(attDocRaw, &attDoc)
+	attestation.Trace(v.log, "parse attestation document", err, "size", len(attDocRaw))
+	if err != nil {
 		return nil, fmt.Errorf("unmarshaling TPM attestation document: %w", err)
 	}
 
@@ -209,6 +230,7 @@ func (v *Validator) Validate(ctx context.Context, attDocRaw []byte, nonce []byte
 
 	// Verify and retrieve the trusted attestation public key using the provided instance info
 	aKP, err := v.getTrustedKey(ctx, attDoc, extraData)
+	attestation.Trace(v.log, "validate attestation key", err)
 	if err != nil {
 		return nil, fmt.Errorf("validating attestation public key: %w", err)
 	}
@@ -224,26 +246,39 @@ func (v *Validator) Validate(ctx context.Context, attDocRaw []byte, nonce []byte
 			AllowSHA1:  false,
 		},
 	)
+	attestation.Trace(v.log, "verify TPM quote", err, "nonce", fmt.Sprintf("%x", nonce))
 	if err != nil {
 		return nil, fmt.Errorf("verifying attestation document: %w", err)
 	}
 
 	// Validate confidential computing capabilities of the VM
-	if err := v.validateCVM(attDoc, state); err != nil {
+	err = v.validateCVM(attDoc, state)
+	attestation.Trace(v.log, "validate confidential VM", err)
+	if err != nil {
 		return nil, fmt.Errorf("verifying VM confidential computing capabilities: %w", err)
 	}
 
 	// Verify PCRs
 	quoteIdx, err := GetSHA256QuoteIndex(attDoc.Attestation.Quotes)
 	if err != nil {
+		attestation.Trace(v.log, "compare measurements", err)
 		return nil, err
 	}
 	warnings, errs := v.expected.Compare(attDoc.Attestation.Quotes[quoteIdx].Pcrs.Pcrs)
 	for _, warning := range warnings {
 		v.log.Warn(warning)
 	}
-	if len(errs) > 0 {
-		return nil, fmt.Errorf("measurement validation failed:\n%w", errors.Join(errs...))
+	err = errors.Join(errs...)
+	attestation.Trace(v.log, "compare measurements", err, "indices", slices.Sorted(maps.Keys(v.expected)), "warnings", len(warnings))
+	if err != nil {
+		return nil, fmt.Errorf("measurement validation failed:\n%w", err)
+	}
+	if v.kernelCmdline != nil {
+		err := ValidateKernelCmdline(attDoc.Attestation.Quotes[quoteIdx].Pcrs.Pcrs, v.kernelCmdline.cmdline, v.kernelCmdline.initrdDigest)
+		attestation.Trace(v.log, "validate kernel command line", err, "cmdline", v.kernelCmdline.cmdline)
+		if err != nil {
+			return nil, fmt.Errorf("validating kernel command line: %w", err)
+		}
 	}
 
 	v.log.Info("Successfully validated attestation document")
@@ -242,6 +242,16 @@ func TestValidate(t *testing.T) {
 			nonce:   nonce,
 			wantErr: false,
 		},
+		"unexpected kernel command line": {
+			validator: func() *Validator {
+				validator := NewValidator(testExpectedPCRs, fakeGetTrustedKey, fakeValidateCVM, warnLog)
+				require.NoError(validator.SetExpectedKernelCmdline("console=ttyS0", make([]byte, 32)))
+				return validator
+			}(),
+			attDoc:  mustMarshalAttestation(attDoc, require),
+			nonce:   nonce,
+			wantErr: true,
+		},
 		"no sha256 quote": {
 			validator: NewValidator(testExpectedPCRs, fakeGetTrustedKey, fakeValidateCVM, warnLog),
 			attDoc: mustMarshalAttestation(AttestationDocument{
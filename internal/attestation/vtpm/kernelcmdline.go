@@ -0,0 +1,70 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package vtpm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+)
+
+// PCRIndexKernelCmdline is the PCR the EFI stub of the kernel measures the kernel command line and the initrd into.
+const PCRIndexKernelCmdline = 9
+
+// KernelCmdlineError is returned if the kernel command line PCR doesn't match the expected kernel command line.
+type KernelCmdlineError struct {
+	// Cmdline is the expected kernel command line.
+	Cmdline string
+	// Expected is the PCR value predicted for the expected kernel command line.
+	Expected []byte
+	// Actual is the quoted PCR value.
+	Actual []byte
+}
+
+// Error returns the error message.
+func (e *KernelCmdlineError) Error() string {
+	return fmt.Sprintf("PCR %d is %x, expected %x for kernel command line %q", PCRIndexKernelCmdline, e.Actual, e.Expected, e.Cmdline)
+}
+
+// PredictKernelCmdlinePCR predicts the value of the kernel command line PCR for the given kernel command line
+// and the SHA-256 digest of the initrd.
+//
+// The computation follows the Linux LOAD_FILE2 protocol of the EFI stub, which is also used to predict
+// the measurements of node images: the null terminated command line is measured as UTF-16LE, then the initrd digest is measured.
+func PredictKernelCmdlinePCR(cmdline string, initrdDigest []byte) ([]byte, error) {
+	if len(initrdDigest) != sha256.Size {
+		return nil, fmt.Errorf("initrd digest has length %d, expected %d bytes", len(initrdDigest), sha256.Size)
+	}
+
+	cmdlineUTF16LE := new(bytes.Buffer)
+	if err := binary.Write(cmdlineUTF16LE, binary.LittleEndian, utf16.Encode([]rune(cmdline+"\x00"))); err != nil {
+		return nil, fmt.Errorf("encoding kernel command line: %w", err)
+	}
+	cmdlineDigest := sha256.Sum256(cmdlineUTF16LE.Bytes())
+
+	pcr := make([]byte, sha256.Size)
+	for _, digest := range [][]byte{cmdlineDigest[:], initrdDigest} {
+		extended := sha256.Sum256(append(pcr, digest...))
+		pcr = extended[:]
+	}
+	return pcr, nil
+}
+
+// ValidateKernelCmdline checks that the quoted kernel command line PCR reflects the given kernel command line and initrd digest.
+func ValidateKernelCmdline(pcrs map[uint32][]byte, cmdline string, initrdDigest []byte) error {
+	expected, err := PredictKernelCmdlinePCR(cmdline, initrdDigest)
+	if err != nil {
+		return err
+	}
+	actual := pcrs[PCRIndexKernelCmdline]
+	if !bytes.Equal(actual, expected) {
+		return &KernelCmdlineError{Cmdline: cmdline, Expected: expected, Actual: actual}
+	}
+	return nil
+}
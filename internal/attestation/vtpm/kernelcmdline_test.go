@@ -0,0 +1,115 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package vtpm
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPredictKernelCmdlinePCR(t *testing.T) {
+	testCases := map[string]struct {
+		cmdline      string
+		initrdDigest []byte
+		wantPCR      []byte
+		wantErr      bool
+	}{
+		"known value": {
+			// Same value as predicted for the image measurements in image/measured-boot/measure.
+			cmdline:      "console=tty0",
+			initrdDigest: make([]byte, 32),
+			wantPCR: []byte{
+				0xeb, 0x4f, 0x7b, 0xca, 0x86, 0x58, 0x07, 0xd3,
+				0x16, 0x3b, 0x95, 0x17, 0x4d, 0x6e, 0x66, 0xcf,
+				0xc7, 0x4a, 0xcf, 0x8b, 0x93, 0x0a, 0x55, 0x3e,
+				0x95, 0xec, 0x94, 0x66, 0x2c, 0xb6, 0xfa, 0xcd,
+			},
+		},
+		"invalid initrd digest": {
+			cmdline:      "console=tty0",
+			initrdDigest: make([]byte, 48),
+			wantErr:      true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			pcr, err := PredictKernelCmdlinePCR(tc.cmdline, tc.initrdDigest)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantPCR, pcr)
+		})
+	}
+}
+
+func TestValidateKernelCmdline(t *testing.T) {
+	const cmdline = "console=ttyS0 constel.csp=gcp constel.attestation-variant=gcp-sev-snp mitigations=auto,nosmt"
+	initrdDigest := bytes.Repeat([]byte{0x01}, 32)
+
+	// The synthetic measurement is the PCR value of a node booted with cmdline.
+	measured, err := PredictKernelCmdlinePCR(cmdline, initrdDigest)
+	require.NoError(t, err)
+	pcrs := map[uint32][]byte{
+		0:                     bytes.Repeat([]byte{0x00}, 32),
+		PCRIndexKernelCmdline: measured,
+	}
+
+	testCases := map[string]struct {
+		pcrs         map[uint32][]byte
+		cmdline      string
+		initrdDigest []byte
+		wantErr      bool
+	}{
+		"matching kernel command line": {
+			pcrs:         pcrs,
+			cmdline:      cmdline,
+			initrdDigest: initrdDigest,
+		},
+		"modified kernel command line": {
+			pcrs:         pcrs,
+			cmdline:      cmdline + " init=/bin/sh",
+			initrdDigest: initrdDigest,
+			wantErr:      true,
+		},
+		"different initrd": {
+			pcrs:         pcrs,
+			cmdline:      cmdline,
+			initrdDigest: bytes.Repeat([]byte{0x02}, 32),
+			wantErr:      true,
+		},
+		"PCR not quoted": {
+			pcrs:         map[uint32][]byte{0: bytes.Repeat([]byte{0x00}, 32)},
+			cmdline:      cmdline,
+			initrdDigest: initrdDigest,
+			wantErr:      true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			err := ValidateKernelCmdline(tc.pcrs, tc.cmdline, tc.initrdDigest)
+			if !tc.wantErr {
+				assert.NoError(err)
+				return
+			}
+			var cmdlineErr *KernelCmdlineError
+			assert.ErrorAs(err, &cmdlineErr)
+			assert.Equal(tc.cmdline, cmdlineErr.Cmdline)
+			assert.Equal(tc.pcrs[PCRIndexKernelCmdline], cmdlineErr.Actual)
+		})
+	}
+}
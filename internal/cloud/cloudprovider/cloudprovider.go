@@ -64,9 +64,25 @@ func (p *Provider) UnmarshalYAML(unmarshal func(any) error) error {
 	return nil
 }
 
+// aliases maps commonly used alternative names of cloud providers to their canonical name.
+var aliases = map[string]string{
+	"amazon":                "aws",
+	"amazon-web-services":   "aws",
+	"az":                    "azure",
+	"microsoft-azure":       "azure",
+	"google":                "gcp",
+	"google-cloud":          "gcp",
+	"google-cloud-platform": "gcp",
+}
+
 // FromString returns cloud provider from string.
+// The string is matched case-insensitively, and common aliases like "az" or "google-cloud"
+// are mapped to their canonical provider. Unknown strings return [Unknown].
 func FromString(s string) Provider {
-	s = strings.ToLower(s)
+	s = strings.ToLower(strings.TrimSpace(s))
+	if canonical, ok := aliases[s]; ok {
+		s = canonical
+	}
 	if isOpenStackProvider(s) {
 		return OpenStack
 	}
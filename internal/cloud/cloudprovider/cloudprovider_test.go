@@ -243,6 +243,54 @@ func TestFromString(t *testing.T) {
 			input: "qemu",
 			want:  QEMU,
 		},
+		"canonical Azure": {
+			input: "Azure",
+			want:  Azure,
+		},
+		"upper case AZURE": {
+			input: "AZURE",
+			want:  Azure,
+		},
+		"az": {
+			input: "az",
+			want:  Azure,
+		},
+		"microsoft-azure": {
+			input: "microsoft-azure",
+			want:  Azure,
+		},
+		"google": {
+			input: "google",
+			want:  GCP,
+		},
+		"google-cloud": {
+			input: "google-cloud",
+			want:  GCP,
+		},
+		"Google-Cloud-Platform": {
+			input: "Google-Cloud-Platform",
+			want:  GCP,
+		},
+		"amazon": {
+			input: "amazon",
+			want:  AWS,
+		},
+		"amazon-web-services": {
+			input: "amazon-web-services",
+			want:  AWS,
+		},
+		"surrounding whitespace": {
+			input: " gcp\n",
+			want:  GCP,
+		},
+		"clearly invalid": {
+			input: "not-a-cloud-provider",
+			want:  Unknown,
+		},
+		"alias prefix is not matched": {
+			input: "azure-stack",
+			want:  Unknown,
+		},
 	}
 
 	for name, tc := range testCases {
@@ -255,3 +303,22 @@ func TestFromString(t *testing.T) {
 		})
 	}
 }
+
+func TestFromStringCanonicalRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	// the canonical string output is part of the config and state file format
+	for p, want := range map[Provider]string{
+		AWS:       "AWS",
+		Azure:     "Azure",
+		GCP:       "GCP",
+		OpenStack: "OpenStack",
+		QEMU:      "QEMU",
+	} {
+		assert.Equal(want, p.String())
+		assert.Equal(p, FromString(p.String()))
+	}
+	assert.Equal(AWS.String(), FromString("amazon").String())
+	assert.Equal(Azure.String(), FromString("az").String())
+	assert.Equal(GCP.String(), FromString("google-cloud").String())
+}
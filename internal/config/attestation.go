@@ -13,6 +13,7 @@ import (
 	"encoding/pem"
 	"fmt"
 
+	"github.com/edgelesssys/constellation/v2/internal/attestation/idkeydigest"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/measurements"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/variant"
 )
@@ -65,6 +66,32 @@ func UnmarshalAttestationConfig(data []byte, attestVariant variant.Variant) (Att
 	}
 }
 
+// NewAttestationConfig returns an AttestationConfig containing only the given attestation config.
+func NewAttestationConfig(cfg AttestationCfg) (AttestationConfig, error) {
+	switch c := cfg.(type) {
+	case *AWSNitroTPM:
+		return AttestationConfig{AWSNitroTPM: c}, nil
+	case *AWSSEVSNP:
+		return AttestationConfig{AWSSEVSNP: c}, nil
+	case *AzureSEVSNP:
+		return AttestationConfig{AzureSEVSNP: c}, nil
+	case *AzureTrustedLaunch:
+		return AttestationConfig{AzureTrustedLaunch: c}, nil
+	case *AzureTDX:
+		return AttestationConfig{AzureTDX: c}, nil
+	case *GCPSEVES:
+		return AttestationConfig{GCPSEVES: c}, nil
+	case *GCPSEVSNP:
+		return AttestationConfig{GCPSEVSNP: c}, nil
+	case *QEMUVTPM:
+		return AttestationConfig{QEMUVTPM: c}, nil
+	case *QEMUTDX:
+		return AttestationConfig{QEMUTDX: c}, nil
+	default:
+		return AttestationConfig{}, fmt.Errorf("unsupported attestation config type %T", cfg)
+	}
+}
+
 func unmarshalTypedConfig[T AttestationCfg](data []byte) (AttestationCfg, error) {
 	var cfg T
 	if err := json.Unmarshal(data, &cfg); err != nil {
@@ -166,3 +193,94 @@ func (c *DummyCfg) SetMeasurements(m measurements.M) {
 func (c DummyCfg) EqualTo(other AttestationCfg) (bool, error) {
 	return c.Measurements.EqualTo(other.GetMeasurements()), nil
 }
+
+// Clone returns a deep copy of the attestation config.
+// Certificates are shared between the original and the copy, since they are never modified in place.
+func (c *AttestationConfig) Clone() *AttestationConfig {
+	if c == nil {
+		return nil
+	}
+
+	clone := &AttestationConfig{}
+	if c.AWSSEVSNP != nil {
+		cfg := *c.AWSSEVSNP
+		cfg.Measurements = cloneMeasurements(cfg.Measurements)
+		cfg.MinMicrocodeSVN = clonePtr(cfg.MinMicrocodeSVN)
+		cfg.VMPL = clonePtr(cfg.VMPL)
+		clone.AWSSEVSNP = &cfg
+	}
+	if c.AWSNitroTPM != nil {
+		clone.AWSNitroTPM = &AWSNitroTPM{Measurements: cloneMeasurements(c.AWSNitroTPM.Measurements)}
+	}
+	if c.AzureSEVSNP != nil {
+		cfg := *c.AzureSEVSNP
+		cfg.Measurements = cloneMeasurements(cfg.Measurements)
+		cfg.MinMicrocodeSVN = clonePtr(cfg.MinMicrocodeSVN)
+		cfg.VMPL = clonePtr(cfg.VMPL)
+		if cfg.FirmwareSignerConfig.AcceptedKeyDigests != nil {
+			digests := make(idkeydigest.List, len(cfg.FirmwareSignerConfig.AcceptedKeyDigests))
+			for i, digest := range cfg.FirmwareSignerConfig.AcceptedKeyDigests {
+				digests[i] = bytes.Clone(digest)
+			}
+			cfg.FirmwareSignerConfig.AcceptedKeyDigests = digests
+		}
+		clone.AzureSEVSNP = &cfg
+	}
+	if c.AzureTDX != nil {
+		cfg := *c.AzureTDX
+		cfg.Measurements = cloneMeasurements(cfg.Measurements)
+		cfg.TEETCBSVN.Value = bytes.Clone(cfg.TEETCBSVN.Value)
+		cfg.QEVendorID.Value = bytes.Clone(cfg.QEVendorID.Value)
+		cfg.MRSeam = bytes.Clone(cfg.MRSeam)
+		cfg.XFAM.Value = bytes.Clone(cfg.XFAM.Value)
+		clone.AzureTDX = &cfg
+	}
+	if c.AzureTrustedLaunch != nil {
+		clone.AzureTrustedLaunch = &AzureTrustedLaunch{Measurements: cloneMeasurements(c.AzureTrustedLaunch.Measurements)}
+	}
+	if c.GCPSEVES != nil {
+		clone.GCPSEVES = &GCPSEVES{Measurements: cloneMeasurements(c.GCPSEVES.Measurements)}
+	}
+	if c.GCPSEVSNP != nil {
+		cfg := *c.GCPSEVSNP
+		cfg.Measurements = cloneMeasurements(cfg.Measurements)
+		cfg.MinMicrocodeSVN = clonePtr(cfg.MinMicrocodeSVN)
+		cfg.VMPL = clonePtr(cfg.VMPL)
+		clone.GCPSEVSNP = &cfg
+	}
+	if c.QEMUTDX != nil {
+		clone.QEMUTDX = &QEMUTDX{Measurements: cloneMeasurements(c.QEMUTDX.Measurements)}
+	}
+	if c.QEMUVTPM != nil {
+		clone.QEMUVTPM = &QEMUVTPM{Measurements: cloneMeasurements(c.QEMUVTPM.Measurements)}
+	}
+	return clone
+}
+
+// cloneMeasurements returns a deep copy of m, including the expected values.
+func cloneMeasurements(m measurements.M) measurements.M {
+	if m == nil {
+		return nil
+	}
+	clone := make(measurements.M, len(m))
+	for idx, measurement := range m {
+		measurement.Expected = bytes.Clone(measurement.Expected)
+		if measurement.OneOf != nil {
+			oneOf := make([][]byte, 0, len(measurement.OneOf))
+			for _, value := range measurement.OneOf {
+				oneOf = append(oneOf, bytes.Clone(value))
+			}
+			measurement.OneOf = oneOf
+		}
+		clone[idx] = measurement
+	}
+	return clone
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
@@ -11,9 +11,11 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/edgelesssys/constellation/v2/internal/attestation/idkeydigest"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/measurements"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/variant"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/encoding"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v3"
@@ -118,3 +120,35 @@ func TestEmptyCertificateMarshalYAML(t *testing.T) {
 	require.NoError(err)
 	assert.YAMLEq(yamlCert, string(out))
 }
+
+func TestAttestationConfigClone(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	newAttestationConfig := func() *AttestationConfig {
+		cfg := Default().Attestation
+		vmpl := uint8(0)
+		cfg.AzureSEVSNP.VMPL = &vmpl
+		cfg.AzureSEVSNP.FirmwareSignerConfig.AcceptedKeyDigests = idkeydigest.List{{0x01, 0x02}}
+		cfg.AzureTDX.MRSeam = encoding.HexBytes{0x01, 0x02}
+		cfg.AzureTDX.XFAM = AttestationVersion[encoding.HexBytes]{Value: encoding.HexBytes{0x01, 0x02}}
+		return &cfg
+	}
+
+	cfg := newAttestationConfig()
+	clone := cfg.Clone()
+	require.Equal(cfg, clone)
+
+	clone.AWSSEVSNP.Measurements[4] = measurements.WithAllBytes(0xff, measurements.Enforce, measurements.PCRMeasurementLength)
+	clone.GCPSEVES.Measurements[9].Expected[0] = 0xff
+	*clone.AzureSEVSNP.VMPL = 3
+	clone.AzureSEVSNP.FirmwareSignerConfig.AcceptedKeyDigests[0][0] = 0xff
+	clone.AzureTDX.MRSeam[0] = 0xff
+	clone.AzureTDX.XFAM.Value[0] = 0xff
+	clone.QEMUVTPM = nil
+
+	assert.Equal(newAttestationConfig(), cfg)
+
+	var nilCfg *AttestationConfig
+	assert.Nil(nilCfg.Clone())
+}
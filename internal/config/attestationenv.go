@@ -0,0 +1,165 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation/measurements"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+)
+
+// applyAttestationEnvOverrides overrides fields of the configured attestation variants
+// with the values of the CONSTELL_ATTESTATION_* environment variables in environ.
+//
+// CONSTELL_ATTESTATION_<FIELD> replaces a field of the attestation config, with <FIELD> being the
+// field name in upper snake case, e.g., CONSTELL_ATTESTATION_BOOTLOADER_VERSION for bootloaderVersion.
+// CONSTELL_ATTESTATION_MEASUREMENT_<INDEX> replaces a single measurement and is applied after the field overrides.
+// Values are given in YAML syntax, like in the config file.
+//
+// The names of the applied environment variables are returned.
+func applyAttestationEnvOverrides(attestation *AttestationConfig, environ []string) ([]string, error) {
+	fieldOverrides := make(map[string]string)
+	measurementOverrides := make(map[string]string)
+	for _, env := range environ {
+		name, value, _ := strings.Cut(env, "=")
+		switch {
+		case strings.HasPrefix(name, constants.EnvVarAttestationMeasurementPrefix):
+			measurementOverrides[name] = value
+		case strings.HasPrefix(name, constants.EnvVarAttestationPrefix):
+			fieldOverrides[name] = value
+		}
+	}
+	if len(fieldOverrides) == 0 && len(measurementOverrides) == 0 {
+		return nil, nil
+	}
+
+	var variants []reflect.Value
+	attestationValue := reflect.ValueOf(attestation).Elem()
+	for i := 0; i < attestationValue.NumField(); i++ {
+		if field := attestationValue.Field(i); !field.IsNil() {
+			variants = append(variants, field.Elem())
+		}
+	}
+	if len(variants) == 0 {
+		return nil, errors.New("no attestation config to override")
+	}
+
+	var errs []error
+	for _, name := range sortedKeys(fieldOverrides) {
+		for _, v := range variants {
+			if err := overrideAttestationField(v, name, fieldOverrides[name]); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+		}
+	}
+	for _, name := range sortedKeys(measurementOverrides) {
+		for _, v := range variants {
+			if err := overrideMeasurement(v, name, measurementOverrides[name]); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return append(sortedKeys(fieldOverrides), sortedKeys(measurementOverrides)...), nil
+}
+
+// overrideAttestationField decodes value into the field of the attestation variant v matching the environment variable name.
+func overrideAttestationField(v reflect.Value, name, value string) error {
+	if strings.TrimSpace(value) == "" {
+		return errors.New("value must not be empty")
+	}
+	fieldName := strings.TrimPrefix(name, constants.EnvVarAttestationPrefix)
+	for i := 0; i < v.NumField(); i++ {
+		yamlName, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("yaml"), ",")
+		if yamlName == "" || yamlName == "-" || upperSnakeCase(yamlName) != fieldName {
+			continue
+		}
+		field := reflect.New(v.Field(i).Type())
+		if err := yaml.Unmarshal([]byte(value), field.Interface()); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", yamlName, err)
+		}
+		v.Field(i).Set(field.Elem())
+		return nil
+	}
+	return fmt.Errorf("attestation variant %s has no field matching %s", v.Type().Name(), fieldName)
+}
+
+// overrideMeasurement sets the measurement of the attestation variant v at the index given by the environment variable name.
+// The new measurement has to have the same length as the measurements of the config.
+func overrideMeasurement(v reflect.Value, name, value string) error {
+	if strings.TrimSpace(value) == "" {
+		return errors.New("value must not be empty")
+	}
+	rawIdx := strings.TrimPrefix(name, constants.EnvVarAttestationMeasurementPrefix)
+	idx, err := strconv.ParseUint(rawIdx, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid measurement index %q: %w", rawIdx, err)
+	}
+	var measurement measurements.Measurement
+	if err := yaml.Unmarshal([]byte(value), &measurement); err != nil {
+		return fmt.Errorf("invalid measurement: %w", err)
+	}
+
+	field := v.FieldByName("Measurements")
+	if !field.IsValid() || field.Type() != reflect.TypeOf(measurements.M{}) {
+		return fmt.Errorf("attestation variant %s has no measurements", v.Type().Name())
+	}
+	m := field.Interface().(measurements.M)
+	for otherIdx, other := range m {
+		if otherIdx == uint32(idx) || other.Any || measurement.Any {
+			continue
+		}
+		if other.Len() != measurement.Len() {
+			return fmt.Errorf("measurement must be %d bytes long, got %d bytes", other.Len(), measurement.Len())
+		}
+	}
+	if m == nil {
+		m = measurements.M{}
+	}
+	m[uint32(idx)] = measurement
+	field.Set(reflect.ValueOf(m))
+	return nil
+}
+
+// upperSnakeCase converts a camel case field name to upper snake case, e.g., "bootloaderVersion" to "BOOTLOADER_VERSION".
+// Acronyms are kept together, e.g., "minMicrocodeSVN" is converted to "MIN_MICROCODE_SVN".
+func upperSnakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prevLower := !unicode.IsUpper(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || nextLower {
+				b.WriteRune('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,183 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation/measurements"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+)
+
+func TestApplyAttestationEnvOverrides(t *testing.T) {
+	hex32 := func(b string) string {
+		return "\"" + strings.Repeat(b, measurements.PCRMeasurementLength) + "\""
+	}
+
+	testCases := map[string]struct {
+		attestation   AttestationConfig
+		environ       []string
+		wantOverrides []string
+		wantConfig    AttestationConfig
+		wantErr       bool
+	}{
+		"no overrides": {
+			attestation: AttestationConfig{GCPSEVES: &GCPSEVES{Measurements: measurements.M{
+				4: measurements.WithAllBytes(0x00, measurements.Enforce, measurements.PCRMeasurementLength),
+			}}},
+			environ: []string{"CONSTELL_NO_SPINNER=1", "PATH=/usr/bin"},
+			wantConfig: AttestationConfig{GCPSEVES: &GCPSEVES{Measurements: measurements.M{
+				4: measurements.WithAllBytes(0x00, measurements.Enforce, measurements.PCRMeasurementLength),
+			}}},
+		},
+		"single measurement": {
+			attestation: AttestationConfig{GCPSEVES: &GCPSEVES{Measurements: measurements.M{
+				4: measurements.WithAllBytes(0x00, measurements.Enforce, measurements.PCRMeasurementLength),
+				9: measurements.WithAllBytes(0x00, measurements.Enforce, measurements.PCRMeasurementLength),
+			}}},
+			environ:       []string{"CONSTELL_ATTESTATION_MEASUREMENT_4=" + hex32("11")},
+			wantOverrides: []string{"CONSTELL_ATTESTATION_MEASUREMENT_4"},
+			wantConfig: AttestationConfig{GCPSEVES: &GCPSEVES{Measurements: measurements.M{
+				4: measurements.WithAllBytes(0x11, measurements.Enforce, measurements.PCRMeasurementLength),
+				9: measurements.WithAllBytes(0x00, measurements.Enforce, measurements.PCRMeasurementLength),
+			}}},
+		},
+		"new measurement with warnOnly": {
+			attestation: AttestationConfig{GCPSEVES: &GCPSEVES{Measurements: measurements.M{
+				4: measurements.WithAllBytes(0x00, measurements.Enforce, measurements.PCRMeasurementLength),
+			}}},
+			environ:       []string{"CONSTELL_ATTESTATION_MEASUREMENT_15={expected: " + hex32("22") + ", warnOnly: true}"},
+			wantOverrides: []string{"CONSTELL_ATTESTATION_MEASUREMENT_15"},
+			wantConfig: AttestationConfig{GCPSEVES: &GCPSEVES{Measurements: measurements.M{
+				4:  measurements.WithAllBytes(0x00, measurements.Enforce, measurements.PCRMeasurementLength),
+				15: measurements.WithAllBytes(0x22, measurements.WarnOnly, measurements.PCRMeasurementLength),
+			}}},
+		},
+		"measurement is applied after field overrides": {
+			attestation: AttestationConfig{GCPSEVES: &GCPSEVES{}},
+			environ: []string{
+				"CONSTELL_ATTESTATION_MEASUREMENT_4=" + hex32("11"),
+				"CONSTELL_ATTESTATION_MEASUREMENTS={9: " + hex32("00") + "}",
+			},
+			wantOverrides: []string{"CONSTELL_ATTESTATION_MEASUREMENTS", "CONSTELL_ATTESTATION_MEASUREMENT_4"},
+			wantConfig: AttestationConfig{GCPSEVES: &GCPSEVES{Measurements: measurements.M{
+				4: measurements.WithAllBytes(0x11, measurements.Enforce, measurements.PCRMeasurementLength),
+				9: measurements.WithAllBytes(0x00, measurements.Enforce, measurements.PCRMeasurementLength),
+			}}},
+		},
+		"version field": {
+			attestation:   AttestationConfig{AzureSEVSNP: &AzureSEVSNP{}},
+			environ:       []string{"CONSTELL_ATTESTATION_BOOTLOADER_VERSION=3", "CONSTELL_ATTESTATION_TEE_VERSION=latest"},
+			wantOverrides: []string{"CONSTELL_ATTESTATION_BOOTLOADER_VERSION", "CONSTELL_ATTESTATION_TEE_VERSION"},
+			wantConfig: AttestationConfig{AzureSEVSNP: &AzureSEVSNP{
+				BootloaderVersion: AttestationVersion[uint8]{Value: 3},
+				TEEVersion:        AttestationVersion[uint8]{WantLatest: true},
+			}},
+		},
+		"pointer field": {
+			attestation:   AttestationConfig{AWSSEVSNP: &AWSSEVSNP{}},
+			environ:       []string{"CONSTELL_ATTESTATION_MIN_MICROCODE_SVN=5"},
+			wantOverrides: []string{"CONSTELL_ATTESTATION_MIN_MICROCODE_SVN"},
+			wantConfig:    AttestationConfig{AWSSEVSNP: &AWSSEVSNP{MinMicrocodeSVN: toPtr(uint8(5))}},
+		},
+		"malformed measurement": {
+			attestation: AttestationConfig{GCPSEVES: &GCPSEVES{}},
+			environ:     []string{"CONSTELL_ATTESTATION_MEASUREMENT_4=not-hex"},
+			wantErr:     true,
+		},
+		"measurement of wrong length": {
+			attestation: AttestationConfig{GCPSEVES: &GCPSEVES{Measurements: measurements.M{
+				4: measurements.WithAllBytes(0x00, measurements.Enforce, measurements.PCRMeasurementLength),
+			}}},
+			environ: []string{"CONSTELL_ATTESTATION_MEASUREMENT_9=\"" + strings.Repeat("11", measurements.TDXMeasurementLength) + "\""},
+			wantErr: true,
+		},
+		"invalid measurement index": {
+			attestation: AttestationConfig{GCPSEVES: &GCPSEVES{}},
+			environ:     []string{"CONSTELL_ATTESTATION_MEASUREMENT_X=" + hex32("11")},
+			wantErr:     true,
+		},
+		"empty value": {
+			attestation: AttestationConfig{GCPSEVES: &GCPSEVES{}},
+			environ:     []string{"CONSTELL_ATTESTATION_MEASUREMENT_4="},
+			wantErr:     true,
+		},
+		"malformed version": {
+			attestation: AttestationConfig{AzureSEVSNP: &AzureSEVSNP{}},
+			environ:     []string{"CONSTELL_ATTESTATION_BOOTLOADER_VERSION=newest"},
+			wantErr:     true,
+		},
+		"unknown field": {
+			attestation: AttestationConfig{GCPSEVES: &GCPSEVES{}},
+			environ:     []string{"CONSTELL_ATTESTATION_BOOTLOADER_VERSION=3"},
+			wantErr:     true,
+		},
+		"no attestation variant": {
+			environ: []string{"CONSTELL_ATTESTATION_MEASUREMENT_4=" + hex32("11")},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			overrides, err := applyAttestationEnvOverrides(&tc.attestation, tc.environ)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tc.wantOverrides, overrides)
+			assert.Equal(tc.wantConfig, tc.attestation)
+		})
+	}
+}
+
+func TestNewWithAttestationEnvOverride(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	conf := Default()
+	modifyConfigForAzureToPassValidate(conf)
+	fileHandler := file.NewHandler(afero.NewMemMapFs())
+	require.NoError(fileHandler.WriteYAML(constants.ConfigFilename, conf, file.OptNone))
+
+	t.Setenv(constants.EnvVarAttestationMeasurementPrefix+"0", "\""+strings.Repeat("aa", measurements.PCRMeasurementLength)+"\"")
+	got, err := New(fileHandler, constants.ConfigFilename, stubAttestationFetcher{}, false)
+	require.NoError(err)
+	assert.Equal(
+		measurements.WithAllBytes(0xaa, measurements.Enforce, measurements.PCRMeasurementLength),
+		got.GetAttestationConfig().GetMeasurements()[0],
+	)
+
+	t.Setenv(constants.EnvVarAttestationMeasurementPrefix+"0", "0xzz")
+	_, err = New(fileHandler, constants.ConfigFilename, stubAttestationFetcher{}, false)
+	assert.Error(err)
+}
+
+func TestUpperSnakeCase(t *testing.T) {
+	testCases := map[string]string{
+		"measurements":         "MEASUREMENTS",
+		"bootloaderVersion":    "BOOTLOADER_VERSION",
+		"amdRootKey":           "AMD_ROOT_KEY",
+		"minMicrocodeSVN":      "MIN_MICROCODE_SVN",
+		"vmpl":                 "VMPL",
+		"firmwareSignerConfig": "FIRMWARE_SIGNER_CONFIG",
+	}
+
+	for name, want := range testCases {
+		assert.Equal(t, want, upperSnakeCase(name), name)
+	}
+}
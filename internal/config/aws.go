@@ -124,5 +124,14 @@ func (c AWSNitroTPM) EqualTo(other AttestationCfg) (bool, error) {
 	if !ok {
 		return false, fmt.Errorf("cannot compare %T with %T", c, other)
 	}
+	if (c.NitroAttestation == nil) != (otherCfg.NitroAttestation == nil) {
+		return false, nil
+	}
+	if c.NitroAttestation != nil {
+		if !c.NitroAttestation.RootCertificate.Equal(otherCfg.NitroAttestation.RootCertificate) ||
+			!c.NitroAttestation.Measurements.EqualTo(otherCfg.NitroAttestation.Measurements) {
+			return false, nil
+		}
+	}
 	return c.Measurements.EqualTo(otherCfg.Measurements), nil
 }
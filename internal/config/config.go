@@ -65,9 +65,17 @@ type Config struct {
 	//   Machine image version used to create Constellation nodes.
 	Image string `yaml:"image" validate:"required,image_compatibility"`
 	// description: |
+	//   Optional release channel to track instead of a fixed image version. One of "stable", "beta", or "nightly".
+	//   If set, "constellation apply" upgrades the nodes to the latest image of the channel. The image field is still used to create the cluster.
+	Channel string `yaml:"channel,omitempty" validate:"omitempty,oneof=stable beta nightly"`
+	// description: |
 	//   Name of the cluster.
 	Name string `yaml:"name" validate:"valid_name,required"`
 	// description: |
+	//   Optional template for the base name of the cloud resources created for the cluster. Supports the placeholders {name} (name of the cluster) and {uid} (random ID generated on cluster creation).
+	//   {uid} is required. Defaults to "{name}-{uid}". Can't be changed after the cluster has been created.
+	NameTemplate string `yaml:"nameTemplate,omitempty" validate:"omitempty,name_template"`
+	// description: |
 	//   Kubernetes version to be installed into the cluster.
 	KubernetesVersion versions.ValidK8sVersion `yaml:"kubernetesVersion" validate:"required,supported_k8s_version"`
 	// description: |
@@ -92,6 +100,54 @@ type Config struct {
 	//   Additional tags that are applied to created resources.
 	Tags cloudprovider.Tags `yaml:"tags" validate:"omitempty"`
 	// description: |
+	//   Optional script passed to the nodes as user data on instance creation, e.g., to install a monitoring agent. Every node runs it as root on each boot.
+	//   Must start with "#!" followed by the absolute path of the interpreter, and must not exceed 16 KiB. Only supported on AWS, Azure, and GCP.
+	//   WARNING: User data is not covered by attestation. Its contents are not measured and not verified by Constellation.
+	UserData string `yaml:"userData" validate:"omitempty,user_data"`
+	// description: |
+	//   Preset of Kubernetes NetworkPolicies applied to the default namespace. One of "none", "baseline", or "restricted".
+	//   "baseline" only allows ingress traffic from pods in the same namespace. "restricted" additionally only allows egress traffic to pods in the same namespace and to the cluster DNS.
+	//   Policies of a previously applied preset are removed when the preset is changed. Defaults to "none".
+	NetworkPolicyPreset string `yaml:"networkPolicyPreset" validate:"omitempty,oneof=none baseline restricted"`
+	// description: |
+	//   Optional commands to run before and after individual phases of "constellation apply", keyed by phase name.
+	//   Valid phase names are the ones accepted by "--skip-phases". Hooks of skipped phases don't run.
+	PhaseHooks map[string]PhaseHook `yaml:"phaseHooks,omitempty" validate:"dive"`
+	// description: |
+	//   Optional OIDC issuer the Kubernetes API server accepts ID tokens from, e.g., to authenticate users with a corporate SSO.
+	//   This value will only be used during the first initialization of the Constellation and can't be changed afterwards.
+	OIDC *OIDCConfig `yaml:"oidc,omitempty" validate:"omitempty"`
+	// description: |
+	//   Optional names of Helm charts managed by Constellation that "constellation apply" doesn't install, upgrade, or back up, e.g., to manage the component yourself.
+	//   One of "coredns", "cert-manager", "constellation-csi", "aws-load-balancer-controller", "yawol", "cilium", "constellation-services", or "constellation-operators".
+	//   Disabling "cilium", "constellation-services", or "constellation-operators" additionally requires "unsafeAllowDisablingProtectedCharts".
+	DisabledCharts []string `yaml:"disabledCharts,omitempty"`
+	// description: |
+	//   DON'T USE IN PRODUCTION: allow disabling charts the security of the cluster depends on, like the CNI that encrypts the network traffic between nodes.
+	UnsafeAllowDisablingProtectedCharts bool `yaml:"unsafeAllowDisablingProtectedCharts,omitempty"`
+	// description: |
+	//   Optional time to wait for the resources of individual Helm charts to become ready, keyed by chart name, e.g., "cert-manager: 20m".
+	//   Overrides the timeout of "constellation apply" for charts that take longer to become ready. Valid chart names are the ones accepted by "disabledCharts".
+	ReadinessTimeouts map[string]string `yaml:"readinessTimeouts,omitempty"`
+	// description: |
+	//   Optional additional tolerations of the system pods of Constellation, e.g., to schedule them on nodes with custom taints.
+	//   Applied to the pods of the "constellation-services", "cert-manager", and "aws-load-balancer-controller" charts by "constellation apply".
+	Tolerations []Toleration `yaml:"tolerations,omitempty"`
+	// description: |
+	//   Optional remote backend Terraform stores the state of the cloud resources in, instead of the "constellation-terraform" directory of the workspace.
+	//   An existing state is copied to the backend on the next "constellation apply".
+	TerraformBackend *TerraformBackendConfig `yaml:"terraformBackend,omitempty"`
+	// description: |
+	//   DON'T USE IN PRODUCTION: optional SSH access to the nodes for debugging.
+	//   Holders of the authorized keys can read the memory and disks of the nodes, which breaks the confidentiality of the cluster.
+	//   This value will only be used during the first initialization of the Constellation and can't be changed afterwards.
+	DebugAccess *DebugAccessConfig `yaml:"debugAccess,omitempty"`
+	// description: |
+	//   Optional mirrors containerd pulls the images of the given registries from, e.g., to use a pull-through cache.
+	//   WARNING: Mirrors aren't covered by attestation. Only images pinned by digest are protected against a compromised mirror.
+	//   This value will only be used during the first initialization of the Constellation and can't be changed afterwards.
+	RegistryMirrors []RegistryMirror `yaml:"registryMirrors,omitempty"`
+	// description: |
 	//   Supported cloud providers and their specific configurations.
 	Provider ProviderConfig `yaml:"provider"`
 	// description: |
@@ -171,6 +227,21 @@ type AzureConfig struct {
 	// description: |
 	//   Use the specified Azure Marketplace image offering.
 	UseMarketplaceImage *bool `yaml:"useMarketplaceImage" validate:"omitempty"`
+	// description: |
+	//   URI of an Azure Managed HSM used to protect the master secret, e.g. "https://my-hsm.managedhsm.azure.net/". Optional. Requires masterKeyName.
+	MasterKeyHSMURI string `yaml:"masterKeyHSMURI,omitempty" validate:"required_with=MasterKeyName"`
+	// description: |
+	//   Name of the RSA key in the Managed HSM used to encrypt the master secret. Optional. Requires masterKeyHSMURI.
+	MasterKeyName string `yaml:"masterKeyName,omitempty" validate:"required_with=MasterKeyHSMURI"`
+	// description: |
+	//   Resource ID of an existing virtual network to attach the cluster's nodes to, e.g. "/subscriptions/<id>/resourceGroups/<rg>/providers/Microsoft.Network/virtualNetworks/<name>". Optional. Requires subnetID and subnetCIDR. If not set, a virtual network is created for the cluster.
+	VirtualNetworkID string `yaml:"virtualNetworkID,omitempty" validate:"required_with=SubnetID SubnetCIDR"`
+	// description: |
+	//   Resource ID of an existing subnet of the virtual network to attach the cluster's nodes to. Optional. Requires virtualNetworkID and subnetCIDR.
+	SubnetID string `yaml:"subnetID,omitempty" validate:"required_with=VirtualNetworkID SubnetCIDR"`
+	// description: |
+	//   CIDR range of the existing subnet, e.g. "10.9.0.0/16". Must be an address prefix of the subnet and must not overlap with serviceCIDR. Optional. Requires virtualNetworkID and subnetID.
+	SubnetCIDR string `yaml:"subnetCIDR,omitempty" validate:"required_with=VirtualNetworkID SubnetID,omitempty,cidrv4"`
 }
 
 // GCPConfig are GCP specific configuration values used by the CLI.
@@ -193,6 +264,18 @@ type GCPConfig struct {
 	// description: |
 	//   Use the specified GCP Marketplace image offering.
 	UseMarketplaceImage *bool `yaml:"useMarketplaceImage" validate:"omitempty"`
+	// description: |
+	//   ID of an existing network to attach the cluster's nodes to, e.g. "projects/<project>/global/networks/<name>". Optional. Requires subnetworkID and subnetworkCIDR. If not set, a network is created for the cluster.
+	NetworkID string `yaml:"networkID,omitempty" validate:"required_with=SubnetworkID SubnetworkCIDR"`
+	// description: |
+	//   ID of an existing subnetwork of the network to attach the cluster's nodes to, e.g. "projects/<project>/regions/<region>/subnetworks/<name>". The subnetwork must have a secondary IP range for the cluster's pods. Optional. Requires networkID and subnetworkCIDR.
+	SubnetworkID string `yaml:"subnetworkID,omitempty" validate:"required_with=NetworkID SubnetworkCIDR"`
+	// description: |
+	//   Primary CIDR range of the existing subnetwork, e.g. "192.168.178.0/24". Must not overlap with serviceCIDR. Optional. Requires networkID and subnetworkID.
+	SubnetworkCIDR string `yaml:"subnetworkCIDR,omitempty" validate:"required_with=NetworkID SubnetworkID,omitempty,cidrv4"`
+	// description: |
+	//   Cloud KMS key to encrypt the boot and state disks of the cluster's nodes with, e.g. "projects/<project>/locations/<location>/keyRings/<keyRing>/cryptoKeys/<key>". The key must be in the cluster's region or global. Optional. If not set, disks are encrypted with Google-managed keys. Can't be changed after the cluster was created.
+	DiskEncryptionKey string `yaml:"diskEncryptionKey,omitempty"`
 }
 
 // OpenStackConfig holds config information for OpenStack based Constellation deployments.
@@ -314,6 +397,90 @@ type NodeGroup struct {
 	InitialCount int `yaml:"initialCount" validate:"min=0"`
 }
 
+// PhaseHook defines commands to run before and after a phase of "constellation apply".
+type PhaseHook struct {
+	// description: |
+	//   Command run with "sh -c" before the phase. The apply is aborted if the command fails.
+	Pre string `yaml:"pre,omitempty"`
+	// description: |
+	//   Command run with "sh -c" after the phase finished successfully. The apply is aborted if the command fails.
+	Post string `yaml:"post,omitempty"`
+}
+
+// OIDCConfig configures an OIDC issuer for the authentication of users at the Kubernetes API server.
+type OIDCConfig struct {
+	// description: |
+	//   URL of the OIDC issuer. Must use HTTPS. The API server fetches the discovery document from "<issuerURL>/.well-known/openid-configuration".
+	IssuerURL string `yaml:"issuerURL" validate:"required,oidc_issuer_url"`
+	// description: |
+	//   Client ID that all ID tokens must be issued for.
+	ClientID string `yaml:"clientID" validate:"required"`
+	// description: |
+	//   Claim of the ID token used as user name. Defaults to "sub".
+	UsernameClaim string `yaml:"usernameClaim,omitempty"`
+	// description: |
+	//   Prefix prepended to user names to prevent clashes with other authentication methods.
+	UsernamePrefix string `yaml:"usernamePrefix,omitempty"`
+	// description: |
+	//   Claim of the ID token used as the user's groups.
+	GroupsClaim string `yaml:"groupsClaim,omitempty"`
+	// description: |
+	//   Prefix prepended to group names to prevent clashes with other authentication methods.
+	GroupsPrefix string `yaml:"groupsPrefix,omitempty"`
+}
+
+// TerraformBackendConfig configures a remote backend for the Terraform state.
+// See https://developer.hashicorp.com/terraform/language/settings/backends/configuration for details.
+type TerraformBackendConfig struct {
+	// description: |
+	//   Type of the backend. One of "s3", "gcs", or "azurerm".
+	Type string `yaml:"type"`
+	// description: |
+	//   Arguments of the backend, e.g., "bucket", "key", and "region" for "s3".
+	//   Required are "bucket", "key", and "region" for "s3", "bucket" for "gcs", and "storage_account_name", "container_name", and "key" for "azurerm".
+	Config map[string]string `yaml:"config"`
+}
+
+// Toleration allows the system pods of Constellation to be scheduled on nodes with matching taints.
+// See https://kubernetes.io/docs/concepts/scheduling-eviction/taint-and-toleration/ for details.
+type Toleration struct {
+	// description: |
+	//   Taint key the toleration applies to. An empty key matches all taint keys, and requires the operator "Exists".
+	Key string `yaml:"key,omitempty"`
+	// description: |
+	//   Relationship of the key to the value. One of "Equal" or "Exists". Defaults to "Equal".
+	Operator string `yaml:"operator,omitempty"`
+	// description: |
+	//   Taint value the toleration matches. Must be empty if the operator is "Exists".
+	Value string `yaml:"value,omitempty"`
+	// description: |
+	//   Taint effect to match. One of "NoSchedule", "PreferNoSchedule", or "NoExecute". An empty effect matches all effects.
+	Effect string `yaml:"effect,omitempty"`
+	// description: |
+	//   Optional time in seconds the pods stay bound to a node with a matching taint. Only valid with the effect "NoExecute".
+	TolerationSeconds *int64 `yaml:"tolerationSeconds,omitempty"`
+}
+
+// DebugAccessConfig configures SSH access to the nodes for debugging.
+type DebugAccessConfig struct {
+	// description: |
+	//   Enable SSH access to the nodes with the authorized keys.
+	Enabled bool `yaml:"enabled"`
+	// description: |
+	//   Public keys in OpenSSH authorized_keys format, e.g., "ssh-ed25519 AAAA... user@host", that may access the nodes.
+	AuthorizedKeys []string `yaml:"authorizedKeys"`
+}
+
+// RegistryMirror configures the mirrors of a container registry.
+type RegistryMirror struct {
+	// description: |
+	//   Host of the registry whose images are pulled from the mirrors, e.g., "docker.io" or "registry.k8s.io:443".
+	Registry string `yaml:"registry"`
+	// description: |
+	//   URLs of the mirrors, e.g., "https://mirror.example.com". The mirrors are tried in order before falling back to the registry.
+	Endpoints []string `yaml:"endpoints"`
+}
+
 // Default returns a struct with the default config.
 // IMPORTANT: Ensure that any state mutation is followed by a call to Validate() to ensure that the config is always in a valid state. Avoid usage outside of tests.
 func Default() *Config {
@@ -462,12 +629,44 @@ func (e *UnsupportedAppRegistrationError) Error() string {
 // 3. Read secrets from environment variables.
 // 4. Validate config. If `--force` is set the version validation will be disabled and any version combination is allowed.
 func New(fileHandler file.Handler, name string, fetcher attestationconfigapi.Fetcher, force bool) (*Config, error) {
-	// Read config file
-	c, err := fromFile(fileHandler, name)
+	return NewWithProfile(fileHandler, name, "", fetcher, force)
+}
+
+// NewWithProfile creates a new config like New, but merges the overlay file of the given profile into the config file
+// before the config is validated. See [ProfileFilename] for the name of the overlay file.
+// If profile is empty, NewWithProfile is equivalent to New.
+func NewWithProfile(fileHandler file.Handler, name, profile string, fetcher attestationconfigapi.Fetcher, force bool) (*Config, error) {
+	c, err := load(fileHandler, name, profile, fetcher)
+	if err != nil {
+		return c, err
+	}
+
+	// Read secrets from env-vars.
+	clientSecretValue := os.Getenv(constants.EnvVarAzureClientSecretValue)
+	if clientSecretValue != "" && c.Provider.Azure != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: the environment variable %s is no longer used %s", constants.EnvVarAzureClientSecretValue, appRegistrationErrStr)
+	}
+
+	return c, c.Validate(force)
+}
+
+// load reads the config file, merges the overlay of the profile, and replaces "latest" placeholders with the actual version numbers.
+func load(fileHandler file.Handler, name, profile string, fetcher attestationconfigapi.Fetcher) (*Config, error) {
+	// Read config file and the overlay of the profile
+	c, err := fromFileWithProfile(fileHandler, name, profile)
 	if err != nil {
 		return nil, err
 	}
 
+	// Override attestation config values with env-vars. These take precedence over the config file and the profile overlay.
+	overrides, err := applyAttestationEnvOverrides(&c.Attestation, os.Environ())
+	if err != nil {
+		return c, fmt.Errorf("overriding attestation config from environment: %w", err)
+	}
+	if len(overrides) > 0 {
+		fmt.Fprintf(os.Stderr, "WARNING: the attestation config is overridden by the environment variables %s\n", strings.Join(overrides, ", "))
+	}
+
 	// Replace "latest" placeholders for attestation version numbers with the actual latest version numbers from config API
 	if azure := c.Attestation.AzureSEVSNP; azure != nil {
 		if err := azure.FetchAndSetLatestVersionNumbers(context.Background(), fetcher); err != nil {
@@ -490,13 +689,7 @@ func New(fileHandler file.Handler, name string, fetcher attestationconfigapi.Fet
 		}
 	}
 
-	// Read secrets from env-vars.
-	clientSecretValue := os.Getenv(constants.EnvVarAzureClientSecretValue)
-	if clientSecretValue != "" && c.Provider.Azure != nil {
-		fmt.Fprintf(os.Stderr, "WARNING: the environment variable %s is no longer used %s", constants.EnvVarAzureClientSecretValue, appRegistrationErrStr)
-	}
-
-	return c, c.Validate(force)
+	return c, nil
 }
 
 // HasProvider checks whether the config contains the provider.
@@ -544,6 +737,33 @@ func (c *Config) UpdateMeasurements(newMeasurements measurements.M) {
 	}
 }
 
+// UpdateSEVSNPVersions sets the minimum TCB versions of all SEV-SNP attestation configs to the given versions.
+// The versions are fixed, so they aren't updated to the latest versions anymore.
+func (c *Config) UpdateSEVSNPVersions(versions attestationconfigapi.SEVSNPVersion) {
+	bootloader := AttestationVersion[uint8]{Value: versions.Bootloader}
+	tee := AttestationVersion[uint8]{Value: versions.TEE}
+	snp := AttestationVersion[uint8]{Value: versions.SNP}
+	microcode := AttestationVersion[uint8]{Value: versions.Microcode}
+	if c.Attestation.AWSSEVSNP != nil {
+		c.Attestation.AWSSEVSNP.BootloaderVersion = bootloader
+		c.Attestation.AWSSEVSNP.TEEVersion = tee
+		c.Attestation.AWSSEVSNP.SNPVersion = snp
+		c.Attestation.AWSSEVSNP.MicrocodeVersion = microcode
+	}
+	if c.Attestation.AzureSEVSNP != nil {
+		c.Attestation.AzureSEVSNP.BootloaderVersion = bootloader
+		c.Attestation.AzureSEVSNP.TEEVersion = tee
+		c.Attestation.AzureSEVSNP.SNPVersion = snp
+		c.Attestation.AzureSEVSNP.MicrocodeVersion = microcode
+	}
+	if c.Attestation.GCPSEVSNP != nil {
+		c.Attestation.GCPSEVSNP.BootloaderVersion = bootloader
+		c.Attestation.GCPSEVSNP.TEEVersion = tee
+		c.Attestation.GCPSEVSNP.SNPVersion = snp
+		c.Attestation.GCPSEVSNP.MicrocodeVersion = microcode
+	}
+}
+
 // RemoveProviderAndAttestationExcept calls RemoveProviderExcept and sets the default attestations for the provider (only used for convenience in tests).
 func (c *Config) RemoveProviderAndAttestationExcept(provider cloudprovider.Provider) {
 	c.RemoveProviderExcept(provider)
@@ -606,6 +826,11 @@ func (c *Config) IsDebugCluster() bool {
 	return false
 }
 
+// IsDebugAccessEnabled checks whether SSH access to the nodes for debugging is enabled.
+func (c *Config) IsDebugAccessEnabled() bool {
+	return c.DebugAccess != nil && c.DebugAccess.Enabled
+}
+
 // IsReleaseImage checks whether image name looks like a release image.
 func (c *Config) IsReleaseImage() bool {
 	return strings.HasPrefix(c.Image, "v")
@@ -727,12 +952,98 @@ func (c *Config) UseMarketplaceImage() bool {
 		(c.Provider.OpenStack != nil && c.Provider.OpenStack.Cloud == "stackit")
 }
 
+// ExistingNetworkCIDR returns the CIDR range of the existing network the cluster's nodes are attached to.
+// An empty string is returned if a network is created for the cluster.
+func (c *Config) ExistingNetworkCIDR() string {
+	switch {
+	case c.Provider.Azure != nil:
+		return c.Provider.Azure.SubnetCIDR
+	case c.Provider.GCP != nil:
+		return c.Provider.GCP.SubnetworkCIDR
+	default:
+		return ""
+	}
+}
+
 // Validate checks the config values and returns validation errors.
 func (c *Config) Validate(force bool) error {
+	validate, trans, err := c.newValidator(force)
+	if err != nil {
+		return err
+	}
+
+	if !force {
+		// Validating MicroserviceVersion separately is required since it is a custom type.
+		// The validation pkg we use does not allow accessing the field name during struct validation.
+		// Because of this we can't print the offending field name in the error message, resulting in
+		// suboptimal UX. Adding the field name to the struct validation of Semver would make it
+		// impossible to use Semver for other fields.
+		if err := ValidateMicroserviceVersion(constants.BinaryVersion(), c.MicroserviceVersion); err != nil {
+			msg := "microserviceVersion: " + msgFromCompatibilityError(err, constants.BinaryVersion().String(), c.MicroserviceVersion.String())
+			return &ValidationError{validationErrMsgs: []string{msg}}
+		}
+	}
+
+	for _, check := range c.checks() {
+		if err := check.validate(); err != nil {
+			return &ValidationError{validationErrMsgs: []string{err.Error()}}
+		}
+	}
+
+	err = validate.Struct(c)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return err
+	}
+
+	var validationErrMsgs []string
+	for _, e := range validationErrs {
+		validationErrMsgs = append(validationErrMsgs, e.Translate(trans))
+	}
+
+	return &ValidationError{validationErrMsgs: validationErrMsgs}
+}
+
+// configCheck is a validation of the config that can't be expressed as a struct tag.
+type configCheck struct {
+	// path is the dotted path of the config key the check refers to.
+	// Errors of the check are prefixed with the last element of the path.
+	path     string
+	validate func() error
+}
+
+// checks returns the validations of the config that can't be expressed as struct tags.
+// [Config.Validate] fails on the first error, while [Config.ValidationResult] collects the errors of all checks.
+func (c *Config) checks() []configCheck {
+	existingNetworkPath := "provider.gcp"
+	if c.Provider.Azure != nil {
+		existingNetworkPath = "provider.azure"
+	}
+	return []configCheck{
+		{path: "internalLoadBalancer", validate: c.validateInternalLoadBalancer},
+		{path: "userData", validate: c.validateUserDataProvider},
+		{path: existingNetworkPath, validate: c.validateExistingNetwork},
+		{path: "provider.gcp.diskEncryptionKey", validate: c.validateGCPDiskEncryptionKey},
+		{path: "attestation.awsNitroTPM.nitroAttestation", validate: c.validateNitroAttestation},
+		{path: "disabledCharts", validate: c.validateDisabledCharts},
+		{path: "readinessTimeouts", validate: c.validateReadinessTimeouts},
+		{path: "tolerations", validate: c.validateTolerations},
+		{path: "terraformBackend", validate: c.validateTerraformBackend},
+		{path: "debugAccess", validate: c.validateDebugAccess},
+		{path: "registryMirrors", validate: c.validateRegistryMirrors},
+	}
+}
+
+// newValidator returns a validator with all validations and translations of the config registered.
+func (c *Config) newValidator(force bool) (*validator.Validate, ut.Translator, error) {
 	trans := ut.New(en.New()).GetFallback()
 	validate := validator.New()
 	if err := en_translations.RegisterDefaultTranslations(validate, trans); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	// Register name function to return yaml name tag
@@ -748,45 +1059,45 @@ func (c *Config) Validate(force bool) error {
 
 	// Register AWS, Azure & GCP InstanceType validation error types
 	if err := validate.RegisterTranslation("instance_type", trans, c.registerTranslateInstanceTypeError, c.translateInstanceTypeError); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	// Register Provider validation error types
 	if err := validate.RegisterTranslation("no_provider", trans, registerNoProviderError, translateNoProviderError); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	if err := validate.RegisterTranslation("more_than_one_provider", trans, registerMoreThanOneProviderError, c.translateMoreThanOneProviderError); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	if err := validate.RegisterTranslation("no_placeholders", trans, registerContainsPlaceholderError, translateContainsPlaceholderError); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	if err := validate.RegisterTranslation("supported_k8s_version", trans, registerInvalidK8sVersionError, translateInvalidK8sVersionError); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	if err := validate.RegisterTranslation("image_compatibility", trans, registerImageCompatibilityError, translateImageCompatibilityError); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	if err := validate.RegisterTranslation("valid_name", trans, c.registerValidateNameError, c.translateValidateNameError); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	if err := validate.RegisterValidation("valid_name", c.validateName); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	if err := validate.RegisterValidation("no_placeholders", validateNoPlaceholder); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	// register custom validator with label supported_k8s_version to validate version based on available versionConfigs.
 	if err := validate.RegisterValidation("supported_k8s_version", c.validateK8sVersion); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	versionCompatibilityValidator := validateVersionCompatibility
@@ -794,22 +1105,22 @@ func (c *Config) Validate(force bool) error {
 		versionCompatibilityValidator = returnsTrue
 	}
 	if err := validate.RegisterValidation("image_compatibility", versionCompatibilityValidator); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	if err := validate.RegisterValidation("disk_type", c.validateStateDiskTypeField); err != nil {
-		return err
+		return nil, nil, err
 	}
 	if err := validate.RegisterTranslation("disk_type", trans, registerTranslateDiskTypeError, c.translateDiskTypeError); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	if err := validate.RegisterValidation("instance_type", c.validateInstanceType); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	if err := validate.RegisterValidation("deprecated", warnDeprecated); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	// Register provider validation
@@ -817,19 +1128,19 @@ func (c *Config) Validate(force bool) error {
 
 	// Register NodeGroup validation error types
 	if err := validate.RegisterTranslation("no_default_control_plane_group", trans, registerNoDefaultControlPlaneGroupError, translateNoDefaultControlPlaneGroupError); err != nil {
-		return err
+		return nil, nil, err
 	}
 	if err := validate.RegisterTranslation("no_default_worker_group", trans, registerNoDefaultWorkerGroupError, translateNoDefaultWorkerGroupError); err != nil {
-		return err
+		return nil, nil, err
 	}
 	if err := validate.RegisterTranslation("control_plane_group_initial_count", trans, registerControlPlaneGroupInitialCountError, translateControlPlaneGroupInitialCountError); err != nil {
-		return err
+		return nil, nil, err
 	}
 	if err := validate.RegisterTranslation("control_plane_group_role_mismatch", trans, registerControlPlaneGroupRoleMismatchError, translateControlPlaneGroupRoleMismatchError); err != nil {
-		return err
+		return nil, nil, err
 	}
 	if err := validate.RegisterTranslation("worker_group_role_mismatch", trans, registerWorkerGroupRoleMismatchError, translateWorkerGroupRoleMismatchError); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	// Register NodeGroup validation
@@ -837,68 +1148,56 @@ func (c *Config) Validate(force bool) error {
 
 	// Register Attestation validation error types
 	if err := validate.RegisterTranslation("no_attestation", trans, registerNoAttestationError, translateNoAttestationError); err != nil {
-		return err
+		return nil, nil, err
 	}
 	if err := validate.RegisterTranslation("more_than_one_attestation", trans, registerMoreThanOneAttestationError, c.translateMoreThanOneAttestationError); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	if err := validate.RegisterValidation("valid_zone", c.validateNodeGroupZoneField); err != nil {
-		return err
+		return nil, nil, err
 	}
 	if err := validate.RegisterValidation("aws_region", validateAWSRegionField); err != nil {
-		return err
+		return nil, nil, err
 	}
 	if err := validate.RegisterValidation("aws_zone", validateAWSZoneField); err != nil {
-		return err
+		return nil, nil, err
 	}
 	if err := validate.RegisterTranslation("valid_zone", trans, registerValidZoneError, c.translateValidZoneError); err != nil {
-		return err
+		return nil, nil, err
 	}
 	if err := validate.RegisterTranslation("aws_region", trans, registerAWSRegionError, translateAWSRegionError); err != nil {
-		return err
+		return nil, nil, err
 	}
 	if err := validate.RegisterTranslation("aws_zone", trans, registerAWSZoneError, translateAWSZoneError); err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	validate.RegisterStructValidation(validateMeasurement, measurements.Measurement{})
-	validate.RegisterStructValidation(validateAttestation, AttestationConfig{})
-
-	if !force {
-		// Validating MicroserviceVersion separately is required since it is a custom type.
-		// The validation pkg we use does not allow accessing the field name during struct validation.
-		// Because of this we can't print the offending field name in the error message, resulting in
-		// suboptimal UX. Adding the field name to the struct validation of Semver would make it
-		// impossible to use Semver for other fields.
-		if err := ValidateMicroserviceVersion(constants.BinaryVersion(), c.MicroserviceVersion); err != nil {
-			msg := "microserviceVersion: " + msgFromCompatibilityError(err, constants.BinaryVersion().String(), c.MicroserviceVersion.String())
-			return &ValidationError{validationErrMsgs: []string{msg}}
-		}
+	if err := validate.RegisterValidation("name_template", c.validateNameTemplateField); err != nil {
+		return nil, nil, err
 	}
-
-	if c.InternalLoadBalancer {
-		if c.GetProvider() != cloudprovider.AWS && c.GetProvider() != cloudprovider.GCP {
-			return &ValidationError{validationErrMsgs: []string{"internalLoadBalancer is only supported for AWS and GCP"}}
-		}
+	if err := validate.RegisterTranslation("name_template", trans, registerNameTemplateError, c.translateNameTemplateError); err != nil {
+		return nil, nil, err
 	}
 
-	err := validate.Struct(c)
-	if err == nil {
-		return nil
+	if err := validate.RegisterValidation("user_data", validateUserDataField); err != nil {
+		return nil, nil, err
 	}
-
-	var validationErrs validator.ValidationErrors
-	if !errors.As(err, &validationErrs) {
-		return err
+	if err := validate.RegisterTranslation("user_data", trans, registerUserDataError, translateUserDataError); err != nil {
+		return nil, nil, err
 	}
 
-	var validationErrMsgs []string
-	for _, e := range validationErrs {
-		validationErrMsgs = append(validationErrMsgs, e.Translate(trans))
+	if err := validate.RegisterValidation("oidc_issuer_url", validateOIDCIssuerURLField); err != nil {
+		return nil, nil, err
+	}
+	if err := validate.RegisterTranslation("oidc_issuer_url", trans, registerOIDCIssuerURLError, translateOIDCIssuerURLError); err != nil {
+		return nil, nil, err
 	}
 
-	return &ValidationError{validationErrMsgs: validationErrMsgs}
+	validate.RegisterStructValidation(validateMeasurement, measurements.Measurement{})
+	validate.RegisterStructValidation(validateAttestation, AttestationConfig{})
+
+	return validate, trans, nil
 }
 
 // WithOpenStackProviderDefaults fills the default values for the specific OpenStack provider.
@@ -1013,6 +1312,12 @@ type GCPSEVSNP struct {
 	// description: |
 	//   AMD Signing Key certificate used to verify the SEV-SNP VCEK / VLEK certificate.
 	AMDSigningKey Certificate `json:"amdSigningKey,omitempty" yaml:"amdSigningKey,omitempty"`
+	// description: |
+	//   Optional lowest acceptable microcode SVN of the reported TCB. Checked independently of microcodeVersion.
+	MinMicrocodeSVN *uint8 `json:"minMicrocodeSVN,omitempty" yaml:"minMicrocodeSVN,omitempty"`
+	// description: |
+	//   Optional Virtual Machine Privilege Level (VMPL) the attestation report must be issued from. Defaults to 0.
+	VMPL *uint8 `json:"vmpl,omitempty" yaml:"vmpl,omitempty" validate:"omitempty,max=3"`
 }
 
 // QEMUVTPM is the configuration for QEMU vTPM attestation.
@@ -1100,6 +1405,12 @@ type AWSSEVSNP struct {
 	// description: |
 	//   AMD Signing Key certificate used to verify the SEV-SNP VCEK / VLEK certificate.
 	AMDSigningKey Certificate `json:"amdSigningKey,omitempty" yaml:"amdSigningKey,omitempty"`
+	// description: |
+	//   Optional lowest acceptable microcode SVN of the reported TCB. Checked independently of microcodeVersion.
+	MinMicrocodeSVN *uint8 `json:"minMicrocodeSVN,omitempty" yaml:"minMicrocodeSVN,omitempty"`
+	// description: |
+	//   Optional Virtual Machine Privilege Level (VMPL) the attestation report must be issued from. Defaults to 0.
+	VMPL *uint8 `json:"vmpl,omitempty" yaml:"vmpl,omitempty" validate:"omitempty,max=3"`
 }
 
 // AWSNitroTPM is the configuration for AWS Nitro TPM attestation.
@@ -1107,6 +1418,20 @@ type AWSNitroTPM struct {
 	// description: |
 	//   Expected TPM measurements.
 	Measurements measurements.M `json:"measurements" yaml:"measurements" validate:"required,no_placeholders"`
+	// description: |
+	//   Optional validation of the attestation document the Nitro Hypervisor signs for the NitroTPM of a node.
+	//   If set, nodes without a valid Nitro attestation document are rejected.
+	NitroAttestation *NitroAttestation `json:"nitroAttestation,omitempty" yaml:"nitroAttestation,omitempty" validate:"omitempty"`
+}
+
+// NitroAttestation is the configuration for validating the Nitro attestation document of AWS NitroTPM nodes.
+type NitroAttestation struct {
+	// description: |
+	//   Root certificate of the AWS Nitro attestation PKI, as published by AWS. The certificate chain of the Nitro attestation document must end in this certificate.
+	RootCertificate Certificate `json:"rootCertificate" yaml:"rootCertificate"`
+	// description: |
+	//   Expected SHA-384 PCR values of the Nitro attestation document.
+	Measurements measurements.M `json:"measurements" yaml:"measurements" validate:"required,no_placeholders"`
 }
 
 // AzureSEVSNP is the configuration for Azure SEV-SNP attestation.
@@ -1135,6 +1460,16 @@ type AzureSEVSNP struct {
 	// description: |
 	//   AMD Signing Key certificate used to verify the SEV-SNP VCEK / VLEK certificate.
 	AMDSigningKey Certificate `json:"amdSigningKey,omitempty" yaml:"amdSigningKey,omitempty"`
+	// description: |
+	//   Optional lowest acceptable microcode SVN of the reported TCB. Checked independently of microcodeVersion.
+	MinMicrocodeSVN *uint8 `json:"minMicrocodeSVN,omitempty" yaml:"minMicrocodeSVN,omitempty"`
+	// description: |
+	//   Optional Virtual Machine Privilege Level (VMPL) the attestation report must be issued from. Defaults to 0.
+	VMPL *uint8 `json:"vmpl,omitempty" yaml:"vmpl,omitempty" validate:"omitempty,max=3"`
+	// description: |
+	//   Optional source of the VCEK certificate to verify the attestation report with first: "THIM" or "KDS".
+	//   If set, the VCEK certificate from the other source is tried if verification fails.
+	PrimaryVCEKSource string `json:"primaryVCEKSource,omitempty" yaml:"primaryVCEKSource,omitempty" validate:"omitempty,oneof=THIM KDS"`
 }
 
 // AzureTrustedLaunch is the configuration for Azure Trusted Launch attestation.
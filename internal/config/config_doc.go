@@ -20,6 +20,12 @@ var (
 	QEMUConfigDoc                      encoder.Doc
 	AttestationConfigDoc               encoder.Doc
 	NodeGroupDoc                       encoder.Doc
+	PhaseHookDoc                       encoder.Doc
+	OIDCConfigDoc                      encoder.Doc
+	TolerationDoc                      encoder.Doc
+	TerraformBackendConfigDoc          encoder.Doc
+	DebugAccessConfigDoc               encoder.Doc
+	RegistryMirrorDoc                  encoder.Doc
 	UnsupportedAppRegistrationErrorDoc encoder.Doc
 	SNPFirmwareSignerConfigDoc         encoder.Doc
 	GCPSEVESDoc                        encoder.Doc
@@ -28,6 +34,7 @@ var (
 	QEMUTDXDoc                         encoder.Doc
 	AWSSEVSNPDoc                       encoder.Doc
 	AWSNitroTPMDoc                     encoder.Doc
+	NitroAttestationDoc                encoder.Doc
 	AzureSEVSNPDoc                     encoder.Doc
 	AzureTrustedLaunchDoc              encoder.Doc
 	AzureTDXDoc                        encoder.Doc
@@ -37,7 +44,7 @@ func init() {
 	ConfigDoc.Type = "Config"
 	ConfigDoc.Comments[encoder.LineComment] = "Config defines configuration used by CLI."
 	ConfigDoc.Description = "Config defines configuration used by CLI."
-	ConfigDoc.Fields = make([]encoder.Doc, 13)
+	ConfigDoc.Fields = make([]encoder.Doc, 26)
 	ConfigDoc.Fields[0].Name = "version"
 	ConfigDoc.Fields[0].Type = "string"
 	ConfigDoc.Fields[0].Note = ""
@@ -48,61 +55,126 @@ func init() {
 	ConfigDoc.Fields[1].Note = ""
 	ConfigDoc.Fields[1].Description = "Machine image version used to create Constellation nodes."
 	ConfigDoc.Fields[1].Comments[encoder.LineComment] = "Machine image version used to create Constellation nodes."
-	ConfigDoc.Fields[2].Name = "name"
+	ConfigDoc.Fields[2].Name = "channel"
 	ConfigDoc.Fields[2].Type = "string"
 	ConfigDoc.Fields[2].Note = ""
-	ConfigDoc.Fields[2].Description = "Name of the cluster."
-	ConfigDoc.Fields[2].Comments[encoder.LineComment] = "Name of the cluster."
-	ConfigDoc.Fields[3].Name = "kubernetesVersion"
-	ConfigDoc.Fields[3].Type = "ValidK8sVersion"
+	ConfigDoc.Fields[2].Description = "Optional release channel to track instead of a fixed image version. One of \"stable\", \"beta\", or \"nightly\".\nIf set, \"constellation apply\" upgrades the nodes to the latest image of the channel. The image field is still used to create the cluster."
+	ConfigDoc.Fields[2].Comments[encoder.LineComment] = "Optional release channel to track instead of a fixed image version. One of \"stable\", \"beta\", or \"nightly\"."
+	ConfigDoc.Fields[3].Name = "name"
+	ConfigDoc.Fields[3].Type = "string"
 	ConfigDoc.Fields[3].Note = ""
-	ConfigDoc.Fields[3].Description = "Kubernetes version to be installed into the cluster."
-	ConfigDoc.Fields[3].Comments[encoder.LineComment] = "Kubernetes version to be installed into the cluster."
-	ConfigDoc.Fields[4].Name = "microserviceVersion"
-	ConfigDoc.Fields[4].Type = "Semver"
+	ConfigDoc.Fields[3].Description = "Name of the cluster."
+	ConfigDoc.Fields[3].Comments[encoder.LineComment] = "Name of the cluster."
+	ConfigDoc.Fields[4].Name = "nameTemplate"
+	ConfigDoc.Fields[4].Type = "string"
 	ConfigDoc.Fields[4].Note = ""
-	ConfigDoc.Fields[4].Description = "Microservice version to be installed into the cluster. Defaults to the version of the CLI."
-	ConfigDoc.Fields[4].Comments[encoder.LineComment] = "Microservice version to be installed into the cluster. Defaults to the version of the CLI."
-	ConfigDoc.Fields[5].Name = "debugCluster"
-	ConfigDoc.Fields[5].Type = "bool"
+	ConfigDoc.Fields[4].Description = "Optional template for the base name of the cloud resources created for the cluster. Supports the placeholders {name} (name of the cluster) and {uid} (random ID generated on cluster creation).\n{uid} is required. Defaults to \"{name}-{uid}\". Can't be changed after the cluster has been created."
+	ConfigDoc.Fields[4].Comments[encoder.LineComment] = "Optional template for the base name of the cloud resources created for the cluster. Supports the placeholders {name} (name of the cluster) and {uid} (random ID generated on cluster creation)."
+	ConfigDoc.Fields[5].Name = "kubernetesVersion"
+	ConfigDoc.Fields[5].Type = "ValidK8sVersion"
 	ConfigDoc.Fields[5].Note = ""
-	ConfigDoc.Fields[5].Description = "DON'T USE IN PRODUCTION: enable debug mode and use debug images."
-	ConfigDoc.Fields[5].Comments[encoder.LineComment] = "DON'T USE IN PRODUCTION: enable debug mode and use debug images."
-	ConfigDoc.Fields[6].Name = "customEndpoint"
-	ConfigDoc.Fields[6].Type = "string"
+	ConfigDoc.Fields[5].Description = "Kubernetes version to be installed into the cluster."
+	ConfigDoc.Fields[5].Comments[encoder.LineComment] = "Kubernetes version to be installed into the cluster."
+	ConfigDoc.Fields[6].Name = "microserviceVersion"
+	ConfigDoc.Fields[6].Type = "Semver"
 	ConfigDoc.Fields[6].Note = ""
-	ConfigDoc.Fields[6].Description = "Optional custom endpoint (DNS name) for the Constellation API server.\nThis can be used to point a custom dns name at the Constellation API server\nand is added to the Subject Alternative Name (SAN) field of the TLS certificate used by the API server.\nA fallback to DNS name is always available."
-	ConfigDoc.Fields[6].Comments[encoder.LineComment] = "Optional custom endpoint (DNS name) for the Constellation API server."
-	ConfigDoc.Fields[7].Name = "internalLoadBalancer"
+	ConfigDoc.Fields[6].Description = "Microservice version to be installed into the cluster. Defaults to the version of the CLI."
+	ConfigDoc.Fields[6].Comments[encoder.LineComment] = "Microservice version to be installed into the cluster. Defaults to the version of the CLI."
+	ConfigDoc.Fields[7].Name = "debugCluster"
 	ConfigDoc.Fields[7].Type = "bool"
 	ConfigDoc.Fields[7].Note = ""
-	ConfigDoc.Fields[7].Description = "Flag to enable/disable the internal load balancer. If enabled, the Constellation is only accessible from within the VPC."
-	ConfigDoc.Fields[7].Comments[encoder.LineComment] = "Flag to enable/disable the internal load balancer. If enabled, the Constellation is only accessible from within the VPC."
-	ConfigDoc.Fields[8].Name = "serviceCIDR"
+	ConfigDoc.Fields[7].Description = "DON'T USE IN PRODUCTION: enable debug mode and use debug images."
+	ConfigDoc.Fields[7].Comments[encoder.LineComment] = "DON'T USE IN PRODUCTION: enable debug mode and use debug images."
+	ConfigDoc.Fields[8].Name = "customEndpoint"
 	ConfigDoc.Fields[8].Type = "string"
 	ConfigDoc.Fields[8].Note = ""
-	ConfigDoc.Fields[8].Description = "The Kubernetes Service CIDR to be used for the cluster. This value will only be used during the first initialization of the Constellation."
-	ConfigDoc.Fields[8].Comments[encoder.LineComment] = "The Kubernetes Service CIDR to be used for the cluster. This value will only be used during the first initialization of the Constellation."
-	ConfigDoc.Fields[9].Name = "tags"
-	ConfigDoc.Fields[9].Type = "Tags"
+	ConfigDoc.Fields[8].Description = "Optional custom endpoint (DNS name) for the Constellation API server.\nThis can be used to point a custom dns name at the Constellation API server\nand is added to the Subject Alternative Name (SAN) field of the TLS certificate used by the API server.\nA fallback to DNS name is always available."
+	ConfigDoc.Fields[8].Comments[encoder.LineComment] = "Optional custom endpoint (DNS name) for the Constellation API server."
+	ConfigDoc.Fields[9].Name = "internalLoadBalancer"
+	ConfigDoc.Fields[9].Type = "bool"
 	ConfigDoc.Fields[9].Note = ""
-	ConfigDoc.Fields[9].Description = "Additional tags that are applied to created resources."
-	ConfigDoc.Fields[9].Comments[encoder.LineComment] = "Additional tags that are applied to created resources."
-	ConfigDoc.Fields[10].Name = "provider"
-	ConfigDoc.Fields[10].Type = "ProviderConfig"
+	ConfigDoc.Fields[9].Description = "Flag to enable/disable the internal load balancer. If enabled, the Constellation is only accessible from within the VPC."
+	ConfigDoc.Fields[9].Comments[encoder.LineComment] = "Flag to enable/disable the internal load balancer. If enabled, the Constellation is only accessible from within the VPC."
+	ConfigDoc.Fields[10].Name = "serviceCIDR"
+	ConfigDoc.Fields[10].Type = "string"
 	ConfigDoc.Fields[10].Note = ""
-	ConfigDoc.Fields[10].Description = "Supported cloud providers and their specific configurations."
-	ConfigDoc.Fields[10].Comments[encoder.LineComment] = "Supported cloud providers and their specific configurations."
-	ConfigDoc.Fields[11].Name = "nodeGroups"
-	ConfigDoc.Fields[11].Type = "map[string]NodeGroup"
+	ConfigDoc.Fields[10].Description = "The Kubernetes Service CIDR to be used for the cluster. This value will only be used during the first initialization of the Constellation."
+	ConfigDoc.Fields[10].Comments[encoder.LineComment] = "The Kubernetes Service CIDR to be used for the cluster. This value will only be used during the first initialization of the Constellation."
+	ConfigDoc.Fields[11].Name = "tags"
+	ConfigDoc.Fields[11].Type = "Tags"
 	ConfigDoc.Fields[11].Note = ""
-	ConfigDoc.Fields[11].Description = "Node groups to be created in the cluster."
-	ConfigDoc.Fields[11].Comments[encoder.LineComment] = "Node groups to be created in the cluster."
-	ConfigDoc.Fields[12].Name = "attestation"
-	ConfigDoc.Fields[12].Type = "AttestationConfig"
+	ConfigDoc.Fields[11].Description = "Additional tags that are applied to created resources."
+	ConfigDoc.Fields[11].Comments[encoder.LineComment] = "Additional tags that are applied to created resources."
+	ConfigDoc.Fields[12].Name = "userData"
+	ConfigDoc.Fields[12].Type = "string"
 	ConfigDoc.Fields[12].Note = ""
-	ConfigDoc.Fields[12].Description = "Configuration for attestation validation. This configuration provides sensible defaults for the Constellation version it was created for.\nSee the docs for an overview on attestation: https://docs.edgeless.systems/constellation/architecture/attestation"
-	ConfigDoc.Fields[12].Comments[encoder.LineComment] = "Configuration for attestation validation. This configuration provides sensible defaults for the Constellation version it was created for.\nSee the docs for an overview on attestation: https://docs.edgeless.systems/constellation/architecture/attestation"
+	ConfigDoc.Fields[12].Description = "Optional script passed to the nodes as user data on instance creation, e.g., to install a monitoring agent. Every node runs it as root on each boot.\nMust start with \"#!\" followed by the absolute path of the interpreter, and must not exceed 16 KiB. Only supported on AWS, Azure, and GCP.\nWARNING: User data is not covered by attestation. Its contents are not measured and not verified by Constellation."
+	ConfigDoc.Fields[12].Comments[encoder.LineComment] = "Optional script passed to the nodes as user data on instance creation, e.g., to install a monitoring agent. Every node runs it as root on each boot."
+	ConfigDoc.Fields[13].Name = "networkPolicyPreset"
+	ConfigDoc.Fields[13].Type = "string"
+	ConfigDoc.Fields[13].Note = ""
+	ConfigDoc.Fields[13].Description = "Preset of Kubernetes NetworkPolicies applied to the default namespace. One of \"none\", \"baseline\", or \"restricted\".\n\"baseline\" only allows ingress traffic from pods in the same namespace. \"restricted\" additionally only allows egress traffic to pods in the same namespace and to the cluster DNS.\nPolicies of a previously applied preset are removed when the preset is changed. Defaults to \"none\"."
+	ConfigDoc.Fields[13].Comments[encoder.LineComment] = "Preset of Kubernetes NetworkPolicies applied to the default namespace. One of \"none\", \"baseline\", or \"restricted\"."
+	ConfigDoc.Fields[14].Name = "phaseHooks"
+	ConfigDoc.Fields[14].Type = "map[string]PhaseHook"
+	ConfigDoc.Fields[14].Note = ""
+	ConfigDoc.Fields[14].Description = "Optional commands to run before and after individual phases of \"constellation apply\", keyed by phase name.\nValid phase names are the ones accepted by \"--skip-phases\". Hooks of skipped phases don't run."
+	ConfigDoc.Fields[14].Comments[encoder.LineComment] = "Optional commands to run before and after individual phases of \"constellation apply\", keyed by phase name."
+	ConfigDoc.Fields[15].Name = "oidc"
+	ConfigDoc.Fields[15].Type = "OIDCConfig"
+	ConfigDoc.Fields[15].Note = ""
+	ConfigDoc.Fields[15].Description = "Optional OIDC issuer the Kubernetes API server accepts ID tokens from, e.g., to authenticate users with a corporate SSO.\nThis value will only be used during the first initialization of the Constellation and can't be changed afterwards."
+	ConfigDoc.Fields[15].Comments[encoder.LineComment] = "Optional OIDC issuer the Kubernetes API server accepts ID tokens from, e.g., to authenticate users with a corporate SSO."
+	ConfigDoc.Fields[16].Name = "disabledCharts"
+	ConfigDoc.Fields[16].Type = "[]string"
+	ConfigDoc.Fields[16].Note = ""
+	ConfigDoc.Fields[16].Description = "Optional names of Helm charts managed by Constellation that \"constellation apply\" doesn't install, upgrade, or back up, e.g., to manage the component yourself.\nOne of \"coredns\", \"cert-manager\", \"constellation-csi\", \"aws-load-balancer-controller\", \"yawol\", \"cilium\", \"constellation-services\", or \"constellation-operators\".\nDisabling \"cilium\", \"constellation-services\", or \"constellation-operators\" additionally requires \"unsafeAllowDisablingProtectedCharts\"."
+	ConfigDoc.Fields[16].Comments[encoder.LineComment] = "Optional names of Helm charts managed by Constellation that \"constellation apply\" doesn't install, upgrade, or back up, e.g., to manage the component yourself."
+	ConfigDoc.Fields[17].Name = "unsafeAllowDisablingProtectedCharts"
+	ConfigDoc.Fields[17].Type = "bool"
+	ConfigDoc.Fields[17].Note = ""
+	ConfigDoc.Fields[17].Description = "DON'T USE IN PRODUCTION: allow disabling charts the security of the cluster depends on, like the CNI that encrypts the network traffic between nodes."
+	ConfigDoc.Fields[17].Comments[encoder.LineComment] = "DON'T USE IN PRODUCTION: allow disabling charts the security of the cluster depends on, like the CNI that encrypts the network traffic between nodes."
+	ConfigDoc.Fields[18].Name = "readinessTimeouts"
+	ConfigDoc.Fields[18].Type = "map[string]string"
+	ConfigDoc.Fields[18].Note = ""
+	ConfigDoc.Fields[18].Description = "Optional time to wait for the resources of individual Helm charts to become ready, keyed by chart name, e.g., \"cert-manager: 20m\".\nOverrides the timeout of \"constellation apply\" for charts that take longer to become ready. Valid chart names are the ones accepted by \"disabledCharts\"."
+	ConfigDoc.Fields[18].Comments[encoder.LineComment] = "Optional time to wait for the resources of individual Helm charts to become ready, keyed by chart name, e.g., \"cert-manager: 20m\"."
+	ConfigDoc.Fields[19].Name = "tolerations"
+	ConfigDoc.Fields[19].Type = "[]Toleration"
+	ConfigDoc.Fields[19].Note = ""
+	ConfigDoc.Fields[19].Description = "Optional additional tolerations of the system pods of Constellation, e.g., to schedule them on nodes with custom taints.\nApplied to the pods of the \"constellation-services\", \"cert-manager\", and \"aws-load-balancer-controller\" charts by \"constellation apply\"."
+	ConfigDoc.Fields[19].Comments[encoder.LineComment] = "Optional additional tolerations of the system pods of Constellation, e.g., to schedule them on nodes with custom taints."
+	ConfigDoc.Fields[20].Name = "terraformBackend"
+	ConfigDoc.Fields[20].Type = "TerraformBackendConfig"
+	ConfigDoc.Fields[20].Note = ""
+	ConfigDoc.Fields[20].Description = "Optional remote backend Terraform stores the state of the cloud resources in, instead of the \"constellation-terraform\" directory of the workspace.\nAn existing state is copied to the backend on the next \"constellation apply\"."
+	ConfigDoc.Fields[20].Comments[encoder.LineComment] = "Optional remote backend Terraform stores the state of the cloud resources in, instead of the \"constellation-terraform\" directory of the workspace."
+	ConfigDoc.Fields[21].Name = "debugAccess"
+	ConfigDoc.Fields[21].Type = "DebugAccessConfig"
+	ConfigDoc.Fields[21].Note = ""
+	ConfigDoc.Fields[21].Description = "DON'T USE IN PRODUCTION: optional SSH access to the nodes for debugging.\nHolders of the authorized keys can read the memory and disks of the nodes, which breaks the confidentiality of the cluster.\nThis value will only be used during the first initialization of the Constellation and can't be changed afterwards."
+	ConfigDoc.Fields[21].Comments[encoder.LineComment] = "DON'T USE IN PRODUCTION: optional SSH access to the nodes for debugging."
+	ConfigDoc.Fields[22].Name = "registryMirrors"
+	ConfigDoc.Fields[22].Type = "[]RegistryMirror"
+	ConfigDoc.Fields[22].Note = ""
+	ConfigDoc.Fields[22].Description = "Optional mirrors containerd pulls the images of the given registries from, e.g., to use a pull-through cache.\nWARNING: Mirrors aren't covered by attestation. Only images pinned by digest are protected against a compromised mirror.\nThis value will only be used during the first initialization of the Constellation and can't be changed afterwards."
+	ConfigDoc.Fields[22].Comments[encoder.LineComment] = "Optional mirrors containerd pulls the images of the given registries from, e.g., to use a pull-through cache."
+	ConfigDoc.Fields[23].Name = "provider"
+	ConfigDoc.Fields[23].Type = "ProviderConfig"
+	ConfigDoc.Fields[23].Note = ""
+	ConfigDoc.Fields[23].Description = "Supported cloud providers and their specific configurations."
+	ConfigDoc.Fields[23].Comments[encoder.LineComment] = "Supported cloud providers and their specific configurations."
+	ConfigDoc.Fields[24].Name = "nodeGroups"
+	ConfigDoc.Fields[24].Type = "map[string]NodeGroup"
+	ConfigDoc.Fields[24].Note = ""
+	ConfigDoc.Fields[24].Description = "Node groups to be created in the cluster."
+	ConfigDoc.Fields[24].Comments[encoder.LineComment] = "Node groups to be created in the cluster."
+	ConfigDoc.Fields[25].Name = "attestation"
+	ConfigDoc.Fields[25].Type = "AttestationConfig"
+	ConfigDoc.Fields[25].Note = ""
+	ConfigDoc.Fields[25].Description = "Configuration for attestation validation. This configuration provides sensible defaults for the Constellation version it was created for.\nSee the docs for an overview on attestation: https://docs.edgeless.systems/constellation/architecture/attestation"
+	ConfigDoc.Fields[25].Comments[encoder.LineComment] = "Configuration for attestation validation. This configuration provides sensible defaults for the Constellation version it was created for.\nSee the docs for an overview on attestation: https://docs.edgeless.systems/constellation/architecture/attestation"
 
 	ProviderConfigDoc.Type = "ProviderConfig"
 	ProviderConfigDoc.Comments[encoder.LineComment] = "ProviderConfig are cloud-provider specific configuration values used by the CLI."
@@ -190,7 +262,7 @@ func init() {
 			FieldName: "azure",
 		},
 	}
-	AzureConfigDoc.Fields = make([]encoder.Doc, 8)
+	AzureConfigDoc.Fields = make([]encoder.Doc, 13)
 	AzureConfigDoc.Fields[0].Name = "subscription"
 	AzureConfigDoc.Fields[0].Type = "string"
 	AzureConfigDoc.Fields[0].Note = ""
@@ -231,6 +303,31 @@ func init() {
 	AzureConfigDoc.Fields[7].Note = ""
 	AzureConfigDoc.Fields[7].Description = "Use the specified Azure Marketplace image offering."
 	AzureConfigDoc.Fields[7].Comments[encoder.LineComment] = "Use the specified Azure Marketplace image offering."
+	AzureConfigDoc.Fields[8].Name = "masterKeyHSMURI"
+	AzureConfigDoc.Fields[8].Type = "string"
+	AzureConfigDoc.Fields[8].Note = ""
+	AzureConfigDoc.Fields[8].Description = "URI of an Azure Managed HSM used to protect the master secret, e.g. \"https://my-hsm.managedhsm.azure.net/\". Optional. Requires masterKeyName."
+	AzureConfigDoc.Fields[8].Comments[encoder.LineComment] = "URI of an Azure Managed HSM used to protect the master secret, e.g. \"https://my-hsm.managedhsm.azure.net/\". Optional. Requires masterKeyName."
+	AzureConfigDoc.Fields[9].Name = "masterKeyName"
+	AzureConfigDoc.Fields[9].Type = "string"
+	AzureConfigDoc.Fields[9].Note = ""
+	AzureConfigDoc.Fields[9].Description = "Name of the RSA key in the Managed HSM used to encrypt the master secret. Optional. Requires masterKeyHSMURI."
+	AzureConfigDoc.Fields[9].Comments[encoder.LineComment] = "Name of the RSA key in the Managed HSM used to encrypt the master secret. Optional. Requires masterKeyHSMURI."
+	AzureConfigDoc.Fields[10].Name = "virtualNetworkID"
+	AzureConfigDoc.Fields[10].Type = "string"
+	AzureConfigDoc.Fields[10].Note = ""
+	AzureConfigDoc.Fields[10].Description = "Resource ID of an existing virtual network to attach the cluster's nodes to, e.g. \"/subscriptions/<id>/resourceGroups/<rg>/providers/Microsoft.Network/virtualNetworks/<name>\". Optional. Requires subnetID and subnetCIDR. If not set, a virtual network is created for the cluster."
+	AzureConfigDoc.Fields[10].Comments[encoder.LineComment] = "Resource ID of an existing virtual network to attach the cluster's nodes to, e.g. \"/subscriptions/<id>/resourceGroups/<rg>/providers/Microsoft.Network/virtualNetworks/<name>\". Optional. Requires subnetID and subnetCIDR. If not set, a virtual network is created for the cluster."
+	AzureConfigDoc.Fields[11].Name = "subnetID"
+	AzureConfigDoc.Fields[11].Type = "string"
+	AzureConfigDoc.Fields[11].Note = ""
+	AzureConfigDoc.Fields[11].Description = "Resource ID of an existing subnet of the virtual network to attach the cluster's nodes to. Optional. Requires virtualNetworkID and subnetCIDR."
+	AzureConfigDoc.Fields[11].Comments[encoder.LineComment] = "Resource ID of an existing subnet of the virtual network to attach the cluster's nodes to. Optional. Requires virtualNetworkID and subnetCIDR."
+	AzureConfigDoc.Fields[12].Name = "subnetCIDR"
+	AzureConfigDoc.Fields[12].Type = "string"
+	AzureConfigDoc.Fields[12].Note = ""
+	AzureConfigDoc.Fields[12].Description = "CIDR range of the existing subnet, e.g. \"10.9.0.0/16\". Must be an address prefix of the subnet and must not overlap with serviceCIDR. Optional. Requires virtualNetworkID and subnetID."
+	AzureConfigDoc.Fields[12].Comments[encoder.LineComment] = "CIDR range of the existing subnet, e.g. \"10.9.0.0/16\". Must be an address prefix of the subnet and must not overlap with serviceCIDR. Optional. Requires virtualNetworkID and subnetID."
 
 	GCPConfigDoc.Type = "GCPConfig"
 	GCPConfigDoc.Comments[encoder.LineComment] = "GCPConfig are GCP specific configuration values used by the CLI."
@@ -241,7 +338,7 @@ func init() {
 			FieldName: "gcp",
 		},
 	}
-	GCPConfigDoc.Fields = make([]encoder.Doc, 6)
+	GCPConfigDoc.Fields = make([]encoder.Doc, 10)
 	GCPConfigDoc.Fields[0].Name = "project"
 	GCPConfigDoc.Fields[0].Type = "string"
 	GCPConfigDoc.Fields[0].Note = ""
@@ -272,6 +369,26 @@ func init() {
 	GCPConfigDoc.Fields[5].Note = ""
 	GCPConfigDoc.Fields[5].Description = "Use the specified GCP Marketplace image offering."
 	GCPConfigDoc.Fields[5].Comments[encoder.LineComment] = "Use the specified GCP Marketplace image offering."
+	GCPConfigDoc.Fields[6].Name = "networkID"
+	GCPConfigDoc.Fields[6].Type = "string"
+	GCPConfigDoc.Fields[6].Note = ""
+	GCPConfigDoc.Fields[6].Description = "ID of an existing network to attach the cluster's nodes to, e.g. \"projects/<project>/global/networks/<name>\". Optional. Requires subnetworkID and subnetworkCIDR. If not set, a network is created for the cluster."
+	GCPConfigDoc.Fields[6].Comments[encoder.LineComment] = "ID of an existing network to attach the cluster's nodes to, e.g. \"projects/<project>/global/networks/<name>\". Optional. Requires subnetworkID and subnetworkCIDR. If not set, a network is created for the cluster."
+	GCPConfigDoc.Fields[7].Name = "subnetworkID"
+	GCPConfigDoc.Fields[7].Type = "string"
+	GCPConfigDoc.Fields[7].Note = ""
+	GCPConfigDoc.Fields[7].Description = "ID of an existing subnetwork of the network to attach the cluster's nodes to, e.g. \"projects/<project>/regions/<region>/subnetworks/<name>\". The subnetwork must have a secondary IP range for the cluster's pods. Optional. Requires networkID and subnetworkCIDR."
+	GCPConfigDoc.Fields[7].Comments[encoder.LineComment] = "ID of an existing subnetwork of the network to attach the cluster's nodes to, e.g. \"projects/<project>/regions/<region>/subnetworks/<name>\". The subnetwork must have a secondary IP range for the cluster's pods. Optional. Requires networkID and subnetworkCIDR."
+	GCPConfigDoc.Fields[8].Name = "subnetworkCIDR"
+	GCPConfigDoc.Fields[8].Type = "string"
+	GCPConfigDoc.Fields[8].Note = ""
+	GCPConfigDoc.Fields[8].Description = "Primary CIDR range of the existing subnetwork, e.g. \"192.168.178.0/24\". Must not overlap with serviceCIDR. Optional. Requires networkID and subnetworkID."
+	GCPConfigDoc.Fields[8].Comments[encoder.LineComment] = "Primary CIDR range of the existing subnetwork, e.g. \"192.168.178.0/24\". Must not overlap with serviceCIDR. Optional. Requires networkID and subnetworkID."
+	GCPConfigDoc.Fields[9].Name = "diskEncryptionKey"
+	GCPConfigDoc.Fields[9].Type = "string"
+	GCPConfigDoc.Fields[9].Note = ""
+	GCPConfigDoc.Fields[9].Description = "Cloud KMS key to encrypt the boot and state disks of the cluster's nodes with, e.g. \"projects/<project>/locations/<location>/keyRings/<keyRing>/cryptoKeys/<key>\". The key must be in the cluster's region or global. Optional. If not set, disks are encrypted with Google-managed keys. Can't be changed after the cluster was created."
+	GCPConfigDoc.Fields[9].Comments[encoder.LineComment] = "Cloud KMS key to encrypt the boot and state disks of the cluster's nodes with, e.g. \"projects/<project>/locations/<location>/keyRings/<keyRing>/cryptoKeys/<key>\". The key must be in the cluster's region or global. Optional. If not set, disks are encrypted with Google-managed keys. Can't be changed after the cluster was created."
 
 	OpenStackConfigDoc.Type = "OpenStackConfig"
 	OpenStackConfigDoc.Comments[encoder.LineComment] = "OpenStackConfig holds config information for OpenStack based Constellation deployments."
@@ -482,6 +599,167 @@ func init() {
 	NodeGroupDoc.Fields[5].Description = "Number of nodes to be initially created."
 	NodeGroupDoc.Fields[5].Comments[encoder.LineComment] = "Number of nodes to be initially created."
 
+	PhaseHookDoc.Type = "PhaseHook"
+	PhaseHookDoc.Comments[encoder.LineComment] = "PhaseHook defines commands to run before and after a phase of \"constellation apply\"."
+	PhaseHookDoc.Description = "PhaseHook defines commands to run before and after a phase of \"constellation apply\"."
+	PhaseHookDoc.AppearsIn = []encoder.Appearance{
+		{
+			TypeName:  "Config",
+			FieldName: "phaseHooks",
+		},
+	}
+	PhaseHookDoc.Fields = make([]encoder.Doc, 2)
+	PhaseHookDoc.Fields[0].Name = "pre"
+	PhaseHookDoc.Fields[0].Type = "string"
+	PhaseHookDoc.Fields[0].Note = ""
+	PhaseHookDoc.Fields[0].Description = "Command run with \"sh -c\" before the phase. The apply is aborted if the command fails."
+	PhaseHookDoc.Fields[0].Comments[encoder.LineComment] = "Command run with \"sh -c\" before the phase. The apply is aborted if the command fails."
+	PhaseHookDoc.Fields[1].Name = "post"
+	PhaseHookDoc.Fields[1].Type = "string"
+	PhaseHookDoc.Fields[1].Note = ""
+	PhaseHookDoc.Fields[1].Description = "Command run with \"sh -c\" after the phase finished successfully. The apply is aborted if the command fails."
+	PhaseHookDoc.Fields[1].Comments[encoder.LineComment] = "Command run with \"sh -c\" after the phase finished successfully. The apply is aborted if the command fails."
+
+	OIDCConfigDoc.Type = "OIDCConfig"
+	OIDCConfigDoc.Comments[encoder.LineComment] = "OIDCConfig configures an OIDC issuer for the authentication of users at the Kubernetes API server."
+	OIDCConfigDoc.Description = "OIDCConfig configures an OIDC issuer for the authentication of users at the Kubernetes API server."
+	OIDCConfigDoc.AppearsIn = []encoder.Appearance{
+		{
+			TypeName:  "Config",
+			FieldName: "oidc",
+		},
+	}
+	OIDCConfigDoc.Fields = make([]encoder.Doc, 6)
+	OIDCConfigDoc.Fields[0].Name = "issuerURL"
+	OIDCConfigDoc.Fields[0].Type = "string"
+	OIDCConfigDoc.Fields[0].Note = ""
+	OIDCConfigDoc.Fields[0].Description = "URL of the OIDC issuer. Must use HTTPS. The API server fetches the discovery document from \"<issuerURL>/.well-known/openid-configuration\"."
+	OIDCConfigDoc.Fields[0].Comments[encoder.LineComment] = "URL of the OIDC issuer. Must use HTTPS. The API server fetches the discovery document from \"<issuerURL>/.well-known/openid-configuration\"."
+	OIDCConfigDoc.Fields[1].Name = "clientID"
+	OIDCConfigDoc.Fields[1].Type = "string"
+	OIDCConfigDoc.Fields[1].Note = ""
+	OIDCConfigDoc.Fields[1].Description = "Client ID that all ID tokens must be issued for."
+	OIDCConfigDoc.Fields[1].Comments[encoder.LineComment] = "Client ID that all ID tokens must be issued for."
+	OIDCConfigDoc.Fields[2].Name = "usernameClaim"
+	OIDCConfigDoc.Fields[2].Type = "string"
+	OIDCConfigDoc.Fields[2].Note = ""
+	OIDCConfigDoc.Fields[2].Description = "Claim of the ID token used as user name. Defaults to \"sub\"."
+	OIDCConfigDoc.Fields[2].Comments[encoder.LineComment] = "Claim of the ID token used as user name. Defaults to \"sub\"."
+	OIDCConfigDoc.Fields[3].Name = "usernamePrefix"
+	OIDCConfigDoc.Fields[3].Type = "string"
+	OIDCConfigDoc.Fields[3].Note = ""
+	OIDCConfigDoc.Fields[3].Description = "Prefix prepended to user names to prevent clashes with other authentication methods."
+	OIDCConfigDoc.Fields[3].Comments[encoder.LineComment] = "Prefix prepended to user names to prevent clashes with other authentication methods."
+	OIDCConfigDoc.Fields[4].Name = "groupsClaim"
+	OIDCConfigDoc.Fields[4].Type = "string"
+	OIDCConfigDoc.Fields[4].Note = ""
+	OIDCConfigDoc.Fields[4].Description = "Claim of the ID token used as the user's groups."
+	OIDCConfigDoc.Fields[4].Comments[encoder.LineComment] = "Claim of the ID token used as the user's groups."
+	OIDCConfigDoc.Fields[5].Name = "groupsPrefix"
+	OIDCConfigDoc.Fields[5].Type = "string"
+	OIDCConfigDoc.Fields[5].Note = ""
+	OIDCConfigDoc.Fields[5].Description = "Prefix prepended to group names to prevent clashes with other authentication methods."
+	OIDCConfigDoc.Fields[5].Comments[encoder.LineComment] = "Prefix prepended to group names to prevent clashes with other authentication methods."
+
+	TolerationDoc.Type = "Toleration"
+	TolerationDoc.Comments[encoder.LineComment] = "Toleration allows the system pods of Constellation to be scheduled on nodes with matching taints."
+	TolerationDoc.Description = "Toleration allows the system pods of Constellation to be scheduled on nodes with matching taints."
+	TolerationDoc.AppearsIn = []encoder.Appearance{
+		{
+			TypeName:  "Config",
+			FieldName: "tolerations",
+		},
+	}
+	TolerationDoc.Fields = make([]encoder.Doc, 5)
+	TolerationDoc.Fields[0].Name = "key"
+	TolerationDoc.Fields[0].Type = "string"
+	TolerationDoc.Fields[0].Note = ""
+	TolerationDoc.Fields[0].Description = "Taint key the toleration applies to. An empty key matches all taint keys, and requires the operator \"Exists\"."
+	TolerationDoc.Fields[0].Comments[encoder.LineComment] = "Taint key the toleration applies to. An empty key matches all taint keys, and requires the operator \"Exists\"."
+	TolerationDoc.Fields[1].Name = "operator"
+	TolerationDoc.Fields[1].Type = "string"
+	TolerationDoc.Fields[1].Note = ""
+	TolerationDoc.Fields[1].Description = "Relationship of the key to the value. One of \"Equal\" or \"Exists\". Defaults to \"Equal\"."
+	TolerationDoc.Fields[1].Comments[encoder.LineComment] = "Relationship of the key to the value. One of \"Equal\" or \"Exists\". Defaults to \"Equal\"."
+	TolerationDoc.Fields[2].Name = "value"
+	TolerationDoc.Fields[2].Type = "string"
+	TolerationDoc.Fields[2].Note = ""
+	TolerationDoc.Fields[2].Description = "Taint value the toleration matches. Must be empty if the operator is \"Exists\"."
+	TolerationDoc.Fields[2].Comments[encoder.LineComment] = "Taint value the toleration matches. Must be empty if the operator is \"Exists\"."
+	TolerationDoc.Fields[3].Name = "effect"
+	TolerationDoc.Fields[3].Type = "string"
+	TolerationDoc.Fields[3].Note = ""
+	TolerationDoc.Fields[3].Description = "Taint effect to match. One of \"NoSchedule\", \"PreferNoSchedule\", or \"NoExecute\". An empty effect matches all effects."
+	TolerationDoc.Fields[3].Comments[encoder.LineComment] = "Taint effect to match. One of \"NoSchedule\", \"PreferNoSchedule\", or \"NoExecute\". An empty effect matches all effects."
+	TolerationDoc.Fields[4].Name = "tolerationSeconds"
+	TolerationDoc.Fields[4].Type = "int64"
+	TolerationDoc.Fields[4].Note = ""
+	TolerationDoc.Fields[4].Description = "Optional time in seconds the pods stay bound to a node with a matching taint. Only valid with the effect \"NoExecute\"."
+	TolerationDoc.Fields[4].Comments[encoder.LineComment] = "Optional time in seconds the pods stay bound to a node with a matching taint. Only valid with the effect \"NoExecute\"."
+
+	TerraformBackendConfigDoc.Type = "TerraformBackendConfig"
+	TerraformBackendConfigDoc.Comments[encoder.LineComment] = "TerraformBackendConfig configures a remote backend for the Terraform state."
+	TerraformBackendConfigDoc.Description = "TerraformBackendConfig configures a remote backend for the Terraform state.\nSee https://developer.hashicorp.com/terraform/language/settings/backends/configuration for details."
+	TerraformBackendConfigDoc.AppearsIn = []encoder.Appearance{
+		{
+			TypeName:  "Config",
+			FieldName: "terraformBackend",
+		},
+	}
+	TerraformBackendConfigDoc.Fields = make([]encoder.Doc, 2)
+	TerraformBackendConfigDoc.Fields[0].Name = "type"
+	TerraformBackendConfigDoc.Fields[0].Type = "string"
+	TerraformBackendConfigDoc.Fields[0].Note = ""
+	TerraformBackendConfigDoc.Fields[0].Description = "Type of the backend. One of \"s3\", \"gcs\", or \"azurerm\"."
+	TerraformBackendConfigDoc.Fields[0].Comments[encoder.LineComment] = "Type of the backend. One of \"s3\", \"gcs\", or \"azurerm\"."
+	TerraformBackendConfigDoc.Fields[1].Name = "config"
+	TerraformBackendConfigDoc.Fields[1].Type = "map[string]string"
+	TerraformBackendConfigDoc.Fields[1].Note = ""
+	TerraformBackendConfigDoc.Fields[1].Description = "Arguments of the backend, e.g., \"bucket\", \"key\", and \"region\" for \"s3\".\nRequired are \"bucket\", \"key\", and \"region\" for \"s3\", \"bucket\" for \"gcs\", and \"storage_account_name\", \"container_name\", and \"key\" for \"azurerm\"."
+	TerraformBackendConfigDoc.Fields[1].Comments[encoder.LineComment] = "Arguments of the backend, e.g., \"bucket\", \"key\", and \"region\" for \"s3\"."
+
+	DebugAccessConfigDoc.Type = "DebugAccessConfig"
+	DebugAccessConfigDoc.Comments[encoder.LineComment] = "DebugAccessConfig configures SSH access to the nodes for debugging."
+	DebugAccessConfigDoc.Description = "DebugAccessConfig configures SSH access to the nodes for debugging."
+	DebugAccessConfigDoc.AppearsIn = []encoder.Appearance{
+		{
+			TypeName:  "Config",
+			FieldName: "debugAccess",
+		},
+	}
+	DebugAccessConfigDoc.Fields = make([]encoder.Doc, 2)
+	DebugAccessConfigDoc.Fields[0].Name = "enabled"
+	DebugAccessConfigDoc.Fields[0].Type = "bool"
+	DebugAccessConfigDoc.Fields[0].Note = ""
+	DebugAccessConfigDoc.Fields[0].Description = "Enable SSH access to the nodes with the authorized keys."
+	DebugAccessConfigDoc.Fields[0].Comments[encoder.LineComment] = "Enable SSH access to the nodes with the authorized keys."
+	DebugAccessConfigDoc.Fields[1].Name = "authorizedKeys"
+	DebugAccessConfigDoc.Fields[1].Type = "[]string"
+	DebugAccessConfigDoc.Fields[1].Note = ""
+	DebugAccessConfigDoc.Fields[1].Description = "Public keys in OpenSSH authorized_keys format, e.g., \"ssh-ed25519 AAAA... user@host\", that may access the nodes."
+	DebugAccessConfigDoc.Fields[1].Comments[encoder.LineComment] = "Public keys in OpenSSH authorized_keys format, e.g., \"ssh-ed25519 AAAA... user@host\", that may access the nodes."
+
+	RegistryMirrorDoc.Type = "RegistryMirror"
+	RegistryMirrorDoc.Comments[encoder.LineComment] = "RegistryMirror configures the mirrors of a container registry."
+	RegistryMirrorDoc.Description = "RegistryMirror configures the mirrors of a container registry."
+	RegistryMirrorDoc.AppearsIn = []encoder.Appearance{
+		{
+			TypeName:  "Config",
+			FieldName: "registryMirrors",
+		},
+	}
+	RegistryMirrorDoc.Fields = make([]encoder.Doc, 2)
+	RegistryMirrorDoc.Fields[0].Name = "registry"
+	RegistryMirrorDoc.Fields[0].Type = "string"
+	RegistryMirrorDoc.Fields[0].Note = ""
+	RegistryMirrorDoc.Fields[0].Description = "Host of the registry whose images are pulled from the mirrors, e.g., \"docker.io\" or \"registry.k8s.io:443\"."
+	RegistryMirrorDoc.Fields[0].Comments[encoder.LineComment] = "Host of the registry whose images are pulled from the mirrors, e.g., \"docker.io\" or \"registry.k8s.io:443\"."
+	RegistryMirrorDoc.Fields[1].Name = "endpoints"
+	RegistryMirrorDoc.Fields[1].Type = "[]string"
+	RegistryMirrorDoc.Fields[1].Note = ""
+	RegistryMirrorDoc.Fields[1].Description = "URLs of the mirrors, e.g., \"https://mirror.example.com\". The mirrors are tried in order before falling back to the registry."
+	RegistryMirrorDoc.Fields[1].Comments[encoder.LineComment] = "URLs of the mirrors, e.g., \"https://mirror.example.com\". The mirrors are tried in order before falling back to the registry."
+
 	UnsupportedAppRegistrationErrorDoc.Type = "UnsupportedAppRegistrationError"
 	UnsupportedAppRegistrationErrorDoc.Comments[encoder.LineComment] = "UnsupportedAppRegistrationError is returned when the config contains configuration related to now unsupported app registrations."
 	UnsupportedAppRegistrationErrorDoc.Description = "UnsupportedAppRegistrationError is returned when the config contains configuration related to now unsupported app registrations."
@@ -538,7 +816,7 @@ func init() {
 			FieldName: "gcpSEVSNP",
 		},
 	}
-	GCPSEVSNPDoc.Fields = make([]encoder.Doc, 7)
+	GCPSEVSNPDoc.Fields = make([]encoder.Doc, 9)
 	GCPSEVSNPDoc.Fields[0].Name = "measurements"
 	GCPSEVSNPDoc.Fields[0].Type = "M"
 	GCPSEVSNPDoc.Fields[0].Note = ""
@@ -574,6 +852,16 @@ func init() {
 	GCPSEVSNPDoc.Fields[6].Note = ""
 	GCPSEVSNPDoc.Fields[6].Description = "AMD Signing Key certificate used to verify the SEV-SNP VCEK / VLEK certificate."
 	GCPSEVSNPDoc.Fields[6].Comments[encoder.LineComment] = "AMD Signing Key certificate used to verify the SEV-SNP VCEK / VLEK certificate."
+	GCPSEVSNPDoc.Fields[7].Name = "minMicrocodeSVN"
+	GCPSEVSNPDoc.Fields[7].Type = "uint8"
+	GCPSEVSNPDoc.Fields[7].Note = ""
+	GCPSEVSNPDoc.Fields[7].Description = "Optional lowest acceptable microcode SVN of the reported TCB. Checked independently of microcodeVersion."
+	GCPSEVSNPDoc.Fields[7].Comments[encoder.LineComment] = "Optional lowest acceptable microcode SVN of the reported TCB. Checked independently of microcodeVersion."
+	GCPSEVSNPDoc.Fields[8].Name = "vmpl"
+	GCPSEVSNPDoc.Fields[8].Type = "uint8"
+	GCPSEVSNPDoc.Fields[8].Note = ""
+	GCPSEVSNPDoc.Fields[8].Description = "Optional Virtual Machine Privilege Level (VMPL) the attestation report must be issued from. Defaults to 0."
+	GCPSEVSNPDoc.Fields[8].Comments[encoder.LineComment] = "Optional Virtual Machine Privilege Level (VMPL) the attestation report must be issued from. Defaults to 0."
 
 	QEMUVTPMDoc.Type = "QEMUVTPM"
 	QEMUVTPMDoc.Comments[encoder.LineComment] = "QEMUVTPM is the configuration for QEMU vTPM attestation."
@@ -616,7 +904,7 @@ func init() {
 			FieldName: "awsSEVSNP",
 		},
 	}
-	AWSSEVSNPDoc.Fields = make([]encoder.Doc, 7)
+	AWSSEVSNPDoc.Fields = make([]encoder.Doc, 9)
 	AWSSEVSNPDoc.Fields[0].Name = "measurements"
 	AWSSEVSNPDoc.Fields[0].Type = "M"
 	AWSSEVSNPDoc.Fields[0].Note = ""
@@ -652,6 +940,16 @@ func init() {
 	AWSSEVSNPDoc.Fields[6].Note = ""
 	AWSSEVSNPDoc.Fields[6].Description = "AMD Signing Key certificate used to verify the SEV-SNP VCEK / VLEK certificate."
 	AWSSEVSNPDoc.Fields[6].Comments[encoder.LineComment] = "AMD Signing Key certificate used to verify the SEV-SNP VCEK / VLEK certificate."
+	AWSSEVSNPDoc.Fields[7].Name = "minMicrocodeSVN"
+	AWSSEVSNPDoc.Fields[7].Type = "uint8"
+	AWSSEVSNPDoc.Fields[7].Note = ""
+	AWSSEVSNPDoc.Fields[7].Description = "Optional lowest acceptable microcode SVN of the reported TCB. Checked independently of microcodeVersion."
+	AWSSEVSNPDoc.Fields[7].Comments[encoder.LineComment] = "Optional lowest acceptable microcode SVN of the reported TCB. Checked independently of microcodeVersion."
+	AWSSEVSNPDoc.Fields[8].Name = "vmpl"
+	AWSSEVSNPDoc.Fields[8].Type = "uint8"
+	AWSSEVSNPDoc.Fields[8].Note = ""
+	AWSSEVSNPDoc.Fields[8].Description = "Optional Virtual Machine Privilege Level (VMPL) the attestation report must be issued from. Defaults to 0."
+	AWSSEVSNPDoc.Fields[8].Comments[encoder.LineComment] = "Optional Virtual Machine Privilege Level (VMPL) the attestation report must be issued from. Defaults to 0."
 
 	AWSNitroTPMDoc.Type = "AWSNitroTPM"
 	AWSNitroTPMDoc.Comments[encoder.LineComment] = "AWSNitroTPM is the configuration for AWS Nitro TPM attestation."
@@ -662,12 +960,38 @@ func init() {
 			FieldName: "awsNitroTPM",
 		},
 	}
-	AWSNitroTPMDoc.Fields = make([]encoder.Doc, 1)
+	AWSNitroTPMDoc.Fields = make([]encoder.Doc, 2)
 	AWSNitroTPMDoc.Fields[0].Name = "measurements"
 	AWSNitroTPMDoc.Fields[0].Type = "M"
 	AWSNitroTPMDoc.Fields[0].Note = ""
 	AWSNitroTPMDoc.Fields[0].Description = "Expected TPM measurements."
 	AWSNitroTPMDoc.Fields[0].Comments[encoder.LineComment] = "Expected TPM measurements."
+	AWSNitroTPMDoc.Fields[1].Name = "nitroAttestation"
+	AWSNitroTPMDoc.Fields[1].Type = "NitroAttestation"
+	AWSNitroTPMDoc.Fields[1].Note = ""
+	AWSNitroTPMDoc.Fields[1].Description = "Optional validation of the attestation document the Nitro Hypervisor signs for the NitroTPM of a node.\nIf set, nodes without a valid Nitro attestation document are rejected."
+	AWSNitroTPMDoc.Fields[1].Comments[encoder.LineComment] = "Optional validation of the attestation document the Nitro Hypervisor signs for the NitroTPM of a node."
+
+	NitroAttestationDoc.Type = "NitroAttestation"
+	NitroAttestationDoc.Comments[encoder.LineComment] = "NitroAttestation is the configuration for validating the Nitro attestation document of AWS NitroTPM nodes."
+	NitroAttestationDoc.Description = "NitroAttestation is the configuration for validating the Nitro attestation document of AWS NitroTPM nodes."
+	NitroAttestationDoc.AppearsIn = []encoder.Appearance{
+		{
+			TypeName:  "AWSNitroTPM",
+			FieldName: "nitroAttestation",
+		},
+	}
+	NitroAttestationDoc.Fields = make([]encoder.Doc, 2)
+	NitroAttestationDoc.Fields[0].Name = "rootCertificate"
+	NitroAttestationDoc.Fields[0].Type = "Certificate"
+	NitroAttestationDoc.Fields[0].Note = ""
+	NitroAttestationDoc.Fields[0].Description = "Root certificate of the AWS Nitro attestation PKI, as published by AWS. The certificate chain of the Nitro attestation document must end in this certificate."
+	NitroAttestationDoc.Fields[0].Comments[encoder.LineComment] = "Root certificate of the AWS Nitro attestation PKI, as published by AWS. The certificate chain of the Nitro attestation document must end in this certificate."
+	NitroAttestationDoc.Fields[1].Name = "measurements"
+	NitroAttestationDoc.Fields[1].Type = "M"
+	NitroAttestationDoc.Fields[1].Note = ""
+	NitroAttestationDoc.Fields[1].Description = "Expected SHA-384 PCR values of the Nitro attestation document."
+	NitroAttestationDoc.Fields[1].Comments[encoder.LineComment] = "Expected SHA-384 PCR values of the Nitro attestation document."
 
 	AzureSEVSNPDoc.Type = "AzureSEVSNP"
 	AzureSEVSNPDoc.Comments[encoder.LineComment] = "AzureSEVSNP is the configuration for Azure SEV-SNP attestation."
@@ -678,7 +1002,7 @@ func init() {
 			FieldName: "azureSEVSNP",
 		},
 	}
-	AzureSEVSNPDoc.Fields = make([]encoder.Doc, 8)
+	AzureSEVSNPDoc.Fields = make([]encoder.Doc, 11)
 	AzureSEVSNPDoc.Fields[0].Name = "measurements"
 	AzureSEVSNPDoc.Fields[0].Type = "M"
 	AzureSEVSNPDoc.Fields[0].Note = ""
@@ -719,6 +1043,21 @@ func init() {
 	AzureSEVSNPDoc.Fields[7].Note = ""
 	AzureSEVSNPDoc.Fields[7].Description = "AMD Signing Key certificate used to verify the SEV-SNP VCEK / VLEK certificate."
 	AzureSEVSNPDoc.Fields[7].Comments[encoder.LineComment] = "AMD Signing Key certificate used to verify the SEV-SNP VCEK / VLEK certificate."
+	AzureSEVSNPDoc.Fields[8].Name = "minMicrocodeSVN"
+	AzureSEVSNPDoc.Fields[8].Type = "uint8"
+	AzureSEVSNPDoc.Fields[8].Note = ""
+	AzureSEVSNPDoc.Fields[8].Description = "Optional lowest acceptable microcode SVN of the reported TCB. Checked independently of microcodeVersion."
+	AzureSEVSNPDoc.Fields[8].Comments[encoder.LineComment] = "Optional lowest acceptable microcode SVN of the reported TCB. Checked independently of microcodeVersion."
+	AzureSEVSNPDoc.Fields[9].Name = "vmpl"
+	AzureSEVSNPDoc.Fields[9].Type = "uint8"
+	AzureSEVSNPDoc.Fields[9].Note = ""
+	AzureSEVSNPDoc.Fields[9].Description = "Optional Virtual Machine Privilege Level (VMPL) the attestation report must be issued from. Defaults to 0."
+	AzureSEVSNPDoc.Fields[9].Comments[encoder.LineComment] = "Optional Virtual Machine Privilege Level (VMPL) the attestation report must be issued from. Defaults to 0."
+	AzureSEVSNPDoc.Fields[10].Name = "primaryVCEKSource"
+	AzureSEVSNPDoc.Fields[10].Type = "string"
+	AzureSEVSNPDoc.Fields[10].Note = ""
+	AzureSEVSNPDoc.Fields[10].Description = "Optional source of the VCEK certificate to verify the attestation report with first: \"THIM\" or \"KDS\".\nIf set, the VCEK certificate from the other source is tried if verification fails."
+	AzureSEVSNPDoc.Fields[10].Comments[encoder.LineComment] = "Optional source of the VCEK certificate to verify the attestation report with first: \"THIM\" or \"KDS\"."
 
 	AzureTrustedLaunchDoc.Type = "AzureTrustedLaunch"
 	AzureTrustedLaunchDoc.Comments[encoder.LineComment] = "AzureTrustedLaunch is the configuration for Azure Trusted Launch attestation."
@@ -824,6 +1163,30 @@ func (_ NodeGroup) Doc() *encoder.Doc {
 	return &NodeGroupDoc
 }
 
+func (_ PhaseHook) Doc() *encoder.Doc {
+	return &PhaseHookDoc
+}
+
+func (_ OIDCConfig) Doc() *encoder.Doc {
+	return &OIDCConfigDoc
+}
+
+func (_ Toleration) Doc() *encoder.Doc {
+	return &TolerationDoc
+}
+
+func (_ TerraformBackendConfig) Doc() *encoder.Doc {
+	return &TerraformBackendConfigDoc
+}
+
+func (_ DebugAccessConfig) Doc() *encoder.Doc {
+	return &DebugAccessConfigDoc
+}
+
+func (_ RegistryMirror) Doc() *encoder.Doc {
+	return &RegistryMirrorDoc
+}
+
 func (_ UnsupportedAppRegistrationError) Doc() *encoder.Doc {
 	return &UnsupportedAppRegistrationErrorDoc
 }
@@ -856,6 +1219,10 @@ func (_ AWSNitroTPM) Doc() *encoder.Doc {
 	return &AWSNitroTPMDoc
 }
 
+func (_ NitroAttestation) Doc() *encoder.Doc {
+	return &NitroAttestationDoc
+}
+
 func (_ AzureSEVSNP) Doc() *encoder.Doc {
 	return &AzureSEVSNPDoc
 }
@@ -883,6 +1250,12 @@ func GetConfigurationDoc() *encoder.FileDoc {
 			&QEMUConfigDoc,
 			&AttestationConfigDoc,
 			&NodeGroupDoc,
+			&PhaseHookDoc,
+			&OIDCConfigDoc,
+			&TolerationDoc,
+			&TerraformBackendConfigDoc,
+			&DebugAccessConfigDoc,
+			&RegistryMirrorDoc,
 			&UnsupportedAppRegistrationErrorDoc,
 			&SNPFirmwareSignerConfigDoc,
 			&GCPSEVESDoc,
@@ -891,6 +1264,7 @@ func GetConfigurationDoc() *encoder.FileDoc {
 			&QEMUTDXDoc,
 			&AWSSEVSNPDoc,
 			&AWSNitroTPMDoc,
+			&NitroAttestationDoc,
 			&AzureSEVSNPDoc,
 			&AzureTrustedLaunchDoc,
 			&AzureTDXDoc,
@@ -402,6 +402,169 @@ func TestValidate(t *testing.T) {
 				return cnf
 			}(),
 		},
+		"Azure config with valid user data": {
+			cnf: func() *Config {
+				cnf := Default()
+				cnf.RemoveProviderAndAttestationExcept(cloudprovider.Azure)
+				cnf.Image = constants.BinaryVersion().String()
+				modifyConfigForAzureToPassValidate(cnf)
+				cnf.UserData = "#!/bin/sh\necho hello\n"
+				return cnf
+			}(),
+		},
+		"Azure config with invalid user data": {
+			cnf: func() *Config {
+				cnf := Default()
+				cnf.RemoveProviderAndAttestationExcept(cloudprovider.Azure)
+				cnf.Image = constants.BinaryVersion().String()
+				modifyConfigForAzureToPassValidate(cnf)
+				cnf.UserData = "echo hello\n"
+				return cnf
+			}(),
+			wantErr:      true,
+			wantErrCount: 1,
+		},
+		"Azure config with OIDC issuer": {
+			cnf: func() *Config {
+				cnf := Default()
+				cnf.RemoveProviderAndAttestationExcept(cloudprovider.Azure)
+				cnf.Image = constants.BinaryVersion().String()
+				modifyConfigForAzureToPassValidate(cnf)
+				cnf.OIDC = &OIDCConfig{
+					IssuerURL:     "https://login.example.com/realms/constellation",
+					ClientID:      "constellation",
+					UsernameClaim: "email",
+					GroupsClaim:   "groups",
+				}
+				return cnf
+			}(),
+		},
+		"Azure config with OIDC issuer using http and missing client ID": {
+			cnf: func() *Config {
+				cnf := Default()
+				cnf.RemoveProviderAndAttestationExcept(cloudprovider.Azure)
+				cnf.Image = constants.BinaryVersion().String()
+				modifyConfigForAzureToPassValidate(cnf)
+				cnf.OIDC = &OIDCConfig{IssuerURL: "http://login.example.com"}
+				return cnf
+			}(),
+			wantErr:      true,
+			wantErrCount: 2,
+		},
+		"Azure config with network policy preset": {
+			cnf: func() *Config {
+				cnf := Default()
+				cnf.RemoveProviderAndAttestationExcept(cloudprovider.Azure)
+				cnf.Image = constants.BinaryVersion().String()
+				modifyConfigForAzureToPassValidate(cnf)
+				cnf.NetworkPolicyPreset = "restricted"
+				return cnf
+			}(),
+		},
+		"Azure config with unknown network policy preset": {
+			cnf: func() *Config {
+				cnf := Default()
+				cnf.RemoveProviderAndAttestationExcept(cloudprovider.Azure)
+				cnf.Image = constants.BinaryVersion().String()
+				modifyConfigForAzureToPassValidate(cnf)
+				cnf.NetworkPolicyPreset = "strict"
+				return cnf
+			}(),
+			wantErr:      true,
+			wantErrCount: 1,
+		},
+		"Azure config with release channel": {
+			cnf: func() *Config {
+				cnf := Default()
+				cnf.RemoveProviderAndAttestationExcept(cloudprovider.Azure)
+				cnf.Image = constants.BinaryVersion().String()
+				modifyConfigForAzureToPassValidate(cnf)
+				cnf.Channel = "beta"
+				return cnf
+			}(),
+		},
+		"Azure config with unknown release channel": {
+			cnf: func() *Config {
+				cnf := Default()
+				cnf.RemoveProviderAndAttestationExcept(cloudprovider.Azure)
+				cnf.Image = constants.BinaryVersion().String()
+				modifyConfigForAzureToPassValidate(cnf)
+				cnf.Channel = "latest"
+				return cnf
+			}(),
+			wantErr:      true,
+			wantErrCount: 1,
+		},
+		"Azure config with name template": {
+			cnf: func() *Config {
+				cnf := Default()
+				cnf.RemoveProviderAndAttestationExcept(cloudprovider.Azure)
+				cnf.Image = constants.BinaryVersion().String()
+				modifyConfigForAzureToPassValidate(cnf)
+				cnf.NameTemplate = "prod-{name}-{uid}"
+				return cnf
+			}(),
+		},
+		"Azure config with invalid name template": {
+			cnf: func() *Config {
+				cnf := Default()
+				cnf.RemoveProviderAndAttestationExcept(cloudprovider.Azure)
+				cnf.Image = constants.BinaryVersion().String()
+				modifyConfigForAzureToPassValidate(cnf)
+				cnf.NameTemplate = "prod-{name}"
+				return cnf
+			}(),
+			wantErr:      true,
+			wantErrCount: 1,
+		},
+		"Azure config with existing network": {
+			cnf: func() *Config {
+				cnf := Default()
+				cnf.RemoveProviderAndAttestationExcept(cloudprovider.Azure)
+				cnf.Image = constants.BinaryVersion().String()
+				modifyConfigForAzureToPassValidate(cnf)
+				cnf.Provider.Azure.VirtualNetworkID = "/subscriptions/01234567-cdef-0123-4567-89abcdef0123/resourceGroups/network/providers/Microsoft.Network/virtualNetworks/enterprise"
+				cnf.Provider.Azure.SubnetID = cnf.Provider.Azure.VirtualNetworkID + "/subnets/nodes"
+				cnf.Provider.Azure.SubnetCIDR = "10.9.0.0/16"
+				return cnf
+			}(),
+		},
+		"Azure config with existing network without subnet": {
+			cnf: func() *Config {
+				cnf := Default()
+				cnf.RemoveProviderAndAttestationExcept(cloudprovider.Azure)
+				cnf.Image = constants.BinaryVersion().String()
+				modifyConfigForAzureToPassValidate(cnf)
+				cnf.Provider.Azure.VirtualNetworkID = "/subscriptions/01234567-cdef-0123-4567-89abcdef0123/resourceGroups/network/providers/Microsoft.Network/virtualNetworks/enterprise"
+				return cnf
+			}(),
+			wantErr:      true,
+			wantErrCount: 2,
+		},
+		"Azure config with existing network overlapping the service CIDR": {
+			cnf: func() *Config {
+				cnf := Default()
+				cnf.RemoveProviderAndAttestationExcept(cloudprovider.Azure)
+				cnf.Image = constants.BinaryVersion().String()
+				modifyConfigForAzureToPassValidate(cnf)
+				cnf.Provider.Azure.VirtualNetworkID = "/subscriptions/01234567-cdef-0123-4567-89abcdef0123/resourceGroups/network/providers/Microsoft.Network/virtualNetworks/enterprise"
+				cnf.Provider.Azure.SubnetID = cnf.Provider.Azure.VirtualNetworkID + "/subnets/nodes"
+				cnf.Provider.Azure.SubnetCIDR = "10.0.0.0/8"
+				return cnf
+			}(),
+			wantErr:      true,
+			wantErrCount: 1,
+		},
+		"user data is not supported on QEMU": {
+			cnf: func() *Config {
+				cnf := Default()
+				cnf.RemoveProviderAndAttestationExcept(cloudprovider.QEMU)
+				cnf.UserData = "#!/bin/sh\necho hello\n"
+				return cnf
+			}(),
+			wantErr:      true,
+			wantErrCount: 1,
+		},
 		"default AWS config is not valid": {
 			cnf: func() *Config {
 				cnf := Default()
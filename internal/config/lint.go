@@ -0,0 +1,79 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/edgelesssys/constellation/v2/internal/api/attestationconfigapi"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/edgelesssys/constellation/v2/internal/role"
+)
+
+// minHAControlPlaneNodes is the number of control-plane nodes required for the cluster to tolerate the failure of a node.
+const minHAControlPlaneNodes = 3
+
+// LintFile reads the config file with the given name, merges the overlay of the profile if one is given, and lints it.
+// An error is only returned if the config file can't be read.
+func LintFile(fileHandler file.Handler, name, profile string, fetcher attestationconfigapi.Fetcher, force bool) (*ValidationResult, error) {
+	c, err := load(fileHandler, name, profile, fetcher)
+	if err != nil {
+		return nil, err
+	}
+	return c.Lint(profile, force)
+}
+
+// Lint returns advisories about best practices the config doesn't follow.
+// In contrast to [Config.ValidationResult], the result never contains findings of severity [SeverityError],
+// since advisories don't make the config invalid. The warnings of the validation, e.g., about deprecated keys, are included.
+// The profile is used to detect configs meant for production.
+func (c *Config) Lint(profile string, force bool) (*ValidationResult, error) {
+	validation, err := c.ValidationResult(force)
+	if err != nil {
+		return nil, err
+	}
+	result := &ValidationResult{}
+	for _, finding := range validation.Findings {
+		if finding.Severity != SeverityError {
+			result.Findings = append(result.Findings, finding)
+		}
+	}
+
+	var controlPlaneNodes int
+	for _, group := range c.NodeGroups {
+		if group.Role == role.ControlPlane.TFString() {
+			controlPlaneNodes += group.InitialCount
+		}
+	}
+	switch {
+	case controlPlaneNodes < minHAControlPlaneNodes:
+		result.add(SeverityWarning, "nodeGroups", fmt.Sprintf(
+			"%d control-plane nodes aren't highly available, use at least %d so the cluster tolerates the failure of a node",
+			controlPlaneNodes, minHAControlPlaneNodes))
+	case controlPlaneNodes%2 == 0:
+		result.add(SeverityInfo, "nodeGroups", fmt.Sprintf(
+			"an even number of %d control-plane nodes doesn't tolerate more failures than %d nodes, use an odd number",
+			controlPlaneNodes, controlPlaneNodes-1))
+	}
+
+	if c.looksLikeProduction(profile) {
+		if c.IsDebugCluster() {
+			result.add(SeverityWarning, "debugCluster", "debugging is enabled in a config that looks like it's meant for production")
+		}
+		if c.IsDebugAccessEnabled() {
+			result.add(SeverityWarning, "debugAccess", "SSH access is enabled in a config that looks like it's meant for production")
+		}
+	}
+	return result, nil
+}
+
+// looksLikeProduction returns true if the config is loaded with the profile "prod",
+// or the name of the cluster indicates a production cluster.
+func (c *Config) looksLikeProduction(profile string) bool {
+	return profile == "prod" || strings.Contains(strings.ToLower(c.Name), "prod")
+}
@@ -0,0 +1,85 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package config
+
+import (
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	testCases := map[string]struct {
+		modify       func(*Config)
+		profile      string
+		wantFindings []Finding
+	}{
+		"best practices are followed": {
+			modify: func(*Config) {},
+		},
+		"several advisories": {
+			modify: func(c *Config) {
+				c.Name = "prod-eu"
+				c.DebugCluster = toPtr(true)
+				group := c.NodeGroups[constants.DefaultControlPlaneGroupName]
+				group.InitialCount = 1
+				c.NodeGroups[constants.DefaultControlPlaneGroupName] = group
+			},
+			wantFindings: []Finding{
+				{Severity: SeverityWarning, Path: "debugCluster", Message: "debug clusters aren't secure and must not be used in production"},
+				{Severity: SeverityWarning, Path: "nodeGroups", Message: "1 control-plane nodes aren't highly available, use at least 3 so the cluster tolerates the failure of a node"},
+				{Severity: SeverityWarning, Path: "debugCluster", Message: "debugging is enabled in a config that looks like it's meant for production"},
+			},
+		},
+		"debug access with prod profile": {
+			modify: func(c *Config) {
+				c.DebugAccess = &DebugAccessConfig{
+					Enabled:        true,
+					AuthorizedKeys: []string{"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIHO6fX0WwmfN84U0OmpPNix0WMZySwwHKx5vcU21QqMj admin@example.com"},
+				}
+			},
+			profile: "prod",
+			wantFindings: []Finding{
+				{Severity: SeverityWarning, Path: "debugAccess", Message: "SSH access to the nodes breaks the confidentiality of the cluster and must not be used in production"},
+				{Severity: SeverityWarning, Path: "debugAccess", Message: "SSH access is enabled in a config that looks like it's meant for production"},
+			},
+		},
+		"even number of control-plane nodes": {
+			modify: func(c *Config) {
+				group := c.NodeGroups[constants.DefaultControlPlaneGroupName]
+				group.InitialCount = 4
+				c.NodeGroups[constants.DefaultControlPlaneGroupName] = group
+			},
+			wantFindings: []Finding{
+				{Severity: SeverityInfo, Path: "nodeGroups", Message: "an even number of 4 control-plane nodes doesn't tolerate more failures than 3 nodes, use an odd number"},
+			},
+		},
+		"validation errors aren't advisories": {
+			modify: func(c *Config) {
+				c.InternalLoadBalancer = true
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			cnf := Default()
+			modifyConfigForAzureToPassValidate(cnf)
+			tc.modify(cnf)
+
+			result, err := cnf.Lint(tc.profile, false)
+			require.NoError(err)
+			assert.Equal(tc.wantFindings, result.Findings)
+			assert.False(result.HasErrors())
+		})
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+)
+
+const (
+	// NameTemplatePlaceholderName is replaced with the name of the cluster.
+	NameTemplatePlaceholderName = "{name}"
+	// NameTemplatePlaceholderUID is replaced with the UID of the cluster.
+	NameTemplatePlaceholderUID = "{uid}"
+	// DefaultNameTemplate is used if no name template is configured.
+	DefaultNameTemplate = NameTemplatePlaceholderName + "-" + NameTemplatePlaceholderUID
+
+	// uidLength is the length of the cluster UID generated by Terraform (4 random bytes, hex encoded).
+	uidLength = 8
+)
+
+var (
+	placeholderRegexp = regexp.MustCompile(`\{[^{}]*\}`)
+	// lowercaseNameRegexp matches names that are valid on AWS and GCP.
+	// GCP requires names to start with a letter.
+	lowercaseNameRegexp = regexp.MustCompile(`^[a-z]([a-z0-9-]*[a-z0-9])?$`)
+	// nameRegexp matches names that are valid on Azure and OpenStack.
+	nameRegexp = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?$`)
+)
+
+// ResourceName returns the base name of the cloud resources created for a cluster with the given UID.
+// The name is derived from the configured name template, or from [DefaultNameTemplate] if none is configured.
+func (c *Config) ResourceName(uid string) (string, error) {
+	template := c.NameTemplate
+	if template == "" {
+		template = DefaultNameTemplate
+	}
+	return ExpandNameTemplate(template, c.Name, uid)
+}
+
+// ExpandNameTemplate replaces the placeholders in template with the given cluster name and UID.
+func ExpandNameTemplate(template, name, uid string) (string, error) {
+	var unknown []string
+	expanded := placeholderRegexp.ReplaceAllStringFunc(template, func(placeholder string) string {
+		switch placeholder {
+		case NameTemplatePlaceholderName:
+			return name
+		case NameTemplatePlaceholderUID:
+			return uid
+		default:
+			unknown = append(unknown, placeholder)
+			return placeholder
+		}
+	})
+	if len(unknown) > 0 {
+		return "", fmt.Errorf("unknown placeholders %s, supported placeholders are %s and %s",
+			strings.Join(unknown, ", "), NameTemplatePlaceholderName, NameTemplatePlaceholderUID)
+	}
+	if strings.ContainsAny(expanded, "{}") {
+		return "", errors.New("unbalanced braces")
+	}
+	return expanded, nil
+}
+
+// ValidateNameTemplate checks that the name template yields names of the cluster's resources
+// that are unique and follow the naming rules of the given provider.
+func ValidateNameTemplate(template, name string, provider cloudprovider.Provider) error {
+	if provider == cloudprovider.QEMU {
+		return errors.New("not supported on QEMU")
+	}
+	if !strings.Contains(template, NameTemplatePlaceholderUID) {
+		return fmt.Errorf("must contain %s, so names are unique across clusters", NameTemplatePlaceholderUID)
+	}
+	expanded, err := ExpandNameTemplate(template, name, strings.Repeat("0", uidLength))
+	if err != nil {
+		return err
+	}
+
+	// Suffixes are appended to the resolved name for the individual resources,
+	// so the resolved name must not be longer than the default one for the longest cluster name.
+	maxLength := constants.ConstellationNameLength + 1 + uidLength
+	charset := nameRegexp
+	if provider == cloudprovider.AWS {
+		maxLength = constants.AWSConstellationNameLength + 1 + uidLength
+	}
+	if provider == cloudprovider.AWS || provider == cloudprovider.GCP {
+		charset = lowercaseNameRegexp
+	}
+	if len(expanded) > maxLength {
+		return fmt.Errorf("resolves to %q, which is longer than %d characters", expanded, maxLength)
+	}
+	if !charset.MatchString(expanded) {
+		return fmt.Errorf("resolves to %q, which doesn't match the naming rules of %s (%s)", expanded, provider, charset)
+	}
+	return nil
+}
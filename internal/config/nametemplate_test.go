@@ -0,0 +1,178 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandNameTemplate(t *testing.T) {
+	testCases := map[string]struct {
+		template string
+		want     string
+		wantErr  bool
+	}{
+		"default template": {
+			template: DefaultNameTemplate,
+			want:     "constell-1a2b3c4d",
+		},
+		"prefix and suffix": {
+			template: "org-{name}-{uid}-k8s",
+			want:     "org-constell-1a2b3c4d-k8s",
+		},
+		"placeholder used twice": {
+			template: "{uid}-{name}-{uid}",
+			want:     "1a2b3c4d-constell-1a2b3c4d",
+		},
+		"no placeholders": {
+			template: "static",
+			want:     "static",
+		},
+		"unknown placeholder": {
+			template: "{env}-{name}-{uid}",
+			wantErr:  true,
+		},
+		"unbalanced braces": {
+			template: "{name}-{uid",
+			wantErr:  true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			got, err := ExpandNameTemplate(tc.template, "constell", "1a2b3c4d")
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.want, got)
+		})
+	}
+}
+
+func TestResourceName(t *testing.T) {
+	assert := assert.New(t)
+
+	conf := &Config{Name: "constell"}
+	name, err := conf.ResourceName("1a2b3c4d")
+	assert.NoError(err)
+	assert.Equal("constell-1a2b3c4d", name)
+
+	conf.NameTemplate = "org-{uid}-{name}"
+	name, err = conf.ResourceName("1a2b3c4d")
+	assert.NoError(err)
+	assert.Equal("org-1a2b3c4d-constell", name)
+}
+
+func TestValidateNameTemplate(t *testing.T) {
+	testCases := map[string]struct {
+		template string
+		name     string
+		provider cloudprovider.Provider
+		wantErr  bool
+	}{
+		"valid on GCP": {
+			template: "org-{name}-{uid}",
+			name:     "constell",
+			provider: cloudprovider.GCP,
+		},
+		"valid on AWS": {
+			template: "a-{name}-{uid}",
+			name:     "constell",
+			provider: cloudprovider.AWS,
+		},
+		"upper case allowed on Azure": {
+			template: "Org-{name}-{uid}",
+			name:     "constell",
+			provider: cloudprovider.Azure,
+		},
+		"upper case not allowed on GCP": {
+			template: "Org-{name}-{uid}",
+			name:     "constell",
+			provider: cloudprovider.GCP,
+			wantErr:  true,
+		},
+		"must start with letter on GCP": {
+			template: "{uid}-{name}",
+			name:     "constell",
+			provider: cloudprovider.GCP,
+			wantErr:  true,
+		},
+		"may start with digit on Azure": {
+			template: "{uid}-{name}",
+			name:     "constell",
+			provider: cloudprovider.Azure,
+		},
+		"invalid characters": {
+			template: "org_{name}.{uid}",
+			name:     "constell",
+			provider: cloudprovider.Azure,
+			wantErr:  true,
+		},
+		"trailing hyphen": {
+			template: "{name}-{uid}-",
+			name:     "constell",
+			provider: cloudprovider.OpenStack,
+			wantErr:  true,
+		},
+		"too long on AWS": {
+			template: "org-{name}-{uid}",
+			name:     "constell",
+			provider: cloudprovider.AWS,
+			wantErr:  true,
+		},
+		"maximum length": {
+			template: "{name}-{uid}",
+			name:     strings.Repeat("a", 37),
+			provider: cloudprovider.Azure,
+		},
+		"too long": {
+			template: "x{name}-{uid}",
+			name:     strings.Repeat("a", 37),
+			provider: cloudprovider.Azure,
+			wantErr:  true,
+		},
+		"missing uid": {
+			template: "org-{name}",
+			name:     "constell",
+			provider: cloudprovider.GCP,
+			wantErr:  true,
+		},
+		"unknown placeholder": {
+			template: "{env}-{name}-{uid}",
+			name:     "constell",
+			provider: cloudprovider.GCP,
+			wantErr:  true,
+		},
+		"not supported on QEMU": {
+			template: "{name}-{uid}",
+			name:     "constell",
+			provider: cloudprovider.QEMU,
+			wantErr:  true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			err := ValidateNameTemplate(tc.template, tc.name, tc.provider)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/edgelesssys/constellation/v2/internal/file"
+)
+
+// profileRegexp matches valid profile names.
+var profileRegexp = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// ProfileFilename returns the name of the overlay file of the given profile,
+// e.g. "constellation-conf.prod.yaml" for the config file "constellation-conf.yaml" and the profile "prod".
+func ProfileFilename(name, profile string) string {
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + profile + ext
+}
+
+// fromFileWithProfile reads the config file and merges the overlay file of the given profile into it.
+// Keys set in the overlay override the keys of the config file. Nested mappings, e.g. the fields of a provider,
+// are merged key by key, all other values, including lists, are replaced.
+// If profile is empty, only the config file is read.
+func fromFileWithProfile(fileHandler file.Handler, name, profile string) (*Config, error) {
+	if profile == "" {
+		return fromFile(fileHandler, name)
+	}
+	if !profileRegexp.MatchString(profile) {
+		return nil, fmt.Errorf("invalid profile name %q: must only contain lowercase letters, digits, and dashes", profile)
+	}
+
+	base, err := readYAMLNode(fileHandler, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("unable to find %s - use `constellation config generate` to generate it first", name)
+		}
+		return nil, fmt.Errorf("could not load config from file %s: %w", name, err)
+	}
+	overlayName := ProfileFilename(name, profile)
+	overlay, err := readYAMLNode(fileHandler, overlayName)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("unable to find overlay %s of profile %q", overlayName, profile)
+		}
+		return nil, fmt.Errorf("could not load overlay from file %s: %w", overlayName, err)
+	}
+	if err := mergeYAMLMappings(base, overlay); err != nil {
+		return nil, fmt.Errorf("merging overlay %s: %w", overlayName, err)
+	}
+
+	merged, err := yaml.Marshal(base)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling merged config: %w", err)
+	}
+	var conf Config
+	decoder := yaml.NewDecoder(bytes.NewReader(merged))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&conf); err != nil {
+		if isAppClientIDError(err) {
+			return nil, &UnsupportedAppRegistrationError{}
+		}
+		return nil, fmt.Errorf("could not load config from file %s with profile %q: %w", name, profile, err)
+	}
+	return &conf, nil
+}
+
+// readYAMLNode reads the YAML file and returns its top-level mapping.
+func readYAMLNode(fileHandler file.Handler, name string) (*yaml.Node, error) {
+	data, err := fileHandler.Read(name)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("file must contain a YAML mapping")
+	}
+	return doc.Content[0], nil
+}
+
+// mergeYAMLMappings merges the overlay mapping into the base mapping.
+func mergeYAMLMappings(base, overlay *yaml.Node) error {
+	for i := 0; i+1 < len(overlay.Content); i += 2 {
+		key, value := overlay.Content[i], overlay.Content[i+1]
+		if key.Kind != yaml.ScalarNode {
+			return fmt.Errorf("line %d: mapping keys must be scalars", key.Line)
+		}
+		baseValue := mappingValue(base, key.Value)
+		switch {
+		case baseValue == nil:
+			base.Content = append(base.Content, key, value)
+		case baseValue.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			if err := mergeYAMLMappings(baseValue, value); err != nil {
+				return err
+			}
+		default:
+			*baseValue = *value
+		}
+	}
+	return nil
+}
+
+// mappingValue returns the value of the key in the mapping, or nil if the key doesn't exist.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
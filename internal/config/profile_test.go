@@ -0,0 +1,122 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileFilename(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("constellation-conf.prod.yaml", ProfileFilename(constants.ConfigFilename, "prod"))
+	assert.Equal("workspace/constellation-conf.dev.yaml", ProfileFilename("workspace/constellation-conf.yaml", "dev"))
+}
+
+func TestNewWithProfile(t *testing.T) {
+	testCases := map[string]struct {
+		profile        string
+		overlay        string
+		wantErr        bool
+		wantValidation bool
+		assertions     func(*assert.Assertions, *Config)
+	}{
+		"no profile uses the base config": {
+			assertions: func(assert *assert.Assertions, c *Config) {
+				assert.Equal("base", c.Name)
+			},
+		},
+		"overlay overrides top-level keys": {
+			profile: "prod",
+			overlay: "name: prod\nnetworkPolicyPreset: restricted\n",
+			assertions: func(assert *assert.Assertions, c *Config) {
+				assert.Equal("prod", c.Name)
+				assert.Equal("restricted", c.NetworkPolicyPreset)
+				assert.Equal(constants.BinaryVersion().String(), c.Image)
+			},
+		},
+		"overlay merges nested provider fields": {
+			profile: "staging",
+			overlay: "provider:\n  azure:\n    location: westeurope\n    resourceGroup: staging\n",
+			assertions: func(assert *assert.Assertions, c *Config) {
+				assert.Equal("westeurope", c.Provider.Azure.Location)
+				assert.Equal("staging", c.Provider.Azure.ResourceGroup)
+				assert.Equal("11111111-1111-1111-1111-111111111111", c.Provider.Azure.SubscriptionID)
+				assert.Equal("base", c.Name)
+			},
+		},
+		"overlay merges node groups": {
+			profile: "prod",
+			overlay: "nodeGroups:\n  " + constants.WorkerDefault + ":\n    initialCount: 5\n",
+			assertions: func(assert *assert.Assertions, c *Config) {
+				assert.Equal(5, c.NodeGroups[constants.WorkerDefault].InitialCount)
+				assert.Equal("Standard_DC4as_v5", c.NodeGroups[constants.WorkerDefault].InstanceType)
+				assert.Equal(3, c.NodeGroups[constants.ControlPlaneDefault].InitialCount)
+			},
+		},
+		"overlay replaces lists": {
+			profile: "prod",
+			overlay: "attestation:\n  azureSEVSNP:\n    firmwareSignerConfig:\n      acceptedKeyDigests:\n        - " +
+				"0356215882a825279a85b300b0b742931d113bf7e32dde2e50ffde7ec743ca491ecdd7f336dc28a6e0b2bb57af7a44a3\n",
+			assertions: func(assert *assert.Assertions, c *Config) {
+				assert.Len(c.Attestation.AzureSEVSNP.FirmwareSignerConfig.AcceptedKeyDigests, 1)
+			},
+		},
+		"unknown key in overlay": {
+			profile: "prod",
+			overlay: "provider:\n  azure:\n    region: westeurope\n",
+			wantErr: true,
+		},
+		"merged config is validated": {
+			profile:        "prod",
+			overlay:        "networkPolicyPreset: strict\n",
+			wantErr:        true,
+			wantValidation: true,
+		},
+		"overlay of profile doesn't exist": {
+			profile: "dev",
+			wantErr: true,
+		},
+		"invalid profile name": {
+			profile: "../prod",
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fileHandler := file.NewHandler(afero.NewMemMapFs())
+			base := Default()
+			modifyConfigForAzureToPassValidate(base)
+			base.Name = "base"
+			require.NoError(fileHandler.WriteYAML(constants.ConfigFilename, base))
+			if tc.overlay != "" {
+				require.NoError(fileHandler.Write(ProfileFilename(constants.ConfigFilename, tc.profile), []byte(tc.overlay)))
+			}
+
+			conf, err := NewWithProfile(fileHandler, constants.ConfigFilename, tc.profile, stubAttestationFetcher{}, false)
+			if tc.wantErr {
+				assert.Error(err)
+				var valErr *ValidationError
+				assert.Equal(tc.wantValidation, errors.As(err, &valErr))
+				return
+			}
+			require.NoError(err)
+			tc.assertions(assert, conf)
+		})
+	}
+}
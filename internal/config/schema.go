@@ -0,0 +1,165 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// jsonSchemaDraft is the JSON schema dialect of the generated schema.
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// JSONSchema returns the JSON schema of the config file.
+// The schema is generated from the Go types of the config, so it can't drift from what the CLI accepts.
+// Field descriptions are taken from the config documentation.
+// The schema only checks the structure and types of the config file,
+// the values are still checked by Validate.
+func JSONSchema() ([]byte, error) {
+	g := schemaGenerator{docs: fieldDescriptions()}
+	schema := g.schemaForType(reflect.TypeOf(Config{}))
+	schema["$schema"] = jsonSchemaDraft
+	schema["title"] = "Constellation config file"
+	schema["description"] = strings.TrimSpace(GetConfigurationDoc().Description)
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// fieldDescriptions maps the names of the documented config structs to the descriptions of their fields.
+func fieldDescriptions() map[string]map[string]string {
+	docs := make(map[string]map[string]string)
+	for _, structDoc := range GetConfigurationDoc().Structs {
+		fields := make(map[string]string, len(structDoc.Fields))
+		for _, field := range structDoc.Fields {
+			fields[field.Name] = strings.TrimSpace(field.Description)
+		}
+		docs[structDoc.Type] = fields
+	}
+	return docs
+}
+
+type schemaGenerator struct {
+	docs map[string]map[string]string
+}
+
+var (
+	durationType          = reflect.TypeOf(time.Duration(0))
+	yamlUnmarshalerType   = reflect.TypeOf((*interface{ UnmarshalYAML(func(any) error) error })(nil)).Elem()
+	jsonUnmarshalerType   = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType   = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	customUnmarshalerList = []reflect.Type{yamlUnmarshalerType, jsonUnmarshalerType, textUnmarshalerType}
+)
+
+// schemaForType returns the schema of values of type t.
+func (g schemaGenerator) schemaForType(t reflect.Type) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		schema := g.schemaForType(t.Elem())
+		if typ, ok := schema["type"]; ok {
+			schema["type"] = append(schemaTypes(typ), "null")
+		}
+		return schema
+	}
+
+	if t == durationType {
+		// Durations are accepted as strings like "10m" and as integers in nanoseconds.
+		return map[string]any{"type": []string{"string", "integer"}}
+	}
+	if hasCustomUnmarshaler(t) {
+		// Types with custom unmarshalers accept more than their Go type suggests,
+		// e.g., measurements are given as hex strings or byte lists, and attestation versions as number or "latest".
+		// Only types that are always encoded as strings are restricted.
+		if t.Kind() == reflect.String {
+			return map[string]any{"type": "string"}
+		}
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.schemaForType(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schemaForType(t.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]any)
+		g.addProperties(properties, t)
+		return map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+	default:
+		// Interfaces and other kinds can hold any value.
+		return map[string]any{}
+	}
+}
+
+// addProperties adds the schemas of the fields of the struct type t to properties.
+// Inlined fields are merged into the properties of t.
+func (g schemaGenerator) addProperties(properties map[string]any, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(opts, "inline") {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			g.addProperties(properties, fieldType)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		schema := g.schemaForType(field.Type)
+		if description := g.docs[t.Name()][name]; description != "" {
+			schema["description"] = description
+		}
+		properties[name] = schema
+	}
+}
+
+// hasCustomUnmarshaler reports whether values of type t are decoded by a custom unmarshaler.
+func hasCustomUnmarshaler(t reflect.Type) bool {
+	ptr := reflect.PointerTo(t)
+	for _, unmarshaler := range customUnmarshalerList {
+		if t.Implements(unmarshaler) || ptr.Implements(unmarshaler) {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaTypes returns the JSON schema type keyword as a list.
+func schemaTypes(typ any) []string {
+	switch typ := typ.(type) {
+	case string:
+		return []string{typ}
+	case []string:
+		return typ
+	default:
+		return nil
+	}
+}
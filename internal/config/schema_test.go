@@ -0,0 +1,111 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package config
+
+import (
+	"testing"
+
+	"github.com/siderolabs/talos/pkg/machinery/config/encoder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xeipuuv/gojsonschema"
+	"sigs.k8s.io/yaml"
+
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+)
+
+func TestJSONSchema(t *testing.T) {
+	testCases := map[string]struct {
+		config    func(t *testing.T) []byte
+		wantValid bool
+	}{
+		"default config": {
+			config: func(t *testing.T) []byte {
+				return configJSON(t, Default())
+			},
+			wantValid: true,
+		},
+		"azure config": {
+			config: func(t *testing.T) []byte {
+				conf := Default()
+				conf.RemoveProviderAndAttestationExcept(cloudprovider.Azure)
+				return configJSON(t, conf)
+			},
+			wantValid: true,
+		},
+		"gcp config": {
+			config: func(t *testing.T) []byte {
+				conf := Default()
+				conf.RemoveProviderAndAttestationExcept(cloudprovider.GCP)
+				return configJSON(t, conf)
+			},
+			wantValid: true,
+		},
+		"wrong type": {
+			config: func(t *testing.T) []byte {
+				return []byte(`{"version": "v4", "debugCluster": "yes"}`)
+			},
+		},
+		"wrong type in nested struct": {
+			config: func(t *testing.T) []byte {
+				return []byte(`{"version": "v4", "provider": {"gcp": {"project": 42}}}`)
+			},
+		},
+		"fractional disk size": {
+			config: func(t *testing.T) []byte {
+				return []byte(`{"version": "v4", "nodeGroups": {"worker_default": {"stateDiskSizeGB": 1.5}}}`)
+			},
+		},
+		"unknown field": {
+			config: func(t *testing.T) []byte {
+				return []byte(`{"version": "v4", "unknownField": true}`)
+			},
+		},
+	}
+
+	schema, err := JSONSchema()
+	require.NoError(t, err)
+	schemaLoader := gojsonschema.NewBytesLoader(schema)
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			result, err := gojsonschema.Validate(schemaLoader, gojsonschema.NewBytesLoader(tc.config(t)))
+			require.NoError(err)
+			assert.Equal(tc.wantValid, result.Valid(), result.Errors())
+		})
+	}
+}
+
+func TestJSONSchemaDescriptions(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	raw, err := JSONSchema()
+	require.NoError(err)
+
+	var schema struct {
+		Properties map[string]struct {
+			Description string `json:"description"`
+		} `json:"properties"`
+	}
+	require.NoError(yaml.Unmarshal(raw, &schema))
+	assert.Contains(schema.Properties, "kubernetesVersion")
+	assert.NotEmpty(schema.Properties["kubernetesVersion"].Description)
+}
+
+// configJSON encodes the config as JSON, the same way a config file in JSON format is written.
+func configJSON(t *testing.T, conf *Config) []byte {
+	t.Helper()
+	yamlData, err := encoder.NewEncoder(conf, encoder.WithComments(encoder.CommentsDisabled)).Encode()
+	require.NoError(t, err)
+	jsonData, err := yaml.YAMLToJSON(yamlData)
+	require.NoError(t, err)
+	return jsonData
+}
@@ -8,17 +8,29 @@ package config
 
 import (
 	"bytes"
+	"crypto/sha512"
 	"errors"
 	"fmt"
+	"maps"
+	"net"
+	"net/netip"
+	"net/url"
 	"os"
+	"path"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
+	"golang.org/x/crypto/ssh"
 	"golang.org/x/mod/semver"
+	corev1 "k8s.io/api/core/v1"
+	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
 
 	"github.com/edgelesssys/constellation/v2/internal/api/versionsapi"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/measurements"
@@ -860,3 +872,465 @@ func validateOpenStackStateDiskField(fl validator.FieldLevel) bool {
 func validateQEMUStateDiskField(_ validator.FieldLevel) bool {
 	return true
 }
+
+func (c *Config) validateNameTemplateField(fl validator.FieldLevel) bool {
+	return ValidateNameTemplate(fl.Field().String(), c.Name, c.GetProvider()) == nil
+}
+
+func registerNameTemplateError(ut ut.Translator) error {
+	return ut.Add("name_template", "{0}: {1}", true)
+}
+
+func (c *Config) translateNameTemplateError(ut ut.Translator, fe validator.FieldError) string {
+	var msg string
+	if err := ValidateNameTemplate(fe.Value().(string), c.Name, c.GetProvider()); err != nil {
+		msg = err.Error()
+	}
+	t, _ := ut.T("name_template", fe.Field(), msg)
+	return t
+}
+
+// maxUserDataSize is the maximum size of the user data in bytes.
+// 16 KiB is the smallest limit of all supported CSPs (AWS).
+const maxUserDataSize = 16 * 1024
+
+func validateUserDataField(fl validator.FieldLevel) bool {
+	return ValidateUserData(fl.Field().String()) == nil
+}
+
+// ValidateUserData checks that the user data is a script the nodes can run and doesn't exceed the maximum size.
+// The nodes don't run cloud-init, so cloud-init configurations are rejected.
+func ValidateUserData(userData string) error {
+	if len(userData) > maxUserDataSize {
+		return fmt.Errorf("must not be larger than %d bytes, got %d bytes", maxUserDataSize, len(userData))
+	}
+	if !utf8.ValidString(userData) || strings.ContainsRune(userData, 0) {
+		return errors.New("must be a text file")
+	}
+
+	firstLine, _, _ := strings.Cut(userData, "\n")
+	firstLine = strings.TrimSpace(firstLine)
+	switch {
+	case strings.HasPrefix(firstLine, "#!"):
+		interpreter := strings.Fields(strings.TrimPrefix(firstLine, "#!"))
+		if len(interpreter) == 0 {
+			return errors.New("script is missing an interpreter after \"#!\"")
+		}
+		if !path.IsAbs(interpreter[0]) {
+			return fmt.Errorf("interpreter %q of the script must be an absolute path", interpreter[0])
+		}
+	case firstLine == "#cloud-config":
+		return errors.New("cloud-init configurations aren't supported, since the nodes don't run cloud-init: use a script starting with \"#!\" instead")
+	default:
+		return errors.New("must be a script starting with \"#!\"")
+	}
+	return nil
+}
+
+func registerUserDataError(ut ut.Translator) error {
+	return ut.Add("user_data", "{0}: {1}", true)
+}
+
+func translateUserDataError(ut ut.Translator, fe validator.FieldError) string {
+	var msg string
+	if err := ValidateUserData(fe.Value().(string)); err != nil {
+		msg = err.Error()
+	}
+	t, _ := ut.T("user_data", fe.Field(), msg)
+	return t
+}
+
+func validateOIDCIssuerURLField(fl validator.FieldLevel) bool {
+	return ValidateOIDCIssuerURL(fl.Field().String()) == nil
+}
+
+// ValidateOIDCIssuerURL checks that the issuer URL is an absolute HTTPS URL
+// without query and fragment, as required by the OIDC discovery specification.
+func ValidateOIDCIssuerURL(issuerURL string) error {
+	u, err := url.Parse(issuerURL)
+	if err != nil {
+		return fmt.Errorf("parsing URL: %w", err)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("must use the https scheme, got %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return errors.New("must contain a host")
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return errors.New("must not contain a query or fragment")
+	}
+	return nil
+}
+
+func registerOIDCIssuerURLError(ut ut.Translator) error {
+	return ut.Add("oidc_issuer_url", "{0}: {1}", true)
+}
+
+func translateOIDCIssuerURLError(ut ut.Translator, fe validator.FieldError) string {
+	var msg string
+	if err := ValidateOIDCIssuerURL(fe.Value().(string)); err != nil {
+		msg = err.Error()
+	}
+	t, _ := ut.T("oidc_issuer_url", fe.Field(), msg)
+	return t
+}
+
+var (
+	azureVirtualNetworkIDRegexp = regexp.MustCompile(`(?i)^/subscriptions/[^/]+/resourceGroups/[^/]+/providers/Microsoft\.Network/virtualNetworks/[^/]+$`)
+	gcpNetworkIDRegexp          = regexp.MustCompile(`^projects/([^/]+)/global/networks/[^/]+$`)
+	gcpSubnetworkIDRegexp       = regexp.MustCompile(`^projects/([^/]+)/regions/([^/]+)/subnetworks/[^/]+$`)
+	gcpCryptoKeyRegexp          = regexp.MustCompile(`^projects/[^/]+/locations/([^/]+)/keyRings/[^/]+/cryptoKeys/[^/]+$`)
+)
+
+// validateInternalLoadBalancer checks that an internal load balancer is only used on providers supporting it.
+func (c *Config) validateInternalLoadBalancer() error {
+	if c.InternalLoadBalancer && c.GetProvider() != cloudprovider.AWS && c.GetProvider() != cloudprovider.GCP {
+		return errors.New("internalLoadBalancer: only supported for AWS and GCP")
+	}
+	return nil
+}
+
+// validateUserDataProvider checks that user data is only set on providers supporting it.
+// The content of the user data is checked by the struct validation.
+func (c *Config) validateUserDataProvider() error {
+	if c.UserData == "" {
+		return nil
+	}
+	switch c.GetProvider() {
+	case cloudprovider.AWS, cloudprovider.Azure, cloudprovider.GCP:
+		return nil
+	default:
+		return errors.New("userData: only supported for AWS, Azure and GCP")
+	}
+}
+
+// validateExistingNetwork checks the references to an existing network the cluster's nodes are attached to,
+// and that the network's CIDR range can be used as the node CIDR of the cluster.
+// Missing fields are reported by the struct validation.
+func (c *Config) validateExistingNetwork() error {
+	var cidrField string
+	switch {
+	case c.Provider.Azure != nil && c.Provider.Azure.VirtualNetworkID != "" && c.Provider.Azure.SubnetID != "":
+		azure := c.Provider.Azure
+		if !azureVirtualNetworkIDRegexp.MatchString(azure.VirtualNetworkID) {
+			return fmt.Errorf("virtualNetworkID: %q is not the resource ID of a virtual network", azure.VirtualNetworkID)
+		}
+		// Azure resource IDs are case-insensitive.
+		subnetName, ok := strings.CutPrefix(strings.ToLower(azure.SubnetID), strings.ToLower(azure.VirtualNetworkID)+"/subnets/")
+		if !ok || subnetName == "" || strings.Contains(subnetName, "/") {
+			return fmt.Errorf("subnetID: %q is not the resource ID of a subnet of virtual network %q", azure.SubnetID, azure.VirtualNetworkID)
+		}
+		cidrField = "subnetCIDR"
+	case c.Provider.GCP != nil && c.Provider.GCP.NetworkID != "" && c.Provider.GCP.SubnetworkID != "":
+		gcp := c.Provider.GCP
+		network := gcpNetworkIDRegexp.FindStringSubmatch(gcp.NetworkID)
+		if network == nil {
+			return fmt.Errorf("networkID: %q is not of the form projects/<project>/global/networks/<name>", gcp.NetworkID)
+		}
+		subnetwork := gcpSubnetworkIDRegexp.FindStringSubmatch(gcp.SubnetworkID)
+		if subnetwork == nil {
+			return fmt.Errorf("subnetworkID: %q is not of the form projects/<project>/regions/<region>/subnetworks/<name>", gcp.SubnetworkID)
+		}
+		if subnetwork[1] != network[1] {
+			return fmt.Errorf("subnetworkID: subnetwork %q is not in project %q of network %q", gcp.SubnetworkID, network[1], gcp.NetworkID)
+		}
+		if subnetwork[2] != gcp.Region {
+			return fmt.Errorf("subnetworkID: subnetwork %q is not in region %q", gcp.SubnetworkID, gcp.Region)
+		}
+		cidrField = "subnetworkCIDR"
+	default:
+		return nil
+	}
+
+	// Invalid CIDRs are reported by the struct validation.
+	nodeCIDR, err := netip.ParsePrefix(c.ExistingNetworkCIDR())
+	if err != nil {
+		return nil
+	}
+	serviceCIDR, err := netip.ParsePrefix(c.ServiceCIDR)
+	if err != nil {
+		return nil
+	}
+	if nodeCIDR.Overlaps(serviceCIDR) {
+		return fmt.Errorf("%s: %s overlaps with serviceCIDR %s", cidrField, nodeCIDR, serviceCIDR)
+	}
+	return nil
+}
+
+// validateGCPDiskEncryptionKey checks that the Cloud KMS key the disks are encrypted with is a key in the cluster's region, or a global key.
+func (c *Config) validateGCPDiskEncryptionKey() error {
+	if c.Provider.GCP == nil || c.Provider.GCP.DiskEncryptionKey == "" {
+		return nil
+	}
+	gcp := c.Provider.GCP
+	key := gcpCryptoKeyRegexp.FindStringSubmatch(gcp.DiskEncryptionKey)
+	if key == nil {
+		return fmt.Errorf("diskEncryptionKey: %q is not of the form projects/<project>/locations/<location>/keyRings/<keyRing>/cryptoKeys/<key>", gcp.DiskEncryptionKey)
+	}
+	if location := key[1]; location != gcp.Region && location != "global" {
+		return fmt.Errorf("diskEncryptionKey: key %q is in location %q, but must be in region %q or global", gcp.DiskEncryptionKey, location, gcp.Region)
+	}
+	return nil
+}
+
+// validateNitroAttestation checks that the Nitro attestation document can be validated
+// against a root certificate and SHA-384 PCR values.
+func (c *Config) validateNitroAttestation() error {
+	if c.Attestation.AWSNitroTPM == nil || c.Attestation.AWSNitroTPM.NitroAttestation == nil {
+		return nil
+	}
+	nitro := c.Attestation.AWSNitroTPM.NitroAttestation
+	if len(nitro.RootCertificate.Raw) == 0 {
+		return errors.New("nitroAttestation: rootCertificate must be set")
+	}
+	for _, idx := range slices.Sorted(maps.Keys(nitro.Measurements)) {
+		measurement := nitro.Measurements[idx]
+		if !measurement.Any && measurement.Len() != sha512.Size384 {
+			return fmt.Errorf("nitroAttestation: measurement %d must be a SHA-384 digest of %d bytes, got %d bytes", idx, sha512.Size384, measurement.Len())
+		}
+	}
+	return nil
+}
+
+var (
+	// managedCharts are the Helm charts managed by Constellation that can be disabled.
+	managedCharts = []string{
+		"coredns", "cert-manager", "constellation-csi", "aws-load-balancer-controller", "yawol",
+		"cilium", "constellation-services", "constellation-operators",
+	}
+	// protectedCharts are the managed charts the security of the cluster depends on.
+	// cilium encrypts the network traffic between nodes, constellation-services runs the
+	// join service and the KMS, and constellation-operators keeps the nodes up to date.
+	protectedCharts = []string{"cilium", "constellation-services", "constellation-operators"}
+)
+
+// validateDisabledCharts checks that only managed charts are disabled,
+// and that protected charts are only disabled if explicitly allowed.
+func (c *Config) validateDisabledCharts() error {
+	for _, chart := range c.DisabledCharts {
+		if !slices.Contains(managedCharts, chart) {
+			return fmt.Errorf("disabledCharts: %q isn't a chart managed by Constellation, must be one of %s", chart, strings.Join(managedCharts, ", "))
+		}
+		if slices.Contains(protectedCharts, chart) && !c.UnsafeAllowDisablingProtectedCharts {
+			return fmt.Errorf("disabledCharts: disabling %q weakens the security of the cluster and requires unsafeAllowDisablingProtectedCharts to be set", chart)
+		}
+	}
+	return nil
+}
+
+// validateReadinessTimeouts checks that the readiness timeouts are positive durations of managed charts.
+func (c *Config) validateReadinessTimeouts() error {
+	for _, chart := range slices.Sorted(maps.Keys(c.ReadinessTimeouts)) {
+		if !slices.Contains(managedCharts, chart) {
+			return fmt.Errorf("readinessTimeouts: %q isn't a chart managed by Constellation, must be one of %s", chart, strings.Join(managedCharts, ", "))
+		}
+		timeout, err := time.ParseDuration(c.ReadinessTimeouts[chart])
+		if err != nil {
+			return fmt.Errorf("readinessTimeouts: timeout of %q: %w", chart, err)
+		}
+		if timeout <= 0 {
+			return fmt.Errorf("readinessTimeouts: timeout of %q must be positive, got %s", chart, c.ReadinessTimeouts[chart])
+		}
+	}
+	return nil
+}
+
+// ReadinessTimeoutDurations returns the readiness timeouts of the Helm charts.
+// Timeouts that can't be parsed are skipped, they are reported by the validation of the config.
+func (c *Config) ReadinessTimeoutDurations() map[string]time.Duration {
+	if len(c.ReadinessTimeouts) == 0 {
+		return nil
+	}
+	timeouts := make(map[string]time.Duration, len(c.ReadinessTimeouts))
+	for chart, timeout := range c.ReadinessTimeouts {
+		if duration, err := time.ParseDuration(timeout); err == nil {
+			timeouts[chart] = duration
+		}
+	}
+	return timeouts
+}
+
+// validateTolerations checks that the tolerations are valid Kubernetes tolerations.
+func (c *Config) validateTolerations() error {
+	for i, toleration := range c.Tolerations {
+		if err := toleration.validate(); err != nil {
+			return fmt.Errorf("tolerations[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (t Toleration) validate() error {
+	if t.Key != "" {
+		if errs := k8svalidation.IsQualifiedName(t.Key); len(errs) > 0 {
+			return fmt.Errorf("invalid key %q: %s", t.Key, strings.Join(errs, "; "))
+		}
+	}
+	switch t.Operator {
+	case "", "Equal":
+		if t.Key == "" {
+			return errors.New("an empty key requires the operator \"Exists\"")
+		}
+		if errs := k8svalidation.IsValidLabelValue(t.Value); len(errs) > 0 {
+			return fmt.Errorf("invalid value %q: %s", t.Value, strings.Join(errs, "; "))
+		}
+	case "Exists":
+		if t.Value != "" {
+			return errors.New("value must be empty if the operator is \"Exists\"")
+		}
+	default:
+		return fmt.Errorf("invalid operator %q, must be one of \"Equal\" or \"Exists\"", t.Operator)
+	}
+	switch t.Effect {
+	case "", "NoSchedule", "PreferNoSchedule", "NoExecute":
+	default:
+		return fmt.Errorf("invalid effect %q, must be one of \"NoSchedule\", \"PreferNoSchedule\", or \"NoExecute\"", t.Effect)
+	}
+	if t.TolerationSeconds != nil && t.Effect != "NoExecute" {
+		return errors.New("tolerationSeconds requires the effect \"NoExecute\"")
+	}
+	return nil
+}
+
+// terraformBackendRequiredArgs are the arguments required by the supported Terraform backends.
+var terraformBackendRequiredArgs = map[string][]string{
+	"s3":      {"bucket", "key", "region"},
+	"gcs":     {"bucket"},
+	"azurerm": {"storage_account_name", "container_name", "key"},
+}
+
+// validateTerraformBackend checks that the Terraform backend is supported and has all required arguments.
+func (c *Config) validateTerraformBackend() error {
+	if c.TerraformBackend == nil {
+		return nil
+	}
+	required, ok := terraformBackendRequiredArgs[c.TerraformBackend.Type]
+	if !ok {
+		return fmt.Errorf("terraformBackend: invalid type %q, must be one of \"s3\", \"gcs\", or \"azurerm\"", c.TerraformBackend.Type)
+	}
+	for _, arg := range required {
+		if c.TerraformBackend.Config[arg] == "" {
+			return fmt.Errorf("terraformBackend: backend %q requires the argument %q", c.TerraformBackend.Type, arg)
+		}
+	}
+	return nil
+}
+
+// validateDebugAccess checks that debug access is only enabled with authorized keys, and that the keys are valid.
+func (c *Config) validateDebugAccess() error {
+	if c.DebugAccess == nil {
+		return nil
+	}
+	if c.DebugAccess.Enabled && len(c.DebugAccess.AuthorizedKeys) == 0 {
+		return errors.New("debugAccess: enabling debug access requires at least one authorized key")
+	}
+	for i, key := range c.DebugAccess.AuthorizedKeys {
+		if err := ValidateAuthorizedKey(key); err != nil {
+			return fmt.Errorf("debugAccess: authorized key %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// ValidateAuthorizedKey checks that key is a single public key in OpenSSH authorized_keys format.
+func ValidateAuthorizedKey(key string) error {
+	if strings.ContainsAny(key, "\r\n") {
+		return errors.New("must be a single line")
+	}
+	if _, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key)); err != nil {
+		return fmt.Errorf("parsing public key: %w", err)
+	}
+	return nil
+}
+
+// registryHostRegexp matches a DNS name or IPv4 address.
+var registryHostRegexp = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?)*$`)
+
+// validateRegistryMirrors checks that each registry is configured once, and that the registries and their mirror URLs are valid.
+func (c *Config) validateRegistryMirrors() error {
+	registries := make(map[string]struct{}, len(c.RegistryMirrors))
+	for i, mirror := range c.RegistryMirrors {
+		if err := ValidateRegistryHost(mirror.Registry); err != nil {
+			return fmt.Errorf("registryMirrors[%d]: registry: %w", i, err)
+		}
+		if _, ok := registries[mirror.Registry]; ok {
+			return fmt.Errorf("registryMirrors[%d]: registry %q is configured more than once", i, mirror.Registry)
+		}
+		registries[mirror.Registry] = struct{}{}
+
+		if len(mirror.Endpoints) == 0 {
+			return fmt.Errorf("registryMirrors[%d]: at least one endpoint is required", i)
+		}
+		for j, endpoint := range mirror.Endpoints {
+			if err := ValidateMirrorURL(endpoint); err != nil {
+				return fmt.Errorf("registryMirrors[%d]: endpoint %d: %w", i, j, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateRegistryHost checks that registry is a host name or IP address with an optional port, e.g., "docker.io" or "registry.example.com:5000".
+func ValidateRegistryHost(registry string) error {
+	host := registry
+	if h, port, err := net.SplitHostPort(registry); err == nil {
+		if p, err := strconv.ParseUint(port, 10, 16); err != nil || p == 0 {
+			return fmt.Errorf("invalid port %q", port)
+		}
+		host = h
+	} else if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		host = host[1 : len(host)-1]
+	}
+	if _, err := netip.ParseAddr(host); err == nil {
+		return nil
+	}
+	if !registryHostRegexp.MatchString(host) {
+		return fmt.Errorf("%q isn't a valid host name", registry)
+	}
+	return nil
+}
+
+// ValidateMirrorURL checks that endpoint is an absolute HTTP or HTTPS URL without credentials, query, and fragment.
+func ValidateMirrorURL(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("parsing URL: %w", err)
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return fmt.Errorf("must use the http or https scheme, got %q", u.Scheme)
+	}
+	if err := ValidateRegistryHost(u.Host); err != nil {
+		return fmt.Errorf("host: %w", err)
+	}
+	if u.User != nil {
+		return errors.New("must not contain credentials")
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return errors.New("must not contain a query or fragment")
+	}
+	return nil
+}
+
+// KubernetesTolerations returns the additional tolerations of the system pods as Kubernetes tolerations.
+func (c *Config) KubernetesTolerations() []corev1.Toleration {
+	if len(c.Tolerations) == 0 {
+		return nil
+	}
+	tolerations := make([]corev1.Toleration, 0, len(c.Tolerations))
+	for _, toleration := range c.Tolerations {
+		tolerations = append(tolerations, corev1.Toleration{
+			Key:               toleration.Key,
+			Operator:          corev1.TolerationOperator(toleration.Operator),
+			Value:             toleration.Value,
+			Effect:            corev1.TaintEffect(toleration.Effect),
+			TolerationSeconds: toleration.TolerationSeconds,
+		})
+	}
+	return tolerations
+}
+
+// ChartDisabled returns true if the Helm chart with the given name is disabled.
+func (c *Config) ChartDisabled(name string) bool {
+	return slices.Contains(c.DisabledCharts, name)
+}
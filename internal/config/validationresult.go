@@ -0,0 +1,178 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/edgelesssys/constellation/v2/internal/api/attestationconfigapi"
+	"github.com/edgelesssys/constellation/v2/internal/attestation/variant"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+)
+
+// Severity is the severity of a validation finding.
+type Severity string
+
+const (
+	// SeverityError marks a finding that makes the config invalid.
+	SeverityError Severity = "error"
+	// SeverityWarning marks a finding that doesn't make the config invalid, but should be reviewed.
+	SeverityWarning Severity = "warning"
+	// SeverityInfo marks a purely informational finding.
+	SeverityInfo Severity = "info"
+)
+
+// Finding is a single issue found while validating a config.
+type Finding struct {
+	// Severity of the finding.
+	Severity Severity
+	// Path is the dotted path of the config key the finding refers to.
+	// It is empty if the finding refers to the config as a whole.
+	Path string
+	// Message describes the finding.
+	Message string
+}
+
+// String returns the finding in the form "severity: path: message".
+func (f Finding) String() string {
+	if f.Path == "" {
+		return fmt.Sprintf("%s: %s", f.Severity, f.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", f.Severity, f.Path, f.Message)
+}
+
+// ValidationResult aggregates all findings of a config validation.
+type ValidationResult struct {
+	Findings []Finding
+}
+
+// HasErrors returns true if the result contains a finding of severity [SeverityError].
+func (r *ValidationResult) HasErrors() bool {
+	for _, f := range r.Findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of findings with the given severity.
+func (r *ValidationResult) Count(severity Severity) int {
+	var count int
+	for _, f := range r.Findings {
+		if f.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *ValidationResult) add(severity Severity, path, msg string) {
+	r.Findings = append(r.Findings, Finding{Severity: severity, Path: path, Message: msg})
+}
+
+// ValidateFile reads the config file with the given name, merges the overlay of the profile if one is given, and validates it.
+// In contrast to [NewWithProfile], all findings are collected instead of failing on the first error.
+// An error is only returned if the config file can't be read.
+func ValidateFile(fileHandler file.Handler, name, profile string, fetcher attestationconfigapi.Fetcher, force bool) (*ValidationResult, error) {
+	c, err := load(fileHandler, name, profile, fetcher)
+	if err != nil {
+		return nil, err
+	}
+	return c.ValidationResult(force)
+}
+
+// ValidationResult validates the config and returns all errors, warnings and informational findings.
+// An error is only returned if the validation itself can't be run.
+func (c *Config) ValidationResult(force bool) (*ValidationResult, error) {
+	result := &ValidationResult{}
+
+	validate, trans, err := c.newValidator(force)
+	if err != nil {
+		return nil, err
+	}
+	// Report deprecated keys as findings instead of printing them to stderr.
+	if err := validate.RegisterValidation("deprecated", func(fl validator.FieldLevel) bool {
+		result.add(SeverityWarning, fl.FieldName(), "the key is deprecated and will be removed in an upcoming version")
+		return true
+	}); err != nil {
+		return nil, err
+	}
+
+	if force {
+		result.add(SeverityInfo, "microserviceVersion", "compatibility with the CLI version isn't checked because validation is forced")
+	} else if err := ValidateMicroserviceVersion(constants.BinaryVersion(), c.MicroserviceVersion); err != nil {
+		result.add(SeverityError, "microserviceVersion", msgFromCompatibilityError(err, constants.BinaryVersion().String(), c.MicroserviceVersion.String()))
+	}
+	for _, check := range c.checks() {
+		if err := check.validate(); err != nil {
+			result.add(SeverityError, check.path, findingMessage(check.path, err))
+		}
+	}
+
+	if err := validate.Struct(c); err != nil {
+		var validationErrs validator.ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			return nil, err
+		}
+		for _, e := range validationErrs {
+			result.add(SeverityError, findingPath(e.Namespace()), e.Translate(trans))
+		}
+	}
+
+	c.addWarnings(result)
+	return result, nil
+}
+
+// addWarnings adds findings for valid, but potentially insecure or unintended settings.
+func (c *Config) addWarnings(result *ValidationResult) {
+	if !c.IsReleaseImage() {
+		result.add(SeverityInfo, "image", "doesn't look like a released production image, double check the image before deploying to production")
+	}
+	if c.IsNamedLikeDebugImage() && !c.IsDebugCluster() {
+		result.add(SeverityWarning, "debugCluster", "a debug image is used but debugCluster is false")
+	}
+	if c.IsDebugCluster() {
+		result.add(SeverityWarning, "debugCluster", "debug clusters aren't secure and must not be used in production")
+	}
+	if c.IsDebugAccessEnabled() {
+		result.add(SeverityWarning, "debugAccess", "SSH access to the nodes breaks the confidentiality of the cluster and must not be used in production")
+	}
+	if c.GetAttestationConfig().GetVariant().Equal(variant.AzureTrustedLaunch{}) {
+		result.add(SeverityWarning, "attestation.azureTrustedLaunch", "disabling Confidential VMs is insecure, use only for evaluation purposes")
+	}
+	if len(c.RegistryMirrors) > 0 {
+		result.add(SeverityWarning, "registryMirrors", "mirrors are outside of Constellation's attestation guarantees, only images pinned by digest are protected against a compromised mirror")
+	}
+	if c.UserData != "" {
+		result.add(SeverityWarning, "userData", "user data runs on the nodes outside of Constellation's attestation guarantees")
+	}
+	if os.Getenv(constants.EnvVarAzureClientSecretValue) != "" && c.Provider.Azure != nil {
+		result.add(SeverityWarning, "", fmt.Sprintf("the environment variable %s is no longer used. %s", constants.EnvVarAzureClientSecretValue, appRegistrationErrStr))
+	}
+}
+
+// findingMessage returns the message of an error of a [configCheck] without the
+// key the error is prefixed with, since the key is already part of the path of the finding.
+func findingMessage(path string, err error) string {
+	key := path[strings.LastIndex(path, ".")+1:]
+	msg, _ := strings.CutPrefix(err.Error(), key+": ")
+	return msg
+}
+
+// findingPath converts the namespace of a validated field, e.g. "Config.provider.aws.region",
+// to the dotted path of the config key, e.g. "provider.aws.region".
+func findingPath(namespace string) string {
+	_, path, _ := strings.Cut(namespace, ".")
+	return path
+}
@@ -0,0 +1,128 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidationResult(t *testing.T) {
+	testCases := map[string]struct {
+		modify       func(*Config)
+		force        bool
+		wantFindings []Finding
+		wantErr      bool
+	}{
+		"valid config has no findings": {
+			modify: func(*Config) {},
+		},
+		"warnings and one error": {
+			modify: func(c *Config) {
+				c.DebugCluster = toPtr(true)
+				c.UserData = "#!/bin/sh\necho hello\n"
+				c.InternalLoadBalancer = true
+			},
+			wantFindings: []Finding{
+				{Severity: SeverityError, Path: "internalLoadBalancer", Message: "only supported for AWS and GCP"},
+				{Severity: SeverityWarning, Path: "debugCluster", Message: "debug clusters aren't secure and must not be used in production"},
+				{Severity: SeverityWarning, Path: "userData", Message: "user data runs on the nodes outside of Constellation's attestation guarantees"},
+			},
+			wantErr: true,
+		},
+		"debug access is warned about": {
+			modify: func(c *Config) {
+				c.DebugAccess = &DebugAccessConfig{
+					Enabled:        true,
+					AuthorizedKeys: []string{"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIHO6fX0WwmfN84U0OmpPNix0WMZySwwHKx5vcU21QqMj admin@example.com"},
+				}
+			},
+			wantFindings: []Finding{
+				{Severity: SeverityWarning, Path: "debugAccess", Message: "SSH access to the nodes breaks the confidentiality of the cluster and must not be used in production"},
+			},
+		},
+		"registry mirrors are warned about": {
+			modify: func(c *Config) {
+				c.RegistryMirrors = []RegistryMirror{{Registry: "docker.io", Endpoints: []string{"https://mirror.example.com"}}}
+			},
+			wantFindings: []Finding{
+				{Severity: SeverityWarning, Path: "registryMirrors", Message: "mirrors are outside of Constellation's attestation guarantees, only images pinned by digest are protected against a compromised mirror"},
+			},
+		},
+		"invalid mirror URL": {
+			modify: func(c *Config) {
+				c.RegistryMirrors = []RegistryMirror{{Registry: "docker.io", Endpoints: []string{"ftp://mirror.example.com"}}}
+			},
+			wantFindings: []Finding{
+				{Severity: SeverityError, Path: "registryMirrors", Message: "registryMirrors[0]: endpoint 0: must use the http or https scheme, got \"ftp\""},
+				{Severity: SeverityWarning, Path: "registryMirrors", Message: "mirrors are outside of Constellation's attestation guarantees, only images pinned by digest are protected against a compromised mirror"},
+			},
+			wantErr: true,
+		},
+		"all errors are collected": {
+			modify: func(c *Config) {
+				c.InternalLoadBalancer = true
+				c.Provider.Azure.Location = ""
+			},
+			wantFindings: []Finding{
+				{Severity: SeverityError, Path: "internalLoadBalancer", Message: "only supported for AWS and GCP"},
+				{Severity: SeverityError, Path: "provider.azure.location", Message: "location is a required field"},
+			},
+			wantErr: true,
+		},
+		"checks shared with Validate are collected": {
+			modify: func(c *Config) {
+				c.DisabledCharts = []string{"foo"}
+				c.ReadinessTimeouts = map[string]string{"cilium": "-1s"}
+				c.Tolerations = []Toleration{{Key: "foo", Operator: "Foo"}}
+				c.TerraformBackend = &TerraformBackendConfig{Type: "foo"}
+			},
+			wantFindings: []Finding{
+				{Severity: SeverityError, Path: "disabledCharts", Message: "\"foo\" isn't a chart managed by Constellation, must be one of coredns, cert-manager, constellation-csi, aws-load-balancer-controller, yawol, cilium, constellation-services, constellation-operators"},
+				{Severity: SeverityError, Path: "readinessTimeouts", Message: "timeout of \"cilium\" must be positive, got -1s"},
+				{Severity: SeverityError, Path: "tolerations", Message: "tolerations[0]: invalid operator \"Foo\", must be one of \"Equal\" or \"Exists\""},
+				{Severity: SeverityError, Path: "terraformBackend", Message: "invalid type \"foo\", must be one of \"s3\", \"gcs\", or \"azurerm\""},
+			},
+			wantErr: true,
+		},
+		"forced validation is reported": {
+			modify: func(*Config) {},
+			force:  true,
+			wantFindings: []Finding{
+				{Severity: SeverityInfo, Path: "microserviceVersion", Message: "compatibility with the CLI version isn't checked because validation is forced"},
+			},
+		},
+		"non-release image is reported": {
+			modify: func(c *Config) { c.Image = "ref/main/stream/nightly/v2.0.0-pre.0.20230101000000-0123456789ab" },
+			force:  true,
+			wantFindings: []Finding{
+				{Severity: SeverityInfo, Path: "microserviceVersion", Message: "compatibility with the CLI version isn't checked because validation is forced"},
+				{Severity: SeverityInfo, Path: "image", Message: "doesn't look like a released production image, double check the image before deploying to production"},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			cnf := Default()
+			modifyConfigForAzureToPassValidate(cnf)
+			tc.modify(cnf)
+
+			result, err := cnf.ValidationResult(tc.force)
+			require.NoError(err)
+			assert.Equal(tc.wantFindings, result.Findings)
+			assert.Equal(tc.wantErr, result.HasErrors())
+			// Validate must fail for exactly the configs the result has errors for.
+			assert.Equal(tc.wantErr, cnf.Validate(tc.force) != nil)
+		})
+	}
+}
@@ -76,6 +76,10 @@ const (
 
 	// StateFilename filename that contains the entire state of the Constellation cluster.
 	StateFilename = "constellation-state.yaml"
+	// StateLockFilename filename of the lock guarding the state file against concurrent modifications.
+	StateLockFilename = "constellation-state.lock"
+	// StateDumpFilename filename the in-memory state is written to if apply fails.
+	StateDumpFilename = "constellation-state.dump.yaml"
 	// ConfigFilename filename of Constellation config file.
 	ConfigFilename = "constellation-conf.yaml"
 	// LicenseFilename filename of Constellation license file.
@@ -136,6 +140,9 @@ const (
 	CertCacheArkKey = "ark"
 	// NodeVersionResourceName resource name used for NodeVersion in constellation-operator and CLI.
 	NodeVersionResourceName = "constellation-version"
+	// NodeGroupLabelKey is the name of the label marking a NodeVersion that only applies to the scaling groups of a single node group.
+	// Its value is the name of the node group.
+	NodeGroupLabelKey = "constellation.edgeless.systems/node-group"
 	// NodeKubernetesComponentsAnnotationKey is the name of the annotation holding the reference to the ConfigMap listing all K8s components.
 	NodeKubernetesComponentsAnnotationKey = "constellation.edgeless.systems/kubernetes-components"
 	// JoiningNodesConfigMapName is the name of the configMap holding the joining nodes with the components hashes the node-operator should annotate the nodes with.
@@ -157,6 +164,12 @@ const (
 	// EnvVarOpenStackPassword is environment variable to overwrite
 	// provider.openstack.password .
 	EnvVarOpenStackPassword = EnvVarPrefix + "OS_PASSWORD"
+	// EnvVarAttestationPrefix is the prefix of environment variables used to overwrite
+	// fields of the attestation config, e.g., CONSTELL_ATTESTATION_BOOTLOADER_VERSION for attestation.<variant>.bootloaderVersion .
+	EnvVarAttestationPrefix = EnvVarPrefix + "ATTESTATION_"
+	// EnvVarAttestationMeasurementPrefix is the prefix of environment variables used to overwrite
+	// a single measurement of the attestation config, e.g., CONSTELL_ATTESTATION_MEASUREMENT_4 for index 4.
+	EnvVarAttestationMeasurementPrefix = EnvVarAttestationPrefix + "MEASUREMENT_"
 	// EnvVarNoSpinner is environment variable used to disable the loading indicator (spinner)
 	// displayed in Constellation CLI. Any non-empty value, e.g., CONSTELL_NO_SPINNER=1,
 	// can be used to disable the spinner.
@@ -222,6 +235,10 @@ const (
 	JoinConfigMap = "join-config"
 	// InternalConfigMap k8s config map with internal Constellation config.
 	InternalConfigMap = "internal-config"
+	// DebugAccessAuthorizedKeysKey is the key in the internal-config ConfigMap holding the newline separated authorized keys of the debug access.
+	DebugAccessAuthorizedKeysKey = "debugAccessAuthorizedKeys"
+	// RegistryMirrorsKey is the key in the internal-config ConfigMap holding the JSON encoded registry mirrors of the cluster.
+	RegistryMirrorsKey = "registryMirrors"
 	// KubeadmConfigMap k8s config map with kubeadm config
 	// (holds ClusterConfiguration).
 	KubeadmConfigMap = "kubeadm-config"
@@ -43,10 +43,14 @@ type Applier struct {
 	applyContext ApplyContext
 
 	// newDialer creates a new aTLS gRPC dialer.
-	newDialer     func(validator atls.Validator) *dialer.Dialer
-	kubecmdClient kubecmdClient
-	helmClient    helmApplier
-	dynamicClient dynamic.Interface
+	newDialer func(validator atls.Validator) *dialer.Dialer
+	// initNonceSource provides the nonce of the aTLS handshake of the init RPC.
+	// If nil, the dialer's default source is used.
+	initNonceSource atls.NonceSource
+	initRetry       InitRetry
+	kubecmdClient   kubecmdClient
+	helmClient      helmApplier
+	dynamicClient   dynamic.Interface
 }
 
 type licenseChecker interface {
@@ -69,9 +73,21 @@ func NewApplier(
 		licenseChecker: license.NewChecker(),
 		applyContext:   applyContext,
 		newDialer:      newDialer,
+		initRetry:      DefaultInitRetry,
 	}
 }
 
+// SetInitRetry configures the retries of the init RPC.
+func (a *Applier) SetInitRetry(initRetry InitRetry) {
+	a.initRetry = initRetry
+}
+
+// SetInitNonceSource sets the source of the nonce the init RPC's aTLS handshake binds the bootstrapper's attestation to.
+// By default, nonces are generated using crypto/rand.
+func (a *Applier) SetInitNonceSource(nonceSource atls.NonceSource) {
+	a.initNonceSource = nonceSource
+}
+
 // SetKubeConfig sets the config file to use for creating Kubernetes clients.
 func (a *Applier) SetKubeConfig(kubeConfig []byte) error {
 	kubecmdClient, err := kubecmd.New(kubeConfig, a.log)
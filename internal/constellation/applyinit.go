@@ -20,6 +20,7 @@ import (
 
 	"github.com/edgelesssys/constellation/v2/bootstrapper/initproto"
 	"github.com/edgelesssys/constellation/v2/internal/atls"
+	"github.com/edgelesssys/constellation/v2/internal/config"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
 	"github.com/edgelesssys/constellation/v2/internal/grpc/grpclog"
@@ -38,6 +39,26 @@ type InitPayload struct {
 	K8sVersion      versions.ValidK8sVersion
 	ConformanceMode bool
 	ServiceCIDR     string
+	OIDC            *config.OIDCConfig
+	// DebugAccess enables SSH access to the nodes for debugging if it is set and enabled.
+	DebugAccess *config.DebugAccessConfig
+	// RegistryMirrors are the mirrors containerd pulls the images of the registries from.
+	RegistryMirrors []config.RegistryMirror
+}
+
+// InitRetry configures the retries of the init RPC while the bootstrapper of the first node is still booting.
+type InitRetry struct {
+	// Timeout bounds the total time of the init RPC, including retries. If it is 0, only the context bounds it.
+	Timeout time.Duration
+	// Interval is the wait time before the first retry. It doubles with every retry, up to MaxInterval.
+	Interval    time.Duration
+	MaxInterval time.Duration
+}
+
+// DefaultInitRetry retries the init RPC quickly at first, since the first node is usually booted shortly after creation.
+var DefaultInitRetry = InitRetry{
+	Interval:    5 * time.Second,
+	MaxInterval: 30 * time.Second,
 }
 
 // GrpcDialer dials a gRPC server.
@@ -69,9 +90,32 @@ func (a *Applier) Init(
 		ApiserverCertSans:    state.Infrastructure.APIServerCertSANs,
 		ServiceCidr:          payload.ServiceCIDR,
 	}
+	if payload.OIDC != nil {
+		req.OidcConfig = &initproto.OIDCConfig{
+			IssuerUrl:      payload.OIDC.IssuerURL,
+			ClientId:       payload.OIDC.ClientID,
+			UsernameClaim:  payload.OIDC.UsernameClaim,
+			UsernamePrefix: payload.OIDC.UsernamePrefix,
+			GroupsClaim:    payload.OIDC.GroupsClaim,
+			GroupsPrefix:   payload.OIDC.GroupsPrefix,
+		}
+	}
+	if payload.DebugAccess != nil && payload.DebugAccess.Enabled {
+		req.DebugAccess = &initproto.DebugAccess{AuthorizedKeys: payload.DebugAccess.AuthorizedKeys}
+	}
+	for _, mirror := range payload.RegistryMirrors {
+		req.RegistryMirrors = append(req.RegistryMirrors, &initproto.RegistryMirror{
+			Registry:  mirror.Registry,
+			Endpoints: mirror.Endpoints,
+		})
+	}
 
+	initDialer := a.newDialer(validator)
+	if a.initNonceSource != nil {
+		initDialer.SetNonceSource(a.initNonceSource)
+	}
 	doer := &initDoer{
-		dialer: a.newDialer(validator),
+		dialer: initDialer,
 		endpoint: net.JoinHostPort(
 			state.Infrastructure.ClusterEndpoint,
 			strconv.Itoa(constants.BootstrapperPort),
@@ -83,17 +127,39 @@ func (a *Applier) Init(
 	}
 
 	// Create a wrapper function that allows logging any returned error from the retrier before checking if it's the expected retriable one.
+	// The last retriable error is kept to explain why the bootstrapper couldn't be reached if the retries time out.
+	var lastUnavailableErr error
 	serviceIsUnavailable := func(err error) bool {
 		isServiceUnavailable := grpcRetry.ServiceIsUnavailable(err)
 		a.log.Debug(fmt.Sprintf("Encountered error (retriable: %t): %q", isServiceUnavailable, err))
+		if isServiceUnavailable {
+			lastUnavailableErr = err
+		}
 		return isServiceUnavailable
 	}
 
+	initRetry := a.initRetry
+	if initRetry.Interval <= 0 {
+		initRetry.Interval = DefaultInitRetry.Interval
+		initRetry.MaxInterval = DefaultInitRetry.MaxInterval
+	}
+	if initRetry.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, initRetry.Timeout)
+		defer cancel()
+	}
+
 	// Perform the RPC
 	a.log.Debug("Initialization call", "endpoint", doer.endpoint)
 	a.spinner.Start("Connecting ", false)
-	retrier := retry.NewIntervalRetrier(doer, 30*time.Second, serviceIsUnavailable)
+	retrier := retry.NewBackoffRetrier(doer, initRetry.Interval, initRetry.MaxInterval, serviceIsUnavailable)
 	if err := retrier.Do(ctx); err != nil {
+		switch {
+		case grpcRetry.AuthenticationFailed(err):
+			return InitOutput{}, &InitAttestationError{Err: err}
+		case ctx.Err() != nil && errors.Is(err, ctx.Err()) && lastUnavailableErr != nil:
+			return InitOutput{}, &InitUnreachableError{Endpoint: doer.endpoint, Err: lastUnavailableErr}
+		}
 		return InitOutput{}, fmt.Errorf("doing init call: %w", err)
 	}
 	a.spinner.Stop()
@@ -289,6 +355,39 @@ func (d *initDoer) handleGRPCStateChanges(ctx context.Context, wg *sync.WaitGrou
 	})
 }
 
+// InitAttestationError is returned if the bootstrapper was reached, but its attestation couldn't be verified.
+// The init request isn't sent to the bootstrapper in this case.
+type InitAttestationError struct {
+	Err error
+}
+
+// Error returns the error message.
+func (e *InitAttestationError) Error() string {
+	return fmt.Sprintf("init rejected: verifying the attestation of the bootstrapper failed: %s", e.Err)
+}
+
+// Unwrap returns the wrapped error.
+func (e *InitAttestationError) Unwrap() error {
+	return e.Err
+}
+
+// InitUnreachableError is returned if the bootstrapper didn't become reachable before the init RPC timed out.
+type InitUnreachableError struct {
+	Endpoint string
+	// Err is the error of the last connection attempt.
+	Err error
+}
+
+// Error returns the error message.
+func (e *InitUnreachableError) Error() string {
+	return fmt.Sprintf("bootstrapper at %s didn't become reachable, the node may still be booting: %s", e.Endpoint, e.Err)
+}
+
+// Unwrap returns the wrapped error.
+func (e *InitUnreachableError) Unwrap() error {
+	return e.Err
+}
+
 // NonRetriableInitError is returned when the init RPC fails and the error is not retriable.
 type NonRetriableInitError struct {
 	LogCollectionErr error
@@ -14,6 +14,8 @@ import (
 	"io"
 	"net"
 	"strconv"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
@@ -66,6 +68,12 @@ func TestInit(t *testing.T) {
 		server             initproto.APIServer
 		state              *state.State
 		initServerEndpoint string
+		oidc               *config.OIDCConfig
+		wantOIDCConfig     *initproto.OIDCConfig
+		debugAccess        *config.DebugAccessConfig
+		wantAuthorizedKeys []string
+		registryMirrors    []config.RegistryMirror
+		wantMirrors        []*initproto.RegistryMirror
 		wantClusterLogs    []byte
 		wantErr            bool
 	}{
@@ -83,6 +91,89 @@ func TestInit(t *testing.T) {
 			state:              newState(clusterEndpoint),
 			initServerEndpoint: clusterEndpoint,
 		},
+		"success with OIDC config": {
+			server: newInitServer(nil,
+				&initproto.InitResponse{
+					Kind: &initproto.InitResponse_InitSuccess{
+						InitSuccess: &initproto.InitSuccessResponse{
+							Kubeconfig: respKubeconfigBytes,
+							OwnerId:    []byte{},
+							ClusterId:  []byte{},
+						},
+					},
+				}),
+			state:              newState(clusterEndpoint),
+			initServerEndpoint: clusterEndpoint,
+			oidc: &config.OIDCConfig{
+				IssuerURL:      "https://login.example.com",
+				ClientID:       "constellation",
+				UsernameClaim:  "email",
+				UsernamePrefix: "oidc:",
+				GroupsClaim:    "groups",
+				GroupsPrefix:   "oidc:",
+			},
+			wantOIDCConfig: &initproto.OIDCConfig{
+				IssuerUrl:      "https://login.example.com",
+				ClientId:       "constellation",
+				UsernameClaim:  "email",
+				UsernamePrefix: "oidc:",
+				GroupsClaim:    "groups",
+				GroupsPrefix:   "oidc:",
+			},
+		},
+		"success with debug access": {
+			server: newInitServer(nil,
+				&initproto.InitResponse{
+					Kind: &initproto.InitResponse_InitSuccess{
+						InitSuccess: &initproto.InitSuccessResponse{
+							Kubeconfig: respKubeconfigBytes,
+							OwnerId:    []byte{},
+							ClusterId:  []byte{},
+						},
+					},
+				}),
+			state:              newState(clusterEndpoint),
+			initServerEndpoint: clusterEndpoint,
+			debugAccess:        &config.DebugAccessConfig{Enabled: true, AuthorizedKeys: []string{"ssh-ed25519 AAAA admin@example.com"}},
+			wantAuthorizedKeys: []string{"ssh-ed25519 AAAA admin@example.com"},
+		},
+		"success with registry mirrors": {
+			server: newInitServer(nil,
+				&initproto.InitResponse{
+					Kind: &initproto.InitResponse_InitSuccess{
+						InitSuccess: &initproto.InitSuccessResponse{
+							Kubeconfig: respKubeconfigBytes,
+							OwnerId:    []byte{},
+							ClusterId:  []byte{},
+						},
+					},
+				}),
+			state:              newState(clusterEndpoint),
+			initServerEndpoint: clusterEndpoint,
+			registryMirrors: []config.RegistryMirror{
+				{Registry: "docker.io", Endpoints: []string{"https://mirror.example.com", "https://fallback.example.com"}},
+				{Registry: "ghcr.io", Endpoints: []string{"https://mirror.example.com"}},
+			},
+			wantMirrors: []*initproto.RegistryMirror{
+				{Registry: "docker.io", Endpoints: []string{"https://mirror.example.com", "https://fallback.example.com"}},
+				{Registry: "ghcr.io", Endpoints: []string{"https://mirror.example.com"}},
+			},
+		},
+		"disabled debug access isn't sent": {
+			server: newInitServer(nil,
+				&initproto.InitResponse{
+					Kind: &initproto.InitResponse_InitSuccess{
+						InitSuccess: &initproto.InitSuccessResponse{
+							Kubeconfig: respKubeconfigBytes,
+							OwnerId:    []byte{},
+							ClusterId:  []byte{},
+						},
+					},
+				}),
+			state:              newState(clusterEndpoint),
+			initServerEndpoint: clusterEndpoint,
+			debugAccess:        &config.DebugAccessConfig{AuthorizedKeys: []string{"ssh-ed25519 AAAA admin@example.com"}},
+		},
 		"kubeconfig without clusters": {
 			server: newInitServer(nil,
 				&initproto.InitResponse{
@@ -221,12 +312,29 @@ func TestInit(t *testing.T) {
 				MeasurementSalt: []byte{},
 				K8sVersion:      "v1.26.5",
 				ConformanceMode: false,
+				OIDC:            tc.oidc,
+				DebugAccess:     tc.debugAccess,
+				RegistryMirrors: tc.registryMirrors,
 			})
 			if tc.wantErr {
 				assert.Error(err)
 				assert.Equal(tc.wantClusterLogs, clusterLogs.Bytes())
 			} else {
 				assert.NoError(err)
+				if stub, ok := tc.server.(*stubInitServer); ok {
+					assert.Equal(tc.wantOIDCConfig.GetIssuerUrl(), stub.gotReq.GetOidcConfig().GetIssuerUrl())
+					assert.Equal(tc.wantOIDCConfig.GetClientId(), stub.gotReq.GetOidcConfig().GetClientId())
+					assert.Equal(tc.wantOIDCConfig.GetUsernameClaim(), stub.gotReq.GetOidcConfig().GetUsernameClaim())
+					assert.Equal(tc.wantOIDCConfig.GetUsernamePrefix(), stub.gotReq.GetOidcConfig().GetUsernamePrefix())
+					assert.Equal(tc.wantOIDCConfig.GetGroupsClaim(), stub.gotReq.GetOidcConfig().GetGroupsClaim())
+					assert.Equal(tc.wantOIDCConfig.GetGroupsPrefix(), stub.gotReq.GetOidcConfig().GetGroupsPrefix())
+					assert.Equal(tc.wantAuthorizedKeys, stub.gotReq.GetDebugAccess().GetAuthorizedKeys())
+					require.Len(t, stub.gotReq.GetRegistryMirrors(), len(tc.wantMirrors))
+					for i, mirror := range tc.wantMirrors {
+						assert.Equal(mirror.GetRegistry(), stub.gotReq.GetRegistryMirrors()[i].GetRegistry())
+						assert.Equal(mirror.GetEndpoints(), stub.gotReq.GetRegistryMirrors()[i].GetEndpoints())
+					}
+				}
 			}
 		})
 	}
@@ -278,7 +386,7 @@ func TestAttestation(t *testing.T) {
 			12: measurements.WithAllBytes(0xcc, measurements.Enforce, measurements.PCRMeasurementLength),
 		},
 	}
-	state := &state.State{Version: state.Version1, Infrastructure: state.Infrastructure{ClusterEndpoint: "192.0.2.4"}}
+	state := &state.State{Version: state.Version2, Infrastructure: state.Infrastructure{ClusterEndpoint: "192.0.2.4"}}
 
 	ctx := context.Background()
 	ctx, cancel := context.WithTimeout(ctx, 4*time.Second)
@@ -290,6 +398,8 @@ func TestAttestation(t *testing.T) {
 			return dialer.New(nil, v, netDialer)
 		},
 		spinner: &nopSpinner{},
+		// A retry would wait longer than the context allows, so the error would be a timeout.
+		initRetry: InitRetry{Interval: time.Hour},
 	}
 
 	_, err := initer.Init(ctx, validator, state, io.Discard, InitPayload{
@@ -301,11 +411,201 @@ func TestAttestation(t *testing.T) {
 	assert.Error(err)
 	// make sure the error is actually a TLS handshake error
 	assert.Contains(err.Error(), "transport: authentication handshake failed")
+	var attestationErr *InitAttestationError
+	assert.ErrorAs(err, &attestationErr)
 	if validationErr, ok := err.(*config.ValidationError); ok {
 		t.Log(validationErr.LongMessage())
 	}
 }
 
+func TestInitRetry(t *testing.T) {
+	respKubeconfigBytes, err := clientcmd.Write(k8sclientapi.Config{
+		Clusters: map[string]*k8sclientapi.Cluster{"cluster": {Server: "https://192.0.2.1:6443"}},
+	})
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		refusedDials    int
+		serverDown      bool
+		timeout         time.Duration
+		wantErr         bool
+		wantUnreachable bool
+	}{
+		"connection refused while booting, then success": {
+			refusedDials: 3,
+		},
+		"bootstrapper never becomes reachable": {
+			serverDown:      true,
+			timeout:         500 * time.Millisecond,
+			wantErr:         true,
+			wantUnreachable: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			netDialer := &bootingDialer{BufconnDialer: testdialer.NewBufconnDialer(), refusedDials: tc.refusedDials}
+			server := &stubInitServer{res: []*initproto.InitResponse{{
+				Kind: &initproto.InitResponse_InitSuccess{
+					InitSuccess: &initproto.InitSuccessResponse{Kubeconfig: respKubeconfigBytes},
+				},
+			}}}
+			if !tc.serverDown {
+				stop := setupTestInitServer(netDialer.BufconnDialer, server, "192.0.2.1")
+				defer stop()
+			}
+
+			a := &Applier{
+				log:     logger.NewTest(t),
+				spinner: &nopSpinner{},
+				newDialer: func(atls.Validator) *dialer.Dialer {
+					return dialer.New(nil, nil, netDialer)
+				},
+				initRetry: InitRetry{Timeout: tc.timeout, Interval: 10 * time.Millisecond, MaxInterval: 50 * time.Millisecond},
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 4*time.Second)
+			defer cancel()
+			_, err := a.Init(ctx, nil, &state.State{Infrastructure: state.Infrastructure{ClusterEndpoint: "192.0.2.1"}}, io.Discard, InitPayload{
+				MasterSecret: uri.MasterSecret{},
+				K8sVersion:   "v1.26.5",
+			})
+			if tc.wantErr {
+				require.Error(err)
+				var unreachableErr *InitUnreachableError
+				assert.Equal(tc.wantUnreachable, errors.As(err, &unreachableErr))
+				assert.NoError(ctx.Err(), "retries must stop at the init timeout")
+				return
+			}
+			require.NoError(err)
+			assert.Greater(netDialer.dialCount(), tc.refusedDials)
+			assert.NotNil(server.gotReq)
+		})
+	}
+}
+
+func TestInitNonceSource(t *testing.T) {
+	respKubeconfigBytes, err := clientcmd.Write(k8sclientapi.Config{
+		Clusters: map[string]*k8sclientapi.Cluster{"cluster": {Server: "https://192.0.2.1:6443"}},
+	})
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		nonce   []byte
+		wantErr bool
+	}{
+		"nonce is used in handshake": {
+			nonce: bytes.Repeat([]byte{0x42}, atls.NonceLength),
+		},
+		"invalid nonce length": {
+			nonce:   []byte{0x42},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			netDialer := testdialer.NewBufconnDialer()
+			issuer := &nonceRecordingIssuer{FakeIssuer: atls.NewFakeIssuer(variant.Dummy{})}
+			initServer := grpc.NewServer(grpc.Creds(atlscredentials.New(issuer, nil)))
+			initproto.RegisterAPIServer(initServer, &stubInitServer{res: []*initproto.InitResponse{{
+				Kind: &initproto.InitResponse_InitSuccess{
+					InitSuccess: &initproto.InitSuccessResponse{Kubeconfig: respKubeconfigBytes},
+				},
+			}}})
+			listener := netDialer.GetListener(net.JoinHostPort("192.0.2.1", strconv.Itoa(constants.BootstrapperPort)))
+			go initServer.Serve(listener)
+			defer initServer.GracefulStop()
+
+			a := &Applier{
+				log:     logger.NewTest(t),
+				spinner: &nopSpinner{},
+				newDialer: func(v atls.Validator) *dialer.Dialer {
+					return dialer.New(nil, v, netDialer)
+				},
+				// A retry would wait longer than the context allows, so the handshake error is returned.
+				initRetry: InitRetry{Interval: time.Hour},
+			}
+			a.SetInitNonceSource(&stubNonceSource{nonce: tc.nonce})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 4*time.Second)
+			defer cancel()
+			_, err := a.Init(ctx, atls.NewFakeValidator(variant.Dummy{}), &state.State{Infrastructure: state.Infrastructure{ClusterEndpoint: "192.0.2.1"}}, io.Discard, InitPayload{
+				MasterSecret: uri.MasterSecret{},
+				K8sVersion:   "v1.26.5",
+			})
+			if tc.wantErr {
+				assert.ErrorContains(err, "invalid nonce length")
+				assert.Empty(issuer.getNonces())
+				return
+			}
+			require.NoError(err)
+			assert.Equal([][]byte{tc.nonce}, issuer.getNonces())
+		})
+	}
+}
+
+type stubNonceSource struct {
+	nonce []byte
+}
+
+func (s *stubNonceSource) Nonce() ([]byte, error) {
+	return s.nonce, nil
+}
+
+// nonceRecordingIssuer records the nonces it issues attestation documents for.
+type nonceRecordingIssuer struct {
+	*atls.FakeIssuer
+
+	mut    sync.Mutex
+	nonces [][]byte
+}
+
+func (i *nonceRecordingIssuer) Issue(ctx context.Context, userData []byte, nonce []byte) ([]byte, error) {
+	i.mut.Lock()
+	i.nonces = append(i.nonces, nonce)
+	i.mut.Unlock()
+	return i.FakeIssuer.Issue(ctx, userData, nonce)
+}
+
+func (i *nonceRecordingIssuer) getNonces() [][]byte {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+	return i.nonces
+}
+
+// bootingDialer refuses the first connections, like a node whose bootstrapper hasn't started yet.
+type bootingDialer struct {
+	*testdialer.BufconnDialer
+	refusedDials int
+
+	mut   sync.Mutex
+	dials int
+}
+
+func (d *bootingDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	d.mut.Lock()
+	d.dials++
+	refuse := d.dials <= d.refusedDials
+	d.mut.Unlock()
+	if refuse {
+		return nil, syscall.ECONNREFUSED
+	}
+	return d.BufconnDialer.DialContext(ctx, network, address)
+}
+
+func (d *bootingDialer) dialCount() int {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	return d.dials
+}
+
 type testValidator struct {
 	variant.Getter
 	pcrs measurements.M
@@ -367,11 +667,13 @@ func setupTestInitServer(dialer *testdialer.BufconnDialer, server initproto.APIS
 type stubInitServer struct {
 	res     []*initproto.InitResponse
 	initErr error
+	gotReq  *initproto.InitRequest
 
 	initproto.UnimplementedAPIServer
 }
 
-func (s *stubInitServer) Init(_ *initproto.InitRequest, stream initproto.API_InitServer) error {
+func (s *stubInitServer) Init(req *initproto.InitRequest, stream initproto.API_InitServer) error {
+	s.gotReq = req
 	for _, r := range s.res {
 		_ = stream.Send(r)
 	}
@@ -14,7 +14,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/file"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/client-go/discovery"
@@ -33,21 +32,22 @@ type applyAction interface {
 	SaveChart(chartsDir string, fileHandler file.Handler) error
 	ReleaseName() string
 	IsAtomic() bool
+	Images() ([]string, error)
 }
 
-// newActionConfig creates a new action configuration for helm actions.
-func newActionConfig(kubeConfig []byte, logger debugLog) (*action.Configuration, error) {
+// newActionConfig creates a new action configuration for helm actions on releases in the given namespace.
+func newActionConfig(kubeConfig []byte, namespace string, logger debugLog) (*action.Configuration, error) {
 	actionConfig := &action.Configuration{}
-	if err := actionConfig.Init(&clientGetter{kubeConfig: kubeConfig}, constants.HelmNamespace,
+	if err := actionConfig.Init(&clientGetter{kubeConfig: kubeConfig}, namespace,
 		"secret", helmLog(logger)); err != nil {
 		return nil, err
 	}
 	return actionConfig, nil
 }
 
-func newHelmInstallAction(config *action.Configuration, release release, timeout time.Duration) *action.Install {
+func newHelmInstallAction(config *action.Configuration, namespace string, release release, timeout time.Duration) *action.Install {
 	action := action.NewInstall(config)
-	action.Namespace = constants.HelmNamespace
+	action.Namespace = namespace
 	action.Timeout = timeout
 	action.ReleaseName = release.releaseName
 	setWaitMode(action, release.waitMode)
@@ -130,10 +130,16 @@ func (a *installAction) IsAtomic() bool {
 	return a.helmAction.Atomic
 }
 
-func newHelmUpgradeAction(config *action.Configuration, timeout time.Duration) *action.Upgrade {
+// Images returns the container images of the chart.
+func (a *installAction) Images() ([]string, error) {
+	return releaseImages(a.release, a.helmAction.Namespace)
+}
+
+func newHelmUpgradeAction(config *action.Configuration, namespace string, timeout time.Duration, historyMax int) *action.Upgrade {
 	action := action.NewUpgrade(config)
-	action.Namespace = constants.HelmNamespace
+	action.Namespace = namespace
 	action.Timeout = timeout
+	action.MaxHistory = historyMax
 	action.ReuseValues = false
 	action.Atomic = true
 	return action
@@ -186,6 +192,11 @@ func (a *upgradeAction) IsAtomic() bool {
 	return a.helmAction.Atomic
 }
 
+// Images returns the container images of the chart.
+func (a *upgradeAction) Images() ([]string, error) {
+	return releaseImages(a.release, a.helmAction.Namespace)
+}
+
 func saveChart(release release, chartsDir string, fileHandler file.Handler) error {
 	if err := saveChartToDisk(release.chart, chartsDir, fileHandler); err != nil {
 		return fmt.Errorf("saving chart %s to %q: %w", release.releaseName, chartsDir, err)
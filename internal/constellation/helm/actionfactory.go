@@ -27,6 +27,7 @@ var errReleaseNotFound = errors.New("release not found")
 type actionFactory struct {
 	versionLister releaseVersionLister
 	cfg           *action.Configuration
+	namespace     string
 	kubeClient    crdClient
 	log           debugLog
 }
@@ -35,23 +36,27 @@ type crdClient interface {
 	ApplyCRD(ctx context.Context, rawCRD []byte) error
 }
 
-// newActionFactory creates a new action factory for managing helm releases.
-func newActionFactory(kubeClient crdClient, lister releaseVersionLister, actionConfig *action.Configuration, log debugLog) *actionFactory {
+// newActionFactory creates a new action factory for managing helm releases in the given namespace.
+func newActionFactory(kubeClient crdClient, lister releaseVersionLister, actionConfig *action.Configuration, namespace string, log debugLog) *actionFactory {
 	return &actionFactory{
 		versionLister: lister,
 		cfg:           actionConfig,
+		namespace:     namespace,
 		kubeClient:    kubeClient,
 		log:           log,
 	}
 }
 
 // GetActions returns a list of actions to apply the given releases.
+// Atomic installs and upgrades use atomicTimeout, all other actions use timeout,
+// unless the release overrides its readiness timeout.
+// Upgrades keep at most historyMax revisions of a release, or all revisions if historyMax is 0.
 func (a actionFactory) GetActions(
-	releases []release, configTargetVersion semver.Semver, force, allowDestructive bool, timeout time.Duration,
+	releases []release, configTargetVersion semver.Semver, force, allowDestructive bool, timeout, atomicTimeout time.Duration, historyMax int,
 ) (actions []applyAction, includesUpgrade bool, err error) {
 	upgradeErrs := []error{}
 	for _, release := range releases {
-		err := a.appendNewAction(release, configTargetVersion, force, allowDestructive, timeout, &actions)
+		err := a.appendNewAction(release, configTargetVersion, force, allowDestructive, timeout, atomicTimeout, historyMax, &actions)
 		var invalidUpgrade *compatibility.InvalidUpgradeError
 		if errors.As(err, &invalidUpgrade) {
 			upgradeErrs = append(upgradeErrs, err)
@@ -71,7 +76,7 @@ func (a actionFactory) GetActions(
 }
 
 func (a actionFactory) appendNewAction(
-	release release, configTargetVersion semver.Semver, force, allowDestructive bool, timeout time.Duration, actions *[]applyAction,
+	release release, configTargetVersion semver.Semver, force, allowDestructive bool, timeout, atomicTimeout time.Duration, historyMax int, actions *[]applyAction,
 ) error {
 	newVersion, err := semver.New(release.chart.Metadata.Version)
 	if err != nil {
@@ -91,7 +96,7 @@ func (a actionFactory) appendNewAction(
 		}
 
 		a.log.Debug(fmt.Sprintf("release %q not found, adding to new releases...", release.releaseName))
-		*actions = append(*actions, a.newInstall(release, timeout))
+		*actions = append(*actions, a.newInstall(release, timeout, atomicTimeout))
 		return nil
 	}
 	if err != nil {
@@ -133,20 +138,30 @@ func (a actionFactory) appendNewAction(
 		return ErrConfirmationMissing
 	}
 	a.log.Debug(fmt.Sprintf("Upgrading %q from %q to %q", release.releaseName, currentVersion, newVersion))
-	*actions = append(*actions, a.newUpgrade(release, timeout))
+	*actions = append(*actions, a.newUpgrade(release, atomicTimeout, historyMax))
 	return nil
 }
 
-func (a actionFactory) newInstall(release release, timeout time.Duration) *installAction {
-	action := &installAction{helmAction: newHelmInstallAction(a.cfg, release, timeout), release: release, log: a.log}
+func (a actionFactory) newInstall(release release, timeout, atomicTimeout time.Duration) *installAction {
+	if release.waitMode == WaitModeAtomic {
+		timeout = atomicTimeout
+	}
+	if release.readinessTimeout > 0 {
+		timeout = release.readinessTimeout
+	}
+	action := &installAction{helmAction: newHelmInstallAction(a.cfg, a.namespace, release, timeout), release: release, log: a.log}
 	if action.IsAtomic() {
 		action.uninstallAction = newHelmUninstallAction(a.cfg, timeout)
 	}
 	return action
 }
 
-func (a actionFactory) newUpgrade(release release, timeout time.Duration) *upgradeAction {
-	action := &upgradeAction{helmAction: newHelmUpgradeAction(a.cfg, timeout), release: release, log: a.log}
+// newUpgrade creates a new upgrade action. Upgrades are always atomic.
+func (a actionFactory) newUpgrade(release release, timeout time.Duration, historyMax int) *upgradeAction {
+	if release.readinessTimeout > 0 {
+		timeout = release.readinessTimeout
+	}
+	action := &upgradeAction{helmAction: newHelmUpgradeAction(a.cfg, a.namespace, timeout, historyMax), release: release, log: a.log}
 	if release.releaseName == constellationOperatorsInfo.releaseName {
 		action.preUpgrade = func(ctx context.Context) error {
 			if err := a.updateCRDs(ctx, release.chart); err != nil {
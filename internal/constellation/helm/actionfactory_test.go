@@ -12,9 +12,11 @@ import (
 	"time"
 
 	"github.com/edgelesssys/constellation/v2/internal/compatibility"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/logger"
 	"github.com/edgelesssys/constellation/v2/internal/semver"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart"
 )
@@ -240,9 +242,9 @@ func TestAppendNewAction(t *testing.T) {
 			assert := assert.New(t)
 
 			actions := []applyAction{}
-			actionFactory := newActionFactory(nil, tc.lister, &action.Configuration{}, logger.NewTest(t))
+			actionFactory := newActionFactory(nil, tc.lister, &action.Configuration{}, constants.HelmNamespace, logger.NewTest(t))
 
-			err := actionFactory.appendNewAction(tc.release, tc.configTargetVersion, tc.force, tc.allowDestructive, time.Second, &actions)
+			err := actionFactory.appendNewAction(tc.release, tc.configTargetVersion, tc.force, tc.allowDestructive, time.Second, time.Second, 0, &actions)
 			if tc.wantErr {
 				assert.Error(err)
 				if tc.assertErr != nil {
@@ -256,6 +258,83 @@ func TestAppendNewAction(t *testing.T) {
 	}
 }
 
+func TestActionTimeouts(t *testing.T) {
+	const timeout = time.Minute
+	const atomicTimeout = 20 * time.Minute
+
+	testCases := map[string]struct {
+		lister           stubLister
+		waitMode         WaitMode
+		readinessTimeout time.Duration
+		wantTimeout      time.Duration
+	}{
+		"atomic install uses atomic timeout": {
+			lister:      stubLister{err: errReleaseNotFound},
+			waitMode:    WaitModeAtomic,
+			wantTimeout: atomicTimeout,
+		},
+		"non-atomic install uses general timeout": {
+			lister:      stubLister{err: errReleaseNotFound},
+			waitMode:    WaitModeWait,
+			wantTimeout: timeout,
+		},
+		"upgrade uses atomic timeout": {
+			lister:      stubLister{version: semver.NewFromInt(1, 0, 0, "")},
+			waitMode:    WaitModeWait,
+			wantTimeout: atomicTimeout,
+		},
+		"atomic install uses readiness timeout override": {
+			lister:           stubLister{err: errReleaseNotFound},
+			waitMode:         WaitModeAtomic,
+			readinessTimeout: 45 * time.Minute,
+			wantTimeout:      45 * time.Minute,
+		},
+		"non-atomic install uses readiness timeout override": {
+			lister:           stubLister{err: errReleaseNotFound},
+			waitMode:         WaitModeWait,
+			readinessTimeout: 45 * time.Minute,
+			wantTimeout:      45 * time.Minute,
+		},
+		"upgrade uses readiness timeout override": {
+			lister:           stubLister{version: semver.NewFromInt(1, 0, 0, "")},
+			waitMode:         WaitModeWait,
+			readinessTimeout: 45 * time.Minute,
+			wantTimeout:      45 * time.Minute,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			rel := release{
+				releaseName:      "test",
+				chart:            &chart.Chart{Metadata: &chart.Metadata{Version: "1.1.0"}},
+				waitMode:         tc.waitMode,
+				readinessTimeout: tc.readinessTimeout,
+			}
+			actionFactory := newActionFactory(nil, tc.lister, &action.Configuration{}, constants.HelmNamespace, logger.NewTest(t))
+
+			actions, _, err := actionFactory.GetActions([]release{rel}, semver.NewFromInt(1, 1, 0, ""), false, false, timeout, atomicTimeout, 0)
+			require.NoError(err)
+			require.Len(actions, 1)
+
+			switch a := actions[0].(type) {
+			case *installAction:
+				assert.Equal(tc.wantTimeout, a.helmAction.Timeout)
+				if a.uninstallAction != nil {
+					assert.Equal(tc.wantTimeout, a.uninstallAction.Timeout)
+				}
+			case *upgradeAction:
+				assert.Equal(tc.wantTimeout, a.helmAction.Timeout)
+			default:
+				t.Fatalf("unexpected action type %T", a)
+			}
+		})
+	}
+}
+
 type stubLister struct {
 	err     error
 	version semver.Semver
@@ -0,0 +1,111 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package helm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/edgelesssys/constellation/v2/internal/semver"
+)
+
+// ChartCompatibilityMatrix maps Helm release names to the chart versions compatible with a Constellation version.
+// Releases without an entry aren't restricted.
+type ChartCompatibilityMatrix map[string]ChartCompatibility
+
+// ChartCompatibility describes which chart versions of a release are compatible with which Constellation versions.
+type ChartCompatibility struct {
+	// MatchCLIVersion requires the chart version to be equal to the CLI version.
+	// This is the case for charts we package ourselves.
+	MatchCLIVersion bool
+	// Ranges are the compatible chart versions for ranges of Constellation versions, sorted by FromCLIVersion.
+	Ranges []ChartVersionRange
+}
+
+// ChartVersionRange is the range of chart versions compatible with Constellation versions
+// starting at FromCLIVersion, up to the FromCLIVersion of the next range.
+// Only the major and minor version of FromCLIVersion are compared, so pre-releases of a version are included.
+type ChartVersionRange struct {
+	// FromCLIVersion is the first Constellation version this range applies to.
+	FromCLIVersion semver.Semver
+	// MinChartVersion is the lowest compatible chart version.
+	MinChartVersion semver.Semver
+	// MaxChartVersion is the lowest chart version that is no longer compatible.
+	MaxChartVersion semver.Semver
+}
+
+// DefaultChartCompatibility is the compatibility matrix of the charts embedded in the CLI.
+// Update it together with the charts.
+var DefaultChartCompatibility = ChartCompatibilityMatrix{
+	constellationOperatorsInfo.releaseName: {MatchCLIVersion: true},
+	constellationServicesInfo.releaseName:  {MatchCLIVersion: true},
+	csiInfo.releaseName:                    {MatchCLIVersion: true},
+	ciliumInfo.releaseName: {Ranges: []ChartVersionRange{
+		{
+			FromCLIVersion:  semver.NewFromInt(2, 20, 0, ""),
+			MinChartVersion: semver.NewFromInt(1, 15, 0, ""),
+			MaxChartVersion: semver.NewFromInt(1, 16, 0, ""),
+		},
+	}},
+	certManagerInfo.releaseName: {Ranges: []ChartVersionRange{
+		{
+			FromCLIVersion:  semver.NewFromInt(2, 20, 0, ""),
+			MinChartVersion: semver.NewFromInt(1, 15, 0, ""),
+			MaxChartVersion: semver.NewFromInt(1, 16, 0, ""),
+		},
+	}},
+	awsLBControllerInfo.releaseName: {Ranges: []ChartVersionRange{
+		{
+			FromCLIVersion:  semver.NewFromInt(2, 20, 0, ""),
+			MinChartVersion: semver.NewFromInt(1, 5, 0, ""),
+			MaxChartVersion: semver.NewFromInt(1, 6, 0, ""),
+		},
+	}},
+}
+
+// CheckChartVersion checks if chartVersion of the given release is compatible with the Constellation version cliVersion.
+func (m ChartCompatibilityMatrix) CheckChartVersion(releaseName string, chartVersion, cliVersion semver.Semver) error {
+	compat, ok := m[releaseName]
+	if !ok {
+		return nil
+	}
+
+	if compat.MatchCLIVersion && chartVersion.Compare(cliVersion) != 0 {
+		return fmt.Errorf("chart %s version %s is not compatible with Constellation %s: the chart version must be %s",
+			releaseName, chartVersion, cliVersion, cliVersion)
+	}
+
+	cliMinor := semver.NewFromInt(cliVersion.Major(), cliVersion.Minor(), 0, "")
+	var applying *ChartVersionRange
+	for i, r := range compat.Ranges {
+		if r.FromCLIVersion.Compare(cliMinor) <= 0 {
+			applying = &compat.Ranges[i]
+		}
+	}
+	if applying == nil {
+		return nil
+	}
+	if chartVersion.Compare(applying.MinChartVersion) < 0 || chartVersion.Compare(applying.MaxChartVersion) >= 0 {
+		return fmt.Errorf("chart %s version %s is not compatible with Constellation %s: compatible versions are >= %s and < %s",
+			releaseName, chartVersion, cliVersion, applying.MinChartVersion, applying.MaxChartVersion)
+	}
+	return nil
+}
+
+// checkReleases checks the chart versions of all releases against the matrix.
+func (m ChartCompatibilityMatrix) checkReleases(releases []release, cliVersion semver.Semver) error {
+	var errs []error
+	for _, release := range releases {
+		chartVersion, err := semver.New(release.chart.Metadata.Version)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("parsing version of chart %s: %w", release.releaseName, err))
+			continue
+		}
+		errs = append(errs, m.CheckChartVersion(release.releaseName, chartVersion, cliVersion))
+	}
+	return errors.Join(errs...)
+}
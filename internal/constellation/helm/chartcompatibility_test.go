@@ -0,0 +1,173 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package helm
+
+import (
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation/variant"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+	"github.com/edgelesssys/constellation/v2/internal/kms/uri"
+	"github.com/edgelesssys/constellation/v2/internal/semver"
+	"github.com/edgelesssys/constellation/v2/internal/versions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func TestCheckChartVersion(t *testing.T) {
+	matrix := ChartCompatibilityMatrix{
+		"ours": {MatchCLIVersion: true},
+		"external": {Ranges: []ChartVersionRange{
+			{
+				FromCLIVersion:  semver.NewFromInt(2, 10, 0, ""),
+				MinChartVersion: semver.NewFromInt(1, 14, 0, ""),
+				MaxChartVersion: semver.NewFromInt(1, 15, 0, ""),
+			},
+			{
+				FromCLIVersion:  semver.NewFromInt(2, 12, 0, ""),
+				MinChartVersion: semver.NewFromInt(1, 15, 0, ""),
+				MaxChartVersion: semver.NewFromInt(1, 16, 0, ""),
+			},
+		}},
+	}
+
+	testCases := map[string]struct {
+		release      string
+		chartVersion semver.Semver
+		cliVersion   semver.Semver
+		wantErr      bool
+	}{
+		"chart version equals CLI version": {
+			release:      "ours",
+			chartVersion: semver.NewFromInt(2, 12, 1, ""),
+			cliVersion:   semver.NewFromInt(2, 12, 1, ""),
+		},
+		"chart version differs from CLI version": {
+			release:      "ours",
+			chartVersion: semver.NewFromInt(2, 12, 0, ""),
+			cliVersion:   semver.NewFromInt(2, 12, 1, ""),
+			wantErr:      true,
+		},
+		"chart version in range": {
+			release:      "external",
+			chartVersion: semver.NewFromInt(1, 15, 8, "edg.0"),
+			cliVersion:   semver.NewFromInt(2, 12, 0, ""),
+		},
+		"chart version in range of older CLI": {
+			release:      "external",
+			chartVersion: semver.NewFromInt(1, 14, 2, ""),
+			cliVersion:   semver.NewFromInt(2, 11, 3, ""),
+		},
+		"range applies to pre-release CLI": {
+			release:      "external",
+			chartVersion: semver.NewFromInt(1, 15, 0, ""),
+			cliVersion:   semver.NewFromInt(2, 12, 0, "pre"),
+		},
+		"chart version below range": {
+			release:      "external",
+			chartVersion: semver.NewFromInt(1, 14, 2, ""),
+			cliVersion:   semver.NewFromInt(2, 12, 0, ""),
+			wantErr:      true,
+		},
+		"chart version above range": {
+			release:      "external",
+			chartVersion: semver.NewFromInt(1, 16, 0, ""),
+			cliVersion:   semver.NewFromInt(2, 13, 0, ""),
+			wantErr:      true,
+		},
+		"CLI older than all ranges": {
+			release:      "external",
+			chartVersion: semver.NewFromInt(1, 16, 0, ""),
+			cliVersion:   semver.NewFromInt(2, 9, 0, ""),
+		},
+		"release not in matrix": {
+			release:      "unknown",
+			chartVersion: semver.NewFromInt(0, 0, 1, ""),
+			cliVersion:   semver.NewFromInt(2, 12, 0, ""),
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			err := matrix.CheckChartVersion(tc.release, tc.chartVersion, tc.cliVersion)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+		})
+	}
+}
+
+func TestCheckReleases(t *testing.T) {
+	cliVersion := semver.NewFromInt(2, 20, 0, "")
+	newRelease := func(name, version string) release {
+		return release{releaseName: name, chart: &chart.Chart{Metadata: &chart.Metadata{Version: version}}}
+	}
+
+	testCases := map[string]struct {
+		releases []release
+		wantErr  string
+	}{
+		"compatible charts": {
+			releases: []release{
+				newRelease(ciliumInfo.releaseName, "1.15.8-edg.0"),
+				newRelease(certManagerInfo.releaseName, "v1.15.0"),
+				newRelease(constellationServicesInfo.releaseName, "v2.20.0"),
+				newRelease(coreDNSInfo.releaseName, "0.0.0"),
+			},
+		},
+		"incompatible chart version": {
+			releases: []release{
+				newRelease(ciliumInfo.releaseName, "1.16.1"),
+				newRelease(certManagerInfo.releaseName, "v1.15.0"),
+			},
+			wantErr: "chart cilium version v1.16.1 is not compatible with Constellation v2.20.0: compatible versions are >= v1.15.0 and < v1.16.0",
+		},
+		"invalid chart version": {
+			releases: []release{newRelease(ciliumInfo.releaseName, "latest")},
+			wantErr:  "parsing version of chart cilium",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			err := DefaultChartCompatibility.checkReleases(tc.releases, cliVersion)
+			if tc.wantErr != "" {
+				assert.ErrorContains(err, tc.wantErr)
+				return
+			}
+			assert.NoError(err)
+		})
+	}
+}
+
+func TestEmbeddedChartsCompatible(t *testing.T) {
+	require := require.New(t)
+
+	cliVersion := semver.NewFromInt(2, 20, 0, "")
+	chartLoader := newLoader(
+		cloudprovider.AWS, variant.AWSSEVSNP{}, versions.Default,
+		state.New().
+			SetInfrastructure(state.Infrastructure{UID: "uid"}).
+			SetClusterValues(state.ClusterValues{MeasurementSalt: []byte{0x41}}),
+		cliVersion,
+	)
+	releases, err := chartLoader.loadReleases(
+		false, true, WaitModeAtomic,
+		uri.MasterSecret{Key: []byte("secret"), Salt: []byte("masterSalt")},
+		fakeServiceAccURI(cloudprovider.AWS), nil, "172.16.128.0/17",
+	)
+	require.NoError(err)
+	require.NoError(DefaultChartCompatibility.checkReleases(releases, cliVersion))
+}
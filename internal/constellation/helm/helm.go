@@ -31,6 +31,7 @@ package helm
 import (
 	"context"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/edgelesssys/constellation/v2/internal/attestation/variant"
@@ -42,6 +43,7 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/kubernetes/kubectl"
 	"github.com/edgelesssys/constellation/v2/internal/semver"
 	"github.com/edgelesssys/constellation/v2/internal/versions"
+	corev1 "k8s.io/api/core/v1"
 )
 
 const (
@@ -60,22 +62,31 @@ type Client struct {
 	factory    *actionFactory
 	cliVersion semver.Semver
 	log        debugLog
+	// newFactory creates an action factory for releases in a namespace other than the one of factory.
+	newFactory func(namespace string) (*actionFactory, error)
 }
 
 // NewClient returns a new Helm client.
+// Releases are applied to the namespace constants.HelmNamespace, unless Options.Namespace is set.
 func NewClient(kubeConfig []byte, log debugLog) (*Client, error) {
 	kubeClient, err := kubectl.NewFromConfig(kubeConfig)
 	if err != nil {
 		return nil, fmt.Errorf("initializing kubectl: %w", err)
 	}
-	actionConfig, err := newActionConfig(kubeConfig, log)
+	newFactory := func(namespace string) (*actionFactory, error) {
+		actionConfig, err := newActionConfig(kubeConfig, namespace, log)
+		if err != nil {
+			return nil, fmt.Errorf("creating action config: %w", err)
+		}
+		lister := ReleaseVersionClient{actionConfig}
+		return newActionFactory(kubeClient, lister, actionConfig, namespace, log), nil
+	}
+	factory, err := newFactory(constants.HelmNamespace)
 	if err != nil {
-		return nil, fmt.Errorf("creating action config: %w", err)
+		return nil, err
 	}
-	lister := ReleaseVersionClient{actionConfig}
 	cliVersion := constants.BinaryVersion()
-	factory := newActionFactory(kubeClient, lister, actionConfig, log)
-	return &Client{factory, cliVersion, log}, nil
+	return &Client{factory: factory, cliVersion: cliVersion, log: log, newFactory: newFactory}, nil
 }
 
 // Options are options for loading charts.
@@ -90,8 +101,15 @@ type Options struct {
 	MicroserviceVersion semver.Semver
 	HelmWaitMode        WaitMode
 	ApplyTimeout        time.Duration
+	AtomicApplyTimeout  time.Duration
 	OpenStackValues     *OpenStackValues
 	ServiceCIDR         string
+	DisabledCharts      []string
+	ReadinessTimeouts   map[string]time.Duration
+	Tolerations         []corev1.Toleration
+	HistoryMax          int
+	// Namespace is the namespace the releases are installed to. Defaults to constants.HelmNamespace if empty.
+	Namespace string
 }
 
 // PrepareApply loads the charts and returns the executor to apply them.
@@ -104,8 +122,27 @@ func (h Client) PrepareApply(
 	}
 
 	h.log.Debug("Loaded Helm releases")
-	actions, includesUpgrades, err := h.factory.GetActions(
-		releases, flags.MicroserviceVersion, flags.Force, flags.AllowDestructive, flags.ApplyTimeout,
+	if !flags.Force {
+		if err := DefaultChartCompatibility.checkReleases(releases, h.cliVersion); err != nil {
+			return nil, false, fmt.Errorf("checking Helm chart compatibility: %w", err)
+		}
+	}
+
+	factory := h.factory
+	if flags.Namespace != "" && flags.Namespace != factory.namespace {
+		h.log.Debug("Using Helm namespace", "namespace", flags.Namespace)
+		factory, err = h.newFactory(flags.Namespace)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	atomicTimeout := flags.AtomicApplyTimeout
+	if atomicTimeout == 0 {
+		atomicTimeout = flags.ApplyTimeout
+	}
+	actions, includesUpgrades, err := factory.GetActions(
+		releases, flags.MicroserviceVersion, flags.Force, flags.AllowDestructive, flags.ApplyTimeout, atomicTimeout, flags.HistoryMax,
 	)
 	return &ChartApplyExecutor{actions: actions, log: h.log}, includesUpgrades, err
 }
@@ -116,13 +153,30 @@ func (h Client) loadReleases(
 	helmLoader := newLoader(flags.CSP, flags.AttestationVariant, flags.K8sVersion, stateFile, h.cliVersion)
 	h.log.Debug("Created new Helm loader")
 	// TODO(burgerdev): pass down the entire flags struct
-	return helmLoader.loadReleases(flags.Conformance, flags.DeployCSIDriver, flags.HelmWaitMode, secret, serviceAccURI, flags.OpenStackValues, flags.ServiceCIDR)
+	releases, err := helmLoader.loadReleases(flags.Conformance, flags.DeployCSIDriver, flags.HelmWaitMode, secret, serviceAccURI, flags.OpenStackValues, flags.ServiceCIDR)
+	if err != nil {
+		return nil, err
+	}
+	releases = slices.DeleteFunc(releases, func(r release) bool {
+		if slices.Contains(flags.DisabledCharts, r.releaseName) {
+			h.log.Debug("Skipping disabled chart", "release", r.releaseName)
+			return true
+		}
+		return false
+	})
+	for i := range releases {
+		releases[i].readinessTimeout = flags.ReadinessTimeouts[releases[i].releaseName]
+		releases[i].values = mergeMaps(releases[i].values, extraTolerationValues(releases[i].releaseName, flags.Tolerations))
+	}
+	return releases, nil
 }
 
 // Applier runs the Helm actions.
 type Applier interface {
 	Apply(ctx context.Context) error
 	SaveCharts(chartsDir string, fileHandler file.Handler) error
+	// Images returns the container images of the charts.
+	Images() ([]string, error)
 }
 
 // ChartApplyExecutor is a Helm action executor that applies all actions.
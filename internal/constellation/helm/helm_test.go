@@ -8,11 +8,14 @@ package helm
 
 import (
 	"errors"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/edgelesssys/constellation/v2/internal/attestation/variant"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
 	"github.com/edgelesssys/constellation/v2/internal/compatibility"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
 	"github.com/edgelesssys/constellation/v2/internal/kms/uri"
 	"github.com/edgelesssys/constellation/v2/internal/logger"
@@ -20,7 +23,9 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/versions"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"helm.sh/helm/v3/pkg/action"
+	corev1 "k8s.io/api/core/v1"
 )
 
 func TestMergeMaps(t *testing.T) {
@@ -135,6 +140,7 @@ func TestHelmApply(t *testing.T) {
 		clusterCertManagerVersion  *string
 		clusterAWSLBVersion        *string
 		allowDestructive           bool
+		disabledCharts             []string
 		expectError                bool
 	}{
 		"CLI microservices are 1 minor version newer than cluster ones": {
@@ -171,6 +177,18 @@ func TestHelmApply(t *testing.T) {
 			clusterAWSLBVersion:        toPtr(""),
 			expectedActions:            []string{"aws-load-balancer-controller"},
 		},
+		"disabled charts aren't upgraded": {
+			clusterMicroServiceVersion: "v1.98.1",
+			disabledCharts:             []string{"constellation-csi", "cilium"},
+			expectedActions:            []string{"constellation-services", "constellation-operators"},
+			expectUpgrade:              true,
+		},
+		"missing disabled chart isn't installed": {
+			clusterMicroServiceVersion: "v1.99.0",
+			clusterAWSLBVersion:        toPtr(""),
+			disabledCharts:             []string{"aws-load-balancer-controller"},
+			expectedActions:            []string{},
+		},
 	}
 
 	log := logger.NewTest(t)
@@ -185,7 +203,7 @@ func TestHelmApply(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			lister := &releaseVersionMock{}
 			sut := Client{
-				factory:    newActionFactory(nil, lister, &action.Configuration{}, log),
+				factory:    newActionFactory(nil, lister, &action.Configuration{}, constants.HelmNamespace, log),
 				log:        log,
 				cliVersion: cliVersion,
 			}
@@ -207,6 +225,7 @@ func TestHelmApply(t *testing.T) {
 			helmListVersion(lister, "aws-load-balancer-controller", awsLbVersion)
 
 			options.AllowDestructive = tc.allowDestructive
+			options.DisabledCharts = tc.disabledCharts
 			options.CSP = csp
 			options.AttestationVariant = attestationVariant
 			options.K8sVersion = versions.Default
@@ -233,6 +252,271 @@ func TestHelmApply(t *testing.T) {
 	}
 }
 
+func TestHelmApplyReadinessTimeouts(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	const globalTimeout = 10 * time.Minute
+	// cert-manager becomes ready after the global timeout, all other charts before it.
+	const certManagerReadyAfter = 15 * time.Minute
+
+	log := logger.NewTest(t)
+	lister := &releaseVersionMock{}
+	for _, name := range []string{
+		"cilium", "coredns", "cert-manager", "constellation-services",
+		"constellation-operators", "constellation-csi", "aws-load-balancer-controller",
+	} {
+		helmListVersion(lister, name, "")
+	}
+	sut := Client{
+		factory:    newActionFactory(nil, lister, &action.Configuration{}, constants.HelmNamespace, log),
+		log:        log,
+		cliVersion: semver.NewFromInt(1, 99, 0, ""),
+	}
+
+	options := Options{
+		CSP:                 cloudprovider.AWS,
+		AttestationVariant:  variant.AWSSEVSNP{},
+		K8sVersion:          versions.Default,
+		MicroserviceVersion: semver.NewFromInt(1, 99, 0, ""),
+		DeployCSIDriver:     true,
+		HelmWaitMode:        WaitModeAtomic,
+		ApplyTimeout:        globalTimeout,
+		ReadinessTimeouts:   map[string]time.Duration{"cert-manager": 20 * time.Minute},
+	}
+	ex, _, err := sut.PrepareApply(
+		options,
+		state.New().
+			SetInfrastructure(state.Infrastructure{UID: "testuid"}).
+			SetClusterValues(state.ClusterValues{MeasurementSalt: []byte{0x41}}),
+		fakeServiceAccURI(cloudprovider.AWS),
+		uri.MasterSecret{Key: []byte("secret"), Salt: []byte("masterSalt")})
+	require.NoError(err)
+	chartExecutor, ok := ex.(*ChartApplyExecutor)
+	require.True(ok)
+
+	var certManagerFound bool
+	for _, a := range chartExecutor.actions {
+		install, ok := a.(*installAction)
+		require.True(ok)
+		if a.ReleaseName() != "cert-manager" {
+			assert.Equal(globalTimeout, install.helmAction.Timeout)
+			continue
+		}
+		certManagerFound = true
+		assert.Less(globalTimeout, certManagerReadyAfter, "the global timeout would fail the readiness gate")
+		assert.LessOrEqual(certManagerReadyAfter, install.helmAction.Timeout)
+		if install.uninstallAction != nil {
+			assert.Equal(install.helmAction.Timeout, install.uninstallAction.Timeout)
+		}
+	}
+	assert.True(certManagerFound)
+}
+
+func TestHelmApplyHistoryMax(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	cliVersion := semver.NewFromInt(1, 99, 0, "")
+	log := logger.NewTest(t)
+	lister := &releaseVersionMock{}
+	helmListVersion(lister, "cilium", "v1.15.8-edg.0")
+	helmListVersion(lister, "coredns", "v0.0.0")
+	helmListVersion(lister, "cert-manager", "v1.15.0")
+	helmListVersion(lister, "constellation-services", "v1.98.1")
+	helmListVersion(lister, "constellation-operators", "v1.98.1")
+	helmListVersion(lister, "constellation-csi", "v1.98.1")
+	helmListVersion(lister, "aws-load-balancer-controller", "v1.5.4")
+	sut := Client{
+		factory:    newActionFactory(nil, lister, &action.Configuration{}, constants.HelmNamespace, log),
+		log:        log,
+		cliVersion: cliVersion,
+	}
+
+	options := Options{
+		CSP:                 cloudprovider.AWS,
+		AttestationVariant:  variant.AWSSEVSNP{},
+		K8sVersion:          versions.Default,
+		MicroserviceVersion: cliVersion,
+		DeployCSIDriver:     true,
+		HelmWaitMode:        WaitModeAtomic,
+		HistoryMax:          3,
+	}
+	ex, includesUpgrade, err := sut.PrepareApply(
+		options,
+		state.New().
+			SetInfrastructure(state.Infrastructure{UID: "testuid"}).
+			SetClusterValues(state.ClusterValues{MeasurementSalt: []byte{0x41}}),
+		fakeServiceAccURI(cloudprovider.AWS),
+		uri.MasterSecret{Key: []byte("secret"), Salt: []byte("masterSalt")})
+	// Charts that are already up to date aren't upgraded.
+	var upgradeErr *compatibility.InvalidUpgradeError
+	require.True(err == nil || errors.As(err, &upgradeErr))
+	require.True(includesUpgrade)
+	chartExecutor, ok := ex.(*ChartApplyExecutor)
+	require.True(ok)
+
+	require.NotEmpty(chartExecutor.actions)
+	for _, a := range chartExecutor.actions {
+		upgrade, ok := a.(*upgradeAction)
+		require.True(ok)
+		assert.Equal(3, upgrade.helmAction.MaxHistory, a.ReleaseName())
+	}
+}
+
+func TestHelmApplyNamespace(t *testing.T) {
+	testCases := map[string]struct {
+		namespace     string
+		wantNamespace string
+		wantNewClient bool
+	}{
+		"default namespace": {
+			wantNamespace: constants.HelmNamespace,
+		},
+		"default namespace set explicitly": {
+			namespace:     constants.HelmNamespace,
+			wantNamespace: constants.HelmNamespace,
+		},
+		"custom namespace": {
+			namespace:     "constellation-system",
+			wantNamespace: "constellation-system",
+			wantNewClient: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			log := logger.NewTest(t)
+			lister := &releaseVersionMock{}
+			for _, name := range []string{
+				"cilium", "coredns", "cert-manager", "constellation-services",
+				"constellation-operators", "constellation-csi", "aws-load-balancer-controller",
+			} {
+				helmListVersion(lister, name, "")
+			}
+			var createdNamespaces []string
+			sut := Client{
+				factory:    newActionFactory(nil, lister, &action.Configuration{}, constants.HelmNamespace, log),
+				log:        log,
+				cliVersion: semver.NewFromInt(1, 99, 0, ""),
+				newFactory: func(namespace string) (*actionFactory, error) {
+					createdNamespaces = append(createdNamespaces, namespace)
+					return newActionFactory(nil, lister, &action.Configuration{}, namespace, log), nil
+				},
+			}
+
+			options := Options{
+				CSP:                 cloudprovider.AWS,
+				AttestationVariant:  variant.AWSSEVSNP{},
+				K8sVersion:          versions.Default,
+				MicroserviceVersion: semver.NewFromInt(1, 99, 0, ""),
+				DeployCSIDriver:     true,
+				Namespace:           tc.namespace,
+			}
+			ex, _, err := sut.PrepareApply(
+				options,
+				state.New().
+					SetInfrastructure(state.Infrastructure{UID: "testuid"}).
+					SetClusterValues(state.ClusterValues{MeasurementSalt: []byte{0x41}}),
+				fakeServiceAccURI(cloudprovider.AWS),
+				uri.MasterSecret{Key: []byte("secret"), Salt: []byte("masterSalt")})
+			require.NoError(err)
+			chartExecutor, ok := ex.(*ChartApplyExecutor)
+			require.True(ok)
+
+			if tc.wantNewClient {
+				assert.Equal([]string{tc.wantNamespace}, createdNamespaces)
+			} else {
+				assert.Empty(createdNamespaces)
+			}
+			require.NotEmpty(chartExecutor.actions)
+			for _, a := range chartExecutor.actions {
+				install, ok := a.(*installAction)
+				require.True(ok)
+				assert.Equal(tc.wantNamespace, install.helmAction.Namespace, a.ReleaseName())
+			}
+		})
+	}
+}
+
+func TestHelmApplyTolerations(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	log := logger.NewTest(t)
+	lister := &releaseVersionMock{}
+	for _, name := range []string{
+		"cilium", "coredns", "cert-manager", "constellation-services",
+		"constellation-operators", "constellation-csi", "aws-load-balancer-controller",
+	} {
+		helmListVersion(lister, name, "")
+	}
+	sut := Client{
+		factory:    newActionFactory(nil, lister, &action.Configuration{}, constants.HelmNamespace, log),
+		log:        log,
+		cliVersion: semver.NewFromInt(1, 99, 0, ""),
+	}
+
+	tolerationSeconds := int64(60)
+	options := Options{
+		CSP:                 cloudprovider.AWS,
+		AttestationVariant:  variant.AWSSEVSNP{},
+		K8sVersion:          versions.Default,
+		MicroserviceVersion: semver.NewFromInt(1, 99, 0, ""),
+		DeployCSIDriver:     true,
+		Tolerations: []corev1.Toleration{
+			{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "constellation", Effect: corev1.TaintEffectNoSchedule},
+			{Key: "maintenance", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute, TolerationSeconds: &tolerationSeconds},
+		},
+	}
+	wantTolerations := []map[string]any{
+		{"key": "dedicated", "operator": "Equal", "value": "constellation", "effect": "NoSchedule"},
+		{"key": "maintenance", "operator": "Exists", "effect": "NoExecute", "tolerationSeconds": int64(60)},
+	}
+	wantWithControlPlane := append(slices.Clone(controlPlaneTolerations), wantTolerations...)
+
+	prepare := func() map[string]map[string]any {
+		ex, _, err := sut.PrepareApply(
+			options,
+			state.New().
+				SetInfrastructure(state.Infrastructure{UID: "testuid"}).
+				SetClusterValues(state.ClusterValues{MeasurementSalt: []byte{0x41}}),
+			fakeServiceAccURI(cloudprovider.AWS),
+			uri.MasterSecret{Key: []byte("secret"), Salt: []byte("masterSalt")})
+		require.NoError(err)
+		chartExecutor, ok := ex.(*ChartApplyExecutor)
+		require.True(ok)
+		values := map[string]map[string]any{}
+		for _, a := range chartExecutor.actions {
+			install, ok := a.(*installAction)
+			require.True(ok)
+			values[a.ReleaseName()] = install.release.values
+		}
+		return values
+	}
+
+	values := prepare()
+	global, ok := values["constellation-services"]["global"].(map[string]any)
+	require.True(ok)
+	assert.Equal(wantTolerations, global["tolerations"])
+	certManager := values["cert-manager"]
+	assert.Equal(wantWithControlPlane, certManager["tolerations"])
+	for _, component := range []string{"webhook", "cainjector", "startupapicheck"} {
+		componentValues, ok := certManager[component].(map[string]any)
+		require.True(ok)
+		assert.Equal(wantWithControlPlane, componentValues["tolerations"], component)
+	}
+	assert.Equal(wantWithControlPlane, values["aws-load-balancer-controller"]["tolerations"])
+	assert.NotContains(values["cilium"], "tolerations")
+
+	// applying the same config again results in the same values
+	assert.Equal(values, prepare())
+	assert.Len(controlPlaneTolerations, 2)
+}
+
 func getActionReleaseNames(actions []applyAction) []string {
 	releaseActionNames := []string{}
 	for _, action := range actions {
@@ -0,0 +1,57 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package helm
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+)
+
+// imagePattern matches the image of a container in a rendered Kubernetes manifest.
+var imagePattern = regexp.MustCompile(`(?m)^\s*(?:-\s+)?image:\s*["']?([^"'\s]+)["']?\s*$`)
+
+// Images returns the container images of all charts, sorted and without duplicates.
+func (c ChartApplyExecutor) Images() ([]string, error) {
+	var images []string
+	for _, action := range c.actions {
+		actionImages, err := action.Images()
+		if err != nil {
+			return nil, fmt.Errorf("getting images of %s: %w", action.ReleaseName(), err)
+		}
+		images = append(images, actionImages...)
+	}
+	slices.Sort(images)
+	return slices.Compact(images), nil
+}
+
+// releaseImages renders the chart of the release in namespace with its values and returns the images of all containers.
+// Rendering happens locally, so templates that look up resources in the cluster don't see them.
+func releaseImages(release release, namespace string) ([]string, error) {
+	values, err := chartutil.ToRenderValues(release.chart, release.values, chartutil.ReleaseOptions{
+		Name:      release.releaseName,
+		Namespace: namespace,
+	}, chartutil.DefaultCapabilities)
+	if err != nil {
+		return nil, fmt.Errorf("preparing values: %w", err)
+	}
+	manifests, err := engine.Render(release.chart, values)
+	if err != nil {
+		return nil, fmt.Errorf("rendering chart: %w", err)
+	}
+
+	var images []string
+	for _, manifest := range manifests {
+		for _, match := range imagePattern.FindAllStringSubmatch(manifest, -1) {
+			images = append(images, match[1])
+		}
+	}
+	return images, nil
+}
@@ -0,0 +1,75 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package helm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/attestation/variant"
+	"github.com/edgelesssys/constellation/v2/internal/cloud/cloudprovider"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
+	"github.com/edgelesssys/constellation/v2/internal/kms/uri"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/edgelesssys/constellation/v2/internal/semver"
+	"github.com/edgelesssys/constellation/v2/internal/versions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/action"
+)
+
+func TestImages(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	chartLoader := newLoader(
+		cloudprovider.GCP, variant.GCPSEVES{}, versions.Default,
+		state.New().
+			SetInfrastructure(state.Infrastructure{
+				GCP: &state.GCP{
+					ProjectID: "test-project-id",
+					IPCidrPod: "192.0.2.0/24",
+				},
+			}).
+			SetClusterValues(state.ClusterValues{MeasurementSalt: []byte{0x41}}),
+		semver.NewFromInt(2, 10, 0, ""),
+	)
+	releases, err := chartLoader.loadReleases(
+		true, false, WaitModeAtomic,
+		uri.MasterSecret{Key: bytes.Repeat([]byte{0x01}, 32), Salt: bytes.Repeat([]byte{0x02}, 32)},
+		fakeServiceAccURI(cloudprovider.GCP), nil, "172.16.128.0/17",
+	)
+	require.NoError(err)
+
+	var actions []applyAction
+	for _, release := range releases {
+		actions = append(actions, &installAction{release: release, helmAction: newHelmInstallAction(&action.Configuration{}, constants.HelmNamespace, release, time.Minute)})
+	}
+	executor := ChartApplyExecutor{actions: actions, log: logger.NewTest(t)}
+
+	images, err := executor.Images()
+	require.NoError(err)
+	assert.IsIncreasing(images, "images must be sorted and unique")
+	for _, want := range []string{"cilium", "cert-manager-controller", "cloud-provider-gcp"} {
+		assert.True(containsImage(images, want), "missing image %q in %v", want, images)
+	}
+	for _, image := range images {
+		assert.NotContains(image, "{{", "unrendered image %q", image)
+	}
+}
+
+func containsImage(images []string, name string) bool {
+	for _, image := range images {
+		if strings.Contains(image, name) {
+			return true
+		}
+	}
+	return false
+}
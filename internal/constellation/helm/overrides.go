@@ -12,6 +12,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"slices"
 
 	"github.com/edgelesssys/constellation/v2/internal/attestation/variant"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/azureshared"
@@ -21,6 +22,7 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/constellation/state"
 	"github.com/edgelesssys/constellation/v2/internal/kms/uri"
+	corev1 "k8s.io/api/core/v1"
 	kubeadmconstants "k8s.io/kubernetes/cmd/kubeadm/app/constants"
 )
 
@@ -280,3 +282,55 @@ func extraCSIValues(provider cloudprovider.Provider, serviceAccURI string) (map[
 	}
 	return csiVals, nil
 }
+
+// extraTolerationValues returns the values that add the given tolerations to the pods of a chart.
+// Charts without values for tolerations are left unchanged.
+func extraTolerationValues(releaseName string, tolerations []corev1.Toleration) map[string]any {
+	if len(tolerations) == 0 {
+		return map[string]any{}
+	}
+	extra := make([]map[string]any, 0, len(tolerations))
+	for _, toleration := range tolerations {
+		value := map[string]any{}
+		if toleration.Key != "" {
+			value["key"] = toleration.Key
+		}
+		if toleration.Operator != "" {
+			value["operator"] = string(toleration.Operator)
+		}
+		if toleration.Value != "" {
+			value["value"] = toleration.Value
+		}
+		if toleration.Effect != "" {
+			value["effect"] = string(toleration.Effect)
+		}
+		if toleration.TolerationSeconds != nil {
+			value["tolerationSeconds"] = *toleration.TolerationSeconds
+		}
+		extra = append(extra, value)
+	}
+	// Lists aren't merged with the values of the chart, so the default tolerations have to be repeated.
+	withControlPlane := slices.Concat(controlPlaneTolerations, extra)
+
+	switch releaseName {
+	case constellationServicesInfo.releaseName:
+		return map[string]any{
+			"global": map[string]any{
+				"tolerations": extra,
+			},
+		}
+	case certManagerInfo.releaseName:
+		return map[string]any{
+			"tolerations":     withControlPlane,
+			"webhook":         map[string]any{"tolerations": withControlPlane},
+			"cainjector":      map[string]any{"tolerations": withControlPlane},
+			"startupapicheck": map[string]any{"tolerations": withControlPlane},
+		}
+	case awsLBControllerInfo.releaseName:
+		return map[string]any{
+			"tolerations": withControlPlane,
+		}
+	default:
+		return map[string]any{}
+	}
+}
@@ -7,7 +7,11 @@ SPDX-License-Identifier: AGPL-3.0-only
 // Package helm provides types and functions shared across services.
 package helm
 
-import "helm.sh/helm/v3/pkg/chart"
+import (
+	"time"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
 
 // Release bundles all information necessary to create a helm release.
 type release struct {
@@ -15,6 +19,8 @@ type release struct {
 	values      map[string]any
 	releaseName string
 	waitMode    WaitMode
+	// readinessTimeout overrides the timeout of the release's actions, if set.
+	readinessTimeout time.Duration
 }
 
 // WaitMode specifies the wait mode for a helm release.
@@ -10,6 +10,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/semver"
 	"helm.sh/helm/v3/pkg/action"
 	helmrelease "helm.sh/helm/v3/pkg/release"
@@ -23,7 +24,7 @@ type ReleaseVersionClient struct {
 
 // NewReleaseVersionClient creates a new ReleaseVersionClient.
 func NewReleaseVersionClient(kubeConfig []byte, log debugLog) (*ReleaseVersionClient, error) {
-	config, err := newActionConfig(kubeConfig, log)
+	config, err := newActionConfig(kubeConfig, constants.HelmNamespace, log)
 	if err != nil {
 		return nil, err
 	}
@@ -36,7 +36,9 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/versions"
 	"github.com/edgelesssys/constellation/v2/internal/versions/components"
 	updatev1alpha1 "github.com/edgelesssys/constellation/v2/operators/constellation-node-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -84,6 +86,12 @@ func New(kubeConfig []byte, log debugLog) (*KubeCmd, error) {
 	}, nil
 }
 
+// ServerVersion returns the Kubernetes version reported by the API server.
+// Since it only requires the API server to be reachable, it can be used to check the connection to the cluster.
+func (k *KubeCmd) ServerVersion() (string, error) {
+	return k.kubectl.KubernetesVersion()
+}
+
 // UpgradeNodeImage upgrades the image version of a Constellation cluster.
 func (k *KubeCmd) UpgradeNodeImage(ctx context.Context, imageVersion semver.Semver, imageReference string, force bool) error {
 	nodeVersion, err := k.getConstellationVersion(ctx)
@@ -109,7 +117,12 @@ func (k *KubeCmd) UpgradeNodeImage(ctx context.Context, imageVersion semver.Semv
 	if err != nil {
 		return fmt.Errorf("applying upgrade: %w", err)
 	}
-	return checkForApplyError(nodeVersion, updatedNodeVersion)
+	if err := checkForApplyError(nodeVersion, updatedNodeVersion); err != nil {
+		return err
+	}
+
+	// Node groups upgraded on their own follow the image of the cluster again.
+	return k.resetNodeGroupVersions(ctx)
 }
 
 // UpgradeKubernetesVersion upgrades the Kubernetes version of a Constellation cluster.
@@ -150,7 +163,38 @@ func (k *KubeCmd) UpgradeKubernetesVersion(ctx context.Context, kubernetesVersio
 	if err != nil {
 		return fmt.Errorf("applying upgrade: %w", err)
 	}
-	return checkForApplyError(nodeVersion, updatedNodeVersion)
+	if err := checkForApplyError(nodeVersion, updatedNodeVersion); err != nil {
+		return err
+	}
+	return k.syncNodeGroupKubernetesVersions(ctx, updatedNodeVersion)
+}
+
+// SetDrainSettings sets the time pods get to terminate when their node is drained during an upgrade,
+// and the time a node gets to drain before pods blocked by a PodDisruptionBudget are deleted.
+// It applies to the NodeVersion of the cluster and the NodeVersions of all node groups.
+// A grace period of zero uses the termination grace period of the pods,
+// a timeout of zero the default timeout of the operator.
+func (k *KubeCmd) SetDrainSettings(ctx context.Context, gracePeriod, timeout time.Duration) error {
+	nodeVersions, err := k.listNodeVersions(ctx)
+	if err != nil {
+		return err
+	}
+
+	gracePeriodSeconds := int64(gracePeriod / time.Second)
+	timeoutSeconds := int64(timeout / time.Second)
+	for _, nodeVersion := range nodeVersions {
+		if nodeVersion.Spec.DrainGracePeriodSeconds == gracePeriodSeconds && nodeVersion.Spec.DrainTimeoutSeconds == timeoutSeconds {
+			continue
+		}
+		k.log.Debug("Updating drain settings of NodeVersion", "name", nodeVersion.Name,
+			"gracePeriodSeconds", gracePeriodSeconds, "timeoutSeconds", timeoutSeconds)
+		nodeVersion.Spec.DrainGracePeriodSeconds = gracePeriodSeconds
+		nodeVersion.Spec.DrainTimeoutSeconds = timeoutSeconds
+		if err := k.createOrUpdateNodeVersion(ctx, nodeVersion, true); err != nil {
+			return fmt.Errorf("updating NodeVersion %s: %w", nodeVersion.Name, err)
+		}
+	}
+	return nil
 }
 
 // ClusterStatus returns a map from node name to NodeStatus.
@@ -515,6 +559,16 @@ type kubectlInterface interface {
 	KubernetesVersion() (string, error)
 	GetCR(ctx context.Context, gvr schema.GroupVersionResource, name string) (*unstructured.Unstructured, error)
 	UpdateCR(ctx context.Context, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) (*unstructured.Unstructured, error)
+	CreateCR(ctx context.Context, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) (*unstructured.Unstructured, error)
+	ListNetworkPolicies(ctx context.Context, labelSelector string) ([]networkingv1.NetworkPolicy, error)
+	CreateNetworkPolicy(ctx context.Context, policy *networkingv1.NetworkPolicy) error
+	UpdateNetworkPolicy(ctx context.Context, policy *networkingv1.NetworkPolicy) error
+	DeleteNetworkPolicy(ctx context.Context, namespace, name string) error
+	GetDaemonSet(ctx context.Context, namespace, name string) (*appsv1.DaemonSet, error)
+	RestartDaemonSet(ctx context.Context, namespace, name string) error
+	CreateDaemonSet(ctx context.Context, daemonSet *appsv1.DaemonSet) error
+	DeleteDaemonSet(ctx context.Context, namespace, name string) error
+	ListPods(ctx context.Context, namespace, labelSelector string) ([]corev1.Pod, error)
 	crdLister
 }
 
@@ -26,7 +26,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -693,6 +695,14 @@ func (u *fakeUnstructuredClient) UpdateCR(ctx context.Context, _ schema.GroupVer
 	return updatedObject, args.Error(1)
 }
 
+func (u *fakeUnstructuredClient) CreateCR(ctx context.Context, _ schema.GroupVersionResource, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	args := u.Called(ctx, obj)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return obj, args.Error(1)
+}
+
 type stubUnstructuredClient struct {
 	object           *unstructured.Unstructured
 	updatedObject    *unstructured.Unstructured
@@ -713,9 +723,14 @@ func (u *stubUnstructuredClient) UpdateCR(_ context.Context, _ schema.GroupVersi
 	return u.updatedObject, u.updateCRErr
 }
 
+func (u *stubUnstructuredClient) CreateCR(_ context.Context, _ schema.GroupVersionResource, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	return obj, nil
+}
+
 type unstructuredInterface interface {
 	GetCR(ctx context.Context, gvr schema.GroupVersionResource, name string) (*unstructured.Unstructured, error)
 	UpdateCR(ctx context.Context, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) (*unstructured.Unstructured, error)
+	CreateCR(ctx context.Context, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) (*unstructured.Unstructured, error)
 }
 
 type stubKubectl struct {
@@ -733,6 +748,10 @@ type stubKubectl struct {
 	getCRDsError      error
 	crs               []unstructured.Unstructured
 	getCRsError       error
+	daemonSet         *appsv1.DaemonSet
+	getDaemonSetErr   error
+	restartErr        error
+	restarted         bool
 }
 
 func (s *stubKubectl) GetConfigMap(_ context.Context, _, name string) (*corev1.ConfigMap, error) {
@@ -763,6 +782,43 @@ func (s *stubKubectl) GetNodes(_ context.Context) ([]corev1.Node, error) {
 	return s.nodes, s.nodesErr
 }
 
+func (s *stubKubectl) ListNetworkPolicies(_ context.Context, _ string) ([]networkingv1.NetworkPolicy, error) {
+	return nil, nil
+}
+
+func (s *stubKubectl) CreateNetworkPolicy(_ context.Context, _ *networkingv1.NetworkPolicy) error {
+	return nil
+}
+
+func (s *stubKubectl) UpdateNetworkPolicy(_ context.Context, _ *networkingv1.NetworkPolicy) error {
+	return nil
+}
+
+func (s *stubKubectl) DeleteNetworkPolicy(_ context.Context, _, _ string) error {
+	return nil
+}
+
+func (s *stubKubectl) GetDaemonSet(_ context.Context, _, _ string) (*appsv1.DaemonSet, error) {
+	return s.daemonSet, s.getDaemonSetErr
+}
+
+func (s *stubKubectl) RestartDaemonSet(_ context.Context, _, _ string) error {
+	s.restarted = true
+	return s.restartErr
+}
+
+func (s *stubKubectl) CreateDaemonSet(_ context.Context, _ *appsv1.DaemonSet) error {
+	return nil
+}
+
+func (s *stubKubectl) DeleteDaemonSet(_ context.Context, _, _ string) error {
+	return nil
+}
+
+func (s *stubKubectl) ListPods(_ context.Context, _, _ string) ([]corev1.Pod, error) {
+	return nil, nil
+}
+
 func unstructedObjectWithGeneration(nodeVersion updatev1alpha1.NodeVersion, generation int64) *unstructured.Unstructured {
 	unstrNodeVersion, _ := runtime.DefaultUnstructuredConverter.ToUnstructured(&nodeVersion)
 	object := &unstructured.Unstructured{Object: unstrNodeVersion}
@@ -0,0 +1,92 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package kubecmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// joinServiceDaemonSet is the name of the join service's DaemonSet.
+const joinServiceDaemonSet = "join-service"
+
+// GetMeasurementSalt returns the measurement salt stored in the cluster's join-config ConfigMap.
+func (k *KubeCmd) GetMeasurementSalt(ctx context.Context) ([]byte, error) {
+	joinConfig, err := k.retryGetJoinConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting %s ConfigMap: %w", constants.JoinConfigMap, err)
+	}
+	return joinConfig.BinaryData[constants.MeasurementSaltFilename], nil
+}
+
+// ApplyMeasurementSalt replaces the measurement salt in the cluster's join-config ConfigMap.
+// The join service only reads the salt on startup, use [KubeCmd.RestartJoinService] to distribute it to joining nodes.
+func (k *KubeCmd) ApplyMeasurementSalt(ctx context.Context, measurementSalt []byte) error {
+	joinConfig, err := k.retryGetJoinConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("getting %s ConfigMap: %w", constants.JoinConfigMap, err)
+	}
+	if bytes.Equal(joinConfig.BinaryData[constants.MeasurementSaltFilename], measurementSalt) {
+		k.log.Debug("Measurement salt is already up to date")
+		return nil
+	}
+
+	if joinConfig.BinaryData == nil {
+		joinConfig.BinaryData = map[string][]byte{}
+	}
+	joinConfig.BinaryData[constants.MeasurementSaltFilename] = measurementSalt
+	k.log.Debug("Updating measurement salt", "name", constants.JoinConfigMap, "namespace", constants.ConstellationNamespace)
+	if err := k.retryAction(ctx, func(ctx context.Context) error {
+		_, err := k.kubectl.UpdateConfigMap(ctx, joinConfig)
+		return err
+	}); err != nil {
+		return fmt.Errorf("setting new measurement salt: %w", err)
+	}
+	return nil
+}
+
+// RestartJoinService restarts all join service pods and waits until the restarted pods are available.
+// The waiting stops once ctx is done.
+func (k *KubeCmd) RestartJoinService(ctx context.Context) error {
+	k.log.Debug("Restarting join service")
+	if err := k.retryAction(ctx, func(ctx context.Context) error {
+		return k.kubectl.RestartDaemonSet(ctx, constants.ConstellationNamespace, joinServiceDaemonSet)
+	}); err != nil {
+		return fmt.Errorf("restarting join service: %w", err)
+	}
+
+	ticker := time.NewTicker(k.retryInterval)
+	defer ticker.Stop()
+	for {
+		daemonSet, err := k.kubectl.GetDaemonSet(ctx, constants.ConstellationNamespace, joinServiceDaemonSet)
+		if err != nil {
+			k.log.Debug("Getting join service DaemonSet failed", "error", err)
+		} else if daemonSetRolledOut(daemonSet) {
+			k.log.Debug("Join service restarted")
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for join service restart: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// daemonSetRolledOut returns true if all pods of the DaemonSet run the latest pod template and are available.
+func daemonSetRolledOut(daemonSet *appsv1.DaemonSet) bool {
+	status := daemonSet.Status
+	return status.ObservedGeneration >= daemonSet.Generation &&
+		status.UpdatedNumberScheduled == status.DesiredNumberScheduled &&
+		status.NumberAvailable == status.DesiredNumberScheduled
+}
@@ -0,0 +1,140 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package kubecmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApplyMeasurementSalt(t *testing.T) {
+	testCases := map[string]struct {
+		kubectl    *stubKubectl
+		wantUpdate bool
+		wantErr    bool
+	}{
+		"salt is updated": {
+			kubectl: &stubKubectl{
+				configMaps: map[string]*corev1.ConfigMap{
+					constants.JoinConfigMap: joinConfigMap([]byte("{}"), []byte("old-salt")),
+				},
+			},
+			wantUpdate: true,
+		},
+		"same salt isn't updated": {
+			kubectl: &stubKubectl{
+				configMaps: map[string]*corev1.ConfigMap{
+					constants.JoinConfigMap: joinConfigMap([]byte("{}"), []byte("new-salt")),
+				},
+			},
+		},
+		"update fails": {
+			kubectl: &stubKubectl{
+				configMaps: map[string]*corev1.ConfigMap{
+					constants.JoinConfigMap: joinConfigMap([]byte("{}"), []byte("old-salt")),
+				},
+				updateCMErr: errors.New("update error"),
+			},
+			wantUpdate: true,
+			wantErr:    true,
+		},
+		"join-config is missing": {
+			kubectl: &stubKubectl{getCMErr: errors.New("get error")},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			k := &KubeCmd{
+				kubectl:       tc.kubectl,
+				retryInterval: time.Millisecond,
+				maxAttempts:   5,
+				log:           logger.NewTest(t),
+			}
+
+			err := k.ApplyMeasurementSalt(context.Background(), []byte("new-salt"))
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			updated, ok := tc.kubectl.updatedConfigMaps[constants.JoinConfigMap]
+			assert.Equal(tc.wantUpdate, ok)
+			if ok {
+				assert.Equal([]byte("new-salt"), updated.BinaryData[constants.MeasurementSaltFilename])
+			}
+		})
+	}
+}
+
+func TestRestartJoinService(t *testing.T) {
+	rolledOut := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Generation: 2},
+		Status: appsv1.DaemonSetStatus{
+			ObservedGeneration:     2,
+			DesiredNumberScheduled: 3,
+			UpdatedNumberScheduled: 3,
+			NumberAvailable:        3,
+		},
+	}
+	inProgress := rolledOut.DeepCopy()
+	inProgress.Status.UpdatedNumberScheduled = 1
+
+	testCases := map[string]struct {
+		kubectl *stubKubectl
+		wantErr bool
+	}{
+		"restart succeeds": {
+			kubectl: &stubKubectl{daemonSet: rolledOut},
+		},
+		"restart fails": {
+			kubectl: &stubKubectl{daemonSet: rolledOut, restartErr: errors.New("patch error")},
+			wantErr: true,
+		},
+		"rollout doesn't finish": {
+			kubectl: &stubKubectl{daemonSet: inProgress},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			k := &KubeCmd{
+				kubectl:       tc.kubectl,
+				retryInterval: time.Millisecond,
+				maxAttempts:   5,
+				log:           logger.NewTest(t),
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			err := k.RestartJoinService(ctx)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.True(tc.kubectl.restarted)
+		})
+	}
+}
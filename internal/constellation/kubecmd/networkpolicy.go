@@ -0,0 +1,161 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package kubecmd
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// NetworkPolicyPresetNone applies no NetworkPolicies.
+	NetworkPolicyPresetNone = "none"
+	// NetworkPolicyPresetBaseline only allows ingress traffic from pods in the same namespace.
+	NetworkPolicyPresetBaseline = "baseline"
+	// NetworkPolicyPresetRestricted additionally only allows egress traffic to pods in the same namespace and to the cluster DNS.
+	NetworkPolicyPresetRestricted = "restricted"
+
+	// networkPolicyPresetLabel marks NetworkPolicies managed by Constellation.
+	// Its value is the preset the policy belongs to.
+	networkPolicyPresetLabel = "constellation.edgeless.systems/network-policy-preset"
+	// networkPolicyNamespace is the namespace the NetworkPolicy presets are applied to.
+	networkPolicyNamespace = metav1.NamespaceDefault
+)
+
+// NetworkPolicies returns the NetworkPolicies of the given preset.
+// An empty preset is treated as [NetworkPolicyPresetNone].
+func NetworkPolicies(preset string) ([]networkingv1.NetworkPolicy, error) {
+	switch preset {
+	case "", NetworkPolicyPresetNone:
+		return nil, nil
+	case NetworkPolicyPresetBaseline:
+		return []networkingv1.NetworkPolicy{
+			sameNamespaceIngressPolicy(preset),
+		}, nil
+	case NetworkPolicyPresetRestricted:
+		return []networkingv1.NetworkPolicy{
+			sameNamespaceIngressPolicy(preset),
+			restrictedEgressPolicy(preset),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown NetworkPolicy preset %q", preset)
+	}
+}
+
+// ApplyNetworkPolicies creates or updates the given NetworkPolicies.
+// NetworkPolicies previously created by Constellation that are not part of policies are deleted,
+// so applying the same set of policies again leaves the cluster unchanged.
+func (k *KubeCmd) ApplyNetworkPolicies(ctx context.Context, policies []networkingv1.NetworkPolicy) error {
+	var existing []networkingv1.NetworkPolicy
+	if err := k.retryAction(ctx, func(ctx context.Context) error {
+		var err error
+		existing, err = k.kubectl.ListNetworkPolicies(ctx, networkPolicyPresetLabel)
+		return err
+	}); err != nil {
+		return fmt.Errorf("listing NetworkPolicies: %w", err)
+	}
+
+	existingByName := make(map[string]networkingv1.NetworkPolicy, len(existing))
+	for _, policy := range existing {
+		existingByName[policy.Namespace+"/"+policy.Name] = policy
+	}
+
+	for _, policy := range policies {
+		key := policy.Namespace + "/" + policy.Name
+		old, ok := existingByName[key]
+		delete(existingByName, key)
+
+		if !ok {
+			k.log.Debug("Creating NetworkPolicy", "name", policy.Name, "namespace", policy.Namespace)
+			if err := k.retryAction(ctx, func(ctx context.Context) error {
+				return k.kubectl.CreateNetworkPolicy(ctx, &policy)
+			}); err != nil {
+				return fmt.Errorf("creating NetworkPolicy %s: %w", key, err)
+			}
+			continue
+		}
+
+		k.log.Debug("Updating NetworkPolicy", "name", policy.Name, "namespace", policy.Namespace)
+		policy.ResourceVersion = old.ResourceVersion
+		if err := k.retryAction(ctx, func(ctx context.Context) error {
+			return k.kubectl.UpdateNetworkPolicy(ctx, &policy)
+		}); err != nil {
+			return fmt.Errorf("updating NetworkPolicy %s: %w", key, err)
+		}
+	}
+
+	for key, stale := range existingByName {
+		k.log.Debug("Deleting NetworkPolicy of previous preset", "name", stale.Name, "namespace", stale.Namespace)
+		if err := k.retryAction(ctx, func(ctx context.Context) error {
+			return k.kubectl.DeleteNetworkPolicy(ctx, stale.Namespace, stale.Name)
+		}); err != nil {
+			return fmt.Errorf("deleting NetworkPolicy %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// sameNamespaceIngressPolicy only allows ingress traffic from pods in the same namespace.
+func sameNamespaceIngressPolicy(preset string) networkingv1.NetworkPolicy {
+	return networkingv1.NetworkPolicy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "NetworkPolicy"},
+		ObjectMeta: networkPolicyMeta("constellation-allow-same-namespace-ingress", preset),
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{From: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}}},
+			},
+		},
+	}
+}
+
+// restrictedEgressPolicy only allows egress traffic to pods in the same namespace and to the cluster DNS.
+func restrictedEgressPolicy(preset string) networkingv1.NetworkPolicy {
+	udp := corev1.ProtocolUDP
+	tcp := corev1.ProtocolTCP
+	dnsPort := intstr.FromInt32(53)
+
+	return networkingv1.NetworkPolicy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "NetworkPolicy"},
+		ObjectMeta: networkPolicyMeta("constellation-restrict-egress", preset),
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			Egress: []networkingv1.NetworkPolicyEgressRule{
+				{To: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}}},
+				{
+					To: []networkingv1.NetworkPolicyPeer{{
+						NamespaceSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{corev1.LabelMetadataName: metav1.NamespaceSystem},
+						},
+						PodSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{"k8s-app": "kube-dns"},
+						},
+					}},
+					Ports: []networkingv1.NetworkPolicyPort{
+						{Protocol: &udp, Port: &dnsPort},
+						{Protocol: &tcp, Port: &dnsPort},
+					},
+				},
+			},
+		},
+	}
+}
+
+func networkPolicyMeta(name, preset string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      name,
+		Namespace: networkPolicyNamespace,
+		Labels:    map[string]string{networkPolicyPresetLabel: preset},
+	}
+}
@@ -0,0 +1,205 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package kubecmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNetworkPolicies(t *testing.T) {
+	testCases := map[string]struct {
+		preset    string
+		wantNames []string
+		wantErr   bool
+	}{
+		"empty preset": {
+			preset: "",
+		},
+		"none": {
+			preset: NetworkPolicyPresetNone,
+		},
+		"baseline": {
+			preset:    NetworkPolicyPresetBaseline,
+			wantNames: []string{"constellation-allow-same-namespace-ingress"},
+		},
+		"restricted": {
+			preset:    NetworkPolicyPresetRestricted,
+			wantNames: []string{"constellation-allow-same-namespace-ingress", "constellation-restrict-egress"},
+		},
+		"unknown preset": {
+			preset:  "strict",
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			policies, err := NetworkPolicies(tc.preset)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+
+			var names []string
+			for _, policy := range policies {
+				names = append(names, policy.Name)
+				assert.Equal(metav1.NamespaceDefault, policy.Namespace)
+				assert.Equal(tc.preset, policy.Labels[networkPolicyPresetLabel])
+			}
+			assert.Equal(tc.wantNames, names)
+		})
+	}
+}
+
+func TestApplyNetworkPolicies(t *testing.T) {
+	existingPolicy := func(name string, preset string) networkingv1.NetworkPolicy {
+		return networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       metav1.NamespaceDefault,
+				Labels:          map[string]string{networkPolicyPresetLabel: preset},
+				ResourceVersion: "42",
+			},
+		}
+	}
+	restricted, err := NetworkPolicies(NetworkPolicyPresetRestricted)
+	require.NoError(t, err)
+	baseline, err := NetworkPolicies(NetworkPolicyPresetBaseline)
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		client      *fakeNetworkPolicyClient
+		policies    []networkingv1.NetworkPolicy
+		wantCreated []string
+		wantUpdated []string
+		wantDeleted []string
+		wantErr     bool
+	}{
+		"create policies": {
+			client:      &fakeNetworkPolicyClient{},
+			policies:    restricted,
+			wantCreated: []string{"constellation-allow-same-namespace-ingress", "constellation-restrict-egress"},
+		},
+		"re-apply updates existing policies": {
+			client: &fakeNetworkPolicyClient{
+				existing: []networkingv1.NetworkPolicy{
+					existingPolicy("constellation-allow-same-namespace-ingress", NetworkPolicyPresetRestricted),
+					existingPolicy("constellation-restrict-egress", NetworkPolicyPresetRestricted),
+				},
+			},
+			policies:    restricted,
+			wantUpdated: []string{"constellation-allow-same-namespace-ingress", "constellation-restrict-egress"},
+		},
+		"switching preset deletes stale policies": {
+			client: &fakeNetworkPolicyClient{
+				existing: []networkingv1.NetworkPolicy{
+					existingPolicy("constellation-allow-same-namespace-ingress", NetworkPolicyPresetRestricted),
+					existingPolicy("constellation-restrict-egress", NetworkPolicyPresetRestricted),
+				},
+			},
+			policies:    baseline,
+			wantUpdated: []string{"constellation-allow-same-namespace-ingress"},
+			wantDeleted: []string{"constellation-restrict-egress"},
+		},
+		"no policies removes all managed policies": {
+			client: &fakeNetworkPolicyClient{
+				existing: []networkingv1.NetworkPolicy{
+					existingPolicy("constellation-allow-same-namespace-ingress", NetworkPolicyPresetBaseline),
+				},
+			},
+			wantDeleted: []string{"constellation-allow-same-namespace-ingress"},
+		},
+		"nothing to do": {
+			client: &fakeNetworkPolicyClient{},
+		},
+		"list error": {
+			client:   &fakeNetworkPolicyClient{listErr: errors.New("failed")},
+			policies: restricted,
+			wantErr:  true,
+		},
+		"create error": {
+			client:   &fakeNetworkPolicyClient{createErr: errors.New("failed")},
+			policies: restricted,
+			wantErr:  true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			cmd := &KubeCmd{
+				kubectl:       tc.client,
+				log:           logger.NewTest(t),
+				retryInterval: time.Millisecond,
+				maxAttempts:   1,
+			}
+
+			err := cmd.ApplyNetworkPolicies(context.Background(), tc.policies)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(networkPolicyPresetLabel, tc.client.labelSelector)
+			assert.Equal(tc.wantCreated, tc.client.created)
+			assert.Equal(tc.wantUpdated, tc.client.updated)
+			assert.ElementsMatch(tc.wantDeleted, tc.client.deleted)
+			for _, policy := range tc.client.updatedPolicies {
+				assert.Equal("42", policy.ResourceVersion)
+			}
+		})
+	}
+}
+
+type fakeNetworkPolicyClient struct {
+	existing        []networkingv1.NetworkPolicy
+	listErr         error
+	createErr       error
+	labelSelector   string
+	created         []string
+	updated         []string
+	updatedPolicies []*networkingv1.NetworkPolicy
+	deleted         []string
+	kubectlInterface
+}
+
+func (f *fakeNetworkPolicyClient) ListNetworkPolicies(_ context.Context, labelSelector string) ([]networkingv1.NetworkPolicy, error) {
+	f.labelSelector = labelSelector
+	return f.existing, f.listErr
+}
+
+func (f *fakeNetworkPolicyClient) CreateNetworkPolicy(_ context.Context, policy *networkingv1.NetworkPolicy) error {
+	if f.createErr != nil {
+		return f.createErr
+	}
+	f.created = append(f.created, policy.Name)
+	return nil
+}
+
+func (f *fakeNetworkPolicyClient) UpdateNetworkPolicy(_ context.Context, policy *networkingv1.NetworkPolicy) error {
+	f.updated = append(f.updated, policy.Name)
+	f.updatedPolicies = append(f.updatedPolicies, policy)
+	return nil
+}
+
+func (f *fakeNetworkPolicyClient) DeleteNetworkPolicy(_ context.Context, _, name string) error {
+	f.deleted = append(f.deleted, name)
+	return nil
+}
@@ -0,0 +1,262 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package kubecmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/edgelesssys/constellation/v2/internal/compatibility"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/semver"
+	updatev1alpha1 "github.com/edgelesssys/constellation/v2/operators/constellation-node-operator/api/v1alpha1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	nodeVersionGVR = schema.GroupVersionResource{
+		Group:    "update.edgeless.systems",
+		Version:  "v1alpha1",
+		Resource: "nodeversions",
+	}
+	scalingGroupGVR = schema.GroupVersionResource{
+		Group:    "update.edgeless.systems",
+		Version:  "v1alpha1",
+		Resource: "scalinggroups",
+	}
+)
+
+// NodeGroupVersionName returns the name of the NodeVersion of the given node group.
+// The scaling groups of a node group use it instead of the cluster's NodeVersion
+// after an image upgrade that only targeted the node group.
+func NodeGroupVersionName(nodeGroup string) string {
+	return constants.NodeVersionResourceName + "-" + nodeGroup
+}
+
+// UpgradeNodeGroupImage upgrades the image version of a single node group of a Constellation cluster.
+// The scaling groups of the node group are switched to a NodeVersion of their own,
+// which starts as a copy of the cluster's NodeVersion. The other node groups keep their image.
+func (k *KubeCmd) UpgradeNodeGroupImage(ctx context.Context, nodeGroup string, imageVersion semver.Semver, imageReference string, force bool) error {
+	clusterVersion, err := k.getConstellationVersion(ctx)
+	if err != nil {
+		return err
+	}
+	scalingGroups, err := k.listScalingGroups(ctx)
+	if err != nil {
+		return err
+	}
+
+	name := NodeGroupVersionName(nodeGroup)
+	var groupScalingGroups []updatev1alpha1.ScalingGroup
+	inUse := false
+	for _, scalingGroup := range scalingGroups {
+		if scalingGroup.Spec.NodeGroupName != nodeGroup {
+			continue
+		}
+		groupScalingGroups = append(groupScalingGroups, scalingGroup)
+		inUse = inUse || scalingGroup.Spec.NodeVersion == name
+	}
+	if len(groupScalingGroups) == 0 {
+		return fmt.Errorf("no scaling groups found for node group %q", nodeGroup)
+	}
+
+	nodeVersion, found, err := k.getNodeVersion(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !inUse {
+		// The nodes of the group still run the cluster's NodeVersion.
+		// A NodeVersion left over from an earlier targeted upgrade is reset to it.
+		nodeVersion.Spec = clusterVersion.Spec
+		nodeVersion.Status = clusterVersion.Status
+	}
+	// The Kubernetes version is the same for all nodes of the cluster.
+	nodeVersion.Spec.KubernetesComponentsReference = clusterVersion.Spec.KubernetesComponentsReference
+	nodeVersion.Spec.KubernetesClusterVersion = clusterVersion.Spec.KubernetesClusterVersion
+
+	k.log.Debug("Checking if image upgrade of node group is valid", "nodeGroup", nodeGroup)
+	var upgradeErr *compatibility.InvalidUpgradeError
+	err = k.isValidImageUpgrade(nodeVersion, imageVersion.String(), force)
+	switch {
+	case errors.As(err, &upgradeErr):
+		return fmt.Errorf("skipping image upgrade of node group %s: %w", nodeGroup, err)
+	case err != nil:
+		return fmt.Errorf("updating image version of node group %s: %w", nodeGroup, err)
+	}
+
+	k.log.Debug("Updating NodeVersion of node group", "nodeGroup", nodeGroup, "oldVersion", nodeVersion.Spec.ImageVersion, "newVersion", imageVersion.String())
+	nodeVersion.Name = name
+	if nodeVersion.Labels == nil {
+		nodeVersion.Labels = map[string]string{}
+	}
+	nodeVersion.Labels[constants.NodeGroupLabelKey] = nodeGroup
+	nodeVersion.Spec.ImageReference = imageReference
+	nodeVersion.Spec.ImageVersion = imageVersion.String()
+	if err := k.createOrUpdateNodeVersion(ctx, nodeVersion, found); err != nil {
+		return fmt.Errorf("applying upgrade of node group %s: %w", nodeGroup, err)
+	}
+
+	for _, scalingGroup := range groupScalingGroups {
+		if err := k.setScalingGroupNodeVersion(ctx, scalingGroup, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resetNodeGroupVersions switches all scaling groups back to the cluster's NodeVersion.
+// It is called after the image of the whole cluster was upgraded.
+func (k *KubeCmd) resetNodeGroupVersions(ctx context.Context) error {
+	scalingGroups, err := k.listScalingGroups(ctx)
+	if err != nil {
+		return err
+	}
+	for _, scalingGroup := range scalingGroups {
+		if err := k.setScalingGroupNodeVersion(ctx, scalingGroup, constants.NodeVersionResourceName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncNodeGroupKubernetesVersions sets the Kubernetes version of all node group NodeVersions
+// to the one of the cluster's NodeVersion.
+func (k *KubeCmd) syncNodeGroupKubernetesVersions(ctx context.Context, clusterVersion updatev1alpha1.NodeVersion) error {
+	nodeVersions, err := k.listNodeVersions(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, nodeVersion := range nodeVersions {
+		if _, ok := nodeVersion.Labels[constants.NodeGroupLabelKey]; !ok {
+			continue
+		}
+		if nodeVersion.Spec.KubernetesComponentsReference == clusterVersion.Spec.KubernetesComponentsReference &&
+			nodeVersion.Spec.KubernetesClusterVersion == clusterVersion.Spec.KubernetesClusterVersion {
+			continue
+		}
+		k.log.Debug("Updating Kubernetes version of node group NodeVersion", "name", nodeVersion.Name)
+		nodeVersion.Spec.KubernetesComponentsReference = clusterVersion.Spec.KubernetesComponentsReference
+		nodeVersion.Spec.KubernetesClusterVersion = clusterVersion.Spec.KubernetesClusterVersion
+		if err := k.createOrUpdateNodeVersion(ctx, nodeVersion, true); err != nil {
+			return fmt.Errorf("updating NodeVersion %s: %w", nodeVersion.Name, err)
+		}
+	}
+	return nil
+}
+
+// getNodeVersion returns the NodeVersion with the given name, and whether it exists.
+func (k *KubeCmd) getNodeVersion(ctx context.Context, name string) (updatev1alpha1.NodeVersion, bool, error) {
+	var raw *unstructured.Unstructured
+	found := true
+	if err := k.retryAction(ctx, func(ctx context.Context) error {
+		var err error
+		raw, err = k.kubectl.GetCR(ctx, nodeVersionGVR, name)
+		if k8serrors.IsNotFound(err) {
+			found = false
+			return nil
+		}
+		return err
+	}); err != nil {
+		return updatev1alpha1.NodeVersion{}, false, fmt.Errorf("retrieving NodeVersion %s: %w", name, err)
+	}
+	if !found {
+		return updatev1alpha1.NodeVersion{}, false, nil
+	}
+
+	var nodeVersion updatev1alpha1.NodeVersion
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw.UnstructuredContent(), &nodeVersion); err != nil {
+		return updatev1alpha1.NodeVersion{}, false, fmt.Errorf("converting unstructured to NodeVersion: %w", err)
+	}
+	return nodeVersion, true, nil
+}
+
+// createOrUpdateNodeVersion creates the given NodeVersion, or updates it if it already exists.
+func (k *KubeCmd) createOrUpdateNodeVersion(ctx context.Context, nodeVersion updatev1alpha1.NodeVersion, exists bool) error {
+	nodeVersion.APIVersion = nodeVersionGVR.GroupVersion().String()
+	nodeVersion.Kind = "NodeVersion"
+	raw, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&nodeVersion)
+	if err != nil {
+		return fmt.Errorf("converting NodeVersion to unstructured: %w", err)
+	}
+	return k.retryAction(ctx, func(ctx context.Context) error {
+		if exists {
+			_, err := k.kubectl.UpdateCR(ctx, nodeVersionGVR, &unstructured.Unstructured{Object: raw})
+			return err
+		}
+		_, err := k.kubectl.CreateCR(ctx, nodeVersionGVR, &unstructured.Unstructured{Object: raw})
+		return err
+	})
+}
+
+// listNodeVersions returns all NodeVersions of the cluster.
+func (k *KubeCmd) listNodeVersions(ctx context.Context) ([]updatev1alpha1.NodeVersion, error) {
+	var raw []unstructured.Unstructured
+	if err := k.retryAction(ctx, func(ctx context.Context) error {
+		var err error
+		raw, err = k.kubectl.ListCRs(ctx, nodeVersionGVR)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("listing NodeVersions: %w", err)
+	}
+
+	nodeVersions := make([]updatev1alpha1.NodeVersion, 0, len(raw))
+	for _, obj := range raw {
+		var nodeVersion updatev1alpha1.NodeVersion
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), &nodeVersion); err != nil {
+			return nil, fmt.Errorf("converting unstructured to NodeVersion: %w", err)
+		}
+		nodeVersions = append(nodeVersions, nodeVersion)
+	}
+	return nodeVersions, nil
+}
+
+// listScalingGroups returns all ScalingGroups of the cluster.
+func (k *KubeCmd) listScalingGroups(ctx context.Context) ([]updatev1alpha1.ScalingGroup, error) {
+	var raw []unstructured.Unstructured
+	if err := k.retryAction(ctx, func(ctx context.Context) error {
+		var err error
+		raw, err = k.kubectl.ListCRs(ctx, scalingGroupGVR)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("listing ScalingGroups: %w", err)
+	}
+
+	scalingGroups := make([]updatev1alpha1.ScalingGroup, 0, len(raw))
+	for _, obj := range raw {
+		var scalingGroup updatev1alpha1.ScalingGroup
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), &scalingGroup); err != nil {
+			return nil, fmt.Errorf("converting unstructured to ScalingGroup: %w", err)
+		}
+		scalingGroups = append(scalingGroups, scalingGroup)
+	}
+	return scalingGroups, nil
+}
+
+// setScalingGroupNodeVersion points the given ScalingGroup to the NodeVersion with the given name.
+func (k *KubeCmd) setScalingGroupNodeVersion(ctx context.Context, scalingGroup updatev1alpha1.ScalingGroup, nodeVersionName string) error {
+	if scalingGroup.Spec.NodeVersion == nodeVersionName {
+		return nil
+	}
+	k.log.Debug("Switching NodeVersion of scaling group", "scalingGroup", scalingGroup.Name, "nodeVersion", nodeVersionName)
+	scalingGroup.Spec.NodeVersion = nodeVersionName
+	raw, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&scalingGroup)
+	if err != nil {
+		return fmt.Errorf("converting ScalingGroup to unstructured: %w", err)
+	}
+	if err := k.retryAction(ctx, func(ctx context.Context) error {
+		_, err := k.kubectl.UpdateCR(ctx, scalingGroupGVR, &unstructured.Unstructured{Object: raw})
+		return err
+	}); err != nil {
+		return fmt.Errorf("updating ScalingGroup %s: %w", scalingGroup.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,387 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package kubecmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/compatibility"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/edgelesssys/constellation/v2/internal/semver"
+	updatev1alpha1 "github.com/edgelesssys/constellation/v2/operators/constellation-node-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestUpgradeNodeGroupImage(t *testing.T) {
+	clusterVersion := updatev1alpha1.NodeVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: constants.NodeVersionResourceName},
+		Spec: updatev1alpha1.NodeVersionSpec{
+			ImageReference:                "/path/to/image:v1.2.2",
+			ImageVersion:                  "v1.2.2",
+			KubernetesComponentsReference: "k8s-components-new",
+			KubernetesClusterVersion:      "v1.30.1",
+		},
+	}
+	scalingGroups := []updatev1alpha1.ScalingGroup{
+		newScalingGroup("control-plane-sg", constants.DefaultControlPlaneGroupName, constants.NodeVersionResourceName),
+		newScalingGroup("worker-sg", constants.DefaultWorkerGroupName, constants.NodeVersionResourceName),
+		newScalingGroup("canary-sg", "worker-canary", constants.NodeVersionResourceName),
+	}
+	canaryVersion := func(imageVersion string) updatev1alpha1.NodeVersion {
+		return updatev1alpha1.NodeVersion{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   NodeGroupVersionName("worker-canary"),
+				Labels: map[string]string{constants.NodeGroupLabelKey: "worker-canary"},
+			},
+			Spec: updatev1alpha1.NodeVersionSpec{
+				ImageReference:                "/path/to/image:" + imageVersion,
+				ImageVersion:                  imageVersion,
+				KubernetesComponentsReference: "k8s-components-old",
+				KubernetesClusterVersion:      "v1.29.5",
+			},
+		}
+	}
+
+	testCases := map[string]struct {
+		nodeGroup          string
+		canaryVersion      *updatev1alpha1.NodeVersion
+		canaryInUse        bool
+		newImageVersion    semver.Semver
+		wantErr            bool
+		wantInvalidUpgrade bool
+		wantCanaryImage    string
+		wantCreated        bool
+	}{
+		"first upgrade of node group": {
+			nodeGroup:       "worker-canary",
+			newImageVersion: semver.NewFromInt(1, 2, 3, ""),
+			wantCanaryImage: "v1.2.3",
+			wantCreated:     true,
+		},
+		"upgrade of node group with own NodeVersion": {
+			nodeGroup:       "worker-canary",
+			canaryVersion:   func() *updatev1alpha1.NodeVersion { nv := canaryVersion("v1.2.3"); return &nv }(),
+			canaryInUse:     true,
+			newImageVersion: semver.NewFromInt(1, 2, 4, ""),
+			wantCanaryImage: "v1.2.4",
+		},
+		"left over NodeVersion is reset to the cluster's image": {
+			nodeGroup:       "worker-canary",
+			canaryVersion:   func() *updatev1alpha1.NodeVersion { nv := canaryVersion("v1.1.0"); return &nv }(),
+			newImageVersion: semver.NewFromInt(1, 2, 3, ""),
+			wantCanaryImage: "v1.2.3",
+		},
+		"node group already runs the image": {
+			nodeGroup:          "worker-canary",
+			canaryVersion:      func() *updatev1alpha1.NodeVersion { nv := canaryVersion("v1.2.3"); return &nv }(),
+			canaryInUse:        true,
+			newImageVersion:    semver.NewFromInt(1, 2, 3, ""),
+			wantErr:            true,
+			wantInvalidUpgrade: true,
+		},
+		"unknown node group": {
+			nodeGroup:       "unknown",
+			newImageVersion: semver.NewFromInt(1, 2, 3, ""),
+			wantErr:         true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			store := newStubCRStore(t)
+			store.add(t, nodeVersionGVR, &clusterVersion)
+			for _, scalingGroup := range scalingGroups {
+				if tc.canaryInUse && scalingGroup.Spec.NodeGroupName == "worker-canary" {
+					scalingGroup.Spec.NodeVersion = NodeGroupVersionName("worker-canary")
+				}
+				store.add(t, scalingGroupGVR, &scalingGroup)
+			}
+			if tc.canaryVersion != nil {
+				store.add(t, nodeVersionGVR, tc.canaryVersion)
+			}
+
+			upgrader := KubeCmd{
+				kubectl:       store,
+				retryInterval: time.Millisecond,
+				maxAttempts:   5,
+				log:           logger.NewTest(t),
+			}
+
+			err := upgrader.UpgradeNodeGroupImage(context.Background(), tc.nodeGroup, tc.newImageVersion,
+				"/path/to/image:"+tc.newImageVersion.String(), false)
+			if tc.wantErr {
+				assert.Error(err)
+				if tc.wantInvalidUpgrade {
+					var upgradeErr *compatibility.InvalidUpgradeError
+					assert.ErrorAs(err, &upgradeErr)
+				}
+				assert.Empty(store.created)
+				assert.Empty(store.updated)
+				return
+			}
+			require.NoError(err)
+
+			// Only the targeted node group uses the new image.
+			var gotCanary updatev1alpha1.NodeVersion
+			store.get(t, nodeVersionGVR, NodeGroupVersionName(tc.nodeGroup), &gotCanary)
+			assert.Equal(tc.wantCanaryImage, gotCanary.Spec.ImageVersion)
+			assert.Equal("/path/to/image:"+tc.wantCanaryImage, gotCanary.Spec.ImageReference)
+			assert.Equal(tc.nodeGroup, gotCanary.Labels[constants.NodeGroupLabelKey])
+			assert.Equal(clusterVersion.Spec.KubernetesComponentsReference, gotCanary.Spec.KubernetesComponentsReference)
+			assert.Equal(clusterVersion.Spec.KubernetesClusterVersion, gotCanary.Spec.KubernetesClusterVersion)
+			assert.Equal(tc.wantCreated, store.created[nodeVersionGVR.Resource+"/"+NodeGroupVersionName(tc.nodeGroup)])
+
+			var gotCluster updatev1alpha1.NodeVersion
+			store.get(t, nodeVersionGVR, constants.NodeVersionResourceName, &gotCluster)
+			assert.Equal(clusterVersion.Spec, gotCluster.Spec)
+
+			for _, scalingGroup := range scalingGroups {
+				var got updatev1alpha1.ScalingGroup
+				store.get(t, scalingGroupGVR, scalingGroup.Name, &got)
+				if scalingGroup.Spec.NodeGroupName == tc.nodeGroup {
+					assert.Equal(NodeGroupVersionName(tc.nodeGroup), got.Spec.NodeVersion)
+				} else {
+					assert.Equal(constants.NodeVersionResourceName, got.Spec.NodeVersion)
+				}
+			}
+		})
+	}
+}
+
+func TestUpgradeNodeImageResetsNodeGroups(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	store := newStubCRStore(t)
+	store.add(t, nodeVersionGVR, &updatev1alpha1.NodeVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: constants.NodeVersionResourceName},
+		Spec: updatev1alpha1.NodeVersionSpec{
+			ImageReference: "/path/to/image:v1.2.2",
+			ImageVersion:   "v1.2.2",
+		},
+	})
+	worker := newScalingGroup("worker-sg", constants.DefaultWorkerGroupName, constants.NodeVersionResourceName)
+	canary := newScalingGroup("canary-sg", "worker-canary", NodeGroupVersionName("worker-canary"))
+	store.add(t, scalingGroupGVR, &worker)
+	store.add(t, scalingGroupGVR, &canary)
+
+	upgrader := KubeCmd{
+		kubectl:       store,
+		retryInterval: time.Millisecond,
+		maxAttempts:   5,
+		log:           logger.NewTest(t),
+	}
+	require.NoError(upgrader.UpgradeNodeImage(context.Background(), semver.NewFromInt(1, 2, 3, ""), "/path/to/image:v1.2.3", false))
+
+	for _, name := range []string{"worker-sg", "canary-sg"} {
+		var got updatev1alpha1.ScalingGroup
+		store.get(t, scalingGroupGVR, name, &got)
+		assert.Equal(constants.NodeVersionResourceName, got.Spec.NodeVersion)
+	}
+}
+
+func TestSetDrainSettings(t *testing.T) {
+	canaryVersion := NodeGroupVersionName("worker-canary")
+
+	testCases := map[string]struct {
+		gracePeriod      time.Duration
+		timeout          time.Duration
+		wantGracePeriods map[string]int64
+		wantTimeouts     map[string]int64
+		wantUpdated      []string
+	}{
+		"grace period is set": {
+			gracePeriod: 5 * time.Minute,
+			wantGracePeriods: map[string]int64{
+				constants.NodeVersionResourceName: 300,
+				canaryVersion:                     300,
+			},
+			wantTimeouts: map[string]int64{
+				constants.NodeVersionResourceName: 0,
+				canaryVersion:                     0,
+			},
+			wantUpdated: []string{constants.NodeVersionResourceName},
+		},
+		"grace period is reset": {
+			wantGracePeriods: map[string]int64{
+				constants.NodeVersionResourceName: 0,
+				canaryVersion:                     0,
+			},
+			wantTimeouts: map[string]int64{
+				constants.NodeVersionResourceName: 0,
+				canaryVersion:                     0,
+			},
+			wantUpdated: []string{canaryVersion},
+		},
+		"timeout is set": {
+			gracePeriod: 5 * time.Minute,
+			timeout:     time.Hour,
+			wantGracePeriods: map[string]int64{
+				constants.NodeVersionResourceName: 300,
+				canaryVersion:                     300,
+			},
+			wantTimeouts: map[string]int64{
+				constants.NodeVersionResourceName: 3600,
+				canaryVersion:                     3600,
+			},
+			wantUpdated: []string{constants.NodeVersionResourceName, canaryVersion},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			store := newStubCRStore(t)
+			store.add(t, nodeVersionGVR, &updatev1alpha1.NodeVersion{
+				ObjectMeta: metav1.ObjectMeta{Name: constants.NodeVersionResourceName},
+			})
+			store.add(t, nodeVersionGVR, &updatev1alpha1.NodeVersion{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   canaryVersion,
+					Labels: map[string]string{constants.NodeGroupLabelKey: "worker-canary"},
+				},
+				Spec: updatev1alpha1.NodeVersionSpec{DrainGracePeriodSeconds: 300},
+			})
+
+			upgrader := KubeCmd{
+				kubectl:       store,
+				retryInterval: time.Millisecond,
+				maxAttempts:   5,
+				log:           logger.NewTest(t),
+			}
+			require.NoError(upgrader.SetDrainSettings(context.Background(), tc.gracePeriod, tc.timeout))
+
+			for name, want := range tc.wantGracePeriods {
+				var got updatev1alpha1.NodeVersion
+				store.get(t, nodeVersionGVR, name, &got)
+				assert.Equal(want, got.Spec.DrainGracePeriodSeconds, name)
+			}
+			for name, want := range tc.wantTimeouts {
+				var got updatev1alpha1.NodeVersion
+				store.get(t, nodeVersionGVR, name, &got)
+				assert.Equal(want, got.Spec.DrainTimeoutSeconds, name)
+			}
+			assert.Len(store.updated, len(tc.wantUpdated))
+			for _, name := range tc.wantUpdated {
+				assert.True(store.updated[nodeVersionGVR.Resource+"/"+name], name)
+			}
+		})
+	}
+}
+
+func newScalingGroup(name, nodeGroup, nodeVersion string) updatev1alpha1.ScalingGroup {
+	return updatev1alpha1.ScalingGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: updatev1alpha1.ScalingGroupSpec{
+			NodeVersion:   nodeVersion,
+			GroupID:       name,
+			NodeGroupName: nodeGroup,
+		},
+	}
+}
+
+// stubCRStore is an in-memory store of custom resources.
+type stubCRStore struct {
+	*stubKubectl
+	objects map[string]*unstructured.Unstructured
+	created map[string]bool
+	updated map[string]bool
+}
+
+func newStubCRStore(t *testing.T) *stubCRStore {
+	t.Helper()
+	return &stubCRStore{
+		stubKubectl: &stubKubectl{},
+		objects:     map[string]*unstructured.Unstructured{},
+		created:     map[string]bool{},
+		updated:     map[string]bool{},
+	}
+}
+
+func (s *stubCRStore) add(t *testing.T, gvr schema.GroupVersionResource, obj runtime.Object) {
+	t.Helper()
+	raw, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	require.NoError(t, err)
+	u := &unstructured.Unstructured{Object: raw}
+	u.SetAPIVersion(gvr.GroupVersion().String())
+	u.SetKind(map[string]string{"nodeversions": "NodeVersion", "scalinggroups": "ScalingGroup"}[gvr.Resource])
+	s.objects[gvr.Resource+"/"+u.GetName()] = u
+}
+
+func (s *stubCRStore) get(t *testing.T, gvr schema.GroupVersionResource, name string, obj any) {
+	t.Helper()
+	u, ok := s.objects[gvr.Resource+"/"+name]
+	require.True(t, ok, "object %s/%s not found", gvr.Resource, name)
+	require.NoError(t, runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), obj))
+}
+
+func (s *stubCRStore) GetCR(_ context.Context, gvr schema.GroupVersionResource, name string) (*unstructured.Unstructured, error) {
+	u, ok := s.objects[gvr.Resource+"/"+name]
+	if !ok {
+		return nil, k8serrors.NewNotFound(gvr.GroupResource(), name)
+	}
+	return copyUnstructured(u), nil
+}
+
+func (s *stubCRStore) UpdateCR(_ context.Context, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	key := gvr.Resource + "/" + obj.GetName()
+	if _, ok := s.objects[key]; !ok {
+		return nil, k8serrors.NewNotFound(gvr.GroupResource(), obj.GetName())
+	}
+	s.objects[key] = copyUnstructured(obj)
+	s.updated[key] = true
+	return obj, nil
+}
+
+func (s *stubCRStore) CreateCR(_ context.Context, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	key := gvr.Resource + "/" + obj.GetName()
+	if _, ok := s.objects[key]; ok {
+		return nil, k8serrors.NewAlreadyExists(gvr.GroupResource(), obj.GetName())
+	}
+	s.objects[key] = copyUnstructured(obj)
+	s.created[key] = true
+	return obj, nil
+}
+
+func (s *stubCRStore) ListCRs(_ context.Context, gvr schema.GroupVersionResource) ([]unstructured.Unstructured, error) {
+	var list []unstructured.Unstructured
+	for key, u := range s.objects {
+		if strings.HasPrefix(key, gvr.Resource+"/") {
+			list = append(list, *copyUnstructured(u))
+		}
+	}
+	return list, nil
+}
+
+// copyUnstructured copies an object through its JSON encoding,
+// since the converted objects may hold values [unstructured.Unstructured.DeepCopy] can't copy.
+func copyUnstructured(u *unstructured.Unstructured) *unstructured.Unstructured {
+	raw, err := u.MarshalJSON()
+	if err != nil {
+		panic(err)
+	}
+	out := &unstructured.Unstructured{}
+	if err := out.UnmarshalJSON(raw); err != nil {
+		panic(err)
+	}
+	return out
+}
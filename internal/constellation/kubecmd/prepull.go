@@ -0,0 +1,169 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package kubecmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// prePullDaemonSet is the name of the DaemonSet that pre-pulls images on all nodes.
+	prePullDaemonSet = "constellation-image-pre-pull"
+	// prePullLabel selects the pods of the pre-pull DaemonSet.
+	prePullLabel = "app.kubernetes.io/name"
+	// prePullCommand is the command of the pre-pull containers.
+	// It doesn't exist in the images, so the containers fail to start once their image is pulled,
+	// without running any code of the image.
+	prePullCommand = "/constellation-pre-pull"
+)
+
+// PrePullImages pulls the given images on all nodes and waits until every node has pulled them.
+// The images are pulled by a temporary DaemonSet with one container per image, which is deleted afterwards.
+// The waiting stops once ctx is done.
+func (k *KubeCmd) PrePullImages(ctx context.Context, images []string) (retErr error) {
+	if len(images) == 0 {
+		return nil
+	}
+
+	// A DaemonSet left over by an interrupted run may pull different images.
+	if err := k.deletePrePullDaemonSet(ctx); err != nil {
+		return err
+	}
+	k.log.Debug("Creating image pre-pull DaemonSet", "name", prePullDaemonSet, "images", images)
+	daemonSet := prePullDaemonSetFor(images)
+	if err := k.retryAction(ctx, func(ctx context.Context) error {
+		return k.kubectl.CreateDaemonSet(ctx, daemonSet)
+	}); err != nil {
+		return fmt.Errorf("creating image pre-pull DaemonSet: %w", err)
+	}
+	defer func() {
+		retErr = errors.Join(retErr, k.deletePrePullDaemonSet(context.WithoutCancel(ctx)))
+	}()
+
+	ticker := time.NewTicker(k.retryInterval)
+	defer ticker.Stop()
+	for {
+		pulled, err := k.imagesPrePulled(ctx)
+		if err != nil {
+			k.log.Debug("Checking image pre-pull status failed", "error", err)
+		} else if pulled {
+			k.log.Debug("Images pre-pulled on all nodes")
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for images to be pre-pulled: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// imagesPrePulled returns true if the pods of the pre-pull DaemonSet pulled their images on all nodes.
+func (k *KubeCmd) imagesPrePulled(ctx context.Context) (bool, error) {
+	daemonSet, err := k.kubectl.GetDaemonSet(ctx, constants.ConstellationNamespace, prePullDaemonSet)
+	if err != nil {
+		return false, fmt.Errorf("getting image pre-pull DaemonSet: %w", err)
+	}
+	pods, err := k.kubectl.ListPods(ctx, constants.ConstellationNamespace, prePullLabel+"="+prePullDaemonSet)
+	if err != nil {
+		return false, fmt.Errorf("listing image pre-pull pods: %w", err)
+	}
+
+	status := daemonSet.Status
+	if status.ObservedGeneration < daemonSet.Generation || status.DesiredNumberScheduled == 0 {
+		return false, nil
+	}
+	var pulled int32
+	for _, pod := range pods {
+		if pod.DeletionTimestamp == nil && podImagesPulled(pod) {
+			pulled++
+		}
+	}
+	k.log.Debug("Waiting for images to be pre-pulled", "nodes", status.DesiredNumberScheduled, "pulled", pulled)
+	return pulled >= status.DesiredNumberScheduled, nil
+}
+
+// deletePrePullDaemonSet deletes the pre-pull DaemonSet, if it exists.
+func (k *KubeCmd) deletePrePullDaemonSet(ctx context.Context) error {
+	if err := k.retryAction(ctx, func(ctx context.Context) error {
+		err := k.kubectl.DeleteDaemonSet(ctx, constants.ConstellationNamespace, prePullDaemonSet)
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}); err != nil {
+		return fmt.Errorf("deleting image pre-pull DaemonSet: %w", err)
+	}
+	return nil
+}
+
+// podImagesPulled returns true if the images of all containers of the pod are pulled.
+// The container runtime only reports the image ID of a container once its image is pulled.
+func podImagesPulled(pod corev1.Pod) bool {
+	imageIDs := make(map[string]string, len(pod.Status.ContainerStatuses))
+	for _, status := range pod.Status.ContainerStatuses {
+		imageIDs[status.Name] = status.ImageID
+	}
+	for _, container := range pod.Spec.Containers {
+		if imageIDs[container.Name] == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// prePullDaemonSetFor returns a DaemonSet that pulls the given images on all nodes.
+// The pods use the host network, so they can pull images before a CNI is installed.
+func prePullDaemonSetFor(images []string) *appsv1.DaemonSet {
+	labels := map[string]string{prePullLabel: prePullDaemonSet}
+	containers := make([]corev1.Container, 0, len(images))
+	for i, image := range images {
+		containers = append(containers, corev1.Container{
+			Name:            fmt.Sprintf("image-%d", i),
+			Image:           image,
+			Command:         []string{prePullCommand},
+			ImagePullPolicy: corev1.PullIfNotPresent,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("1m"),
+					corev1.ResourceMemory: resource.MustParse("1Mi"),
+				},
+			},
+		})
+	}
+
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      prePullDaemonSet,
+			Namespace: constants.ConstellationNamespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					Containers:                    containers,
+					HostNetwork:                   true,
+					Tolerations:                   []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+					TerminationGracePeriodSeconds: new(int64),
+				},
+			},
+		},
+	}
+}
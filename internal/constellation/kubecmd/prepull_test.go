@@ -0,0 +1,209 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package kubecmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestPrePullImages(t *testing.T) {
+	images := []string{"registry.example.com/a:v1", "registry.example.com/b:v2"}
+	scheduled := appsv1.DaemonSetStatus{ObservedGeneration: 1, DesiredNumberScheduled: 2}
+	pulledPod := func() corev1.Pod {
+		return prePullPod(map[string]string{"image-0": "sha256:a", "image-1": "sha256:b"})
+	}
+
+	testCases := map[string]struct {
+		client      *fakePrePullClient
+		images      []string
+		wantCreated bool
+		wantErr     bool
+	}{
+		"images pulled on all nodes": {
+			client: &fakePrePullClient{
+				status: scheduled,
+				pods:   []corev1.Pod{pulledPod(), pulledPod()},
+			},
+			images:      images,
+			wantCreated: true,
+		},
+		"images pulled after a while": {
+			client: &fakePrePullClient{
+				status:      scheduled,
+				pods:        []corev1.Pod{pulledPod(), prePullPod(map[string]string{"image-0": "sha256:a", "image-1": ""})},
+				pulledAfter: 3,
+			},
+			images:      images,
+			wantCreated: true,
+		},
+		"leftover DaemonSet is replaced": {
+			client: &fakePrePullClient{
+				status:   scheduled,
+				pods:     []corev1.Pod{pulledPod(), pulledPod()},
+				existing: true,
+			},
+			images:      images,
+			wantCreated: true,
+		},
+		"no images": {},
+		"images not pulled on all nodes": {
+			client: &fakePrePullClient{
+				status: scheduled,
+				pods:   []corev1.Pod{pulledPod()},
+			},
+			images:      images,
+			wantCreated: true,
+			wantErr:     true,
+		},
+		"terminating pods are ignored": {
+			client: &fakePrePullClient{
+				status: scheduled,
+				pods: []corev1.Pod{pulledPod(), func() corev1.Pod {
+					pod := pulledPod()
+					pod.DeletionTimestamp = &metav1.Time{}
+					return pod
+				}()},
+			},
+			images:      images,
+			wantCreated: true,
+			wantErr:     true,
+		},
+		"DaemonSet not scheduled yet": {
+			client: &fakePrePullClient{
+				status: appsv1.DaemonSetStatus{},
+			},
+			images:      images,
+			wantCreated: true,
+			wantErr:     true,
+		},
+		"creating DaemonSet fails": {
+			client: &fakePrePullClient{
+				createErr: errors.New("create error"),
+			},
+			images:  images,
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			if tc.client == nil {
+				tc.client = &fakePrePullClient{}
+			}
+			k := &KubeCmd{
+				kubectl:       tc.client,
+				retryInterval: time.Millisecond,
+				maxAttempts:   1,
+				log:           logger.NewTest(t),
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			err := k.PrePullImages(ctx, tc.images)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				require.NoError(err)
+			}
+
+			if !tc.wantCreated {
+				assert.Nil(tc.client.created)
+				return
+			}
+			require.NotNil(tc.client.created)
+			assert.Equal(prePullDaemonSet, tc.client.created.Name)
+			assert.Equal(constants.ConstellationNamespace, tc.client.created.Namespace)
+			podSpec := tc.client.created.Spec.Template.Spec
+			assert.True(podSpec.HostNetwork)
+			require.Len(podSpec.Containers, len(tc.images))
+			for i, container := range podSpec.Containers {
+				assert.Equal(tc.images[i], container.Image)
+				assert.Equal(corev1.PullIfNotPresent, container.ImagePullPolicy)
+			}
+			assert.Positive(tc.client.polls)
+			assert.False(tc.client.existing, "pre-pull DaemonSet must be deleted")
+		})
+	}
+}
+
+func prePullPod(imageIDs map[string]string) corev1.Pod {
+	var pod corev1.Pod
+	for name, imageID := range imageIDs {
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: name})
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, corev1.ContainerStatus{Name: name, ImageID: imageID})
+	}
+	return pod
+}
+
+type fakePrePullClient struct {
+	existing    bool
+	created     *appsv1.DaemonSet
+	createErr   error
+	status      appsv1.DaemonSetStatus
+	pods        []corev1.Pod
+	pulledAfter int
+	polls       int
+	kubectlInterface
+}
+
+func (f *fakePrePullClient) CreateDaemonSet(_ context.Context, daemonSet *appsv1.DaemonSet) error {
+	if f.createErr != nil {
+		return f.createErr
+	}
+	if f.existing {
+		return k8serrors.NewAlreadyExists(schema.GroupResource{Resource: "daemonsets"}, daemonSet.Name)
+	}
+	f.created = daemonSet.DeepCopy()
+	f.created.Generation = 1
+	f.existing = true
+	return nil
+}
+
+func (f *fakePrePullClient) DeleteDaemonSet(_ context.Context, _, name string) error {
+	if !f.existing {
+		return k8serrors.NewNotFound(schema.GroupResource{Resource: "daemonsets"}, name)
+	}
+	f.existing = false
+	return nil
+}
+
+func (f *fakePrePullClient) GetDaemonSet(_ context.Context, _, name string) (*appsv1.DaemonSet, error) {
+	if !f.existing {
+		return nil, k8serrors.NewNotFound(schema.GroupResource{Resource: "daemonsets"}, name)
+	}
+	daemonSet := f.created.DeepCopy()
+	daemonSet.Status = f.status
+	return daemonSet, nil
+}
+
+func (f *fakePrePullClient) ListPods(_ context.Context, _, _ string) ([]corev1.Pod, error) {
+	f.polls++
+	if f.pulledAfter > 0 && f.polls >= f.pulledAfter {
+		for i := range f.pods {
+			for j := range f.pods[i].Status.ContainerStatuses {
+				f.pods[i].Status.ContainerStatuses[j].ImageID = "sha256:pulled"
+			}
+		}
+	}
+	return f.pods, nil
+}
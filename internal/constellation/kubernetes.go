@@ -10,17 +10,28 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/edgelesssys/constellation/v2/internal/attestation/variant"
 	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/constellation/kubecmd"
 	"github.com/edgelesssys/constellation/v2/internal/file"
 	"github.com/edgelesssys/constellation/v2/internal/semver"
 	"github.com/edgelesssys/constellation/v2/internal/versions"
+	networkingv1 "k8s.io/api/networking/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 )
 
 var errKubecmdNotInitialised = errors.New("kubernetes client not initialized")
 
+// CheckAPIServerReachable checks that the Kubernetes API server can be reached with the clients set by [Applier.SetKubeConfig].
+func (a *Applier) CheckAPIServerReachable() error {
+	if _, err := a.kubecmdClient.ServerVersion(); err != nil {
+		return fmt.Errorf("getting Kubernetes server version: %w", err)
+	}
+	return nil
+}
+
 // ExtendClusterConfigCertSANs extends the ClusterConfig stored under "kube-system/kubeadm-config" with the given SANs.
 func (a *Applier) ExtendClusterConfigCertSANs(ctx context.Context, clusterEndpoint, customEndpoint string, additionalAPIServerCertSANs []string) error {
 	if a.kubecmdClient == nil {
@@ -61,6 +72,24 @@ func (a *Applier) UpgradeNodeImage(ctx context.Context, imageVersion semver.Semv
 	return a.kubecmdClient.UpgradeNodeImage(ctx, imageVersion, imageReference, force)
 }
 
+// UpgradeNodeGroupImage upgrades the node image of a single node group to the given version.
+func (a *Applier) UpgradeNodeGroupImage(ctx context.Context, nodeGroup string, imageVersion semver.Semver, imageReference string, force bool) error {
+	if a.kubecmdClient == nil {
+		return errKubecmdNotInitialised
+	}
+
+	return a.kubecmdClient.UpgradeNodeGroupImage(ctx, nodeGroup, imageVersion, imageReference, force)
+}
+
+// GetConstellationVersion returns the image and Kubernetes versions of the cluster.
+func (a *Applier) GetConstellationVersion(ctx context.Context) (kubecmd.NodeVersion, error) {
+	if a.kubecmdClient == nil {
+		return kubecmd.NodeVersion{}, errKubecmdNotInitialised
+	}
+
+	return a.kubecmdClient.GetConstellationVersion(ctx)
+}
+
 // UpgradeKubernetesVersion upgrades the Kubernetes version of the cluster to the given version.
 func (a *Applier) UpgradeKubernetesVersion(ctx context.Context, kubernetesVersion versions.ValidK8sVersion, force bool) error {
 	if a.kubecmdClient == nil {
@@ -70,6 +99,35 @@ func (a *Applier) UpgradeKubernetesVersion(ctx context.Context, kubernetesVersio
 	return a.kubecmdClient.UpgradeKubernetesVersion(ctx, kubernetesVersion, force)
 }
 
+// SetDrainSettings sets the time pods get to terminate when their node is drained during an upgrade,
+// and the time a node gets to drain before it's replaced anyway.
+func (a *Applier) SetDrainSettings(ctx context.Context, gracePeriod, timeout time.Duration) error {
+	if a.kubecmdClient == nil {
+		return errKubecmdNotInitialised
+	}
+
+	return a.kubecmdClient.SetDrainSettings(ctx, gracePeriod, timeout)
+}
+
+// ApplyNetworkPolicies creates or updates the given NetworkPolicies and removes NetworkPolicies
+// previously applied by Constellation that are no longer part of the given policies.
+func (a *Applier) ApplyNetworkPolicies(ctx context.Context, policies []networkingv1.NetworkPolicy) error {
+	if a.kubecmdClient == nil {
+		return errKubecmdNotInitialised
+	}
+
+	return a.kubecmdClient.ApplyNetworkPolicies(ctx, policies)
+}
+
+// PrePullImages pulls the given images on all nodes of the cluster.
+func (a *Applier) PrePullImages(ctx context.Context, images []string) error {
+	if a.kubecmdClient == nil {
+		return errKubecmdNotInitialised
+	}
+
+	return a.kubecmdClient.PrePullImages(ctx, images)
+}
+
 // BackupCRDs backs up all CRDs to the upgrade workspace.
 func (a *Applier) BackupCRDs(ctx context.Context, fileHandler file.Handler, upgradeDir string) ([]apiextensionsv1.CustomResourceDefinition, error) {
 	if a.kubecmdClient == nil {
@@ -89,11 +147,17 @@ func (a *Applier) BackupCRs(ctx context.Context, fileHandler file.Handler, crds
 }
 
 type kubecmdClient interface {
+	ServerVersion() (string, error)
 	UpgradeNodeImage(ctx context.Context, imageVersion semver.Semver, imageReference string, force bool) error
+	UpgradeNodeGroupImage(ctx context.Context, nodeGroup string, imageVersion semver.Semver, imageReference string, force bool) error
 	UpgradeKubernetesVersion(ctx context.Context, kubernetesVersion versions.ValidK8sVersion, force bool) error
+	SetDrainSettings(ctx context.Context, gracePeriod, timeout time.Duration) error
+	GetConstellationVersion(ctx context.Context) (kubecmd.NodeVersion, error)
 	ExtendClusterConfigCertSANs(ctx context.Context, alternativeNames []string) error
 	GetClusterAttestationConfig(ctx context.Context, variant variant.Variant) (config.AttestationCfg, error)
 	ApplyJoinConfig(ctx context.Context, newAttestConfig config.AttestationCfg, measurementSalt []byte) error
+	ApplyNetworkPolicies(ctx context.Context, policies []networkingv1.NetworkPolicy) error
+	PrePullImages(ctx context.Context, images []string) error
 	BackupCRs(ctx context.Context, fileHandler file.Handler, crds []apiextensionsv1.CustomResourceDefinition, upgradeDir string) error
 	BackupCRDs(ctx context.Context, fileHandler file.Handler, upgradeDir string) ([]apiextensionsv1.CustomResourceDefinition, error)
 }
@@ -0,0 +1,297 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package state
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/file"
+)
+
+const (
+	// DefaultLockHeartbeatInterval is the interval in which the holder of a lock refreshes its heartbeat.
+	DefaultLockHeartbeatInterval = 10 * time.Second
+	// DefaultLockStaleAfter is the time after which a lock without a heartbeat is considered stale.
+	DefaultLockStaleAfter = 2 * time.Minute
+)
+
+// Lock is the content of a state lock file.
+type Lock struct {
+	// Holder identifies the process holding the lock, e.g. "host:pid".
+	Holder string `json:"holder"`
+	// AcquiredAt is the time the lock was acquired.
+	AcquiredAt time.Time `json:"acquiredAt"`
+	// Heartbeat is the last time the holder signaled that it is still alive.
+	Heartbeat time.Time `json:"heartbeat"`
+}
+
+// LockHeldError is returned if a lock can't be acquired because it is held by another process.
+type LockHeldError struct {
+	// Holder of the lock.
+	Holder string
+	// Age is the time since the lock was acquired.
+	Age time.Duration
+	// Stale is true if the holder didn't refresh its heartbeat in time.
+	Stale bool
+}
+
+// Error returns the error message.
+func (e *LockHeldError) Error() string {
+	msg := fmt.Sprintf("state is locked by %s since %s", e.Holder, e.Age.Round(time.Second))
+	if e.Stale {
+		msg += "; the lock is stale and can be removed with --force-unlock"
+	}
+	return msg
+}
+
+// Locker guards a state file against concurrent modifications using a lock file.
+// The holder of the lock periodically refreshes a heartbeat, so locks left behind by crashed
+// processes can be detected as stale.
+type Locker struct {
+	fileHandler file.Handler
+	path        string
+	holder      string
+
+	heartbeatInterval time.Duration
+	staleAfter        time.Duration
+	now               func() time.Time
+
+	stop chan struct{}
+	done sync.WaitGroup
+}
+
+// NewLocker creates a new Locker using the lock file at path.
+// holder identifies the calling process in the lock file.
+func NewLocker(fileHandler file.Handler, path, holder string) *Locker {
+	return &Locker{
+		fileHandler:       fileHandler,
+		path:              path,
+		holder:            holder,
+		heartbeatInterval: DefaultLockHeartbeatInterval,
+		staleAfter:        DefaultLockStaleAfter,
+		now:               time.Now,
+	}
+}
+
+// Acquire acquires the lock, waiting up to timeout for another holder to release it.
+// If forceUnlock is set, a stale lock is removed instead of waiting for it.
+// Once acquired, the heartbeat of the lock is refreshed until [Locker.Release] is called.
+func (l *Locker) Acquire(ctx context.Context, timeout time.Duration, forceUnlock bool) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	retry := time.NewTicker(l.heartbeatInterval / 4)
+	defer retry.Stop()
+
+	for {
+		heldErr, err := l.tryAcquire()
+		if err != nil {
+			return err
+		}
+		if heldErr == nil {
+			l.startHeartbeat()
+			return nil
+		}
+		if heldErr.Stale && forceUnlock {
+			if err := l.ForceUnlock(); err != nil && !errors.Is(err, errLockChanged) {
+				return err
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for state lock: %w", heldErr)
+		case <-retry.C:
+		}
+	}
+}
+
+// Release stops refreshing the heartbeat and removes the lock file.
+func (l *Locker) Release() error {
+	if l.stop == nil {
+		return nil
+	}
+	close(l.stop)
+	l.done.Wait()
+	l.stop = nil
+	lock, raw, err := l.read()
+	if err != nil || lock.Holder != l.holder {
+		// The lock was removed or taken over by someone else.
+		return nil
+	}
+	if _, err := l.compareAndRemove(raw); err != nil {
+		return fmt.Errorf("removing state lock: %w", err)
+	}
+	return nil
+}
+
+// ForceUnlock removes the lock file if the lock is stale.
+// Locks with a recent heartbeat are never removed, since their holder is likely still running.
+// If the lock changes while it's removed, e.g., because its holder refreshed the heartbeat
+// or another process acquired it, the changed lock is kept.
+func (l *Locker) ForceUnlock() error {
+	lock, raw, err := l.read()
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil && !errors.Is(err, errIncompleteLock) {
+		return err
+	}
+	stale, err := l.isStale(lock, err)
+	if err != nil {
+		return err
+	}
+	if !stale {
+		return fmt.Errorf("refusing to remove state lock held by %s: the holder is still alive", lock.Holder)
+	}
+	removed, err := l.compareAndRemove(raw)
+	if err != nil {
+		return fmt.Errorf("removing stale state lock: %w", err)
+	}
+	if !removed {
+		return fmt.Errorf("refusing to remove state lock: %w", errLockChanged)
+	}
+	return nil
+}
+
+// tryAcquire creates the lock file.
+// The lock file is created exclusively, so only one process can acquire the lock.
+// If the lock is held by another process, a [LockHeldError] describing the holder is returned.
+func (l *Locker) tryAcquire() (*LockHeldError, error) {
+	now := l.now()
+	data, err := json.Marshal(Lock{Holder: l.holder, AcquiredAt: now, Heartbeat: now})
+	if err != nil {
+		return nil, fmt.Errorf("marshalling state lock: %w", err)
+	}
+	// Without options, the file is opened with O_CREATE|O_EXCL.
+	err = l.fileHandler.Write(l.path, data)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, fs.ErrExist) {
+		return nil, fmt.Errorf("creating state lock: %w", err)
+	}
+
+	lock, _, err := l.read()
+	if errors.Is(err, fs.ErrNotExist) {
+		// The lock was released in the meantime, try again.
+		return l.tryAcquire()
+	}
+	if err != nil && !errors.Is(err, errIncompleteLock) {
+		return nil, err
+	}
+	stale, err := l.isStale(lock, err)
+	if err != nil {
+		return nil, err
+	}
+	heldErr := &LockHeldError{Holder: lock.Holder, Stale: stale}
+	if !lock.AcquiredAt.IsZero() {
+		heldErr.Age = now.Sub(lock.AcquiredAt)
+	}
+	return heldErr, nil
+}
+
+// startHeartbeat refreshes the heartbeat of the lock in the background.
+// The lock file is replaced atomically, so other processes never read a partially written lock.
+func (l *Locker) startHeartbeat() {
+	stop := make(chan struct{})
+	l.stop = stop
+	l.done.Add(1)
+	go func() {
+		defer l.done.Done()
+		ticker := time.NewTicker(l.heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				lock, _, err := l.read()
+				if err != nil || lock.Holder != l.holder {
+					// The lock was removed or taken over, nothing left to refresh.
+					continue
+				}
+				lock.Heartbeat = l.now()
+				_ = l.fileHandler.WriteJSON(l.path, lock, file.OptOverwrite, file.OptAtomic)
+			}
+		}
+	}()
+}
+
+// compareAndRemove removes the lock file if its content still equals raw.
+// The lock file is first moved out of the way atomically, so a lock that is created or refreshed
+// concurrently is never removed. If the moved lock differs from raw, it's restored,
+// unless another process acquired the lock in the meantime.
+// It returns false if the lock wasn't removed because it changed.
+func (l *Locker) compareAndRemove(raw []byte) (bool, error) {
+	removing := fmt.Sprintf("%s.%d.remove", l.path, time.Now().UnixNano())
+	if err := l.fileHandler.RenameFile(l.path, removing); errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	moved, err := l.fileHandler.Read(removing)
+	if err != nil {
+		return false, err
+	}
+	if bytes.Equal(moved, raw) {
+		if err := l.fileHandler.Remove(removing); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return false, err
+		}
+		return true, nil
+	}
+
+	// The lock changed after it was read: put it back without replacing a newly acquired lock.
+	if err := l.fileHandler.Write(l.path, moved); err != nil && !errors.Is(err, fs.ErrExist) {
+		return false, fmt.Errorf("restoring state lock: %w", err)
+	}
+	return false, l.fileHandler.Remove(removing)
+}
+
+// read reads the lock file and returns the parsed lock along with its raw content.
+// If the lock file exists but can't be parsed, e.g., because its holder crashed
+// while creating it, errIncompleteLock is returned.
+func (l *Locker) read() (Lock, []byte, error) {
+	raw, err := l.fileHandler.Read(l.path)
+	if err != nil {
+		return Lock{}, nil, fmt.Errorf("reading state lock: %w", err)
+	}
+	var lock Lock
+	if err := json.Unmarshal(raw, &lock); err != nil {
+		return Lock{Holder: "unknown"}, raw, fmt.Errorf("%w: %w", errIncompleteLock, err)
+	}
+	return lock, raw, nil
+}
+
+// isStale reports whether the lock wasn't refreshed in time.
+// Incomplete locks have no heartbeat, so the modification time of the lock file is used instead.
+func (l *Locker) isStale(lock Lock, readErr error) (bool, error) {
+	heartbeat := lock.Heartbeat
+	if errors.Is(readErr, errIncompleteLock) {
+		info, err := l.fileHandler.Stat(l.path)
+		if err != nil {
+			return false, fmt.Errorf("reading state lock: %w", err)
+		}
+		heartbeat = info.ModTime()
+	}
+	return l.now().Sub(heartbeat) > l.staleAfter, nil
+}
+
+var (
+	// errIncompleteLock is returned if the lock file can't be parsed.
+	errIncompleteLock = errors.New("state lock is incomplete")
+	// errLockChanged is returned if the lock changed while it was removed.
+	errLockChanged = errors.New("the lock changed while removing it")
+)
@@ -0,0 +1,245 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package state
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLocker(fh file.Handler, holder string) *Locker {
+	l := NewLocker(fh, constants.StateLockFilename, holder)
+	l.heartbeatInterval = 10 * time.Millisecond
+	return l
+}
+
+func TestLockerAcquire(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fh := file.NewHandler(afero.NewMemMapFs())
+	locker := newTestLocker(fh, "host:1")
+
+	require.NoError(locker.Acquire(context.Background(), 0, false))
+	var lock Lock
+	require.NoError(fh.ReadJSON(constants.StateLockFilename, &lock))
+	assert.Equal("host:1", lock.Holder)
+	assert.False(lock.AcquiredAt.IsZero())
+
+	require.NoError(locker.Release())
+	_, err := fh.Stat(constants.StateLockFilename)
+	assert.ErrorIs(err, afero.ErrFileNotFound)
+
+	// the lock can be acquired again after it was released
+	require.NoError(locker.Acquire(context.Background(), 0, false))
+	require.NoError(locker.Release())
+}
+
+func TestLockerAcquireHeld(t *testing.T) {
+	testCases := map[string]struct {
+		releaseAfter time.Duration
+		timeout      time.Duration
+		wantErr      bool
+	}{
+		"held until timeout": {
+			timeout: 50 * time.Millisecond,
+			wantErr: true,
+		},
+		"no timeout fails immediately": {
+			wantErr: true,
+		},
+		"released while waiting": {
+			releaseAfter: 20 * time.Millisecond,
+			timeout:      time.Minute,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fh := file.NewHandler(afero.NewMemMapFs())
+			holder := newTestLocker(fh, "host:1")
+			require.NoError(holder.Acquire(context.Background(), 0, false))
+			if tc.releaseAfter > 0 {
+				go func() {
+					time.Sleep(tc.releaseAfter)
+					assert.NoError(holder.Release())
+				}()
+			} else {
+				defer func() { assert.NoError(holder.Release()) }()
+			}
+
+			waiter := newTestLocker(fh, "host:2")
+			start := time.Now()
+			err := waiter.Acquire(context.Background(), tc.timeout, false)
+			if !tc.wantErr {
+				require.NoError(err)
+				assert.NoError(waiter.Release())
+				return
+			}
+			assert.GreaterOrEqual(time.Since(start), tc.timeout)
+			var heldErr *LockHeldError
+			require.ErrorAs(err, &heldErr)
+			assert.Equal("host:1", heldErr.Holder)
+			assert.False(heldErr.Stale)
+			assert.ErrorContains(err, "state is locked by host:1 since")
+		})
+	}
+}
+
+func TestLockerForceUnlock(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		heartbeat   time.Time
+		forceUnlock bool
+		wantStale   bool
+		wantErr     bool
+	}{
+		"stale lock is removed": {
+			heartbeat:   now.Add(-time.Hour),
+			forceUnlock: true,
+		},
+		"stale lock is kept without force unlock": {
+			heartbeat: now.Add(-time.Hour),
+			wantStale: true,
+			wantErr:   true,
+		},
+		"live lock is never removed": {
+			heartbeat:   now.Add(-time.Second),
+			forceUnlock: true,
+			wantErr:     true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fh := file.NewHandler(afero.NewMemMapFs())
+			require.NoError(fh.WriteJSON(constants.StateLockFilename, Lock{
+				Holder:     "crashed:1",
+				AcquiredAt: now.Add(-2 * time.Hour),
+				Heartbeat:  tc.heartbeat,
+			}))
+
+			locker := newTestLocker(fh, "host:2")
+			locker.now = func() time.Time { return now }
+			err := locker.Acquire(context.Background(), 0, tc.forceUnlock)
+			if !tc.wantErr {
+				require.NoError(err)
+				var lock Lock
+				require.NoError(fh.ReadJSON(constants.StateLockFilename, &lock))
+				assert.Equal("host:2", lock.Holder)
+				assert.NoError(locker.Release())
+				return
+			}
+			var heldErr *LockHeldError
+			if assert.ErrorAs(err, &heldErr) {
+				assert.Equal("crashed:1", heldErr.Holder)
+				assert.Equal(2*time.Hour, heldErr.Age)
+				assert.Equal(tc.wantStale, heldErr.Stale)
+			}
+			// force unlocking removes stale locks, but keeps live ones
+			err = locker.ForceUnlock()
+			_, statErr := fh.Stat(constants.StateLockFilename)
+			if tc.wantStale {
+				assert.NoError(err)
+				assert.ErrorIs(statErr, afero.ErrFileNotFound)
+			} else {
+				assert.Error(err)
+				assert.NoError(statErr)
+			}
+		})
+	}
+}
+
+func TestLockerIncompleteLock(t *testing.T) {
+	now := time.Now()
+
+	testCases := map[string]struct {
+		modTime   time.Time
+		wantStale bool
+	}{
+		"lock being written is held": {
+			modTime: now,
+		},
+		"lock left incomplete by a crash is stale": {
+			modTime:   now.Add(-time.Hour),
+			wantStale: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fs := afero.NewMemMapFs()
+			fh := file.NewHandler(fs)
+			require.NoError(fh.Write(constants.StateLockFilename, []byte(`{"holder":`)))
+			require.NoError(fs.Chtimes(constants.StateLockFilename, tc.modTime, tc.modTime))
+
+			locker := newTestLocker(fh, "host:2")
+			locker.now = func() time.Time { return now }
+			err := locker.Acquire(context.Background(), 0, false)
+			var heldErr *LockHeldError
+			require.ErrorAs(err, &heldErr)
+			assert.Equal("unknown", heldErr.Holder)
+			assert.Equal(tc.wantStale, heldErr.Stale)
+
+			err = locker.Acquire(context.Background(), 0, true)
+			if !tc.wantStale {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.NoError(locker.Release())
+		})
+	}
+}
+
+func TestLockerCompareAndRemove(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fh := file.NewHandler(afero.NewMemMapFs())
+	stale := []byte(`{"holder":"crashed:1"}`)
+	refreshed := []byte(`{"holder":"crashed:1","heartbeat":"2024-01-01T12:00:00Z"}`)
+	require.NoError(fh.Write(constants.StateLockFilename, refreshed))
+
+	// a lock that changed after it was read is kept
+	locker := newTestLocker(fh, "host:2")
+	removed, err := locker.compareAndRemove(stale)
+	require.NoError(err)
+	assert.False(removed)
+	got, err := fh.Read(constants.StateLockFilename)
+	require.NoError(err)
+	assert.Equal(refreshed, got)
+
+	// an unchanged lock is removed
+	removed, err = locker.compareAndRemove(refreshed)
+	require.NoError(err)
+	assert.True(removed)
+	_, err = fh.Stat(constants.StateLockFilename)
+	assert.ErrorIs(err, afero.ErrFileNotFound)
+
+	// no temporary files are left behind
+	entries, err := fh.ReadDir(".")
+	require.NoError(err)
+	assert.Empty(entries)
+}
@@ -13,20 +13,31 @@ SPDX-License-Identifier: AGPL-3.0-only
 package state
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
+	"sort"
 
 	"dario.cat/mergo"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/variant"
+	"github.com/edgelesssys/constellation/v2/internal/config"
+	"github.com/edgelesssys/constellation/v2/internal/crypto"
 	"github.com/edgelesssys/constellation/v2/internal/encoding"
 	"github.com/edgelesssys/constellation/v2/internal/file"
 	"github.com/edgelesssys/constellation/v2/internal/validation"
+	"github.com/siderolabs/talos/pkg/machinery/config/encoder"
+	"gopkg.in/yaml.v3"
 )
 
 const (
 	// Version1 is the first version of the state file.
 	Version1 = "v1"
+	// Version2 adds phaseFingerprints, resolvedImage, nodeGroupVersions, and clusterValues.helmNamespace.
+	// CLIs that only know Version1 would silently drop these fields when writing the state file.
+	Version2 = "v2"
 )
 
 const (
@@ -48,15 +59,62 @@ type ConstraintSet int
 // ReadFromFile reads the state file at the given path and validates it.
 // If the state file is valid, the state is returned. Otherwise, an error
 // describing why the validation failed is returned.
+// States of older schema versions are migrated to the current version, and a
+// [NewerVersionError] is returned for states written by a newer CLI.
+// The API server certificate SANs of the returned state are sorted and free of duplicates.
 func ReadFromFile(fileHandler file.Handler, path string) (*State, error) {
+	// Check the version first, since newer states may not be readable into this State.
+	var header struct {
+		Version string `yaml:"version"`
+	}
+	if err := fileHandler.ReadYAML(path, &header); err != nil {
+		return nil, fmt.Errorf("reading state file: %w", err)
+	}
+	if err := checkVersion(header.Version); err != nil {
+		return nil, err
+	}
+
 	state := &State{}
 	if err := fileHandler.ReadYAML(path, &state); err != nil {
 		return nil, fmt.Errorf("reading state file: %w", err)
 	}
+	if err := state.migrate(); err != nil {
+		return nil, fmt.Errorf("migrating state file: %w", err)
+	}
+	if err := state.normalizeAPIServerCertSANs(); err != nil {
+		return nil, fmt.Errorf("reading state file: %w", err)
+	}
 
 	return state, nil
 }
 
+// normalizeAPIServerCertSANs sorts the API server certificate SANs and removes duplicates.
+// An error is returned if a SAN is neither an IP address nor a DNS name.
+func (s *State) normalizeAPIServerCertSANs() error {
+	sans := s.Infrastructure.APIServerCertSANs
+	if len(sans) == 0 {
+		return nil
+	}
+	for _, san := range sans {
+		if err := validation.Or(validation.IPAddress(san), validation.DNSName(san)).Satisfied(); err != nil {
+			return fmt.Errorf("invalid API server cert SAN %q: must be an IP address or a DNS name", san)
+		}
+	}
+	sans = slices.Clone(sans)
+	slices.Sort(sans)
+	s.Infrastructure.APIServerCertSANs = slices.Compact(sans)
+	return nil
+}
+
+// ValidateEndpoint checks that the endpoint is a valid DNS name or IP address,
+// as required for the cluster endpoints of the infrastructure state.
+func ValidateEndpoint(endpoint string) error {
+	if err := validation.Or(validation.DNSName(endpoint), validation.IPAddress(endpoint)).Satisfied(); err != nil {
+		return fmt.Errorf("invalid endpoint %q: must be an IP address or a DNS name", endpoint)
+	}
+	return nil
+}
+
 // CreateOrRead reads the state file at the given path, if it exists, and returns the state.
 // If the file does not exist, a new state is created and written to disk.
 func CreateOrRead(fileHandler file.Handler, path string) (*State, error) {
@@ -86,6 +144,22 @@ type State struct {
 	//   DO NOT EDIT. State of the Constellation Kubernetes cluster.
 	//   These values are set during cluster initialization and should not be changed.
 	ClusterValues ClusterValues `yaml:"clusterValues"`
+	// description: |
+	//   DO NOT EDIT. Attestation config the cluster was last applied with.
+	//   Used by "constellation verify" if no config file is available.
+	Attestation *config.AttestationConfig `yaml:"attestation,omitempty"`
+	// description: |
+	//   DO NOT EDIT. Fingerprints of the inputs each apply phase last succeeded with, keyed by phase name.
+	//   Used by "constellation apply --reconcile" to skip phases whose inputs haven't changed.
+	PhaseFingerprints map[string]string `yaml:"phaseFingerprints,omitempty"`
+	// description: |
+	//   DO NOT EDIT. Image version the release channel of the config resolved to during the last successful image upgrade.
+	//   Set the image field of the config to this version to reproduce the cluster without tracking the channel.
+	ResolvedImage string `yaml:"resolvedImage,omitempty"`
+	// description: |
+	//   DO NOT EDIT. Image versions of the node groups upgraded with "constellation apply --target-groups", keyed by node group name.
+	//   Node groups that aren't listed run the image of the cluster.
+	NodeGroupVersions map[string]string `yaml:"nodeGroupVersions,omitempty"`
 }
 
 // ClusterValues describe the (Kubernetes) cluster state, set during initialization of the cluster.
@@ -99,6 +173,12 @@ type ClusterValues struct {
 	// description: |
 	//   Salt used to generate the ClusterID on the bootstrapping node.
 	MeasurementSalt encoding.HexBytes `yaml:"measurementSalt"`
+	// description: |
+	//   OIDC issuer the API server was configured with during initialization. Empty if no OIDC issuer is configured.
+	OIDC *config.OIDCConfig `yaml:"oidc,omitempty"`
+	// description: |
+	//   Namespace the Helm charts of the cluster's system components are installed to. Empty if the charts are installed to kube-system.
+	HelmNamespace string `yaml:"helmNamespace,omitempty"`
 }
 
 // Infrastructure describe the state related to the cloud resources of the cluster.
@@ -145,6 +225,15 @@ type GCP struct {
 	// description: |
 	//   CIDR range of the cluster's pods.
 	IPCidrPod string `yaml:"ipCidrPod"`
+	// description: |
+	//   ID of the network the cluster's nodes are attached to.
+	NetworkID string `yaml:"networkID,omitempty"`
+	// description: |
+	//   ID of the subnetwork the cluster's nodes are attached to.
+	SubnetworkID string `yaml:"subnetworkID,omitempty"`
+	// description: |
+	//   Cloud KMS key the disks of the cluster's nodes are encrypted with. Empty if Google-managed keys are used.
+	DiskEncryptionKey string `yaml:"diskEncryptionKey,omitempty"`
 }
 
 // Azure describes the infra state related to Azure.
@@ -169,6 +258,18 @@ type Azure struct {
 	//   in the cluster's attestation report if the enforcement policy is set accordingly.
 	//   Can be left empty otherwise.
 	AttestationURL string `yaml:"attestationURL"`
+	// description: |
+	//   ID of the virtual network the cluster's nodes are attached to.
+	VirtualNetworkID string `yaml:"virtualNetworkID,omitempty"`
+	// description: |
+	//   ID of the subnet the cluster's nodes are attached to.
+	SubnetID string `yaml:"subnetID,omitempty"`
+	// description: |
+	//   URI of the Azure Managed HSM the master secret is encrypted with. Empty if the master secret is stored unencrypted.
+	MasterKeyHSMURI string `yaml:"masterKeyHSMURI,omitempty"`
+	// description: |
+	//   Name of the Managed HSM key the master secret is encrypted with.
+	MasterKeyName string `yaml:"masterKeyName,omitempty"`
 }
 
 // OpenStack describes the infra state related to OpenStack.
@@ -184,7 +285,7 @@ type OpenStack struct {
 // New creates a new cluster state (file).
 func New() *State {
 	return &State{
-		Version: Version1,
+		Version: Version2,
 	}
 }
 
@@ -201,13 +302,79 @@ func (s *State) SetClusterValues(clusterValues ClusterValues) *State {
 }
 
 // WriteToFile writes the state to the given path, overwriting any existing file.
+// The state is written in its canonical form, see [State.MarshalCanonical].
+// The file is replaced atomically, so an interrupted write leaves the previous state intact.
+// Writing an unchanged state is a no-op. Concurrent writers are excluded by [Locker].
 func (s *State) WriteToFile(fileHandler file.Handler, path string) error {
-	if err := fileHandler.WriteYAML(path, s, file.OptMkdirAll, file.OptOverwrite); err != nil {
+	data, err := s.MarshalCanonical()
+	if err != nil {
+		return fmt.Errorf("marshalling state file: %w", err)
+	}
+	if current, err := fileHandler.Read(path); err == nil && bytes.Equal(current, data) {
+		return nil
+	}
+	if err := fileHandler.Write(path, data, file.OptMkdirAll, file.OptOverwrite, file.OptAtomic); err != nil {
 		return fmt.Errorf("writing state file: %w", err)
 	}
 	return nil
 }
 
+// MarshalCanonical returns the canonical YAML encoding of the state.
+// Mapping keys are sorted and byte slices are hex encoded in lower case,
+// so reading and re-writing an unchanged state produces byte-identical output.
+func (s *State) MarshalCanonical() (data []byte, err error) {
+	defer func() {
+		// the talos encoder panics on some invalid input
+		if r := recover(); r != nil {
+			err = fmt.Errorf("recovered from panic: %v", r)
+		}
+	}()
+
+	// The encoder writes nil byte slices as empty YAML lists, which are read back
+	// as empty, non-nil slices. Always encode them as empty hex strings instead.
+	canonical := *s
+	if canonical.Infrastructure.InitSecret == nil {
+		canonical.Infrastructure.InitSecret = encoding.HexBytes{}
+	}
+	if canonical.ClusterValues.MeasurementSalt == nil {
+		canonical.ClusterValues.MeasurementSalt = encoding.HexBytes{}
+	}
+
+	encoded, err := encoder.NewEncoder(&canonical).Encode()
+	if err != nil {
+		return nil, err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(encoded, &node); err != nil {
+		return nil, fmt.Errorf("decoding encoded state: %w", err)
+	}
+	sortMappingKeys(&node)
+	return yaml.Marshal(&node)
+}
+
+// sortMappingKeys recursively sorts the keys of all mappings in node.
+// Comments are attached to the key nodes, so they are moved along with their keys.
+func sortMappingKeys(node *yaml.Node) {
+	for _, child := range node.Content {
+		sortMappingKeys(child)
+	}
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	pairs := make([][2]*yaml.Node, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		pairs = append(pairs, [2]*yaml.Node{node.Content[i], node.Content[i+1]})
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i][0].Value < pairs[j][0].Value
+	})
+	node.Content = node.Content[:0]
+	for _, pair := range pairs {
+		node.Content = append(node.Content, pair[0], pair[1])
+	}
+}
+
 // Merge merges the state information from other into the current state.
 // If a field is set in both states, the value of the other state is used.
 func (s *State) Merge(other *State) (*State, error) {
@@ -217,6 +384,48 @@ func (s *State) Merge(other *State) (*State, error) {
 	return s, nil
 }
 
+// Redacted returns a copy of the state with secret values removed.
+// The init secret and the measurement salt are cleared, so the copy can be shared for debugging.
+func (s *State) Redacted() *State {
+	redacted := s.Clone()
+	redacted.Infrastructure.InitSecret = nil
+	redacted.ClusterValues.MeasurementSalt = nil
+	return redacted
+}
+
+// Clone returns a deep copy of the state.
+// The copy shares no byte slices, slices, maps, or provider specific values with the original,
+// so it can be modified freely, e.g. to compute the result of a dry-run or a diff.
+func (s *State) Clone() *State {
+	clone := *s
+
+	clone.Infrastructure.InitSecret = bytes.Clone(s.Infrastructure.InitSecret)
+	clone.Infrastructure.APIServerCertSANs = slices.Clone(s.Infrastructure.APIServerCertSANs)
+	if s.Infrastructure.Azure != nil {
+		azure := *s.Infrastructure.Azure
+		clone.Infrastructure.Azure = &azure
+	}
+	if s.Infrastructure.GCP != nil {
+		gcp := *s.Infrastructure.GCP
+		clone.Infrastructure.GCP = &gcp
+	}
+	if s.Infrastructure.OpenStack != nil {
+		openStack := *s.Infrastructure.OpenStack
+		clone.Infrastructure.OpenStack = &openStack
+	}
+
+	clone.ClusterValues.MeasurementSalt = bytes.Clone(s.ClusterValues.MeasurementSalt)
+	if s.ClusterValues.OIDC != nil {
+		oidc := *s.ClusterValues.OIDC
+		clone.ClusterValues.OIDC = &oidc
+	}
+
+	clone.Attestation = s.Attestation.Clone()
+	clone.PhaseFingerprints = maps.Clone(s.PhaseFingerprints)
+	clone.NodeGroupVersions = maps.Clone(s.NodeGroupVersions)
+	return &clone
+}
+
 /*
 Validate validates the state against the given constraint set and CSP, which can be one of
   - PreCreate, which is the constraint set that should be enforced before "constellation create" is run.
@@ -252,7 +461,7 @@ func (s *State) Validate(constraintSet ConstraintSet, variant variant.Variant) e
 func (s *State) preCreateConstraints() []*validation.Constraint {
 	return []*validation.Constraint{
 		// state version needs to be accepted by the parsing CLI.
-		validation.OneOf(s.Version, []string{Version1}).
+		validation.OneOf(s.Version, []string{Version2}).
 			WithFieldTrace(s, &s.Version),
 		// Infrastructure must be empty.
 		// As the infrastructure struct contains slices, we cannot use the
@@ -296,7 +505,7 @@ func (s *State) preInitConstraints(attestation variant.Variant) func() []*valida
 	return func() []*validation.Constraint {
 		constraints := []*validation.Constraint{
 			// state version needs to be accepted by the parsing CLI.
-			validation.OneOf(s.Version, []string{Version1}).
+			validation.OneOf(s.Version, []string{Version2}).
 				WithFieldTrace(s, &s.Version),
 			// infrastructure must be valid.
 			// out-of-cluster endpoint needs to be a valid DNS name or IP address.
@@ -428,7 +637,7 @@ func (s *State) postInitConstraints(attestation variant.Variant) func() []*valid
 	return func() []*validation.Constraint {
 		constraints := []*validation.Constraint{
 			// state version needs to be accepted by the parsing CLI.
-			validation.OneOf(s.Version, []string{Version1}).
+			validation.OneOf(s.Version, []string{Version2}).
 				WithFieldTrace(s, &s.Version),
 			// infrastructure must be valid.
 			// out-of-cluster endpoint needs to be a valid DNS name or IP address.
@@ -465,6 +674,10 @@ func (s *State) postInitConstraints(attestation variant.Variant) func() []*valid
 			// MeasurementSalt needs to be filled.
 			validation.NotEmptySlice(s.ClusterValues.MeasurementSalt).
 				WithFieldTrace(s, &s.ClusterValues.MeasurementSalt),
+			// MeasurementSalt is generated with the default length during init,
+			// so any other length means the state file was modified.
+			validation.SliceLength(s.ClusterValues.MeasurementSalt, crypto.RNGLengthDefault).
+				WithFieldTrace(s, &s.ClusterValues.MeasurementSalt),
 		}
 
 		switch attestation {
@@ -23,7 +23,7 @@ func init() {
 	StateDoc.Type = "State"
 	StateDoc.Comments[encoder.LineComment] = "State describe the entire state to describe a Constellation cluster."
 	StateDoc.Description = "State describe the entire state to describe a Constellation cluster."
-	StateDoc.Fields = make([]encoder.Doc, 3)
+	StateDoc.Fields = make([]encoder.Doc, 7)
 	StateDoc.Fields[0].Name = "version"
 	StateDoc.Fields[0].Type = "string"
 	StateDoc.Fields[0].Note = ""
@@ -39,6 +39,26 @@ func init() {
 	StateDoc.Fields[2].Note = ""
 	StateDoc.Fields[2].Description = "DO NOT EDIT. State of the Constellation Kubernetes cluster.\nThese values are set during cluster initialization and should not be changed."
 	StateDoc.Fields[2].Comments[encoder.LineComment] = "DO NOT EDIT. State of the Constellation Kubernetes cluster."
+	StateDoc.Fields[3].Name = "attestation"
+	StateDoc.Fields[3].Type = "AttestationConfig"
+	StateDoc.Fields[3].Note = ""
+	StateDoc.Fields[3].Description = "DO NOT EDIT. Attestation config the cluster was last applied with.\nUsed by \"constellation verify\" if no config file is available."
+	StateDoc.Fields[3].Comments[encoder.LineComment] = "DO NOT EDIT. Attestation config the cluster was last applied with."
+	StateDoc.Fields[4].Name = "phaseFingerprints"
+	StateDoc.Fields[4].Type = "map[string]string"
+	StateDoc.Fields[4].Note = ""
+	StateDoc.Fields[4].Description = "DO NOT EDIT. Fingerprints of the inputs each apply phase last succeeded with, keyed by phase name.\nUsed by \"constellation apply --reconcile\" to skip phases whose inputs haven't changed."
+	StateDoc.Fields[4].Comments[encoder.LineComment] = "DO NOT EDIT. Fingerprints of the inputs each apply phase last succeeded with, keyed by phase name."
+	StateDoc.Fields[5].Name = "resolvedImage"
+	StateDoc.Fields[5].Type = "string"
+	StateDoc.Fields[5].Note = ""
+	StateDoc.Fields[5].Description = "DO NOT EDIT. Image version the release channel of the config resolved to during the last successful image upgrade.\nSet the image field of the config to this version to reproduce the cluster without tracking the channel."
+	StateDoc.Fields[5].Comments[encoder.LineComment] = "DO NOT EDIT. Image version the release channel of the config resolved to during the last successful image upgrade."
+	StateDoc.Fields[6].Name = "nodeGroupVersions"
+	StateDoc.Fields[6].Type = "map[string]string"
+	StateDoc.Fields[6].Note = ""
+	StateDoc.Fields[6].Description = "DO NOT EDIT. Image versions of the node groups upgraded with \"constellation apply --target-groups\", keyed by node group name.\nNode groups that aren't listed run the image of the cluster."
+	StateDoc.Fields[6].Comments[encoder.LineComment] = "DO NOT EDIT. Image versions of the node groups upgraded with \"constellation apply --target-groups\", keyed by node group name."
 
 	ClusterValuesDoc.Type = "ClusterValues"
 	ClusterValuesDoc.Comments[encoder.LineComment] = "ClusterValues describe the (Kubernetes) cluster state, set during initialization of the cluster."
@@ -49,7 +69,7 @@ func init() {
 			FieldName: "clusterValues",
 		},
 	}
-	ClusterValuesDoc.Fields = make([]encoder.Doc, 3)
+	ClusterValuesDoc.Fields = make([]encoder.Doc, 5)
 	ClusterValuesDoc.Fields[0].Name = "clusterID"
 	ClusterValuesDoc.Fields[0].Type = "string"
 	ClusterValuesDoc.Fields[0].Note = ""
@@ -65,6 +85,16 @@ func init() {
 	ClusterValuesDoc.Fields[2].Note = ""
 	ClusterValuesDoc.Fields[2].Description = "Salt used to generate the ClusterID on the bootstrapping node."
 	ClusterValuesDoc.Fields[2].Comments[encoder.LineComment] = "Salt used to generate the ClusterID on the bootstrapping node."
+	ClusterValuesDoc.Fields[3].Name = "oidc"
+	ClusterValuesDoc.Fields[3].Type = "OIDCConfig"
+	ClusterValuesDoc.Fields[3].Note = ""
+	ClusterValuesDoc.Fields[3].Description = "OIDC issuer the API server was configured with during initialization. Empty if no OIDC issuer is configured."
+	ClusterValuesDoc.Fields[3].Comments[encoder.LineComment] = "OIDC issuer the API server was configured with during initialization. Empty if no OIDC issuer is configured."
+	ClusterValuesDoc.Fields[4].Name = "helmNamespace"
+	ClusterValuesDoc.Fields[4].Type = "string"
+	ClusterValuesDoc.Fields[4].Note = ""
+	ClusterValuesDoc.Fields[4].Description = "Namespace the Helm charts of the cluster's system components are installed to. Empty if the charts are installed to kube-system."
+	ClusterValuesDoc.Fields[4].Comments[encoder.LineComment] = "Namespace the Helm charts of the cluster's system components are installed to. Empty if the charts are installed to kube-system."
 
 	InfrastructureDoc.Type = "Infrastructure"
 	InfrastructureDoc.Comments[encoder.LineComment] = "Infrastructure describe the state related to the cloud resources of the cluster."
@@ -136,7 +166,7 @@ func init() {
 			FieldName: "gcp",
 		},
 	}
-	GCPDoc.Fields = make([]encoder.Doc, 2)
+	GCPDoc.Fields = make([]encoder.Doc, 5)
 	GCPDoc.Fields[0].Name = "projectID"
 	GCPDoc.Fields[0].Type = "string"
 	GCPDoc.Fields[0].Note = ""
@@ -147,6 +177,21 @@ func init() {
 	GCPDoc.Fields[1].Note = ""
 	GCPDoc.Fields[1].Description = "CIDR range of the cluster's pods."
 	GCPDoc.Fields[1].Comments[encoder.LineComment] = "CIDR range of the cluster's pods."
+	GCPDoc.Fields[2].Name = "networkID"
+	GCPDoc.Fields[2].Type = "string"
+	GCPDoc.Fields[2].Note = ""
+	GCPDoc.Fields[2].Description = "ID of the network the cluster's nodes are attached to."
+	GCPDoc.Fields[2].Comments[encoder.LineComment] = "ID of the network the cluster's nodes are attached to."
+	GCPDoc.Fields[3].Name = "subnetworkID"
+	GCPDoc.Fields[3].Type = "string"
+	GCPDoc.Fields[3].Note = ""
+	GCPDoc.Fields[3].Description = "ID of the subnetwork the cluster's nodes are attached to."
+	GCPDoc.Fields[3].Comments[encoder.LineComment] = "ID of the subnetwork the cluster's nodes are attached to."
+	GCPDoc.Fields[4].Name = "diskEncryptionKey"
+	GCPDoc.Fields[4].Type = "string"
+	GCPDoc.Fields[4].Note = ""
+	GCPDoc.Fields[4].Description = "Cloud KMS key the disks of the cluster's nodes are encrypted with. Empty if Google-managed keys are used."
+	GCPDoc.Fields[4].Comments[encoder.LineComment] = "Cloud KMS key the disks of the cluster's nodes are encrypted with. Empty if Google-managed keys are used."
 
 	AzureDoc.Type = "Azure"
 	AzureDoc.Comments[encoder.LineComment] = "Azure describes the infra state related to Azure."
@@ -157,7 +202,7 @@ func init() {
 			FieldName: "azure",
 		},
 	}
-	AzureDoc.Fields = make([]encoder.Doc, 6)
+	AzureDoc.Fields = make([]encoder.Doc, 10)
 	AzureDoc.Fields[0].Name = "resourceGroup"
 	AzureDoc.Fields[0].Type = "string"
 	AzureDoc.Fields[0].Note = ""
@@ -188,6 +233,26 @@ func init() {
 	AzureDoc.Fields[5].Note = ""
 	AzureDoc.Fields[5].Description = "MAA endpoint that can be used as a fallback for veryifying the ID key digests\nin the cluster's attestation report if the enforcement policy is set accordingly.\nCan be left empty otherwise."
 	AzureDoc.Fields[5].Comments[encoder.LineComment] = "MAA endpoint that can be used as a fallback for veryifying the ID key digests"
+	AzureDoc.Fields[6].Name = "virtualNetworkID"
+	AzureDoc.Fields[6].Type = "string"
+	AzureDoc.Fields[6].Note = ""
+	AzureDoc.Fields[6].Description = "ID of the virtual network the cluster's nodes are attached to."
+	AzureDoc.Fields[6].Comments[encoder.LineComment] = "ID of the virtual network the cluster's nodes are attached to."
+	AzureDoc.Fields[7].Name = "subnetID"
+	AzureDoc.Fields[7].Type = "string"
+	AzureDoc.Fields[7].Note = ""
+	AzureDoc.Fields[7].Description = "ID of the subnet the cluster's nodes are attached to."
+	AzureDoc.Fields[7].Comments[encoder.LineComment] = "ID of the subnet the cluster's nodes are attached to."
+	AzureDoc.Fields[8].Name = "masterKeyHSMURI"
+	AzureDoc.Fields[8].Type = "string"
+	AzureDoc.Fields[8].Note = ""
+	AzureDoc.Fields[8].Description = "URI of the Azure Managed HSM the master secret is encrypted with. Empty if the master secret is stored unencrypted."
+	AzureDoc.Fields[8].Comments[encoder.LineComment] = "URI of the Azure Managed HSM the master secret is encrypted with. Empty if the master secret is stored unencrypted."
+	AzureDoc.Fields[9].Name = "masterKeyName"
+	AzureDoc.Fields[9].Type = "string"
+	AzureDoc.Fields[9].Note = ""
+	AzureDoc.Fields[9].Description = "Name of the Managed HSM key the master secret is encrypted with."
+	AzureDoc.Fields[9].Comments[encoder.LineComment] = "Name of the Managed HSM key the master secret is encrypted with."
 
 	OpenStackDoc.Type = "OpenStack"
 	OpenStackDoc.Comments[encoder.LineComment] = "OpenStack describes the infra state related to OpenStack."
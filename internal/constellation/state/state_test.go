@@ -7,20 +7,25 @@ SPDX-License-Identifier: AGPL-3.0-only
 package state
 
 import (
+	"bytes"
+	"errors"
 	"testing"
 
+	"github.com/edgelesssys/constellation/v2/internal/attestation/measurements"
+	"github.com/edgelesssys/constellation/v2/internal/config"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/file"
 	"github.com/siderolabs/talos/pkg/machinery/config/encoder"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 )
 
 // defaultState returns a valid default state for testing.
 func defaultState() *State {
 	return &State{
-		Version: "v1",
+		Version: "v2",
 		Infrastructure: Infrastructure{
 			UID:               "123",
 			Name:              "test-cluster",
@@ -48,7 +53,7 @@ func defaultState() *State {
 		ClusterValues: ClusterValues{
 			ClusterID:       "test-cluster-id",
 			OwnerID:         "test-owner-id",
-			MeasurementSalt: []byte{0x41},
+			MeasurementSalt: bytes.Repeat([]byte{0x41}, 32),
 		},
 	}
 }
@@ -105,12 +110,65 @@ func TestWriteToFile(t *testing.T) {
 				assert.Error(err)
 			} else {
 				assert.NoError(err)
-				assert.YAMLEq(mustMarshalYaml(require, tc.state), mustReadFromFile(require, tc.fh))
+				assert.Equal(mustMarshalCanonical(require, tc.state), mustReadFromFile(require, tc.fh))
 			}
 		})
 	}
 }
 
+func TestWriteToFileInterrupted(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	memFs := afero.NewMemMapFs()
+	fh := file.NewHandler(memFs)
+	original := defaultState()
+	require.NoError(original.WriteToFile(fh, constants.StateFilename))
+
+	updated := defaultState()
+	updated.Infrastructure.ClusterEndpoint = "192.0.2.1"
+	data, err := updated.MarshalCanonical()
+	require.NoError(err)
+
+	// a write that was interrupted before the rename leaves a partial temporary file behind
+	require.NoError(fh.Write("."+constants.StateFilename+".1234.tmp", data[:len(data)/2]))
+	got, err := ReadFromFile(fh, constants.StateFilename)
+	require.NoError(err)
+	assert.Equal(original, got)
+
+	// a failing rename keeps the original state and removes its temporary file
+	failingFh := file.NewHandler(renameErrFs{memFs})
+	assert.Error(updated.WriteToFile(failingFh, constants.StateFilename))
+	assert.Equal(mustMarshalCanonical(require, original), mustReadFromFile(require, fh))
+	tmpFiles, err := afero.Glob(memFs, ".*.tmp")
+	require.NoError(err)
+	assert.Len(tmpFiles, 1)
+
+	// the next write succeeds
+	require.NoError(updated.WriteToFile(fh, constants.StateFilename))
+	got, err = ReadFromFile(fh, constants.StateFilename)
+	require.NoError(err)
+	assert.Equal(updated, got)
+}
+
+func TestWriteToFileUnchanged(t *testing.T) {
+	require := require.New(t)
+
+	// writing an unchanged state doesn't touch the file, so it succeeds on a read-only file system
+	memFs := afero.NewMemMapFs()
+	require.NoError(defaultState().WriteToFile(file.NewHandler(memFs), constants.StateFilename))
+	require.NoError(defaultState().WriteToFile(file.NewHandler(afero.NewReadOnlyFs(memFs)), constants.StateFilename))
+}
+
+// renameErrFs is a file system whose renames always fail.
+type renameErrFs struct {
+	afero.Fs
+}
+
+func (renameErrFs) Rename(string, string) error {
+	return errors.New("rename failed")
+}
+
 func TestReadFromFile(t *testing.T) {
 	testCases := map[string]struct {
 		fs        file.Handler
@@ -129,6 +187,26 @@ func TestReadFromFile(t *testing.T) {
 			fs:      file.NewHandler(afero.NewMemMapFs()),
 			wantErr: true,
 		},
+		"duplicate SANs are removed": {
+			fs: stateFileWithSANs(t, "www.example.com", "127.0.0.1", "www.example.com", "127.0.0.1"),
+			wantState: func() *State {
+				s := defaultState()
+				s.Infrastructure.APIServerCertSANs = []string{"127.0.0.1", "www.example.com"}
+				return s
+			}(),
+		},
+		"mixed IP and DNS SANs are sorted": {
+			fs: stateFileWithSANs(t, "www.example.com", "2001:db8::1", "192.0.2.1", "api.example.com"),
+			wantState: func() *State {
+				s := defaultState()
+				s.Infrastructure.APIServerCertSANs = []string{"192.0.2.1", "2001:db8::1", "api.example.com", "www.example.com"}
+				return s
+			}(),
+		},
+		"malformed SAN": {
+			fs:      stateFileWithSANs(t, "127.0.0.1", "not a hostname"),
+			wantErr: true,
+		},
 	}
 
 	for name, tc := range testCases {
@@ -148,18 +226,265 @@ func TestReadFromFile(t *testing.T) {
 	}
 }
 
+// stateFileWithSANs returns a file handler with the default state using the given API server cert SANs.
+func stateFileWithSANs(t *testing.T, sans ...string) file.Handler {
+	t.Helper()
+	s := defaultState()
+	s.Infrastructure.APIServerCertSANs = sans
+	fh := file.NewHandler(afero.NewMemMapFs())
+	require.NoError(t, fh.WriteYAML(constants.StateFilename, s))
+	return fh
+}
+
+func TestValidateEndpoint(t *testing.T) {
+	testCases := map[string]struct {
+		endpoint string
+		wantErr  bool
+	}{
+		"IPv4 address": {endpoint: "192.0.2.1"},
+		"IPv6 address": {endpoint: "2001:db8::1"},
+		"DNS name":     {endpoint: "cluster.example.com"},
+		"empty":        {endpoint: "", wantErr: true},
+		"with port":    {endpoint: "192.0.2.1:6443", wantErr: true},
+		"URL":          {endpoint: "https://cluster.example.com", wantErr: true},
+		"invalid name": {endpoint: "cluster_1.example.com", wantErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateEndpoint(tc.endpoint)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestMarshalCanonical(t *testing.T) {
+	testCases := map[string]struct {
+		state *State
+	}{
+		"default state": {
+			state: defaultState(),
+		},
+		"azure state": {
+			state: defaultAzureState(),
+		},
+		"gcp state": {
+			state: defaultGCPState(),
+		},
+		"new state": {
+			state: New(),
+		},
+		"empty state": {
+			state: &State{},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			fh := file.NewHandler(afero.NewMemMapFs())
+			require.NoError(tc.state.WriteToFile(fh, constants.StateFilename))
+			saved := mustReadFromFile(require, fh)
+
+			loaded, err := ReadFromFile(fh, constants.StateFilename)
+			require.NoError(err)
+			require.NoError(loaded.WriteToFile(fh, constants.StateFilename))
+			assert.Equal(saved, mustReadFromFile(require, fh))
+
+			canonical, err := tc.state.MarshalCanonical()
+			require.NoError(err)
+			assert.Equal(saved, string(canonical))
+		})
+	}
+}
+
+func TestMarshalCanonicalNormalizes(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// keys out of order and upper case hex encoding
+	stateFile := `
+version: v1
+infrastructure:
+  uid: "123"
+  name: test-cluster
+  initSecret: "ABCD"
+  clusterEndpoint: 0.0.0.0
+clusterValues:
+  ownerID: test-owner-id
+  measurementSalt: "EF01"
+  clusterID: test-cluster-id
+`
+	fh := file.NewHandler(afero.NewMemMapFs())
+	require.NoError(fh.Write(constants.StateFilename, []byte(stateFile)))
+	loaded, err := ReadFromFile(fh, constants.StateFilename)
+	require.NoError(err)
+
+	got, err := loaded.MarshalCanonical()
+	require.NoError(err)
+
+	want, err := New().SetInfrastructure(Infrastructure{
+		UID:             "123",
+		Name:            "test-cluster",
+		InitSecret:      []byte{0xab, 0xcd},
+		ClusterEndpoint: "0.0.0.0",
+	}).SetClusterValues(ClusterValues{
+		ClusterID:       "test-cluster-id",
+		OwnerID:         "test-owner-id",
+		MeasurementSalt: []byte{0xef, 0x01},
+	}).MarshalCanonical()
+	require.NoError(err)
+	assert.Equal(string(want), string(got))
+	assert.Contains(string(got), `initSecret: abcd`)
+}
+
+func FuzzInfrastructureRoundTrip(f *testing.F) {
+	f.Add("123", "0.0.0.0", "test-cluster", []byte{0x41}, "127.0.0.1")
+	f.Add("", "", "", []byte{}, "")
+	f.Add("uid: x", "# comment", "\n", []byte{0x00, 0xff}, "[]")
+
+	f.Fuzz(func(t *testing.T, uid, endpoint, name string, initSecret []byte, san string) {
+		state := New().SetInfrastructure(Infrastructure{
+			UID:               uid,
+			ClusterEndpoint:   endpoint,
+			Name:              name,
+			InitSecret:        initSecret,
+			APIServerCertSANs: []string{san},
+		})
+		first, err := state.MarshalCanonical()
+		if err != nil {
+			t.Skip("state not encodable")
+		}
+
+		var loaded State
+		require.NoError(t, yaml.Unmarshal(first, &loaded))
+		second, err := loaded.MarshalCanonical()
+		require.NoError(t, err)
+		assert.Equal(t, string(first), string(second))
+	})
+}
+
 func mustMarshalYaml(require *require.Assertions, v any) string {
 	b, err := encoder.NewEncoder(v).Encode()
 	require.NoError(err)
 	return string(b)
 }
 
+func mustMarshalCanonical(require *require.Assertions, s *State) string {
+	b, err := s.MarshalCanonical()
+	require.NoError(err)
+	return string(b)
+}
+
 func mustReadFromFile(require *require.Assertions, fh file.Handler) string {
 	b, err := fh.Read(constants.StateFilename)
 	require.NoError(err)
 	return string(b)
 }
 
+func TestRedacted(t *testing.T) {
+	assert := assert.New(t)
+
+	s := defaultState()
+	redacted := s.Redacted()
+
+	assert.Nil(redacted.Infrastructure.InitSecret)
+	assert.Nil(redacted.ClusterValues.MeasurementSalt)
+	assert.Equal(s.Infrastructure.ClusterEndpoint, redacted.Infrastructure.ClusterEndpoint)
+	assert.Equal(s.ClusterValues.ClusterID, redacted.ClusterValues.ClusterID)
+
+	// the original state is not modified
+	assert.Equal(defaultState(), s)
+}
+
+func TestClone(t *testing.T) {
+	newState := func() *State {
+		s := defaultState()
+		s.Infrastructure.OpenStack = &OpenStack{NetworkID: "test-network", SubnetID: "test-subnet"}
+		s.ClusterValues.OIDC = &config.OIDCConfig{IssuerURL: "https://issuer.example.com", ClientID: "test-client"}
+		s.Attestation = &config.AttestationConfig{
+			AzureSEVSNP: &config.AzureSEVSNP{
+				Measurements: measurements.M{
+					4: measurements.WithAllBytes(0x44, measurements.Enforce, measurements.PCRMeasurementLength),
+				},
+			},
+		}
+		s.PhaseFingerprints = map[string]string{"init": "abc"}
+		s.NodeGroupVersions = map[string]string{"worker-canary": "v2.17.0"}
+		return s
+	}
+
+	testCases := map[string]struct {
+		mutate func(*State)
+	}{
+		"init secret": {
+			mutate: func(s *State) { s.Infrastructure.InitSecret[0] = 0xff },
+		},
+		"measurement salt": {
+			mutate: func(s *State) { s.ClusterValues.MeasurementSalt[0] = 0xff },
+		},
+		"API server cert SANs": {
+			mutate: func(s *State) { s.Infrastructure.APIServerCertSANs[0] = "192.0.2.1" },
+		},
+		"Azure": {
+			mutate: func(s *State) { s.Infrastructure.Azure.ResourceGroup = "other-rg" },
+		},
+		"GCP": {
+			mutate: func(s *State) { s.Infrastructure.GCP.ProjectID = "other-project" },
+		},
+		"OpenStack": {
+			mutate: func(s *State) { s.Infrastructure.OpenStack.NetworkID = "other-network" },
+		},
+		"OIDC": {
+			mutate: func(s *State) { s.ClusterValues.OIDC.ClientID = "other-client" },
+		},
+		"attestation measurements": {
+			mutate: func(s *State) { s.Attestation.AzureSEVSNP.Measurements[4].Expected[0] = 0xff },
+		},
+		"attestation variant": {
+			mutate: func(s *State) { s.Attestation.AzureSEVSNP = nil },
+		},
+		"phase fingerprints": {
+			mutate: func(s *State) { s.PhaseFingerprints["init"] = "def" },
+		},
+		"node group versions": {
+			mutate: func(s *State) { s.NodeGroupVersions["worker-canary"] = "v2.18.0" },
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			s := newState()
+			clone := s.Clone()
+			assert.Equal(s, clone)
+
+			tc.mutate(clone)
+			assert.NotEqual(s, clone)
+			assert.Equal(newState(), s)
+		})
+	}
+}
+
+func TestCloneEmpty(t *testing.T) {
+	assert := assert.New(t)
+
+	s := New()
+	clone := s.Clone()
+	assert.Equal(s, clone)
+	assert.Nil(clone.Infrastructure.InitSecret)
+	assert.Nil(clone.Infrastructure.APIServerCertSANs)
+	assert.Nil(clone.Attestation)
+	assert.Nil(clone.PhaseFingerprints)
+}
+
 func TestMerge(t *testing.T) {
 	testCases := map[string]struct {
 		state    *State
@@ -175,7 +500,7 @@ func TestMerge(t *testing.T) {
 				},
 			},
 			other: &State{
-				Version: "v1",
+				Version: "v2",
 				Infrastructure: Infrastructure{
 					UID: "456",
 				},
@@ -184,7 +509,7 @@ func TestMerge(t *testing.T) {
 				},
 			},
 			expected: &State{
-				Version: "v1",
+				Version: "v2",
 				Infrastructure: Infrastructure{
 					ClusterEndpoint: "test-cluster-endpoint",
 					UID:             "456",
@@ -194,10 +519,37 @@ func TestMerge(t *testing.T) {
 				},
 			},
 		},
+		"master key HSM is kept on infrastructure update": {
+			state: &State{
+				Infrastructure: Infrastructure{
+					Azure: &Azure{
+						ResourceGroup:   "old-rg",
+						MasterKeyHSMURI: "https://test-hsm.managedhsm.azure.net/",
+						MasterKeyName:   "master-key",
+					},
+				},
+			},
+			other: &State{
+				Infrastructure: Infrastructure{
+					Azure: &Azure{
+						ResourceGroup: "new-rg",
+					},
+				},
+			},
+			expected: &State{
+				Infrastructure: Infrastructure{
+					Azure: &Azure{
+						ResourceGroup:   "new-rg",
+						MasterKeyHSMURI: "https://test-hsm.managedhsm.azure.net/",
+						MasterKeyName:   "master-key",
+					},
+				},
+			},
+		},
 		"empty state": {
 			state: &State{},
 			other: &State{
-				Version: "v1",
+				Version: "v2",
 				Infrastructure: Infrastructure{
 					UID: "456",
 				},
@@ -206,7 +558,7 @@ func TestMerge(t *testing.T) {
 				},
 			},
 			expected: &State{
-				Version: "v1",
+				Version: "v2",
 				Infrastructure: Infrastructure{
 					UID: "456",
 				},
@@ -217,7 +569,7 @@ func TestMerge(t *testing.T) {
 		},
 		"empty other": {
 			state: &State{
-				Version: "v1",
+				Version: "v2",
 				Infrastructure: Infrastructure{
 					UID: "456",
 				},
@@ -227,7 +579,7 @@ func TestMerge(t *testing.T) {
 			},
 			other: &State{},
 			expected: &State{
-				Version: "v1",
+				Version: "v2",
 				Infrastructure: Infrastructure{
 					UID: "456",
 				},
@@ -243,7 +595,7 @@ func TestMerge(t *testing.T) {
 		},
 		"identical": {
 			state: &State{
-				Version: "v1",
+				Version: "v2",
 				Infrastructure: Infrastructure{
 					UID: "456",
 				},
@@ -252,7 +604,7 @@ func TestMerge(t *testing.T) {
 				},
 			},
 			other: &State{
-				Version: "v1",
+				Version: "v2",
 				Infrastructure: Infrastructure{
 					UID: "456",
 				},
@@ -261,7 +613,7 @@ func TestMerge(t *testing.T) {
 				},
 			},
 			expected: &State{
-				Version: "v1",
+				Version: "v2",
 				Infrastructure: Infrastructure{
 					UID: "456",
 				},
@@ -272,7 +624,7 @@ func TestMerge(t *testing.T) {
 		},
 		"nested pointer": {
 			state: &State{
-				Version: "v1",
+				Version: "v2",
 				Infrastructure: Infrastructure{
 					UID: "123",
 					Azure: &Azure{
@@ -284,7 +636,7 @@ func TestMerge(t *testing.T) {
 				},
 			},
 			other: &State{
-				Version: "v1",
+				Version: "v2",
 				Infrastructure: Infrastructure{
 					UID: "456",
 					Azure: &Azure{
@@ -296,7 +648,7 @@ func TestMerge(t *testing.T) {
 				},
 			},
 			expected: &State{
-				Version: "v1",
+				Version: "v2",
 				Infrastructure: Infrastructure{
 					UID: "456",
 					Azure: &Azure{
@@ -363,7 +715,7 @@ func TestCreateOrRead(t *testing.T) {
 			}
 			assert.NoError(err)
 			assert.YAMLEq(mustMarshalYaml(require, tc.wantState), mustMarshalYaml(require, state))
-			assert.YAMLEq(mustMarshalYaml(require, tc.wantState), mustReadFromFile(require, tc.fs))
+			assert.YAMLEq(mustMarshalCanonical(require, tc.wantState), mustReadFromFile(require, tc.fs))
 		})
 	}
 }
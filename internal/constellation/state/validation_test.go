@@ -7,6 +7,7 @@ SPDX-License-Identifier: AGPL-3.0-only
 package state
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/edgelesssys/constellation/v2/internal/attestation/variant"
@@ -23,7 +24,7 @@ func TestPreCreateValidation(t *testing.T) {
 		"valid": {
 			stateFile: func() *State {
 				return &State{
-					Version: Version1,
+					Version: Version2,
 				}
 			},
 		},
@@ -35,13 +36,13 @@ func TestPreCreateValidation(t *testing.T) {
 			},
 			wantErr: true,
 			errAssertions: func(a *assert.Assertions, err error) {
-				a.Contains(err.Error(), "validating State.version: invalid must be one of [v1]")
+				a.Contains(err.Error(), "validating State.version: invalid must be one of [v2]")
 			},
 		},
 		"infrastructure not empty": {
 			stateFile: func() *State {
 				return &State{
-					Version: Version1,
+					Version: Version2,
 					Infrastructure: Infrastructure{
 						ClusterEndpoint: "test",
 					},
@@ -55,7 +56,7 @@ func TestPreCreateValidation(t *testing.T) {
 		"cluster values not empty": {
 			stateFile: func() *State {
 				return &State{
-					Version: Version1,
+					Version: Version2,
 					ClusterValues: ClusterValues{
 						ClusterID: "test",
 					},
@@ -107,7 +108,7 @@ func TestPreInitValidation(t *testing.T) {
 			},
 			wantErr: true,
 			errAssertions: func(a *assert.Assertions, err error) {
-				a.Contains(err.Error(), "validating State.version: invalid must be one of [v1]")
+				a.Contains(err.Error(), "validating State.version: invalid must be one of [v2]")
 			},
 		},
 		"cluster endpoint invalid": {
@@ -263,7 +264,7 @@ func TestPostInitValidation(t *testing.T) {
 			},
 			wantErr: true,
 			errAssertions: func(a *assert.Assertions, err error) {
-				a.Contains(err.Error(), "validating State.version: invalid must be one of [v1]")
+				a.Contains(err.Error(), "validating State.version: invalid must be one of [v2]")
 			},
 		},
 		"cluster endpoint invalid": {
@@ -389,6 +390,30 @@ func TestPostInitValidation(t *testing.T) {
 				a.Contains(err.Error(), "validating State.clusterValues.clusterID: must not be empty")
 			},
 		},
+		"measurement salt too short": {
+			stateFile: func() *State {
+				s := defaultGCPState()
+				s.ClusterValues.MeasurementSalt = []byte{0x41}
+				return s
+			},
+			variant: variant.GCPSEVES{},
+			wantErr: true,
+			errAssertions: func(a *assert.Assertions, err error) {
+				a.Contains(err.Error(), "validating State.clusterValues.measurementSalt: must have length 32, got 1")
+			},
+		},
+		"measurement salt too long": {
+			stateFile: func() *State {
+				s := defaultAzureState()
+				s.ClusterValues.MeasurementSalt = bytes.Repeat([]byte{0x41}, 64)
+				return s
+			},
+			variant: variant.AzureSEVSNP{},
+			wantErr: true,
+			errAssertions: func(a *assert.Assertions, err error) {
+				a.Contains(err.Error(), "validating State.clusterValues.measurementSalt: must have length 32, got 64")
+			},
+		},
 	}
 
 	for name, tc := range testCases {
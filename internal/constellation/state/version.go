@@ -0,0 +1,95 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package state
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// versions are the schema versions of the state file, ordered from oldest to newest.
+// The newest version is the one written by this CLI.
+var versions = []string{Version1, Version2}
+
+// migrations migrate a state from the schema version they are keyed by to the next version.
+// Every version but the newest one needs a migration.
+var migrations = map[string]func(*State) error{
+	Version1: migrateV1ToV2,
+}
+
+// migrateV1ToV2 migrates a Version1 state.
+// Version2 only adds optional fields, which Version1 states don't set.
+func migrateV1ToV2(*State) error {
+	return nil
+}
+
+// NewerVersionError is returned when a state file was written by a newer CLI,
+// whose schema version this CLI doesn't know.
+type NewerVersionError struct {
+	// Version is the schema version of the state file.
+	Version string
+	// Supported is the newest schema version supported by this CLI.
+	Supported string
+}
+
+// Error returns the error message.
+func (e *NewerVersionError) Error() string {
+	return fmt.Sprintf("state file was written by a newer CLI (schema version %s, this CLI supports up to %s): upgrade your CLI to use this state file",
+		e.Version, e.Supported)
+}
+
+// checkVersion checks that the schema version of a state file can be read by this CLI.
+// States without a version are left to the validation of the state.
+func checkVersion(version string) error {
+	if version == "" || slices.Contains(versions, version) {
+		return nil
+	}
+	newest := versions[len(versions)-1]
+	number, ok := versionNumber(version)
+	if ok && number > mustVersionNumber(newest) {
+		return &NewerVersionError{Version: version, Supported: newest}
+	}
+	return fmt.Errorf("unknown state file schema version %q", version)
+}
+
+// migrate migrates the state to the newest schema version.
+func (s *State) migrate() error {
+	idx := slices.Index(versions, s.Version)
+	if idx < 0 {
+		return nil
+	}
+	for ; idx < len(versions)-1; idx++ {
+		migration, ok := migrations[versions[idx]]
+		if !ok {
+			return fmt.Errorf("no migration from schema version %s to %s", versions[idx], versions[idx+1])
+		}
+		if err := migration(s); err != nil {
+			return fmt.Errorf("migrating from schema version %s to %s: %w", versions[idx], versions[idx+1], err)
+		}
+		s.Version = versions[idx+1]
+	}
+	return nil
+}
+
+// versionNumber returns the number of a schema version of the form "v<number>".
+func versionNumber(version string) (int, bool) {
+	number, err := strconv.Atoi(strings.TrimPrefix(version, "v"))
+	if err != nil || !strings.HasPrefix(version, "v") {
+		return 0, false
+	}
+	return number, true
+}
+
+func mustVersionNumber(version string) int {
+	number, ok := versionNumber(version)
+	if !ok {
+		panic(fmt.Sprintf("invalid state file schema version %q", version))
+	}
+	return number
+}
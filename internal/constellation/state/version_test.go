@@ -0,0 +1,127 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package state
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/file"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadFromFileVersion(t *testing.T) {
+	testCases := map[string]struct {
+		stateFile      string
+		versions       []string
+		migrations     map[string]func(*State) error
+		wantVersion    string
+		wantName       string
+		wantNewerErr   bool
+		wantErr        bool
+		wantErrMessage string
+	}{
+		"current version": {
+			stateFile:   "version: v2\ninfrastructure:\n  name: constell\n",
+			wantVersion: Version2,
+			wantName:    "constell",
+		},
+		"v1 is migrated to v2": {
+			stateFile:   "version: v1\ninfrastructure:\n  name: constell\n",
+			wantVersion: Version2,
+			wantName:    "constell",
+		},
+		"newer version": {
+			stateFile:      "version: v3\ninfrastructure:\n  name: constell\n",
+			wantNewerErr:   true,
+			wantErr:        true,
+			wantErrMessage: "state file was written by a newer CLI (schema version v3, this CLI supports up to v2): upgrade your CLI to use this state file",
+		},
+		"newer version with incompatible fields": {
+			stateFile:    "version: v4\ninfrastructure: [renamed]\n",
+			wantNewerErr: true,
+			wantErr:      true,
+		},
+		"unknown version": {
+			stateFile: "version: latest\n",
+			wantErr:   true,
+		},
+		"older version is migrated": {
+			stateFile: "version: v0\ninfrastructure:\n  name: constell\n",
+			versions:  []string{"v0", Version1},
+			migrations: map[string]func(*State) error{
+				"v0": func(s *State) error {
+					s.Infrastructure.Name += "-migrated"
+					return nil
+				},
+			},
+			wantVersion: Version1,
+			wantName:    "constell-migrated",
+		},
+		"older version is migrated over multiple versions": {
+			stateFile: "version: v0\ninfrastructure:\n  name: constell\n",
+			versions:  []string{"v0", "v0.5", Version1},
+			migrations: map[string]func(*State) error{
+				"v0": func(s *State) error {
+					s.Infrastructure.Name += "-0"
+					return nil
+				},
+				"v0.5": func(s *State) error {
+					s.Infrastructure.Name += "-1"
+					return nil
+				},
+			},
+			wantVersion: Version1,
+			wantName:    "constell-0-1",
+		},
+		"migration fails": {
+			stateFile: "version: v0\n",
+			versions:  []string{"v0", Version1},
+			migrations: map[string]func(*State) error{
+				"v0": func(*State) error { return errors.New("failed") },
+			},
+			wantErr: true,
+		},
+		"migration missing": {
+			stateFile: "version: v0\n",
+			versions:  []string{"v0", Version1},
+			wantErr:   true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			if tc.versions != nil {
+				oldVersions, oldMigrations := versions, migrations
+				versions, migrations = tc.versions, tc.migrations
+				t.Cleanup(func() { versions, migrations = oldVersions, oldMigrations })
+			}
+			fh := file.NewHandler(afero.NewMemMapFs())
+			require.NoError(fh.Write(constants.StateFilename, []byte(tc.stateFile)))
+
+			state, err := ReadFromFile(fh, constants.StateFilename)
+			if tc.wantErr {
+				require.Error(err)
+				var newerErr *NewerVersionError
+				assert.Equal(tc.wantNewerErr, errors.As(err, &newerErr))
+				if tc.wantErrMessage != "" {
+					assert.EqualError(err, tc.wantErrMessage)
+				}
+				return
+			}
+			require.NoError(err)
+			assert.Equal(tc.wantVersion, state.Version)
+			assert.Equal(tc.wantName, state.Infrastructure.Name)
+		})
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/siderolabs/talos/pkg/machinery/config/encoder"
 	"github.com/spf13/afero"
@@ -50,6 +51,8 @@ const (
 	optMkdirAll
 	// OptAppend appends to the file.
 	optAppend
+	// OptAtomic replaces the file atomically.
+	optAtomic
 )
 
 var (
@@ -61,8 +64,15 @@ var (
 	OptMkdirAll = Option{optMkdirAll}
 	// OptAppend appends to the file.
 	OptAppend = Option{optAppend}
+	// OptAtomic writes the data to a temporary file and renames it over the file,
+	// so the file is never left partially written. Combine with OptOverwrite to replace an existing file.
+	OptAtomic = Option{optAtomic}
 )
 
+// atomicRenameAttempts is the number of attempts to rename the temporary file of an atomic write.
+// Renames can fail temporarily, e.g., on Windows if another process has the file open.
+const atomicRenameAttempts = 3
+
 // Handler handles file interaction.
 type Handler struct {
 	fs *afero.Afero
@@ -91,6 +101,12 @@ func (h *Handler) Write(name string, data []byte, options ...Option) error {
 			return err
 		}
 	}
+	if hasOption(options, OptAtomic) {
+		if hasOption(options, OptAppend) {
+			return errors.New("atomic writes can't append to a file")
+		}
+		return h.writeAtomic(name, data, hasOption(options, OptOverwrite))
+	}
 	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
 	if hasOption(options, OptOverwrite) {
 		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
@@ -109,6 +125,52 @@ func (h *Handler) Write(name string, data []byte, options ...Option) error {
 	return err
 }
 
+// writeAtomic writes data to a temporary file in the directory of name, syncs it to disk,
+// and renames it over name. If the write is interrupted, name keeps its previous content.
+func (h *Handler) writeAtomic(name string, data []byte, overwrite bool) error {
+	if !overwrite {
+		if _, err := h.fs.Stat(name); err == nil {
+			return &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	tmp, err := h.fs.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if errTmp := tmp.Close(); errTmp != nil && err == nil {
+		err = errTmp
+	}
+	if err == nil {
+		err = h.renameWithRetry(tmp.Name(), name)
+	}
+	if err != nil {
+		_ = h.fs.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// renameWithRetry renames old to new, retrying temporary failures.
+func (h *Handler) renameWithRetry(old, new string) error {
+	var err error
+	for attempt := 1; attempt <= atomicRenameAttempts; attempt++ {
+		if err = h.fs.Rename(old, new); err == nil {
+			return nil
+		}
+		if attempt < atomicRenameAttempts {
+			time.Sleep(time.Duration(attempt) * 50 * time.Millisecond)
+		}
+	}
+	return err
+}
+
 // ReadJSON reads a JSON file from name and unmarshals it into the content interface.
 // The interface content must be a pointer to a JSON marchalable object.
 func (h *Handler) ReadJSON(name string, content any) error {
@@ -237,6 +299,11 @@ func (h *Handler) RenameFile(old, new string) error {
 	return h.fs.Rename(old, new)
 }
 
+// ReadDir returns the entries of the given directory, sorted by filename.
+func (h *Handler) ReadDir(dirName string) ([]fs.FileInfo, error) {
+	return h.fs.ReadDir(dirName)
+}
+
 // IsEmpty returns true if the given directory is empty.
 func (h *Handler) IsEmpty(dirName string) (bool, error) {
 	f, err := h.fs.Open(dirName)
@@ -8,6 +8,7 @@ package file
 
 import (
 	"encoding/json"
+	"errors"
 	"io/fs"
 	"path/filepath"
 	"testing"
@@ -92,6 +93,84 @@ func TestWrite(t *testing.T) {
 	}
 }
 
+func TestWriteAtomic(t *testing.T) {
+	testCases := map[string]struct {
+		fs              afero.Fs
+		setupFs         func(af afero.Afero) error
+		options         []Option
+		expectedContent string
+		wantErr         bool
+	}{
+		"successful write": {
+			fs:              afero.NewMemMapFs(),
+			options:         []Option{OptMkdirAll},
+			expectedContent: "asdf",
+		},
+		"successful overwrite": {
+			fs:              afero.NewMemMapFs(),
+			setupFs:         func(af afero.Afero) error { return af.WriteFile("somedir/somefile", []byte("fdsa"), 0o644) },
+			options:         []Option{OptOverwrite},
+			expectedContent: "asdf",
+		},
+		"file already exists": {
+			fs:              afero.NewMemMapFs(),
+			setupFs:         func(af afero.Afero) error { return af.WriteFile("somedir/somefile", []byte("fdsa"), 0o644) },
+			expectedContent: "fdsa",
+			wantErr:         true,
+		},
+		"append": {
+			fs:              afero.NewMemMapFs(),
+			setupFs:         func(af afero.Afero) error { return af.WriteFile("somedir/somefile", []byte("fdsa"), 0o644) },
+			options:         []Option{OptAppend},
+			expectedContent: "fdsa",
+			wantErr:         true,
+		},
+		"rename fails": {
+			fs:              renameErrFs{afero.NewMemMapFs()},
+			setupFs:         func(af afero.Afero) error { return af.WriteFile("somedir/somefile", []byte("fdsa"), 0o644) },
+			options:         []Option{OptOverwrite},
+			expectedContent: "fdsa",
+			wantErr:         true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			handler := NewHandler(tc.fs)
+			if tc.setupFs != nil {
+				require.NoError(tc.setupFs(afero.Afero{Fs: tc.fs}))
+			}
+
+			err := handler.Write("somedir/somefile", []byte("asdf"), append(tc.options, OptAtomic)...)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			content, err := handler.Read("somedir/somefile")
+			require.NoError(err)
+			assert.Equal(tc.expectedContent, string(content))
+
+			// no temporary files are left behind
+			tmpFiles, err := afero.Glob(tc.fs, "somedir/.*.tmp")
+			require.NoError(err)
+			assert.Empty(tmpFiles)
+		})
+	}
+}
+
+// renameErrFs is a file system whose renames always fail.
+type renameErrFs struct {
+	afero.Fs
+}
+
+func (renameErrFs) Rename(string, string) error {
+	return errors.New("rename failed")
+}
+
 func TestReadJSON(t *testing.T) {
 	type testContent struct {
 		First  string
@@ -637,3 +716,54 @@ func TestIsEmpty(t *testing.T) {
 		})
 	}
 }
+
+func TestReadDir(t *testing.T) {
+	testCases := map[string]struct {
+		setupFs   func(fs *afero.Afero, dirName string) error
+		wantNames []string
+		wantErr   bool
+	}{
+		"empty directory": {
+			setupFs: func(fs *afero.Afero, dirName string) error { return fs.Mkdir(dirName, 0o755) },
+		},
+		"sorted entries": {
+			setupFs: func(fs *afero.Afero, dirName string) error {
+				for _, name := range []string{"b", "c", "a"} {
+					if err := fs.WriteFile(filepath.Join(dirName, name), []byte("some content"), 0o755); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			wantNames: []string{"a", "b", "c"},
+		},
+		"directory not existent": {
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			dirName := "test"
+
+			handler := NewHandler(afero.NewMemMapFs())
+			if tc.setupFs != nil {
+				require.NoError(tc.setupFs(handler.fs, dirName))
+			}
+
+			entries, err := handler.ReadDir(dirName)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			var names []string
+			for _, entry := range entries {
+				names = append(names, entry.Name())
+			}
+			assert.Equal(tc.wantNames, names)
+		})
+	}
+}
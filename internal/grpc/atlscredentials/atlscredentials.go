@@ -18,21 +18,28 @@ import (
 
 // Credentials for attested TLS (ATLS).
 type Credentials struct {
-	issuer     atls.Issuer
-	validators []atls.Validator
+	issuer      atls.Issuer
+	validators  []atls.Validator
+	nonceSource atls.NonceSource
 }
 
 // New creates new ATLS Credentials.
 func New(issuer atls.Issuer, validators []atls.Validator) *Credentials {
+	return NewWithNonceSource(issuer, validators, atls.RandomNonceSource{})
+}
+
+// NewWithNonceSource creates new ATLS Credentials that take the nonces of client handshakes from nonceSource.
+func NewWithNonceSource(issuer atls.Issuer, validators []atls.Validator, nonceSource atls.NonceSource) *Credentials {
 	return &Credentials{
-		issuer:     issuer,
-		validators: validators,
+		issuer:      issuer,
+		validators:  validators,
+		nonceSource: nonceSource,
 	}
 }
 
 // ClientHandshake performs the client handshake.
 func (c *Credentials) ClientHandshake(ctx context.Context, authority string, rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
-	clientCfg, err := atls.CreateAttestationClientTLSConfig(c.issuer, c.validators)
+	clientCfg, err := atls.CreateAttestationClientTLSConfigWithNonceSource(c.issuer, c.validators, c.nonceSource)
 	if err != nil {
 		return nil, nil, err
 	}
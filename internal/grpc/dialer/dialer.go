@@ -9,37 +9,46 @@ package dialer
 
 import (
 	"context"
+	"crypto/tls"
 	"net"
 
 	"github.com/edgelesssys/constellation/v2/internal/atls"
 	"github.com/edgelesssys/constellation/v2/internal/grpc/atlscredentials"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
 // Dialer can open grpc client connections with different levels of ATLS encryption / verification.
 type Dialer struct {
-	issuer    atls.Issuer
-	validator atls.Validator
-	netDialer NetDialer
+	issuer      atls.Issuer
+	validator   atls.Validator
+	nonceSource atls.NonceSource
+	netDialer   NetDialer
 }
 
 // New creates a new Dialer.
 func New(issuer atls.Issuer, validator atls.Validator, netDialer NetDialer) *Dialer {
 	return &Dialer{
-		issuer:    issuer,
-		validator: validator,
-		netDialer: netDialer,
+		issuer:      issuer,
+		validator:   validator,
+		nonceSource: atls.RandomNonceSource{},
+		netDialer:   netDialer,
 	}
 }
 
+// SetNonceSource sets the source of the nonces sent in the aTLS handshakes of Dial.
+func (d *Dialer) SetNonceSource(nonceSource atls.NonceSource) {
+	d.nonceSource = nonceSource
+}
+
 // Dial creates a new grpc client connection to the given target using the atls validator.
 func (d *Dialer) Dial(target string) (*grpc.ClientConn, error) {
 	var validators []atls.Validator
 	if d.validator != nil {
 		validators = append(validators, d.validator)
 	}
-	credentials := atlscredentials.New(d.issuer, validators)
+	credentials := atlscredentials.NewWithNonceSource(d.issuer, validators, d.nonceSource)
 
 	return grpc.NewClient(target,
 		d.grpcWithDialer(),
@@ -56,6 +65,15 @@ func (d *Dialer) DialInsecure(target string) (*grpc.ClientConn, error) {
 	)
 }
 
+// DialTLS creates a new grpc client connection to the given target using standard TLS with the given config.
+// Use this method to authenticate to the target with a client certificate.
+func (d *Dialer) DialTLS(target string, tlsConfig *tls.Config) (*grpc.ClientConn, error) {
+	return grpc.NewClient(target,
+		d.grpcWithDialer(),
+		grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)),
+	)
+}
+
 // DialNoVerify creates a new grpc client connection to the given target without verifying the server's attestation.
 func (d *Dialer) DialNoVerify(target string) (*grpc.ClientConn, error) {
 	credentials := atlscredentials.New(nil, nil)
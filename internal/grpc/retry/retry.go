@@ -90,3 +90,24 @@ func LoadbalancerIsNotReady(err error) bool {
 	// retry if GCP proxy LB isn't fully available yet
 	return strings.HasPrefix(statusErr.Message(), authReadTCPErr)
 }
+
+// AuthenticationFailed checks if the error is a failed authentication handshake,
+// e.g. because the attestation of the server couldn't be verified.
+// Handshake failures that are retried by ServiceIsUnavailable, e.g. those of a GCP LB that isn't ready yet, aren't authentication failures.
+func AuthenticationFailed(err error) bool {
+	var targetErr grpcErr
+	if !errors.As(err, &targetErr) {
+		return false
+	}
+
+	statusErr, ok := status.FromError(targetErr)
+	if !ok {
+		return false
+	}
+
+	if statusErr.Code() != codes.Unavailable {
+		return false
+	}
+
+	return strings.HasPrefix(statusErr.Message(), authHandshakeErr) && !ServiceIsUnavailable(err)
+}
@@ -108,3 +108,45 @@ func TestLoadbalancerIsNotReady(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthenticationFailed(t *testing.T) {
+	testCases := map[string]struct {
+		err        error
+		wantFailed bool
+	}{
+		"nil": {},
+		"not status error": {
+			err: errors.New("error"),
+		},
+		"not unavailable": {
+			err: status.Error(codes.Internal, "error"),
+		},
+		"unavailable error with authentication handshake failure": {
+			err:        status.Error(codes.Unavailable, `connection error: desc = "transport: authentication handshake failed: bad certificate"`),
+			wantFailed: true,
+		},
+		"handshake EOF error": {
+			err: status.Error(codes.Unavailable, `connection error: desc = "transport: authentication handshake failed: EOF"`),
+		},
+		"handshake read tcp error": {
+			err: status.Error(codes.Unavailable, `connection error: desc = "transport: authentication handshake failed: read tcp error"`),
+		},
+		"handshake deadline exceeded error": {
+			err: status.Error(codes.Unavailable, `connection error: desc = "transport: authentication handshake failed: context deadline exceeded"`),
+		},
+		"normal unavailable error": {
+			err: status.Error(codes.Unavailable, "error"),
+		},
+		"wrapped error": {
+			err:        fmt.Errorf("some wrapping: %w", status.Error(codes.Unavailable, `connection error: desc = "transport: authentication handshake failed: invalid PCR value"`)),
+			wantFailed: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			assert.Equal(tc.wantFailed, AuthenticationFailed(tc.err))
+		})
+	}
+}
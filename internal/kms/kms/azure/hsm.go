@@ -0,0 +1,148 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package azure
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/keyvault/azkeys"
+	"github.com/edgelesssys/constellation/v2/internal/kms/uri"
+)
+
+// ErrPermissionDenied is returned if the identity used to access the Managed HSM is not allowed to use the key.
+var ErrPermissionDenied = errors.New("permission denied")
+
+// ManagedHSM implements the KMSBackend interface for Azure Managed HSM.
+type ManagedHSM struct {
+	client  hsmClient
+	keyName string
+}
+
+// NewManagedHSM creates a KMS backend using the key keyName of the Azure Managed HSM at hsmURI.
+func NewManagedHSM(hsmURI, keyName string, credential azcore.TokenCredential, options *azkeys.ClientOptions) (*ManagedHSM, error) {
+	if err := ValidateManagedHSMURI(hsmURI); err != nil {
+		return nil, err
+	}
+	if keyName == "" {
+		return nil, errors.New("no key name provided for Managed HSM")
+	}
+	client, err := azkeys.NewClient(hsmURI, credential, options)
+	if err != nil {
+		return nil, fmt.Errorf("creating Managed HSM client: %w", err)
+	}
+	return &ManagedHSM{client: client, keyName: keyName}, nil
+}
+
+// ValidateManagedHSMURI checks that hsmURI points to an Azure Managed HSM,
+// e.g. "https://my-hsm.managedhsm.azure.net/".
+func ValidateManagedHSMURI(hsmURI string) error {
+	u, err := url.Parse(hsmURI)
+	if err != nil {
+		return fmt.Errorf("parsing Managed HSM URI: %w", err)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("invalid Managed HSM URI %q: scheme must be https", hsmURI)
+	}
+	name, ok := strings.CutSuffix(u.Host, "."+string(uri.HSMDefaultCloud))
+	if !ok || name == "" || strings.ContainsAny(name, ".:") {
+		return fmt.Errorf("invalid Managed HSM URI %q: host must be of the form <name>.%s", hsmURI, uri.HSMDefaultCloud)
+	}
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return fmt.Errorf("invalid Managed HSM URI %q: must not contain a path, query, fragment, or user info", hsmURI)
+	}
+	return nil
+}
+
+// Encrypt encrypts the plaintext using the latest version of the HSM key.
+// The returned ciphertext records the key version, so it can still be decrypted after the key was rotated.
+func (h *ManagedHSM) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	algorithm := azkeys.JSONWebKeyEncryptionAlgorithmRSAOAEP256
+	resp, err := h.client.Encrypt(ctx, h.keyName, "", azkeys.KeyOperationsParameters{
+		Algorithm: &algorithm,
+		Value:     plaintext,
+	}, nil)
+	if err != nil {
+		return nil, wrapHSMError("encrypting with Managed HSM key", err)
+	}
+
+	var keyVersion string
+	if resp.KID != nil {
+		keyVersion = resp.KID.Version()
+	}
+	return json.Marshal(hsmCiphertext{
+		KeyVersion: keyVersion,
+		Ciphertext: resp.Result,
+	})
+}
+
+// Decrypt decrypts a ciphertext previously returned by Encrypt.
+func (h *ManagedHSM) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	var ct hsmCiphertext
+	if err := json.Unmarshal(ciphertext, &ct); err != nil {
+		return nil, fmt.Errorf("unmarshalling Managed HSM ciphertext: %w", err)
+	}
+
+	algorithm := azkeys.JSONWebKeyEncryptionAlgorithmRSAOAEP256
+	resp, err := h.client.Decrypt(ctx, h.keyName, ct.KeyVersion, azkeys.KeyOperationsParameters{
+		Algorithm: &algorithm,
+		Value:     ct.Ciphertext,
+	}, nil)
+	if err != nil {
+		return nil, wrapHSMError("decrypting with Managed HSM key", err)
+	}
+	return resp.Result, nil
+}
+
+// ValidatePermissions checks that the key can be used for encryption and decryption,
+// by encrypting and decrypting a random value.
+func (h *ManagedHSM) ValidatePermissions(ctx context.Context) error {
+	probe := make([]byte, 32)
+	if _, err := rand.Read(probe); err != nil {
+		return fmt.Errorf("generating probe value: %w", err)
+	}
+	ciphertext, err := h.Encrypt(ctx, probe)
+	if err != nil {
+		return err
+	}
+	plaintext, err := h.Decrypt(ctx, ciphertext)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(probe, plaintext) {
+		return errors.New("decrypting with Managed HSM key returned a different value than was encrypted")
+	}
+	return nil
+}
+
+// hsmCiphertext is the serialized form of a ciphertext returned by [ManagedHSM.Encrypt].
+type hsmCiphertext struct {
+	KeyVersion string `json:"keyVersion"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// wrapHSMError marks authorization failures of the Managed HSM with ErrPermissionDenied.
+func wrapHSMError(msg string, err error) error {
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) && (respErr.StatusCode == http.StatusForbidden || respErr.StatusCode == http.StatusUnauthorized) {
+		return fmt.Errorf("%s: %w: the identity needs the \"Managed HSM Crypto User\" role for the key: %w", msg, ErrPermissionDenied, err)
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+type hsmClient interface {
+	Encrypt(ctx context.Context, name string, version string, parameters azkeys.KeyOperationsParameters, options *azkeys.EncryptOptions) (azkeys.EncryptResponse, error)
+	Decrypt(ctx context.Context, name string, version string, parameters azkeys.KeyOperationsParameters, options *azkeys.DecryptOptions) (azkeys.DecryptResponse, error)
+}
@@ -0,0 +1,305 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/fake"
+	"github.com/Azure/azure-sdk-for-go/sdk/keyvault/azkeys"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+const testHSMURI = "https://test-hsm.managedhsm.azure.net/"
+
+func TestValidateManagedHSMURI(t *testing.T) {
+	testCases := map[string]struct {
+		uri     string
+		wantErr bool
+	}{
+		"valid": {
+			uri: testHSMURI,
+		},
+		"valid without trailing slash": {
+			uri: "https://test-hsm.managedhsm.azure.net",
+		},
+		"key vault instead of managed HSM": {
+			uri:     "https://test-vault.vault.azure.net/",
+			wantErr: true,
+		},
+		"http": {
+			uri:     "http://test-hsm.managedhsm.azure.net/",
+			wantErr: true,
+		},
+		"missing name": {
+			uri:     "https://managedhsm.azure.net/",
+			wantErr: true,
+		},
+		"nested subdomain": {
+			uri:     "https://a.test-hsm.managedhsm.azure.net/",
+			wantErr: true,
+		},
+		"with port": {
+			uri:     "https://test-hsm.managedhsm.azure.net:8443/",
+			wantErr: true,
+		},
+		"with path": {
+			uri:     "https://test-hsm.managedhsm.azure.net/keys/master",
+			wantErr: true,
+		},
+		"with query": {
+			uri:     "https://test-hsm.managedhsm.azure.net/?api-version=7.4",
+			wantErr: true,
+		},
+		"not a URI": {
+			uri:     "test-hsm",
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			err := ValidateManagedHSMURI(tc.uri)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
+
+func TestNewManagedHSM(t *testing.T) {
+	testCases := map[string]struct {
+		uri     string
+		keyName string
+		wantErr bool
+	}{
+		"success": {
+			uri:     testHSMURI,
+			keyName: "master-key",
+		},
+		"invalid URI": {
+			uri:     "https://test-vault.vault.azure.net/",
+			keyName: "master-key",
+			wantErr: true,
+		},
+		"missing key name": {
+			uri:     testHSMURI,
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			_, err := NewManagedHSM(tc.uri, tc.keyName, &fake.TokenCredential{}, nil)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
+
+func TestManagedHSMEncryptDecrypt(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	server := &fakeHSMServer{keyName: "master-key", keyVersion: "v1"}
+	hsm := newTestManagedHSM(t, server, &fake.TokenCredential{})
+
+	plaintext := []byte("master secret")
+	ciphertext, err := hsm.Encrypt(context.Background(), plaintext)
+	require.NoError(err)
+	assert.NotContains(string(ciphertext), string(plaintext))
+
+	// decryption uses the key version the plaintext was encrypted with
+	server.keyVersion = "v2"
+	decrypted, err := hsm.Decrypt(context.Background(), ciphertext)
+	require.NoError(err)
+	assert.Equal(plaintext, decrypted)
+	assert.Equal([]string{"v1"}, server.decryptVersions)
+
+	_, err = hsm.Decrypt(context.Background(), []byte("not a ciphertext"))
+	assert.Error(err)
+}
+
+func TestManagedHSMValidatePermissions(t *testing.T) {
+	testCases := map[string]struct {
+		server         *fakeHSMServer
+		credentialErr  error
+		wantErr        bool
+		wantPermission bool
+	}{
+		"success": {
+			server: &fakeHSMServer{keyName: "master-key", keyVersion: "v1"},
+		},
+		"missing encrypt permission": {
+			server:         &fakeHSMServer{keyName: "master-key", keyVersion: "v1", forbidden: map[string]bool{"encrypt": true}},
+			wantErr:        true,
+			wantPermission: true,
+		},
+		"missing decrypt permission": {
+			server:         &fakeHSMServer{keyName: "master-key", keyVersion: "v1", forbidden: map[string]bool{"decrypt": true}},
+			wantErr:        true,
+			wantPermission: true,
+		},
+		"key does not exist": {
+			server:  &fakeHSMServer{keyName: "other-key", keyVersion: "v1"},
+			wantErr: true,
+		},
+		"decryption returns wrong plaintext": {
+			server:  &fakeHSMServer{keyName: "master-key", keyVersion: "v1", corruptDecrypt: true},
+			wantErr: true,
+		},
+		"credential error": {
+			server:        &fakeHSMServer{keyName: "master-key", keyVersion: "v1"},
+			credentialErr: errors.New("no credentials"),
+			wantErr:       true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			credential := &fake.TokenCredential{}
+			if tc.credentialErr != nil {
+				credential.SetError(tc.credentialErr)
+			}
+			hsm := newTestManagedHSM(t, tc.server, credential)
+
+			err := hsm.ValidatePermissions(context.Background())
+			if !tc.wantErr {
+				assert.NoError(err)
+				return
+			}
+			assert.Error(err)
+			assert.Equal(tc.wantPermission, errors.Is(err, ErrPermissionDenied))
+		})
+	}
+}
+
+func newTestManagedHSM(t *testing.T, server *fakeHSMServer, credential azcore.TokenCredential) *ManagedHSM {
+	t.Helper()
+	hsm, err := NewManagedHSM(testHSMURI, "master-key", credential, &azkeys.ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			Transport: server,
+		},
+	})
+	require.NoError(t, err)
+	return hsm
+}
+
+// fakeHSMServer fakes the Managed HSM key operations API.
+// Encryption is simulated by XORing the plaintext with the key version.
+type fakeHSMServer struct {
+	keyName         string
+	keyVersion      string
+	forbidden       map[string]bool
+	corruptDecrypt  bool
+	decryptVersions []string
+}
+
+func (s *fakeHSMServer) Do(req *http.Request) (*http.Response, error) {
+	// /keys/{name}/{version}/{operation}, where the version is omitted for the latest key version
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(parts) == 3 {
+		parts = []string{parts[0], parts[1], "", parts[2]}
+	}
+	if len(parts) != 4 || parts[0] != "keys" {
+		return newFakeResponse(req, http.StatusNotFound, nil), nil
+	}
+	name, version, operation := parts[1], parts[2], parts[3]
+
+	if s.forbidden[operation] {
+		return newFakeErrorResponse(req, http.StatusForbidden, "Forbidden"), nil
+	}
+	if req.Header.Get("Authorization") == "" {
+		resp := newFakeResponse(req, http.StatusUnauthorized, nil)
+		resp.Header.Set("WWW-Authenticate",
+			`Bearer authorization="https://login.microsoftonline.com/00000000-0000-0000-0000-000000000000", resource="https://managedhsm.azure.net"`)
+		return resp, nil
+	}
+	if name != s.keyName {
+		return newFakeErrorResponse(req, http.StatusNotFound, "KeyNotFound"), nil
+	}
+
+	var params azkeys.KeyOperationsParameters
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &params); err != nil {
+		return newFakeErrorResponse(req, http.StatusBadRequest, "BadParameter"), nil
+	}
+
+	switch operation {
+	case "encrypt":
+		version = s.keyVersion
+	case "decrypt":
+		s.decryptVersions = append(s.decryptVersions, version)
+	default:
+		return newFakeErrorResponse(req, http.StatusBadRequest, "BadParameter"), nil
+	}
+	result := xorWith(params.Value, version)
+	if operation == "decrypt" && s.corruptDecrypt {
+		result = append(result, 0x00)
+	}
+
+	respBody, err := json.Marshal(map[string]string{
+		"kid":   fmt.Sprintf("%skeys/%s/%s", testHSMURI, name, version),
+		"value": base64.RawURLEncoding.EncodeToString(result),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newFakeResponse(req, http.StatusOK, respBody), nil
+}
+
+func xorWith(data []byte, key string) []byte {
+	out := make([]byte, len(data))
+	for i := range data {
+		out[i] = data[i] ^ key[i%len(key)]
+	}
+	return out
+}
+
+func newFakeResponse(req *http.Request, status int, body []byte) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}
+}
+
+func newFakeErrorResponse(req *http.Request, status int, code string) *http.Response {
+	body := fmt.Sprintf(`{"error":{"code":%q,"message":"fake error"}}`, code)
+	return newFakeResponse(req, status, []byte(body))
+}
@@ -27,3 +27,11 @@ type Storage interface {
 	// Put saves a DEK to the storage by key ID.
 	Put(context.Context, string, []byte) error
 }
+
+// KMSBackend protects the master secret of a cluster using an external Key Management Service.
+type KMSBackend interface {
+	// Encrypt encrypts the plaintext using a key stored in the KMS.
+	Encrypt(ctx context.Context, plaintext []byte) ([]byte, error)
+	// Decrypt decrypts a ciphertext previously returned by Encrypt.
+	Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
@@ -14,8 +14,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apiextensionsclientv1 "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1"
@@ -26,6 +29,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -114,6 +118,11 @@ func (k *Kubectl) UpdateCR(ctx context.Context, gvr schema.GroupVersionResource,
 	return k.dynamicClient.Resource(gvr).Update(ctx, obj, metav1.UpdateOptions{})
 }
 
+// CreateCR creates a Custom Resource given its group version resource.
+func (k *Kubectl) CreateCR(ctx context.Context, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	return k.dynamicClient.Resource(gvr).Create(ctx, obj, metav1.CreateOptions{})
+}
+
 // CreateConfigMap creates the provided configmap.
 func (k *Kubectl) CreateConfigMap(ctx context.Context, configMap *corev1.ConfigMap) error {
 	_, err := k.CoreV1().ConfigMaps(configMap.ObjectMeta.Namespace).Create(ctx, configMap, metav1.CreateOptions{})
@@ -128,11 +137,48 @@ func (k *Kubectl) GetConfigMap(ctx context.Context, namespace, name string) (*co
 	return k.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
 }
 
+// GetSecret returns a Secret given its name and namespace.
+func (k *Kubectl) GetSecret(ctx context.Context, namespace, name string) (*corev1.Secret, error) {
+	return k.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
 // UpdateConfigMap updates the given ConfigMap.
 func (k *Kubectl) UpdateConfigMap(ctx context.Context, configMap *corev1.ConfigMap) (*corev1.ConfigMap, error) {
 	return k.CoreV1().ConfigMaps(configMap.ObjectMeta.Namespace).Update(ctx, configMap, metav1.UpdateOptions{})
 }
 
+// ListNetworkPolicies returns all NetworkPolicies in the cluster matching the given label selector.
+func (k *Kubectl) ListNetworkPolicies(ctx context.Context, labelSelector string) ([]networkingv1.NetworkPolicy, error) {
+	policies, err := k.NetworkingV1().NetworkPolicies(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, err
+	}
+	return policies.Items, nil
+}
+
+// CreateNetworkPolicy creates the provided NetworkPolicy.
+func (k *Kubectl) CreateNetworkPolicy(ctx context.Context, policy *networkingv1.NetworkPolicy) error {
+	_, err := k.NetworkingV1().NetworkPolicies(policy.ObjectMeta.Namespace).Create(ctx, policy, metav1.CreateOptions{})
+	return err
+}
+
+// UpdateNetworkPolicy updates the given NetworkPolicy.
+func (k *Kubectl) UpdateNetworkPolicy(ctx context.Context, policy *networkingv1.NetworkPolicy) error {
+	_, err := k.NetworkingV1().NetworkPolicies(policy.ObjectMeta.Namespace).Update(ctx, policy, metav1.UpdateOptions{})
+	return err
+}
+
+// DeleteNetworkPolicy deletes the NetworkPolicy identified by name and namespace.
+func (k *Kubectl) DeleteNetworkPolicy(ctx context.Context, namespace, name string) error {
+	return k.NetworkingV1().NetworkPolicies(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+// WatchEvents watches the events of all namespaces, starting after the given resource version.
+// If resourceVersion is empty, the watch starts with the events currently stored in the cluster.
+func (k *Kubectl) WatchEvents(ctx context.Context, resourceVersion string) (watch.Interface, error) {
+	return k.CoreV1().Events(metav1.NamespaceAll).Watch(ctx, metav1.ListOptions{ResourceVersion: resourceVersion})
+}
+
 // AnnotateNode adds the provided annotations to the node, identified by name.
 func (k *Kubectl) AnnotateNode(ctx context.Context, nodeName, annotationKey, annotationValue string) error {
 	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
@@ -149,6 +195,39 @@ func (k *Kubectl) AnnotateNode(ctx context.Context, nodeName, annotationKey, ann
 	})
 }
 
+// GetDaemonSet returns the DaemonSet with the given name in the namespace.
+func (k *Kubectl) GetDaemonSet(ctx context.Context, namespace, name string) (*appsv1.DaemonSet, error) {
+	return k.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+// CreateDaemonSet creates the given DaemonSet.
+func (k *Kubectl) CreateDaemonSet(ctx context.Context, daemonSet *appsv1.DaemonSet) error {
+	_, err := k.AppsV1().DaemonSets(daemonSet.Namespace).Create(ctx, daemonSet, metav1.CreateOptions{})
+	return err
+}
+
+// DeleteDaemonSet deletes the DaemonSet with the given name in the namespace, including its pods.
+func (k *Kubectl) DeleteDaemonSet(ctx context.Context, namespace, name string) error {
+	propagation := metav1.DeletePropagationBackground
+	return k.AppsV1().DaemonSets(namespace).Delete(ctx, name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+}
+
+// ListPods returns the pods in the namespace that match the label selector.
+func (k *Kubectl) ListPods(ctx context.Context, namespace, labelSelector string) ([]corev1.Pod, error) {
+	pods, err := k.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, err
+	}
+	return pods.Items, nil
+}
+
+// RestartDaemonSet triggers a rolling restart of the DaemonSet, like "kubectl rollout restart".
+func (k *Kubectl) RestartDaemonSet(ctx context.Context, namespace, name string) error {
+	patch := fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{"kubectl.kubernetes.io/restartedAt":"%s"}}}}}`, time.Now().Format(time.RFC3339))
+	_, err := k.AppsV1().DaemonSets(namespace).Patch(ctx, name, types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{})
+	return err
+}
+
 // KubernetesVersion returns the Kubernetes version of the cluster.
 func (k *Kubectl) KubernetesVersion() (string, error) {
 	serverVersion, err := k.Discovery().ServerVersion()
@@ -25,9 +25,12 @@ package logger
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -98,6 +101,50 @@ func middlewareLogger(l *slog.Logger) logging.Logger {
 	})
 }
 
+// Format is the format log messages are written in.
+type Format string
+
+const (
+	// FormatText writes log messages as key=value pairs.
+	FormatText Format = "text"
+	// FormatJSON writes log messages as JSON objects, one per line.
+	FormatJSON Format = "json"
+)
+
+// ParseFormat parses the name of a log format.
+func ParseFormat(format string) (Format, error) {
+	switch Format(format) {
+	case FormatText, FormatJSON:
+		return Format(format), nil
+	default:
+		return "", fmt.Errorf("unknown log format %q: must be one of %q, %q", format, FormatText, FormatJSON)
+	}
+}
+
+// sensitiveKeys are substrings of attribute keys whose values are never logged.
+var sensitiveKeys = []string{"secret", "password", "token", "salt"}
+
+// redactSensitive replaces the values of attributes with sensitive keys.
+func redactSensitive(_ []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return slog.String(a.Key, "[REDACTED]")
+		}
+	}
+	return a
+}
+
+// New creates a new slog.Logger that writes log messages in the given format to w.
+// The values of attributes whose keys indicate secrets, e.g., "masterSecret", are redacted.
+func New(w io.Writer, format Format, level slog.Level) *slog.Logger {
+	opts := &slog.HandlerOptions{AddSource: true, Level: level, ReplaceAttr: redactSensitive}
+	if format == FormatJSON {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
 // NewTextLogger creates a new slog.Logger that writes text formatted log messages
 // to os.Stderr.
 func NewTextLogger(level slog.Level) *slog.Logger {
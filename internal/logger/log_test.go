@@ -0,0 +1,83 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func TestNewJSON(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var out bytes.Buffer
+	log := New(&out, FormatJSON, slog.LevelInfo)
+	log.Info("Applying cluster", "phase", "infrastructure", "nodes", 3)
+	log.Warn("Using master secret", "masterSecret", "c2VjcmV0", "initSecret", []byte("secret"))
+	log.Debug("Not logged at info level")
+
+	var lines []map[string]any
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var line map[string]any
+		require.NoError(json.Unmarshal(scanner.Bytes(), &line), "log line isn't valid JSON: %s", scanner.Text())
+		lines = append(lines, line)
+	}
+	require.NoError(scanner.Err())
+	require.Len(lines, 2)
+
+	assert.Equal("INFO", lines[0][slog.LevelKey])
+	assert.Equal("Applying cluster", lines[0][slog.MessageKey])
+	assert.Equal("infrastructure", lines[0]["phase"])
+	assert.EqualValues(3, lines[0]["nodes"])
+	assert.Contains(lines[0], slog.TimeKey)
+	assert.Contains(lines[0], slog.SourceKey)
+
+	assert.Equal("WARN", lines[1][slog.LevelKey])
+	assert.Equal("[REDACTED]", lines[1]["masterSecret"])
+	assert.Equal("[REDACTED]", lines[1]["initSecret"])
+	assert.NotContains(out.String(), "c2VjcmV0")
+}
+
+func TestParseFormat(t *testing.T) {
+	testCases := map[string]struct {
+		format     string
+		wantFormat Format
+		wantErr    bool
+	}{
+		"text":    {format: "text", wantFormat: FormatText},
+		"json":    {format: "json", wantFormat: FormatJSON},
+		"unknown": {format: "xml", wantErr: true},
+		"empty":   {format: "", wantErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			format, err := ParseFormat(tc.format)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tc.wantFormat, format)
+		})
+	}
+}
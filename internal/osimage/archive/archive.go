@@ -8,14 +8,17 @@ SPDX-License-Identifier: AGPL-3.0-only
 package archive
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/url"
 	"time"
 
-	s3manager "github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/edgelesssys/constellation/v2/internal/api/versionsapi"
@@ -23,12 +26,25 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/staticupload"
 )
 
+const (
+	// defaultPartSize is the size of the parts OS images are uploaded in.
+	defaultPartSize = 64 * 1024 * 1024
+	// maxPartAttempts is how often uploading a single part is attempted before the upload fails.
+	maxPartAttempts = 5
+	// defaultRetryInterval is the time to wait before retrying a failed part upload.
+	defaultRetryInterval = 5 * time.Second
+)
+
 // Archivist uploads OS images to S3.
 type Archivist struct {
 	uploadClient      uploadClient
 	uploadClientClose func(ctx context.Context) error
 	// bucket is the name of the S3 bucket to use.
 	bucket string
+	// partSize is the size of the parts images are uploaded in.
+	partSize int
+	// retryInterval is the time to wait before retrying a failed part upload.
+	retryInterval time.Duration
 
 	log *slog.Logger
 }
@@ -50,6 +66,8 @@ func New(ctx context.Context, region, bucket, distributionID string, log *slog.L
 		uploadClient:      staticUploadClient,
 		uploadClientClose: staticUploadClientClose,
 		bucket:            bucket,
+		partSize:          defaultPartSize,
+		retryInterval:     defaultRetryInterval,
 		log:               log,
 	}
 	archivistClose := func(ctx context.Context) error {
@@ -69,23 +87,222 @@ func (a *Archivist) Close(ctx context.Context) error {
 }
 
 // Archive reads the OS image in img and uploads it as key.
+// The image is uploaded in parts, and uploading a part is retried on failure.
+// If a previous upload of the image was interrupted, parts that were already uploaded are not uploaded again.
 func (a *Archivist) Archive(ctx context.Context, version versionsapi.Version, csp, attestationVariant string, img io.Reader) (string, error) {
 	key, err := url.JoinPath(version.ArtifactPath(versionsapi.APIV1), version.Kind().String(), "csp", csp, attestationVariant, "image.raw")
 	if err != nil {
 		return "", err
 	}
 	a.log.Debug(fmt.Sprintf("Archiving OS image %q to s3://%s/%s", fmt.Sprintf("%s %s %v", csp, attestationVariant, version.ShortPath()), a.bucket, key))
-	_, err = a.uploadClient.Upload(ctx, &s3.PutObjectInput{
-		Bucket:            &a.bucket,
-		Key:               &key,
-		Body:              img,
-		ChecksumAlgorithm: s3types.ChecksumAlgorithmSha256,
+	if err := a.uploadResumable(ctx, key, img); err != nil {
+		return "", err
+	}
+	return constants.CDNRepositoryURL + "/" + key, nil
+}
+
+// uploadResumable uploads img as key using an S3 multipart upload.
+// An unfinished multipart upload for key is resumed, if it exists.
+func (a *Archivist) uploadResumable(ctx context.Context, key string, img io.Reader) error {
+	uploadID, uploadedParts, err := a.resumeOrCreateUpload(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	totalSize := imageSize(img)
+	buf := make([]byte, a.partSize)
+	var completedParts []s3types.CompletedPart
+	var uploadedBytes int64
+	for partNumber := int32(1); ; partNumber++ {
+		n, err := io.ReadFull(img, buf)
+		if errors.Is(err, io.EOF) && partNumber > 1 {
+			break
+		}
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("reading OS image: %w", err)
+		}
+		part := buf[:n]
+		checksum := sha256.Sum256(part)
+		encodedChecksum := base64.StdEncoding.EncodeToString(checksum[:])
+
+		if uploaded, ok := uploadedParts[partNumber]; ok && uploaded.size == int64(n) && uploaded.checksum == encodedChecksum {
+			a.log.Debug(fmt.Sprintf("Part %d of OS image was already uploaded, skipping", partNumber))
+			completedParts = append(completedParts, uploaded.completedPart)
+		} else {
+			completedPart, err := a.uploadPart(ctx, key, uploadID, partNumber, part, encodedChecksum)
+			if err != nil {
+				return err
+			}
+			completedParts = append(completedParts, completedPart)
+		}
+
+		uploadedBytes += int64(n)
+		if totalSize > 0 {
+			a.log.Info(fmt.Sprintf("Archived %d of %d bytes of OS image (%d%%)", uploadedBytes, totalSize, uploadedBytes*100/totalSize))
+		} else {
+			a.log.Info(fmt.Sprintf("Archived %d bytes of OS image", uploadedBytes))
+		}
+		if n < len(buf) {
+			break
+		}
+	}
+
+	if _, err := a.uploadClient.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          &a.bucket,
+		Key:             &key,
+		UploadId:        &uploadID,
+		MultipartUpload: &s3types.CompletedMultipartUpload{Parts: completedParts},
+	}); err != nil {
+		return fmt.Errorf("completing upload of OS image: %w", err)
+	}
+	return nil
+}
+
+// resumeOrCreateUpload returns the ID of the most recent unfinished multipart upload for key and the parts uploaded so far.
+// If there is no unfinished upload, a new multipart upload is created.
+func (a *Archivist) resumeOrCreateUpload(ctx context.Context, key string) (string, map[int32]uploadedPart, error) {
+	var resumable *s3types.MultipartUpload
+	uploads := s3.NewListMultipartUploadsPaginator(a.uploadClient, &s3.ListMultipartUploadsInput{
+		Bucket: &a.bucket,
+		Prefix: &key,
+	})
+	for uploads.HasMorePages() {
+		page, err := uploads.NextPage(ctx)
+		if err != nil {
+			return "", nil, fmt.Errorf("listing unfinished uploads of OS image: %w", err)
+		}
+		for _, upload := range page.Uploads {
+			if upload.Key == nil || *upload.Key != key || upload.UploadId == nil {
+				continue
+			}
+			if resumable == nil || (upload.Initiated != nil && resumable.Initiated != nil && upload.Initiated.After(*resumable.Initiated)) {
+				resumable = &upload
+			}
+		}
+	}
+
+	if resumable == nil {
+		out, err := a.uploadClient.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+			Bucket:            &a.bucket,
+			Key:               &key,
+			ChecksumAlgorithm: s3types.ChecksumAlgorithmSha256,
+		})
+		if err != nil {
+			return "", nil, fmt.Errorf("creating upload of OS image: %w", err)
+		}
+		if out.UploadId == nil {
+			return "", nil, errors.New("creating upload of OS image: no upload ID returned")
+		}
+		return *out.UploadId, nil, nil
+	}
+
+	a.log.Info(fmt.Sprintf("Resuming unfinished upload of OS image to s3://%s/%s", a.bucket, key))
+	uploadedParts := make(map[int32]uploadedPart)
+	parts := s3.NewListPartsPaginator(a.uploadClient, &s3.ListPartsInput{
+		Bucket:   &a.bucket,
+		Key:      &key,
+		UploadId: resumable.UploadId,
 	})
-	return constants.CDNRepositoryURL + "/" + key, err
+	for parts.HasMorePages() {
+		page, err := parts.NextPage(ctx)
+		if err != nil {
+			return "", nil, fmt.Errorf("listing uploaded parts of OS image: %w", err)
+		}
+		for _, part := range page.Parts {
+			if part.PartNumber == nil || part.Size == nil || part.ChecksumSHA256 == nil {
+				continue
+			}
+			uploadedParts[*part.PartNumber] = uploadedPart{
+				size:     *part.Size,
+				checksum: *part.ChecksumSHA256,
+				completedPart: s3types.CompletedPart{
+					ETag:           part.ETag,
+					ChecksumSHA256: part.ChecksumSHA256,
+					PartNumber:     part.PartNumber,
+				},
+			}
+		}
+	}
+	return *resumable.UploadId, uploadedParts, nil
+}
+
+// uploadPart uploads a single part of a multipart upload, retrying on failure.
+func (a *Archivist) uploadPart(ctx context.Context, key, uploadID string, partNumber int32, part []byte, checksum string) (s3types.CompletedPart, error) {
+	var err error
+	for attempt := 1; attempt <= maxPartAttempts; attempt++ {
+		var out *s3.UploadPartOutput
+		out, err = a.uploadClient.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:            &a.bucket,
+			Key:               &key,
+			UploadId:          &uploadID,
+			PartNumber:        &partNumber,
+			Body:              bytes.NewReader(part),
+			ChecksumAlgorithm: s3types.ChecksumAlgorithmSha256,
+			ChecksumSHA256:    &checksum,
+		})
+		if err == nil {
+			return s3types.CompletedPart{
+				ETag:           out.ETag,
+				ChecksumSHA256: &checksum,
+				PartNumber:     &partNumber,
+			}, nil
+		}
+		if ctx.Err() != nil || attempt == maxPartAttempts {
+			break
+		}
+
+		a.log.Warn(fmt.Sprintf("Uploading part %d of OS image failed, retrying: %v", partNumber, err))
+		select {
+		case <-ctx.Done():
+		case <-time.After(a.retryInterval):
+		}
+	}
+	return s3types.CompletedPart{}, fmt.Errorf("uploading part %d of OS image: %w", partNumber, err)
+}
+
+// imageSize returns the size of img in bytes, or -1 if it can't be determined.
+func imageSize(img io.Reader) int64 {
+	seeker, ok := img.(io.Seeker)
+	if !ok {
+		return -1
+	}
+	current, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return -1
+	}
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return -1
+	}
+	if _, err := seeker.Seek(current, io.SeekStart); err != nil {
+		return -1
+	}
+	return end - current
+}
+
+// uploadedPart is a part of an unfinished multipart upload.
+type uploadedPart struct {
+	size          int64
+	checksum      string
+	completedPart s3types.CompletedPart
 }
 
 type uploadClient interface {
-	Upload(ctx context.Context, input *s3.PutObjectInput, opts ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error)
+	CreateMultipartUpload(
+		ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options),
+	) (*s3.CreateMultipartUploadOutput, error)
+	ListMultipartUploads(
+		ctx context.Context, params *s3.ListMultipartUploadsInput, optFns ...func(*s3.Options),
+	) (*s3.ListMultipartUploadsOutput, error)
+	ListParts(
+		ctx context.Context, params *s3.ListPartsInput, optFns ...func(*s3.Options),
+	) (*s3.ListPartsOutput, error)
+	UploadPart(
+		ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options),
+	) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(
+		ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options),
+	) (*s3.CompleteMultipartUploadOutput, error)
 }
 
 // CloseFunc is a function that closes the client.
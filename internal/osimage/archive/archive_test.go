@@ -0,0 +1,299 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package archive
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/edgelesssys/constellation/v2/internal/api/versionsapi"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
+	"github.com/edgelesssys/constellation/v2/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m, goleak.IgnoreAnyFunction("github.com/bazelbuild/rules_go/go/tools/bzltestutil.RegisterTimeoutHandler.func1"))
+}
+
+const testKey = "constellation/v1/ref/-/stream/stable/v2.0.0/image/csp/gcp/gcp-sev-es/image.raw"
+
+func TestArchive(t *testing.T) {
+	// with a part size of 4 bytes, the image is uploaded in 3 parts
+	img := []byte("0123456789")
+
+	testCases := map[string]struct {
+		img           []byte
+		existingParts map[int32][]byte
+		failures      map[int32]int
+		completeErr   error
+		wantAttempts  map[int32]int
+		wantCreated   int
+		wantErr       bool
+	}{
+		"upload in parts": {
+			img:          img,
+			wantAttempts: map[int32]int{1: 1, 2: 1, 3: 1},
+			wantCreated:  1,
+		},
+		"part failing mid-chunk is retried": {
+			img:          img,
+			failures:     map[int32]int{2: 2},
+			wantAttempts: map[int32]int{1: 1, 2: 3, 3: 1},
+			wantCreated:  1,
+		},
+		"part failing too often": {
+			img:          img,
+			failures:     map[int32]int{2: maxPartAttempts},
+			wantAttempts: map[int32]int{1: 1, 2: maxPartAttempts},
+			wantCreated:  1,
+			wantErr:      true,
+		},
+		"unfinished upload is resumed": {
+			img:           img,
+			existingParts: map[int32][]byte{1: []byte("0123"), 2: []byte("4567")},
+			wantAttempts:  map[int32]int{3: 1},
+		},
+		"changed parts of unfinished upload are uploaded again": {
+			img:           img,
+			existingParts: map[int32][]byte{1: []byte("0123"), 2: []byte("abcd")},
+			wantAttempts:  map[int32]int{2: 1, 3: 1},
+		},
+		"image size is a multiple of the part size": {
+			img:          []byte("01234567"),
+			wantAttempts: map[int32]int{1: 1, 2: 1},
+			wantCreated:  1,
+		},
+		"empty image": {
+			img:          []byte{},
+			wantAttempts: map[int32]int{1: 1},
+			wantCreated:  1,
+		},
+		"completing upload fails": {
+			img:          img,
+			completeErr:  errors.New("failed"),
+			wantAttempts: map[int32]int{1: 1, 2: 1, 3: 1},
+			wantCreated:  1,
+			wantErr:      true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			client := newFakeMultipartClient()
+			if tc.existingParts != nil {
+				client.uploads["existing-upload"] = &fakeUpload{key: testKey, parts: tc.existingParts}
+			}
+			client.failures = tc.failures
+			client.completeErr = tc.completeErr
+			archivist := newTestArchivist(t, client)
+
+			url, err := archivist.Archive(context.Background(), testVersion(t), "gcp", "gcp-sev-es", bytes.NewReader(tc.img))
+
+			assert.Equal(tc.wantAttempts, client.attempts)
+			assert.Equal(tc.wantCreated, client.created)
+			if tc.wantErr {
+				assert.Error(err)
+				assert.Empty(client.objects)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(constants.CDNRepositoryURL+"/"+testKey, url)
+			assert.Equal(tc.img, client.objects[testKey])
+		})
+	}
+}
+
+func TestArchiveRerunContinuesUpload(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	img := []byte("0123456789")
+	client := newFakeMultipartClient()
+	archivist := newTestArchivist(t, client)
+
+	// the first run fails on the last part
+	client.failures = map[int32]int{3: maxPartAttempts}
+	_, err := archivist.Archive(context.Background(), testVersion(t), "gcp", "gcp-sev-es", bytes.NewReader(img))
+	require.Error(err)
+	assert.Equal(map[int32]int{1: 1, 2: 1, 3: maxPartAttempts}, client.attempts)
+
+	// the second run only uploads the missing part
+	client.attempts = map[int32]int{}
+	_, err = archivist.Archive(context.Background(), testVersion(t), "gcp", "gcp-sev-es", bytes.NewReader(img))
+	require.NoError(err)
+	assert.Equal(map[int32]int{3: 1}, client.attempts)
+	assert.Equal(1, client.created)
+	assert.Equal(img, client.objects[testKey])
+}
+
+func newTestArchivist(t *testing.T, client *fakeMultipartClient) *Archivist {
+	return &Archivist{
+		uploadClient:  client,
+		bucket:        "test-bucket",
+		partSize:      4,
+		retryInterval: time.Millisecond,
+		log:           logger.NewTest(t),
+	}
+}
+
+func testVersion(t *testing.T) versionsapi.Version {
+	version, err := versionsapi.NewVersion("-", "stable", "v2.0.0", versionsapi.VersionKindImage)
+	require.NoError(t, err)
+	return version
+}
+
+// fakeMultipartClient is an in-memory implementation of S3 multipart uploads.
+type fakeMultipartClient struct {
+	uploads map[string]*fakeUpload
+	objects map[string][]byte
+	created int
+	// failures is the number of times uploading a part fails, by part number.
+	// A failing upload reads half of the part before returning an error.
+	failures    map[int32]int
+	attempts    map[int32]int
+	completeErr error
+}
+
+type fakeUpload struct {
+	key   string
+	parts map[int32][]byte
+}
+
+func newFakeMultipartClient() *fakeMultipartClient {
+	return &fakeMultipartClient{
+		uploads:  map[string]*fakeUpload{},
+		objects:  map[string][]byte{},
+		attempts: map[int32]int{},
+	}
+}
+
+func (c *fakeMultipartClient) CreateMultipartUpload(
+	_ context.Context, params *s3.CreateMultipartUploadInput, _ ...func(*s3.Options),
+) (*s3.CreateMultipartUploadOutput, error) {
+	c.created++
+	uploadID := fmt.Sprintf("upload-%d", c.created)
+	c.uploads[uploadID] = &fakeUpload{key: *params.Key, parts: map[int32][]byte{}}
+	return &s3.CreateMultipartUploadOutput{UploadId: &uploadID}, nil
+}
+
+func (c *fakeMultipartClient) ListMultipartUploads(
+	_ context.Context, params *s3.ListMultipartUploadsInput, _ ...func(*s3.Options),
+) (*s3.ListMultipartUploadsOutput, error) {
+	out := &s3.ListMultipartUploadsOutput{}
+	for uploadID, upload := range c.uploads {
+		if upload.key != *params.Prefix {
+			continue
+		}
+		out.Uploads = append(out.Uploads, s3types.MultipartUpload{
+			Key:       &upload.key,
+			UploadId:  &uploadID,
+			Initiated: ptr(time.Now()),
+		})
+	}
+	return out, nil
+}
+
+func (c *fakeMultipartClient) ListParts(
+	_ context.Context, params *s3.ListPartsInput, _ ...func(*s3.Options),
+) (*s3.ListPartsOutput, error) {
+	upload, ok := c.uploads[*params.UploadId]
+	if !ok {
+		return nil, errors.New("upload not found")
+	}
+	out := &s3.ListPartsOutput{}
+	for partNumber, data := range upload.parts {
+		out.Parts = append(out.Parts, s3types.Part{
+			PartNumber:     &partNumber,
+			Size:           ptr(int64(len(data))),
+			ChecksumSHA256: ptr(checksum(data)),
+			ETag:           ptr(checksum(data)),
+		})
+	}
+	return out, nil
+}
+
+func (c *fakeMultipartClient) UploadPart(
+	_ context.Context, params *s3.UploadPartInput, _ ...func(*s3.Options),
+) (*s3.UploadPartOutput, error) {
+	c.attempts[*params.PartNumber]++
+	upload, ok := c.uploads[*params.UploadId]
+	if !ok {
+		return nil, errors.New("upload not found")
+	}
+
+	if c.failures[*params.PartNumber] > 0 {
+		c.failures[*params.PartNumber]--
+		size := params.Body.(*bytes.Reader).Len()
+		if _, err := io.CopyN(io.Discard, params.Body, int64(size/2)); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("connection reset")
+	}
+
+	data, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	if checksum(data) != *params.ChecksumSHA256 {
+		return nil, errors.New("checksum mismatch")
+	}
+	upload.parts[*params.PartNumber] = data
+	return &s3.UploadPartOutput{ETag: ptr(checksum(data))}, nil
+}
+
+func (c *fakeMultipartClient) CompleteMultipartUpload(
+	_ context.Context, params *s3.CompleteMultipartUploadInput, _ ...func(*s3.Options),
+) (*s3.CompleteMultipartUploadOutput, error) {
+	if c.completeErr != nil {
+		return nil, c.completeErr
+	}
+	upload, ok := c.uploads[*params.UploadId]
+	if !ok {
+		return nil, errors.New("upload not found")
+	}
+
+	parts := params.MultipartUpload.Parts
+	if !sort.SliceIsSorted(parts, func(i, j int) bool { return *parts[i].PartNumber < *parts[j].PartNumber }) {
+		return nil, errors.New("parts are not in ascending order")
+	}
+	object := []byte{}
+	for _, part := range parts {
+		data, ok := upload.parts[*part.PartNumber]
+		if !ok || *part.ETag != checksum(data) {
+			return nil, fmt.Errorf("invalid part %d", *part.PartNumber)
+		}
+		object = append(object, data...)
+	}
+	c.objects[upload.key] = object
+	delete(c.uploads, *params.UploadId)
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -60,6 +60,57 @@ func (r *IntervalRetrier) Do(ctx context.Context) error {
 	}
 }
 
+// BackoffRetrier retries a call with an exponential backoff. The call is defined in the Doer property.
+type BackoffRetrier struct {
+	interval    time.Duration
+	maxInterval time.Duration
+	doer        Doer
+	clock       clock.Clock
+	retriable   func(error) bool
+}
+
+// NewBackoffRetrier returns a new BackoffRetrier.
+// The wait time before the first retry is interval. It doubles with every retry, up to maxInterval.
+// The optional clock is used for testing.
+func NewBackoffRetrier(doer Doer, interval, maxInterval time.Duration, retriable func(error) bool, optClock ...clock.Clock) *BackoffRetrier {
+	var clock clock.Clock = clock.RealClock{}
+	if len(optClock) > 0 {
+		clock = optClock[0]
+	}
+
+	return &BackoffRetrier{
+		interval:    interval,
+		maxInterval: max(interval, maxInterval),
+		doer:        doer,
+		clock:       clock,
+		retriable:   retriable,
+	}
+}
+
+// Do retries performing a call until it succeeds, returns a permanent error or the context is cancelled.
+func (r *BackoffRetrier) Do(ctx context.Context) error {
+	interval := r.interval
+	for {
+		err := r.doer.Do(ctx)
+		if err == nil {
+			return nil
+		}
+
+		if !r.retriable(err) {
+			return err
+		}
+
+		timer := r.clock.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C():
+		}
+		interval = min(2*interval, r.maxInterval)
+	}
+}
+
 // Doer does something and returns an error.
 type Doer interface {
 	// Do performs an operation.
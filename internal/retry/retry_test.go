@@ -89,6 +89,84 @@ func TestDo(t *testing.T) {
 	}
 }
 
+func TestBackoffDo(t *testing.T) {
+	testCases := map[string]struct {
+		cancel  bool
+		errors  []error
+		wantErr error
+	}{
+		"no error": {
+			errors: []error{
+				nil,
+			},
+		},
+		"permanent error": {
+			errors: []error{
+				errors.New("error"),
+			},
+			wantErr: errors.New("error"),
+		},
+		"service unavailable then success": {
+			errors: []error{
+				errors.New("retry me"),
+				errors.New("retry me"),
+				errors.New("retry me"),
+				errors.New("retry me"),
+				nil,
+			},
+		},
+		"service unavailable then permanent error": {
+			errors: []error{
+				errors.New("retry me"),
+				errors.New("error"),
+			},
+			wantErr: errors.New("error"),
+		},
+		"cancellation works": {
+			cancel: true,
+			errors: []error{
+				errors.New("retry me"),
+			},
+			wantErr: context.Canceled,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			doer := newStubDoer()
+			clock := testclock.NewFakeClock(time.Now())
+			retrier := NewBackoffRetrier(doer, time.Second, 3*time.Second, isRetriable, clock)
+			retrierResult := make(chan error, 1)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			go func() { retrierResult <- retrier.Do(ctx) }()
+			// The wait time doubles with every retry and is capped at the maximum interval.
+			wantIntervals := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}
+			for i, err := range tc.errors {
+				doer.errC <- err
+				if err == nil || !isRetriable(err) {
+					break
+				}
+				for !clock.HasWaiters() {
+					time.Sleep(time.Millisecond)
+				}
+				// Stepping less than the current interval must not trigger the next attempt.
+				clock.Step(wantIntervals[i] - time.Millisecond)
+				assert.True(clock.HasWaiters())
+				clock.Step(time.Millisecond)
+			}
+
+			if tc.cancel {
+				cancel()
+			}
+
+			assert.Equal(tc.wantErr, <-retrierResult)
+		})
+	}
+}
+
 type stubDoer struct {
 	errC chan error
 }
@@ -0,0 +1,80 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package staticupload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// CreateMultipartUpload initiates a multipart upload.
+func (c *Client) CreateMultipartUpload(
+	ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options),
+) (*s3.CreateMultipartUploadOutput, error) {
+	return c.multipartClient.CreateMultipartUpload(ctx, params, optFns...)
+}
+
+// ListMultipartUploads lists multipart uploads that were initiated but not yet completed or aborted.
+func (c *Client) ListMultipartUploads(
+	ctx context.Context, params *s3.ListMultipartUploadsInput, optFns ...func(*s3.Options),
+) (*s3.ListMultipartUploadsOutput, error) {
+	return c.multipartClient.ListMultipartUploads(ctx, params, optFns...)
+}
+
+// ListParts lists the parts that have been uploaded for a multipart upload.
+func (c *Client) ListParts(
+	ctx context.Context, params *s3.ListPartsInput, optFns ...func(*s3.Options),
+) (*s3.ListPartsOutput, error) {
+	return c.multipartClient.ListParts(ctx, params, optFns...)
+}
+
+// UploadPart uploads a part of a multipart upload.
+func (c *Client) UploadPart(
+	ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options),
+) (*s3.UploadPartOutput, error) {
+	return c.multipartClient.UploadPart(ctx, params, optFns...)
+}
+
+// CompleteMultipartUpload completes a multipart upload and invalidates the CDN cache.
+// The error will be of type InvalidationError if the CDN cache could not be invalidated.
+func (c *Client) CompleteMultipartUpload(
+	ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options),
+) (*s3.CompleteMultipartUploadOutput, error) {
+	if params == nil || params.Key == nil {
+		return nil, errors.New("key is not set")
+	}
+	output, err := c.multipartClient.CompleteMultipartUpload(ctx, params, optFns...)
+	if err != nil {
+		return nil, fmt.Errorf("completing multipart upload: %w", err)
+	}
+
+	if err := c.invalidate(ctx, []string{*params.Key}); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+type multipartClient interface {
+	CreateMultipartUpload(
+		ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options),
+	) (*s3.CreateMultipartUploadOutput, error)
+	ListMultipartUploads(
+		ctx context.Context, params *s3.ListMultipartUploadsInput, optFns ...func(*s3.Options),
+	) (*s3.ListMultipartUploadsOutput, error)
+	ListParts(
+		ctx context.Context, params *s3.ListPartsInput, optFns ...func(*s3.Options),
+	) (*s3.ListPartsOutput, error)
+	UploadPart(
+		ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options),
+	) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(
+		ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options),
+	) (*s3.CompleteMultipartUploadOutput, error)
+}
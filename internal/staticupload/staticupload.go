@@ -32,12 +32,13 @@ import (
 // Client is a static file uploader/updater/remover for the CDN / static API.
 // It has the same interface as the S3 uploader.
 type Client struct {
-	mux            sync.Mutex
-	cdnClient      cdnClient
-	uploadClient   uploadClient
-	s3Client       objectStorageClient
-	distributionID string
-	bucketID       string
+	mux             sync.Mutex
+	cdnClient       cdnClient
+	uploadClient    uploadClient
+	s3Client        objectStorageClient
+	multipartClient multipartClient
+	distributionID  string
+	bucketID        string
 
 	cacheInvalidationStrategy    CacheInvalidationStrategy
 	cacheInvalidationWaitTimeout time.Duration
@@ -115,6 +116,7 @@ func New(ctx context.Context, config Config, log *slog.Logger) (*Client, CloseFu
 	client := &Client{
 		cdnClient:                    cdnClient,
 		s3Client:                     s3Client,
+		multipartClient:              s3Client,
 		uploadClient:                 uploadClient,
 		distributionID:               config.DistributionID,
 		cacheInvalidationStrategy:    config.CacheInvalidationStrategy,
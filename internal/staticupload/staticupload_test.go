@@ -130,6 +130,68 @@ func TestUpload(t *testing.T) {
 	}
 }
 
+func TestCompleteMultipartUpload(t *testing.T) {
+	testCases := map[string]struct {
+		in                        *s3.CompleteMultipartUploadInput
+		cacheInvalidationStrategy CacheInvalidationStrategy
+		completeFails             bool
+		wantInvalidations         int
+		wantErr                   bool
+		wantDirtyKeys             []string
+	}{
+		"eager invalidation": {
+			in:                        &s3.CompleteMultipartUploadInput{Key: ptr("test-key")},
+			cacheInvalidationStrategy: CacheInvalidateEager,
+			wantInvalidations:         1,
+		},
+		"lazy invalidation": {
+			in:                        &s3.CompleteMultipartUploadInput{Key: ptr("test-key")},
+			cacheInvalidationStrategy: CacheInvalidateBatchOnFlush,
+			wantDirtyKeys:             []string{"test-key"},
+		},
+		"completing fails": {
+			in:            &s3.CompleteMultipartUploadInput{Key: ptr("test-key")},
+			completeFails: true,
+			wantErr:       true,
+		},
+		"key is nil": {
+			in:      &s3.CompleteMultipartUploadInput{},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			cdnClient := &fakeCDNClient{}
+			multipartClient := &stubMultipartClient{}
+			if tc.completeFails {
+				multipartClient.err = errors.New("complete failed")
+			}
+
+			client := &Client{
+				cdnClient:                 cdnClient,
+				multipartClient:           multipartClient,
+				distributionID:            "test-distribution-id",
+				cacheInvalidationStrategy: tc.cacheInvalidationStrategy,
+				logger:                    logger.NewTest(t),
+			}
+			_, err := client.CompleteMultipartUpload(context.Background(), tc.in)
+			if tc.wantErr {
+				assert.Error(err)
+				assert.Zero(cdnClient.createInvalidationCounter)
+				return
+			}
+
+			require.NoError(err)
+			assert.Equal(tc.wantDirtyKeys, client.dirtyKeys)
+			assert.Equal(tc.wantInvalidations, cdnClient.createInvalidationCounter)
+		})
+	}
+}
+
 func TestDeleteObject(t *testing.T) {
 	newObjectInput := func(nilInput, nilKey bool) *s3.DeleteObjectInput {
 		if nilInput {
@@ -581,3 +643,41 @@ func (s *stubObjectStorageClient) ListObjectsV2(
 ) (*s3.ListObjectsV2Output, error) {
 	return nil, nil
 }
+
+type stubMultipartClient struct {
+	err error
+}
+
+// currently not needed so no-Op.
+func (s *stubMultipartClient) CreateMultipartUpload(
+	_ context.Context, _ *s3.CreateMultipartUploadInput, _ ...func(*s3.Options),
+) (*s3.CreateMultipartUploadOutput, error) {
+	return nil, nil
+}
+
+// currently not needed so no-Op.
+func (s *stubMultipartClient) ListMultipartUploads(
+	_ context.Context, _ *s3.ListMultipartUploadsInput, _ ...func(*s3.Options),
+) (*s3.ListMultipartUploadsOutput, error) {
+	return nil, nil
+}
+
+// currently not needed so no-Op.
+func (s *stubMultipartClient) ListParts(
+	_ context.Context, _ *s3.ListPartsInput, _ ...func(*s3.Options),
+) (*s3.ListPartsOutput, error) {
+	return nil, nil
+}
+
+// currently not needed so no-Op.
+func (s *stubMultipartClient) UploadPart(
+	_ context.Context, _ *s3.UploadPartInput, _ ...func(*s3.Options),
+) (*s3.UploadPartOutput, error) {
+	return nil, nil
+}
+
+func (s *stubMultipartClient) CompleteMultipartUpload(
+	_ context.Context, _ *s3.CompleteMultipartUploadInput, _ ...func(*s3.Options),
+) (*s3.CompleteMultipartUploadOutput, error) {
+	return &s3.CompleteMultipartUploadOutput{}, s.err
+}
@@ -243,6 +243,18 @@ func NotEmptySlice[T comparable](s []T) *Constraint {
 	}
 }
 
+// SliceLength is a constraint that checks if slice s has exactly the given length.
+func SliceLength[T comparable](s []T, length int) *Constraint {
+	return &Constraint{
+		Satisfied: func() *TreeError {
+			if len(s) != length {
+				return NewErrorTree(fmt.Errorf("must have length %d, got %d", length, len(s)))
+			}
+			return nil
+		},
+	}
+}
+
 // All is a constraint that checks if all elements of s satisfy the constraint c.
 // The constraint should be parametric in regards to the index of the element in s,
 // as well as the element itself.
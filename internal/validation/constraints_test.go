@@ -164,6 +164,45 @@ func TestNotEmptySlice(t *testing.T) {
 	}
 }
 
+func TestSliceLength(t *testing.T) {
+	testCases := map[string]struct {
+		s       []any
+		length  int
+		wantErr bool
+	}{
+		"valid": {
+			s:      []any{1, 2},
+			length: 2,
+		},
+		"too short": {
+			s:       []any{1},
+			length:  2,
+			wantErr: true,
+		},
+		"too long": {
+			s:       []any{1, 2, 3},
+			length:  2,
+			wantErr: true,
+		},
+		"nil": {
+			s:       nil,
+			length:  2,
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := SliceLength(tc.s, tc.length).Satisfied()
+			if tc.wantErr {
+				require.Error(t, err)
+			} else {
+				require.Nil(t, err)
+			}
+		})
+	}
+}
+
 func TestAll(t *testing.T) {
 	c := func(_ int, s string) *Constraint {
 		return Equal(s, "abc")
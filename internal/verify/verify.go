@@ -46,6 +46,8 @@ type Report struct {
 	CertChain            []Certificate `json:"cert_chain"`
 	*AzureReportAddition `json:"azure,omitempty"`
 	*AWSReportAddition   `json:"aws,omitempty"`
+	// VCEKSource is the source of the VCEK certificate the report was verified with, if known.
+	VCEKSource snp.VCEKSource `json:"vcek_source,omitempty"`
 }
 
 // AzureReportAddition contains attestation report data specific to Azure.
@@ -131,7 +133,7 @@ func getCertChain(cfg config.AttestationCfg) ([]byte, error) {
 	}
 
 	if awsCfg.AMDSigningKey.Equal(config.Certificate{}) {
-		certs, err := trust.GetProductChain(kds.ProductLine(snp.Product()), abi.VlekReportSigner, trust.DefaultHTTPSGetter())
+		certs, err := trust.GetProductChain(kds.ProductLine(snp.Product()), abi.VlekReportSigner, snp.NewKDSGetter(trust.DefaultHTTPSGetter(), (*x509.Certificate)(&awsCfg.AMDRootKey)))
 		if err != nil {
 			return nil, fmt.Errorf("getting product certificate chain: %w", err)
 		}
@@ -171,6 +173,10 @@ func (r *Report) FormatString(b *strings.Builder) (string, error) {
 		return "", fmt.Errorf("building certificate chain string: %w", err)
 	}
 
+	if r.VCEKSource != "" {
+		writeIndentfln(b, 1, "VCEK certificate source: %s", r.VCEKSource)
+	}
+
 	r.SNPReport.formatString(b)
 	if r.AzureReportAddition != nil {
 		if err := r.AzureReportAddition.MAAToken.formatString(b); err != nil {
@@ -326,6 +332,11 @@ type TCBVersion struct {
 	Spl7       uint8 `json:"spl7"`
 }
 
+// atLeast returns true if all SVNs checked against the attestation config are at least the ones of other.
+func (t TCBVersion) atLeast(other TCBVersion) bool {
+	return t.Bootloader >= other.Bootloader && t.TEE >= other.TEE && t.SNP >= other.SNP && t.Microcode >= other.Microcode
+}
+
 // formatString builds a string representation of a TCB version that is inteded for console output.
 func (t *TCBVersion) formatString(b *strings.Builder) {
 	writeIndentfln(b, 3, "Secure Processor bootloader SVN: %d", t.Bootloader)
@@ -403,6 +414,118 @@ type SNPReport struct {
 	Signature            []byte       `json:"signature"`
 }
 
+// ChipID parses a marshalled SNP report and returns the ID of the chip that generated it.
+func ChipID(reportBytes []byte) ([]byte, error) {
+	report, err := newSNPReport(reportBytes)
+	if err != nil {
+		return nil, err
+	}
+	return report.ChipID, nil
+}
+
+// DebugEnabled parses a marshalled SNP report and returns true if its guest policy allows debugging the guest.
+func DebugEnabled(reportBytes []byte) (bool, error) {
+	report, err := newSNPReport(reportBytes)
+	if err != nil {
+		return false, err
+	}
+	return report.PolicyDebug, nil
+}
+
+// ReportVersion parses a marshalled SNP report and returns the version of its format.
+func ReportVersion(reportBytes []byte) (uint32, error) {
+	report, err := newSNPReport(reportBytes)
+	if err != nil {
+		return 0, err
+	}
+	return report.Version, nil
+}
+
+// TCBReport compares the TCB versions of an SNP report with the minimum versions of an attestation config.
+type TCBReport struct {
+	ReportedTCB  TCBVersion `json:"reported_tcb"`
+	LaunchTCB    TCBVersion `json:"launch_tcb"`
+	MinimumTCB   TCBVersion `json:"minimum_tcb"`
+	CurrentTCB   TCBVersion `json:"current_tcb"`
+	CommittedTCB TCBVersion `json:"committed_tcb"`
+}
+
+// NewTCBReport parses a marshalled SNP report and returns its TCB versions
+// together with the minimum versions configured in attestationCfg.
+func NewTCBReport(reportBytes []byte, attestationCfg config.AttestationCfg) (TCBReport, error) {
+	report, err := newSNPReport(reportBytes)
+	if err != nil {
+		return TCBReport{}, err
+	}
+
+	var minimum TCBVersion
+	switch cfg := attestationCfg.(type) {
+	case *config.AWSSEVSNP:
+		minimum = newMinimumTCBVersion(cfg.BootloaderVersion, cfg.TEEVersion, cfg.SNPVersion, cfg.MicrocodeVersion)
+	case *config.AzureSEVSNP:
+		minimum = newMinimumTCBVersion(cfg.BootloaderVersion, cfg.TEEVersion, cfg.SNPVersion, cfg.MicrocodeVersion)
+	case *config.GCPSEVSNP:
+		minimum = newMinimumTCBVersion(cfg.BootloaderVersion, cfg.TEEVersion, cfg.SNPVersion, cfg.MicrocodeVersion)
+	default:
+		return TCBReport{}, fmt.Errorf("attestation config of type %T has no TCB versions", attestationCfg)
+	}
+
+	return TCBReport{
+		ReportedTCB:  report.ReportedTCB,
+		LaunchTCB:    report.LaunchTCB,
+		MinimumTCB:   minimum,
+		CurrentTCB:   report.CurrentTCB,
+		CommittedTCB: report.CommittedTCB,
+	}, nil
+}
+
+// MeetsMinimum returns true if the reported and the launch TCB versions are at least the minimum versions.
+func (t *TCBReport) MeetsMinimum() bool {
+	return t.ReportedTCB.atLeast(t.MinimumTCB) && t.LaunchTCB.atLeast(t.MinimumTCB)
+}
+
+// InRecovery returns true if the platform is in the middle of a TCB update to the recovery target,
+// as it happens after a security errata: the reported or launch TCB versions are still below the minimum,
+// but the platform already runs firmware of at least the minimum and the target versions, which hasn't been committed yet.
+func (t *TCBReport) InRecovery(target TCBVersion) bool {
+	return !t.MeetsMinimum() &&
+		t.CurrentTCB.atLeast(t.MinimumTCB) &&
+		t.CurrentTCB.atLeast(target) &&
+		!t.CommittedTCB.atLeast(t.CurrentTCB)
+}
+
+func newMinimumTCBVersion(bootloader, tee, snp, microcode config.AttestationVersion[uint8]) TCBVersion {
+	return TCBVersion{
+		Bootloader: bootloader.Value,
+		TEE:        tee.Value,
+		SNP:        snp.Value,
+		Microcode:  microcode.Value,
+	}
+}
+
+// FormatString builds a string representation of the TCB report that is intended for console output.
+func (t *TCBReport) FormatString(b *strings.Builder) string {
+	components := []struct {
+		name                      string
+		reported, launch, minimum uint8
+	}{
+		{"Secure Processor bootloader SVN", t.ReportedTCB.Bootloader, t.LaunchTCB.Bootloader, t.MinimumTCB.Bootloader},
+		{"Secure Processor operating system SVN", t.ReportedTCB.TEE, t.LaunchTCB.TEE, t.MinimumTCB.TEE},
+		{"SEV-SNP firmware SVN", t.ReportedTCB.SNP, t.LaunchTCB.SNP, t.MinimumTCB.SNP},
+		{"Microcode SVN", t.ReportedTCB.Microcode, t.LaunchTCB.Microcode, t.MinimumTCB.Microcode},
+	}
+
+	writeIndentfln(b, 0, "TCB report:")
+	for _, c := range components {
+		status := "OK"
+		if c.reported < c.minimum || c.launch < c.minimum {
+			status = "below minimum"
+		}
+		writeIndentfln(b, 1, "%s: reported %d, launch %d, minimum %d (%s)", c.name, c.reported, c.launch, c.minimum, status)
+	}
+	return b.String()
+}
+
 // newSNPReport parses a marshalled SNP report and returns a SNPReport object.
 func newSNPReport(reportBytes []byte) (SNPReport, error) {
 	report, err := abi.ReportToProto(reportBytes)
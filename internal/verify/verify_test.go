@@ -6,12 +6,15 @@ SPDX-License-Identifier: AGPL-3.0-only
 package verify
 
 import (
+	"encoding/json"
 	"strings"
 	"testing"
 
+	"github.com/edgelesssys/constellation/v2/internal/attestation/snp"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/snp/testdata"
 	"github.com/edgelesssys/constellation/v2/internal/logger"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseCerts(t *testing.T) {
@@ -62,3 +65,114 @@ func TestParseCerts(t *testing.T) {
 		})
 	}
 }
+
+func TestReportVCEKSource(t *testing.T) {
+	testCases := map[string]struct {
+		source      snp.VCEKSource
+		wantJSON    string
+		wantDefault string
+	}{
+		"KDS": {
+			source:      snp.VCEKSourceKDS,
+			wantJSON:    `"vcek_source":"KDS"`,
+			wantDefault: "\tVCEK certificate source: KDS\n",
+		},
+		"THIM": {
+			source:      snp.VCEKSourceTHIM,
+			wantJSON:    `"vcek_source":"THIM"`,
+			wantDefault: "\tVCEK certificate source: THIM\n",
+		},
+		"unknown": {},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			reportSigner, err := newCertificates(vcekCert, testdata.AzureThimVCEK, logger.NewTest(t))
+			require.NoError(err)
+			report := Report{ReportSigner: reportSigner, VCEKSource: tc.source}
+
+			jsonBytes, err := json.Marshal(report)
+			require.NoError(err)
+			formatted, err := report.FormatString(&strings.Builder{})
+			require.NoError(err)
+
+			if tc.source == "" {
+				assert.NotContains(string(jsonBytes), "vcek_source")
+				assert.NotContains(formatted, "VCEK certificate source")
+				return
+			}
+			assert.Contains(string(jsonBytes), tc.wantJSON)
+			assert.Contains(formatted, tc.wantDefault)
+		})
+	}
+}
+
+func TestTCBReportRecovery(t *testing.T) {
+	minimum := TCBVersion{Bootloader: 3, TEE: 0, SNP: 8, Microcode: 115}
+	outdated := TCBVersion{Bootloader: 3, TEE: 0, SNP: 8, Microcode: 93}
+
+	testCases := map[string]struct {
+		report          TCBReport
+		target          TCBVersion
+		wantMeetMinimum bool
+		wantInRecovery  bool
+	}{
+		"up to date": {
+			report: TCBReport{
+				ReportedTCB: minimum, LaunchTCB: minimum, MinimumTCB: minimum,
+				CurrentTCB: minimum, CommittedTCB: minimum,
+			},
+			target:          minimum,
+			wantMeetMinimum: true,
+		},
+		"update to target in progress": {
+			report: TCBReport{
+				ReportedTCB: outdated, LaunchTCB: outdated, MinimumTCB: minimum,
+				CurrentTCB: minimum, CommittedTCB: outdated,
+			},
+			target:         minimum,
+			wantInRecovery: true,
+		},
+		"update committed but not reported": {
+			report: TCBReport{
+				ReportedTCB: outdated, LaunchTCB: outdated, MinimumTCB: minimum,
+				CurrentTCB: minimum, CommittedTCB: minimum,
+			},
+			target: minimum,
+		},
+		"update below target": {
+			report: TCBReport{
+				ReportedTCB: outdated, LaunchTCB: outdated, MinimumTCB: minimum,
+				CurrentTCB: minimum, CommittedTCB: outdated,
+			},
+			target: TCBVersion{Bootloader: 3, TEE: 0, SNP: 9, Microcode: 115},
+		},
+		"outdated firmware": {
+			report: TCBReport{
+				ReportedTCB: outdated, LaunchTCB: outdated, MinimumTCB: minimum,
+				CurrentTCB: outdated, CommittedTCB: outdated,
+			},
+			target: minimum,
+		},
+		"launch TCB below minimum": {
+			report: TCBReport{
+				ReportedTCB: minimum, LaunchTCB: outdated, MinimumTCB: minimum,
+				CurrentTCB: minimum, CommittedTCB: outdated,
+			},
+			target:         minimum,
+			wantInRecovery: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			assert.Equal(tc.wantMeetMinimum, tc.report.MeetsMinimum())
+			assert.Equal(tc.wantInRecovery, tc.report.InRecovery(tc.target))
+		})
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/edgelesssys/constellation/v2/internal/cloud/metadata"
 	"github.com/edgelesssys/constellation/v2/internal/cloud/openstack"
 	qemucloud "github.com/edgelesssys/constellation/v2/internal/cloud/qemu"
+	"github.com/edgelesssys/constellation/v2/internal/config"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/file"
 	"github.com/edgelesssys/constellation/v2/internal/grpc/atlscredentials"
@@ -72,7 +73,18 @@ func main() {
 		os.Exit(1)
 	}
 
-	certCacheClient := certcache.NewClient(log.WithGroup("certcache"), kubeClient, attVariant)
+	attestationConfig, err := handler.Read(filepath.Join(constants.ServiceBasePath, constants.AttestationConfigFilename))
+	if err != nil {
+		log.With(slog.Any("error", err)).Error("Failed to read attestation config")
+		os.Exit(1)
+	}
+	attCfg, err := config.UnmarshalAttestationConfig(attestationConfig, attVariant)
+	if err != nil {
+		log.With(slog.Any("error", err)).Error("Failed to parse attestation config")
+		os.Exit(1)
+	}
+
+	certCacheClient := certcache.NewClient(log.WithGroup("certcache"), kubeClient, attVariant, certcache.TrustedARK(attCfg))
 	cachedCerts, err := certCacheClient.CreateCertChainCache(context.Background())
 	if err != nil {
 		log.With(slog.Any("error", err)).Error("Failed to create certificate chain cache")
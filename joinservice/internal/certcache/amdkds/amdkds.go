@@ -17,19 +17,21 @@ import (
 
 // KDSClient is a client for interacting with the AMD KDS.
 type KDSClient struct {
-	getter trust.HTTPSGetter
+	getter      trust.HTTPSGetter
+	productLine string
 }
 
-// NewKDSClient creates a new KDS Client.
-func NewKDSClient(getter trust.HTTPSGetter) *KDSClient {
+// NewKDSClient creates a new KDS Client retrieving the certificate chains of the given product line, e.g., "Milan".
+func NewKDSClient(getter trust.HTTPSGetter, productLine string) *KDSClient {
 	return &KDSClient{
-		getter: getter,
+		getter:      getter,
+		productLine: productLine,
 	}
 }
 
 // CertChain queries the AMD KDS for the certificate chain for given signing type (VCEK / VLEK).
 func (c *KDSClient) CertChain(signingType abi.ReportSigner) (ask, ark *x509.Certificate, err error) {
-	askark, err := trust.GetProductChain("Milan", signingType, c.getter)
+	askark, err := trust.GetProductChain(c.productLine, signingType, c.getter)
 	if err != nil {
 		return nil, nil, fmt.Errorf("retrieving certificate chain: %w", err)
 	}
@@ -20,28 +20,42 @@ import (
 
 func TestCertChain(t *testing.T) {
 	testCases := map[string]struct {
-		getter  *stubGetter
-		wantErr bool
+		getter      *stubGetter
+		productLine string
+		wantURL     string
+		wantErr     bool
 	}{
 		"success": {
 			getter: &stubGetter{
 				log: logger.NewTest(t),
 				ret: testdata.CertChain,
 			},
+			productLine: "Milan",
+			wantURL:     "https://kdsintf.amd.com/vcek/v1/Milan/cert_chain",
+		},
+		"chain of Genoa is requested": {
+			getter: &stubGetter{
+				log: logger.NewTest(t),
+				ret: testdata.CertChain,
+			},
+			productLine: "Genoa",
+			wantURL:     "https://kdsintf.amd.com/vcek/v1/Genoa/cert_chain",
 		},
 		"getter error": {
 			getter: &stubGetter{
 				log: logger.NewTest(t),
 				err: assert.AnError,
 			},
-			wantErr: true,
+			productLine: "Milan",
+			wantErr:     true,
 		},
 		"empty cert chain": {
 			getter: &stubGetter{
 				log: logger.NewTest(t),
 				ret: nil,
 			},
-			wantErr: true,
+			productLine: "Milan",
+			wantErr:     true,
 		},
 	}
 
@@ -52,13 +66,14 @@ func TestCertChain(t *testing.T) {
 
 			assert := assert.New(t)
 
-			kdsClient := NewKDSClient(tc.getter)
+			kdsClient := NewKDSClient(tc.getter, tc.productLine)
 
-			_, _, err := kdsClient.CertChain(abi.NoneReportSigner)
+			_, _, err := kdsClient.CertChain(abi.VcekReportSigner)
 			if tc.wantErr {
 				assert.Error(err)
 			} else {
 				assert.NoError(err)
+				assert.Equal(tc.wantURL, tc.getter.url)
 			}
 		})
 	}
@@ -68,9 +83,11 @@ type stubGetter struct {
 	log *slog.Logger
 	ret []byte
 	err error
+	url string
 }
 
 func (s *stubGetter) Get(url string) ([]byte, error) {
 	s.log.Debug(fmt.Sprintf("Request to %q", url))
+	s.url = url
 	return s.ret, s.err
 }
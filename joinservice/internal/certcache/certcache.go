@@ -12,8 +12,11 @@ import (
 	"crypto/x509"
 	"fmt"
 	"log/slog"
+	"strings"
 
+	"github.com/edgelesssys/constellation/v2/internal/attestation/snp"
 	"github.com/edgelesssys/constellation/v2/internal/attestation/variant"
+	"github.com/edgelesssys/constellation/v2/internal/config"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/crypto"
 	"github.com/edgelesssys/constellation/v2/joinservice/internal/certcache/amdkds"
@@ -22,6 +25,26 @@ import (
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
+// defaultProductLine is the product line whose certificate chain is cached if no ARK is set in the attestation config.
+const defaultProductLine = "Milan"
+
+// TrustedARK returns the ARK set in the given attestation config, or nil if the config doesn't set one.
+func TrustedARK(cfg config.AttestationCfg) *x509.Certificate {
+	var ark config.Certificate
+	switch c := cfg.(type) {
+	case *config.AzureSEVSNP:
+		ark = c.AMDRootKey
+	case *config.AWSSEVSNP:
+		ark = c.AMDRootKey
+	case *config.GCPSEVSNP:
+		ark = c.AMDRootKey
+	}
+	if len(ark.Raw) == 0 {
+		return nil
+	}
+	return (*x509.Certificate)(&ark)
+}
+
 // Client is a client for interacting with the certificate chain cache.
 type Client struct {
 	log        *slog.Logger
@@ -31,8 +54,16 @@ type Client struct {
 }
 
 // NewClient creates a new CertCacheClient.
-func NewClient(log *slog.Logger, kubeClient kubeClient, attVariant variant.Variant) *Client {
-	kdsClient := amdkds.NewKDSClient(trust.DefaultHTTPSGetter())
+// trustedARK is the ARK set in the attestation config, or nil if none is set.
+// It selects the product line whose certificate chain is cached, and is trusted in addition to the ARKs pinned in the binary.
+func NewClient(log *slog.Logger, kubeClient kubeClient, attVariant variant.Variant, trustedARK *x509.Certificate) *Client {
+	productLine := defaultProductLine
+	if trustedARK != nil {
+		if arkProductLine, ok := strings.CutPrefix(trustedARK.Subject.CommonName, "ARK-"); ok {
+			productLine = arkProductLine
+		}
+	}
+	kdsClient := amdkds.NewKDSClient(snp.NewKDSGetter(trust.DefaultHTTPSGetter(), trustedARK), productLine)
 
 	return &Client{
 		attVariant: attVariant,
@@ -12,6 +12,7 @@ import (
 	"testing"
 
 	"github.com/edgelesssys/constellation/v2/internal/attestation/variant"
+	"github.com/edgelesssys/constellation/v2/internal/config"
 	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/crypto"
 	"github.com/edgelesssys/constellation/v2/internal/logger"
@@ -206,7 +207,7 @@ func TestGetCertChainCache(t *testing.T) {
 
 			ctx := context.Background()
 
-			c := NewClient(logger.NewTest(t), tc.kubeClient, variant.Dummy{})
+			c := NewClient(logger.NewTest(t), tc.kubeClient, variant.Dummy{}, nil)
 
 			ask, ark, err := c.getCertChainCache(ctx)
 			if tc.wantErr {
@@ -245,3 +246,37 @@ func (s *stubKubeClient) GetConfigMapData(_ context.Context, _ string, key strin
 func (s *stubKubeClient) UpdateConfigMap(context.Context, string, string, string) error {
 	return s.updateConfigMapErr
 }
+
+func TestTrustedARK(t *testing.T) {
+	ark, err := crypto.PemToX509Cert(testdata.Ark)
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		cfg     config.AttestationCfg
+		wantARK *x509.Certificate
+	}{
+		"ARK set in SEV-SNP config": {
+			cfg:     &config.AzureSEVSNP{AMDRootKey: config.Certificate(*ark)},
+			wantARK: ark,
+		},
+		"no ARK set": {
+			cfg: &config.AWSSEVSNP{},
+		},
+		"variant without ARK": {
+			cfg: &config.DummyCfg{},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			got := TrustedARK(tc.cfg)
+			if tc.wantARK == nil {
+				assert.Nil(got)
+				return
+			}
+			assert.Equal(tc.wantARK.Raw, got.Raw)
+		})
+	}
+}
@@ -9,9 +9,11 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net"
+	"strings"
 	"time"
 
 	"github.com/edgelesssys/constellation/v2/internal/attestation"
@@ -80,6 +82,8 @@ func (s *Server) Run(creds credentials.TransportCredentials, port string) error
 // - stateful disk encryption key.
 // - Kubernetes join token.
 // - measurement salt and secret, to mark the node as initialized.
+// - authorized keys of the debug access, if it is enabled.
+// - registry mirrors, if they are configured.
 // In addition, control plane nodes receive:
 // - a decryption key for CA certificates uploaded to the Kubernetes cluster.
 func (s *Server) IssueJoinTicket(ctx context.Context, req *joinproto.IssueJoinTicketRequest) (*joinproto.IssueJoinTicketResponse, error) {
@@ -121,6 +125,20 @@ func (s *Server) IssueJoinTicket(ctx context.Context, req *joinproto.IssueJoinTi
 		return nil, status.Errorf(codes.Internal, "getting components: %s", err)
 	}
 
+	log.Info(fmt.Sprintf("Querying %s ConfigMap for debug access", constants.InternalConfigMap))
+	debugAccessAuthorizedKeys, err := s.getDebugAccessAuthorizedKeys(ctx)
+	if err != nil {
+		log.With(slog.Any("error", err)).Error("Failed getting debug access authorized keys")
+		return nil, status.Errorf(codes.Internal, "getting debug access authorized keys: %s", err)
+	}
+
+	log.Info(fmt.Sprintf("Querying %s ConfigMap for registry mirrors", constants.InternalConfigMap))
+	registryMirrors, err := s.getRegistryMirrors(ctx)
+	if err != nil {
+		log.With(slog.Any("error", err)).Error("Failed getting registry mirrors")
+		return nil, status.Errorf(codes.Internal, "getting registry mirrors: %s", err)
+	}
+
 	log.Info("Creating signed kubelet certificate")
 	kubeletCert, err := s.ca.GetCertificate(req.CertificateRequest)
 	if err != nil {
@@ -158,15 +176,17 @@ func (s *Server) IssueJoinTicket(ctx context.Context, req *joinproto.IssueJoinTi
 
 	log.Info("IssueJoinTicket successful")
 	return &joinproto.IssueJoinTicketResponse{
-		StateDiskKey:             stateDiskKey,
-		MeasurementSalt:          s.measurementSalt,
-		MeasurementSecret:        measurementSecret,
-		ApiServerEndpoint:        kubeArgs.APIServerEndpoint,
-		Token:                    kubeArgs.Token,
-		DiscoveryTokenCaCertHash: kubeArgs.CACertHashes[0],
-		KubeletCert:              kubeletCert,
-		ControlPlaneFiles:        controlPlaneFiles,
-		KubernetesComponents:     components,
+		StateDiskKey:              stateDiskKey,
+		MeasurementSalt:           s.measurementSalt,
+		MeasurementSecret:         measurementSecret,
+		ApiServerEndpoint:         kubeArgs.APIServerEndpoint,
+		Token:                     kubeArgs.Token,
+		DiscoveryTokenCaCertHash:  kubeArgs.CACertHashes[0],
+		KubeletCert:               kubeletCert,
+		ControlPlaneFiles:         controlPlaneFiles,
+		KubernetesComponents:      components,
+		DebugAccessAuthorizedKeys: debugAccessAuthorizedKeys,
+		RegistryMirrors:           registryMirrors,
 	}, nil
 }
 
@@ -205,6 +225,45 @@ func (s *Server) getK8sComponentsConfigMapName(ctx context.Context) (string, err
 	return k8sComponentsRef, nil
 }
 
+// getDebugAccessAuthorizedKeys returns the authorized keys of the debug access the bootstrapper stored in the internal-config ConfigMap.
+// If debug access is disabled, no keys are returned.
+func (s *Server) getDebugAccessAuthorizedKeys(ctx context.Context) ([]string, error) {
+	authorizedKeys, err := s.kubeClient.GetConfigMapData(ctx, constants.InternalConfigMap, constants.DebugAccessAuthorizedKeysKey)
+	if err != nil {
+		return nil, err
+	}
+	if authorizedKeys == "" {
+		return nil, nil
+	}
+	return strings.Split(authorizedKeys, "\n"), nil
+}
+
+// getRegistryMirrors returns the registry mirrors the bootstrapper stored in the internal-config ConfigMap.
+func (s *Server) getRegistryMirrors(ctx context.Context) ([]*joinproto.RegistryMirror, error) {
+	registryMirrorsJSON, err := s.kubeClient.GetConfigMapData(ctx, constants.InternalConfigMap, constants.RegistryMirrorsKey)
+	if err != nil {
+		return nil, err
+	}
+	if registryMirrorsJSON == "" {
+		return nil, nil
+	}
+	var mirrors []registryMirror
+	if err := json.Unmarshal([]byte(registryMirrorsJSON), &mirrors); err != nil {
+		return nil, fmt.Errorf("unmarshaling registry mirrors: %w", err)
+	}
+	var res []*joinproto.RegistryMirror
+	for _, mirror := range mirrors {
+		res = append(res, &joinproto.RegistryMirror{Registry: mirror.Registry, Endpoints: mirror.Endpoints})
+	}
+	return res, nil
+}
+
+// registryMirror is a registry mirror in the format the bootstrapper stores it in the internal-config ConfigMap.
+type registryMirror struct {
+	Registry  string   `json:"registry"`
+	Endpoints []string `json:"endpoints"`
+}
+
 // joinTokenGetter returns Kubernetes bootstrap (join) tokens.
 type joinTokenGetter interface {
 	// GetJoinToken returns a bootstrap (join) token.
@@ -229,4 +288,5 @@ type kubeClient interface {
 	GetK8sComponentsRefFromNodeVersionCRD(ctx context.Context, nodeName string) (string, error)
 	GetComponents(ctx context.Context, configMapName string) (components.Components, error)
 	AddNodeToJoiningNodes(ctx context.Context, nodeName string, componentsHash string, isControlPlane bool) error
+	GetConfigMapData(ctx context.Context, name, key string) (string, error)
 }
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/edgelesssys/constellation/v2/internal/attestation"
+	"github.com/edgelesssys/constellation/v2/internal/constants"
 	"github.com/edgelesssys/constellation/v2/internal/logger"
 	"github.com/edgelesssys/constellation/v2/internal/versions/components"
 	"github.com/edgelesssys/constellation/v2/joinservice/joinproto"
@@ -55,6 +56,8 @@ func TestIssueJoinTicket(t *testing.T) {
 		ca                             stubCA
 		kubeClient                     stubKubeClient
 		missingComponentsReferenceFile bool
+		wantAuthorizedKeys             []string
+		wantRegistryMirrors            []*joinproto.RegistryMirror
 		wantErr                        bool
 	}{
 		"worker node": {
@@ -66,6 +69,64 @@ func TestIssueJoinTicket(t *testing.T) {
 			ca:         stubCA{cert: testCert, nodeName: "node"},
 			kubeClient: stubKubeClient{getComponentsVal: clusterComponents, getK8sComponentsRefFromNodeVersionCRDVal: "k8s-components-ref"},
 		},
+		"worker node with debug access": {
+			kubeadm: stubTokenGetter{token: testJoinToken},
+			kms: stubKeyGetter{dataKeys: map[string][]byte{
+				uuid:                                 testKey,
+				attestation.MeasurementSecretContext: measurementSecret,
+			}},
+			ca: stubCA{cert: testCert, nodeName: "node"},
+			kubeClient: stubKubeClient{
+				getComponentsVal:                         clusterComponents,
+				getK8sComponentsRefFromNodeVersionCRDVal: "k8s-components-ref",
+				configMapData:                            map[string]string{constants.DebugAccessAuthorizedKeysKey: "ssh-ed25519 AAAA key1\nssh-ed25519 AAAA key2"},
+			},
+			wantAuthorizedKeys: []string{"ssh-ed25519 AAAA key1", "ssh-ed25519 AAAA key2"},
+		},
+		"worker node with registry mirrors": {
+			kubeadm: stubTokenGetter{token: testJoinToken},
+			kms: stubKeyGetter{dataKeys: map[string][]byte{
+				uuid:                                 testKey,
+				attestation.MeasurementSecretContext: measurementSecret,
+			}},
+			ca: stubCA{cert: testCert, nodeName: "node"},
+			kubeClient: stubKubeClient{
+				getComponentsVal:                         clusterComponents,
+				getK8sComponentsRefFromNodeVersionCRDVal: "k8s-components-ref",
+				configMapData: map[string]string{
+					constants.RegistryMirrorsKey: `[{"registry":"docker.io","endpoints":["https://mirror.example.com"]}]`,
+				},
+			},
+			wantRegistryMirrors: []*joinproto.RegistryMirror{{Registry: "docker.io", Endpoints: []string{"https://mirror.example.com"}}},
+		},
+		"invalid registry mirrors": {
+			kubeadm: stubTokenGetter{token: testJoinToken},
+			kms: stubKeyGetter{dataKeys: map[string][]byte{
+				uuid:                                 testKey,
+				attestation.MeasurementSecretContext: measurementSecret,
+			}},
+			ca: stubCA{cert: testCert, nodeName: "node"},
+			kubeClient: stubKubeClient{
+				getComponentsVal:                         clusterComponents,
+				getK8sComponentsRefFromNodeVersionCRDVal: "k8s-components-ref",
+				configMapData:                            map[string]string{constants.RegistryMirrorsKey: "invalid"},
+			},
+			wantErr: true,
+		},
+		"getting internal config fails": {
+			kubeadm: stubTokenGetter{token: testJoinToken},
+			kms: stubKeyGetter{dataKeys: map[string][]byte{
+				uuid:                                 testKey,
+				attestation.MeasurementSecretContext: measurementSecret,
+			}},
+			ca: stubCA{cert: testCert, nodeName: "node"},
+			kubeClient: stubKubeClient{
+				getComponentsVal:                         clusterComponents,
+				getK8sComponentsRefFromNodeVersionCRDVal: "k8s-components-ref",
+				getConfigMapDataErr:                      someErr,
+			},
+			wantErr: true,
+		},
 		"kubeclient fails": {
 			kubeadm: stubTokenGetter{token: testJoinToken},
 			kms: stubKeyGetter{dataKeys: map[string][]byte{
@@ -184,6 +245,12 @@ func TestIssueJoinTicket(t *testing.T) {
 			assert.Equal(tc.kubeadm.token.Token, resp.Token)
 			assert.Equal(tc.ca.cert, resp.KubeletCert)
 			assert.Equal(tc.kubeClient.getComponentsVal, resp.KubernetesComponents)
+			assert.Equal(tc.wantAuthorizedKeys, resp.DebugAccessAuthorizedKeys)
+			require.Len(resp.RegistryMirrors, len(tc.wantRegistryMirrors))
+			for i, mirror := range tc.wantRegistryMirrors {
+				assert.Equal(mirror.Registry, resp.RegistryMirrors[i].Registry)
+				assert.Equal(mirror.Endpoints, resp.RegistryMirrors[i].Endpoints)
+			}
 			assert.Equal(tc.ca.nodeName, tc.kubeClient.joiningNodeName)
 			assert.Equal(tc.kubeClient.getK8sComponentsRefFromNodeVersionCRDVal, tc.kubeClient.componentsRef)
 
@@ -295,6 +362,13 @@ type stubKubeClient struct {
 	addNodeToJoiningNodesErr error
 	joiningNodeName          string
 	componentsRef            string
+
+	configMapData       map[string]string
+	getConfigMapDataErr error
+}
+
+func (s *stubKubeClient) GetConfigMapData(_ context.Context, _, key string) (string, error) {
+	return s.configMapData[key], s.getConfigMapDataErr
 }
 
 func (s *stubKubeClient) GetK8sComponentsRefFromNodeVersionCRD(_ context.Context, _ string) (string, error) {
@@ -21,6 +21,13 @@ type NodeVersionSpec struct {
 	KubernetesComponentsReference string `json:"kubernetesComponentsReference,omitempty"`
 	// KubernetesClusterVersion is the advertised Kubernetes version of the cluster.
 	KubernetesClusterVersion string `json:"kubernetesClusterVersion,omitempty"`
+	// DrainGracePeriodSeconds is the time pods get to terminate when their node is drained for replacement.
+	// If unset, the termination grace period of the pods is used.
+	DrainGracePeriodSeconds int64 `json:"drainGracePeriodSeconds,omitempty"`
+	// DrainTimeoutSeconds is the time a node gets to drain before it's deleted anyway.
+	// Pods whose eviction is still blocked by a PodDisruptionBudget are deleted then.
+	// If unset, draining doesn't time out, so PodDisruptionBudgets can block it indefinitely.
+	DrainTimeoutSeconds int64 `json:"drainTimeoutSeconds,omitempty"`
 }
 
 // NodeVersionStatus defines the observed state of NodeVersion.
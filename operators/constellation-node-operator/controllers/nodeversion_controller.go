@@ -10,6 +10,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"reflect"
 	"strings"
 	"time"
@@ -43,7 +44,10 @@ const (
 	// nodeJoinTimeout is the time limit pending nodes have to join the cluster before being terminated.
 	nodeJoinTimeout = time.Minute * 30
 	// nodeLeaveTimeout is the time limit pending nodes have to leave the cluster and being terminated.
-	nodeLeaveTimeout                     = time.Minute
+	nodeLeaveTimeout = time.Minute
+	// drainCheckInterval is the interval in which the progress of draining nodes is checked.
+	drainCheckInterval                   = 10 * time.Second
+	drainStartAnnotation                 = "constellation.edgeless.systems/drain-start"
 	donorAnnotation                      = "constellation.edgeless.systems/donor"
 	heirAnnotation                       = "constellation.edgeless.systems/heir"
 	scalingGroupAnnotation               = "constellation.edgeless.systems/scaling-group-id"
@@ -61,17 +65,19 @@ type NodeVersionReconciler struct {
 	etcdRemover
 	clusterUpgrader
 	kubernetesServerVersionGetter
+	nodeDrainer
 	client.Client
 	Scheme *runtime.Scheme
 }
 
 // NewNodeVersionReconciler creates a new NodeVersionReconciler.
-func NewNodeVersionReconciler(nodeReplacer nodeReplacer, etcdRemover etcdRemover, clusterUpgrader clusterUpgrader, k8sVerGetter kubernetesServerVersionGetter, client client.Client, scheme *runtime.Scheme) *NodeVersionReconciler {
+func NewNodeVersionReconciler(nodeReplacer nodeReplacer, etcdRemover etcdRemover, clusterUpgrader clusterUpgrader, k8sVerGetter kubernetesServerVersionGetter, nodeDrainer nodeDrainer, client client.Client, scheme *runtime.Scheme) *NodeVersionReconciler {
 	return &NodeVersionReconciler{
 		nodeReplacer:                  nodeReplacer,
 		etcdRemover:                   etcdRemover,
 		clusterUpgrader:               clusterUpgrader,
 		kubernetesServerVersionGetter: k8sVerGetter,
+		nodeDrainer:                   nodeDrainer,
 		Client:                        client,
 		Scheme:                        scheme,
 	}
@@ -83,6 +89,8 @@ func NewNodeVersionReconciler(nodeReplacer nodeReplacer, etcdRemover etcdRemover
 //+kubebuilder:rbac:groups=nodemaintenance.medik8s.io,resources=nodemaintenances,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=nodes/status,verbs=get
+//+kubebuilder:rbac:groups="",resources=pods,verbs=list;delete
+//+kubebuilder:rbac:groups="",resources=pods/eviction,verbs=create
 //+kubebuilder:rbac:groups="",resources=configmaps,verbs=list;get
 
 // Reconcile replaces outdated nodes with new nodes as specified in the NodeVersion spec.
@@ -95,13 +103,17 @@ func (r *NodeVersionReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	// A NodeVersion of a single node group only replaces the nodes of that group.
+	// The cluster version and autoscaling are left to the NodeVersion of the cluster.
+	_, isNodeGroupVersion := desiredNodeVersion.Labels[mainconstants.NodeGroupLabelKey]
+
 	// Check if we need to upgrade the cluster version.
 	serverVer, err := r.ServerVersion()
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 	// GitVersion is the semantic version of the Kubernetes server e.g. "v1.24.9"
-	if semver.Compare(serverVer.GitVersion, desiredNodeVersion.Spec.KubernetesClusterVersion) != 0 {
+	if !isNodeGroupVersion && semver.Compare(serverVer.GitVersion, desiredNodeVersion.Spec.KubernetesClusterVersion) != 0 {
 		r.tryStartClusterVersionUpgrade(ctx, req.NamespacedName)
 	}
 
@@ -143,6 +155,7 @@ func (r *NodeVersionReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		scalingGroupByID[strings.ToLower(scalingGroup.Spec.GroupID)] = scalingGroup
 	}
 	annotatedNodes, invalidNodes := r.annotateNodes(ctx, nodeList.Items)
+	annotatedNodes, pendingNodeList.Items = nodesOfNodeVersion(desiredNodeVersion, isNodeGroupVersion, annotatedNodes, pendingNodeList.Items, scalingGroupByID)
 	groups := groupNodes(annotatedNodes, pendingNodeList.Items, desiredNodeVersion.Spec.ImageReference, desiredNodeVersion.Spec.KubernetesComponentsReference)
 
 	logr.Info("Grouped nodes",
@@ -175,9 +188,11 @@ func (r *NodeVersionReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	}
 
 	allNodesUpToDate := len(groups.Outdated)+len(groups.Heirs)+len(groups.AwaitingAnnotation)+len(pendingNodeList.Items)+len(groups.Obsolete) == 0
-	if err := r.ensureAutoscaling(ctx, autoscalingEnabled, allNodesUpToDate); err != nil {
-		logr.Error(err, "Ensure autoscaling", "autoscalingEnabledIs", autoscalingEnabled, "autoscalingEnabledWant", allNodesUpToDate)
-		return ctrl.Result{}, err
+	if !isNodeGroupVersion {
+		if err := r.ensureAutoscaling(ctx, autoscalingEnabled, allNodesUpToDate); err != nil {
+			logr.Error(err, "Ensure autoscaling", "autoscalingEnabledIs", autoscalingEnabled, "autoscalingEnabledWant", allNodesUpToDate)
+			return ctrl.Result{}, err
+		}
 	}
 
 	if allNodesUpToDate {
@@ -187,6 +202,9 @@ func (r *NodeVersionReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 	// should requeue is set if a node is deleted
 	var shouldRequeue bool
+	// pods evicted by the operator are not watched, and drains time out,
+	// so the progress of draining nodes is polled
+	requeueAfter := drainCheckInterval
 	// find pairs of mint nodes and outdated nodes in the same scaling group to become donor & heir
 	replacementPairs := r.pairDonorsAndHeirs(ctx, &desiredNodeVersion, groups.Outdated, groups.Mint)
 	// extend replacement pairs to include existing pairs of donors and heirs
@@ -211,13 +229,13 @@ func (r *NodeVersionReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	// only create new nodes if the autoscaler is disabled.
 	// otherwise, new nodes will also be created by the autoscaler
 	if autoscalingEnabled {
-		return ctrl.Result{Requeue: shouldRequeue}, nil
+		return ctrl.Result{Requeue: shouldRequeue, RequeueAfter: requeueAfter}, nil
 	}
 
 	newNodeConfig := newNodeConfig{desiredNodeVersion, groups.Outdated, pendingNodeList.Items, scalingGroupByID, newNodesBudget}
 	if err := r.createNewNodes(ctx, newNodeConfig); err != nil {
 		logr.Error(err, "Creating new nodes")
-		return ctrl.Result{Requeue: shouldRequeue}, nil
+		return ctrl.Result{Requeue: shouldRequeue, RequeueAfter: requeueAfter}, nil
 	}
 	// cleanup obsolete nodes
 	for _, node := range groups.Obsolete {
@@ -230,7 +248,7 @@ func (r *NodeVersionReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		}
 	}
 
-	return ctrl.Result{Requeue: shouldRequeue}, nil
+	return ctrl.Result{Requeue: shouldRequeue, RequeueAfter: requeueAfter}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -385,7 +403,7 @@ func (r *NodeVersionReconciler) tryStartClusterVersionUpgrade(ctx context.Contex
 
 // pairDonorsAndHeirs takes a list of outdated nodes (that do not yet have a heir node) and a list of mint nodes (nodes using the latest image) and pairs matching nodes to become donor and heir.
 // outdatedNodes is also updated with heir annotations.
-func (r *NodeVersionReconciler) pairDonorsAndHeirs(ctx context.Context, controller metav1.Object, outdatedNodes []corev1.Node, mintNodes []mintNode) []replacementPair {
+func (r *NodeVersionReconciler) pairDonorsAndHeirs(ctx context.Context, nodeVersion *updatev1alpha1.NodeVersion, outdatedNodes []corev1.Node, mintNodes []mintNode) []replacementPair {
 	logr := log.FromContext(ctx)
 	var pairs []replacementPair
 	for _, mintNode := range mintNodes {
@@ -433,7 +451,7 @@ func (r *NodeVersionReconciler) pairDonorsAndHeirs(ctx context.Context, controll
 				logr.Error(err, "Unable to update mint node obsolete annotation", "mintNode", mintNode.node.Name)
 				break
 			}
-			if _, err := r.deleteNode(ctx, controller, mintNode.node); err != nil {
+			if _, err := r.deleteNode(ctx, nodeVersion, mintNode.node); err != nil {
 				logr.Error(err, "Unable to delete obsolete node", "obsoleteNode", mintNode.node.Name)
 				break
 			}
@@ -518,7 +536,7 @@ func (r *NodeVersionReconciler) ensureAutoscaling(ctx context.Context, autoscali
 // Labels are copied from the donor node to the heir node.
 // Readiness of the heir node is awaited.
 // Deletion of the donor node is scheduled.
-func (r *NodeVersionReconciler) replaceNode(ctx context.Context, controller metav1.Object, pair replacementPair) (bool, error) {
+func (r *NodeVersionReconciler) replaceNode(ctx context.Context, nodeVersion *updatev1alpha1.NodeVersion, pair replacementPair) (bool, error) {
 	logr := log.FromContext(ctx)
 	if !reflect.DeepEqual(nodeutil.FilterLabels(pair.donor.Labels), nodeutil.FilterLabels(pair.heir.Labels)) {
 		if err := r.copyNodeLabels(ctx, pair.donor.Name, pair.heir.Name); err != nil {
@@ -530,11 +548,11 @@ func (r *NodeVersionReconciler) replaceNode(ctx context.Context, controller meta
 	if !heirReady {
 		return false, nil
 	}
-	return r.deleteNode(ctx, controller, pair.donor)
+	return r.deleteNode(ctx, nodeVersion, pair.donor)
 }
 
 // deleteNode safely removes a node from the cluster and issues termination of the node by the CSP.
-func (r *NodeVersionReconciler) deleteNode(ctx context.Context, controller metav1.Object, node corev1.Node) (bool, error) {
+func (r *NodeVersionReconciler) deleteNode(ctx context.Context, nodeVersion *updatev1alpha1.NodeVersion, node corev1.Node) (bool, error) {
 	logr := log.FromContext(ctx)
 	// cordon & drain node using node-maintenance-operator
 	var foundNodeMaintenance nodemaintenancev1beta1.NodeMaintenance
@@ -543,8 +561,29 @@ func (r *NodeVersionReconciler) deleteNode(ctx context.Context, controller metav
 		// unexpected error occurred
 		return false, err
 	}
+	// nodes are drained by the operator first, if a grace period is set, and then by the node-maintenance-operator
+	drainStart, drainStartErr := r.drainStart(ctx, node)
+	if drainStartErr != nil {
+		return false, drainStartErr
+	}
 	if err != nil {
 		// NodeMaintenance resource does not exist yet
+		if gracePeriod := nodeVersion.Spec.DrainGracePeriodSeconds; gracePeriod > 0 {
+			// the node-maintenance-operator evicts pods without a grace period of our choice,
+			// so the node is drained before handing it over
+			timedOut := drainTimedOut(nodeVersion, drainStart)
+			if timedOut {
+				logr.Info("Draining node timed out, deleting pods blocked by PodDisruptionBudgets", "drainingNode", node.Name, "drainStart", drainStart)
+			}
+			drained, err := r.Drain(ctx, node.Name, gracePeriod, timedOut)
+			if err != nil {
+				return false, err
+			}
+			if !drained {
+				logr.Info("Draining node in progress", "drainingNode", node.Name, "gracePeriodSeconds", gracePeriod)
+				return false, nil
+			}
+		}
 		nodeMaintenance := nodemaintenancev1beta1.NodeMaintenance{
 			ObjectMeta: metav1.ObjectMeta{
 				Name: node.Name,
@@ -559,8 +598,12 @@ func (r *NodeVersionReconciler) deleteNode(ctx context.Context, controller metav
 
 	// NodeMaintenance resource already exists. Check cordon & drain status.
 	if foundNodeMaintenance.Status.Phase != nodemaintenancev1beta1.MaintenanceSucceeded {
-		logr.Info("Cordon & drain in progress", "maintenanceNode", node.Name, "nodeMaintenanceStatus", foundNodeMaintenance.Status.Phase)
-		return false, nil
+		if !drainTimedOut(nodeVersion, drainStart) {
+			logr.Info("Cordon & drain in progress", "maintenanceNode", node.Name, "nodeMaintenanceStatus", foundNodeMaintenance.Status.Phase)
+			return false, nil
+		}
+		// pods left on the node are removed with the node
+		logr.Info("Cordon & drain timed out, deleting node anyway", "maintenanceNode", node.Name, "nodeMaintenanceStatus", foundNodeMaintenance.Status.Phase, "drainStart", drainStart)
 	}
 
 	// node is unused & ready to be replaced
@@ -588,7 +631,7 @@ func (r *NodeVersionReconciler) deleteNode(ctx context.Context, controller metav
 	deadline := metav1.NewTime(time.Now().Add(nodeLeaveTimeout))
 	pendingNode := updatev1alpha1.PendingNode{
 		ObjectMeta: metav1.ObjectMeta{
-			Namespace: controller.GetNamespace(),
+			Namespace: nodeVersion.GetNamespace(),
 			Name:      node.Name,
 		},
 		Spec: updatev1alpha1.PendingNodeSpec{
@@ -599,7 +642,7 @@ func (r *NodeVersionReconciler) deleteNode(ctx context.Context, controller metav
 			Deadline:       &deadline,
 		},
 	}
-	if err := ctrl.SetControllerReference(controller, &pendingNode, r.Scheme); err != nil {
+	if err := ctrl.SetControllerReference(nodeVersion, &pendingNode, r.Scheme); err != nil {
 		return false, err
 	}
 	if err := r.Create(ctx, &pendingNode); err != nil {
@@ -687,6 +730,28 @@ func (r *NodeVersionReconciler) createNewNodes(ctx context.Context, config newNo
 	return nil
 }
 
+// drainStart returns when draining the node started.
+// If the node isn't draining yet, the current time is recorded in an annotation of the node.
+func (r *NodeVersionReconciler) drainStart(ctx context.Context, node corev1.Node) (time.Time, error) {
+	if drainStart, err := time.Parse(time.RFC3339, node.Annotations[drainStartAnnotation]); err == nil {
+		return drainStart, nil
+	}
+	now := time.Now()
+	if err := r.patchNodeAnnotations(ctx, node.Name, map[string]string{drainStartAnnotation: now.Format(time.RFC3339)}); err != nil {
+		return time.Time{}, fmt.Errorf("annotating node %s with the start of the drain: %w", node.Name, err)
+	}
+	return now, nil
+}
+
+// drainTimedOut returns whether a node that started draining at drainStart exceeded the drain timeout of the NodeVersion.
+// Without a drain timeout, draining never times out, so PodDisruptionBudgets can block it indefinitely.
+func drainTimedOut(nodeVersion *updatev1alpha1.NodeVersion, drainStart time.Time) bool {
+	if nodeVersion.Spec.DrainTimeoutSeconds <= 0 {
+		return false
+	}
+	return time.Since(drainStart) > time.Duration(nodeVersion.Spec.DrainTimeoutSeconds)*time.Second
+}
+
 // patchNodeAnnotations attempts to patch node annotations in a retry loop.
 func (r *NodeVersionReconciler) patchNodeAnnotations(ctx context.Context, nodeName string, annotations map[string]string) error {
 	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
@@ -874,6 +939,34 @@ type nodeGroups struct {
 	Mint []mintNode
 }
 
+// nodesOfNodeVersion returns the nodes and pending nodes of the scaling groups using the given NodeVersion.
+// Nodes of scaling groups without a NodeVersion, or without a matching resource, belong to the NodeVersion of the cluster.
+func nodesOfNodeVersion(nodeVersion updatev1alpha1.NodeVersion, isNodeGroupVersion bool, nodes []corev1.Node,
+	pendingNodes []updatev1alpha1.PendingNode, scalingGroupByID map[string]updatev1alpha1.ScalingGroup,
+) ([]corev1.Node, []updatev1alpha1.PendingNode) {
+	usesNodeVersion := func(scalingGroupID string) bool {
+		scalingGroup, ok := scalingGroupByID[strings.ToLower(scalingGroupID)]
+		if !ok || scalingGroup.Spec.NodeVersion == "" {
+			return !isNodeGroupVersion
+		}
+		return scalingGroup.Spec.NodeVersion == nodeVersion.Name
+	}
+
+	var filteredNodes []corev1.Node
+	for _, node := range nodes {
+		if usesNodeVersion(node.Annotations[scalingGroupAnnotation]) {
+			filteredNodes = append(filteredNodes, node)
+		}
+	}
+	var filteredPendingNodes []updatev1alpha1.PendingNode
+	for _, pendingNode := range pendingNodes {
+		if usesNodeVersion(pendingNode.Spec.ScalingGroupID) {
+			filteredPendingNodes = append(filteredPendingNodes, pendingNode)
+		}
+	}
+	return filteredNodes, filteredPendingNodes
+}
+
 // groupNodes classifies nodes by placing each into exactly one group.
 func groupNodes(nodes []corev1.Node, pendingNodes []updatev1alpha1.PendingNode, latestImageReference string, latestK8sComponentsReference string) nodeGroups {
 	groups := nodeGroups{}
@@ -936,6 +1029,13 @@ type kubernetesServerVersionGetter interface {
 	ServerVersion() (*version.Info, error)
 }
 
+type nodeDrainer interface {
+	// Drain cordons a node and evicts its pods with the given grace period.
+	// If force is set, pods whose eviction is blocked by a PodDisruptionBudget are deleted.
+	// It returns true once no pods are left to evict.
+	Drain(ctx context.Context, nodeName string, gracePeriodSeconds int64, force bool) (bool, error)
+}
+
 type newNodeConfig struct {
 	desiredNodeVersion updatev1alpha1.NodeVersion
 	outdatedNodes      []corev1.Node
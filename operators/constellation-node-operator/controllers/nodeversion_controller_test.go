@@ -11,14 +11,19 @@ import (
 	"errors"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/version"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	nodemaintenancev1beta1 "github.com/edgelesssys/constellation/v2/3rdparty/node-maintenance-operator/api/v1beta1"
 	mainconstants "github.com/edgelesssys/constellation/v2/internal/constants"
 	updatev1alpha1 "github.com/edgelesssys/constellation/v2/operators/constellation-node-operator/api/v1alpha1"
 )
@@ -600,6 +605,241 @@ func TestCreateNewNodes(t *testing.T) {
 	}
 }
 
+func TestDeleteNodeDrain(t *testing.T) {
+	newNode := func(drainStart time.Time) corev1.Node {
+		node := corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node"},
+			Spec:       corev1.NodeSpec{ProviderID: "provider-id"},
+		}
+		if !drainStart.IsZero() {
+			node.Annotations = map[string]string{drainStartAnnotation: drainStart.Format(time.RFC3339)}
+		}
+		return node
+	}
+	const drainTimeout = int64(15 * time.Minute / time.Second)
+	timedOut := time.Now().Add(-time.Hour)
+	runningMaintenance := &nodemaintenancev1beta1.NodeMaintenance{
+		ObjectMeta: metav1.ObjectMeta{Name: "node"},
+		Status:     nodemaintenancev1beta1.NodeMaintenanceStatus{Phase: nodemaintenancev1beta1.MaintenanceRunning},
+	}
+
+	testCases := map[string]struct {
+		node                corev1.Node
+		gracePeriod         int64
+		drainTimeout        int64
+		nodeMaintenance     *nodemaintenancev1beta1.NodeMaintenance
+		drainer             *stubNodeDrainer
+		wantDrainStart      bool
+		wantGracePeriods    []int64
+		wantForce           []bool
+		wantNodeMaintenance bool
+		wantDeleted         bool
+		wantErr             bool
+	}{
+		"no grace period": {
+			node:                newNode(time.Time{}),
+			drainer:             &stubNodeDrainer{},
+			wantDrainStart:      true,
+			wantNodeMaintenance: true,
+		},
+		"node is drained with grace period": {
+			node:                newNode(time.Time{}),
+			gracePeriod:         300,
+			drainer:             &stubNodeDrainer{drained: true},
+			wantDrainStart:      true,
+			wantGracePeriods:    []int64{300},
+			wantForce:           []bool{false},
+			wantNodeMaintenance: true,
+		},
+		"draining blocked by pod disruption budget": {
+			node:             newNode(time.Now()),
+			gracePeriod:      300,
+			drainer:          &stubNodeDrainer{drained: false},
+			wantGracePeriods: []int64{300},
+			wantForce:        []bool{false},
+		},
+		"draining blocked by pod disruption budget without drain timeout": {
+			node:             newNode(timedOut),
+			gracePeriod:      300,
+			drainer:          &stubNodeDrainer{drained: false},
+			wantGracePeriods: []int64{300},
+			wantForce:        []bool{false},
+		},
+		"draining blocked by pod disruption budget times out": {
+			node:                newNode(timedOut),
+			gracePeriod:         300,
+			drainTimeout:        drainTimeout,
+			drainer:             &stubNodeDrainer{drained: true},
+			wantGracePeriods:    []int64{300},
+			wantForce:           []bool{true},
+			wantNodeMaintenance: true,
+		},
+		"configured drain timeout": {
+			node:             newNode(timedOut),
+			gracePeriod:      300,
+			drainTimeout:     int64(2 * time.Hour / time.Second),
+			drainer:          &stubNodeDrainer{drained: false},
+			wantGracePeriods: []int64{300},
+			wantForce:        []bool{false},
+		},
+		"draining fails": {
+			node:             newNode(time.Now()),
+			gracePeriod:      300,
+			drainer:          &stubNodeDrainer{drainErr: errors.New("drain failed")},
+			wantGracePeriods: []int64{300},
+			wantForce:        []bool{false},
+			wantErr:          true,
+		},
+		"node maintenance in progress": {
+			node:            newNode(time.Now()),
+			gracePeriod:     300,
+			nodeMaintenance: runningMaintenance,
+			drainer:         &stubNodeDrainer{},
+		},
+		"node maintenance without drain timeout": {
+			node:            newNode(timedOut),
+			gracePeriod:     300,
+			nodeMaintenance: runningMaintenance,
+			drainer:         &stubNodeDrainer{},
+		},
+		"node maintenance times out": {
+			node:            newNode(timedOut),
+			gracePeriod:     300,
+			drainTimeout:    drainTimeout,
+			nodeMaintenance: runningMaintenance,
+			drainer:         &stubNodeDrainer{},
+			wantDeleted:     true,
+		},
+		"drain start of node maintenance is recorded": {
+			node:            newNode(time.Time{}),
+			nodeMaintenance: runningMaintenance,
+			drainer:         &stubNodeDrainer{},
+			wantDrainStart:  true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			objects := []runtime.Object{&tc.node}
+			if tc.nodeMaintenance != nil {
+				objects = append(objects, tc.nodeMaintenance)
+			}
+			recorder := &createRecordingClient{
+				stubReadWriterClient: &stubReadWriterClient{
+					stubReaderClient: *newStubReaderClient(t, objects, nil, nil),
+				},
+			}
+			nodeReplacer := &stubNodeReplacerWriter{}
+			reconciler := NodeVersionReconciler{
+				nodeReplacer: nodeReplacer,
+				nodeDrainer:  tc.drainer,
+				Client:       recorder,
+				Scheme:       getScheme(t),
+			}
+			nodeVersion := updatev1alpha1.NodeVersion{
+				Spec: updatev1alpha1.NodeVersionSpec{
+					DrainGracePeriodSeconds: tc.gracePeriod,
+					DrainTimeoutSeconds:     tc.drainTimeout,
+				},
+			}
+
+			done, err := reconciler.deleteNode(context.Background(), &nodeVersion, tc.node)
+			if tc.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			assert.Equal(tc.wantDeleted, done)
+			assert.Equal(tc.wantGracePeriods, tc.drainer.gracePeriods)
+			assert.Equal(tc.wantForce, tc.drainer.force)
+			if tc.wantDrainStart {
+				assert.Contains(recorder.patchedAnnotations, drainStartAnnotation)
+			} else {
+				assert.NotContains(recorder.patchedAnnotations, drainStartAnnotation)
+			}
+			switch {
+			case tc.wantNodeMaintenance:
+				assert.Equal([]string{"node"}, recorder.created)
+			case tc.wantDeleted:
+				// the pending node tracking the deletion at the CSP
+				assert.Equal([]string{"node"}, recorder.created)
+				assert.Equal([]string{"provider-id"}, nodeReplacer.deleteCalls)
+			default:
+				assert.Empty(recorder.created)
+				assert.Empty(nodeReplacer.deleteCalls)
+			}
+		})
+	}
+}
+
+func TestNodesOfNodeVersion(t *testing.T) {
+	nodeWithScalingGroup := func(name, scalingGroupID string) corev1.Node {
+		return corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Annotations: map[string]string{scalingGroupAnnotation: scalingGroupID},
+			},
+		}
+	}
+	nodes := []corev1.Node{
+		nodeWithScalingGroup("worker", "Worker-Group"),
+		nodeWithScalingGroup("canary", "canary-group"),
+		nodeWithScalingGroup("unknown", "unknown-group"),
+	}
+	pendingNodes := []updatev1alpha1.PendingNode{
+		{ObjectMeta: metav1.ObjectMeta{Name: "pending-worker"}, Spec: updatev1alpha1.PendingNodeSpec{ScalingGroupID: "worker-group"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pending-canary"}, Spec: updatev1alpha1.PendingNodeSpec{ScalingGroupID: "canary-group"}},
+	}
+	scalingGroupByID := map[string]updatev1alpha1.ScalingGroup{
+		"worker-group": {Spec: updatev1alpha1.ScalingGroupSpec{GroupID: "worker-group", NodeVersion: mainconstants.NodeVersionResourceName}},
+		"canary-group": {Spec: updatev1alpha1.ScalingGroupSpec{GroupID: "canary-group", NodeVersion: "constellation-version-canary"}},
+	}
+
+	testCases := map[string]struct {
+		nodeVersionName    string
+		isNodeGroupVersion bool
+		wantNodes          []string
+		wantPendingNodes   []string
+	}{
+		"cluster NodeVersion": {
+			nodeVersionName:  mainconstants.NodeVersionResourceName,
+			wantNodes:        []string{"worker", "unknown"},
+			wantPendingNodes: []string{"pending-worker"},
+		},
+		"node group NodeVersion": {
+			nodeVersionName:    "constellation-version-canary",
+			isNodeGroupVersion: true,
+			wantNodes:          []string{"canary"},
+			wantPendingNodes:   []string{"pending-canary"},
+		},
+		"unused node group NodeVersion": {
+			nodeVersionName:    "constellation-version-other",
+			isNodeGroupVersion: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			nodeVersion := updatev1alpha1.NodeVersion{ObjectMeta: metav1.ObjectMeta{Name: tc.nodeVersionName}}
+			gotNodes, gotPendingNodes := nodesOfNodeVersion(nodeVersion, tc.isNodeGroupVersion, nodes, pendingNodes, scalingGroupByID)
+
+			var gotNodeNames, gotPendingNodeNames []string
+			for _, node := range gotNodes {
+				gotNodeNames = append(gotNodeNames, node.Name)
+			}
+			for _, pendingNode := range gotPendingNodes {
+				gotPendingNodeNames = append(gotPendingNodeNames, pendingNode.Name)
+			}
+			assert.Equal(tc.wantNodes, gotNodeNames)
+			assert.Equal(tc.wantPendingNodes, gotPendingNodeNames)
+		})
+	}
+}
+
 func TestGroupNodes(t *testing.T) {
 	latestImageReference := "latest-image"
 	latestK8sComponentsReference := "latest-k8s-components-ref"
@@ -836,6 +1076,49 @@ func (g *stubKubernetesServerVersionGetter) ServerVersion() (*version.Info, erro
 	return &version.Info{GitVersion: g.version}, g.err
 }
 
+type stubNodeDrainer struct {
+	drained  bool
+	drainErr error
+
+	gracePeriods []int64
+	force        []bool
+}
+
+func (d *stubNodeDrainer) Drain(_ context.Context, _ string, gracePeriodSeconds int64, force bool) (bool, error) {
+	d.gracePeriods = append(d.gracePeriods, gracePeriodSeconds)
+	d.force = append(d.force, force)
+	return d.drained, d.drainErr
+}
+
+// createRecordingClient records the names of created objects, and the annotations of patched objects.
+type createRecordingClient struct {
+	*stubReadWriterClient
+	created            []string
+	patchedAnnotations map[string]string
+}
+
+// Get returns a NotFound error for objects that don't exist.
+func (c *createRecordingClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	gvks, _, err := c.scheme.ObjectKinds(obj)
+	if err != nil {
+		return err
+	}
+	if c.objects[gvks[0]][key] == nil {
+		return k8serrors.NewNotFound(schema.GroupResource{}, key.Name)
+	}
+	return c.stubReadWriterClient.Get(ctx, key, obj, opts...)
+}
+
+func (c *createRecordingClient) Patch(_ context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+	c.patchedAnnotations = obj.GetAnnotations()
+	return nil
+}
+
+func (c *createRecordingClient) Create(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+	c.created = append(c.created, obj.GetName())
+	return nil
+}
+
 type stubNodeReplacerReader struct {
 	nodeImage         string
 	scalingGroupID    string
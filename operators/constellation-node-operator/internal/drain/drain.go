@@ -0,0 +1,116 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package drain
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Drainer cordons nodes and evicts their pods.
+type Drainer struct {
+	client kubernetes.Interface
+}
+
+// New creates a new Drainer.
+func New(client kubernetes.Interface) *Drainer {
+	return &Drainer{client: client}
+}
+
+// Drain cordons the node and evicts its pods, giving them gracePeriodSeconds to terminate.
+// It doesn't wait for the pods to terminate, but returns true once no pods are left to evict.
+// Evictions that would violate a PodDisruptionBudget are refused by the API server
+// and retried on the next call, unless force is set. Then, these pods are deleted instead.
+func (d *Drainer) Drain(ctx context.Context, nodeName string, gracePeriodSeconds int64, force bool) (bool, error) {
+	if err := d.cordon(ctx, nodeName); err != nil {
+		return false, err
+	}
+
+	pods, err := d.client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+	})
+	if err != nil {
+		return false, fmt.Errorf("listing pods of node %s: %w", nodeName, err)
+	}
+
+	drained := true
+	for _, pod := range pods.Items {
+		if !needsEviction(pod) {
+			continue
+		}
+		drained = false
+		if pod.DeletionTimestamp != nil {
+			// pod is already terminating
+			continue
+		}
+		err := d.client.PolicyV1().Evictions(pod.Namespace).Evict(ctx, &policyv1.Eviction{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      pod.Name,
+				Namespace: pod.Namespace,
+			},
+			DeleteOptions: &metav1.DeleteOptions{
+				GracePeriodSeconds: &gracePeriodSeconds,
+			},
+		})
+		if k8serrors.IsTooManyRequests(err) && force {
+			// eviction is blocked by a PodDisruptionBudget, but the drain timed out
+			err = d.client.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{
+				GracePeriodSeconds: &gracePeriodSeconds,
+			})
+		}
+		switch {
+		case k8serrors.IsTooManyRequests(err):
+			// eviction is blocked by a PodDisruptionBudget
+			continue
+		case k8serrors.IsNotFound(err):
+			continue
+		case err != nil:
+			return false, fmt.Errorf("evicting pod %s/%s: %w", pod.Namespace, pod.Name, err)
+		}
+	}
+	return drained, nil
+}
+
+// cordon marks the node as unschedulable.
+func (d *Drainer) cordon(ctx context.Context, nodeName string) error {
+	node, err := d.client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("getting node %s: %w", nodeName, err)
+	}
+	if node.Spec.Unschedulable {
+		return nil
+	}
+	node.Spec.Unschedulable = true
+	if _, err := d.client.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("cordoning node %s: %w", nodeName, err)
+	}
+	return nil
+}
+
+// needsEviction returns true if the pod has to be evicted before the node can be removed.
+// Mirror pods and pods of DaemonSets are bound to the node, and finished pods don't run anymore.
+func needsEviction(pod corev1.Pod) bool {
+	if _, ok := pod.Annotations[corev1.MirrorPodAnnotationKey]; ok {
+		return false
+	}
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false
+	}
+	for _, owner := range pod.OwnerReferences {
+		if owner.Controller != nil && *owner.Controller && owner.Kind == "DaemonSet" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,250 @@
+/*
+Copyright (c) Edgeless Systems GmbH
+
+SPDX-License-Identifier: AGPL-3.0-only
+*/
+
+package drain
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+)
+
+func TestDrain(t *testing.T) {
+	const gracePeriod int64 = 120
+
+	testCases := map[string]struct {
+		node        *corev1.Node
+		pods        []*corev1.Pod
+		pdbs        []*policyv1.PodDisruptionBudget
+		force       bool
+		evictErr    error
+		wantEvicted []string
+		wantDeleted []string
+		wantDrained bool
+		wantErr     bool
+	}{
+		"pods are evicted with grace period": {
+			node:        newNode(),
+			pods:        []*corev1.Pod{newPod("app-1", nil), newPod("app-2", nil)},
+			wantEvicted: []string{"app-1", "app-2"},
+			wantDrained: true,
+		},
+		"pod disruption budget blocks eviction": {
+			node: newNode(),
+			pods: []*corev1.Pod{newPod("db-1", map[string]string{"app": "db"}), newPod("app-1", nil)},
+			pdbs: []*policyv1.PodDisruptionBudget{
+				newPDB(map[string]string{"app": "db"}, 0),
+			},
+			wantEvicted: []string{"app-1"},
+		},
+		"pods blocked by pod disruption budget are deleted when forced": {
+			node: newNode(),
+			pods: []*corev1.Pod{newPod("db-1", map[string]string{"app": "db"}), newPod("app-1", nil)},
+			pdbs: []*policyv1.PodDisruptionBudget{
+				newPDB(map[string]string{"app": "db"}, 0),
+			},
+			force:       true,
+			wantEvicted: []string{"app-1"},
+			wantDeleted: []string{"db-1"},
+			wantDrained: true,
+		},
+		"pod disruption budget allows eviction": {
+			node: newNode(),
+			pods: []*corev1.Pod{newPod("db-1", map[string]string{"app": "db"})},
+			pdbs: []*policyv1.PodDisruptionBudget{
+				newPDB(map[string]string{"app": "db"}, 1),
+			},
+			wantEvicted: []string{"db-1"},
+			wantDrained: true,
+		},
+		"daemon set and mirror pods are not evicted": {
+			node: newNode(),
+			pods: []*corev1.Pod{
+				func() *corev1.Pod {
+					pod := newPod("daemon", nil)
+					pod.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "daemon", Controller: ptr.To(true)}}
+					return pod
+				}(),
+				func() *corev1.Pod {
+					pod := newPod("static", nil)
+					pod.Annotations = map[string]string{corev1.MirrorPodAnnotationKey: "mirror"}
+					return pod
+				}(),
+				func() *corev1.Pod {
+					pod := newPod("job", nil)
+					pod.Status.Phase = corev1.PodSucceeded
+					return pod
+				}(),
+			},
+			wantDrained: true,
+		},
+		"terminating pods are awaited": {
+			node: newNode(),
+			pods: []*corev1.Pod{
+				func() *corev1.Pod {
+					pod := newPod("app-1", nil)
+					pod.DeletionTimestamp = &metav1.Time{}
+					pod.Finalizers = []string{"test"}
+					return pod
+				}(),
+			},
+		},
+		"eviction fails": {
+			node:     newNode(),
+			pods:     []*corev1.Pod{newPod("app-1", nil)},
+			evictErr: errors.New("failed"),
+			wantErr:  true,
+		},
+		"node not found": {
+			pods:    []*corev1.Pod{newPod("app-1", nil)},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			var objects []runtime.Object
+			if tc.node != nil {
+				objects = append(objects, tc.node)
+			}
+			for _, pod := range tc.pods {
+				objects = append(objects, pod)
+			}
+			for _, pdb := range tc.pdbs {
+				objects = append(objects, pdb)
+			}
+			client := fake.NewSimpleClientset(objects...)
+			evicted := map[string]int64{}
+			client.PrependReactor("create", "pods", evictionReactor(client, tc.evictErr, evicted))
+			deleted := map[string]int64{}
+			client.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				deleteAction := action.(k8stesting.DeleteActionImpl)
+				deleted[deleteAction.Name] = *deleteAction.DeleteOptions.GracePeriodSeconds
+				return false, nil, nil
+			})
+
+			drainer := New(client)
+			_, err := drainer.Drain(context.Background(), "node", gracePeriod, tc.force)
+			if tc.wantErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			// The second call sees the result of the evictions of the first one.
+			drained, err := drainer.Drain(context.Background(), "node", gracePeriod, tc.force)
+			require.NoError(err)
+			assert.Equal(tc.wantDrained, drained)
+
+			assert.Len(evicted, len(tc.wantEvicted))
+			for _, name := range tc.wantEvicted {
+				assert.Contains(evicted, name)
+				assert.Equal(gracePeriod, evicted[name])
+			}
+			assert.Len(deleted, len(tc.wantDeleted))
+			for _, name := range tc.wantDeleted {
+				assert.Contains(deleted, name)
+				assert.Equal(gracePeriod, deleted[name])
+			}
+
+			node, err := client.CoreV1().Nodes().Get(context.Background(), "node", metav1.GetOptions{})
+			require.NoError(err)
+			assert.True(node.Spec.Unschedulable)
+		})
+	}
+}
+
+// evictionReactor mimics the eviction API of the API server.
+// Evictions are refused if a PodDisruptionBudget selecting the pod allows no disruptions.
+// Otherwise, the pod is deleted and its grace period is recorded in evicted.
+func evictionReactor(client *fake.Clientset, evictErr error, evicted map[string]int64) k8stesting.ReactionFunc {
+	return func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		if evictErr != nil {
+			return true, nil, evictErr
+		}
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+		podsResource := corev1.SchemeGroupVersion.WithResource("pods")
+
+		// The tracker is used directly, since the client is locked while reacting.
+		obj, err := client.Tracker().Get(podsResource, eviction.Namespace, eviction.Name)
+		if err != nil {
+			return true, nil, err
+		}
+		pod := obj.(*corev1.Pod)
+		obj, err = client.Tracker().List(
+			policyv1.SchemeGroupVersion.WithResource("poddisruptionbudgets"),
+			policyv1.SchemeGroupVersion.WithKind("PodDisruptionBudget"),
+			eviction.Namespace,
+		)
+		if err != nil {
+			return true, nil, err
+		}
+		pdbs := obj.(*policyv1.PodDisruptionBudgetList)
+		for _, pdb := range pdbs.Items {
+			selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+			if err != nil {
+				return true, nil, err
+			}
+			if selector.Matches(labels.Set(pod.Labels)) && pdb.Status.DisruptionsAllowed < 1 {
+				return true, nil, k8serrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 10)
+			}
+		}
+
+		evicted[eviction.Name] = *eviction.DeleteOptions.GracePeriodSeconds
+		return true, nil, client.Tracker().Delete(podsResource, eviction.Namespace, eviction.Name)
+	}
+}
+
+func newNode() *corev1.Node {
+	return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}}
+}
+
+func newPod(name string, podLabels map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    podLabels,
+		},
+		Spec: corev1.PodSpec{NodeName: "node"},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+		},
+	}
+}
+
+func newPDB(selector map[string]string, disruptionsAllowed int32) *policyv1.PodDisruptionBudget {
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pdb",
+			Namespace: "default",
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: selector},
+		},
+		Status: policyv1.PodDisruptionBudgetStatus{
+			DisruptionsAllowed: disruptionsAllowed,
+		},
+	}
+}
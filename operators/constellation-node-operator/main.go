@@ -15,6 +15,7 @@ import (
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"k8s.io/apimachinery/pkg/runtime"
@@ -32,6 +33,7 @@ import (
 	cloudfake "github.com/edgelesssys/constellation/v2/operators/constellation-node-operator/internal/cloud/fake/client"
 	gcpclient "github.com/edgelesssys/constellation/v2/operators/constellation-node-operator/internal/cloud/gcp/client"
 	"github.com/edgelesssys/constellation/v2/operators/constellation-node-operator/internal/deploy"
+	"github.com/edgelesssys/constellation/v2/operators/constellation-node-operator/internal/drain"
 	"github.com/edgelesssys/constellation/v2/operators/constellation-node-operator/internal/executor"
 	"github.com/edgelesssys/constellation/v2/operators/constellation-node-operator/internal/upgrade"
 	"github.com/edgelesssys/constellation/v2/operators/constellation-node-operator/sgreconciler"
@@ -141,6 +143,11 @@ func main() {
 		setupLog.Error(err, "Unable to create discovery client")
 		os.Exit(1)
 	}
+	clientset, err := kubernetes.NewForConfig(ctrl.GetConfigOrDie())
+	if err != nil {
+		setupLog.Error(err, "Unable to create clientset")
+		os.Exit(1)
+	}
 	etcdClient, err := etcd.New(k8sClient)
 	if err != nil {
 		setupLog.Error(err, "Unable to create etcd client")
@@ -169,7 +176,7 @@ func main() {
 	// Create Controllers
 	if csp == "azure" || csp == "gcp" || csp == "aws" {
 		if err = controllers.NewNodeVersionReconciler(
-			cspClient, etcdClient, upgrade.NewClient(), discoveryClient, mgr.GetClient(), mgr.GetScheme(),
+			cspClient, etcdClient, upgrade.NewClient(), discoveryClient, drain.New(clientset), mgr.GetClient(), mgr.GetScheme(),
 		).SetupWithManager(mgr); err != nil {
 			setupLog.Error(err, "Unable to create controller", "controller", "NodeVersion")
 			os.Exit(1)
@@ -17,7 +17,7 @@ provider "aws" {
 
 locals {
   uid                   = random_id.uid.hex
-  name                  = "${var.name}-${local.uid}"
+  name                  = var.name_template == "" ? "${var.name}-${local.uid}" : replace(replace(var.name_template, "{name}", var.name), "{uid}", local.uid)
   init_secret_hash      = random_password.init_secret.bcrypt_hash
   cidr_vpc_subnet_nodes = "192.168.176.0/20"
   ports_node_range      = "30000-32767"
@@ -200,6 +200,7 @@ module "instance_group" {
   subnetwork           = module.public_private_subnet.private_subnet_id[each.value.zone]
   iam_instance_profile = local.iam_instance_profile[each.value.role]
   enable_snp           = var.enable_snp
+  user_data            = var.user_data
   tags = merge(
     local.tags,
     { Name = "${local.name}-${each.value.role}" },
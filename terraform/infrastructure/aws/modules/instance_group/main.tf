@@ -28,6 +28,7 @@ resource "aws_launch_template" "launch_template" {
     name = var.iam_instance_profile
   }
   vpc_security_group_ids = var.security_groups
+  user_data              = var.user_data != "" ? base64encode(var.user_data) : null
   metadata_options {
     http_endpoint               = "enabled"
     http_tokens                 = "required"
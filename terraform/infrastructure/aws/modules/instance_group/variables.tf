@@ -82,3 +82,9 @@ variable "zone" {
   type        = string
   description = "Zone to deploy the instance group in."
 }
+
+variable "user_data" {
+  type        = string
+  default     = ""
+  description = "User data script run by the instances on boot."
+}
@@ -13,6 +13,12 @@ variable "name" {
   }
 }
 
+variable "name_template" {
+  type        = string
+  default     = ""
+  description = "Template for the base name of the created resources. The placeholders {name} and {uid} are replaced with the name and the UID of the cluster. Defaults to \"{name}-{uid}\"."
+}
+
 variable "node_groups" {
   type = map(object({
     role          = string
@@ -85,3 +91,9 @@ variable "additional_tags" {
   default     = {}
   description = "Additional tags that should be applied to created resources."
 }
+
+variable "user_data" {
+  type        = string
+  default     = ""
+  description = "User data script run by the instances on boot. Not covered by attestation."
+}
@@ -25,14 +25,19 @@ provider "azurerm" {
 
 locals {
   uid              = random_id.uid.hex
-  name             = "${var.name}-${local.uid}"
+  name             = var.name_template == "" ? "${var.name}-${local.uid}" : replace(replace(var.name_template, "{name}", var.name), "{uid}", local.uid)
   init_secret_hash = random_password.init_secret.bcrypt_hash
   tags = merge(
     var.additional_tags,
     { constellation-uid = local.uid }
   )
-  ports_node_range      = "30000-32767"
-  cidr_vpc_subnet_nodes = "10.9.0.0/16"
+  ports_node_range = "30000-32767"
+  // create_network is false if the nodes are attached to an existing virtual network and subnet.
+  create_network        = var.subnet_id == ""
+  virtual_network_id    = local.create_network ? azurerm_virtual_network.network[0].id : var.virtual_network_id
+  virtual_network_name  = local.create_network ? azurerm_virtual_network.network[0].name : data.azurerm_subnet.existing[0].virtual_network_name
+  node_subnet_id        = local.create_network ? azurerm_subnet.node_subnet[0].id : data.azurerm_subnet.existing[0].id
+  cidr_vpc_subnet_nodes = local.create_network ? "10.9.0.0/16" : var.subnet_cidr
   ports = flatten([
     { name = "kubernetes", port = "6443", health_check_protocol = "Https", path = "/readyz", priority = 100 },
     { name = "bootstrapper", port = "9000", health_check_protocol = "Tcp", path = null, priority = 101 },
@@ -136,9 +141,11 @@ resource "azurerm_nat_gateway" "gateway" {
   tags                    = local.tags
 }
 
+# Existing subnets are expected to provide their own egress.
 resource "azurerm_subnet_nat_gateway_association" "example" {
+  count          = local.create_network ? 1 : 0
   nat_gateway_id = azurerm_nat_gateway.gateway.id
-  subnet_id      = azurerm_subnet.node_subnet.id
+  subnet_id      = azurerm_subnet.node_subnet[0].id
 }
 
 resource "azurerm_nat_gateway_public_ip_association" "example" {
@@ -200,6 +207,7 @@ resource "azurerm_lb_backend_address_pool" "all" {
 
 
 resource "azurerm_virtual_network" "network" {
+  count               = local.create_network ? 1 : 0
   name                = local.name
   resource_group_name = var.resource_group
   location            = var.location
@@ -211,17 +219,33 @@ resource "azurerm_subnet" "loadbalancer_subnet" {
   count                = var.internal_load_balancer ? 1 : 0
   name                 = "${local.name}-lb"
   resource_group_name  = var.resource_group
-  virtual_network_name = azurerm_virtual_network.network.name
+  virtual_network_name = local.virtual_network_name
   address_prefixes     = ["10.10.0.0/16"]
 }
 
 resource "azurerm_subnet" "node_subnet" {
+  count                = local.create_network ? 1 : 0
   name                 = "${local.name}-node"
   resource_group_name  = var.resource_group
-  virtual_network_name = azurerm_virtual_network.network.name
+  virtual_network_name = azurerm_virtual_network.network[0].name
   address_prefixes     = [local.cidr_vpc_subnet_nodes]
 }
 
+// Subnet IDs have the form /subscriptions/$ID/resourceGroups/$RG/providers/Microsoft.Network/virtualNetworks/$VNET/subnets/$NAME
+data "azurerm_subnet" "existing" {
+  count                = local.create_network ? 0 : 1
+  name                 = element(split("/", var.subnet_id), 10)
+  virtual_network_name = element(split("/", var.subnet_id), 8)
+  resource_group_name  = element(split("/", var.subnet_id), 4)
+
+  lifecycle {
+    postcondition {
+      condition     = contains(self.address_prefixes, var.subnet_cidr)
+      error_message = "Subnet ${var.subnet_id} doesn't have the configured address prefix ${var.subnet_cidr}."
+    }
+  }
+}
+
 resource "azurerm_network_security_group" "security_group" {
   name                = local.name
   location            = var.location
@@ -292,9 +316,10 @@ module "scale_set_group" {
   user_assigned_identity    = var.user_assigned_identity
   image_id                  = var.image_id
   network_security_group_id = azurerm_network_security_group.security_group.id
-  subnet_id                 = azurerm_subnet.node_subnet.id
+  subnet_id                 = local.node_subnet_id
   backend_address_pool_ids  = each.value.role == "control-plane" ? [module.loadbalancer_backend_control_plane.backendpool_id] : []
   marketplace_image         = var.marketplace_image
+  user_data                 = var.user_data
 }
 
 module "jump_host" {
@@ -316,3 +341,18 @@ data "azurerm_user_assigned_identity" "uaid" {
   name                = local.uai_name
   resource_group_name = local.uai_resource_group
 }
+
+moved {
+  from = azurerm_virtual_network.network
+  to   = azurerm_virtual_network.network[0]
+}
+
+moved {
+  from = azurerm_subnet.node_subnet
+  to   = azurerm_subnet.node_subnet[0]
+}
+
+moved {
+  from = azurerm_subnet_nat_gateway_association.example
+  to   = azurerm_subnet_nat_gateway_association.example[0]
+}
@@ -52,6 +52,7 @@ resource "azurerm_linux_virtual_machine_scale_set" "scale_set" {
   source_image_id = var.marketplace_image != null ? null : var.image_id
   tags            = local.tags
   zones           = var.zones
+  user_data       = var.user_data != "" ? base64encode(var.user_data) : null
   identity {
     type         = "UserAssigned"
     identity_ids = [var.user_assigned_identity]
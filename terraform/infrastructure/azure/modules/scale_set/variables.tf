@@ -107,3 +107,9 @@ variable "marketplace_image" {
   default     = null
   description = "Marketplace image to use for the cluster nodes."
 }
+
+variable "user_data" {
+  type        = string
+  default     = ""
+  description = "User data script run by the instances on boot."
+}
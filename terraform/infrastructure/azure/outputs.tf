@@ -78,3 +78,13 @@ output "subscription_id" {
   value       = data.azurerm_subscription.current.subscription_id
   description = "ID of the Azure subscription the cluster resides in."
 }
+
+output "virtual_network_id" {
+  value       = local.virtual_network_id
+  description = "ID of the virtual network the cluster's nodes are attached to."
+}
+
+output "subnet_id" {
+  value       = local.node_subnet_id
+  description = "ID of the subnet the cluster's nodes are attached to."
+}
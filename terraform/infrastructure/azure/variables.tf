@@ -5,6 +5,12 @@ variable "name" {
   description = "Name of the Constellation cluster."
 }
 
+variable "name_template" {
+  type        = string
+  default     = ""
+  description = "Template for the base name of the created resources. The placeholders {name} and {uid} are replaced with the name and the UID of the cluster. Defaults to \"{name}-{uid}\"."
+}
+
 variable "node_groups" {
   type = map(object({
     role          = string
@@ -101,3 +107,27 @@ variable "additional_tags" {
   default     = {}
   description = "Additional tags that should be applied to created resources."
 }
+
+variable "user_data" {
+  type        = string
+  default     = ""
+  description = "User data script run by the instances on boot. Not covered by attestation."
+}
+
+variable "virtual_network_id" {
+  type        = string
+  default     = ""
+  description = "Resource ID of an existing virtual network to attach the nodes to. If empty, a virtual network is created."
+}
+
+variable "subnet_id" {
+  type        = string
+  default     = ""
+  description = "Resource ID of an existing subnet of the virtual network to attach the nodes to. If empty, a subnet is created."
+}
+
+variable "subnet_cidr" {
+  type        = string
+  default     = ""
+  description = "CIDR range of the existing subnet. Must be one of the address prefixes of the subnet."
+}
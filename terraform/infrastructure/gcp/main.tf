@@ -20,15 +20,20 @@ provider "google" {
 
 locals {
   uid              = random_id.uid.hex
-  name             = "${var.name}-${local.uid}"
+  name             = var.name_template == "" ? "${var.name}-${local.uid}" : replace(replace(var.name_template, "{name}", var.name), "{uid}", local.uid)
   init_secret_hash = random_password.init_secret.bcrypt_hash
   labels = merge(
     var.additional_labels,
     { constellation-uid = local.uid }
   )
-  ports_node_range      = "30000-32767"
-  cidr_vpc_subnet_nodes = "192.168.178.0/24"
-  cidr_vpc_subnet_pods  = "10.10.0.0/16"
+  ports_node_range = "30000-32767"
+  // create_network is false if the nodes are attached to an existing network and subnetwork.
+  create_network        = var.subnetwork_id == ""
+  network_id            = local.create_network ? google_compute_network.vpc_network[0].id : var.network_id
+  subnetwork_id         = local.create_network ? google_compute_subnetwork.vpc_subnetwork[0].id : data.google_compute_subnetwork.existing[0].id
+  pod_range_name        = local.create_network ? google_compute_subnetwork.vpc_subnetwork[0].secondary_ip_range[0].range_name : data.google_compute_subnetwork.existing[0].secondary_ip_range[0].range_name
+  cidr_vpc_subnet_nodes = local.create_network ? "192.168.178.0/24" : var.subnetwork_cidr
+  cidr_vpc_subnet_pods  = local.create_network ? "10.10.0.0/16" : data.google_compute_subnetwork.existing[0].secondary_ip_range[0].ip_cidr_range
   cidr_vpc_subnet_proxy = "192.168.179.0/24"
   cidr_vpc_subnet_ilb   = "192.168.180.0/24"
   kube_env              = "AUTOSCALER_ENV_VARS: kube_reserved=cpu=1060m,memory=1019Mi,ephemeral-storage=41Gi;node_labels=;os=linux;os_distribution=cos;evictionHard="
@@ -69,21 +74,53 @@ resource "random_password" "init_secret" {
 }
 
 resource "google_compute_network" "vpc_network" {
+  count                   = local.create_network ? 1 : 0
   name                    = local.name
-  description             = "Constellation VPC network"
+  description             = "Constellation VPC network constellation-uid=${local.uid}"
   auto_create_subnetworks = false
   mtu                     = 8896
+
+  lifecycle {
+    # Changing the description replaces the network. Networks of existing clusters keep the description without the UID.
+    ignore_changes = [description]
+  }
 }
 
 resource "google_compute_subnetwork" "vpc_subnetwork" {
+  count         = local.create_network ? 1 : 0
   name          = local.name
-  description   = "Constellation VPC subnetwork"
-  network       = google_compute_network.vpc_network.id
+  description   = "Constellation VPC subnetwork constellation-uid=${local.uid}"
+  network       = google_compute_network.vpc_network[0].id
   ip_cidr_range = local.cidr_vpc_subnet_nodes
   secondary_ip_range {
     range_name    = local.name
     ip_cidr_range = local.cidr_vpc_subnet_pods
   }
+
+  lifecycle {
+    # Changing the description replaces the subnetwork. Subnetworks of existing clusters keep the description without the UID.
+    ignore_changes = [description]
+  }
+}
+
+data "google_compute_subnetwork" "existing" {
+  count     = local.create_network ? 0 : 1
+  self_link = var.subnetwork_id
+
+  lifecycle {
+    postcondition {
+      condition     = endswith(self.network, var.network_id)
+      error_message = "Subnetwork ${var.subnetwork_id} is not part of network ${var.network_id}."
+    }
+    postcondition {
+      condition     = self.ip_cidr_range == var.subnetwork_cidr
+      error_message = "CIDR range ${self.ip_cidr_range} of subnetwork ${var.subnetwork_id} doesn't match the configured CIDR range ${var.subnetwork_cidr}."
+    }
+    postcondition {
+      condition     = length(self.secondary_ip_range) > 0
+      error_message = "Subnetwork ${var.subnetwork_id} needs a secondary IP range for the cluster's pods."
+    }
+  }
 }
 
 resource "google_compute_subnetwork" "proxy_subnet" {
@@ -93,7 +130,7 @@ resource "google_compute_subnetwork" "proxy_subnet" {
   region        = var.region
   purpose       = "REGIONAL_MANAGED_PROXY"
   role          = "ACTIVE"
-  network       = google_compute_network.vpc_network.id
+  network       = local.network_id
 }
 
 resource "google_compute_subnetwork" "ilb_subnet" {
@@ -101,19 +138,22 @@ resource "google_compute_subnetwork" "ilb_subnet" {
   name          = "${local.name}-ilb"
   ip_cidr_range = local.cidr_vpc_subnet_ilb
   region        = var.region
-  network       = google_compute_network.vpc_network.id
+  network       = local.network_id
   depends_on    = [google_compute_subnetwork.proxy_subnet]
 }
 
+# Existing networks are expected to provide their own egress.
 resource "google_compute_router" "vpc_router" {
+  count       = local.create_network ? 1 : 0
   name        = local.name
   description = "Constellation VPC router"
-  network     = google_compute_network.vpc_network.id
+  network     = google_compute_network.vpc_network[0].id
 }
 
 resource "google_compute_router_nat" "vpc_router_nat" {
+  count                              = local.create_network ? 1 : 0
   name                               = local.name
-  router                             = google_compute_router.vpc_router.name
+  router                             = google_compute_router.vpc_router[0].name
   nat_ip_allocate_option             = "AUTO_ONLY"
   source_subnetwork_ip_ranges_to_nat = "ALL_SUBNETWORKS_ALL_IP_RANGES"
 }
@@ -121,7 +161,7 @@ resource "google_compute_router_nat" "vpc_router_nat" {
 resource "google_compute_firewall" "firewall_external" {
   name          = local.name
   description   = "Constellation VPC firewall"
-  network       = google_compute_network.vpc_network.id
+  network       = local.network_id
   source_ranges = ["0.0.0.0/0"]
   direction     = "INGRESS"
 
@@ -138,7 +178,7 @@ resource "google_compute_firewall" "firewall_external" {
 resource "google_compute_firewall" "firewall_internal_nodes" {
   name          = "${local.name}-nodes"
   description   = "Constellation VPC firewall"
-  network       = google_compute_network.vpc_network.id
+  network       = local.network_id
   source_ranges = [local.cidr_vpc_subnet_nodes]
   direction     = "INGRESS"
 
@@ -150,7 +190,7 @@ resource "google_compute_firewall" "firewall_internal_nodes" {
 resource "google_compute_firewall" "firewall_internal_pods" {
   name          = "${local.name}-pods"
   description   = "Constellation VPC firewall"
-  network       = google_compute_network.vpc_network.id
+  network       = local.network_id
   source_ranges = [local.cidr_vpc_subnet_pods]
   direction     = "INGRESS"
 
@@ -172,9 +212,9 @@ module "instance_group" {
   image_id            = var.image_id
   disk_size           = each.value.disk_size
   disk_type           = each.value.disk_type
-  network             = google_compute_network.vpc_network.id
-  subnetwork          = google_compute_subnetwork.vpc_subnetwork.id
-  alias_ip_range_name = google_compute_subnetwork.vpc_subnetwork.secondary_ip_range[0].range_name
+  network             = local.network_id
+  subnetwork          = local.subnetwork_id
+  alias_ip_range_name = local.pod_range_name
   kube_env            = local.kube_env
   debug               = var.debug
   named_ports         = each.value.role == "control-plane" ? local.control_plane_named_ports : []
@@ -182,6 +222,8 @@ module "instance_group" {
   init_secret_hash    = local.init_secret_hash
   custom_endpoint     = var.custom_endpoint
   cc_technology       = var.cc_technology
+  user_data           = var.user_data
+  disk_encryption_key = var.disk_encryption_key
 }
 
 resource "google_compute_address" "loadbalancer_ip_internal" {
@@ -224,7 +266,7 @@ module "loadbalancer_internal" {
   frontend_labels        = merge(local.labels, { constellation-use = each.value.name })
 
   region         = var.region
-  network        = google_compute_network.vpc_network.id
+  network        = local.network_id
   backend_subnet = google_compute_subnetwork.ilb_subnet[0].id
 }
 
@@ -233,7 +275,7 @@ module "jump_host" {
   source         = "./modules/jump_host"
   base_name      = local.name
   zone           = var.zone
-  subnetwork     = google_compute_subnetwork.vpc_subnetwork.id
+  subnetwork     = local.subnetwork_id
   labels         = var.additional_labels
   lb_internal_ip = google_compute_address.loadbalancer_ip_internal[0].address
   ports          = [for port in local.control_plane_named_ports : port.port]
@@ -267,3 +309,23 @@ moved {
   from = module.loadbalancer_debugd[0]
   to   = module.loadbalancer_public["debugd"]
 }
+
+moved {
+  from = google_compute_network.vpc_network
+  to   = google_compute_network.vpc_network[0]
+}
+
+moved {
+  from = google_compute_subnetwork.vpc_subnetwork
+  to   = google_compute_subnetwork.vpc_subnetwork[0]
+}
+
+moved {
+  from = google_compute_router.vpc_router
+  to   = google_compute_router.vpc_router[0]
+}
+
+moved {
+  from = google_compute_router_nat.vpc_router_nat
+  to   = google_compute_router_nat.vpc_router_nat[0]
+}
@@ -46,6 +46,13 @@ resource "google_compute_instance_template" "template" {
     auto_delete  = true
     boot         = true
     mode         = "READ_WRITE"
+
+    dynamic "disk_encryption_key" {
+      for_each = var.disk_encryption_key != "" ? [1] : []
+      content {
+        kms_key_self_link = var.disk_encryption_key
+      }
+    }
   }
 
   disk {
@@ -56,14 +63,24 @@ resource "google_compute_instance_template" "template" {
     boot         = false
     mode         = "READ_WRITE"
     type         = "PERSISTENT"
-  }
 
-  metadata = {
-    kube-env                       = var.kube_env
-    constellation-init-secret-hash = var.init_secret_hash
-    serial-port-enable             = "TRUE"
+    dynamic "disk_encryption_key" {
+      for_each = var.disk_encryption_key != "" ? [1] : []
+      content {
+        kms_key_self_link = var.disk_encryption_key
+      }
+    }
   }
 
+  metadata = merge(
+    {
+      kube-env                       = var.kube_env
+      constellation-init-secret-hash = var.init_secret_hash
+      serial-port-enable             = "TRUE"
+    },
+    var.user_data != "" ? { user-data = var.user_data } : {},
+  )
+
   network_interface {
     network    = var.network
     subnetwork = var.subnetwork
@@ -108,3 +108,15 @@ variable "cc_technology" {
     error_message = "The confidential computing technology has to be 'SEV' or 'SEV_SNP'."
   }
 }
+
+variable "user_data" {
+  type        = string
+  default     = ""
+  description = "User data script run by the instances on boot."
+}
+
+variable "disk_encryption_key" {
+  type        = string
+  default     = ""
+  description = "Cloud KMS key to encrypt the boot and state disks with. If empty, Google-managed keys are used."
+}
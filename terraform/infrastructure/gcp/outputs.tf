@@ -56,3 +56,18 @@ output "ip_cidr_pod" {
   value       = local.cidr_vpc_subnet_pods
   description = "CIDR block of the pod network."
 }
+
+output "network_id" {
+  value       = local.network_id
+  description = "ID of the network the cluster's nodes are attached to."
+}
+
+output "subnetwork_id" {
+  value       = local.subnetwork_id
+  description = "ID of the subnetwork the cluster's nodes are attached to."
+}
+
+output "disk_encryption_key" {
+  value       = var.disk_encryption_key
+  description = "Cloud KMS key the disks of the cluster's nodes are encrypted with."
+}
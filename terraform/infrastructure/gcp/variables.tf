@@ -5,6 +5,12 @@ variable "name" {
   description = "Name of the Constellation cluster."
 }
 
+variable "name_template" {
+  type        = string
+  default     = ""
+  description = "Template for the base name of the created resources. The placeholders {name} and {uid} are replaced with the name and the UID of the cluster. Defaults to \"{name}-{uid}\"."
+}
+
 variable "node_groups" {
   type = map(object({
     role          = string
@@ -75,3 +81,33 @@ variable "additional_labels" {
   default     = {}
   description = "Additional labels that should be given to created recources."
 }
+
+variable "user_data" {
+  type        = string
+  default     = ""
+  description = "User data script run by the instances on boot. Not covered by attestation."
+}
+
+variable "network_id" {
+  type        = string
+  default     = ""
+  description = "ID of an existing network to attach the nodes to, in the form `projects/<project>/global/networks/<network>`. If empty, a network is created."
+}
+
+variable "subnetwork_id" {
+  type        = string
+  default     = ""
+  description = "ID of an existing subnetwork of the network to attach the nodes to, in the form `projects/<project>/regions/<region>/subnetworks/<subnetwork>`. If empty, a subnetwork is created."
+}
+
+variable "subnetwork_cidr" {
+  type        = string
+  default     = ""
+  description = "CIDR range of the existing subnetwork. Must match the primary IP range of the subnetwork."
+}
+
+variable "disk_encryption_key" {
+  type        = string
+  default     = ""
+  description = "Cloud KMS key to encrypt the boot and state disks of the nodes with, in the form `projects/<project>/locations/<location>/keyRings/<key-ring>/cryptoKeys/<key>`. If empty, Google-managed keys are used."
+}
@@ -32,7 +32,7 @@ data "openstack_identity_auth_scope_v3" "scope" {
 
 locals {
   uid                    = random_id.uid.hex
-  name                   = "${var.name}-${local.uid}"
+  name                   = var.name_template == "" ? "${var.name}-${local.uid}" : replace(replace(var.name_template, "{name}", var.name), "{uid}", local.uid)
   init_secret_hash       = random_password.init_secret.bcrypt_hash
   ports_node_range_start = "30000"
   ports_node_range_end   = "32767"
@@ -102,7 +102,7 @@ resource "openstack_networking_subnet_v2" "vpc_subnetwork" {
 }
 
 resource "openstack_networking_subnet_v2" "lb_subnetwork" {
-  name        = "${var.name}-${local.uid}-lb"
+  name        = "${local.name}-lb"
   description = "Constellation LB subnetwork"
   network_id  = openstack_networking_network_v2.vpc_network.id
   cidr        = local.cidr_vpc_subnet_lbs
@@ -5,6 +5,13 @@ variable "name" {
   default     = "constell"
   description = "Base name of the cluster."
 }
+
+variable "name_template" {
+  type        = string
+  default     = ""
+  description = "Template for the base name of the created resources. The placeholders {name} and {uid} are replaced with the name and the UID of the cluster. Defaults to \"{name}-{uid}\"."
+}
+
 variable "node_groups" {
   type = map(object({
     role            = string